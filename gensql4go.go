@@ -24,14 +24,31 @@ type Repository[T Entity] interface {
 	repository.Repository[T]
 }
 
+// RepositoryOption configures a single repository (e.g. repository.WithSegmentedCollections)
+type RepositoryOption = repository.Option
+
 // RedisConfig represents Redis configuration
 type RedisConfig = redis.Config
 
 // NewRepository creates a new repository instance
 // If redisManager is nil, operates in database-only mode
 // If redisManager is provided, automatically enables intelligent caching
-func NewRepository[T Entity](dbManager *db.Manager, redisManager *redis.Manager) Repository[T] {
-	return repository.NewGenericRepository[T](dbManager, redisManager)
+func NewRepository[T Entity](dbManager *db.Manager, redisManager *redis.Manager, opts ...RepositoryOption) Repository[T] {
+	return repository.NewGenericRepository[T](dbManager, redisManager, opts...)
+}
+
+// Cache is the cache of a repository: a *redis.Manager or a *repository.MemoryCache
+type Cache = repository.Cache
+
+// NewRepositoryWithCache creates a new repository caching through cache (nil operates in
+// database-only mode)
+func NewRepositoryWithCache[T Entity](dbManager *db.Manager, cache Cache, opts ...RepositoryOption) Repository[T] {
+	return repository.NewGenericRepositoryWithCache[T](dbManager, cache, opts...)
+}
+
+// NewCache creates the cache selected by config.Backend: Redis or an in-memory map
+func NewCache(config *RedisConfig) (Cache, error) {
+	return repository.NewCache(config)
 }
 
 // NewRedisManager creates a new Redis manager
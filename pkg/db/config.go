@@ -7,11 +7,15 @@ import (
 	"encoding/hex"
 	"fmt"
 	"os"
+	"regexp"
 	"time"
 
 	"github.com/go-sql-driver/mysql"
 )
 
+// sessionVariableName matches valid MySQL system variable names
+var sessionVariableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
 // Validate checks if the database configuration is valid
 func (c *Config) Validate() error {
 	if c.Host == "" {
@@ -32,6 +36,18 @@ func (c *Config) Validate() error {
 	if c.MaxIdleConns > c.MaxOpenConns {
 		return fmt.Errorf("max_idle_conns cannot be greater than max_open_conns")
 	}
+	if c.MaxQueryRows < 0 {
+		return fmt.Errorf("max_query_rows cannot be negative")
+	}
+
+	for name, value := range c.SessionVariables {
+		if !sessionVariableName.MatchString(name) {
+			return fmt.Errorf("invalid session variable name %q", name)
+		}
+		if value == "" {
+			return fmt.Errorf("session variable %s has an empty value", name)
+		}
+	}
 
 	// Validate TLS configuration if SSL is enabled
 	if c.SSL.Enabled && !c.SSL.SkipVerify {
@@ -86,6 +102,14 @@ func (c *Config) GetDSN() string {
 		AllowNativePasswords: true,
 	}
 
+	// Session variables are sent by the driver as SET statements on each new connection
+	if len(c.SessionVariables) > 0 {
+		cfg.Params = make(map[string]string, len(c.SessionVariables))
+		for name, value := range c.SessionVariables {
+			cfg.Params[name] = value
+		}
+	}
+
 	// Handle TLS configuration properly
 	if c.SSL.Enabled {
 		if c.SSL.SkipVerify {
@@ -0,0 +1,231 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+)
+
+// queryRecorder is a minimal MySQL server that accepts any login and records the
+// text of each COM_QUERY it receives. The driver's max_allowed_packet lookup gets
+// a one-row result; every other command is answered with OK
+type queryRecorder struct {
+	listener net.Listener
+
+	mu      sync.Mutex
+	queries []string
+}
+
+// startQueryRecorder listens on a loopback port until the test ends
+func startQueryRecorder(t *testing.T) *queryRecorder {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	recorder := &queryRecorder{listener: listener}
+	t.Cleanup(func() { _ = listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go recorder.serve(conn)
+		}
+	}()
+	return recorder
+}
+
+// port returns the port the recorder listens on
+func (r *queryRecorder) port() int {
+	return r.listener.Addr().(*net.TCPAddr).Port
+}
+
+// recorded returns the queries received so far
+func (r *queryRecorder) recorded() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.queries...)
+}
+
+func (r *queryRecorder) serve(conn net.Conn) {
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	// Capabilities: long password, protocol 41, transactions, secure connection, plugin auth
+	const capabilities = 0x1 | 0x200 | 0x2000 | 0x8000 | 0x80000
+	handshake := []byte{10}
+	handshake = append(handshake, "8.0.36\x00"...)
+	handshake = append(handshake, 1, 0, 0, 0)
+	handshake = append(handshake, "abcdefgh"...)
+	handshake = append(handshake, 0)
+	handshake = binary.LittleEndian.AppendUint16(handshake, capabilities&0xffff)
+	handshake = append(handshake, 255, 2, 0)
+	handshake = binary.LittleEndian.AppendUint16(handshake, capabilities>>16)
+	handshake = append(handshake, 21)
+	handshake = append(handshake, make([]byte, 10)...)
+	handshake = append(handshake, "ijklmnopqrst\x00"...)
+	handshake = append(handshake, "mysql_native_password\x00"...)
+	ok := []byte{0, 0, 0, 2, 0, 0, 0}
+
+	if writeMySQLPacket(conn, 0, handshake) != nil {
+		return
+	}
+	if _, err := readMySQLPacket(conn); err != nil {
+		return
+	}
+	if writeMySQLPacket(conn, 2, ok) != nil {
+		return
+	}
+
+	for {
+		payload, err := readMySQLPacket(conn)
+		if err != nil || len(payload) == 0 || payload[0] == 0x01 { // COM_QUIT
+			return
+		}
+		if payload[0] != 0x03 { // not COM_QUERY
+			if writeMySQLPacket(conn, 1, ok) != nil {
+				return
+			}
+			continue
+		}
+
+		query := string(payload[1:])
+		r.mu.Lock()
+		r.queries = append(r.queries, query)
+		r.mu.Unlock()
+
+		reply := [][]byte{ok}
+		if query == "SELECT @@max_allowed_packet" {
+			reply = singleValueResult(query, "67108864")
+		}
+		for i, packet := range reply {
+			if writeMySQLPacket(conn, byte(i+1), packet) != nil {
+				return
+			}
+		}
+	}
+}
+
+// singleValueResult returns the packets of a one-column, one-row text result set
+func singleValueResult(column, value string) [][]byte {
+	lenenc := func(b []byte, s string) []byte {
+		return append(append(b, byte(len(s))), s...)
+	}
+	eof := []byte{0xfe, 0, 0, 2, 0}
+
+	definition := lenenc(nil, "def")
+	for _, field := range []string{"", "", "", column, ""} {
+		definition = lenenc(definition, field)
+	}
+	// Fixed fields: charset binary, length 20, type LONGLONG, no flags or decimals
+	definition = append(definition, 0x0c, 63, 0, 20, 0, 0, 0, 0x08, 0, 0, 0, 0, 0)
+
+	return [][]byte{{1}, definition, eof, lenenc(nil, value), eof}
+}
+
+func readMySQLPacket(conn net.Conn) ([]byte, error) {
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return nil, err
+	}
+	payload := make([]byte, int(header[0])|int(header[1])<<8|int(header[2])<<16)
+	_, err := io.ReadFull(conn, payload)
+	return payload, err
+}
+
+func writeMySQLPacket(conn net.Conn, seq byte, payload []byte) error {
+	n := len(payload)
+	_, err := conn.Write(append([]byte{byte(n), byte(n >> 8), byte(n >> 16), seq}, payload...))
+	return err
+}
+
+func TestSessionVariablesIssuedOnNewConnection(t *testing.T) {
+	recorder := startQueryRecorder(t)
+
+	config := &Config{
+		Host:      "127.0.0.1",
+		Port:      recorder.port(),
+		Database:  "app",
+		Username:  "app",
+		Collation: "utf8mb4_unicode_ci",
+		TimeZone:  "UTC",
+		SessionVariables: map[string]string{
+			"sql_mode":              "'STRICT_ALL_TABLES'",
+			"transaction_isolation": "'READ-COMMITTED'",
+		},
+	}
+
+	sqlDB, err := sql.Open("mysql", config.GetDSN())
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer sqlDB.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := sqlDB.PingContext(ctx); err != nil {
+		t.Fatalf("ping: %v", err)
+	}
+
+	var sets []string
+	for _, query := range recorder.recorded() {
+		if strings.HasPrefix(query, "SET ") {
+			sets = append(sets, query)
+		}
+	}
+	if len(sets) != 1 {
+		t.Fatalf("SET statements on connect = %q, want one", sets)
+	}
+	for name, value := range config.SessionVariables {
+		if want := name + " = " + value; !strings.Contains(sets[0], want) {
+			t.Errorf("SET statement %q is missing %q", sets[0], want)
+		}
+	}
+}
+
+func TestValidateSessionVariables(t *testing.T) {
+	tests := []struct {
+		name      string
+		variables map[string]string
+		wantErr   string
+	}{
+		{name: "valid", variables: map[string]string{"time_zone": "'+00:00'"}},
+		{name: "invalid name", variables: map[string]string{"sql_mode; DROP": "''"}, wantErr: "invalid session variable name"},
+		{name: "empty value", variables: map[string]string{"sql_mode": ""}, wantErr: "empty value"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{
+				Host:             "localhost",
+				Port:             3306,
+				Database:         "app",
+				Username:         "app",
+				MaxOpenConns:     1,
+				SessionVariables: tt.variables,
+			}
+			err := config.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Validate: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Validate error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
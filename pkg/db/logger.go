@@ -0,0 +1,274 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"gorm.io/gorm/logger"
+)
+
+// Redaction styles for LoggingConfig.RedactionStyle
+const (
+	RedactionStyleType        = "type"        // <redacted:string>
+	RedactionStylePlaceholder = "placeholder" // ?
+)
+
+// redactedPlaceholder stands in for a redacted "?" until the SQL is rendered
+// GORM fills every "?" left in the SQL with the next bound value, so a literal "?"
+// cannot mark a redacted value; it is restored in Trace.
+const redactedPlaceholder = "\x1a"
+
+var (
+	// insertColumnsPattern matches the column list of an INSERT statement
+	insertColumnsPattern = regexp.MustCompile("(?is)^\\s*(?:INSERT|REPLACE)\\s+(?:IGNORE\\s+)?INTO\\s+\\S+\\s*\\(([^)]*)\\)\\s*VALUES")
+
+	// comparisonOperators bind the placeholder after them to the column before them
+	comparisonOperators = []string{"<=>", "<>", "!=", "<=", ">=", "=", "<", ">", " NOT IN", " IN", " NOT LIKE", " LIKE", " BETWEEN"}
+
+	// errorValuePattern matches quoted values in database errors (e.g. "Duplicate entry 'x'")
+	errorValuePattern = regexp.MustCompile(`'[^']*'`)
+)
+
+// queryLogger is the GORM logger built from LoggingConfig
+// It logs through GORM's standard logger and, unless LogQueryParameters is set, redacts
+// bound values before they are interpolated into the logged SQL (see gorm.ParamsFilter).
+type queryLogger struct {
+	logger.Interface
+	config           LoggingConfig
+	loggedColumns    map[string]bool
+	loggedPositions  map[int]bool
+	placeholderStyle bool
+}
+
+// newQueryLogger creates the GORM logger for a logging configuration
+func newQueryLogger(writer logger.Writer, config LoggingConfig) logger.Interface {
+	level := getLogLevel(config.Level)
+	if config.LogQueries {
+		level = logger.Info
+	}
+
+	slowThreshold := time.Duration(0)
+	if config.LogSlowQueries {
+		slowThreshold = config.SlowQueryThreshold
+	}
+
+	l := &queryLogger{
+		Interface: logger.New(writer, logger.Config{
+			SlowThreshold: slowThreshold,
+			LogLevel:      level,
+			Colorful:      true,
+		}),
+		config:           config,
+		loggedColumns:    make(map[string]bool, len(config.LoggedParameterColumns)),
+		loggedPositions:  make(map[int]bool, len(config.LoggedParameterPositions)),
+		placeholderStyle: strings.EqualFold(config.RedactionStyle, RedactionStylePlaceholder),
+	}
+	for _, column := range config.LoggedParameterColumns {
+		l.loggedColumns[strings.ToLower(column)] = true
+	}
+	for _, position := range config.LoggedParameterPositions {
+		l.loggedPositions[position] = true
+	}
+
+	return l
+}
+
+// defaultQueryLogger creates the GORM logger writing to stdout
+func defaultQueryLogger(config LoggingConfig) logger.Interface {
+	return newQueryLogger(log.New(os.Stdout, "\r\n", log.LstdFlags), config)
+}
+
+// LogMode returns a copy of the logger with a different log level
+func (l *queryLogger) LogMode(level logger.LogLevel) logger.Interface {
+	newLogger := *l
+	newLogger.Interface = l.Interface.LogMode(level)
+	return &newLogger
+}
+
+// Trace logs a statement, restoring redacted placeholders and redacting error values
+func (l *queryLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	if l.config.LogQueryParameters {
+		l.Interface.Trace(ctx, begin, fc, err)
+		return
+	}
+
+	if err != nil {
+		err = &redactedError{err: err}
+	}
+	l.Interface.Trace(ctx, begin, func() (string, int64) {
+		sql, rows := fc()
+		return strings.ReplaceAll(sql, redactedPlaceholder, "?"), rows
+	}, err)
+}
+
+// ParamsFilter redacts bound values before GORM interpolates them into the logged SQL
+// Redacted placeholders are rewritten in the SQL itself and dropped from params, so only
+// allow-listed values are interpolated.
+func (l *queryLogger) ParamsFilter(ctx context.Context, sql string, params ...interface{}) (string, []interface{}) {
+	if l.config.LogQueryParameters {
+		return sql, params
+	}
+
+	columns := placeholderColumns(sql)
+	kept := make([]interface{}, 0, len(params))
+
+	var b strings.Builder
+	b.Grow(len(sql))
+	position := 0
+	for i := 0; i < len(sql); i++ {
+		if sql[i] != '?' || position >= len(columns) || position >= len(params) || columns[position].offset != i {
+			b.WriteByte(sql[i])
+			continue
+		}
+
+		if l.loggedPositions[position+1] || (columns[position].name != "" && l.loggedColumns[columns[position].name]) {
+			b.WriteByte('?')
+			kept = append(kept, params[position])
+		} else if l.placeholderStyle {
+			b.WriteString(redactedPlaceholder)
+		} else {
+			b.WriteString(redactionMarker(params[position]))
+		}
+		position++
+	}
+
+	return b.String(), kept
+}
+
+// redactionMarker returns the marker replacing a redacted value, e.g. <redacted:string>
+func redactionMarker(value interface{}) string {
+	kind := "null"
+	switch v := value.(type) {
+	case nil:
+	case time.Time, *time.Time:
+		kind = "time"
+	case []byte:
+		kind = "bytes"
+	default:
+		rv := reflect.Indirect(reflect.ValueOf(v))
+		switch rv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			kind = "int"
+		case reflect.Float32, reflect.Float64:
+			kind = "float"
+		case reflect.Invalid:
+		default:
+			kind = rv.Kind().String() // string, bool, struct (e.g. sql.NullString), ...
+		}
+	}
+	return fmt.Sprintf("<redacted:%s>", kind)
+}
+
+// placeholder is a "?" in SQL and the column it is bound to
+type placeholder struct {
+	offset int
+	name   string // Lowercase column name, "" if unknown
+}
+
+// placeholderColumns finds the "?" placeholders of sql outside quoted literals and
+// the column each is bound to: the column compared or assigned before it
+// ("`email` = ?", "tenant_id IN (?,?)", "SET `name`=?") or, for INSERT, the column
+// at its position in the column list. Unknown columns are only loggable by position.
+func placeholderColumns(sql string) []placeholder {
+	var placeholders []placeholder
+	var quote byte
+	for i := 0; i < len(sql); i++ {
+		switch c := sql[i]; {
+		case quote != 0:
+			if c == '\\' && quote != '`' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '?':
+			placeholders = append(placeholders, placeholder{offset: i, name: columnBefore(sql[:i])})
+		}
+	}
+
+	// INSERT ... (a, b) VALUES (?, ?), (?, ?): bind by position within each row
+	if match := insertColumnsPattern.FindStringSubmatchIndex(sql); match != nil {
+		var columns []string
+		for _, column := range strings.Split(sql[match[2]:match[3]], ",") {
+			columns = append(columns, normalizeColumn(column))
+		}
+
+		valuesEnd := len(sql)
+		if idx := strings.Index(strings.ToUpper(sql), "ON DUPLICATE KEY"); idx > match[1] {
+			valuesEnd = idx
+		}
+
+		position := 0
+		for i := range placeholders {
+			if placeholders[i].offset > match[1] && placeholders[i].offset < valuesEnd && len(columns) > 0 {
+				placeholders[i].name = columns[position%len(columns)]
+				position++
+			}
+		}
+	}
+
+	return placeholders
+}
+
+// columnBefore returns the column compared with a placeholder at the end of sql
+func columnBefore(sql string) string {
+	// Skip the rest of an IN list: "col IN (?,?," -> "col IN"
+	sql = strings.TrimRight(sql, " \t\n(,?")
+
+	upper := strings.ToUpper(sql)
+	matched := false
+	for _, op := range comparisonOperators {
+		if strings.HasSuffix(upper, op) {
+			sql = strings.TrimRight(sql[:len(sql)-len(op)], " \t\n")
+			matched = true
+			break
+		}
+	}
+	if !matched {
+		return ""
+	}
+
+	// Identifier, possibly qualified and quoted: `users`.`email`
+	start := len(sql)
+	for start > 0 {
+		c := sql[start-1]
+		if c == '_' || c == '`' || c == '.' || c == '$' ||
+			(c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') {
+			start--
+			continue
+		}
+		break
+	}
+	return normalizeColumn(sql[start:])
+}
+
+// normalizeColumn strips quoting and table qualification from a column reference
+func normalizeColumn(column string) string {
+	column = strings.TrimSpace(column)
+	if idx := strings.LastIndex(column, "."); idx >= 0 {
+		column = column[idx+1:]
+	}
+	return strings.ToLower(strings.Trim(column, "`\""))
+}
+
+// redactedError hides the quoted values of a database error message
+// (e.g. "Duplicate entry 'a@example.com' for key 'email'") while keeping errors.Is working
+type redactedError struct {
+	err error
+}
+
+func (e *redactedError) Error() string {
+	return errorValuePattern.ReplaceAllString(e.err.Error(), "'<redacted>'")
+}
+
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
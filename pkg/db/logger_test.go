@@ -0,0 +1,112 @@
+package db
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+// bufferWriter collects the lines written by the GORM logger
+type bufferWriter struct {
+	bytes.Buffer
+}
+
+func (w *bufferWriter) Printf(format string, args ...interface{}) {
+	fmt.Fprintf(&w.Buffer, format+"\n", args...)
+}
+
+type loggedUser struct {
+	ID       uint
+	TenantID uint
+	Email    string
+}
+
+func TestSlowQueryLogRedactsEmail(t *testing.T) {
+	queries := []struct {
+		name   string
+		expect func(sqlmock.Sqlmock)
+		run    func(*gorm.DB) error
+	}{
+		{
+			name: "in list",
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT \\* FROM `logged_users`").
+					WillDelayFor(5 * time.Millisecond).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "tenant_id", "email"}))
+			},
+			run: func(db *gorm.DB) error {
+				var users []loggedUser
+				return db.Where("email IN ?", []string{"ann@example.com", "bob@example.com"}).
+					Where("tenant_id = ?", 4242).Find(&users).Error
+			},
+		},
+		{
+			name: "insert",
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("INSERT INTO `logged_users`").
+					WillDelayFor(5 * time.Millisecond).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+			},
+			run: func(db *gorm.DB) error {
+				return db.Create(&loggedUser{TenantID: 4242, Email: "ann@example.com"}).Error
+			},
+		},
+	}
+
+	for _, prepare := range []bool{false, true} {
+		for _, query := range queries {
+			t.Run(fmt.Sprintf("%s/prepare=%v", query.name, prepare), func(t *testing.T) {
+				sqlDB, mock, err := sqlmock.New()
+				if err != nil {
+					t.Fatalf("sqlmock: %v", err)
+				}
+				defer sqlDB.Close()
+
+				out := &bufferWriter{}
+				gormDB, err := gorm.Open(mysql.New(mysql.Config{Conn: sqlDB, SkipInitializeWithVersion: true}), &gorm.Config{
+					SkipDefaultTransaction: true,
+					PrepareStmt:            prepare,
+					Logger: newQueryLogger(out, LoggingConfig{
+						Level:                  "warn",
+						LogSlowQueries:         true,
+						SlowQueryThreshold:     time.Millisecond,
+						LoggedParameterColumns: []string{"tenant_id"},
+					}),
+				})
+				if err != nil {
+					t.Fatalf("gorm: %v", err)
+				}
+
+				if prepare {
+					mock.ExpectPrepare(".*")
+				}
+				query.expect(mock)
+				if err := query.run(gormDB); err != nil {
+					t.Fatalf("query: %v", err)
+				}
+
+				logged := out.String()
+				if !strings.Contains(logged, "SLOW SQL") {
+					t.Fatalf("no slow query logged: %q", logged)
+				}
+				for _, email := range []string{"ann@example.com", "bob@example.com"} {
+					if strings.Contains(logged, email) {
+						t.Errorf("logged query contains %q: %s", email, logged)
+					}
+				}
+				if !strings.Contains(logged, "<redacted:string>") {
+					t.Errorf("logged query has no redaction marker: %s", logged)
+				}
+				if !strings.Contains(logged, "4242") {
+					t.Errorf("allow-listed tenant_id is missing from the logged query: %s", logged)
+				}
+			})
+		}
+	}
+}
@@ -70,12 +70,11 @@ func NewManager(config *Config) (*Manager, error) {
 		return nil, fmt.Errorf("invalid config: %w", err)
 	}
 
-	logLevel := getLogLevel(config.Logging.Level)
 	gormConfig := &gorm.Config{
 		SkipDefaultTransaction:                   config.SkipDefaultTransaction,
 		DisableForeignKeyConstraintWhenMigrating: config.DisableForeignKeyConstraintWhenMigrating,
 		PrepareStmt:                              config.PrepareStmt,
-		Logger:                                   logger.Default.LogMode(logLevel),
+		Logger:                                   defaultQueryLogger(config.Logging),
 	}
 
 	db, err := gorm.Open(mysql.Open(config.GetDSN()), gormConfig)
@@ -93,10 +92,46 @@ func NewManager(config *Config) (*Manager, error) {
 	sqlDB.SetConnMaxLifetime(config.ConnMaxLifetime)
 	sqlDB.SetConnMaxIdleTime(config.ConnMaxIdleTime)
 
-	return &Manager{
+	manager := &Manager{
 		config: config,
 		db:     db,
-	}, nil
+	}
+
+	// Apply configured plugins once the connection is set up
+	for _, plugin := range config.Plugins {
+		if err := manager.Use(plugin); err != nil {
+			_ = sqlDB.Close()
+			return nil, err
+		}
+	}
+
+	return manager, nil
+}
+
+// NewManagerWithDB creates a database manager around an already opened GORM connection
+// (a shared *gorm.DB, another dialector, or a mock driver in tests). The connection pool
+// is left as configured by the caller and Close closes it; config only supplies plugins
+// and settings such as QueryTimeout, and may be nil
+func NewManagerWithDB(gormDB *gorm.DB, config *Config) (*Manager, error) {
+	if gormDB == nil {
+		return nil, fmt.Errorf("gorm db cannot be nil")
+	}
+	if config == nil {
+		config = &Config{}
+	}
+
+	manager := &Manager{
+		config: config,
+		db:     gormDB,
+	}
+
+	for _, plugin := range config.Plugins {
+		if err := manager.Use(plugin); err != nil {
+			return nil, err
+		}
+	}
+
+	return manager, nil
 }
 
 // NewSingletonManager returns the singleton database manager instance
@@ -155,6 +190,26 @@ func (m *Manager) Close() error {
 	return nil
 }
 
+// Use registers a GORM plugin (e.g. dbresolver for read replicas) on the underlying *gorm.DB
+// Register plugins before creating repositories, as repositories share this connection
+func (m *Manager) Use(plugin gorm.Plugin) error {
+	if plugin == nil {
+		return fmt.Errorf("plugin cannot be nil")
+	}
+	if err := m.db.Use(plugin); err != nil {
+		return fmt.Errorf("failed to register plugin %s: %w", plugin.Name(), err)
+	}
+	return nil
+}
+
+// ReadOnlyTransaction runs fn inside a read-only transaction (START TRANSACTION READ ONLY)
+// MySQL skips transaction ID assignment for read-only transactions, and proxies can route
+// them to replicas. The transaction commits if fn returns nil and rolls back otherwise;
+// writes inside it are rejected by MySQL.
+func (m *Manager) ReadOnlyTransaction(ctx context.Context, fn func(tx *gorm.DB) error) error {
+	return m.db.WithContext(ctx).Transaction(fn, &sql.TxOptions{ReadOnly: true})
+}
+
 // Config returns the manager's configuration
 func (m *Manager) Config() *Config {
 	return m.config
@@ -0,0 +1,50 @@
+package db
+
+import (
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+// recordingPlugin records the databases it was initialized with
+type recordingPlugin struct {
+	initialized []*gorm.DB
+}
+
+func (p *recordingPlugin) Name() string { return "recording" }
+
+func (p *recordingPlugin) Initialize(db *gorm.DB) error {
+	p.initialized = append(p.initialized, db)
+	return nil
+}
+
+func TestUseInitializesPlugin(t *testing.T) {
+	manager, _ := newMockManager(t, nil)
+
+	plugin := &recordingPlugin{}
+	if err := manager.Use(plugin); err != nil {
+		t.Fatalf("Use: %v", err)
+	}
+	if len(plugin.initialized) != 1 || plugin.initialized[0] != manager.DB() {
+		t.Fatalf("Initialize called with %v, want the manager's DB once", plugin.initialized)
+	}
+	if _, ok := manager.DB().Config.Plugins["recording"]; !ok {
+		t.Error("plugin not registered on the GORM config")
+	}
+
+	if err := manager.Use(&recordingPlugin{}); err == nil {
+		t.Error("registering a plugin name twice succeeded")
+	}
+	if err := manager.Use(nil); err == nil {
+		t.Error("Use(nil) succeeded")
+	}
+}
+
+func TestConfigPluginsApplied(t *testing.T) {
+	plugin := &recordingPlugin{}
+	manager, _ := newMockManager(t, &Config{Plugins: []gorm.Plugin{plugin}})
+
+	if len(plugin.initialized) != 1 || plugin.initialized[0] != manager.DB() {
+		t.Fatalf("configured plugin initialized %d times, want once", len(plugin.initialized))
+	}
+}
@@ -353,6 +353,47 @@ func (b *Builder) BuildSelect() (string, []interface{}) {
 	return query.String(), args
 }
 
+// BuildWhere builds only the WHERE conditions (without the WHERE keyword)
+// Returns an empty string when no conditions were added
+func (b *Builder) BuildWhere() (string, []interface{}) {
+	return b.buildConditionGroup(b.where)
+}
+
+// OrderByTerms returns the ORDER BY terms in the order they were added, e.g. "created_at DESC"
+func (b *Builder) OrderByTerms() []string {
+	return append([]string(nil), b.orderBy...)
+}
+
+// LimitOffset returns the LIMIT and OFFSET values (0 when not set)
+func (b *Builder) LimitOffset() (limit, offset int) {
+	return b.limit, b.offset
+}
+
+// ExtraClauses names the clauses set beyond WHERE, ORDER BY, LIMIT and OFFSET
+// (column list, DISTINCT, JOIN, GROUP BY, HAVING, subqueries); nil if none
+func (b *Builder) ExtraClauses() []string {
+	var clauses []string
+	if len(b.selectCols) != 1 || b.selectCols[0] != "*" {
+		clauses = append(clauses, "SELECT")
+	}
+	if b.distinct {
+		clauses = append(clauses, "DISTINCT")
+	}
+	if len(b.joins) > 0 {
+		clauses = append(clauses, "JOIN")
+	}
+	if len(b.groupBy) > 0 {
+		clauses = append(clauses, "GROUP BY")
+	}
+	if b.having != nil && len(b.having.Conditions) > 0 {
+		clauses = append(clauses, "HAVING")
+	}
+	if len(b.subqueries) > 0 {
+		clauses = append(clauses, "subqueries")
+	}
+	return clauses
+}
+
 // buildConditionGroup builds SQL for a condition group with proper logical operators
 func (b *Builder) buildConditionGroup(group *ConditionGroup) (string, []interface{}) {
 	if len(group.Conditions) == 0 {
@@ -32,11 +32,25 @@ type Config struct {
 	PrepareStmt                              bool          `json:"prepare_stmt" yaml:"prepare_stmt"`
 	QueryTimeout                             time.Duration `json:"query_timeout" yaml:"query_timeout"`
 
+	// MaxQueryRows caps the rows read by repository FindAll and FindWhere (0 = unlimited);
+	// larger results are cut and reported as ErrResultTruncated, so callers paginate
+	MaxQueryRows int `json:"max_query_rows" yaml:"max_query_rows"`
+
+	// Session Variables
+	// Applied with SET on every new pooled connection (e.g. sql_mode, time_zone,
+	// transaction_isolation). Values are SQL expressions, so quote strings:
+	// {"time_zone": "'+00:00'", "transaction_isolation": "'READ-COMMITTED'"}
+	SessionVariables map[string]string `json:"session_variables" yaml:"session_variables"`
+
 	// SSL Configuration
 	SSL SSLConfig `json:"ssl" yaml:"ssl"`
 
 	// Logging Configuration
 	Logging LoggingConfig `json:"logging" yaml:"logging"`
+
+	// GORM Plugins (e.g. dbresolver, opentelemetry), applied after connection setup
+	// in order; see Manager.Use for registering plugins later
+	Plugins []gorm.Plugin `json:"-" yaml:"-"`
 }
 
 // SSLConfig holds SSL/TLS configuration for MySQL
@@ -62,6 +76,13 @@ type LoggingConfig struct {
 	SlowQueryThreshold time.Duration `json:"slow_query_threshold" yaml:"slow_query_threshold"`
 	LogQueryParameters bool          `json:"log_query_parameters" yaml:"log_query_parameters"`
 
+	// Parameter Redaction (when LogQueryParameters is false)
+	// Bound values are replaced in logged SQL, including IN lists and inserted rows,
+	// except for allow-listed columns (e.g. tenant_id) and 1-based placeholder positions
+	RedactionStyle           string   `json:"redaction_style" yaml:"redaction_style"` // type (<redacted:string>, default), placeholder (?)
+	LoggedParameterColumns   []string `json:"logged_parameter_columns" yaml:"logged_parameter_columns"`
+	LoggedParameterPositions []int    `json:"logged_parameter_positions" yaml:"logged_parameter_positions"`
+
 	// Performance Logging
 	LogPerformanceMetrics bool          `json:"log_performance_metrics" yaml:"log_performance_metrics"`
 	MetricsInterval       time.Duration `json:"metrics_interval" yaml:"metrics_interval"`
@@ -0,0 +1,40 @@
+package redis
+
+import (
+	"bytes"
+	"context"
+	"testing"
+)
+
+// BenchmarkSetLarge measures storing large values: compressed into a single key, and
+// compressed then split into chunks. Run with -benchmem and compare runs on the same
+// machine; the time includes the miniredis round trips.
+func BenchmarkSetLarge(b *testing.B) {
+	value := bytes.Repeat([]byte(`{"id":1,"name":"ann","email":"ann@example.com"},`), 20000) // ~1MB
+
+	for _, bench := range []struct {
+		name      string
+		chunkSize int
+	}{
+		{name: "compressed", chunkSize: 4 << 20},
+		{name: "compressed_chunked", chunkSize: 1 << 10},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			m, _ := newTestManager(b, func(c *Config) {
+				c.LargeValue.EnableCompression = true
+				c.LargeValue.EnableChunking = true
+				c.LargeValue.ChunkSize = bench.chunkSize
+			})
+			ctx := context.Background()
+
+			b.ReportAllocs()
+			b.SetBytes(int64(len(value)))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := m.SetLarge(ctx, "sql4go:app:users:s1:find_all:all", value); err != nil {
+					b.Fatalf("SetLarge: %v", err)
+				}
+			}
+		})
+	}
+}
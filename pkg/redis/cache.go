@@ -0,0 +1,102 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// cacheKVPrefix is the key segment of Cache keys, after the manager's key prefix
+const cacheKVPrefix = "kv"
+
+// Cache is a key-value cache of JSON values over a Manager, for application data
+// without an entity (feature flags, rate-limit state, computed reports). Values are
+// JSON-encoded, independent of the manager's serialization format, and stored with the
+// large-value machinery (compression and chunking per LargeValueConfig). Keys live under
+// their own prefix, "gensql4go:kv:{namespace}:{key}" (with the manager's namespace after
+// "gensql4go"), so they never collide with entity keys and are not touched by entity or
+// table invalidation. Reads go through the L1 cache when it is enabled.
+type Cache struct {
+	manager   *Manager
+	namespace string
+}
+
+// NewCache creates a key-value cache whose keys are scoped to namespace
+func NewCache(manager *Manager, namespace string) *Cache {
+	return &Cache{manager: manager, namespace: namespace}
+}
+
+// Key returns the Redis key of a cache key
+func (c *Cache) Key(key string) string {
+	return c.manager.keyPrefix() + cacheKeySeparator + cacheKVPrefix + cacheKeySeparator + c.namespace + cacheKeySeparator + key
+}
+
+// Get reads a value into target
+// Returns ErrKeyNotFound if the key is not cached; a value that cannot be decoded into
+// target is deleted and reported as a miss too (see IsSerializationFailed).
+func (c *Cache) Get(ctx context.Context, key string, target interface{}) error {
+	data, err := c.manager.GetLarge(ctx, c.Key(key))
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(data, target); err != nil {
+		return c.manager.discardCorruptValue(ctx, c.Key(key), err)
+	}
+	return nil
+}
+
+// Set stores a value for ttl (<= 0 uses the manager's DefaultTTL)
+func (c *Cache) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	_, err := c.set(ctx, key, value, ttl)
+	return err
+}
+
+// set encodes and stores a value and returns its encoding
+func (c *Cache) set(ctx context.Context, key string, value interface{}, ttl time.Duration) ([]byte, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal value: %w", err)
+	}
+
+	if ttl <= 0 {
+		ttl = c.manager.config.DefaultTTL
+	}
+	return data, c.manager.SetLargeWithTTL(ctx, c.Key(key), data, ttl)
+}
+
+// Delete removes a value, including its compression metadata and chunks
+func (c *Cache) Delete(ctx context.Context, key string) error {
+	return c.manager.DeleteLarge(ctx, c.Key(key))
+}
+
+// Exists checks if a key is cached
+func (c *Cache) Exists(ctx context.Context, key string) (bool, error) {
+	return c.manager.Exists(ctx, c.Key(key))
+}
+
+// GetOrSet reads a value into target or, if the key is not cached, loads it, stores it
+// for ttl and decodes the loaded value into target
+// A failure to store the loaded value is not an error: target is filled either way.
+// Concurrent misses each call load; there is no stampede protection.
+func (c *Cache) GetOrSet(ctx context.Context, key string, target interface{}, ttl time.Duration, load func(ctx context.Context) (interface{}, error)) error {
+	err := c.Get(ctx, key, target)
+	if !IsKeyNotFound(err) {
+		return err
+	}
+
+	value, err := load(ctx)
+	if err != nil {
+		return err
+	}
+
+	data, err := c.set(ctx, key, value, ttl)
+	if data == nil {
+		return err // Not encodable
+	}
+	if err != nil {
+		c.manager.logger.Printf("sql4go: failed to cache %s: %v", c.Key(key), err)
+	}
+	return json.Unmarshal(data, target)
+}
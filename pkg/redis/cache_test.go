@@ -0,0 +1,99 @@
+package redis
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCacheTTL(t *testing.T) {
+	m, mr := newTestManager(t)
+	cache := NewCache(m, "flags")
+	ctx := context.Background()
+
+	if err := cache.Set(ctx, "beta", map[string]bool{"enabled": true}, time.Minute); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if err := cache.Set(ctx, "default", true, 0); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if ttl := mr.TTL(cache.Key("beta")); ttl != time.Minute {
+		t.Errorf("TTL = %v, want 1m", ttl)
+	}
+	if ttl := mr.TTL(cache.Key("default")); ttl != m.Config().DefaultTTL {
+		t.Errorf("TTL without one = %v, want DefaultTTL %v", ttl, m.Config().DefaultTTL)
+	}
+	if !strings.HasPrefix(cache.Key("beta"), "gensql4go:kv:flags:") {
+		t.Errorf("key %s is not under the key-value prefix", cache.Key("beta"))
+	}
+
+	var flags map[string]bool
+	if err := cache.Get(ctx, "beta", &flags); err != nil || !flags["enabled"] {
+		t.Fatalf("Get = (%v, %v), want the stored value", flags, err)
+	}
+
+	mr.FastForward(time.Minute + time.Second)
+	if err := cache.Get(ctx, "beta", &flags); !IsKeyNotFound(err) {
+		t.Errorf("Get after the TTL = %v, want ErrKeyNotFound", err)
+	}
+	if exists, err := cache.Exists(ctx, "beta"); err != nil || exists {
+		t.Errorf("Exists after the TTL = (%v, %v), want false", exists, err)
+	}
+}
+
+func TestCacheLargeValue(t *testing.T) {
+	m, mr := newTestManager(t, func(c *Config) {
+		c.LargeValue.EnableCompression = false
+		c.LargeValue.EnableChunking = true
+		c.LargeValue.ChunkSize = 1000
+	})
+	cache := NewCache(m, "reports")
+	ctx := context.Background()
+
+	random := make([]byte, 2000)
+	_, _ = rand.Read(random)
+	report := hex.EncodeToString(random) // 4000 bytes, stored in chunks
+
+	if err := cache.Set(ctx, "daily", report, time.Hour); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if keys := mr.Keys(); len(keys) < 2 {
+		t.Fatalf("keys %q, want the value split into chunks", keys)
+	}
+
+	var got string
+	if err := cache.Get(ctx, "daily", &got); err != nil || got != report {
+		t.Fatalf("Get = (%d bytes, %v), want the %d-byte report", len(got), err, len(report))
+	}
+
+	if err := cache.Delete(ctx, "daily"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if keys := mr.Keys(); len(keys) != 0 {
+		t.Errorf("keys after Delete = %q, want none", keys)
+	}
+}
+
+func TestCacheGetOrSetLoadsOnce(t *testing.T) {
+	m, _ := newTestManager(t)
+	cache := NewCache(m, "limits")
+	ctx := context.Background()
+
+	loads := 0
+	load := func(context.Context) (interface{}, error) {
+		loads++
+		return 42, nil
+	}
+	for i := 0; i < 2; i++ {
+		var limit int
+		if err := cache.GetOrSet(ctx, "api", &limit, time.Minute, load); err != nil || limit != 42 {
+			t.Fatalf("GetOrSet = (%d, %v), want 42", limit, err)
+		}
+	}
+	if loads != 1 {
+		t.Errorf("load called %d times, want 1", loads)
+	}
+}
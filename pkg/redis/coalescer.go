@@ -0,0 +1,85 @@
+package redis
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// invalidationCoalescer merges repeated pattern invalidations within a debounce window
+// The first invalidation of a pattern schedules a single wipe at the end of the window;
+// further invalidations of the same pattern before then are absorbed into that wipe.
+// This is independent of the batch invalidation strategy.
+type invalidationCoalescer struct {
+	manager *Manager
+	window  time.Duration
+
+	mu      sync.Mutex
+	pending map[string]*time.Timer // pattern -> scheduled wipe
+	closed  bool
+	wg      sync.WaitGroup // In-flight wipes
+}
+
+// newInvalidationCoalescer creates a coalescer for the given debounce window
+func newInvalidationCoalescer(manager *Manager, window time.Duration) *invalidationCoalescer {
+	return &invalidationCoalescer{
+		manager: manager,
+		window:  window,
+		pending: make(map[string]*time.Timer),
+	}
+}
+
+// schedule registers an invalidation of pattern, merging it with a pending one if present
+// Returns false if the coalescer is closed and the caller should invalidate directly
+func (c *invalidationCoalescer) schedule(pattern string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return false
+	}
+
+	if _, exists := c.pending[pattern]; exists {
+		c.manager.metrics.RecordCoalescedInvalidation()
+		return true
+	}
+
+	c.wg.Add(1)
+	c.pending[pattern] = time.AfterFunc(c.window, func() {
+		defer c.wg.Done()
+		c.flush(pattern)
+	})
+	return true
+}
+
+// flush executes the pending wipe for pattern
+func (c *invalidationCoalescer) flush(pattern string) {
+	c.mu.Lock()
+	delete(c.pending, pattern)
+	c.mu.Unlock()
+
+	// The triggering request may be long gone - use a background context (ignore errors - best effort)
+	_ = c.manager.InvalidatePattern(context.Background(), pattern)
+}
+
+// close stops accepting invalidations and executes all pending wipes immediately
+func (c *invalidationCoalescer) close() {
+	c.mu.Lock()
+	c.closed = true
+	var stopped []string
+	for pattern, timer := range c.pending {
+		if timer.Stop() {
+			stopped = append(stopped, pattern)
+		}
+	}
+	c.mu.Unlock()
+
+	// Run the wipes whose timers never fired
+	for _, pattern := range stopped {
+		c.flush(pattern)
+		c.wg.Done()
+	}
+
+	// Wait for wipes already running in timer goroutines
+	c.wg.Wait()
+}
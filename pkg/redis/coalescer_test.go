@@ -0,0 +1,87 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestCoalescerMergesInvalidationsWithinWindow(t *testing.T) {
+	m, mr := newTestManager(t, func(c *Config) { c.Invalidation.DebounceWindow = 50 * time.Millisecond })
+	ctx := context.Background()
+
+	mr.Set("users:1", "x")
+	mr.Set("users:2", "x")
+	mr.Set("orders:1", "x")
+	for i := 0; i < 5; i++ {
+		if err := m.InvalidatePatternCoalesced(ctx, "users:*"); err != nil {
+			t.Fatalf("InvalidatePatternCoalesced: %v", err)
+		}
+	}
+
+	// The wipe waits for the end of the window
+	if !mr.Exists("users:1") {
+		t.Fatal("users:1 was wiped before the end of the debounce window")
+	}
+	if metrics := m.GetMetrics(); metrics.CoalescedInvalidations != 4 || metrics.PatternInvalidations != 0 {
+		t.Errorf("within the window: coalesced %d, executed %d; want 4 and 0", metrics.CoalescedInvalidations, metrics.PatternInvalidations)
+	}
+
+	if !waitFor(t, time.Second, func() bool { return !mr.Exists("users:1") && !mr.Exists("users:2") }) {
+		t.Fatalf("keys %q remain after the debounce window", mr.Keys())
+	}
+	if !mr.Exists("orders:1") {
+		t.Error("orders:1 was wiped by the users:* pattern")
+	}
+
+	// A single wipe ran: a key written after it survives another window
+	mr.Set("users:3", "x")
+	time.Sleep(100 * time.Millisecond)
+	if !mr.Exists("users:3") {
+		t.Error("users:3 was wiped by a second, uncoalesced wipe")
+	}
+	if metrics := m.GetMetrics(); metrics.CoalescedInvalidations != 4 || metrics.PatternInvalidations != 1 {
+		t.Errorf("after the window: coalesced %d, executed %d; want 4 and 1", metrics.CoalescedInvalidations, metrics.PatternInvalidations)
+	}
+}
+
+func TestCoalescerKeepsPatternsApart(t *testing.T) {
+	m, mr := newTestManager(t, func(c *Config) { c.Invalidation.DebounceWindow = 20 * time.Millisecond })
+	ctx := context.Background()
+
+	mr.Set("users:1", "x")
+	mr.Set("orders:1", "x")
+	_ = m.InvalidatePatternCoalesced(ctx, "users:*")
+	_ = m.InvalidatePatternCoalesced(ctx, "orders:*")
+
+	if !waitFor(t, time.Second, func() bool { return len(mr.Keys()) == 0 }) {
+		t.Fatalf("keys %q remain after the debounce window", mr.Keys())
+	}
+	if metrics := m.GetMetrics(); metrics.CoalescedInvalidations != 0 || metrics.PatternInvalidations != 2 {
+		t.Errorf("coalesced %d, executed %d; want 0 and 2", metrics.CoalescedInvalidations, metrics.PatternInvalidations)
+	}
+}
+
+func TestCloseFlushesPendingInvalidations(t *testing.T) {
+	m, mr := newTestManager(t, func(c *Config) { c.Invalidation.DebounceWindow = time.Hour })
+	ctx := context.Background()
+
+	mr.Set("users:1", "x")
+	mr.Set("orders:1", "x")
+	_ = m.InvalidatePatternCoalesced(ctx, "users:*")
+	_ = m.InvalidatePatternCoalesced(ctx, "users:*")
+	_ = m.InvalidatePatternCoalesced(ctx, "orders:*")
+	if len(mr.Keys()) != 2 {
+		t.Fatalf("keys %q, want both pending until the window ends", mr.Keys())
+	}
+
+	if err := m.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if keys := mr.Keys(); len(keys) != 0 {
+		t.Errorf("keys after Close = %q, want the pending wipes executed", keys)
+	}
+	if executed := m.GetMetrics().PatternInvalidations; executed != 2 {
+		t.Errorf("PatternInvalidations = %d, want 2", executed)
+	}
+}
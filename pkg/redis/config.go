@@ -1,7 +1,11 @@
 package redis
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
+	"slices"
+	"strings"
 	"time"
 )
 
@@ -9,15 +13,37 @@ import (
 type Config struct {
 	// Cache Strategy
 	Enabled      bool          `json:"enabled" yaml:"enabled"`
-	Strategy     CacheStrategy `json:"strategy" yaml:"strategy"` // read_through, write_through, write_behind
+	Strategy     CacheStrategy `json:"strategy" yaml:"strategy"` // read_through, write_through, write_behind, lazy_loading
 	DefaultTTL   time.Duration `json:"default_ttl" yaml:"default_ttl"`
 	NullCacheTTL time.Duration `json:"null_cache_ttl" yaml:"null_cache_ttl"` // Cache null results
 
+	// OperationTTLs overrides DefaultTTL per repository operation
+	// Keys: find_by_id, find_all, find_where, find_where_in, find_by_unique, first, take, last,
+	// count, count_distinct
+	OperationTTLs map[string]time.Duration `json:"operation_ttls" yaml:"operation_ttls"`
+
+	// DisabledOperations lists repository operations (same keys as OperationTTLs) that
+	// bypass the cache entirely: no reads, writes or keys. Repositories can override it
+	// per operation (see repository.WithOperationCache).
+	DisabledOperations []string `json:"disabled_operations" yaml:"disabled_operations"`
+
+	// DisableNullCaching stops caching empty and not-found results (FindByID/First not
+	// found, empty FindAll/FindWhere). By default they are cached as null markers for
+	// NullCacheTTL; when set, every such read queries the database.
+	DisableNullCaching bool `json:"disable_null_caching" yaml:"disable_null_caching"`
+
+	// Backend selects where values are stored: redis (default) or memory, a map in the
+	// process for development without Redis. Only repository.NewCache reads it, creating
+	// a *Manager or a repository.MemoryCache; NewManager rejects memory. The connection
+	// and cluster settings are ignored by the memory backend.
+	Backend CacheBackend `json:"backend" yaml:"backend"`
+
 	// Redis Connection
 	Host     string `json:"host" yaml:"host"`
 	Port     int    `json:"port" yaml:"port"`
+	Username string `json:"username" yaml:"username"` // Redis 6+ ACL user (empty = default user)
 	Password string `json:"password" yaml:"password"`
-	Database int    `json:"database" yaml:"database"`
+	Database int    `json:"database" yaml:"database"` // Logical DB index; must be 0 in cluster mode
 
 	// Connection Pool
 	PoolSize           int           `json:"pool_size" yaml:"pool_size"`
@@ -35,14 +61,56 @@ type Config struct {
 	// Clustering (for Redis Cluster)
 	Cluster ClusterConfig `json:"cluster" yaml:"cluster"`
 
+	// Cache Keys
+	// Keys longer than this have their variable suffix (id, query hash, ...) replaced
+	// by a deterministic hash, keeping keys bounded regardless of input. 0 disables the limit.
+	MaxKeyLength int `json:"max_key_length" yaml:"max_key_length"`
+
+	// Namespace is inserted after the key prefix of every sql4go key ("sql4go:{ns}:app:users:..."),
+	// isolating managers that share a Redis instance, e.g. parallel test processes
+	// (see RandomNamespace). Empty by default, leaving the key format unchanged.
+	Namespace string `json:"namespace" yaml:"namespace"`
+
+	// AllowFlushNamespace enables Manager.FlushNamespace, which deletes every key of the
+	// namespace. Off by default; keep it off in production.
+	AllowFlushNamespace bool `json:"allow_flush_namespace" yaml:"allow_flush_namespace"`
+
+	// AllowReservedKeys accepts keys containing the internal marker "_internal:", which
+	// are rejected with ErrInvalidKey by default as they can collide with the metadata
+	// and chunk keys of large values
+	AllowReservedKeys bool `json:"allow_reserved_keys" yaml:"allow_reserved_keys"`
+
 	// Cache Invalidation
 	Invalidation InvalidationConfig `json:"invalidation" yaml:"invalidation"`
 
+	// Distributed Regeneration Lock
+	RegenerationLock RegenerationLockConfig `json:"regeneration_lock" yaml:"regeneration_lock"`
+
+	// Write-Behind Queue (Strategy write_behind)
+	WriteBehind WriteBehindConfig `json:"write_behind" yaml:"write_behind"`
+
 	// Cache Warming
 	WarmUp WarmUpConfig `json:"warm_up" yaml:"warm_up"`
 
 	// Cache Metrics
 	EnableMetrics bool `json:"enable_metrics" yaml:"enable_metrics"`
+	// Per-table metrics track at most this many tables; the others are counted under
+	// "other" (default 100)
+	MaxMetricLabels int `json:"max_metric_labels" yaml:"max_metric_labels"`
+
+	// maxmemory Evictions (see Manager.CheckEvictions)
+	// Poll the server's evicted_keys counter at this interval, recording its growth in
+	// the ServerEvictions metric and logging a warning while it rises. 0 disables polling.
+	EvictionCheckInterval time.Duration `json:"eviction_check_interval" yaml:"eviction_check_interval"`
+
+	// Deferred Population
+	DeferredPopulation DeferredPopulationConfig `json:"deferred_population" yaml:"deferred_population"`
+
+	// Hot Key Tracking (see Manager.TopKeys)
+	HotKeys HotKeyConfig `json:"hot_keys" yaml:"hot_keys"`
+
+	// In-Process L1 Cache
+	LocalCache LocalCacheConfig `json:"local_cache" yaml:"local_cache"`
 
 	// Serialization Format
 	// msgpack: 5-10x faster than JSON, smaller payload (recommended for production)
@@ -62,6 +130,13 @@ type ClusterConfig struct {
 	Addresses []string `json:"addresses" yaml:"addresses"`
 	Username  string   `json:"username" yaml:"username"`
 	Password  string   `json:"password" yaml:"password"`
+
+	// HashTags wraps the {db:table} portion of all sql4go keys in a hash tag so a
+	// table's cache keys and its dependency sets land on the same slot. Keys depending
+	// on entities of other tables are tracked in sets under their own table's tag.
+	// This changes the key format and uses a versioned namespace ("v2"), so keys
+	// written with and without hash tags never mix. Existing keys expire via TTL.
+	HashTags bool `json:"hash_tags" yaml:"hash_tags"`
 }
 
 // InvalidationConfig controls relationship-aware cache invalidation
@@ -76,8 +151,65 @@ type InvalidationConfig struct {
 	BatchSize          int                  `json:"batch_size" yaml:"batch_size"`
 	BatchFlushInterval time.Duration        `json:"batch_flush_interval" yaml:"batch_flush_interval"`
 
+	// Debounced Invalidation
+	// Table-wide invalidations within this window are merged into one pattern wipe
+	// at the window's end (e.g. 200ms for bulk imports). 0 disables coalescing.
+	DebounceWindow time.Duration `json:"debounce_window" yaml:"debounce_window"`
+
+	// Invalidation Deadline
+	// Invalidation after a write runs detached from the write's context, so a request
+	// timeout firing mid-invalidation cannot abandon it, and is bounded by Timeout
+	// (default 5s). Invalidations that fail or time out are retried by a background
+	// worker of the repository (or of the invalidation callbacks), in the order they
+	// fall due: up to RetryQueueSize waiting (default 1024), at most
+	// RetryAttempts times (default 5), the first after RetryInterval (default 100ms),
+	// doubled after each failure.
+	Timeout        time.Duration `json:"timeout" yaml:"timeout"`
+	RetryQueueSize int           `json:"retry_queue_size" yaml:"retry_queue_size"`
+	RetryAttempts  int           `json:"retry_attempts" yaml:"retry_attempts"`
+	RetryInterval  time.Duration `json:"retry_interval" yaml:"retry_interval"`
+
+	// Dependency Set Limits
+	// Above this many cache keys, an entity's dependency set stops growing and is
+	// invalidated by pattern instead (checked periodically via SCARD). 0 = unlimited.
+	MaxDependencySetSize int `json:"max_dependency_set_size" yaml:"max_dependency_set_size"`
+	// Above this many entities of one type, a cache key depends on the type's table-level
+	// dependency set instead of one set per entity.
+	// 0 = unlimited.
+	MaxDependenciesPerKey int `json:"max_dependencies_per_key" yaml:"max_dependencies_per_key"`
+
+	// Missing Dependency Sets
+	// Invalidating an entity without a dependency set (never cached, or the set was
+	// evicted under maxmemory) wipes the entity's FindByID keys by pattern, a SCAN per
+	// invalidation. Enable while the server evicts keys (see Manager.CheckEvictions).
+	FallbackOnMissingDependencies bool `json:"fallback_on_missing_dependencies" yaml:"fallback_on_missing_dependencies"`
+
 	// Pattern-based Invalidation
-	KeyPatterns map[string][]string `json:"key_patterns" yaml:"key_patterns"` // entity -> patterns to invalidate
+	// Table -> glob patterns wiped when an entity of the table is invalidated, with
+	// {prefix}, {db}, {table} and {id} placeholders (see Manager.RegisterInvalidationPattern)
+	KeyPatterns map[string][]string `json:"key_patterns" yaml:"key_patterns"`
+}
+
+// RegenerationLockConfig controls the distributed lock protecting expensive cache regeneration
+// On a FindAll/FindWhere miss, one process (the lock winner) queries the database and
+// populates the cache; the others poll the cache until WaitTimeout and then fall through
+// to the database themselves. Waiters never block longer than WaitTimeout, even if the
+// winner crashes; LockTTL only bounds how long a crashed winner keeps others from winning.
+// LockTTL should exceed the regeneration query duration.
+type RegenerationLockConfig struct {
+	Enabled      bool          `json:"enabled" yaml:"enabled"`
+	LockTTL      time.Duration `json:"lock_ttl" yaml:"lock_ttl"`           // Lock expiry (SET NX PX)
+	WaitTimeout  time.Duration `json:"wait_timeout" yaml:"wait_timeout"`   // Max time losers poll the cache
+	PollInterval time.Duration `json:"poll_interval" yaml:"poll_interval"` // Delay between cache polls
+}
+
+// WriteBehindConfig controls the write-behind queue used when Strategy is write_behind
+// Queued writes are held in process memory and lost if the process crashes before they
+// are flushed; see repository.GenericRepository.Flush and Close.
+type WriteBehindConfig struct {
+	QueueSize     int           `json:"queue_size" yaml:"queue_size"`         // Max queued writes per repository; full queues write synchronously
+	BatchSize     int           `json:"batch_size" yaml:"batch_size"`         // Max writes per database statement
+	FlushInterval time.Duration `json:"flush_interval" yaml:"flush_interval"` // Max time a write waits in the queue
 }
 
 // WarmUpConfig controls cache warming strategies
@@ -93,6 +225,33 @@ type WarmUpConfig struct {
 	Entities []string `json:"entities" yaml:"entities"`
 }
 
+// HotKeyConfig controls sampled tracking of the most-read cache keys
+// Disabled tracking adds no overhead to reads
+type HotKeyConfig struct {
+	Enabled        bool `json:"enabled" yaml:"enabled"`
+	SampleRate     int  `json:"sample_rate" yaml:"sample_rate"`         // Track 1 in SampleRate reads
+	Capacity       int  `json:"capacity" yaml:"capacity"`               // Number of prefixes tracked
+	PrefixSegments int  `json:"prefix_segments" yaml:"prefix_segments"` // Key segments kept (through the operation)
+}
+
+// LocalCacheConfig controls the optional in-process L1 cache in front of Redis
+// Entries can be served for up to TTL after being overwritten in Redis, so keep it short
+type LocalCacheConfig struct {
+	Enabled    bool          `json:"enabled" yaml:"enabled"`
+	MaxEntries int           `json:"max_entries" yaml:"max_entries"` // LRU size bound
+	TTL        time.Duration `json:"ttl" yaml:"ttl"`                 // Lifetime of an entry
+}
+
+// DeferredPopulationConfig controls retrying cache stores that failed transiently
+// (timeouts, connection errors, a failover in progress); values are held in process
+// memory and lost on Close
+type DeferredPopulationConfig struct {
+	Enabled       bool          `json:"enabled" yaml:"enabled"`
+	MaxAttempts   int           `json:"max_attempts" yaml:"max_attempts"`     // Retries of a value before it is dropped
+	RetryInterval time.Duration `json:"retry_interval" yaml:"retry_interval"` // Delay of the first retry, doubled after each failure
+	MaxBytes      int           `json:"max_bytes" yaml:"max_bytes"`           // Serialized bytes held; values that do not fit are dropped
+}
+
 // LoggingConfig controls Redis cache logging behavior
 type LoggingConfig struct {
 	LogCacheHits     bool `json:"log_cache_hits" yaml:"log_cache_hits"`
@@ -102,21 +261,52 @@ type LoggingConfig struct {
 
 // LargeValueConfig controls handling of large cache values
 type LargeValueConfig struct {
-	MaxValueSize      int  `json:"max_value_size" yaml:"max_value_size"`         // Maximum size per key (bytes)
+	MaxValueSize      int  `json:"max_value_size" yaml:"max_value_size"`         // Maximum value size (bytes); after compression if enabled
 	ChunkSize         int  `json:"chunk_size" yaml:"chunk_size"`                 // Size per chunk (bytes)
 	CompressThreshold int  `json:"compress_threshold" yaml:"compress_threshold"` // Auto-compress above this size
 	EnableCompression bool `json:"enable_compression" yaml:"enable_compression"` // Enable/disable compression
 	EnableChunking    bool `json:"enable_chunking" yaml:"enable_chunking"`       // Enable/disable chunking
+
+	// BalanceChunks splits a chunked value into chunks of (almost) equal size instead of
+	// ChunkSize-sized chunks plus a ragged remainder. The chunk count stays the same, so
+	// no chunk exceeds ChunkSize: a 2.1MB value with 2MB chunks becomes 2 x 1.05MB
+	// rather than 2MB + 0.1MB.
+	BalanceChunks bool `json:"balance_chunks" yaml:"balance_chunks"`
+
+	// SkipCachingAbove skips caching query results (FindWhere, FindWhereIn) whose encoded
+	// size exceeds it (bytes, 0 = no limit). Unlike MaxValueSize, which rejects a value
+	// as an error, a skip is expected: the result is served uncached and counted as
+	// OversizeSkips, so an unexpectedly large result never becomes a chunked value.
+	SkipCachingAbove int `json:"skip_caching_above" yaml:"skip_caching_above"`
+
+	// Dictionary compresses small values (below CompressThreshold) with a shared
+	// dictionary trained from cached values (see Manager.TrainDictionary)
+	Dictionary DictionaryConfig `json:"dictionary" yaml:"dictionary"`
+}
+
+// DictionaryConfig controls dictionary compression of small values (see Manager.TrainDictionary)
+type DictionaryConfig struct {
+	Enabled bool `json:"enabled" yaml:"enabled"`
+	MinSize int  `json:"min_size" yaml:"min_size"` // Smallest value compressed (bytes, default 256)
+	MaxSize int  `json:"max_size" yaml:"max_size"` // Size of trained dictionaries (bytes, default and maximum 32KB)
 }
 
 // Cache strategy enums
 type CacheStrategy string
 
 const (
-	CacheStrategyReadThrough  CacheStrategy = "read_through"
-	CacheStrategyWriteThrough CacheStrategy = "write_through"
-	CacheStrategyWriteBehind  CacheStrategy = "write_behind"
-	CacheStrategyLazyLoading  CacheStrategy = "lazy_loading"
+	CacheStrategyReadThrough  CacheStrategy = "read_through"  // Cache on read miss, invalidate on write
+	CacheStrategyWriteThrough CacheStrategy = "write_through" // Also cache written entities under their FindByID key
+	CacheStrategyWriteBehind  CacheStrategy = "write_behind"  // Cache on write, write to the database asynchronously
+	CacheStrategyLazyLoading  CacheStrategy = "lazy_loading"  // Cache-aside: only cache on first read, WarmCache is a no-op
+)
+
+// Cache backend enums
+type CacheBackend string
+
+const (
+	BackendRedis  CacheBackend = "redis"  // A Redis server or cluster (default)
+	BackendMemory CacheBackend = "memory" // In-process map (repository.MemoryCache), for development and tests
 )
 
 // Invalidation strategy enums
@@ -158,18 +348,52 @@ func DefaultConfig() *Config {
 		Cluster: ClusterConfig{
 			Enabled: false,
 		},
+		MaxKeyLength: 256,
+		RegenerationLock: RegenerationLockConfig{
+			Enabled:      false,
+			LockTTL:      time.Second * 30,
+			WaitTimeout:  time.Second * 5,
+			PollInterval: time.Millisecond * 50,
+		},
+		WriteBehind: WriteBehindConfig{
+			QueueSize:     10000,
+			BatchSize:     100,
+			FlushInterval: time.Second,
+		},
 		Invalidation: InvalidationConfig{
 			AutoDetectRelationships: true,
 			MaxRelationshipDepth:    3,
 			Strategy:                InvalidationImmediate,
 			BatchSize:               100,
 			BatchFlushInterval:      time.Millisecond * 100,
+			MaxDependenciesPerKey:   1000,
+			Timeout:                 5 * time.Second,
+			RetryQueueSize:          1024,
+			RetryAttempts:           5,
+			RetryInterval:           100 * time.Millisecond,
 		},
 		WarmUp: WarmUpConfig{
 			Enabled:       false,
 			WarmUpTimeout: time.Minute * 5,
 		},
-		EnableMetrics:       true,
+		EnableMetrics: true,
+		HotKeys: HotKeyConfig{
+			Enabled:        false,
+			SampleRate:     100,
+			Capacity:       64,
+			PrefixSegments: 5, // sql4go:{db}:{table}:{schema}:{operation}
+		},
+		LocalCache: LocalCacheConfig{
+			Enabled:    false,
+			MaxEntries: 10000,
+			TTL:        time.Second,
+		},
+		DeferredPopulation: DeferredPopulationConfig{
+			Enabled:       false,
+			MaxAttempts:   3,
+			RetryInterval: time.Second,
+			MaxBytes:      32 * 1024 * 1024,
+		},
 		SerializationFormat: SerializationMsgPack, // Default to MessagePack for best performance
 		Logging: LoggingConfig{
 			LogCacheHits:     false,
@@ -182,6 +406,7 @@ func DefaultConfig() *Config {
 			CompressThreshold: 1024 * 100,       // Compress values larger than 100KB
 			EnableCompression: true,
 			EnableChunking:    true,
+			BalanceChunks:     true,
 		},
 	}
 }
@@ -192,11 +417,23 @@ func (c *Config) Validate() error {
 		return nil // Skip validation if cache is disabled
 	}
 
-	if c.Host == "" {
-		return fmt.Errorf("redis host is required when cache is enabled")
+	switch c.Backend {
+	case "", BackendRedis:
+		if c.Host == "" {
+			return fmt.Errorf("redis host is required when cache is enabled")
+		}
+		if c.Port <= 0 {
+			return fmt.Errorf("redis port must be positive")
+		}
+	case BackendMemory:
+		if c.IsClusterMode() {
+			return fmt.Errorf("the memory backend does not support cluster mode")
+		}
+	default:
+		return fmt.Errorf("unknown cache backend %q (redis or memory)", c.Backend)
 	}
-	if c.Port <= 0 {
-		return fmt.Errorf("redis port must be positive")
+	if c.IsClusterMode() && c.Database != 0 {
+		return fmt.Errorf("database must be 0 in cluster mode (Redis Cluster does not support SELECT)")
 	}
 	if c.DefaultTTL <= 0 {
 		return fmt.Errorf("default_ttl must be positive when cache is enabled")
@@ -204,6 +441,67 @@ func (c *Config) Validate() error {
 	if c.Invalidation.MaxRelationshipDepth < 1 {
 		return fmt.Errorf("max_relationship_depth must be at least 1")
 	}
+	for operation, ttl := range c.OperationTTLs {
+		if ttl <= 0 {
+			return fmt.Errorf("operation_ttls[%s] must be positive", operation)
+		}
+	}
+	if c.MaxKeyLength < 0 {
+		return fmt.Errorf("max_key_length cannot be negative")
+	}
+	if strings.ContainsAny(c.Namespace, ":{}*?[] ") {
+		return fmt.Errorf("namespace cannot contain separators, hash tag braces, glob characters or spaces")
+	}
+	if c.MaxMetricLabels < 0 {
+		return fmt.Errorf("max_metric_labels cannot be negative")
+	}
+	if c.Invalidation.MaxDependencySetSize < 0 {
+		return fmt.Errorf("max_dependency_set_size cannot be negative")
+	}
+	if c.EvictionCheckInterval < 0 {
+		return fmt.Errorf("eviction_check_interval cannot be negative")
+	}
+	if c.Invalidation.DebounceWindow < 0 {
+		return fmt.Errorf("debounce_window cannot be negative")
+	}
+	if c.Invalidation.Timeout < 0 || c.Invalidation.RetryQueueSize < 0 || c.Invalidation.RetryAttempts < 0 || c.Invalidation.RetryInterval < 0 {
+		return fmt.Errorf("invalidation timeout, retry_queue_size, retry_attempts and retry_interval cannot be negative")
+	}
+	for entityType, patterns := range c.Invalidation.KeyPatterns {
+		for _, pattern := range patterns {
+			if err := validateInvalidationPattern(pattern); err != nil {
+				return fmt.Errorf("key_patterns[%s]: %w", entityType, err)
+			}
+		}
+	}
+	if c.RegenerationLock.Enabled {
+		if c.RegenerationLock.LockTTL <= 0 {
+			return fmt.Errorf("regeneration_lock.lock_ttl must be positive")
+		}
+		if c.RegenerationLock.WaitTimeout <= 0 || c.RegenerationLock.PollInterval <= 0 {
+			return fmt.Errorf("regeneration_lock wait_timeout and poll_interval must be positive")
+		}
+	}
+	if c.Strategy == CacheStrategyWriteBehind {
+		if c.WriteBehind.QueueSize < 1 || c.WriteBehind.BatchSize < 1 {
+			return fmt.Errorf("write_behind queue_size and batch_size must be at least 1")
+		}
+		if c.WriteBehind.FlushInterval <= 0 {
+			return fmt.Errorf("write_behind flush_interval must be positive")
+		}
+	}
+	if c.LargeValue.SkipCachingAbove < 0 {
+		return fmt.Errorf("large_value.skip_caching_above cannot be negative")
+	}
+	if c.LargeValue.Dictionary.MinSize < 0 || c.LargeValue.Dictionary.MaxSize < 0 || c.LargeValue.Dictionary.MaxSize > maxDictionarySize {
+		return fmt.Errorf("large_value.dictionary min_size and max_size cannot be negative, max_size is at most %d", maxDictionarySize)
+	}
+	if c.LocalCache.Enabled && (c.LocalCache.MaxEntries < 1 || c.LocalCache.TTL <= 0) {
+		return fmt.Errorf("local_cache max_entries and ttl must be positive")
+	}
+	if c.DeferredPopulation.Enabled && (c.DeferredPopulation.MaxAttempts < 1 || c.DeferredPopulation.RetryInterval <= 0 || c.DeferredPopulation.MaxBytes < 1) {
+		return fmt.Errorf("deferred_population max_attempts, retry_interval and max_bytes must be positive")
+	}
 	if c.PoolSize < 1 {
 		return fmt.Errorf("pool_size must be at least 1")
 	}
@@ -211,6 +509,39 @@ func (c *Config) Validate() error {
 	return nil
 }
 
+// RandomNamespace returns a unique namespace such as "test-3f9a1c2e7b4d", for isolating
+// cache keys of parallel test runs sharing one Redis instance
+func RandomNamespace(prefix string) string {
+	b := make([]byte, 6)
+	_, _ = rand.Read(b) // crypto/rand.Read never fails on supported platforms
+	return prefix + "-" + hex.EncodeToString(b)
+}
+
+// OperationEnabled reports whether a repository operation uses the cache
+func (c *Config) OperationEnabled(operation string) bool {
+	return !slices.Contains(c.DisabledOperations, operation)
+}
+
+// TTLFor returns the cache TTL of a repository operation, falling back to DefaultTTL
+func (c *Config) TTLFor(operation string) time.Duration {
+	if ttl, ok := c.OperationTTLs[operation]; ok && ttl > 0 {
+		return ttl
+	}
+	return c.DefaultTTL
+}
+
+// dependencyTTL returns the TTL of dependency sets
+// Sets outlive every cached value (twice the longest TTL) so no value is left untracked
+func (c *Config) dependencyTTL() time.Duration {
+	longest := c.DefaultTTL
+	for _, ttl := range c.OperationTTLs {
+		if ttl > longest {
+			longest = ttl
+		}
+	}
+	return longest * 2
+}
+
 // GetAddr returns the Redis connection address
 func (c *Config) GetAddr() string {
 	return fmt.Sprintf("%s:%d", c.Host, c.Port)
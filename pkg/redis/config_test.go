@@ -0,0 +1,42 @@
+package redis
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateRejectsDatabaseInClusterMode(t *testing.T) {
+	cluster := func(c *Config) {
+		c.Cluster.Enabled = true
+		c.Cluster.Addresses = []string{"redis-1:6379", "redis-2:6379"}
+	}
+	tests := []struct {
+		name      string
+		configure func(*Config)
+		database  int
+		wantErr   bool
+	}{
+		{name: "cluster with database 0", configure: cluster, database: 0},
+		{name: "cluster with database 2", configure: cluster, database: 2, wantErr: true},
+		{name: "single instance with database 2", configure: func(*Config) {}, database: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultConfig()
+			tt.configure(config)
+			config.Database = tt.database
+
+			err := config.Validate()
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatalf("Validate: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), "cluster mode") {
+				t.Fatalf("Validate error = %v, want the cluster mode database error", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,275 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// deferredEntry is a cache store waiting to be retried
+type deferredEntry struct {
+	data         []byte
+	dependencies map[string][]interface{}
+	ttl          time.Duration
+	large        bool // Stored with SetLarge (compression, chunking)
+	attempts     int  // Retries so far
+	due          time.Time
+}
+
+// deferredPopulation holds failed cache stores and retries them in the background
+//
+// A cache store that fails transiently - a timeout or dropped connection during a
+// failover, a replica that is still read-only - leaves the entry a miss until the next
+// read populates it, which for a hot FindAll means every read hits the database for as
+// long as the store keeps failing. With deferred population the serialized value is kept
+// and a background worker retries the store with exponential backoff:
+//   - Values are held in process memory up to MaxBytes; values that do not fit, and values
+//     still failing after MaxAttempts retries, are dropped (DeferredPopulationDrops)
+//   - A value is never written over fresher data: it is discarded when its key is stored
+//     again, deleted, matched by a pattern invalidation or an invalidation of one of its
+//     dependencies in this process (or, with the L1 cache, in another process), and a
+//     retry is skipped if the key exists by then
+//   - Invalidations by other processes are otherwise not seen; a retry can then restore a
+//     value read before such a write, for at most the retry window
+//     (RetryInterval * (2^MaxAttempts - 1))
+//
+// Callers still see the original error (e.g. cacheStored == false in repositories).
+type deferredPopulation struct {
+	manager *Manager
+	config  DeferredPopulationConfig
+
+	mu      sync.Mutex
+	entries map[string]*deferredEntry // Cache key -> latest failed store
+	bytes   int                       // Serialized bytes held
+
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+// newDeferredPopulation starts the retry worker
+func newDeferredPopulation(manager *Manager, config DeferredPopulationConfig) *deferredPopulation {
+	d := &deferredPopulation{
+		manager: manager,
+		config:  config,
+		entries: make(map[string]*deferredEntry),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go d.run()
+	return d
+}
+
+// deferPopulation records the outcome of a cache store of encoded data: a successful
+// store supersedes a pending retry of the key, a transient failure queues one
+func (m *Manager) deferPopulation(ctx context.Context, key string, data []byte, dependencies map[string][]interface{}, ttl time.Duration, large bool, err error) {
+	if m.deferred == nil {
+		return
+	}
+	if err == nil {
+		m.deferred.discardKeys(key)
+		return
+	}
+	if isTransientWriteError(ctx, err) {
+		m.deferred.add(key, &deferredEntry{data: data, dependencies: dependencies, ttl: ttl, large: large})
+	}
+}
+
+// isTransientWriteError reports whether a failed write may succeed when retried:
+// timeouts, connection errors and the errors of a failover in progress
+func isTransientWriteError(ctx context.Context, err error) bool {
+	err = classifyError(ctx, err)
+	if IsCanceled(err) {
+		return false
+	}
+
+	var netErr net.Error
+	switch {
+	case IsTimeout(err), errors.As(err, &netErr), errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return true
+	}
+	for _, prefix := range []string{"READONLY", "LOADING", "MASTERDOWN", "CLUSTERDOWN", "TRYAGAIN"} {
+		if redis.HasErrorPrefix(err, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// add queues a store, replacing a pending one of the same key
+func (d *deferredPopulation) add(key string, entry *deferredEntry) {
+	entry.due = time.Now().Add(d.config.RetryInterval)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if previous, ok := d.entries[key]; ok {
+		d.bytes -= len(previous.data)
+		delete(d.entries, key)
+	}
+	if d.bytes+len(entry.data) > d.config.MaxBytes {
+		d.manager.metrics.RecordDeferredPopulationDrop()
+		return
+	}
+	d.entries[key] = entry
+	d.bytes += len(entry.data)
+	d.manager.metrics.RecordDeferredPopulation()
+}
+
+// remove drops a pending store if it is still entry; the caller holds mu
+func (d *deferredPopulation) remove(key string, entry *deferredEntry) bool {
+	if d.entries[key] != entry {
+		return false
+	}
+	delete(d.entries, key)
+	d.bytes -= len(entry.data)
+	return true
+}
+
+// discardKeys drops the pending stores of keys (stored again or deleted)
+func (d *deferredPopulation) discardKeys(keys ...string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, key := range keys {
+		if entry, ok := d.entries[key]; ok {
+			d.remove(key, entry)
+		}
+	}
+}
+
+// discardPattern drops the pending stores of keys matching a pattern invalidation
+// Patterns other than a plain prefix followed by "*" drop every pending store.
+func (d *deferredPopulation) discardPattern(pattern string) {
+	prefix, isPrefix := strings.CutSuffix(pattern, "*")
+	if strings.ContainsAny(prefix, `*?[]\`) {
+		isPrefix = false
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for key, entry := range d.entries {
+		if !isPrefix || strings.HasPrefix(key, prefix) {
+			d.remove(key, entry)
+		}
+	}
+}
+
+// discardDependency drops the pending stores depending on an invalidated entity
+func (d *deferredPopulation) discardDependency(entityType string, entityID interface{}) {
+	id := fmt.Sprintf("%v", entityID)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for key, entry := range d.entries {
+		for _, dependencyID := range entry.dependencies[entityType] {
+			if fmt.Sprintf("%v", dependencyID) == id {
+				d.remove(key, entry)
+				break
+			}
+		}
+	}
+}
+
+// run retries due stores until close
+func (d *deferredPopulation) run() {
+	defer close(d.done)
+	ticker := time.NewTicker(d.config.RetryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.stop:
+			return
+		case now := <-ticker.C:
+			d.mu.Lock()
+			due := make(map[string]*deferredEntry)
+			for key, entry := range d.entries {
+				if !entry.due.After(now) {
+					due[key] = entry
+				}
+			}
+			d.mu.Unlock()
+
+			for key, entry := range due {
+				select {
+				case <-d.stop:
+					return
+				default:
+				}
+				d.retry(key, entry)
+			}
+		}
+	}
+}
+
+// retry stores a pending value unless fresher data exists, rescheduling it on failure
+func (d *deferredPopulation) retry(key string, entry *deferredEntry) {
+	m := d.manager
+	ctx := context.Background()
+
+	// A key written meanwhile holds fresher data than the queued value
+	exists, err := m.client.Exists(ctx, key, key+cacheMetadataSuffix).Result()
+	if err == nil && exists > 0 {
+		d.discardKeys(key)
+		return
+	}
+	if err == nil {
+		err = d.store(ctx, key, entry)
+	}
+
+	if err == nil {
+		d.mu.Lock()
+		current := d.remove(key, entry)
+		d.mu.Unlock()
+		if !current {
+			// Invalidated while the store was in flight: undo it (best effort)
+			_ = m.DeleteLarge(ctx, key)
+			return
+		}
+		m.metrics.RecordDeferredPopulated()
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.entries[key] != entry {
+		return // Discarded or replaced meanwhile
+	}
+	entry.attempts++
+	if entry.attempts >= d.config.MaxAttempts || !isTransientWriteError(ctx, err) {
+		d.remove(key, entry)
+		m.metrics.RecordDeferredPopulationDrop()
+		m.logger.Printf("sql4go: dropped deferred cache store of %s after %d attempts: %v", key, entry.attempts, err)
+		return
+	}
+	entry.due = time.Now().Add(d.config.RetryInterval << entry.attempts)
+}
+
+// store writes a pending value like the original store
+func (d *deferredPopulation) store(ctx context.Context, key string, entry *deferredEntry) error {
+	m := d.manager
+	switch {
+	case entry.large && len(entry.dependencies) > 0:
+		return m.SetLargeWithDependenciesTTL(ctx, key, entry.data, entry.dependencies, entry.ttl)
+	case entry.large:
+		return m.SetLargeWithTTL(ctx, key, entry.data, entry.ttl)
+	case len(entry.dependencies) > 0:
+		return m.SetWithDependenciesTTL(ctx, key, entry.data, entry.dependencies, entry.ttl)
+	}
+	return m.SetWithTTL(ctx, key, entry.data, entry.ttl)
+}
+
+// close stops the worker; pending stores are dropped
+func (d *deferredPopulation) close() {
+	d.once.Do(func() {
+		close(d.stop)
+		<-d.done
+	})
+}
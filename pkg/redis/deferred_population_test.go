@@ -0,0 +1,154 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// newDeferredTestManager returns a manager retrying failed stores and a switch failing its
+// SET commands with a connection error
+func newDeferredTestManager(t *testing.T, configure func(*DeferredPopulationConfig)) (*Manager, *miniredis.Miniredis, *atomic.Bool) {
+	t.Helper()
+	m, mr := newTestManager(t, func(c *Config) {
+		c.DeferredPopulation = DeferredPopulationConfig{Enabled: true, MaxAttempts: 3, RetryInterval: 10 * time.Millisecond, MaxBytes: 1 << 20}
+		if configure != nil {
+			configure(&c.DeferredPopulation)
+		}
+	})
+
+	failing := &atomic.Bool{}
+	failing.Store(true)
+	m.client.AddHook(&failingCommands{
+		fail: func(cmd redis.Cmder) bool { return failing.Load() && cmd.Name() == "set" },
+		err:  &net.OpError{Op: "write", Net: "tcp", Err: errors.New("connection reset by peer")},
+	})
+	return m, mr, failing
+}
+
+func TestDeferredPopulationRetriesFailedStore(t *testing.T) {
+	m, mr, failing := newDeferredTestManager(t, nil)
+	ctx := context.Background()
+
+	if err := m.SetValueWithTTL(ctx, "report", "daily", time.Hour); err == nil {
+		t.Fatal("SetValueWithTTL succeeded with SET failing")
+	}
+	if queued := m.GetMetrics().DeferredPopulations; queued != 1 {
+		t.Fatalf("DeferredPopulations = %d, want the failed store queued", queued)
+	}
+
+	failing.Store(false)
+	if !waitFor(t, time.Second, func() bool { return mr.Exists("report") }) {
+		t.Fatal("the queued store was not retried once SET recovered")
+	}
+	var got string
+	if err := m.GetValue(ctx, "report", &got); err != nil || got != "daily" {
+		t.Errorf("GetValue = (%q, %v), want the queued value", got, err)
+	}
+	if populated := m.GetMetrics().DeferredPopulated; populated != 1 {
+		t.Errorf("DeferredPopulated = %d, want 1", populated)
+	}
+}
+
+func TestDeferredPopulationKeepsFresherValue(t *testing.T) {
+	m, mr, failing := newDeferredTestManager(t, nil)
+	ctx := context.Background()
+
+	_ = m.SetValueWithTTL(ctx, "report", "stale", time.Hour)
+
+	// Another process stores a fresher value before the retry
+	fresher, err := m.encodeValue(ctx, "report", "fresh")
+	if err != nil {
+		t.Fatalf("encodeValue: %v", err)
+	}
+	mr.Set("report", string(fresher))
+	failing.Store(false)
+
+	time.Sleep(100 * time.Millisecond) // Several retry intervals
+	var got string
+	if err := m.GetValue(ctx, "report", &got); err != nil || got != "fresh" {
+		t.Errorf("GetValue after the retry window = (%q, %v), want the fresher value", got, err)
+	}
+	if populated := m.GetMetrics().DeferredPopulated; populated != 0 {
+		t.Errorf("DeferredPopulated = %d, want the queued value discarded", populated)
+	}
+}
+
+func TestDeferredPopulationDiscardedByLaterWrites(t *testing.T) {
+	m, mr, failing := newDeferredTestManager(t, nil)
+	ctx := context.Background()
+
+	_ = m.SetValueWithTTL(ctx, "users:1", "stale", time.Hour)
+	_ = m.SetValueWithTTL(ctx, "users:2", "stale", time.Hour)
+
+	// A successful store supersedes the queued one, an invalidation drops it
+	failing.Store(false)
+	if err := m.SetValueWithTTL(ctx, "users:1", "fresh", time.Hour); err != nil {
+		t.Fatalf("SetValueWithTTL: %v", err)
+	}
+	mr.Del("users:1")
+	if err := m.InvalidatePattern(ctx, "users:*"); err != nil {
+		t.Fatalf("InvalidatePattern: %v", err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if keys := mr.Keys(); len(keys) != 0 {
+		t.Errorf("keys after the retry window = %q, want no queued value restored", keys)
+	}
+}
+
+func TestDeferredPopulationDropsAfterMaxAttempts(t *testing.T) {
+	m, mr, _ := newDeferredTestManager(t, func(c *DeferredPopulationConfig) { c.MaxAttempts = 2 })
+	logger := &recordingLogger{}
+	m.SetLogger(logger)
+	ctx := context.Background()
+
+	_ = m.SetValueWithTTL(ctx, "report", "daily", time.Hour)
+	if !waitFor(t, 2*time.Second, func() bool { return m.GetMetrics().DeferredPopulationDrops == 1 }) {
+		t.Fatal("the store was not dropped while SET kept failing")
+	}
+	if mr.Exists("report") {
+		t.Error("report was stored with SET failing")
+	}
+
+	dropped := false
+	for _, message := range logger.logged() {
+		dropped = dropped || strings.Contains(message, "dropped deferred cache store of report after 2 attempts")
+	}
+	if !dropped {
+		t.Errorf("logged %q, want the dropped store", logger.logged())
+	}
+
+	// Nothing is retried after the drop
+	time.Sleep(50 * time.Millisecond)
+	if metrics := m.GetMetrics(); metrics.DeferredPopulationDrops != 1 || metrics.DeferredPopulated != 0 {
+		t.Errorf("drops %d, populated %d after the drop; want 1 and 0", metrics.DeferredPopulationDrops, metrics.DeferredPopulated)
+	}
+}
+
+func TestDeferredPopulationSizeCap(t *testing.T) {
+	m, _, _ := newDeferredTestManager(t, func(c *DeferredPopulationConfig) {
+		c.MaxBytes = 64
+		c.RetryInterval = time.Hour // Keep the values queued
+	})
+	ctx := context.Background()
+
+	_ = m.SetValueWithTTL(ctx, "small", "x", time.Hour)
+	_ = m.SetValueWithTTL(ctx, "large", strings.Repeat("x", 100), time.Hour)
+	if metrics := m.GetMetrics(); metrics.DeferredPopulations != 1 || metrics.DeferredPopulationDrops != 1 {
+		t.Errorf("queued %d, dropped %d; want the small value queued and the large one dropped", metrics.DeferredPopulations, metrics.DeferredPopulationDrops)
+	}
+
+	// Replacing a queued value frees its bytes first
+	_ = m.SetValueWithTTL(ctx, "small", strings.Repeat("y", 40), time.Hour)
+	if m.deferred.bytes > 64 || len(m.deferred.entries) != 1 {
+		t.Errorf("queued %d bytes in %d entries, want one entry within MaxBytes", m.deferred.bytes, len(m.deferred.entries))
+	}
+}
@@ -0,0 +1,150 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	dependencyOverflowSuffix    = ":overflow"
+	dependencySizeCheckInterval = 100  // Check set sizes via SCARD once per this many registrations
+	dependencyScanBatchSize     = 1000 // Members per SSCAN when invalidating an oversized set
+)
+
+// checkDependencySetSizes marks dependency sets that exceed MaxDependencySetSize as overflowed
+// Runs on a sample of registrations only, so SCARD is not issued on every add
+//
+// A popular entity can end up in the dependency set of a huge number of cache keys,
+// making the set grow until its TTL and InvalidateEntityDependencies take seconds.
+// With Invalidation.MaxDependencySetSize set, oversized sets are "overflowed":
+//   - further cache keys are not added to the set; instead their fallback pattern
+//     (the key up to its last separator, e.g. "sql4go:app:orders:find_where:*")
+//     is recorded in a small companion set "<dependency key>:overflow"
+//   - InvalidateEntityDependencies wipes those patterns, and invalidates an oversized
+//     set by the patterns of its members instead of deleting each key individually
+func (m *Manager) checkDependencySetSizes(ctx context.Context, dependencyKeys []string) {
+	maxSize := m.config.Invalidation.MaxDependencySetSize
+	if maxSize <= 0 || len(dependencyKeys) == 0 {
+		return
+	}
+	if m.dependencyRegistrations.Add(1)%dependencySizeCheckInterval != 0 {
+		return
+	}
+
+	pipe := m.client.Pipeline()
+	sizes := make(map[string]*redis.IntCmd, len(dependencyKeys))
+	for _, dependencyKey := range dependencyKeys {
+		if dependencyKey != "" {
+			sizes[dependencyKey] = pipe.SCard(ctx, dependencyKey)
+		}
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return // Best effort - check again on a later registration
+	}
+
+	for dependencyKey, size := range sizes {
+		if size.Val() > int64(maxSize) {
+			m.markDependencyOverflowed(dependencyKey)
+		}
+	}
+}
+
+// isDependencyOverflowed reports whether this process has seen the dependency set overflow
+func (m *Manager) isDependencyOverflowed(dependencyKey string) bool {
+	expiry, ok := m.overflowedDependencies.Load(dependencyKey)
+	if !ok {
+		return false
+	}
+	if time.Now().After(expiry.(time.Time)) {
+		m.overflowedDependencies.Delete(dependencyKey)
+		return false
+	}
+	return true
+}
+
+// markDependencyOverflowed stops registering keys in the set until it expires or is invalidated
+func (m *Manager) markDependencyOverflowed(dependencyKey string) {
+	m.overflowedDependencies.Store(dependencyKey, time.Now().Add(m.config.dependencyTTL()))
+}
+
+// isDependencySetOversized reports whether a dependency set should be invalidated by pattern
+// Uses SCARD so sets overflowed by other processes are detected as well
+func (m *Manager) isDependencySetOversized(ctx context.Context, dependencyKey string) bool {
+	maxSize := m.config.Invalidation.MaxDependencySetSize
+	if maxSize <= 0 {
+		return false
+	}
+	if m.isDependencyOverflowed(dependencyKey) {
+		return true
+	}
+	size, err := m.client.SCard(ctx, dependencyKey).Result()
+	return err == nil && size > int64(maxSize)
+}
+
+// invalidateOverflowPatterns wipes the fallback patterns recorded for an overflowed set
+func (m *Manager) invalidateOverflowPatterns(ctx context.Context, dependencyKey string) {
+	overflowKey := dependencyKey + dependencyOverflowSuffix
+
+	patterns, err := m.client.SMembers(ctx, overflowKey).Result()
+	if err != nil || len(patterns) == 0 {
+		return
+	}
+
+	for _, pattern := range patterns {
+		if err := m.InvalidatePattern(ctx, pattern); err != nil {
+			// Log error but continue with other patterns
+			m.logger.Printf("sql4go: failed to invalidate overflow pattern %s: %v", pattern, err)
+		}
+	}
+
+	m.client.Del(ctx, overflowKey)
+}
+
+// invalidateDependencySetByPattern invalidates an oversized dependency set
+// The members are reduced to their distinct fallback patterns, which are wiped with
+// SCAN instead of deleting every member key individually
+func (m *Manager) invalidateDependencySetByPattern(ctx context.Context, dependencyKey string) error {
+	patterns := make(map[string]struct{})
+
+	var cursor uint64
+	for {
+		members, nextCursor, err := m.client.SScan(ctx, dependencyKey, cursor, "", dependencyScanBatchSize).Result()
+		if err != nil {
+			return fmt.Errorf("failed to scan dependencies: %w", err)
+		}
+		for _, cacheKey := range members {
+			patterns[fallbackPattern(cacheKey)] = struct{}{}
+		}
+		cursor = nextCursor
+		if cursor == 0 {
+			break
+		}
+	}
+
+	for pattern := range patterns {
+		if err := m.InvalidatePattern(ctx, pattern); err != nil {
+			// Log error but continue with other patterns
+			m.logger.Printf("sql4go: failed to invalidate dependency pattern %s: %v", pattern, err)
+		}
+	}
+
+	// Clean up the dependency set itself (UNLINK frees large sets in the background)
+	m.client.Unlink(ctx, dependencyKey)
+	m.overflowedDependencies.Delete(dependencyKey)
+
+	return nil
+}
+
+// fallbackPattern returns the SCAN pattern used instead of tracking a cache key precisely
+// The key is cut at its last separator: "sql4go:app:orders:find_where:ab12" -> "sql4go:app:orders:find_where:*"
+func fallbackPattern(cacheKey string) string {
+	idx := strings.LastIndex(cacheKey, cacheKeySeparator)
+	if idx < 0 {
+		return escapePattern(cacheKey) + "*"
+	}
+	return escapePattern(cacheKey[:idx+1]) + "*"
+}
@@ -0,0 +1,231 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	dependencyPipelineBatchSize = 1000      // Commands per dependency registration pipeline
+	tableDependencyID           = "_table"  // Entity id segment of table-level dependency sets
+	dependencyOwnersID          = "_owners" // Entity id segment of the sets of tables depending on a type
+)
+
+// dependencyRegistration is the deduplicated set of members to add to dependency sets
+type dependencyRegistration struct {
+	keys    []string            // Set keys, in registration order
+	members map[string][]string // Set key -> members to add
+	tracked []string            // Entity dependency sets, for checkDependencySetSizes
+	owners  map[string][]string // Entity type -> other tables whose keys depend on it (hash tags only)
+}
+
+// add queues member for the set key, skipping duplicates
+func (d *dependencyRegistration) add(key, member string) {
+	for _, existing := range d.members[key] {
+		if existing == member {
+			return
+		}
+	}
+	if _, ok := d.members[key]; !ok {
+		d.keys = append(d.keys, key)
+	}
+	d.members[key] = append(d.members[key], member)
+}
+
+// planDependencies builds the registration of cacheKeys as dependents of entities
+// (map[entityType] -> []entityIDs), applying overflow and MaxDependenciesPerKey
+//
+// A query result depends on every entity it contains, so a 2000-row FindWhere registers
+// its cache key in 2000 dependency sets: an SADD and an EXPIRE per set. Registration
+// keeps that bounded:
+//   - ids are deduplicated per entity type, and each set gets one SADD with all of its
+//     members (and one EXPIRE)
+//   - the commands are sent in pipelines of at most dependencyPipelineBatchSize
+//     commands, so no single pipeline buffers megabytes of commands
+//   - with Invalidation.MaxDependenciesPerKey set, an entity type with more ids than
+//     that in one registration is tracked by a table-level dependency instead: the
+//     cache key joins the set "<prefix>:deps:<type>:_table", which is invalidated
+//     together with any entity of the type. Invalidation is coarser, registration
+//     costs two commands.
+//
+// With cluster hash tags, a cache key is registered in sets carrying its own hash tag,
+// so the value and all of its dependency sets live in one slot, even when it depends
+// on entities of other tables (see ownedDependencyKey). The tables holding such sets
+// for an entity type are recorded in "<prefix>:v2:deps:{db:type}:_owners", which
+// InvalidateEntityDependencies reads to find them.
+func (m *Manager) planDependencies(dependencies map[string][]interface{}, cacheKeys ...string) *dependencyRegistration {
+	plan := &dependencyRegistration{members: make(map[string][]string), owners: make(map[string][]string)}
+
+	// With hash tags, each cache key is registered in sets of its own slot
+	owners := make([]string, len(cacheKeys))
+	if m.HashTagsEnabled() {
+		for i, cacheKey := range cacheKeys {
+			owners[i] = hashTag(cacheKey)
+		}
+	}
+
+	entityTypes := make([]string, 0, len(dependencies))
+	for entityType := range dependencies {
+		entityTypes = append(entityTypes, entityType)
+	}
+	sort.Strings(entityTypes)
+
+	maxIDs := m.config.Invalidation.MaxDependenciesPerKey
+	for _, entityType := range entityTypes {
+		ids := distinctIDs(dependencies[entityType])
+		if len(ids) == 0 {
+			continue
+		}
+
+		for _, owner := range owners {
+			if owner != "" && owner != entityType && !containsString(plan.owners[entityType], owner) {
+				plan.owners[entityType] = append(plan.owners[entityType], owner)
+			}
+		}
+
+		// Too many entities of one type - depend on the whole table
+		if maxIDs > 0 && len(ids) > maxIDs {
+			for i, cacheKey := range cacheKeys {
+				plan.add(m.ownedDependencyKey(owners[i], entityType, tableDependencyID), cacheKey)
+			}
+			m.metrics.RecordTableDependency()
+			continue
+		}
+
+		for _, entityID := range ids {
+			overflowed := false
+			for i, cacheKey := range cacheKeys {
+				dependencyKey := m.ownedDependencyKey(owners[i], entityType, entityID)
+				if m.isDependencyOverflowed(dependencyKey) {
+					plan.add(dependencyKey+dependencyOverflowSuffix, fallbackPattern(cacheKey))
+					overflowed = true
+					continue
+				}
+				if _, seen := plan.members[dependencyKey]; !seen {
+					plan.tracked = append(plan.tracked, dependencyKey)
+				}
+				plan.add(dependencyKey, cacheKey)
+			}
+			if overflowed {
+				m.metrics.RecordDependencyOverflow(fmt.Sprintf("%s%s%v", entityType, cacheKeySeparator, entityID))
+			}
+		}
+	}
+	return plan
+}
+
+// containsString reports whether values contains value
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// distinctIDs returns ids without duplicates, compared by their key segment
+func distinctIDs(ids []interface{}) []interface{} {
+	if len(ids) < 2 {
+		return ids
+	}
+	seen := make(map[string]struct{}, len(ids))
+	distinct := make([]interface{}, 0, len(ids))
+	for _, id := range ids {
+		segment := fmt.Sprintf("%v", id)
+		if _, ok := seen[segment]; ok {
+			continue
+		}
+		seen[segment] = struct{}{}
+		distinct = append(distinct, id)
+	}
+	return distinct
+}
+
+// registerDependencies sends a dependency registration in bounded pipelines
+// first, if set, queues commands sent with the first pipeline (e.g. the SET of the
+// value). A failed pipeline stops the registration, deletes the rollback keys and
+// returns ErrPartialWrite (see execWrite); earlier pipelines are not undone.
+func (m *Manager) registerDependencies(ctx context.Context, plan *dependencyRegistration, first func(pipe redis.Pipeliner), rollback []string) error {
+	ttl := m.config.dependencyTTL()
+
+	// Record the tables holding sets for other types first, so the sets are never
+	// written without invalidation being able to find them
+	if len(plan.owners) > 0 {
+		pipe := m.client.Pipeline()
+		for entityType, owners := range plan.owners {
+			ownersKey := m.dependencyKey(entityType, dependencyOwnersID)
+			members := make([]interface{}, len(owners))
+			for i, owner := range owners {
+				members[i] = owner
+			}
+			pipe.SAdd(ctx, ownersKey, members...)
+			pipe.Expire(ctx, ownersKey, ttl)
+		}
+		if _, err := pipe.Exec(ctx); err != nil {
+			return fmt.Errorf("failed to record dependency owners: %w", err)
+		}
+	}
+
+	pipe := m.client.Pipeline()
+	if first != nil {
+		first(pipe)
+	}
+
+	for _, key := range plan.keys {
+		members := make([]interface{}, len(plan.members[key]))
+		for i, member := range plan.members[key] {
+			members[i] = member
+		}
+		pipe.SAdd(ctx, key, members...)
+		pipe.Expire(ctx, key, ttl)
+
+		if pipe.Len() >= dependencyPipelineBatchSize {
+			if err := m.execWrite(ctx, pipe, rollback); err != nil {
+				return err
+			}
+			pipe = m.client.Pipeline()
+		}
+	}
+
+	if pipe.Len() > 0 {
+		if err := m.execWrite(ctx, pipe, rollback); err != nil {
+			return err
+		}
+	}
+
+	m.checkDependencySetSizes(ctx, plan.tracked)
+	return nil
+}
+
+// dependencyOwners returns the owners of an entity type's dependency sets: "" for its own
+// sets, and with hash tags the other tables recorded as holding sets for it
+func (m *Manager) dependencyOwners(ctx context.Context, entityType string) ([]string, error) {
+	if !m.HashTagsEnabled() {
+		return []string{""}, nil
+	}
+	owners, err := m.client.SMembers(ctx, m.dependencyKey(entityType, dependencyOwnersID)).Result()
+	if err != nil && err != redis.Nil {
+		return nil, err
+	}
+	sort.Strings(owners)
+	return append([]string{""}, owners...), nil
+}
+
+// invalidateTableDependencies deletes the cache keys registered in a table-level
+// dependency set of an entity type (see MaxDependenciesPerKey)
+func (m *Manager) invalidateTableDependencies(ctx context.Context, tableKey string) {
+	if m.config.Invalidation.MaxDependenciesPerKey <= 0 {
+		return
+	}
+
+	dependentKeys, err := m.client.SMembers(ctx, tableKey).Result()
+	if err != nil || len(dependentKeys) == 0 {
+		return
+	}
+	m.deleteDependentKeys(ctx, dependentKeys)
+	m.client.Del(ctx, tableKey)
+}
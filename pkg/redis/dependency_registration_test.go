@@ -0,0 +1,177 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// commandCounter is a client hook counting the commands sent, by name, and the size of
+// the largest pipeline
+type commandCounter struct {
+	mu           sync.Mutex
+	commands     map[string]int
+	largestBatch int
+}
+
+func countCommands(m *Manager) *commandCounter {
+	counter := &commandCounter{commands: make(map[string]int)}
+	m.client.AddHook(counter)
+	return counter
+}
+
+func (c *commandCounter) DialHook(next redis.DialHook) redis.DialHook { return next }
+
+func (c *commandCounter) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		c.record(cmd)
+		return next(ctx, cmd)
+	}
+}
+
+func (c *commandCounter) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		c.mu.Lock()
+		c.largestBatch = max(c.largestBatch, len(cmds))
+		c.mu.Unlock()
+		for _, cmd := range cmds {
+			c.record(cmd)
+		}
+		return next(ctx, cmds)
+	}
+}
+
+func (c *commandCounter) record(cmd redis.Cmder) {
+	c.mu.Lock()
+	c.commands[cmd.Name()]++
+	c.mu.Unlock()
+}
+
+// registrations returns the number of SADD and EXPIRE commands sent (connection
+// setup commands are not counted)
+func (c *commandCounter) registrations() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.commands["sadd"] + c.commands["expire"]
+}
+
+// entityIDs returns the ids 1..n, each listed twice as a result with joined rows would
+func entityIDs(n int) []interface{} {
+	ids := make([]interface{}, 0, 2*n)
+	for id := 1; id <= n; id++ {
+		ids = append(ids, id, id)
+	}
+	return ids
+}
+
+func TestHashTaggedDependenciesShareTheKeySlot(t *testing.T) {
+	m, mr := newTestManager(t, func(c *Config) { c.Cluster.HashTags = true })
+	ctx := context.Background()
+
+	cacheKey := "sql4go:v2:{app:users}:s1:find_where:ab12"
+	dependencies := map[string][]interface{}{"app:users": {1, 2}, "app:customers": {5}}
+	if err := m.SetWithDependencies(ctx, cacheKey, []byte("[]"), dependencies); err != nil {
+		t.Fatalf("SetWithDependencies: %v", err)
+	}
+
+	// Every set holding the key carries the key's hash tag
+	for _, key := range mr.Keys() {
+		if !strings.Contains(key, ":deps:") || strings.HasSuffix(key, ":"+dependencyOwnersID) {
+			continue
+		}
+		if tag := hashTag(key); tag != "app:users" {
+			t.Errorf("dependency set %s has hash tag %q, want app:users", key, tag)
+		}
+	}
+	if got := m.ownedDependencyKey("app:users", "app:customers", 5); !mr.Exists(got) {
+		t.Fatalf("cross-table set %s not written; keys: %v", got, mr.Keys())
+	}
+
+	// Invalidating the other table's entity finds the set through its owners
+	keys, err := m.GetDependencies(ctx, "app:customers", 5)
+	if err != nil || len(keys) != 1 || keys[0] != cacheKey {
+		t.Fatalf("GetDependencies = (%v, %v), want [%s]", keys, err, cacheKey)
+	}
+	if err := m.InvalidateEntityDependencies(ctx, "app:customers", 5); err != nil {
+		t.Fatalf("InvalidateEntityDependencies: %v", err)
+	}
+	if mr.Exists(cacheKey) {
+		t.Errorf("%s survived the invalidation of customer 5", cacheKey)
+	}
+}
+
+func TestDependenciesWithoutHashTagsUseEntitySets(t *testing.T) {
+	m, mr := newTestManager(t)
+	ctx := context.Background()
+
+	cacheKey := "sql4go:app:users:s1:find_where:ab12"
+	if err := m.SetWithDependencies(ctx, cacheKey, []byte("[]"), map[string][]interface{}{"customers": {5}}); err != nil {
+		t.Fatalf("SetWithDependencies: %v", err)
+	}
+	if !mr.Exists("gensql4go:deps:customers:5") {
+		t.Fatalf("dependency set not written; keys: %v", mr.Keys())
+	}
+	if err := m.InvalidateEntityDependencies(ctx, "customers", 5); err != nil {
+		t.Fatalf("InvalidateEntityDependencies: %v", err)
+	}
+	if mr.Exists(cacheKey) {
+		t.Errorf("%s survived the invalidation", cacheKey)
+	}
+}
+
+func TestDependencyRegistrationIsDeduplicatedAndBatched(t *testing.T) {
+	m, mr := newTestManager(t, func(c *Config) { c.Invalidation.MaxDependenciesPerKey = 0 })
+	ctx := context.Background()
+	counter := countCommands(m)
+
+	// 2000 users listed twice each: the old registration sent an SADD and an EXPIRE per
+	// listed pair, 8000 commands in one pipeline
+	const users = 2000
+	cacheKey := "sql4go:app:orders:s1:find_where:ab12"
+	if err := m.AddMultipleDependencies(ctx, map[string][]interface{}{"users": entityIDs(users)}, cacheKey); err != nil {
+		t.Fatalf("AddMultipleDependencies: %v", err)
+	}
+
+	if got := counter.registrations(); got != 2*users {
+		t.Errorf("commands sent = %d (%v), want an SADD and an EXPIRE per distinct user (%d)", got, counter.commands, 2*users)
+	}
+	if counter.largestBatch > dependencyPipelineBatchSize {
+		t.Errorf("largest pipeline = %d commands, want at most %d", counter.largestBatch, dependencyPipelineBatchSize)
+	}
+	for _, id := range []int{1, users} {
+		key := fmt.Sprintf("gensql4go:deps:users:%d", id)
+		if members, err := mr.Members(key); err != nil || len(members) != 1 || members[0] != cacheKey {
+			t.Errorf("members of %s = (%v, %v), want [%s]", key, members, err, cacheKey)
+		}
+	}
+}
+
+func TestDependencyRegistrationAboveCapUsesTableDependency(t *testing.T) {
+	m, mr := newTestManager(t, func(c *Config) { c.Invalidation.MaxDependenciesPerKey = 100 })
+	ctx := context.Background()
+	counter := countCommands(m)
+
+	cacheKey := "sql4go:app:orders:s1:find_where:ab12"
+	if err := m.AddMultipleDependencies(ctx, map[string][]interface{}{"users": entityIDs(2000)}, cacheKey); err != nil {
+		t.Fatalf("AddMultipleDependencies: %v", err)
+	}
+	if got := counter.registrations(); got != 2 {
+		t.Errorf("commands sent = %d (%v), want one SADD and one EXPIRE of the table set", got, counter.commands)
+	}
+	if !mr.Exists("gensql4go:deps:users:_table") {
+		t.Fatalf("table-level dependency not written; keys: %v", mr.Keys())
+	}
+
+	// Any user invalidates the key
+	mr.Set(cacheKey, "[]")
+	if err := m.InvalidateEntityDependencies(ctx, "users", 1234); err != nil {
+		t.Fatalf("InvalidateEntityDependencies: %v", err)
+	}
+	if mr.Exists(cacheKey) {
+		t.Errorf("%s survived the invalidation of user 1234", cacheKey)
+	}
+}
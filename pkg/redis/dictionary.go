@@ -0,0 +1,390 @@
+package redis
+
+import (
+	"bytes"
+	"compress/flate"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	cacheDictionaryPrefix       = "dict"    // Key segment of dictionary keys, after the manager's key prefix
+	cacheDictionaryCurrent      = "current" // Key of the current dictionary id, after the dictionary prefix
+	dictionaryRefreshInterval   = time.Minute
+	defaultDictionaryMinSize    = 256
+	maxDictionarySize           = 32 * 1024 // Deflate window; larger dictionaries are truncated by flate
+	minDictionaryTrainingValues = 8
+)
+
+// dictionaryValuePrefix marks a value deflated with a shared dictionary
+// Like msgpackValuePrefix, serialized values never start with a NUL byte.
+const dictionaryValuePrefix = "\x00sql4go:dict:"
+
+// compressionDictionary is a loaded preset dictionary and a pool of its deflate writers
+type compressionDictionary struct {
+	id      string
+	data    []byte
+	writers sync.Pool // *flate.Writer; Reset keeps the dictionary
+}
+
+// dictionaryStore holds the dictionaries loaded by a manager
+type dictionaryStore struct {
+	mu      sync.Mutex
+	byID    map[string]*compressionDictionary
+	current *compressionDictionary // nil until a dictionary is trained
+	checked time.Time              // Last lookup of the current dictionary id in Redis
+}
+
+// flateReaders pools deflate readers (flate.Resetter) across dictionaries
+var flateReaders sync.Pool
+
+// newDictionaryStore creates an empty dictionary store
+func newDictionaryStore() *dictionaryStore {
+	return &dictionaryStore{byID: make(map[string]*compressionDictionary)}
+}
+
+// dictionaryID returns the id of a dictionary: the hex xxhash of its content
+func dictionaryID(data []byte) string {
+	return fmt.Sprintf("%016x", xxhash.Sum64(data))
+}
+
+// dictionaryKey returns the key of a dictionary (or of the current id, cacheDictionaryCurrent)
+func (m *Manager) dictionaryKey(id string) string {
+	return m.keyPrefix() + cacheKeySeparator + cacheDictionaryPrefix + cacheKeySeparator + id
+}
+
+// encodeValue serializes a value and, if enabled, compresses it with the current dictionary
+func (m *Manager) encodeValue(ctx context.Context, key string, value interface{}) ([]byte, error) {
+	data, err := m.marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return m.compressWithDictionary(ctx, key, data), nil
+}
+
+// decodeValue expands a dictionary-compressed value and deserializes it into target
+func (m *Manager) decodeValue(ctx context.Context, data []byte, target interface{}) error {
+	data, err := m.expandWithDictionary(ctx, data)
+	if err != nil {
+		return err
+	}
+	return m.unmarshal(data, target)
+}
+
+// compressWithDictionary deflates a small value with the current dictionary (best effort)
+// data is returned unchanged if dictionary compression is disabled, out of its size
+// range, no dictionary is trained yet, or compression does not make it smaller.
+func (m *Manager) compressWithDictionary(ctx context.Context, key string, data []byte) []byte {
+	if m.dictionaries == nil {
+		return data
+	}
+	minSize := m.config.LargeValue.Dictionary.MinSize
+	if minSize <= 0 {
+		minSize = defaultDictionaryMinSize
+	}
+	_, _, compressThreshold, _, _ := m.getLargeValueConfig()
+	if len(data) < minSize || len(data) > compressThreshold {
+		return data
+	}
+
+	dictionary := m.currentDictionary(ctx)
+	if dictionary == nil {
+		return data
+	}
+
+	var buf bytes.Buffer
+	buf.Grow(len(dictionaryValuePrefix) + len(dictionary.id) + 1 + len(data)/2)
+	buf.WriteString(dictionaryValuePrefix)
+	buf.WriteString(dictionary.id)
+	buf.WriteByte(0)
+
+	writer, _ := dictionary.writers.Get().(*flate.Writer)
+	if writer == nil {
+		var err error
+		if writer, err = flate.NewWriterDict(&buf, flate.DefaultCompression, dictionary.data); err != nil {
+			return data
+		}
+	} else {
+		writer.Reset(&buf)
+	}
+	_, err := writer.Write(data)
+	if err == nil {
+		err = writer.Close()
+	}
+	dictionary.writers.Put(writer)
+	if err != nil || buf.Len() >= len(data) {
+		return data
+	}
+
+	m.metrics.RecordDictionaryCompression(m.tableOfKey(key), len(data), buf.Len())
+	return buf.Bytes()
+}
+
+// expandWithDictionary inflates a dictionary-compressed value; other values are returned
+// unchanged. Values whose dictionary cannot be loaded fail with ErrSerializationFailed.
+func (m *Manager) expandWithDictionary(ctx context.Context, data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, []byte(dictionaryValuePrefix)) {
+		return data, nil
+	}
+
+	rest := data[len(dictionaryValuePrefix):]
+	end := bytes.IndexByte(rest, 0)
+	if end < 0 {
+		return nil, fmt.Errorf("%w: malformed dictionary value header", ErrSerializationFailed)
+	}
+	dictionary, err := m.loadDictionary(ctx, string(rest[:end]))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSerializationFailed, err)
+	}
+
+	compressed := bytes.NewReader(rest[end+1:])
+	reader, _ := flateReaders.Get().(io.ReadCloser)
+	if reader == nil {
+		reader = flate.NewReaderDict(compressed, dictionary.data)
+	} else if err := reader.(flate.Resetter).Reset(compressed, dictionary.data); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSerializationFailed, err)
+	}
+	defer flateReaders.Put(reader)
+
+	expanded, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to inflate dictionary value: %v", ErrSerializationFailed, err)
+	}
+	return expanded, nil
+}
+
+// currentDictionary returns the dictionary new values are compressed with (nil if none)
+// The current id is looked up in Redis at most once per dictionaryRefreshInterval.
+func (m *Manager) currentDictionary(ctx context.Context) *compressionDictionary {
+	store := m.dictionaries
+	store.mu.Lock()
+	current := store.current
+	if time.Since(store.checked) < dictionaryRefreshInterval {
+		store.mu.Unlock()
+		return current
+	}
+	store.checked = time.Now() // Other writers keep the current dictionary meanwhile
+	store.mu.Unlock()
+
+	id, err := m.client.Get(ctx, m.dictionaryKey(cacheDictionaryCurrent)).Result()
+	if err != nil {
+		if err != redis.Nil {
+			m.logger.Printf("sql4go: failed to look up the current compression dictionary: %v", err)
+		}
+		return current
+	}
+	if current != nil && current.id == id {
+		return current
+	}
+
+	dictionary, err := m.loadDictionary(ctx, id)
+	if err != nil {
+		m.logger.Printf("sql4go: failed to load compression dictionary %s: %v", id, err)
+		return current
+	}
+	store.mu.Lock()
+	store.current = dictionary
+	store.mu.Unlock()
+	return dictionary
+}
+
+// loadDictionary returns a dictionary by id, reading it from Redis on first use
+func (m *Manager) loadDictionary(ctx context.Context, id string) (*compressionDictionary, error) {
+	store := m.dictionaries
+	if store == nil {
+		return nil, fmt.Errorf("dictionary compression is disabled")
+	}
+	store.mu.Lock()
+	dictionary, ok := store.byID[id]
+	store.mu.Unlock()
+	if ok {
+		return dictionary, nil
+	}
+
+	data, err := m.client.Get(ctx, m.dictionaryKey(id)).Bytes()
+	if err == redis.Nil {
+		return nil, fmt.Errorf("compression dictionary %s not found", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read compression dictionary %s: %w", id, err)
+	}
+	if dictionaryID(data) != id {
+		return nil, fmt.Errorf("compression dictionary %s does not match its id", id)
+	}
+
+	return m.addDictionary(id, data), nil
+}
+
+// addDictionary registers a loaded dictionary, keeping an existing one with the same id
+func (m *Manager) addDictionary(id string, data []byte) *compressionDictionary {
+	store := m.dictionaries
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if dictionary, ok := store.byID[id]; ok {
+		return dictionary
+	}
+	dictionary := &compressionDictionary{id: id, data: data}
+	store.byID[id] = dictionary
+	return dictionary
+}
+
+// TrainDictionary builds a compression dictionary from up to sampleSize cached values
+// matching pattern (e.g. "sql4go:app:users:*", after a warm-up) and makes it the
+// current dictionary of all instances. It returns the dictionary id.
+// Internal keys (metadata, chunks, dependency sets), null markers and values outside the
+// dictionary size range are skipped. Retrain when the cached entities change shape;
+// values compressed with the previous dictionary stay readable until they expire.
+//
+// Cached entities are mostly small documents with the same field names, too small to
+// gain from CompressThreshold-based gzip one by one. With LargeValue.Dictionary enabled,
+// values between Dictionary.MinSize and CompressThreshold are deflated with a preset
+// dictionary trained from cached values (see TrainDictionary), so the shared structure
+// is not stored in every value.
+//
+// A compressed value carries its dictionary id in a header (dictionaryValuePrefix, id,
+// NUL), so it stays a single GET for GetMany and the L1 cache. Dictionaries are stored
+// in Redis ("gensql4go:dict:{id}") and shared by all instances: each instance looks up
+// the current dictionary at most once per dictionaryRefreshInterval and loads older
+// dictionaries on demand to read values compressed with them. A retrained dictionary
+// replaces the current one; the previous one expires after the longest cache TTL
+// (twice, as dependency sets), so values compressed with it stay readable. A value
+// whose dictionary is gone is discarded as corrupt and re-read from the database.
+//
+// Values are only stored compressed if that makes them smaller. Until a dictionary is
+// trained, values are stored as before.
+func (m *Manager) TrainDictionary(ctx context.Context, pattern string, sampleSize int) (string, error) {
+	if err := m.checkClient(); err != nil {
+		return "", err
+	}
+	if m.dictionaries == nil {
+		return "", fmt.Errorf("dictionary compression is disabled (large_value.dictionary.enabled)")
+	}
+
+	keys, _, err := m.ScanKeys(ctx, pattern, sampleSize)
+	if err != nil {
+		return "", err
+	}
+	samples, err := m.dictionarySamples(ctx, keys)
+	if err != nil {
+		return "", err
+	}
+	if len(samples) < minDictionaryTrainingValues {
+		return "", fmt.Errorf("not enough values to train a dictionary: %d of at least %d matching %s", len(samples), minDictionaryTrainingValues, pattern)
+	}
+
+	data := buildDictionary(samples, m.config.LargeValue.Dictionary.MaxSize)
+	id := dictionaryID(data)
+	if err := m.client.Set(ctx, m.dictionaryKey(id), data, 0).Err(); err != nil {
+		return "", fmt.Errorf("failed to store compression dictionary: %w", err)
+	}
+	previous, err := m.client.SetArgs(ctx, m.dictionaryKey(cacheDictionaryCurrent), id, redis.SetArgs{Get: true}).Result()
+	if err != nil && err != redis.Nil {
+		return "", fmt.Errorf("failed to activate compression dictionary: %w", err)
+	}
+
+	// Keep the previous dictionary until the values compressed with it have expired
+	if previous != "" && previous != id {
+		if err := m.client.Expire(ctx, m.dictionaryKey(previous), m.config.dependencyTTL()).Err(); err != nil {
+			m.logger.Printf("sql4go: failed to expire compression dictionary %s: %v", previous, err)
+		}
+	}
+
+	dictionary := m.addDictionary(id, data)
+	m.dictionaries.mu.Lock()
+	m.dictionaries.current = dictionary
+	m.dictionaries.checked = time.Now()
+	m.dictionaries.mu.Unlock()
+	return id, nil
+}
+
+// dictionarySamples reads the values of keys usable for dictionary training in one pipeline
+func (m *Manager) dictionarySamples(ctx context.Context, keys []string) ([][]byte, error) {
+	var candidates []string
+	for _, key := range keys {
+		if !strings.Contains(key, "_internal:") && !strings.HasPrefix(key, m.dictionaryKey("")) {
+			candidates = append(candidates, key)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	pipe := m.client.Pipeline()
+	results := make([]*redis.StringCmd, len(candidates))
+	for i, key := range candidates {
+		results[i] = pipe.Get(ctx, key)
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		var replyErr redis.Error
+		if !errors.As(err, &replyErr) { // Reply errors (WRONGTYPE) only skip their key
+			return nil, fmt.Errorf("failed to read dictionary samples: %w", err)
+		}
+	}
+
+	minSize := m.config.LargeValue.Dictionary.MinSize
+	if minSize <= 0 {
+		minSize = defaultDictionaryMinSize
+	}
+	_, _, compressThreshold, _, _ := m.getLargeValueConfig()
+
+	var samples [][]byte
+	for _, result := range results {
+		data, err := result.Bytes()
+		if err != nil || IsNullMarker(data) { // Missing, not a string (e.g. dependency sets)
+			continue
+		}
+		if data, err = m.expandWithDictionary(ctx, data); err != nil {
+			continue
+		}
+		if len(data) >= minSize && len(data) <= compressThreshold {
+			samples = append(samples, data)
+		}
+	}
+	return samples, nil
+}
+
+// buildDictionary concatenates samples into a preset dictionary of at most maxSize bytes
+// Each sample contributes at most a quarter of the dictionary, so it covers several
+// values; deflate finds the repeated field names and common values in any of them.
+func buildDictionary(samples [][]byte, maxSize int) []byte {
+	if maxSize <= 0 || maxSize > maxDictionarySize {
+		maxSize = maxDictionarySize
+	}
+	perSample := max(maxSize/4, 1)
+
+	dictionary := make([]byte, 0, maxSize)
+	for _, sample := range samples {
+		room := maxSize - len(dictionary)
+		if room == 0 {
+			break
+		}
+		dictionary = append(dictionary, sample[:min(len(sample), perSample, room)]...)
+	}
+	return dictionary
+}
+
+// tableOfKey returns the table of a repository cache key for per-table metrics
+// ("sql4go:app:users:..." or, with hash tags, "sql4go:v2:{app:users}:...")
+func (m *Manager) tableOfKey(key string) string {
+	rest, ok := strings.CutPrefix(key, m.entityKeyPrefix()+cacheKeySeparator)
+	if !ok {
+		return OtherMetricLabel
+	}
+	if m.HashTagsEnabled() {
+		rest = strings.TrimPrefix(rest, cacheHashTagVersion+cacheKeySeparator+"{")
+	}
+	parts := strings.SplitN(rest, cacheKeySeparator, 3)
+	if len(parts) < 3 {
+		return OtherMetricLabel
+	}
+	return strings.TrimSuffix(parts[1], "}")
+}
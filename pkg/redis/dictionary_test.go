@@ -0,0 +1,220 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// dictionaryUser is a small cached entity whose field names repeat in every value
+type dictionaryUser struct {
+	ID           int    `json:"id"`
+	Name         string `json:"name"`
+	Email        string `json:"email"`
+	Organization string `json:"organization"`
+	Role         string `json:"role"`
+	Locale       string `json:"locale"`
+	Timezone     string `json:"timezone"`
+	Address      string `json:"address"`
+}
+
+func newDictionaryUser(id int) dictionaryUser {
+	return dictionaryUser{
+		ID:           id,
+		Name:         fmt.Sprintf("user %d", id),
+		Email:        fmt.Sprintf("user%d@example.com", id),
+		Organization: "Example Organization International",
+		Role:         "administrator",
+		Locale:       "en_US",
+		Timezone:     "Europe/Amsterdam",
+		Address:      fmt.Sprintf("%d Long Example Street, Springfield, Example Country", id),
+	}
+}
+
+// newDictionaryTestManager returns a manager compressing values from 128 bytes with a dictionary
+func newDictionaryTestManager(t *testing.T) (*Manager, *Config) {
+	t.Helper()
+	m, _ := newTestManager(t, func(c *Config) {
+		c.LargeValue.Dictionary.Enabled = true
+		c.LargeValue.Dictionary.MinSize = 128
+	})
+	return m, m.config
+}
+
+// newPeerManager returns another manager on the same server, like another instance
+func newPeerManager(t *testing.T, config *Config) *Manager {
+	t.Helper()
+	peerConfig := *config
+	peer, err := NewManager(&peerConfig)
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+	t.Cleanup(func() { _ = peer.Close() })
+	return peer
+}
+
+// storeDictionaryUsers caches users from..to-1 under repository keys of the users table
+func storeDictionaryUsers(t *testing.T, m *Manager, from, to int, value func(int) interface{}) {
+	t.Helper()
+	for id := from; id < to; id++ {
+		if err := m.SetValueWithTTL(context.Background(), dictionaryUserKey(id), value(id), time.Hour); err != nil {
+			t.Fatalf("SetValueWithTTL: %v", err)
+		}
+	}
+}
+
+func dictionaryUserKey(id int) string {
+	return fmt.Sprintf("sql4go:app:users:s1:find_by_id:%d", id)
+}
+
+func TestDictionaryCompressionRoundTrip(t *testing.T) {
+	m, _ := newDictionaryTestManager(t)
+	ctx := context.Background()
+
+	// Until a dictionary is trained values are stored as serialized
+	storeDictionaryUsers(t, m, 0, 10, func(id int) interface{} { return newDictionaryUser(id) })
+	raw, err := m.client.Get(ctx, dictionaryUserKey(0)).Bytes()
+	if err != nil || strings.HasPrefix(string(raw), dictionaryValuePrefix) {
+		t.Fatalf("value before training = (%q, %v), want it uncompressed", raw, err)
+	}
+
+	id, err := m.TrainDictionary(ctx, "sql4go:app:users:*", 100)
+	if err != nil {
+		t.Fatalf("TrainDictionary: %v", err)
+	}
+
+	user := newDictionaryUser(42)
+	if err := m.SetValueWithTTL(ctx, dictionaryUserKey(42), user, time.Hour); err != nil {
+		t.Fatalf("SetValueWithTTL: %v", err)
+	}
+	raw, err = m.client.Get(ctx, dictionaryUserKey(42)).Bytes()
+	if err != nil || !strings.HasPrefix(string(raw), dictionaryValuePrefix+id+"\x00") {
+		t.Fatalf("value after training = (%q, %v), want it compressed with dictionary %s", raw, err, id)
+	}
+	serialized, _ := m.marshal(user)
+	if len(raw) >= len(serialized) {
+		t.Errorf("compressed value is %d bytes, serialized %d", len(raw), len(serialized))
+	}
+
+	var got dictionaryUser
+	if err := m.GetValue(ctx, dictionaryUserKey(42), &got); err != nil || got != user {
+		t.Errorf("GetValue = (%+v, %v), want %+v", got, err, user)
+	}
+
+	// Per-table compression metrics
+	stats := m.GetMetrics().ByTable["users"]
+	if stats.DictionaryValues != 1 || stats.DictionaryOriginalBytes != uint64(len(serialized)) || stats.DictionaryCompressedBytes != uint64(len(raw)) {
+		t.Errorf("users metrics = %+v, want one value of %d compressed to %d bytes", stats, len(serialized), len(raw))
+	}
+	if stats.DictionaryRatio <= 1 {
+		t.Errorf("DictionaryRatio = %v, want > 1", stats.DictionaryRatio)
+	}
+}
+
+func TestDictionarySharedByInstances(t *testing.T) {
+	m, config := newDictionaryTestManager(t)
+	ctx := context.Background()
+
+	storeDictionaryUsers(t, m, 0, 10, func(id int) interface{} { return newDictionaryUser(id) })
+	id, err := m.TrainDictionary(ctx, "sql4go:app:users:*", 100)
+	if err != nil {
+		t.Fatalf("TrainDictionary: %v", err)
+	}
+	user := newDictionaryUser(42)
+	if err := m.SetValueWithTTL(ctx, dictionaryUserKey(42), user, time.Hour); err != nil {
+		t.Fatalf("SetValueWithTTL: %v", err)
+	}
+
+	// Another instance loads the dictionary from Redis to read the value and compress its own
+	peer := newPeerManager(t, config)
+	var got dictionaryUser
+	if err := peer.GetValue(ctx, dictionaryUserKey(42), &got); err != nil || got != user {
+		t.Fatalf("peer GetValue = (%+v, %v), want %+v", got, err, user)
+	}
+	if err := peer.SetValueWithTTL(ctx, dictionaryUserKey(43), newDictionaryUser(43), time.Hour); err != nil {
+		t.Fatalf("peer SetValueWithTTL: %v", err)
+	}
+	raw, _ := peer.client.Get(ctx, dictionaryUserKey(43)).Bytes()
+	if !strings.HasPrefix(string(raw), dictionaryValuePrefix+id+"\x00") {
+		t.Errorf("peer value = %q, want it compressed with the current dictionary %s", raw, id)
+	}
+
+	// A value whose dictionary is gone is discarded as corrupt
+	m.client.Del(ctx, m.dictionaryKey(id))
+	fresh := newPeerManager(t, config)
+	if err := fresh.GetValue(ctx, dictionaryUserKey(42), &got); !IsKeyNotFound(err) {
+		t.Errorf("GetValue without the dictionary = %v, want ErrKeyNotFound", err)
+	}
+}
+
+func TestDictionaryRetraining(t *testing.T) {
+	m, config := newDictionaryTestManager(t)
+	ctx := context.Background()
+
+	storeDictionaryUsers(t, m, 0, 10, func(id int) interface{} { return newDictionaryUser(id) })
+	first, err := m.TrainDictionary(ctx, "sql4go:app:users:*", 100)
+	if err != nil {
+		t.Fatalf("TrainDictionary: %v", err)
+	}
+	old := newDictionaryUser(42)
+	if err := m.SetValueWithTTL(ctx, dictionaryUserKey(42), old, time.Hour); err != nil {
+		t.Fatalf("SetValueWithTTL: %v", err)
+	}
+
+	// The entities change shape: retrain on the new values
+	type profile struct {
+		UserID      int    `json:"user_id"`
+		Biography   string `json:"biography"`
+		Preferences string `json:"preferences"`
+	}
+	newProfile := func(id int) interface{} {
+		return profile{UserID: id, Biography: fmt.Sprintf("profile %d of a long-standing member of the community", id), Preferences: "notifications=weekly;theme=dark;language=en;newsletter=true;digest=monthly;timezone=UTC;currency=EUR;units=metric;privacy=strict"}
+	}
+	storeDictionaryUsers(t, m, 100, 120, newProfile)
+	for id := 0; id < 10; id++ {
+		m.client.Del(ctx, dictionaryUserKey(id))
+	}
+	second, err := m.TrainDictionary(ctx, "sql4go:app:users:*", 100)
+	if err != nil {
+		t.Fatalf("retraining: %v", err)
+	}
+	if second == first {
+		t.Fatal("retraining on other values produced the same dictionary")
+	}
+	if current, _ := m.client.Get(ctx, m.dictionaryKey(cacheDictionaryCurrent)).Result(); current != second {
+		t.Errorf("current dictionary = %s, want %s", current, second)
+	}
+	if ttl := m.client.TTL(ctx, m.dictionaryKey(first)).Val(); ttl <= 0 {
+		t.Errorf("TTL of the previous dictionary = %v, want it to expire", ttl)
+	}
+
+	// New values use the new dictionary; values compressed with the previous one stay readable
+	if err := m.SetValueWithTTL(ctx, dictionaryUserKey(200), newProfile(200), time.Hour); err != nil {
+		t.Fatalf("SetValueWithTTL: %v", err)
+	}
+	raw, _ := m.client.Get(ctx, dictionaryUserKey(200)).Bytes()
+	if !strings.HasPrefix(string(raw), dictionaryValuePrefix+second+"\x00") {
+		t.Errorf("value after retraining = %q, want dictionary %s", raw, second)
+	}
+	var got dictionaryUser
+	if err := newPeerManager(t, config).GetValue(ctx, dictionaryUserKey(42), &got); err != nil || got != old {
+		t.Errorf("GetValue of a value of the previous dictionary = (%+v, %v), want %+v", got, err, old)
+	}
+}
+
+func TestTrainDictionaryErrors(t *testing.T) {
+	ctx := context.Background()
+
+	disabled, _ := newTestManager(t)
+	if _, err := disabled.TrainDictionary(ctx, "sql4go:*", 100); err == nil {
+		t.Error("TrainDictionary succeeded with dictionary compression disabled")
+	}
+
+	m, _ := newDictionaryTestManager(t)
+	storeDictionaryUsers(t, m, 0, minDictionaryTrainingValues-1, func(id int) interface{} { return newDictionaryUser(id) })
+	if _, err := m.TrainDictionary(ctx, "sql4go:app:users:*", 100); err == nil {
+		t.Errorf("TrainDictionary on %d values succeeded, want at least %d", minDictionaryTrainingValues-1, minDictionaryTrainingValues)
+	}
+}
@@ -1,6 +1,11 @@
 package redis
 
-import "errors"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+)
 
 // Sentinel errors for Redis operations
 var (
@@ -19,8 +24,35 @@ var (
 	// ErrInvalidKey is returned for invalid cache key operations
 	ErrInvalidKey = errors.New("invalid cache key")
 
-	// ErrSerializationFailed is returned when JSON marshaling/unmarshaling fails
+	// ErrSerializationFailed is returned when marshaling/unmarshaling fails
+	// Corrupt values on read are reported wrapped together with ErrKeyNotFound
 	ErrSerializationFailed = errors.New("cache serialization failed")
+
+	// ErrNullValue is returned when a key holds a cached empty or not-found result
+	// This is a cache hit: the caller should return its empty result without querying the DB
+	ErrNullValue = errors.New("cached null value")
+
+	// ErrTimeout is returned when a cache operation was aborted by a deadline
+	// (context deadline or Read/WriteTimeout) rather than a Redis failure
+	ErrTimeout = errors.New("cache operation timed out")
+
+	// ErrCanceled is returned when a cache operation was aborted by context cancellation
+	ErrCanceled = errors.New("cache operation canceled")
+
+	// ErrPartialWrite is returned when some commands of a write pipeline failed
+	// The written keys are rolled back, so the value must be treated as not cached
+	ErrPartialWrite = errors.New("cache write partially failed")
+
+	// ErrValueSkipped is returned when a value was not cached because it exceeds
+	// LargeValue.SkipCachingAbove; nothing was written
+	ErrValueSkipped = errors.New("cache value skipped due to size")
+
+	// ErrInvalidSnapshot is returned by ImportNamespace for data that is not a complete
+	// snapshot written by ExportNamespace
+	ErrInvalidSnapshot = errors.New("invalid cache snapshot")
+
+	// ErrFlushDisabled is returned by FlushNamespace unless Config.AllowFlushNamespace is set
+	ErrFlushDisabled = errors.New("namespace flush is disabled")
 )
 
 // IsCacheDisabled checks if an error is ErrCacheDisabled
@@ -33,6 +65,67 @@ func IsKeyNotFound(err error) bool {
 	return errors.Is(err, ErrKeyNotFound)
 }
 
+// IsNullValue checks if an error is ErrNullValue
+func IsNullValue(err error) bool {
+	return errors.Is(err, ErrNullValue)
+}
+
+// IsSerializationFailed checks if an error is ErrSerializationFailed
+func IsSerializationFailed(err error) bool {
+	return errors.Is(err, ErrSerializationFailed)
+}
+
+// IsPartialWrite checks if an error is ErrPartialWrite
+func IsPartialWrite(err error) bool {
+	return errors.Is(err, ErrPartialWrite)
+}
+
+// IsValueSkipped checks if an error is ErrValueSkipped
+func IsValueSkipped(err error) bool {
+	return errors.Is(err, ErrValueSkipped)
+}
+
+// IsInvalidSnapshot checks if an error is ErrInvalidSnapshot
+func IsInvalidSnapshot(err error) bool {
+	return errors.Is(err, ErrInvalidSnapshot)
+}
+
+// IsTimeout checks if an error is ErrTimeout
+func IsTimeout(err error) bool {
+	return errors.Is(err, ErrTimeout)
+}
+
+// IsCanceled checks if an error is ErrCanceled
+func IsCanceled(err error) bool {
+	return errors.Is(err, ErrCanceled)
+}
+
+// IsFlushDisabled checks if an error is ErrFlushDisabled
+func IsFlushDisabled(err error) bool {
+	return errors.Is(err, ErrFlushDisabled)
+}
+
+// IsInvalidKey checks if an error is ErrInvalidKey
+func IsInvalidKey(err error) bool {
+	return errors.Is(err, ErrInvalidKey)
+}
+
+// classifyError wraps timeouts with ErrTimeout and cancellations with ErrCanceled
+// Other errors are returned unchanged
+func classifyError(ctx context.Context, err error) error {
+	var netErr net.Error
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, context.DeadlineExceeded) || errors.Is(ctx.Err(), context.DeadlineExceeded) ||
+		(errors.As(err, &netErr) && netErr.Timeout()):
+		return fmt.Errorf("%w: %w", ErrTimeout, err)
+	case errors.Is(err, context.Canceled) || errors.Is(ctx.Err(), context.Canceled):
+		return fmt.Errorf("%w: %w", ErrCanceled, err)
+	}
+	return err
+}
+
 // IsConnectionFailed checks if an error is ErrConnectionFailed
 func IsConnectionFailed(err error) bool {
 	return errors.Is(err, ErrConnectionFailed)
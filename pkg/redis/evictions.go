@@ -0,0 +1,168 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// evictionMonitor tracks the server's evicted_keys counter between checks
+type evictionMonitor struct {
+	mu       sync.Mutex
+	last     uint64
+	baseline bool // last holds a reading
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// newEvictionMonitor creates a monitor; start begins periodic checks
+func newEvictionMonitor() *evictionMonitor {
+	return &evictionMonitor{}
+}
+
+// start checks for evictions every interval until close
+func (e *evictionMonitor) start(manager *Manager, interval time.Duration) {
+	stop := make(chan struct{})
+	e.stop = stop
+	e.done = make(chan struct{})
+	go func() {
+		defer close(e.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				ctx, cancel := context.WithTimeout(context.Background(), interval)
+				if _, err := manager.CheckEvictions(ctx); err != nil {
+					manager.logger.Printf("sql4go: failed to check redis evictions: %v", err)
+				}
+				cancel()
+			}
+		}
+	}()
+}
+
+// close stops periodic checks; it may be called more than once
+func (e *evictionMonitor) close() {
+	e.mu.Lock()
+	stop := e.stop
+	e.stop = nil
+	e.mu.Unlock()
+
+	if stop != nil {
+		close(stop)
+		<-e.done
+	}
+}
+
+// CheckEvictions reads the server's evicted_keys counter and returns the number of keys
+// evicted since the previous check (0 on the first check, which sets the baseline)
+// The growth is added to the ServerEvictions metric and logged as a warning.
+//
+// A Redis server at maxmemory with an LRU/LFU/random eviction policy drops keys one by
+// one, regardless of how sql4go groups them:
+//   - Chunks of a large value: a read finding a chunk missing deletes the remaining
+//     chunks and metadata, so the entry becomes a clean miss (counted as PartialValues).
+//   - Dependency sets: the values they track survive and are no longer invalidated with
+//     their entity. With Invalidation.FallbackOnMissingDependencies, invalidating an
+//     entity whose set is missing wipes the entity's FindByID keys by pattern instead.
+//     Entities that were never cached have no set either, so every such invalidation
+//     costs a SCAN: enable it only while the server evicts.
+//
+// With EvictionCheckInterval set, the manager polls the server's evicted_keys counter
+// (INFO stats, summed over the masters of a cluster), adds its growth to the
+// ServerEvictions metric and logs a warning while it rises. CheckEvictions does the
+// same on demand. Evictions mean the cache no longer fits: raise maxmemory or lower TTLs.
+func (m *Manager) CheckEvictions(ctx context.Context) (uint64, error) {
+	if err := m.checkClient(); err != nil {
+		return 0, err
+	}
+
+	evicted, err := m.serverEvictedKeys(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	monitor := m.evictions
+	monitor.mu.Lock()
+	var delta uint64
+	if monitor.baseline && evicted > monitor.last {
+		delta = evicted - monitor.last
+	}
+	// A lower counter means a restart or CONFIG RESETSTAT: start over from it
+	monitor.last, monitor.baseline = evicted, true
+	monitor.mu.Unlock()
+
+	if delta > 0 {
+		m.metrics.RecordServerEvictions(delta)
+		m.logger.Printf("sql4go: redis evicted %d keys under maxmemory since the last check; cached values may have lost chunks or dependency tracking", delta)
+	}
+	return delta, nil
+}
+
+// serverEvictedKeys returns the evicted_keys counter of the server (or of all cluster masters)
+func (m *Manager) serverEvictedKeys(ctx context.Context) (uint64, error) {
+	if m.clusterClient == nil {
+		info, err := m.client.Info(ctx, "stats").Result()
+		if err != nil {
+			return 0, fmt.Errorf("failed to get redis info: %w", err)
+		}
+		return parseEvictedKeys(info)
+	}
+
+	var mu sync.Mutex
+	var total uint64
+	err := m.clusterClient.ForEachMaster(ctx, func(ctx context.Context, client *redis.Client) error {
+		info, err := client.Info(ctx, "stats").Result()
+		if err != nil {
+			return err
+		}
+		evicted, err := parseEvictedKeys(info)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		total += evicted
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to get redis info: %w", err)
+	}
+	return total, nil
+}
+
+// parseEvictedKeys extracts evicted_keys from the text of INFO stats
+func parseEvictedKeys(info string) (uint64, error) {
+	for _, line := range strings.Split(info, "\n") {
+		if value, ok := strings.CutPrefix(strings.TrimSpace(line), "evicted_keys:"); ok {
+			return strconv.ParseUint(value, 10, 64)
+		}
+	}
+	return 0, fmt.Errorf("evicted_keys not found in redis info")
+}
+
+// invalidateUntrackedEntity wipes the FindByID keys of an entity whose dependency set is
+// missing, e.g. evicted under maxmemory (see Invalidation.FallbackOnMissingDependencies)
+// entityType is the dependency type: the table, or "db:table" with cluster hash tags.
+func (m *Manager) invalidateUntrackedEntity(ctx context.Context, entityType string, entityID interface{}) error {
+	m.metrics.RecordMissingDependencySet()
+
+	id := escapePattern(fmt.Sprintf("%v", entityID))
+	var pattern string
+	if m.HashTagsEnabled() {
+		// "sql4go:v2:{app:users}:{schema}:find_by_id:42"
+		pattern = fmt.Sprintf("%s%s%s%s{%s}%s*%sfind_by_id%s%s", m.entityKeyPrefix(), cacheKeySeparator, cacheHashTagVersion, cacheKeySeparator, escapePattern(entityType), cacheKeySeparator, cacheKeySeparator, cacheKeySeparator, id)
+	} else {
+		// "sql4go:app:users:{schema}:find_by_id:42", the database name is not known here
+		pattern = fmt.Sprintf("%s%s*%s%s%s*%sfind_by_id%s%s", m.entityKeyPrefix(), cacheKeySeparator, cacheKeySeparator, escapePattern(entityType), cacheKeySeparator, cacheKeySeparator, cacheKeySeparator, id)
+	}
+	return m.InvalidatePattern(ctx, pattern)
+}
@@ -0,0 +1,178 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func TestLargeValueWithEvictedChunkIsDiscarded(t *testing.T) {
+	m, mr := newTestManager(t, func(c *Config) {
+		c.LargeValue.EnableCompression = false
+		c.LargeValue.EnableChunking = true
+		c.LargeValue.ChunkSize = 1000
+	})
+	ctx := context.Background()
+
+	report := strings.Repeat("0123456789", 400) // 4000 bytes: metadata and several chunks
+	if err := m.SetLargeValueWithTTL(ctx, "reports:daily", report, time.Hour); err != nil {
+		t.Fatalf("SetLargeValueWithTTL: %v", err)
+	}
+	if keys := mr.Keys(); len(keys) < 3 {
+		t.Fatalf("keys %q, want the value split into chunks", keys)
+	}
+
+	// The server evicts one chunk; the read discards the rest instead of failing until the TTL
+	mr.Del("reports:daily" + cacheChunkPrefix + ":1")
+	var got string
+	if err := m.GetLargeValue(ctx, "reports:daily", &got); !IsKeyNotFound(err) {
+		t.Fatalf("GetLargeValue with a missing chunk = %v, want ErrKeyNotFound", err)
+	}
+	if keys := mr.Keys(); len(keys) != 0 {
+		t.Errorf("keys after the read = %q, want the surviving chunks and metadata deleted", keys)
+	}
+	if partial := m.GetMetrics().PartialValues; partial != 1 {
+		t.Errorf("PartialValues = %d, want 1", partial)
+	}
+}
+
+func TestFallbackOnMissingDependenciesWipesEntityKeys(t *testing.T) {
+	for _, fallback := range []bool{false, true} {
+		t.Run(fmt.Sprintf("fallback=%t", fallback), func(t *testing.T) {
+			m, mr := newTestManager(t, func(c *Config) { c.Invalidation.FallbackOnMissingDependencies = fallback })
+			ctx := context.Background()
+
+			entityKey := "sql4go:app:users:s1:find_by_id:42"
+			unrelated := []string{
+				"sql4go:app:users:s1:find_by_id:421",   // Another id sharing the prefix
+				"sql4go:app:orders:s1:find_by_id:42",   // Another table
+				"sql4go:app:users:s1:find_where:id=42", // Not a FindByID key
+			}
+			for _, key := range append([]string{entityKey}, unrelated...) {
+				mr.Set(key, "x")
+			}
+
+			// The dependency set of users 42 was evicted (or never written)
+			if err := m.InvalidateEntityDependencies(ctx, "users", 42); err != nil {
+				t.Fatalf("InvalidateEntityDependencies: %v", err)
+			}
+			if exists := mr.Exists(entityKey); exists == fallback {
+				t.Errorf("%s exists = %v after the invalidation, want %v", entityKey, exists, !fallback)
+			}
+			for _, key := range unrelated {
+				if !mr.Exists(key) {
+					t.Errorf("%s was wiped with users 42", key)
+				}
+			}
+			want := uint64(0)
+			if fallback {
+				want = 1
+			}
+			if missing := m.GetMetrics().MissingDependencySets; missing != want {
+				t.Errorf("MissingDependencySets = %d, want %d", missing, want)
+			}
+		})
+	}
+}
+
+func TestFallbackOnMissingDependenciesSkipsTrackedEntities(t *testing.T) {
+	m, mr := newTestManager(t, func(c *Config) { c.Invalidation.FallbackOnMissingDependencies = true })
+	ctx := context.Background()
+
+	tracked := "sql4go:app:orders:s1:find_where:user_id=42"
+	if err := m.SetValueWithDependenciesTTL(ctx, tracked, []int{1}, map[string][]interface{}{"users": {42}}, time.Hour); err != nil {
+		t.Fatalf("SetValueWithDependenciesTTL: %v", err)
+	}
+	entityKey := "sql4go:app:users:s1:find_by_id:42"
+	mr.Set(entityKey, "x")
+
+	// The set exists, so its keys are deleted and no pattern wipe runs
+	if err := m.InvalidateEntityDependencies(ctx, "users", 42); err != nil {
+		t.Fatalf("InvalidateEntityDependencies: %v", err)
+	}
+	if mr.Exists(tracked) {
+		t.Errorf("%s survived the invalidation of its dependency", tracked)
+	}
+	if !mr.Exists(entityKey) {
+		t.Errorf("%s was wiped although the dependency set was present", entityKey)
+	}
+	if missing := m.GetMetrics().MissingDependencySets; missing != 0 {
+		t.Errorf("MissingDependencySets = %d, want 0", missing)
+	}
+}
+
+// evictedKeysInfo is a client hook answering INFO with an evicted_keys counter, which
+// miniredis does not report
+type evictedKeysInfo struct {
+	evicted uint64
+}
+
+func (h *evictedKeysInfo) DialHook(next redis.DialHook) redis.DialHook { return next }
+
+func (h *evictedKeysInfo) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if info, ok := cmd.(*redis.StringCmd); ok && cmd.Name() == "info" {
+			info.SetVal(fmt.Sprintf("# Stats\r\ntotal_connections_received:1\r\nevicted_keys:%d\r\n", h.evicted))
+			return nil
+		}
+		return next(ctx, cmd)
+	}
+}
+
+func (h *evictedKeysInfo) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return next
+}
+
+func TestCheckEvictionsReportsGrowth(t *testing.T) {
+	m, _ := newTestManager(t)
+	logger := &recordingLogger{}
+	m.SetLogger(logger)
+	info := &evictedKeysInfo{evicted: 5}
+	m.client.AddHook(info)
+	ctx := context.Background()
+
+	steps := []struct {
+		evicted uint64
+		want    uint64
+	}{
+		{5, 0},  // The first check sets the baseline
+		{5, 0},  // No growth
+		{12, 7}, // Growth since the previous check
+		{3, 0},  // A lower counter (restart, CONFIG RESETSTAT) is the new baseline
+		{4, 1},
+	}
+	for i, step := range steps {
+		info.evicted = step.evicted
+		delta, err := m.CheckEvictions(ctx)
+		if err != nil {
+			t.Fatalf("check %d: CheckEvictions: %v", i, err)
+		}
+		if delta != step.want {
+			t.Errorf("check %d: CheckEvictions at evicted_keys %d = %d, want %d", i, step.evicted, delta, step.want)
+		}
+	}
+
+	if evictions := m.GetMetrics().ServerEvictions; evictions != 8 {
+		t.Errorf("ServerEvictions = %d, want 8", evictions)
+	}
+	warnings := slices.DeleteFunc(logger.logged(), func(message string) bool {
+		return !strings.Contains(message, "evicted")
+	})
+	if len(warnings) != 2 || !strings.Contains(warnings[0], "evicted 7 keys") {
+		t.Errorf("warnings = %q, want one per check with growth", warnings)
+	}
+}
+
+func TestParseEvictedKeys(t *testing.T) {
+	if evicted, err := parseEvictedKeys("# Stats\r\nexpired_keys:3\r\nevicted_keys:17\r\n"); err != nil || evicted != 17 {
+		t.Errorf("parseEvictedKeys = (%d, %v), want 17", evicted, err)
+	}
+	if _, err := parseEvictedKeys("# Stats\r\nexpired_keys:3\r\n"); err == nil {
+		t.Error("parseEvictedKeys without evicted_keys succeeded")
+	}
+}
@@ -0,0 +1,37 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+)
+
+// FlushNamespace deletes every key this manager's namespace owns, for admin and debug
+// tooling: repository cache keys ("sql4go[:ns]:*") and the manager's own keys
+// ("gensql4go[:ns]:*": dependency sets, dictionaries, key-value cache, locks, ...).
+// Keys are found with SCAN and deleted in batches like InvalidatePattern; FLUSHDB is
+// never used, so unrelated data in the same database survives. Without a Namespace the
+// patterns also match the keys of namespaced managers sharing the server. On error, the
+// keys deleted before it stay deleted.
+//
+// The call is refused with ErrFlushDisabled unless Config.AllowFlushNamespace is set,
+// so an admin endpoint wired up by mistake cannot wipe a production cache.
+func (m *Manager) FlushNamespace(ctx context.Context) error {
+	if !m.config.AllowFlushNamespace {
+		return ErrFlushDisabled
+	}
+	if err := m.checkClient(); err != nil {
+		return err
+	}
+
+	var deleted int64
+	for _, pattern := range []string{m.entityKeyPrefix() + cacheKeySeparator + "*", m.keyPrefix() + cacheKeySeparator + "*"} {
+		count, err := m.InvalidatePatternCount(ctx, pattern)
+		deleted += count
+		if err != nil {
+			return fmt.Errorf("failed to flush namespace after %d keys: %w", deleted, err)
+		}
+	}
+
+	m.Logger().Printf("sql4go: flushed %d keys of namespace %q", deleted, m.config.Namespace)
+	return nil
+}
@@ -0,0 +1,46 @@
+package redis
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestFlushNamespaceRemovesOnlyNamespacedKeys(t *testing.T) {
+	m, mr := newTestManager(t, func(c *Config) { c.Namespace = "shop" })
+	ctx := context.Background()
+
+	owned := []string{
+		"sql4go:shop:app:users:s1:find_by_id:1",
+		"gensql4go:shop:deps:app:users:1",
+		"gensql4go:shop:kv:sessions:abc",
+	}
+	unrelated := []string{
+		"session:abc",
+		"sql4go:other:app:users:s1:find_by_id:1", // Another namespace on the same server
+		"gensql4go:other:kv:sessions:abc",
+		"sql4gox:shop:1",
+	}
+	for _, key := range append(slices.Clone(owned), unrelated...) {
+		mr.Set(key, "x")
+	}
+
+	// Refused unless enabled
+	if err := m.FlushNamespace(ctx); !IsFlushDisabled(err) {
+		t.Fatalf("FlushNamespace without AllowFlushNamespace = %v, want ErrFlushDisabled", err)
+	}
+	if len(mr.Keys()) != len(owned)+len(unrelated) {
+		t.Fatalf("a refused flush deleted keys, left %v", mr.Keys())
+	}
+
+	m.config.AllowFlushNamespace = true
+	if err := m.FlushNamespace(ctx); err != nil {
+		t.Fatalf("FlushNamespace: %v", err)
+	}
+	remaining := mr.Keys()
+	slices.Sort(remaining)
+	slices.Sort(unrelated)
+	if !slices.Equal(remaining, unrelated) {
+		t.Errorf("keys after the flush = %v, want only %v", remaining, unrelated)
+	}
+}
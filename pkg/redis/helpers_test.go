@@ -0,0 +1,126 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// newTestManager returns a manager connected to a fresh miniredis server
+func newTestManager(t testing.TB, configure ...func(*Config)) (*Manager, *miniredis.Miniredis) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	config := DefaultConfig()
+	config.Host = mr.Host()
+	config.Port, _ = strconv.Atoi(mr.Port())
+	config.MinIdleConns = 0
+	for _, fn := range configure {
+		fn(config)
+	}
+
+	manager, err := NewManager(config)
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+	t.Cleanup(func() { _ = manager.Close() })
+	return manager, mr
+}
+
+// waitFor polls cond every few milliseconds until it holds or timeout elapses
+func waitFor(t *testing.T, timeout time.Duration, cond func() bool) bool {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return true
+}
+
+// recordingLogger collects the messages logged by a manager
+type recordingLogger struct {
+	mu       sync.Mutex
+	messages []string
+}
+
+func (l *recordingLogger) Printf(format string, args ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.messages = append(l.messages, fmt.Sprintf(format, args...))
+}
+
+func (l *recordingLogger) logged() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string(nil), l.messages...)
+}
+
+// errInjected is the error of commands failed by a failingCommands hook
+var errInjected = errors.New("injected failure")
+
+// failingCommands is a client hook that fails the commands matching fail instead of
+// sending them, so the rest of a pipeline still lands: a partially failed write
+type failingCommands struct {
+	fail func(cmd redis.Cmder) bool
+	err  error // errInjected if nil
+}
+
+// failCommands installs a hook failing the commands named name (e.g. "sadd") whose
+// first key contains keyPart
+func failCommands(m *Manager, name, keyPart string) {
+	m.client.AddHook(&failingCommands{fail: func(cmd redis.Cmder) bool {
+		args := cmd.Args()
+		return cmd.Name() == name && len(args) > 1 && strings.Contains(fmt.Sprint(args[1]), keyPart)
+	}})
+}
+
+func (h *failingCommands) DialHook(next redis.DialHook) redis.DialHook { return next }
+
+func (h *failingCommands) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if h.fail(cmd) {
+			cmd.SetErr(h.error())
+			return h.error()
+		}
+		return next(ctx, cmd)
+	}
+}
+
+func (h *failingCommands) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		var sent []redis.Cmder
+		var failed error
+		for _, cmd := range cmds {
+			if h.fail(cmd) {
+				cmd.SetErr(h.error())
+				failed = h.error()
+				continue
+			}
+			sent = append(sent, cmd)
+		}
+		if len(sent) > 0 {
+			if err := next(ctx, sent); err != nil {
+				return err
+			}
+		}
+		return failed
+	}
+}
+
+func (h *failingCommands) error() error {
+	if h.err == nil {
+		return errInjected
+	}
+	return h.err
+}
@@ -0,0 +1,145 @@
+package redis
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// HotKey is an approximate read count for a cache key prefix
+type HotKey struct {
+	Prefix string // Key truncated after the operation segment, e.g. "sql4go:app:users:1a2b3c4d:find_by_id"
+	Count  uint64 // Estimated reads (sampled count scaled by the sample rate)
+}
+
+// hotKeyTracker keeps approximate heavy hitters of sampled Get keys
+// It uses the Space-Saving algorithm: a fixed number of counters, where a new prefix
+// replaces the least-read one and inherits its count. Counts are overestimates by at
+// most the replaced count, and the most-read prefixes are always retained.
+type hotKeyTracker struct {
+	sampleRate     uint64
+	capacity       int
+	prefixSegments int
+
+	sampled atomic.Uint64 // Get calls seen, for 1-in-sampleRate sampling
+
+	mu     sync.Mutex
+	counts map[string]uint64
+}
+
+// newHotKeyTracker creates a tracker from the hot key configuration of a (namespaced) manager
+func newHotKeyTracker(config HotKeyConfig, namespace string) *hotKeyTracker {
+	sampleRate := config.SampleRate
+	if sampleRate < 1 {
+		sampleRate = 1
+	}
+	capacity := config.Capacity
+	if capacity < 1 {
+		capacity = 64
+	}
+	prefixSegments := config.PrefixSegments
+	if prefixSegments < 1 {
+		prefixSegments = 5
+	}
+	if namespace != "" {
+		prefixSegments++ // The namespace segment follows the key prefix
+	}
+
+	return &hotKeyTracker{
+		sampleRate:     uint64(sampleRate),
+		capacity:       capacity,
+		prefixSegments: prefixSegments,
+		counts:         make(map[string]uint64, capacity),
+	}
+}
+
+// record counts a read of key if it is sampled
+func (t *hotKeyTracker) record(key string) {
+	if t.sampled.Add(1)%t.sampleRate != 0 {
+		return
+	}
+
+	prefix := keyPrefix(key, t.prefixSegments)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, tracked := t.counts[prefix]; tracked || len(t.counts) < t.capacity {
+		t.counts[prefix]++
+		return
+	}
+
+	// Replace the least-read prefix, inheriting its count
+	minPrefix, minCount := "", uint64(0)
+	for p, count := range t.counts {
+		if minPrefix == "" || count < minCount {
+			minPrefix, minCount = p, count
+		}
+	}
+	delete(t.counts, minPrefix)
+	t.counts[prefix] = minCount + 1
+}
+
+// top returns the n most-read prefixes, most-read first
+func (t *hotKeyTracker) top(n int) []HotKey {
+	t.mu.Lock()
+	hotKeys := make([]HotKey, 0, len(t.counts))
+	for prefix, count := range t.counts {
+		hotKeys = append(hotKeys, HotKey{Prefix: prefix, Count: count * t.sampleRate})
+	}
+	t.mu.Unlock()
+
+	sort.Slice(hotKeys, func(i, j int) bool {
+		if hotKeys[i].Count != hotKeys[j].Count {
+			return hotKeys[i].Count > hotKeys[j].Count
+		}
+		return hotKeys[i].Prefix < hotKeys[j].Prefix
+	})
+
+	if n >= 0 && n < len(hotKeys) {
+		hotKeys = hotKeys[:n]
+	}
+	return hotKeys
+}
+
+// reset clears all counters
+func (t *hotKeyTracker) reset() {
+	t.mu.Lock()
+	t.counts = make(map[string]uint64, t.capacity)
+	t.mu.Unlock()
+}
+
+// keyPrefix truncates key after its first segments, keeping report cardinality low
+// Separators inside a {hash tag} do not count, so "sql4go:app:users:1a2b3c4d:find_by_id:42"
+// and "sql4go:v2:{app:users}:1a2b3c4d:find_by_id:42" are both cut after the operation segment
+func keyPrefix(key string, segments int) string {
+	depth := 0
+	for i := 0; i < len(key); i++ {
+		switch key[i] {
+		case '{':
+			depth++
+		case '}':
+			if depth > 0 {
+				depth--
+			}
+		case cacheKeySeparator[0]:
+			if depth > 0 {
+				continue
+			}
+			segments--
+			if segments == 0 {
+				return key[:i]
+			}
+		}
+	}
+	return key
+}
+
+// TopKeys returns the n most-read cache key prefixes since the last ResetMetrics
+// Counts are estimates from sampled reads. Returns nil if hot key tracking is disabled.
+func (m *Manager) TopKeys(n int) []HotKey {
+	if m.hotKeys == nil {
+		return nil
+	}
+	return m.hotKeys.top(n)
+}
@@ -0,0 +1,98 @@
+package redis
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestKeyPrefix(t *testing.T) {
+	tests := []struct {
+		key      string
+		segments int
+		want     string
+	}{
+		{"sql4go:app:users:1a2b3c4d:find_by_id:42", 5, "sql4go:app:users:1a2b3c4d:find_by_id"},
+		{"sql4go:v2:{app:users}:1a2b3c4d:find_by_id:42", 5, "sql4go:v2:{app:users}:1a2b3c4d:find_by_id"},
+		{"sql4go:tenant:app:users:1a2b3c4d:find_where:name=a:b", 6, "sql4go:tenant:app:users:1a2b3c4d:find_where"},
+		{"sessions:abc", 5, "sessions:abc"},
+	}
+	for _, tt := range tests {
+		if got := keyPrefix(tt.key, tt.segments); got != tt.want {
+			t.Errorf("keyPrefix(%q, %d) = %q, want %q", tt.key, tt.segments, got, tt.want)
+		}
+	}
+}
+
+func TestTopKeysOrdersByReads(t *testing.T) {
+	m, mr := newTestManager(t, func(c *Config) { c.HotKeys = HotKeyConfig{Enabled: true, SampleRate: 1} })
+	ctx := context.Background()
+
+	reads := map[string]int{
+		"sql4go:app:orders:s1:find_by_id:1":   5,
+		"sql4go:app:users:s1:find_by_id:1":    1,
+		"sql4go:app:users:s1:find_by_id:2":    2, // Same prefix as users 1
+		"sql4go:v2:{app:carts}:s1:find_all":   1,
+		"sql4go:v2:{app:carts}:s1:find_all:x": 1, // The separator in the hash tag does not count
+	}
+	for key, count := range reads {
+		mr.Set(key, "x")
+		for i := 0; i < count; i++ {
+			if _, err := m.Get(ctx, key); err != nil {
+				t.Fatalf("Get(%s): %v", key, err)
+			}
+		}
+	}
+
+	want := []HotKey{
+		{Prefix: "sql4go:app:orders:s1:find_by_id", Count: 5},
+		{Prefix: "sql4go:app:users:s1:find_by_id", Count: 3},
+		{Prefix: "sql4go:v2:{app:carts}:s1:find_all", Count: 2},
+	}
+	if got := m.TopKeys(-1); !slices.Equal(got, want) {
+		t.Errorf("TopKeys(-1) = %+v, want %+v", got, want)
+	}
+	if got := m.TopKeys(2); !slices.Equal(got, want[:2]) {
+		t.Errorf("TopKeys(2) = %+v, want %+v", got, want[:2])
+	}
+
+	// ResetMetrics starts the counts over
+	m.ResetMetrics()
+	if got := m.TopKeys(-1); len(got) != 0 {
+		t.Errorf("TopKeys after ResetMetrics = %+v, want none", got)
+	}
+	if _, err := m.Get(ctx, "sql4go:app:users:s1:find_by_id:1"); err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if got := m.TopKeys(-1); len(got) != 1 || got[0].Count != 1 {
+		t.Errorf("TopKeys after a read = %+v, want one prefix read once", got)
+	}
+}
+
+func TestHotKeyTrackerSamplingAndCapacity(t *testing.T) {
+	tracker := newHotKeyTracker(HotKeyConfig{SampleRate: 2, Capacity: 2}, "")
+	for i := 0; i < 8; i++ {
+		tracker.record("sql4go:app:users:s1:find_by_id:1")
+	}
+	for i := 0; i < 4; i++ {
+		tracker.record("sql4go:app:orders:s1:find_by_id:1")
+	}
+	// A third prefix replaces the least-read one and inherits its count
+	tracker.record("sql4go:app:carts:s1:find_by_id:1")
+	tracker.record("sql4go:app:carts:s1:find_by_id:1")
+
+	want := []HotKey{
+		{Prefix: "sql4go:app:users:s1:find_by_id", Count: 8}, // 4 sampled reads of 8
+		{Prefix: "sql4go:app:carts:s1:find_by_id", Count: 6}, // Orders' 2 plus its own sampled read
+	}
+	if got := tracker.top(-1); !slices.Equal(got, want) {
+		t.Errorf("top = %+v, want %+v", got, want)
+	}
+}
+
+func TestTopKeysDisabled(t *testing.T) {
+	m, _ := newTestManager(t)
+	if got := m.TopKeys(10); got != nil {
+		t.Errorf("TopKeys without hot key tracking = %+v, want nil", got)
+	}
+}
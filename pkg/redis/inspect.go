@@ -0,0 +1,179 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// KeyInfo is the remaining TTL and sampled memory usage of a cache key
+type KeyInfo struct {
+	Key         string
+	TTL         time.Duration // Remaining TTL; -1 if the key has no expiry, -2 if it no longer exists
+	MemoryBytes int64         // MEMORY USAGE of the key; -1 if not sampled
+}
+
+// ScanKeys returns the keys matching a pattern using SCAN
+// At most limit keys are returned (0 = no limit); truncated reports that the scan
+// stopped early and more keys may match.
+func (m *Manager) ScanKeys(ctx context.Context, pattern string, limit int) (keys []string, truncated bool, err error) {
+	if err := m.checkClient(); err != nil {
+		return nil, false, err
+	}
+
+	const scanBatchSize = 100
+	var cursor uint64
+	for {
+		var batch []string
+		batch, cursor, err = m.client.Scan(ctx, cursor, pattern, scanBatchSize).Result()
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to scan keys with pattern %s: %w", pattern, err)
+		}
+
+		keys = append(keys, batch...)
+		if limit > 0 && len(keys) >= limit {
+			return keys[:limit], cursor != 0 || len(keys) > limit, nil
+		}
+		if cursor == 0 {
+			return keys, false, nil
+		}
+	}
+}
+
+// InspectKeys returns the remaining TTL of each key and, for every sampleEvery-th key,
+// its memory usage, in one pipeline. sampleEvery < 1 samples every key.
+func (m *Manager) InspectKeys(ctx context.Context, keys []string, sampleEvery int) ([]KeyInfo, error) {
+	if err := m.checkClient(); err != nil {
+		return nil, err
+	}
+	if sampleEvery < 1 {
+		sampleEvery = 1
+	}
+
+	pipe := m.client.Pipeline()
+	ttls := make([]*redis.DurationCmd, len(keys))
+	memory := make([]*redis.IntCmd, len(keys))
+	for i, key := range keys {
+		ttls[i] = pipe.PTTL(ctx, key)
+		if i%sampleEvery == 0 {
+			memory[i] = pipe.MemoryUsage(ctx, key)
+		}
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, fmt.Errorf("failed to inspect keys: %w", m.recordError(ctx, err))
+	}
+
+	infos := make([]KeyInfo, len(keys))
+	for i, key := range keys {
+		infos[i] = KeyInfo{Key: key, TTL: ttls[i].Val(), MemoryBytes: -1}
+		if memory[i] != nil && memory[i].Err() == nil {
+			infos[i].MemoryBytes = memory[i].Val()
+		}
+	}
+	return infos, nil
+}
+
+const (
+	maxTTLReportSample    = 10000 // Keys inspected by TTLReport at most
+	maxTTLReportScanCalls = 1000  // SCAN calls of TTLReport at most (sparse patterns)
+	ttlReportScanCount    = 1000  // SCAN COUNT hint of TTLReport
+	ttlReportBatchSize    = 500   // Keys inspected per pipeline
+)
+
+// ttlReportBounds are the upper bounds of the TTLReport buckets
+var ttlReportBounds = []time.Duration{
+	time.Minute,
+	5 * time.Minute,
+	15 * time.Minute,
+	time.Hour,
+	6 * time.Hour,
+	24 * time.Hour,
+}
+
+// TTLHistogram is the remaining-TTL distribution of a sample of keys
+type TTLHistogram struct {
+	Pattern    string
+	Keys       int         // Keys sampled (excluding keys that expired while sampled)
+	TotalBytes int64       // MEMORY USAGE of the sampled keys
+	Truncated  bool        // The scan stopped at the sample size or scan limit; more keys may match
+	NoExpiry   TTLBucket   // Keys without a TTL
+	Buckets    []TTLBucket // Keys with a TTL, by remaining TTL
+}
+
+// TTLBucket counts the keys of one TTL range and their memory usage
+type TTLBucket struct {
+	UpperBound time.Duration // Inclusive upper bound of the remaining TTL; 0 for the last bucket (longer TTLs)
+	Keys       int
+	Bytes      int64
+}
+
+// TTLReport samples up to sampleSize keys matching pattern and returns the distribution
+// of their remaining TTLs and memory usage, e.g. to size Redis memory or see how much of
+// the keyspace is about to expire
+// The work is bounded for production use: keys are found with SCAN, at most 10000 keys
+// are sampled (sampleSize <= 0 samples the maximum) and the scan stops after 1000 SCAN
+// calls even if fewer keys matched. Keys are sampled in SCAN order, not uniformly.
+func (m *Manager) TTLReport(ctx context.Context, pattern string, sampleSize int) (TTLHistogram, error) {
+	report := TTLHistogram{Pattern: pattern, Buckets: make([]TTLBucket, len(ttlReportBounds)+1)}
+	for i, bound := range ttlReportBounds {
+		report.Buckets[i].UpperBound = bound
+	}
+	if err := m.checkClient(); err != nil {
+		return report, err
+	}
+	if sampleSize <= 0 || sampleSize > maxTTLReportSample {
+		sampleSize = maxTTLReportSample
+	}
+
+	// Bounded SCAN: stop at the sample size or the scan call limit
+	var keys []string
+	var cursor uint64
+	for calls := 0; ; calls++ {
+		if calls == maxTTLReportScanCalls {
+			report.Truncated = true
+			break
+		}
+		batch, next, err := m.client.Scan(ctx, cursor, pattern, ttlReportScanCount).Result()
+		if err != nil {
+			return report, fmt.Errorf("failed to scan keys with pattern %s: %w", pattern, err)
+		}
+		keys = append(keys, batch...)
+		cursor = next
+		if len(keys) >= sampleSize {
+			report.Truncated = cursor != 0 || len(keys) > sampleSize
+			keys = keys[:sampleSize]
+			break
+		}
+		if cursor == 0 {
+			break
+		}
+	}
+
+	for start := 0; start < len(keys); start += ttlReportBatchSize {
+		infos, err := m.InspectKeys(ctx, keys[start:min(start+ttlReportBatchSize, len(keys))], 1)
+		if err != nil {
+			return report, err
+		}
+		for _, info := range infos {
+			bucket := &report.NoExpiry
+			switch {
+			case info.TTL == -2:
+				continue // Expired while sampled
+			case info.TTL >= 0:
+				index := sort.Search(len(ttlReportBounds), func(i int) bool { return info.TTL <= ttlReportBounds[i] })
+				bucket = &report.Buckets[index]
+			}
+
+			bucket.Keys++
+			report.Keys++
+			if info.MemoryBytes > 0 {
+				bucket.Bytes += info.MemoryBytes
+				report.TotalBytes += info.MemoryBytes
+			}
+		}
+	}
+	return report, nil
+}
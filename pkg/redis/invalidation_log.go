@@ -0,0 +1,83 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// invalidationLogPrefix is the key segment of the invalidation log hashes
+const invalidationLogPrefix = "invalidations"
+
+// TableInvalidation is the last table-level invalidation of a table
+type TableInvalidation struct {
+	Time      time.Time `json:"time"`
+	Operation string    `json:"operation"` // create, update, delete, invalidate_cache, ...
+	Host      string    `json:"host"`      // Hostname of the invalidating process
+}
+
+// invalidationHost is the hostname recorded with invalidations
+var invalidationHost = sync.OnceValue(func() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		return "unknown"
+	}
+	return host
+})
+
+// invalidationLogKey returns the key of the invalidation log hash of a database
+func (m *Manager) invalidationLogKey(dbName string) string {
+	return m.keyPrefix() + cacheKeySeparator + invalidationLogPrefix + cacheKeySeparator + dbName
+}
+
+// RecordTableInvalidation records a table-level invalidation of dbName.table in the
+// invalidation log, replacing the table's previous entry
+//
+// When chasing a stale data report, the first question is when the table's cache was
+// last wiped, and by what. Repositories record every table-level invalidation in one
+// hash per database ("gensql4go:invalidations:app", namespaced like the manager's other
+// keys) holding a field per table with the time, the operation and the host. Each table
+// overwrites its own field, so the hash never grows beyond the number of tables. It lives
+// outside the repository keys ("sql4go:*"), so table and database wipes never match it
+// and cache snapshots, which hold only strings there, skip it.
+func (m *Manager) RecordTableInvalidation(ctx context.Context, dbName, table, operation string) error {
+	if err := m.checkClient(); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(TableInvalidation{Time: time.Now(), Operation: operation, Host: invalidationHost()})
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrSerializationFailed, err)
+	}
+	if err := m.client.HSet(ctx, m.invalidationLogKey(dbName), table, data).Err(); err != nil {
+		return fmt.Errorf("failed to record invalidation of %s.%s: %w", dbName, table, classifyError(ctx, err))
+	}
+	return nil
+}
+
+// LastTableInvalidation returns the last recorded table-level invalidation of
+// dbName.table, or ErrKeyNotFound if none was recorded
+func (m *Manager) LastTableInvalidation(ctx context.Context, dbName, table string) (TableInvalidation, error) {
+	if err := m.checkClient(); err != nil {
+		return TableInvalidation{}, err
+	}
+
+	data, err := m.client.HGet(ctx, m.invalidationLogKey(dbName), table).Bytes()
+	if err == redis.Nil {
+		return TableInvalidation{}, ErrKeyNotFound
+	}
+	if err != nil {
+		return TableInvalidation{}, fmt.Errorf("failed to read invalidation of %s.%s: %w", dbName, table, classifyError(ctx, err))
+	}
+
+	var record TableInvalidation
+	if err := json.Unmarshal(data, &record); err != nil {
+		return TableInvalidation{}, fmt.Errorf("%w: %w", ErrSerializationFailed, err)
+	}
+	return record, nil
+}
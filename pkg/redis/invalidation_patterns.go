@@ -0,0 +1,159 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Invalidation pattern placeholders
+const (
+	patternPrefixPlaceholder = "{prefix}"
+	patternDBPlaceholder     = "{db}"
+	patternTablePlaceholder  = "{table}"
+	patternIDPlaceholder     = "{id}"
+)
+
+// patternPlaceholderRegexp matches placeholder-like words in braces; hash tags such as
+// "{app:orders}" contain a separator and are not matched
+var patternPlaceholderRegexp = regexp.MustCompile(`\{[A-Za-z_]+\}`)
+
+// validateInvalidationPattern checks that a pattern only uses known placeholders and
+// can only match repository cache keys
+func validateInvalidationPattern(pattern string) error {
+	if pattern == "" {
+		return fmt.Errorf("pattern cannot be empty")
+	}
+	for _, placeholder := range patternPlaceholderRegexp.FindAllString(pattern, -1) {
+		switch placeholder {
+		case patternPrefixPlaceholder, patternDBPlaceholder, patternTablePlaceholder, patternIDPlaceholder:
+		default:
+			return fmt.Errorf("pattern %q has unknown placeholder %s ({prefix}, {db}, {table} or {id})", pattern, placeholder)
+		}
+	}
+	if strings.Contains(pattern, "_internal") {
+		return fmt.Errorf("pattern %q targets internal keys", pattern)
+	}
+	if !strings.HasPrefix(pattern, patternPrefixPlaceholder+cacheKeySeparator) &&
+		!strings.HasPrefix(pattern, entityKeyPrefix+cacheKeySeparator) {
+		return fmt.Errorf("pattern %q must start with %s%s or %s%s, so it cannot match internal keys", pattern, patternPrefixPlaceholder, cacheKeySeparator, entityKeyPrefix, cacheKeySeparator)
+	}
+	return nil
+}
+
+// expandInvalidationPattern resolves the placeholders of a pattern
+func expandInvalidationPattern(pattern, prefix, dbName, tableName string, entityID interface{}) string {
+	return strings.NewReplacer(
+		patternPrefixPlaceholder, prefix,
+		patternDBPlaceholder, dbName,
+		patternTablePlaceholder, tableName,
+		patternIDPlaceholder, fmt.Sprintf("%v", entityID),
+	).Replace(pattern)
+}
+
+// invalidationPatterns holds the patterns registered at runtime (table -> patterns)
+type invalidationPatterns struct {
+	mu       sync.RWMutex
+	byEntity map[string][]string
+}
+
+// RegisterInvalidationPattern adds a pattern wiped whenever an entity of entityType (a
+// table name) is invalidated, in addition to Invalidation.KeyPatterns; for relationship
+// topologies discovered at startup. The pattern is validated as the configured ones are;
+// an invalid pattern is rejected with an error wrapping ErrInvalidKey. Registering the
+// same pattern twice has no effect.
+//
+// Invalidation.KeyPatterns and RegisterInvalidationPattern add glob patterns wiped
+// whenever an entity of a table is invalidated by a repository. Patterns are written
+// against the repository key layout with placeholders resolved at invalidation time:
+//   - {prefix}: the repository key prefix, including the namespace ("sql4go:tenant")
+//   - {db}, {table}: database and table of the invalidated entity
+//   - {id}: its primary key
+//
+// e.g. "{prefix}:{db}:orders:*:find_where:*{id}*" wipes the order queries mentioning a
+// user when that user is written. With Cluster.HashTags, keys carry the table in braces:
+// "{prefix}:v2:{{db}:orders}:*".
+//
+// Patterns are confined to repository keys: they must start with {prefix} or the
+// literal repository prefix, and may not name internal keys ("_internal" suffixes, the
+// manager's own dependency sets and dictionaries). Keys matched by a trailing * still
+// include the metadata and chunks of the large values they match, as for table wipes.
+func (m *Manager) RegisterInvalidationPattern(entityType, pattern string) error {
+	if entityType == "" {
+		return fmt.Errorf("%w: entity type is required", ErrInvalidKey)
+	}
+	if err := validateInvalidationPattern(pattern); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidKey, err)
+	}
+
+	m.patterns.mu.Lock()
+	defer m.patterns.mu.Unlock()
+
+	for _, existing := range m.patterns.byEntity[entityType] {
+		if existing == pattern {
+			return nil
+		}
+	}
+	if m.patterns.byEntity == nil {
+		m.patterns.byEntity = make(map[string][]string)
+	}
+	m.patterns.byEntity[entityType] = append(m.patterns.byEntity[entityType], pattern)
+	return nil
+}
+
+// InvalidationPatterns returns the configured and registered patterns of an entity type
+// with their placeholders resolved for an entity
+func (m *Manager) InvalidationPatterns(dbName, entityType string, entityID interface{}) []string {
+	configured := m.config.Invalidation.KeyPatterns[entityType]
+
+	m.patterns.mu.RLock()
+	registered := m.patterns.byEntity[entityType]
+	m.patterns.mu.RUnlock()
+
+	return expandInvalidationPatterns(append(configured[:len(configured):len(configured)], registered...), m.entityKeyPrefix(), dbName, entityType, entityID)
+}
+
+// InvalidationPatterns returns the Invalidation.KeyPatterns of an entity type with their
+// placeholders resolved for an entity, for caches other than the Manager (which also
+// resolves the patterns registered with RegisterInvalidationPattern)
+func (c *Config) InvalidationPatterns(dbName, entityType string, entityID interface{}) []string {
+	prefix := entityKeyPrefix
+	if c.Namespace != "" {
+		prefix += cacheKeySeparator + c.Namespace
+	}
+	return expandInvalidationPatterns(c.Invalidation.KeyPatterns[entityType], prefix, dbName, entityType, entityID)
+}
+
+// expandInvalidationPatterns resolves the placeholders of patterns; nil for none
+func expandInvalidationPatterns(patterns []string, prefix, dbName, tableName string, entityID interface{}) []string {
+	if len(patterns) == 0 {
+		return nil
+	}
+
+	expanded := make([]string, 0, len(patterns))
+	for _, pattern := range patterns {
+		expanded = append(expanded, expandInvalidationPattern(pattern, prefix, dbName, tableName, entityID))
+	}
+	return expanded
+}
+
+// InvalidateKeyPatterns wipes the invalidation patterns of an entity (see
+// InvalidationPatterns), coalesced like table wipes (see InvalidatePatternCoalesced)
+// Every pattern is attempted; the errors are joined.
+func (m *Manager) InvalidateKeyPatterns(ctx context.Context, dbName, entityType string, entityID interface{}) error {
+	patterns := m.InvalidationPatterns(dbName, entityType, entityID)
+	if len(patterns) == 0 {
+		return nil
+	}
+
+	var errs []error
+	for _, pattern := range patterns {
+		if err := m.InvalidatePatternCoalesced(ctx, pattern); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,106 @@
+package redis
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestValidateInvalidationPattern(t *testing.T) {
+	valid := []string{
+		"{prefix}:{db}:orders:*:find_where:*{id}*",
+		"{prefix}:{db}:{table}:*:find_all",
+		"{prefix}:v2:{{db}:orders}:*", // Hash tags are not placeholders
+		"sql4go:app:orders:*",
+	}
+	for _, pattern := range valid {
+		if err := validateInvalidationPattern(pattern); err != nil {
+			t.Errorf("validateInvalidationPattern(%q) = %v, want nil", pattern, err)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"{prefix}:{db}:orders:*_internal:chunk*", // Chunks of large values
+		"{prefix}:{db}:orders:*:_internal:lock",
+		"sql4go:app:orders:*_internal*",
+		"{prefix}:{tenant}:orders:*", // Unknown placeholder
+		"gensql4go:deps:*",           // The manager's dependency sets
+		"*:orders:*",
+		"orders:*",
+	}
+	for _, pattern := range invalid {
+		if err := validateInvalidationPattern(pattern); err == nil {
+			t.Errorf("validateInvalidationPattern(%q) = nil, want an error", pattern)
+		}
+	}
+}
+
+func TestConfigRejectsInternalKeyPatterns(t *testing.T) {
+	config := DefaultConfig()
+	config.Invalidation.KeyPatterns = map[string][]string{"users": {"{prefix}:{db}:orders:*_internal*"}}
+	if err := config.Validate(); err == nil {
+		t.Error("Validate accepted a key pattern naming internal keys")
+	}
+}
+
+func TestInvalidationPatternsExpandPlaceholders(t *testing.T) {
+	m, _ := newTestManager(t, func(c *Config) {
+		c.Namespace = "tenant"
+		c.Invalidation.KeyPatterns = map[string][]string{"users": {"{prefix}:{db}:orders:*:find_where:*{id}*"}}
+	})
+
+	if err := m.RegisterInvalidationPattern("users", "{prefix}:{db}:{table}:*:find_all"); err != nil {
+		t.Fatalf("RegisterInvalidationPattern: %v", err)
+	}
+	if err := m.RegisterInvalidationPattern("users", "{prefix}:{db}:{table}:*:find_all"); err != nil {
+		t.Fatalf("registering a pattern again: %v", err)
+	}
+	if err := m.RegisterInvalidationPattern("users", "{prefix}:{db}:{table}:*_internal*"); !IsInvalidKey(err) {
+		t.Errorf("RegisterInvalidationPattern of internal keys = %v, want ErrInvalidKey", err)
+	}
+
+	want := []string{
+		"sql4go:tenant:app:orders:*:find_where:*42*",
+		"sql4go:tenant:app:users:*:find_all",
+	}
+	if got := m.InvalidationPatterns("app", "users", 42); !slices.Equal(got, want) {
+		t.Errorf("InvalidationPatterns = %q, want %q", got, want)
+	}
+	if got := m.InvalidationPatterns("app", "orders", 42); got != nil {
+		t.Errorf("InvalidationPatterns of a table without patterns = %q, want none", got)
+	}
+
+	// Caches other than the manager only see the configured patterns
+	if got := m.Config().InvalidationPatterns("app", "users", 42); !slices.Equal(got, want[:1]) {
+		t.Errorf("Config.InvalidationPatterns = %q, want %q", got, want[:1])
+	}
+}
+
+func TestInvalidateKeyPatternsWipesMatchingKeys(t *testing.T) {
+	m, mr := newTestManager(t, func(c *Config) {
+		c.Invalidation.KeyPatterns = map[string][]string{"users": {"{prefix}:{db}:orders:*:find_where:*{id}*"}}
+	})
+
+	wiped := "sql4go:app:orders:s1:find_where:user_id=42"
+	kept := []string{
+		"sql4go:app:orders:s1:find_where:user_id=7",
+		"sql4go:app:orders:s1:find_by_id:42",
+		"sql4go:other:orders:s1:find_where:user_id=42",
+	}
+	for _, key := range append([]string{wiped}, kept...) {
+		mr.Set(key, "x")
+	}
+
+	if err := m.InvalidateKeyPatterns(context.Background(), "app", "users", 42); err != nil {
+		t.Fatalf("InvalidateKeyPatterns: %v", err)
+	}
+	if mr.Exists(wiped) {
+		t.Errorf("%s survived the invalidation of user 42", wiped)
+	}
+	for _, key := range kept {
+		if !mr.Exists(key) {
+			t.Errorf("%s was wiped with user 42", key)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package redis
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// localCacheChannelSuffix names the Pub/Sub channel of L1 evictions, after the key prefix
+const localCacheChannelSuffix = ":l1:evict"
+
+// localCache is a size-bounded LRU of raw values read from Redis
+//
+// With LocalCache enabled, values read by Get are kept in a small in-process LRU for a
+// short TTL and served from it without a Redis round trip. Every deletion (Delete,
+// DeleteKeys, InvalidatePattern and thereby dependency and table invalidation) evicts
+// the local entries and is broadcast on a Pub/Sub channel, so the L1 caches of other
+// processes sharing the Redis keyspace are evicted too. Writes evict local entries only;
+// repository writes delete the entity's keys before any write-through, so peers are
+// evicted through the deletion. InvalidateEntityDependencies broadcasts all keys that
+// depend on the entity in one message.
+//
+// A value read from Redis is only kept if no eviction arrived while it was being read:
+// otherwise a read racing a peer's write could put the pre-write value back into L1
+// after the eviction was applied.
+//
+// Staleness bound: a process can serve an L1 value for up to LocalCache.TTL after it
+// was overwritten in Redis without an invalidation, or after an invalidation message
+// was lost (Pub/Sub is at-most-once, e.g. during a reconnect). Keep the TTL short.
+type localCache struct {
+	ttl        time.Duration
+	maxEntries int
+
+	mu         sync.Mutex
+	entries    map[string]*list.Element
+	lru        *list.List // Front is the most recently used entry
+	generation uint64     // Incremented by every eviction (see fill)
+}
+
+// localEntry is one L1 entry
+type localEntry struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+// localEviction is the Pub/Sub message of an L1 eviction
+type localEviction struct {
+	Keys    []string `json:"keys,omitempty"`
+	Pattern string   `json:"pattern,omitempty"`
+}
+
+// newLocalCache creates an L1 cache from the local cache configuration
+func newLocalCache(config LocalCacheConfig) *localCache {
+	maxEntries := config.MaxEntries
+	if maxEntries < 1 {
+		maxEntries = 10000
+	}
+	ttl := config.TTL
+	if ttl <= 0 {
+		ttl = time.Second
+	}
+	return &localCache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    make(map[string]*list.Element),
+		lru:        list.New(),
+	}
+}
+
+// get returns the value of a live entry
+func (c *localCache) get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := element.Value.(*localEntry)
+	if time.Now().After(entry.expires) {
+		c.lru.Remove(element)
+		delete(c.entries, key)
+		return nil, false
+	}
+	c.lru.MoveToFront(element)
+	return entry.value, true
+}
+
+// store stores a value, evicting the least recently used entry when full; c.mu must be held
+func (c *localCache) store(key string, value []byte) {
+	expires := time.Now().Add(c.ttl)
+	if element, ok := c.entries[key]; ok {
+		entry := element.Value.(*localEntry)
+		entry.value, entry.expires = value, expires
+		c.lru.MoveToFront(element)
+		return
+	}
+
+	c.entries[key] = c.lru.PushFront(&localEntry{key: key, value: value, expires: expires})
+	if c.lru.Len() > c.maxEntries {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*localEntry).key)
+	}
+}
+
+// currentGeneration returns the eviction generation, taken before reading a value from Redis
+func (c *localCache) currentGeneration() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.generation
+}
+
+// fill stores a value read from Redis unless an eviction happened since generation was
+// taken, in which case the value may predate it
+func (c *localCache) fill(key string, value []byte, generation uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.generation == generation {
+		c.store(key, value)
+	}
+}
+
+// evict removes keys
+func (c *localCache) evict(keys ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	for _, key := range keys {
+		if element, ok := c.entries[key]; ok {
+			c.lru.Remove(element)
+			delete(c.entries, key)
+		}
+	}
+}
+
+// evictPattern removes the keys matching a Redis glob pattern
+// Prefix patterns ("prefix*") are matched exactly; any other pattern clears the cache.
+func (c *localCache) evictPattern(pattern string) {
+	prefix, isPrefix := strings.CutSuffix(pattern, "*")
+	if strings.ContainsAny(prefix, `*?[]\`) {
+		isPrefix = false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	for key, element := range c.entries {
+		if !isPrefix || strings.HasPrefix(key, prefix) {
+			c.lru.Remove(element)
+			delete(c.entries, key)
+		}
+	}
+}
+
+// localCacheChannel returns the Pub/Sub channel of L1 evictions of this keyspace
+func (m *Manager) localCacheChannel() string {
+	return m.keyPrefix() + localCacheChannelSuffix
+}
+
+// startLocalCache subscribes to the eviction channel and applies remote evictions
+func (m *Manager) startLocalCache() {
+	m.localSub = m.client.Subscribe(context.Background(), m.localCacheChannel())
+	go func(messages <-chan *redis.Message) {
+		for message := range messages {
+			var eviction localEviction
+			if err := json.Unmarshal([]byte(message.Payload), &eviction); err != nil {
+				continue
+			}
+			m.local.evict(eviction.Keys...)
+			if eviction.Pattern != "" {
+				m.local.evictPattern(eviction.Pattern)
+			}
+
+			// Another process invalidated: pending retries would restore stale values
+			if m.deferred != nil {
+				m.deferred.discardKeys(eviction.Keys...)
+				if eviction.Pattern != "" {
+					m.deferred.discardPattern(eviction.Pattern)
+				}
+			}
+		}
+	}(m.localSub.Channel())
+}
+
+// evictLocal evicts deleted keys from the L1 caches of this and all other processes
+func (m *Manager) evictLocal(ctx context.Context, eviction localEviction) {
+	if m.local == nil || (len(eviction.Keys) == 0 && eviction.Pattern == "") {
+		return
+	}
+
+	m.local.evict(eviction.Keys...)
+	if eviction.Pattern != "" {
+		m.local.evictPattern(eviction.Pattern)
+	}
+
+	payload, err := json.Marshal(eviction)
+	if err != nil {
+		return
+	}
+	// Best effort - remote entries expire with the L1 TTL
+	if err := m.client.Publish(context.WithoutCancel(ctx), m.localCacheChannel(), payload).Err(); err != nil {
+		m.logger.Printf("sql4go: failed to broadcast L1 eviction: %v", err)
+	}
+}
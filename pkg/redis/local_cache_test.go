@@ -0,0 +1,70 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLocalCacheServesHotKeyWithoutRedis(t *testing.T) {
+	m, mr := newTestManager(t, func(c *Config) {
+		c.LocalCache = LocalCacheConfig{Enabled: true, MaxEntries: 10, TTL: time.Minute}
+	})
+	ctx := context.Background()
+	key := "sql4go:app:users:s1:find_by_id:1"
+	mr.Set(key, `{"id":1}`)
+
+	if value, err := m.Get(ctx, key); err != nil || string(value) != `{"id":1}` {
+		t.Fatalf("first Get = (%s, %v), want the Redis value", value, err)
+	}
+
+	commands := mr.CommandCount()
+	value, err := m.Get(ctx, key)
+	if err != nil || string(value) != `{"id":1}` {
+		t.Fatalf("second Get = (%s, %v), want the L1 value", value, err)
+	}
+	if got := mr.CommandCount() - commands; got != 0 {
+		t.Errorf("second Get sent %d commands to Redis, want 0", got)
+	}
+}
+
+func TestLocalCacheEvictedByPeerDeletion(t *testing.T) {
+	local := func(c *Config) {
+		c.LocalCache = LocalCacheConfig{Enabled: true, MaxEntries: 10, TTL: time.Minute}
+	}
+	writer, mr := newTestManager(t, local)
+	config := *writer.Config()
+	reader, err := NewManager(&config)
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+	t.Cleanup(func() { _ = reader.Close() })
+
+	ctx := context.Background()
+	key := "sql4go:app:users:s1:find_by_id:1"
+	mr.Set(key, `{"id":1,"name":"ann"}`)
+	if _, err := reader.Get(ctx, key); err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if _, ok := reader.local.get(key); !ok {
+		t.Fatal("the read did not fill the reader's L1")
+	}
+
+	// The writer deletes the key; the reader's L1 entry is evicted via Pub/Sub
+	if err := writer.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if _, ok := reader.local.get(key); !ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the reader still holds the L1 entry after the writer's deletion")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if _, err := reader.Get(ctx, key); !IsKeyNotFound(err) {
+		t.Errorf("Get after the peer's deletion = %v, want a miss", err)
+	}
+}
@@ -0,0 +1,66 @@
+package redis
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// cacheLockSuffix is the internal suffix of regeneration lock keys
+const cacheLockSuffix = "_internal:lock"
+
+// unlockScript deletes a lock only if it is still held by the caller's token,
+// so a winner whose lock expired cannot release a lock taken over by another process
+var unlockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// TryLock attempts to take the regeneration lock of a cache key (SET NX PX)
+// Returns the lock token and true if acquired. The lock expires after ttl, so a
+// crashed holder blocks others for at most ttl.
+func (m *Manager) TryLock(ctx context.Context, key string, ttl time.Duration) (string, bool, error) {
+	if err := m.checkClient(); err != nil {
+		return "", false, err
+	}
+
+	token, err := newLockToken()
+	if err != nil {
+		return "", false, err
+	}
+
+	acquired, err := m.client.SetNX(ctx, key+cacheLockSuffix, token, ttl).Result()
+	if err != nil {
+		return "", false, fmt.Errorf("failed to acquire lock: %w", err)
+	}
+
+	return token, acquired, nil
+}
+
+// Unlock releases a regeneration lock taken with TryLock
+func (m *Manager) Unlock(ctx context.Context, key, token string) error {
+	if err := m.checkClient(); err != nil {
+		return err
+	}
+
+	if err := unlockScript.Run(ctx, m.client, []string{key + cacheLockSuffix}, token).Err(); err != nil && err != redis.Nil {
+		return fmt.Errorf("failed to release lock: %w", err)
+	}
+
+	return nil
+}
+
+// newLockToken returns a random token identifying a lock holder
+func newLockToken() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate lock token: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
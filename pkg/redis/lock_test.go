@@ -0,0 +1,58 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestTryLockIsExclusiveUntilUnlock(t *testing.T) {
+	m, _ := newTestManager(t)
+	ctx := context.Background()
+
+	token, acquired, err := m.TryLock(ctx, "report", time.Minute)
+	if err != nil || !acquired || token == "" {
+		t.Fatalf("TryLock = (%q, %v, %v), want the lock", token, acquired, err)
+	}
+	if _, acquired, err := m.TryLock(ctx, "report", time.Minute); err != nil || acquired {
+		t.Fatalf("second TryLock = (%v, %v), want the lock held", acquired, err)
+	}
+	if _, acquired, err := m.TryLock(ctx, "other", time.Minute); err != nil || !acquired {
+		t.Fatalf("TryLock of another key = (%v, %v), want its own lock", acquired, err)
+	}
+
+	if err := m.Unlock(ctx, "report", token); err != nil {
+		t.Fatalf("Unlock: %v", err)
+	}
+	if _, acquired, err := m.TryLock(ctx, "report", time.Minute); err != nil || !acquired {
+		t.Fatalf("TryLock after Unlock = (%v, %v), want the lock", acquired, err)
+	}
+}
+
+func TestUnlockWithStaleTokenKeepsCurrentHolder(t *testing.T) {
+	m, mr := newTestManager(t)
+	ctx := context.Background()
+
+	stale, acquired, err := m.TryLock(ctx, "report", time.Second)
+	if err != nil || !acquired {
+		t.Fatalf("TryLock = (%v, %v), want the lock", acquired, err)
+	}
+
+	// The first holder's lock expires and another process takes it over
+	mr.FastForward(2 * time.Second)
+	current, acquired, err := m.TryLock(ctx, "report", time.Minute)
+	if err != nil || !acquired {
+		t.Fatalf("TryLock after expiry = (%v, %v), want the lock", acquired, err)
+	}
+
+	// The late release of the first holder must not free the new holder's lock
+	if err := m.Unlock(ctx, "report", stale); err != nil {
+		t.Fatalf("Unlock with the stale token: %v", err)
+	}
+	if got, _ := mr.Get("report" + cacheLockSuffix); got != current {
+		t.Fatalf("lock token after the stale Unlock = %q, want the current holder's %q", got, current)
+	}
+	if _, acquired, err := m.TryLock(ctx, "report", time.Minute); err != nil || acquired {
+		t.Errorf("TryLock after the stale Unlock = (%v, %v), want the lock still held", acquired, err)
+	}
+}
@@ -5,10 +5,15 @@ import (
 	"compress/gzip"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log"
+	"reflect"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -18,18 +23,51 @@ import (
 // Cache key constants for consistent key generation across the application
 const (
 	cacheKeyPrefix        = "gensql4go"
+	entityKeyPrefix       = "sql4go" // Prefix of repository cache keys (see repository.cacheKeyNamespace)
 	cacheKeySeparator     = ":"
 	cacheDependencyPrefix = "deps"
 	cacheMetadataSuffix   = "_internal:meta"  // Internal suffix to prevent user key collisions
 	cacheChunkPrefix      = "_internal:chunk" // Internal prefix for chunk keys
+	cacheHashTagVersion   = "v2"              // Key namespace version when cluster hash tags are enabled
 )
 
+// nullValueMarker is stored in place of empty or not-found results
+// It cannot be produced by the JSON or MessagePack encoding of an entity or slice
+var nullValueMarker = []byte("\x00sql4go:null")
+
+// IsNullMarker reports whether raw cached data (e.g. from GetMany) is a null marker
+func IsNullMarker(data []byte) bool {
+	return bytes.Equal(data, nullValueMarker)
+}
+
 // Manager manages Redis connections and cache operations
+// It is safe for concurrent use: metrics are atomic counters, and the local cache, hot
+// key sampling, invalidation coalescing and dependency tracking are mutex-guarded.
+// Configure it (SetLogger, RegisterInvalidationPattern at startup) before sharing it.
 type Manager struct {
 	config        *Config
 	client        redis.UniversalClient
 	clusterClient *redis.ClusterClient
 	metrics       *Metrics
+	coalescer     *invalidationCoalescer // nil when debouncing is disabled
+	hotKeys       *hotKeyTracker         // nil when hot key tracking is disabled
+	local         *localCache            // In-process L1 cache; nil unless LocalCache is enabled
+	localSub      *redis.PubSub          // L1 eviction subscription (see localCache)
+	dictionaries  *dictionaryStore       // nil unless LargeValue.Dictionary is enabled (see TrainDictionary)
+	evictions     *evictionMonitor       // maxmemory eviction tracking (see CheckEvictions)
+	deferred      *deferredPopulation    // Retries of failed stores; nil unless DeferredPopulation is enabled
+	patterns      invalidationPatterns   // Patterns added by RegisterInvalidationPattern
+	logger        Logger
+
+	// Dependency set size limits (see checkDependencySetSizes)
+	overflowedDependencies  sync.Map // dependency key -> local overflow expiry (time.Time)
+	dependencyRegistrations atomic.Uint64
+}
+
+// Logger receives cache warnings such as discarded corrupt values
+// *log.Logger satisfies this interface
+type Logger interface {
+	Printf(format string, args ...interface{})
 }
 
 // NewManager creates a new Redis cache manager
@@ -39,15 +77,49 @@ func NewManager(config *Config) (*Manager, error) {
 	}
 
 	manager := &Manager{
-		config:  config,
-		metrics: NewMetrics(),
+		config:    config,
+		metrics:   NewMetrics(),
+		logger:    log.Default(),
+		evictions: newEvictionMonitor(),
 	}
+	manager.metrics.maxLabels = config.MaxMetricLabels
 
 	// Initialize Redis client based on configuration
 	if err := manager.initializeClient(); err != nil {
 		return nil, fmt.Errorf("failed to initialize redis client: %w", err)
 	}
 
+	// Track the most-read keys if enabled
+	if config.HotKeys.Enabled {
+		manager.hotKeys = newHotKeyTracker(config.HotKeys, config.Namespace)
+	}
+
+	// Serve hot keys from an in-process L1 cache if enabled
+	if config.Enabled && config.LocalCache.Enabled {
+		manager.local = newLocalCache(config.LocalCache)
+		manager.startLocalCache()
+	}
+
+	// Compress small values with a shared dictionary if enabled
+	if config.Enabled && config.LargeValue.Dictionary.Enabled {
+		manager.dictionaries = newDictionaryStore()
+	}
+
+	// Watch the server's evicted_keys counter if enabled
+	if config.Enabled && config.EvictionCheckInterval > 0 {
+		manager.evictions.start(manager, config.EvictionCheckInterval)
+	}
+
+	// Coalesce repeated pattern invalidations if a debounce window is configured
+	if config.Enabled && config.Invalidation.DebounceWindow > 0 {
+		manager.coalescer = newInvalidationCoalescer(manager, config.Invalidation.DebounceWindow)
+	}
+
+	// Retry cache stores that failed transiently if enabled
+	if config.Enabled && config.DeferredPopulation.Enabled {
+		manager.deferred = newDeferredPopulation(manager, config.DeferredPopulation)
+	}
+
 	return manager, nil
 }
 
@@ -57,6 +129,10 @@ func (m *Manager) initializeClient() error {
 		return nil // Skip initialization if cache is disabled
 	}
 
+	if m.config.Backend == BackendMemory {
+		return fmt.Errorf("the memory backend is not a redis server; create it with repository.NewCache or repository.NewMemoryCache")
+	}
+
 	if m.config.IsClusterMode() {
 		// Redis Cluster configuration
 		m.clusterClient = redis.NewClusterClient(&redis.ClusterOptions{
@@ -77,6 +153,7 @@ func (m *Manager) initializeClient() error {
 		// Single Redis instance configuration
 		m.client = redis.NewClient(&redis.Options{
 			Addr:            m.config.GetAddr(),
+			Username:        m.config.Username,
 			Password:        m.config.Password,
 			DB:              m.config.Database,
 			PoolSize:        m.config.PoolSize,
@@ -93,17 +170,42 @@ func (m *Manager) initializeClient() error {
 	return nil
 }
 
+// SetLogger replaces the logger used for cache warnings (defaults to log.Default())
+// Call during setup, before the manager is shared between goroutines
+func (m *Manager) SetLogger(logger Logger) {
+	if logger != nil {
+		m.logger = logger
+	}
+}
+
+// Logger returns the logger used for cache warnings
+func (m *Manager) Logger() Logger {
+	return m.logger
+}
+
 // Config returns the manager's configuration
 func (m *Manager) Config() *Config {
 	return m.config
 }
 
 // Close closes the Redis connection
+// Pending coalesced invalidations are executed before the connection is closed
 func (m *Manager) Close() error {
+	if m.coalescer != nil {
+		m.coalescer.close()
+	}
+	if m.deferred != nil {
+		m.deferred.close()
+	}
+	m.evictions.close()
+	if m.localSub != nil {
+		_ = m.localSub.Close()
+	}
+	var err error
 	if m.client != nil {
-		return m.client.Close()
+		err = m.client.Close()
 	}
-	return nil
+	return err
 }
 
 // Ping tests the Redis connection
@@ -144,12 +246,20 @@ func (m *Manager) checkClient() error {
 }
 
 // marshal serializes a value using the configured format (JSON or MessagePack)
+// Under JSON, values encoding/json would alter are stored as MessagePack (see msgpackValuePrefix)
 func (m *Manager) marshal(value interface{}) ([]byte, error) {
 	switch m.config.SerializationFormat {
 	case SerializationMsgPack:
 		return msgpack.Marshal(value)
 	case SerializationJSON:
-		return json.Marshal(value)
+		if !needsVerbatimEncoding(reflect.TypeOf(value)) {
+			return json.Marshal(value)
+		}
+		data, err := msgpack.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte(msgpackValuePrefix), data...), nil
 	default:
 		// Default to MessagePack for best performance
 		return msgpack.Marshal(value)
@@ -160,12 +270,15 @@ func (m *Manager) marshal(value interface{}) ([]byte, error) {
 func (m *Manager) unmarshal(data []byte, target interface{}) error {
 	switch m.config.SerializationFormat {
 	case SerializationMsgPack:
-		return msgpack.Unmarshal(data, target)
+		return msgpackUnmarshal(data, target)
 	case SerializationJSON:
+		if bytes.HasPrefix(data, []byte(msgpackValuePrefix)) {
+			return msgpackUnmarshal(data[len(msgpackValuePrefix):], target)
+		}
 		return json.Unmarshal(data, target)
 	default:
 		// Default to MessagePack for best performance
-		return msgpack.Unmarshal(data, target)
+		return msgpackUnmarshal(data, target)
 	}
 }
 
@@ -174,6 +287,24 @@ func (m *Manager) Get(ctx context.Context, key string) ([]byte, error) {
 	if err := m.checkClient(); err != nil {
 		return nil, err
 	}
+	if err := m.checkKey(key); err != nil {
+		return nil, err
+	}
+
+	if m.hotKeys != nil {
+		m.hotKeys.record(key)
+	}
+
+	// L1 first - no round trip for hot keys
+	var generation uint64
+	if m.local != nil {
+		if value, ok := m.local.get(key); ok {
+			m.metrics.RecordCacheHit()
+			m.metrics.RecordLocalHit()
+			return value, nil
+		}
+		generation = m.local.currentGeneration()
+	}
 
 	start := time.Now()
 	result := m.client.Get(ctx, key)
@@ -185,12 +316,15 @@ func (m *Manager) Get(ctx context.Context, key string) ([]byte, error) {
 	}
 
 	if result.Err() != nil {
-		m.metrics.RecordCacheError()
-		return nil, fmt.Errorf("redis get error: %w", result.Err())
+		return nil, fmt.Errorf("redis get error: %w", m.recordError(ctx, result.Err()))
 	}
 
 	m.metrics.RecordCacheHit()
-	return []byte(result.Val()), nil
+	value := []byte(result.Val())
+	if m.local != nil {
+		m.local.fill(key, value, generation)
+	}
+	return value, nil
 }
 
 // Set stores a value in cache with TTL
@@ -198,10 +332,16 @@ func (m *Manager) Set(ctx context.Context, key string, value []byte) error {
 	if err := m.checkClient(); err != nil {
 		return err
 	}
+	if err := m.validateKey(key); err != nil {
+		return err
+	}
 
 	start := time.Now()
 	result := m.client.Set(ctx, key, value, m.config.DefaultTTL)
 	m.metrics.RecordSet(time.Since(start))
+	if m.local != nil {
+		m.local.evict(key)
+	}
 
 	return result.Err()
 }
@@ -211,8 +351,14 @@ func (m *Manager) SetWithTTL(ctx context.Context, key string, value []byte, ttl
 	if err := m.checkClient(); err != nil {
 		return err
 	}
+	if err := m.validateKey(key); err != nil {
+		return err
+	}
 
 	result := m.client.Set(ctx, key, value, ttl)
+	if m.local != nil {
+		m.local.evict(key)
+	}
 	return result.Err()
 }
 
@@ -222,9 +368,14 @@ func (m *Manager) Delete(ctx context.Context, key string) error {
 		return err
 	}
 
+	if m.deferred != nil {
+		m.deferred.discardKeys(key)
+	}
+
 	start := time.Now()
 	result := m.client.Del(ctx, key)
 	m.metrics.RecordDelete(time.Since(start))
+	m.evictLocal(ctx, localEviction{Keys: []string{key}})
 
 	return result.Err()
 }
@@ -238,21 +389,37 @@ func (m *Manager) DeleteKeys(ctx context.Context, keys []string) error {
 	if len(keys) == 0 {
 		return nil
 	}
+	if m.deferred != nil {
+		m.deferred.discardKeys(keys...)
+	}
 
 	result := m.client.Del(ctx, keys...)
+	m.evictLocal(ctx, localEviction{Keys: keys})
 	return result.Err()
 }
 
 // InvalidatePattern removes keys matching a pattern using SCAN instead of KEYS
 // SCAN is non-blocking and production-safe, unlike KEYS which blocks the Redis server
 func (m *Manager) InvalidatePattern(ctx context.Context, pattern string) error {
+	_, err := m.InvalidatePatternCount(ctx, pattern)
+	return err
+}
+
+// InvalidatePatternCount removes keys matching a pattern like InvalidatePattern and
+// returns the number of keys deleted, e.g. for admin endpoints reporting the impact
+// Keys that expire between the scan and the delete are not counted. On error, the keys
+// deleted before it are counted.
+func (m *Manager) InvalidatePatternCount(ctx context.Context, pattern string) (int64, error) {
 	if err := m.checkClient(); err != nil {
-		return err
+		return 0, err
+	}
+	if m.deferred != nil {
+		m.deferred.discardPattern(pattern)
 	}
 
 	// Use SCAN to iterate through keys without blocking Redis
 	var cursor uint64
-	var keys []string
+	var deleted int64
 	const scanBatchSize = 100 // Process keys in batches
 
 	for {
@@ -262,16 +429,16 @@ func (m *Manager) InvalidatePattern(ctx context.Context, pattern string) error {
 
 		batch, cursor, err = m.client.Scan(ctx, cursor, pattern, scanBatchSize).Result()
 		if err != nil {
-			return fmt.Errorf("failed to scan keys with pattern %s: %w", pattern, err)
+			return deleted, fmt.Errorf("failed to scan keys with pattern %s: %w", pattern, err)
 		}
 
-		keys = append(keys, batch...)
-
 		// Delete keys in batches to avoid large atomic operations
 		if len(batch) > 0 {
-			if err := m.client.Del(ctx, batch...).Err(); err != nil {
-				return fmt.Errorf("failed to delete batch: %w", err)
+			count, err := m.client.Del(ctx, batch...).Result()
+			if err != nil {
+				return deleted, fmt.Errorf("failed to delete batch: %w", err)
 			}
+			deleted += count
 			m.metrics.RecordInvalidation()
 		}
 
@@ -281,7 +448,29 @@ func (m *Manager) InvalidatePattern(ctx context.Context, pattern string) error {
 		}
 	}
 
-	return nil
+	m.evictLocal(ctx, localEviction{Pattern: pattern})
+	m.metrics.RecordPatternInvalidation()
+	return deleted, nil
+}
+
+// InvalidatePatternCoalesced removes keys matching a pattern, debounced per pattern
+// With Invalidation.DebounceWindow set, invalidations of the same pattern within the
+// window are merged into a single wipe executed at the window's end. Keys matching the
+// pattern may be served for up to one window after the call.
+// Without a debounce window this is equivalent to InvalidatePattern.
+func (m *Manager) InvalidatePatternCoalesced(ctx context.Context, pattern string) error {
+	if err := m.checkClient(); err != nil {
+		return err
+	}
+	if m.deferred != nil {
+		m.deferred.discardPattern(pattern) // Before the debounced wipe, which may be delayed
+	}
+
+	if m.coalescer != nil && m.coalescer.schedule(pattern) {
+		return nil
+	}
+
+	return m.InvalidatePattern(ctx, pattern)
 }
 
 // InvalidateRelationships invalidates related cache keys based on entity relationships
@@ -308,18 +497,17 @@ func (m *Manager) InvalidateRelationships(ctx context.Context, entityType string
 func (m *Manager) buildInvalidationPatterns(entityType string, entityID interface{}) []string {
 	patterns := []string{
 		// Base entity patterns
-		fmt.Sprintf("%s%s%s%s*", cacheKeyPrefix, cacheKeySeparator, entityType, cacheKeySeparator),
-		fmt.Sprintf("%s%s%s%sfind_by_id%s%v", cacheKeyPrefix, cacheKeySeparator, entityType, cacheKeySeparator, cacheKeySeparator, entityID),
+		fmt.Sprintf("%s%s%s%s*", m.keyPrefix(), cacheKeySeparator, entityType, cacheKeySeparator),
+		fmt.Sprintf("%s%s%s%sfind_by_id%s%v", m.keyPrefix(), cacheKeySeparator, entityType, cacheKeySeparator, cacheKeySeparator, entityID),
 	}
 
-	// Add custom invalidation patterns if configured
-	if customPatterns, exists := m.config.Invalidation.KeyPatterns[entityType]; exists {
-		for _, customPattern := range customPatterns {
-			// Replace placeholders with actual entity ID
-			pattern := strings.ReplaceAll(customPattern, "{id}", fmt.Sprintf("%v", entityID))
-			patterns = append(patterns, pattern)
-		}
+	// Add custom invalidation patterns; the database is only known from a qualified
+	// "db:table" entity type, and matches any database otherwise
+	dbName, tableName := "*", entityType
+	if idx := strings.Index(entityType, cacheKeySeparator); idx > 0 {
+		dbName, tableName = entityType[:idx], entityType[idx+1:]
 	}
+	patterns = append(patterns, m.InvalidationPatterns(dbName, tableName, entityID)...)
 
 	return patterns
 }
@@ -343,29 +531,82 @@ func (m *Manager) WarmCache(ctx context.Context, entities []string) error {
 	return nil
 }
 
+// HashTagsEnabled reports whether sql4go keys use Redis Cluster hash tags
+func (m *Manager) HashTagsEnabled() bool {
+	return m.config.Cluster.HashTags
+}
+
+// Namespace returns the configured key namespace ("" if none)
+func (m *Manager) Namespace() string {
+	return m.config.Namespace
+}
+
+// keyPrefix returns the prefix of the manager's own keys, including the namespace
+func (m *Manager) keyPrefix() string {
+	if m.config.Namespace != "" {
+		return cacheKeyPrefix + cacheKeySeparator + m.config.Namespace
+	}
+	return cacheKeyPrefix
+}
+
+// entityKeyPrefix returns the prefix of repository cache keys, including the namespace
+func (m *Manager) entityKeyPrefix() string {
+	if m.config.Namespace != "" {
+		return entityKeyPrefix + cacheKeySeparator + m.config.Namespace
+	}
+	return entityKeyPrefix
+}
+
+// dependencyKey returns the key of the dependency set for an entity
+// Default: "gensql4go:deps:customers:123" (namespaced: "gensql4go:{ns}:deps:customers:123")
+// Hash tags: "gensql4go:v2:deps:{app:customers}:123" - entityType is expected to be
+// qualified as "db:table" so the set shares the hash slot of the table's cache keys
+func (m *Manager) dependencyKey(entityType string, entityID interface{}) string {
+	if m.HashTagsEnabled() {
+		return fmt.Sprintf("%s%s%s%s%s%s{%s}%s%v", m.keyPrefix(), cacheKeySeparator, cacheHashTagVersion, cacheKeySeparator, cacheDependencyPrefix, cacheKeySeparator, entityType, cacheKeySeparator, entityID)
+	}
+	return fmt.Sprintf("%s%s%s%s%s%s%v", m.keyPrefix(), cacheKeySeparator, cacheDependencyPrefix, cacheKeySeparator, entityType, cacheKeySeparator, entityID)
+}
+
+// ownedDependencyKey returns the key of the dependency set for an entity holding cache
+// keys of the table owner (their hash tag, "db:table"). With hash tags, sets holding keys
+// of another table carry that table's hash tag, e.g. for FindWhere keys of users that
+// depend on customer 123: "gensql4go:v2:deps:{app:users}:app:customers:123". Otherwise
+// (and for keys of the entity's own table) it is dependencyKey.
+func (m *Manager) ownedDependencyKey(owner, entityType string, entityID interface{}) string {
+	if !m.HashTagsEnabled() || owner == "" || owner == entityType {
+		return m.dependencyKey(entityType, entityID)
+	}
+	return fmt.Sprintf("%s%s%s%s%s%s{%s}%s%s%s%v", m.keyPrefix(), cacheKeySeparator, cacheHashTagVersion, cacheKeySeparator, cacheDependencyPrefix, cacheKeySeparator, owner, cacheKeySeparator, entityType, cacheKeySeparator, entityID)
+}
+
+// hashTag returns the hash tag of a key: the text between its first "{" and the next
+// "}", as Redis Cluster computes it ("" if none)
+func hashTag(key string) string {
+	start := strings.IndexByte(key, '{')
+	if start < 0 {
+		return ""
+	}
+	end := strings.IndexByte(key[start+1:], '}')
+	if end <= 0 {
+		return ""
+	}
+	return key[start+1 : start+1+end]
+}
+
 // AddDependency links a cache key to an entity for relationship-aware invalidation
 func (m *Manager) AddDependency(ctx context.Context, entityType string, entityID interface{}, cacheKey string) error {
 	if err := m.checkClient(); err != nil {
 		return err
 	}
 
-	// Create dependency key: "gensql4go:deps:customer:123"
-	dependencyKey := fmt.Sprintf("%s%s%s%s%s%s%v", cacheKeyPrefix, cacheKeySeparator, cacheDependencyPrefix, cacheKeySeparator, entityType, cacheKeySeparator, entityID)
-
-	// Add cache key to the set of dependencies for this entity
-	result := m.client.SAdd(ctx, dependencyKey, cacheKey)
-	if result.Err() != nil {
-		return fmt.Errorf("failed to add dependency: %w", result.Err())
+	// Add cache key to the set of dependencies for this entity (with TTL to prevent memory leaks)
+	plan := m.planDependencies(map[string][]interface{}{entityType: {entityID}}, cacheKey)
+	if err := m.registerDependencies(ctx, plan, nil, nil); err != nil {
+		return fmt.Errorf("failed to add dependency: %w", err)
 	}
 
 	m.metrics.RecordDependency()
-
-	// Set TTL on dependency key to prevent memory leaks
-	if res := m.client.Expire(ctx, dependencyKey, m.config.DefaultTTL*2); res.Err() != nil {
-		// record cache error but do not fail the whole operation
-		m.metrics.RecordCacheError()
-	}
-
 	return nil
 }
 
@@ -376,19 +617,8 @@ func (m *Manager) AddMultipleDependencies(ctx context.Context, dependencies map[
 		return err
 	}
 
-	// Use pipeline for atomic operation
-	pipe := m.client.Pipeline()
-
-	for entityType, ids := range dependencies {
-		for _, entityID := range ids {
-			dependencyKey := fmt.Sprintf("%s%s%s%s%s%s%v", cacheKeyPrefix, cacheKeySeparator, cacheDependencyPrefix, cacheKeySeparator, entityType, cacheKeySeparator, entityID)
-			pipe.SAdd(ctx, dependencyKey, cacheKey)
-			pipe.Expire(ctx, dependencyKey, m.config.DefaultTTL*2)
-		}
-	}
-
-	_, err := pipe.Exec(ctx)
-	return err
+	// Nothing to roll back here - the caller owns the cached value
+	return m.registerDependencies(ctx, m.planDependencies(dependencies, cacheKey), nil, nil)
 }
 
 // InvalidateEntityDependencies clears all caches that depend on a specific entity
@@ -396,74 +626,190 @@ func (m *Manager) InvalidateEntityDependencies(ctx context.Context, entityType s
 	if err := m.checkClient(); err != nil {
 		return err
 	}
+	if m.deferred != nil {
+		m.deferred.discardDependency(entityType, entityID)
+	}
 
-	dependencyKey := fmt.Sprintf("%s%s%s%s%s%s%v", cacheKeyPrefix, cacheKeySeparator, cacheDependencyPrefix, cacheKeySeparator, entityType, cacheKeySeparator, entityID)
+	// With hash tags, keys of other tables depending on the entity are in sets of their own slot
+	owners, err := m.dependencyOwners(ctx, entityType)
+	if err != nil {
+		return fmt.Errorf("failed to get dependencies: %w", err)
+	}
+
+	tracked := false
+	for _, owner := range owners {
+		// Keys depending on too many entities of the type to track each (MaxDependenciesPerKey)
+		m.invalidateTableDependencies(ctx, m.ownedDependencyKey(owner, entityType, tableDependencyID))
+
+		found, err := m.invalidateDependencySet(ctx, m.ownedDependencyKey(owner, entityType, entityID))
+		if err != nil {
+			return err
+		}
+		tracked = tracked || found
+	}
+
+	// No dependencies to invalidate - or the sets were evicted under maxmemory
+	if !tracked && m.config.Invalidation.FallbackOnMissingDependencies {
+		return m.invalidateUntrackedEntity(ctx, entityType, entityID)
+	}
+	return nil
+}
+
+// invalidateDependencySet deletes the cache keys of a dependency set and the set itself,
+// reporting whether the set tracked any keys
+func (m *Manager) invalidateDependencySet(ctx context.Context, dependencyKey string) (bool, error) {
+	// Wipe the patterns of cache keys not tracked because the set overflowed
+	m.invalidateOverflowPatterns(ctx, dependencyKey)
+
+	// Oversized sets are invalidated by pattern - deleting every key would take seconds
+	if m.isDependencySetOversized(ctx, dependencyKey) {
+		return true, m.invalidateDependencySetByPattern(ctx, dependencyKey)
+	}
 
 	// Get all cache keys that depend on this entity
 	result := m.client.SMembers(ctx, dependencyKey)
 	if result.Err() != nil && result.Err() != redis.Nil {
-		return fmt.Errorf("failed to get dependencies: %w", result.Err())
+		return false, fmt.Errorf("failed to get dependencies: %w", result.Err())
 	}
 
 	dependentKeys := result.Val()
 	if len(dependentKeys) == 0 {
-		return nil // No dependencies to invalidate
+		return false, nil
 	}
 
-	// Delete all dependent cache keys (including chunked and metadata keys)
+	m.deleteDependentKeys(ctx, dependentKeys)
+
+	// Clean up the dependency set itself
+	m.client.Del(ctx, dependencyKey)
+
+	return true, nil
+}
+
+// deleteDependentKeys deletes the members of a dependency set (best effort)
+func (m *Manager) deleteDependentKeys(ctx context.Context, dependentKeys []string) {
+	// Delete all dependent cache keys (including chunked and metadata keys), one cache
+	// key at a time since they may live in different cluster slots
+	evicted := make([]string, 0, len(dependentKeys))
 	for _, cacheKey := range dependentKeys {
-		if err := m.DeleteLarge(ctx, cacheKey); err != nil {
+		keys, err := m.largeValueKeys(ctx, cacheKey)
+		if err != nil {
 			// Log error but continue with other keys
 			continue
 		}
+		if err := m.client.Del(ctx, keys...).Err(); err != nil {
+			continue
+		}
+		evicted = append(evicted, keys...)
 	}
 
-	// Clean up the dependency set itself
-	m.client.Del(ctx, dependencyKey)
-
-	return nil
+	// Evict the dependent keys from all L1 caches with a single broadcast
+	m.evictLocal(ctx, localEviction{Keys: evicted})
 }
 
 // SetWithDependencies stores a value and registers its dependencies in one operation
 func (m *Manager) SetWithDependencies(ctx context.Context, cacheKey string, value []byte, dependencies map[string][]interface{}) error {
+	return m.SetWithDependenciesTTL(ctx, cacheKey, value, dependencies, m.config.DefaultTTL)
+}
+
+// SetWithDependenciesTTL stores a value with a custom TTL and registers its dependencies in one operation
+func (m *Manager) SetWithDependenciesTTL(ctx context.Context, cacheKey string, value []byte, dependencies map[string][]interface{}, ttl time.Duration) error {
 	if err := m.checkClient(); err != nil {
 		return err
 	}
+	if err := m.validateKey(cacheKey); err != nil {
+		return err
+	}
 
-	// Use pipeline for atomic operation
-	pipe := m.client.Pipeline()
+	if m.local != nil {
+		m.local.evict(cacheKey)
+	}
+
+	// Store the value with the first pipeline of dependency registrations; roll it back
+	// if any dependency failed to register, so it is never cached but untracked
+	plan := m.planDependencies(dependencies, cacheKey)
+	return m.registerDependencies(ctx, plan, func(pipe redis.Pipeliner) {
+		pipe.Set(ctx, cacheKey, value, ttl)
+	}, []string{cacheKey})
+}
+
+// execWrite executes a write pipeline and inspects every command result
+// A pipeline can partially succeed (e.g. the SET lands while an SADD fails). In that
+// case the rollback keys are deleted (best effort) and ErrPartialWrite is returned.
+func (m *Manager) execWrite(ctx context.Context, pipe redis.Pipeliner, rollback []string) error {
+	cmds, err := pipe.Exec(ctx)
+	if err == nil {
+		return nil
+	}
 
-	// 1. Store the cache value
-	pipe.Set(ctx, cacheKey, value, m.config.DefaultTTL)
+	failed := 0
+	for _, cmd := range cmds {
+		if cmd.Err() != nil {
+			failed++
+		}
+	}
+	err = m.recordError(ctx, err)
 
-	// 2. Register all dependencies
-	for entityType, ids := range dependencies {
-		for _, entityID := range ids {
-			dependencyKey := fmt.Sprintf("%s%s%s%s%s%s%v", cacheKeyPrefix, cacheKeySeparator, cacheDependencyPrefix, cacheKeySeparator, entityType, cacheKeySeparator, entityID)
-			pipe.SAdd(ctx, dependencyKey, cacheKey)
-			pipe.Expire(ctx, dependencyKey, m.config.DefaultTTL*2)
+	if len(rollback) > 0 {
+		// Roll back even if the request was cancelled; delete keys individually so
+		// the pipeline can route them in cluster mode without CROSSSLOT errors
+		rollbackCtx := context.WithoutCancel(ctx)
+		rollbackPipe := m.client.Pipeline()
+		for _, key := range rollback {
+			rollbackPipe.Del(rollbackCtx, key)
+		}
+		if _, rollbackErr := rollbackPipe.Exec(rollbackCtx); rollbackErr != nil {
+			m.logger.Printf("sql4go: failed to roll back partial cache write: %v", rollbackErr)
 		}
 	}
 
-	_, err := pipe.Exec(ctx)
+	return fmt.Errorf("%w: %d of %d commands failed: %w", ErrPartialWrite, failed, len(cmds), err)
+}
+
+// recordError counts a failed operation as a timeout, cancellation or error
+// and returns the error classified (see classifyError)
+func (m *Manager) recordError(ctx context.Context, err error) error {
+	err = classifyError(ctx, err)
+	switch {
+	case errors.Is(err, ErrTimeout):
+		m.metrics.RecordCacheTimeout()
+	case errors.Is(err, ErrCanceled):
+		m.metrics.RecordCacheCancellation()
+	default:
+		m.metrics.RecordCacheError()
+	}
 	return err
 }
 
 // SetLargeWithDependencies stores a large value and registers its dependencies
 func (m *Manager) SetLargeWithDependencies(ctx context.Context, cacheKey string, value []byte, dependencies map[string][]interface{}) error {
+	return m.SetLargeWithDependenciesTTL(ctx, cacheKey, value, dependencies, m.config.DefaultTTL)
+}
+
+// SetLargeWithDependenciesTTL stores a large value with a custom TTL and registers its dependencies
+func (m *Manager) SetLargeWithDependenciesTTL(ctx context.Context, cacheKey string, value []byte, dependencies map[string][]interface{}, ttl time.Duration) error {
 	// First store the large value
-	if err := m.SetLarge(ctx, cacheKey, value); err != nil {
+	if err := m.SetLargeWithTTL(ctx, cacheKey, value, ttl); err != nil {
 		return err
 	}
 
-	// Then register dependencies
-	return m.AddMultipleDependencies(ctx, dependencies, cacheKey)
+	// Then register dependencies; drop the value if they could not all be registered
+	if err := m.AddMultipleDependencies(ctx, dependencies, cacheKey); err != nil {
+		if deleteErr := m.DeleteLarge(context.WithoutCancel(ctx), cacheKey); deleteErr != nil {
+			m.logger.Printf("sql4go: failed to roll back partial cache write for key %s: %v", cacheKey, deleteErr)
+		}
+		if IsPartialWrite(err) {
+			return err
+		}
+		return fmt.Errorf("%w: %v", ErrPartialWrite, err)
+	}
+
+	return nil
 }
 
 // SetValueWithDependencies stores value and registers its dependencies
 // Uses configured serialization format (JSON or MessagePack)
 func (m *Manager) SetValueWithDependencies(ctx context.Context, cacheKey string, value interface{}, dependencies map[string][]interface{}) error {
-	data, err := m.marshal(value)
+	data, err := m.encodeValue(ctx, cacheKey, value)
 	if err != nil {
 		return fmt.Errorf("failed to marshal value: %w", err)
 	}
@@ -471,20 +817,64 @@ func (m *Manager) SetValueWithDependencies(ctx context.Context, cacheKey string,
 	return m.SetWithDependencies(ctx, cacheKey, data, dependencies)
 }
 
+// SetValueWithDependenciesTTL stores value with a custom TTL and registers its dependencies
+// Uses configured serialization format (JSON or MessagePack)
+func (m *Manager) SetValueWithDependenciesTTL(ctx context.Context, cacheKey string, value interface{}, dependencies map[string][]interface{}, ttl time.Duration) error {
+	data, err := m.encodeValue(ctx, cacheKey, value)
+	if err != nil {
+		return fmt.Errorf("failed to marshal value: %w", err)
+	}
+
+	err = m.SetWithDependenciesTTL(ctx, cacheKey, data, dependencies, ttl)
+	m.deferPopulation(ctx, cacheKey, data, dependencies, ttl, false, err)
+	return err
+}
+
+// SetLargeValueWithDependencies stores a large value and registers its dependencies
+// Uses configured serialization format (JSON or MessagePack), matching GetLargeValue
+func (m *Manager) SetLargeValueWithDependencies(ctx context.Context, cacheKey string, value interface{}, dependencies map[string][]interface{}) error {
+	return m.SetLargeValueWithDependenciesTTL(ctx, cacheKey, value, dependencies, m.config.DefaultTTL)
+}
+
+// SetLargeValueWithDependenciesTTL stores a large value with a custom TTL and registers its dependencies
+// Uses configured serialization format (JSON or MessagePack), matching GetLargeValue
+// Values encoded larger than LargeValue.SkipCachingAbove are not stored (ErrValueSkipped).
+func (m *Manager) SetLargeValueWithDependenciesTTL(ctx context.Context, cacheKey string, value interface{}, dependencies map[string][]interface{}, ttl time.Duration) error {
+	data, err := m.encodeValue(ctx, cacheKey, value)
+	if err != nil {
+		return fmt.Errorf("failed to marshal value: %w", err)
+	}
+
+	if limit := m.config.LargeValue.SkipCachingAbove; limit > 0 && len(data) > limit {
+		m.metrics.RecordOversizeSkip(m.tableOfKey(cacheKey))
+		return fmt.Errorf("%w: %d bytes exceeds %d bytes", ErrValueSkipped, len(data), limit)
+	}
+
+	err = m.SetLargeWithDependenciesTTL(ctx, cacheKey, data, dependencies, ttl)
+	m.deferPopulation(ctx, cacheKey, data, dependencies, ttl, true, err)
+	return err
+}
+
 // GetDependencies returns all cache keys that depend on an entity
 func (m *Manager) GetDependencies(ctx context.Context, entityType string, entityID interface{}) ([]string, error) {
 	if err := m.checkClient(); err != nil {
 		return nil, err
 	}
 
-	dependencyKey := fmt.Sprintf("%s%s%s%s%s%s%v", cacheKeyPrefix, cacheKeySeparator, cacheDependencyPrefix, cacheKeySeparator, entityType, cacheKeySeparator, entityID)
-
-	result := m.client.SMembers(ctx, dependencyKey)
-	if result.Err() == redis.Nil {
-		return []string{}, nil // No dependencies
+	owners, err := m.dependencyOwners(ctx, entityType)
+	if err != nil {
+		return nil, err
 	}
 
-	return result.Val(), result.Err()
+	dependentKeys := []string{}
+	for _, owner := range owners {
+		keys, err := m.client.SMembers(ctx, m.ownedDependencyKey(owner, entityType, entityID)).Result()
+		if err != nil && err != redis.Nil {
+			return nil, err
+		}
+		dependentKeys = append(dependentKeys, keys...)
+	}
+	return dependentKeys, nil
 }
 
 // GetStats returns Redis connection and performance statistics
@@ -511,7 +901,7 @@ func (m *Manager) SetValue(ctx context.Context, key string, value interface{}) e
 		return err
 	}
 
-	data, err := m.marshal(value)
+	data, err := m.encodeValue(ctx, key, value)
 	if err != nil {
 		return fmt.Errorf("failed to marshal value: %w", err)
 	}
@@ -519,6 +909,22 @@ func (m *Manager) SetValue(ctx context.Context, key string, value interface{}) e
 	return m.Set(ctx, key, data)
 }
 
+// SetValueWithTTL stores a value with a custom TTL using the configured serialization format
+func (m *Manager) SetValueWithTTL(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	if err := m.checkClient(); err != nil {
+		return err
+	}
+
+	data, err := m.encodeValue(ctx, key, value)
+	if err != nil {
+		return fmt.Errorf("failed to marshal value: %w", err)
+	}
+
+	err = m.SetWithTTL(ctx, key, data, ttl)
+	m.deferPopulation(ctx, key, data, nil, ttl, false, err)
+	return err
+}
+
 // GetValue retrieves and unmarshals a value from cache using the configured serialization format
 func (m *Manager) GetValue(ctx context.Context, key string, target interface{}) error {
 	if err := m.checkClient(); err != nil {
@@ -531,13 +937,107 @@ func (m *Manager) GetValue(ctx context.Context, key string, target interface{})
 		return err
 	}
 
-	if err := m.unmarshal(data, target); err != nil {
-		return fmt.Errorf("failed to unmarshal value: %w", err)
+	if bytes.Equal(data, nullValueMarker) {
+		return ErrNullValue
+	}
+
+	if err := m.decodeValue(ctx, data, target); err != nil {
+		return m.discardCorruptValue(ctx, key, err)
 	}
 
 	return nil
 }
 
+// GetMany reads several keys in one round trip (a pipeline of GETs, which also works
+// across Redis Cluster slots). Keys that are not cached are omitted from the result.
+func (m *Manager) GetMany(ctx context.Context, keys []string) (map[string][]byte, error) {
+	if err := m.checkClient(); err != nil {
+		return nil, err
+	}
+	if err := m.checkKeys(keys); err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return map[string][]byte{}, nil
+	}
+
+	start := time.Now()
+	pipe := m.client.Pipeline()
+	results := make([]*redis.StringCmd, len(keys))
+	for i, key := range keys {
+		results[i] = pipe.Get(ctx, key)
+	}
+	_, err := pipe.Exec(ctx)
+	m.metrics.RecordGet(time.Since(start))
+	if err != nil && err != redis.Nil {
+		return nil, fmt.Errorf("redis get error: %w", m.recordError(ctx, err))
+	}
+
+	values := make(map[string][]byte, len(keys))
+	for i, key := range keys {
+		if m.hotKeys != nil {
+			m.hotKeys.record(key)
+		}
+		if results[i].Err() != nil {
+			m.metrics.RecordCacheMiss()
+			continue
+		}
+		m.metrics.RecordCacheHit()
+		values[key] = []byte(results[i].Val())
+	}
+	return values, nil
+}
+
+// GetManyValues reads and deserializes several values stored by SetValue in one round trip
+// newTarget returns a pointer to decode each value into (e.g. func() interface{} { return &User{} }).
+// Keys that are not cached, hold a null marker or cannot be decoded (corrupt values are
+// discarded as in GetValue) are omitted from the result.
+func (m *Manager) GetManyValues(ctx context.Context, keys []string, newTarget func() interface{}) (map[string]interface{}, error) {
+	data, err := m.GetMany(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]interface{}, len(data))
+	for key, value := range data {
+		if bytes.Equal(value, nullValueMarker) {
+			continue
+		}
+		target := newTarget()
+		if err := m.decodeValue(ctx, value, target); err != nil {
+			_ = m.discardCorruptValue(ctx, key, err)
+			continue
+		}
+		values[key] = target
+	}
+	return values, nil
+}
+
+// discardCorruptValue handles a cached value that cannot be deserialized
+// (corrupt payload or incompatible schema version). The key is deleted so later
+// reads fall through to the database and re-cache it. The returned error wraps both
+// ErrKeyNotFound and ErrSerializationFailed, so callers treat it as a cache miss.
+func (m *Manager) discardCorruptValue(ctx context.Context, key string, cause error) error {
+	m.metrics.RecordCacheError()
+	m.logger.Printf("sql4go: discarding corrupt cache value for key %s: %v", key, cause)
+
+	if err := m.DeleteLarge(ctx, key); err != nil {
+		m.logger.Printf("sql4go: failed to delete corrupt cache key %s: %v", key, err)
+	}
+
+	return fmt.Errorf("%w: %w: %v", ErrKeyNotFound, ErrSerializationFailed, cause)
+}
+
+// SetNull caches an empty or not-found result for NullCacheTTL
+// Reads of the key via GetValue/GetLargeValue return ErrNullValue
+func (m *Manager) SetNull(ctx context.Context, key string) error {
+	ttl := m.config.NullCacheTTL
+	if ttl <= 0 {
+		ttl = m.config.DefaultTTL
+	}
+	return m.SetWithTTL(ctx, key, nullValueMarker, ttl)
+}
+
 // Exists checks if a key exists in cache
 func (m *Manager) Exists(ctx context.Context, key string) (bool, error) {
 	if err := m.checkClient(); err != nil {
@@ -579,14 +1079,23 @@ func (m *Manager) getLargeValueConfig() (maxSize, chunkSize, compressThreshold i
 
 // SetLarge stores large values using compression and chunking if needed
 func (m *Manager) SetLarge(ctx context.Context, key string, value []byte) error {
+	return m.SetLargeWithTTL(ctx, key, value, m.config.DefaultTTL)
+}
+
+// SetLargeWithTTL stores large values with a custom TTL using compression and chunking if needed
+func (m *Manager) SetLargeWithTTL(ctx context.Context, key string, value []byte, ttl time.Duration) error {
 	if err := m.checkClient(); err != nil {
 		return err
 	}
+	if err := m.validateKey(key); err != nil {
+		return err
+	}
 
 	maxSize, chunkSize, compressThreshold, enableCompression, enableChunking := m.getLargeValueConfig()
 
-	// Check if value exceeds maximum allowed size
-	if len(value) > maxSize {
+	// MaxValueSize limits the stored size: with compression enabled that is the compressed
+	// size (checked below), so compressible values above it still fit
+	if len(value) > maxSize && !enableCompression {
 		return fmt.Errorf("value too large: %d bytes exceeds maximum %d bytes", len(value), maxSize)
 	}
 
@@ -594,7 +1103,7 @@ func (m *Manager) SetLarge(ctx context.Context, key string, value []byte) error
 	processedValue := value
 	compressed := false
 
-	if enableCompression && len(value) > compressThreshold {
+	if enableCompression && (len(value) > compressThreshold || len(value) > maxSize) {
 		compressedValue, err := m.compressData(value)
 		if err != nil {
 			return fmt.Errorf("failed to compress large value: %w", err)
@@ -610,14 +1119,18 @@ func (m *Manager) SetLarge(ctx context.Context, key string, value []byte) error
 		}
 	}
 
+	if len(processedValue) > maxSize {
+		return fmt.Errorf("value too large: %d bytes (%d after compression) exceeds maximum %d bytes", len(value), len(processedValue), maxSize)
+	}
+
 	// Check if chunking is needed and enabled
 	if enableChunking && len(processedValue) > chunkSize {
 		m.metrics.RecordChunked()
-		return m.setChunked(ctx, key, processedValue, compressed, chunkSize)
+		return m.setChunked(ctx, key, processedValue, compressed, chunkSize, ttl)
 	}
 
 	// Store normally with compression metadata
-	return m.setWithMetadata(ctx, key, processedValue, compressed, false)
+	return m.setWithMetadata(ctx, key, processedValue, compressed, false, ttl)
 }
 
 // GetLarge retrieves large values, handling decompression and chunk reassembly
@@ -625,6 +1138,9 @@ func (m *Manager) GetLarge(ctx context.Context, key string) ([]byte, error) {
 	if err := m.checkClient(); err != nil {
 		return nil, err
 	}
+	if err := m.checkKey(key); err != nil {
+		return nil, err
+	}
 
 	// Check if this is a chunked value
 	isChunked, err := m.isChunkedValue(ctx, key)
@@ -636,20 +1152,29 @@ func (m *Manager) GetLarge(ctx context.Context, key string) ([]byte, error) {
 	var compressed bool
 
 	if isChunked {
-		data, compressed, err = m.getChunked(ctx, key)
-		if err != nil {
-			return nil, err
+		// Non-chunked values are tracked by Get
+		if m.hotKeys != nil {
+			m.hotKeys.record(key)
 		}
+		data, compressed, err = m.getChunked(ctx, key)
 	} else {
 		data, compressed, err = m.getWithMetadata(ctx, key)
-		if err != nil {
-			return nil, err
+	}
+	if err != nil {
+		// Malformed metadata or missing chunks would fail every read until TTL
+		if IsSerializationFailed(err) {
+			return nil, m.discardCorruptValue(ctx, key, err)
 		}
+		return nil, err
 	}
 
 	// Decompress if needed
 	if compressed {
-		return m.decompressData(data)
+		decompressed, err := m.decompressData(data)
+		if err != nil {
+			return nil, m.discardCorruptValue(ctx, key, fmt.Errorf("%w: failed to decompress: %v", ErrSerializationFailed, err))
+		}
+		return decompressed, nil
 	}
 
 	return data, nil
@@ -658,12 +1183,20 @@ func (m *Manager) GetLarge(ctx context.Context, key string) ([]byte, error) {
 // SetLargeValue stores large values with compression and chunking
 // Uses configured serialization format (JSON or MessagePack)
 func (m *Manager) SetLargeValue(ctx context.Context, key string, value interface{}) error {
-	data, err := m.marshal(value)
+	return m.SetLargeValueWithTTL(ctx, key, value, m.config.DefaultTTL)
+}
+
+// SetLargeValueWithTTL stores large values with a custom TTL
+// Uses configured serialization format (JSON or MessagePack)
+func (m *Manager) SetLargeValueWithTTL(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	data, err := m.encodeValue(ctx, key, value)
 	if err != nil {
 		return fmt.Errorf("failed to marshal value: %w", err)
 	}
 
-	return m.SetLarge(ctx, key, data)
+	err = m.SetLargeWithTTL(ctx, key, data, ttl)
+	m.deferPopulation(ctx, key, data, nil, ttl, true, err)
+	return err
 }
 
 // GetLargeValue retrieves and unmarshals large values
@@ -679,7 +1212,15 @@ func (m *Manager) GetLargeValue(ctx context.Context, key string, target interfac
 		return ErrKeyNotFound
 	}
 
-	return m.unmarshal(data, target)
+	if bytes.Equal(data, nullValueMarker) {
+		return ErrNullValue
+	}
+
+	if err := m.decodeValue(ctx, data, target); err != nil {
+		return m.discardCorruptValue(ctx, key, err)
+	}
+
+	return nil
 }
 
 // compressData compresses data using gzip
@@ -711,31 +1252,47 @@ func (m *Manager) decompressData(data []byte) ([]byte, error) {
 }
 
 // setChunked stores large values in chunks
-func (m *Manager) setChunked(ctx context.Context, key string, data []byte, compressed bool, chunkSize int) error {
+func (m *Manager) setChunked(ctx context.Context, key string, data []byte, compressed bool, chunkSize int, ttl time.Duration) error {
 	chunkCount := (len(data) + chunkSize - 1) / chunkSize // Ceiling division
 
-	// Use pipeline for atomic chunked storage
-	pipe := m.client.Pipeline()
+	// Use pipeline for chunked storage
+	// With hash tags, the metadata and chunk keys share the key's slot, so the
+	// chunks can be written atomically in a MULTI/EXEC transaction
+	var pipe redis.Pipeliner
+	if m.HashTagsEnabled() {
+		pipe = m.client.TxPipeline()
+	} else {
+		pipe = m.client.Pipeline()
+	}
 
 	// Store metadata with internal suffix to prevent collisions
 	metadataKey := key + cacheMetadataSuffix
 	metadata := fmt.Sprintf("chunked:%t:%d", compressed, chunkCount)
-	pipe.Set(ctx, metadataKey, metadata, m.config.DefaultTTL)
+	pipe.Set(ctx, metadataKey, metadata, ttl)
+	writtenKeys := []string{metadataKey}
 
 	// Store chunks with internal prefix to prevent collisions
 	for i := 0; i < chunkCount; i++ {
-		start := i * chunkSize
-		end := start + chunkSize
-		if end > len(data) {
-			end = len(data)
-		}
+		start, end := chunkBounds(len(data), chunkSize, chunkCount, i, m.config.LargeValue.BalanceChunks)
 
 		chunkKey := fmt.Sprintf("%s%s:%d", key, cacheChunkPrefix, i)
-		pipe.Set(ctx, chunkKey, data[start:end], m.config.DefaultTTL)
+		pipe.Set(ctx, chunkKey, data[start:end], ttl)
+		writtenKeys = append(writtenKeys, chunkKey)
 	}
 
-	_, err := pipe.Exec(ctx)
-	return err
+	// Roll back so chunks are never left without metadata (or metadata without chunks)
+	return m.execWrite(ctx, pipe, writtenKeys)
+}
+
+// chunkBounds returns the byte range of chunk i of a value split into chunkCount chunks
+// Balanced chunks differ in size by at most one byte; otherwise all chunks but the last
+// are chunkSize bytes.
+func chunkBounds(size, chunkSize, chunkCount, i int, balanced bool) (start, end int) {
+	if balanced {
+		return i * size / chunkCount, (i + 1) * size / chunkCount
+	}
+	start = i * chunkSize
+	return start, min(start+chunkSize, size)
 }
 
 // getChunked retrieves and reassembles chunked values
@@ -753,13 +1310,13 @@ func (m *Manager) getChunked(ctx context.Context, key string) ([]byte, bool, err
 	metadata := metadataResult.Val()
 	parts := strings.Split(metadata, ":")
 	if len(parts) != 3 || parts[0] != "chunked" {
-		return nil, false, fmt.Errorf("invalid chunk metadata: %s", metadata)
+		return nil, false, fmt.Errorf("%w: invalid chunk metadata: %s", ErrSerializationFailed, metadata)
 	}
 
 	compressed := parts[1] == "true"
 	chunkCount, err := strconv.Atoi(parts[2])
-	if err != nil {
-		return nil, false, fmt.Errorf("invalid chunk count in metadata: %s", parts[2])
+	if err != nil || chunkCount < 0 {
+		return nil, false, fmt.Errorf("%w: invalid chunk count in metadata: %s", ErrSerializationFailed, parts[2])
 	}
 
 	// Retrieve all chunks
@@ -767,6 +1324,11 @@ func (m *Manager) getChunked(ctx context.Context, key string) ([]byte, bool, err
 	for i := 0; i < chunkCount; i++ {
 		chunkKey := fmt.Sprintf("%s%s:%d", key, cacheChunkPrefix, i)
 		chunkResult := m.client.Get(ctx, chunkKey)
+		if chunkResult.Err() == redis.Nil {
+			// A chunk expired or was evicted before its metadata; GetLarge discards the rest
+			m.metrics.RecordPartialValue()
+			return nil, false, fmt.Errorf("%w: missing chunk %d of %d", ErrSerializationFailed, i, chunkCount)
+		}
 		if chunkResult.Err() != nil {
 			return nil, false, fmt.Errorf("failed to get chunk %d: %w", i, chunkResult.Err())
 		}
@@ -783,21 +1345,21 @@ func (m *Manager) getChunked(ctx context.Context, key string) ([]byte, bool, err
 }
 
 // setWithMetadata stores value with compression metadata
-func (m *Manager) setWithMetadata(ctx context.Context, key string, data []byte, compressed, chunked bool) error {
+func (m *Manager) setWithMetadata(ctx context.Context, key string, data []byte, compressed, chunked bool, ttl time.Duration) error {
 	if compressed {
 		metadataKey := key + cacheMetadataSuffix
 		metadata := fmt.Sprintf("single:%t:1", compressed)
 
 		pipe := m.client.Pipeline()
-		pipe.Set(ctx, metadataKey, metadata, m.config.DefaultTTL)
-		pipe.Set(ctx, key, data, m.config.DefaultTTL)
+		pipe.Set(ctx, metadataKey, metadata, ttl)
+		pipe.Set(ctx, key, data, ttl)
 
-		_, err := pipe.Exec(ctx)
-		return err
+		// Roll back so compressed data is never left without its metadata
+		return m.execWrite(ctx, pipe, []string{metadataKey, key})
 	}
 
 	// Store normally without metadata for uncompressed values
-	return m.Set(ctx, key, data)
+	return m.SetWithTTL(ctx, key, data, ttl)
 }
 
 // getWithMetadata retrieves value with compression metadata
@@ -818,7 +1380,7 @@ func (m *Manager) getWithMetadata(ctx context.Context, key string) ([]byte, bool
 	metadata := metadataResult.Val()
 	parts := strings.Split(metadata, ":")
 	if len(parts) != 3 || parts[0] != "single" {
-		return nil, false, fmt.Errorf("invalid metadata: %s", metadata)
+		return nil, false, fmt.Errorf("%w: invalid metadata: %s", ErrSerializationFailed, metadata)
 	}
 
 	compressed := parts[1] == "true"
@@ -849,11 +1411,27 @@ func (m *Manager) DeleteLarge(ctx context.Context, key string) error {
 	if err := m.checkClient(); err != nil {
 		return err
 	}
+	if err := m.checkKey(key); err != nil {
+		return err
+	}
+	if m.deferred != nil {
+		m.deferred.discardKeys(key) // Also if looking up the chunks fails
+	}
 
+	keysToDelete, err := m.largeValueKeys(ctx, key)
+	if err != nil {
+		return err
+	}
+	return m.DeleteKeys(ctx, keysToDelete)
+}
+
+// largeValueKeys returns the keys storing a value: the key itself and, for compressed
+// or chunked values, its metadata and chunk keys
+func (m *Manager) largeValueKeys(ctx context.Context, key string) ([]string, error) {
 	// Check if chunked
 	isChunked, err := m.isChunkedValue(ctx, key)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	keysToDelete := []string{key}
@@ -865,14 +1443,22 @@ func (m *Manager) DeleteLarge(ctx context.Context, key string) error {
 		if metadataResult.Err() == nil {
 			metadata := metadataResult.Val()
 			parts := strings.Split(metadata, ":")
+			chunkCount := -1
 			if len(parts) == 3 {
-				if chunkCount, err := strconv.Atoi(parts[2]); err == nil {
-					// Add all chunk keys
-					for i := 0; i < chunkCount; i++ {
-						chunkKey := fmt.Sprintf("%s%s:%d", key, cacheChunkPrefix, i)
-						keysToDelete = append(keysToDelete, chunkKey)
-					}
+				if count, err := strconv.Atoi(parts[2]); err == nil {
+					chunkCount = count
+				}
+			}
+
+			if chunkCount >= 0 {
+				// Add all chunk keys
+				for i := 0; i < chunkCount; i++ {
+					chunkKey := fmt.Sprintf("%s%s:%d", key, cacheChunkPrefix, i)
+					keysToDelete = append(keysToDelete, chunkKey)
 				}
+			} else {
+				// Malformed metadata - chunk count unknown, so find the chunks by pattern (ignore errors - best effort)
+				_ = m.InvalidatePattern(ctx, escapePattern(key)+cacheChunkPrefix+":*")
 			}
 		}
 		keysToDelete = append(keysToDelete, metadataKey)
@@ -885,7 +1471,47 @@ func (m *Manager) DeleteLarge(ctx context.Context, key string) error {
 		}
 	}
 
-	return m.DeleteKeys(ctx, keysToDelete)
+	return keysToDelete, nil
+}
+
+// patternEscaper escapes Redis glob metacharacters
+var patternEscaper = strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`, `[`, `\[`, `]`, `\]`)
+
+// escapePattern escapes glob metacharacters so a key can be used as a literal SCAN pattern prefix
+func escapePattern(key string) string {
+	return patternEscaper.Replace(key)
+}
+
+// RecordCacheHitFor counts a cache hit of a logical read of a table (see Metrics.RecordCacheHitFor)
+func (m *Manager) RecordCacheHitFor(table string) {
+	m.metrics.RecordCacheHitFor(table)
+}
+
+// RecordCacheMissFor counts a cache miss of a logical read of a table
+func (m *Manager) RecordCacheMissFor(table string) {
+	m.metrics.RecordCacheMissFor(table)
+}
+
+// RecordRelationshipDepthExceeded counts a relationship traversal of a table's entity cut
+// by Invalidation.MaxRelationshipDepth (see Metrics.RecordRelationshipDepthExceeded)
+func (m *Manager) RecordRelationshipDepthExceeded(table string) {
+	m.metrics.RecordRelationshipDepthExceeded(table)
+}
+
+// RecordIncompleteInvalidation counts an invalidation after a write that failed or timed
+// out (see Metrics.RecordIncompleteInvalidation)
+func (m *Manager) RecordIncompleteInvalidation() {
+	m.metrics.RecordIncompleteInvalidation()
+}
+
+// RecordRetriedInvalidation counts an incomplete invalidation completed by a retry
+func (m *Manager) RecordRetriedInvalidation() {
+	m.metrics.RecordRetriedInvalidation()
+}
+
+// RecordDroppedInvalidation counts an incomplete invalidation given up on
+func (m *Manager) RecordDroppedInvalidation() {
+	m.metrics.RecordDroppedInvalidation()
 }
 
 // GetMetrics returns current cache performance metrics
@@ -901,4 +1527,7 @@ func (m *Manager) ResetMetrics() {
 	if m.metrics != nil {
 		m.metrics.Reset()
 	}
+	if m.hotKeys != nil {
+		m.hotKeys.reset()
+	}
 }
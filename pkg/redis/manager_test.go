@@ -0,0 +1,268 @@
+package redis
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func TestGetValueDiscardsCorruptValue(t *testing.T) {
+	m, mr := newTestManager(t)
+	key := "sql4go:app:users:s1:find_by_id:1"
+	mr.Set(key, "{not json")
+
+	var target map[string]interface{}
+	err := m.GetValue(context.Background(), key, &target)
+	if !IsKeyNotFound(err) || !IsSerializationFailed(err) {
+		t.Fatalf("GetValue of a corrupt value: err = %v, want a serialization cache miss", err)
+	}
+	if mr.Exists(key) {
+		t.Errorf("corrupt key %s was not deleted", key)
+	}
+}
+
+func TestGetLargeDiscardsMalformedChunkMetadata(t *testing.T) {
+	m, mr := newTestManager(t)
+	key := "sql4go:app:users:s1:find_all:x"
+	metadataKey := key + cacheMetadataSuffix
+	chunkKey := key + cacheChunkPrefix + ":0"
+	mr.Set(metadataKey, "chunked:true")
+	mr.Set(chunkKey, "partial")
+
+	_, err := m.GetLarge(context.Background(), key)
+	if !IsKeyNotFound(err) || !IsSerializationFailed(err) {
+		t.Fatalf("GetLarge with malformed metadata: err = %v, want a serialization cache miss", err)
+	}
+	for _, k := range []string{metadataKey, chunkKey} {
+		if mr.Exists(k) {
+			t.Errorf("key %s of the malformed value was not deleted", k)
+		}
+	}
+}
+
+func TestUsernameFlowsIntoSingleInstanceClient(t *testing.T) {
+	m, mr := newTestManager(t, func(c *Config) {
+		c.Username = "app"
+		c.Password = "secret"
+	})
+	mr.RequireUserAuth("app", "secret")
+
+	client, ok := m.client.(*redis.Client)
+	if !ok {
+		t.Fatalf("client is %T, want a single-instance *redis.Client", m.client)
+	}
+	if opts := client.Options(); opts.Username != "app" || opts.Password != "secret" {
+		t.Errorf("client options username/password = %q/%q, want app/secret", opts.Username, opts.Password)
+	}
+
+	// The ACL user authenticates against the server
+	if err := m.SetValue(context.Background(), "sql4go:app:users:s1:find_by_id:1", map[string]string{"name": "ann"}); err != nil {
+		t.Fatalf("SetValue as ACL user: %v", err)
+	}
+}
+
+func TestGetManyValuesDecodesSeveralEntities(t *testing.T) {
+	m, mr := newTestManager(t)
+	ctx := context.Background()
+
+	type user struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	}
+	key := func(id int) string { return "sql4go:app:users:s1:find_by_id:" + strconv.Itoa(id) }
+	for _, u := range []user{{ID: 1, Name: "ann"}, {ID: 2, Name: "bob"}, {ID: 3, Name: "cid"}} {
+		if err := m.SetValue(ctx, key(u.ID), u); err != nil {
+			t.Fatalf("SetValue: %v", err)
+		}
+	}
+	mr.Set(key(4), "{not json")
+
+	values, err := m.GetManyValues(ctx, []string{key(1), key(2), key(3), key(4), key(5)}, func() interface{} { return &user{} })
+	if err != nil {
+		t.Fatalf("GetManyValues: %v", err)
+	}
+	if len(values) != 3 {
+		t.Fatalf("decoded %d values, want 3 (missing and corrupt keys omitted): %v", len(values), values)
+	}
+	for id, name := range map[int]string{1: "ann", 2: "bob", 3: "cid"} {
+		u, ok := values[key(id)].(*user)
+		if !ok || u.ID != id || u.Name != name {
+			t.Errorf("value of %s = %#v, want user %d %q", key(id), values[key(id)], id, name)
+		}
+	}
+}
+
+func TestBalancedChunksAreEvenlySized(t *testing.T) {
+	ctx := context.Background()
+	value := bytes.Repeat([]byte("0123456789"), 210) // 2100 bytes over 1000-byte chunks
+
+	for _, tt := range []struct {
+		balanced bool
+		want     []int
+	}{
+		{balanced: true, want: []int{700, 700, 700}},
+		{balanced: false, want: []int{1000, 1000, 100}},
+	} {
+		m, mr := newTestManager(t, func(c *Config) {
+			c.LargeValue.EnableCompression = false
+			c.LargeValue.EnableChunking = true
+			c.LargeValue.ChunkSize = 1000
+			c.LargeValue.BalanceChunks = tt.balanced
+		})
+		key := "sql4go:app:reports:s1:find_all:all"
+		if err := m.SetLarge(ctx, key, value); err != nil {
+			t.Fatalf("SetLarge: %v", err)
+		}
+
+		for i, want := range tt.want {
+			chunk, err := mr.Get(fmt.Sprintf("%s%s:%d", key, cacheChunkPrefix, i))
+			if err != nil || len(chunk) != want {
+				t.Errorf("balanced=%v: chunk %d is %d bytes (%v), want %d", tt.balanced, i, len(chunk), err, want)
+			}
+		}
+		if got, err := m.GetLarge(ctx, key); err != nil || !bytes.Equal(got, value) {
+			t.Errorf("balanced=%v: GetLarge did not reassemble the value (%v)", tt.balanced, err)
+		}
+	}
+}
+
+func TestCompressibleValueFitsWithChunkingDisabled(t *testing.T) {
+	m, _ := newTestManager(t, func(c *Config) {
+		c.LargeValue.EnableChunking = false
+		c.LargeValue.EnableCompression = true
+		c.LargeValue.MaxValueSize = 1000
+	})
+	ctx := context.Background()
+
+	// 20KB that compresses far below MaxValueSize
+	value := bytes.Repeat([]byte(`{"name":"ann","email":"a@x"},`), 700)
+	key := "sql4go:app:users:s1:find_all:all"
+	if err := m.SetLarge(ctx, key, value); err != nil {
+		t.Fatalf("SetLarge of a compressible %d-byte value: %v", len(value), err)
+	}
+	if got, err := m.GetLarge(ctx, key); err != nil || !bytes.Equal(got, value) {
+		t.Fatalf("GetLarge did not return the value (%v)", err)
+	}
+
+	// Incompressible values are still checked against MaxValueSize
+	random := make([]byte, 4096)
+	_, _ = rand.Read(random)
+	if err := m.SetLarge(ctx, key, random); err == nil {
+		t.Error("SetLarge of an incompressible value over MaxValueSize succeeded")
+	}
+}
+
+func TestMaxValueSizeAppliesToCompressedSize(t *testing.T) {
+	m, mr := newTestManager(t, func(c *Config) {
+		c.LargeValue.EnableCompression = true
+		c.LargeValue.MaxValueSize = 2048
+	})
+	ctx := context.Background()
+
+	value := bytes.Repeat([]byte("sql4go "), 1000) // 7000 bytes raw
+	key := "sql4go:app:logs:s1:find_all:all"
+	if err := m.SetLarge(ctx, key, value); err != nil {
+		t.Fatalf("SetLarge of a value over MaxValueSize that compresses under it: %v", err)
+	}
+
+	stored := 0
+	for _, k := range mr.Keys() {
+		if strings.HasPrefix(k, key) {
+			data, _ := mr.Get(k)
+			stored += len(data)
+		}
+	}
+	if stored == 0 || stored > 2048 {
+		t.Errorf("stored %d bytes, want the compressed value under MaxValueSize", stored)
+	}
+	if got, err := m.GetLarge(ctx, key); err != nil || !bytes.Equal(got, value) {
+		t.Errorf("GetLarge did not return the original value (%v)", err)
+	}
+}
+
+func TestInvalidatePatternCountReportsDeletedKeys(t *testing.T) {
+	m, mr := newTestManager(t)
+	ctx := context.Background()
+
+	// More matching keys than one SCAN batch, and keys of another table
+	for i := 0; i < 250; i++ {
+		_ = mr.Set(fmt.Sprintf("sql4go:app:orders:s1:find_by_id:%d", i), "x")
+	}
+	for i := 0; i < 5; i++ {
+		_ = mr.Set(fmt.Sprintf("sql4go:app:users:s1:find_by_id:%d", i), "x")
+	}
+
+	deleted, err := m.InvalidatePatternCount(ctx, "sql4go:app:orders:*")
+	if err != nil || deleted != 250 {
+		t.Fatalf("InvalidatePatternCount = (%d, %v), want 250", deleted, err)
+	}
+	if keys := mr.Keys(); len(keys) != 5 {
+		t.Errorf("%d keys left, want the 5 keys of the other table", len(keys))
+	}
+
+	if deleted, err := m.InvalidatePatternCount(ctx, "sql4go:app:orders:*"); err != nil || deleted != 0 {
+		t.Errorf("second InvalidatePatternCount = (%d, %v), want 0", deleted, err)
+	}
+}
+
+func TestPartiallyFailedWritesAreRolledBack(t *testing.T) {
+	ctx := context.Background()
+	key := "sql4go:app:users:s1:find_all:all"
+	dependencies := map[string][]interface{}{"users": {1, 2}}
+	large := bytes.Repeat([]byte("0123456789"), 300) // Three 1000-byte chunks
+
+	tests := []struct {
+		name    string
+		command string
+		keyPart string
+		write   func(m *Manager) error
+	}{
+		{
+			name: "dependency SADD", command: "sadd", keyPart: ":deps:users:2",
+			write: func(m *Manager) error { return m.SetWithDependencies(ctx, key, []byte(`[]`), dependencies) },
+		},
+		{
+			name: "dependency EXPIRE", command: "expire", keyPart: ":deps:users:1",
+			write: func(m *Manager) error { return m.SetWithDependencies(ctx, key, []byte(`[]`), dependencies) },
+		},
+		{
+			name: "chunk SET", command: "set", keyPart: cacheChunkPrefix + ":1",
+			write: func(m *Manager) error { return m.SetLarge(ctx, key, large) },
+		},
+		{
+			name: "dependency SADD of a chunked value", command: "sadd", keyPart: ":deps:users:1",
+			write: func(m *Manager) error { return m.SetLargeWithDependencies(ctx, key, large, dependencies) },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, mr := newTestManager(t, func(c *Config) {
+				c.LargeValue.EnableCompression = false
+				c.LargeValue.EnableChunking = true
+				c.LargeValue.ChunkSize = 1000
+			})
+			failCommands(m, tt.command, tt.keyPart)
+
+			err := tt.write(m)
+			if !IsPartialWrite(err) || !errors.Is(err, errInjected) {
+				t.Fatalf("write error = %v, want ErrPartialWrite wrapping the injected failure", err)
+			}
+			for _, k := range mr.Keys() {
+				if strings.HasPrefix(k, key) {
+					t.Errorf("%s was left behind by the failed write", k)
+				}
+			}
+			if _, err := m.GetLarge(ctx, key); err == nil {
+				t.Error("GetLarge found the value of a failed write")
+			}
+		})
+	}
+}
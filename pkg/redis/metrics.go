@@ -1,16 +1,30 @@
 package redis
 
 import (
+	"sync"
 	"sync/atomic"
 	"time"
 )
 
+// maxTrackedOverflowEntities bounds the per-entity overflow counters
+const maxTrackedOverflowEntities = 100
+
+const (
+	defaultMaxMetricLabels = 100     // Per-table counters unless Config.MaxMetricLabels is set
+	OtherMetricLabel       = "other" // Label of the tables beyond the label limit
+)
+
 // Metrics tracks cache performance statistics
 type Metrics struct {
 	// Cache hit/miss counters
 	cacheHits   atomic.Uint64
 	cacheMisses atomic.Uint64
 	cacheErrors atomic.Uint64
+	localHits   atomic.Uint64 // Hits served by the in-process L1 cache (included in cacheHits)
+
+	// Aborted operations, counted apart from cacheErrors
+	cacheTimeouts      atomic.Uint64
+	cacheCancellations atomic.Uint64
 
 	// Operation counters
 	getOperations    atomic.Uint64
@@ -25,10 +39,54 @@ type Metrics struct {
 	// Large value metrics
 	compressionSaves  atomic.Uint64 // Bytes saved via compression
 	chunkedOperations atomic.Uint64
+	oversizeSkips     atomic.Uint64 // Values not cached due to LargeValue.SkipCachingAbove
 
 	// Invalidation metrics
-	invalidationCount atomic.Uint64
-	dependencyCount   atomic.Uint64
+	invalidationCount      atomic.Uint64
+	dependencyCount        atomic.Uint64
+	patternInvalidations   atomic.Uint64 // Pattern wipes executed
+	coalescedInvalidations atomic.Uint64 // Pattern wipes merged into a pending one
+	depthExceeded          atomic.Uint64 // Relationship traversals cut by Invalidation.MaxRelationshipDepth
+
+	// Invalidation completion metrics (see Invalidation.Timeout)
+	incompleteInvalidations atomic.Uint64 // Invalidations after writes that failed or timed out
+	retriedInvalidations    atomic.Uint64 // Incomplete invalidations completed by a retry
+	droppedInvalidations    atomic.Uint64 // Incomplete invalidations given up on
+
+	// Dependency set overflow metrics
+	dependencyOverflows atomic.Uint64
+	overflowMu          sync.Mutex
+	overflowsByEntity   map[string]uint64 // "entityType:id" -> diverted registrations
+	tableDependencies   atomic.Uint64     // Registrations tracked by table-level dependency sets
+
+	// Deferred population metrics (see DeferredPopulationConfig)
+	deferredPopulations     atomic.Uint64 // Failed stores queued for retry
+	deferredPopulated       atomic.Uint64 // Queued stores written by a retry
+	deferredPopulationDrops atomic.Uint64 // Stores dropped: over MaxBytes or out of attempts
+
+	// maxmemory eviction metrics (see Manager.CheckEvictions)
+	serverEvictions       atomic.Uint64 // Growth of the server's evicted_keys counter
+	partialValues         atomic.Uint64 // Large values read with missing chunks
+	missingDependencySets atomic.Uint64 // Invalidations falling back to a pattern wipe
+
+	// Per-table counters of logical reads, bounded by maxLabels
+	labelsMu  sync.RWMutex
+	labels    map[string]*labelCounters
+	maxLabels int // 0 = defaultMaxMetricLabels
+}
+
+// labelCounters are the per-table counters of one label
+type labelCounters struct {
+	hits   atomic.Uint64
+	misses atomic.Uint64
+
+	// Dictionary compression (see Manager.TrainDictionary)
+	dictionaryValues          atomic.Uint64
+	dictionaryOriginalBytes   atomic.Uint64
+	dictionaryCompressedBytes atomic.Uint64
+
+	oversizeSkips atomic.Uint64 // Values not cached due to LargeValue.SkipCachingAbove
+	depthExceeded atomic.Uint64 // Relationship traversals cut by Invalidation.MaxRelationshipDepth
 }
 
 // NewMetrics creates a new metrics instance
@@ -41,6 +99,11 @@ func (m *Metrics) RecordCacheHit() {
 	m.cacheHits.Add(1)
 }
 
+// RecordLocalHit increments the counter of hits served by the L1 cache
+func (m *Metrics) RecordLocalHit() {
+	m.localHits.Add(1)
+}
+
 // RecordCacheMiss increments cache miss counter
 func (m *Metrics) RecordCacheMiss() {
 	m.cacheMisses.Add(1)
@@ -51,6 +114,16 @@ func (m *Metrics) RecordCacheError() {
 	m.cacheErrors.Add(1)
 }
 
+// RecordCacheTimeout increments the counter of operations aborted by a deadline
+func (m *Metrics) RecordCacheTimeout() {
+	m.cacheTimeouts.Add(1)
+}
+
+// RecordCacheCancellation increments the counter of operations aborted by cancellation
+func (m *Metrics) RecordCacheCancellation() {
+	m.cacheCancellations.Add(1)
+}
+
 // RecordGet records a get operation with latency
 func (m *Metrics) RecordGet(duration time.Duration) {
 	m.getOperations.Add(1)
@@ -79,11 +152,157 @@ func (m *Metrics) RecordChunked() {
 	m.chunkedOperations.Add(1)
 }
 
+// RecordOversizeSkip records a value of a table not cached due to LargeValue.SkipCachingAbove
+func (m *Metrics) RecordOversizeSkip(table string) {
+	m.oversizeSkips.Add(1)
+	m.labelCounters(table).oversizeSkips.Add(1)
+}
+
+// RecordRelationshipDepthExceeded records a relationship traversal of an entity of a table
+// that stopped at Invalidation.MaxRelationshipDepth with loaded associations left
+func (m *Metrics) RecordRelationshipDepthExceeded(table string) {
+	m.depthExceeded.Add(1)
+	m.labelCounters(table).depthExceeded.Add(1)
+}
+
+// RecordIncompleteInvalidation records an invalidation after a write that failed or
+// timed out, queued for retry
+func (m *Metrics) RecordIncompleteInvalidation() {
+	m.incompleteInvalidations.Add(1)
+}
+
+// RecordRetriedInvalidation records an incomplete invalidation completed by a retry
+func (m *Metrics) RecordRetriedInvalidation() {
+	m.retriedInvalidations.Add(1)
+}
+
+// RecordDroppedInvalidation records an incomplete invalidation given up on
+func (m *Metrics) RecordDroppedInvalidation() {
+	m.droppedInvalidations.Add(1)
+}
+
+// RecordDeferredPopulation records a failed cache store queued for retry
+func (m *Metrics) RecordDeferredPopulation() {
+	m.deferredPopulations.Add(1)
+}
+
+// RecordDeferredPopulated records a queued cache store written by a retry
+func (m *Metrics) RecordDeferredPopulated() {
+	m.deferredPopulated.Add(1)
+}
+
+// RecordDeferredPopulationDrop records a failed cache store given up on
+func (m *Metrics) RecordDeferredPopulationDrop() {
+	m.deferredPopulationDrops.Add(1)
+}
+
 // RecordInvalidation increments invalidation counter
 func (m *Metrics) RecordInvalidation() {
 	m.invalidationCount.Add(1)
 }
 
+// RecordPatternInvalidation increments executed pattern invalidation counter
+func (m *Metrics) RecordPatternInvalidation() {
+	m.patternInvalidations.Add(1)
+}
+
+// RecordCoalescedInvalidation increments coalesced pattern invalidation counter
+func (m *Metrics) RecordCoalescedInvalidation() {
+	m.coalescedInvalidations.Add(1)
+}
+
+// RecordTableDependency records a registration of an entity type tracked by its
+// table-level dependency set (see Invalidation.MaxDependenciesPerKey)
+func (m *Metrics) RecordTableDependency() {
+	m.tableDependencies.Add(1)
+}
+
+// RecordDependencyOverflow records a dependency diverted from an overflowed set
+// Per-entity counts are kept for at most maxTrackedOverflowEntities entities
+func (m *Metrics) RecordDependencyOverflow(entity string) {
+	m.dependencyOverflows.Add(1)
+
+	m.overflowMu.Lock()
+	defer m.overflowMu.Unlock()
+	if m.overflowsByEntity == nil {
+		m.overflowsByEntity = make(map[string]uint64)
+	}
+	if _, tracked := m.overflowsByEntity[entity]; tracked || len(m.overflowsByEntity) < maxTrackedOverflowEntities {
+		m.overflowsByEntity[entity]++
+	}
+}
+
+// RecordCacheHitFor counts a cache hit of a logical read of a table
+// Repositories record one hit or miss per read (FindByID, FindWhere, ...), unlike
+// CacheHits, which counts raw Redis reads. Beyond the label limit, tables are counted
+// under OtherMetricLabel.
+func (m *Metrics) RecordCacheHitFor(table string) {
+	m.labelCounters(table).hits.Add(1)
+}
+
+// RecordCacheMissFor counts a cache miss of a logical read of a table
+func (m *Metrics) RecordCacheMissFor(table string) {
+	m.labelCounters(table).misses.Add(1)
+}
+
+// RecordDictionaryCompression records a value of a table stored dictionary-compressed
+func (m *Metrics) RecordDictionaryCompression(table string, originalBytes, compressedBytes int) {
+	counters := m.labelCounters(table)
+	counters.dictionaryValues.Add(1)
+	counters.dictionaryOriginalBytes.Add(uint64(originalBytes))
+	counters.dictionaryCompressedBytes.Add(uint64(compressedBytes))
+	m.compressionSaves.Add(uint64(originalBytes - compressedBytes))
+}
+
+// labelCounters returns the counters of a label, creating them on first use
+func (m *Metrics) labelCounters(label string) *labelCounters {
+	m.labelsMu.RLock()
+	counters, ok := m.labels[label]
+	m.labelsMu.RUnlock()
+	if ok {
+		return counters
+	}
+
+	m.labelsMu.Lock()
+	defer m.labelsMu.Unlock()
+	if counters, ok = m.labels[label]; ok {
+		return counters
+	}
+	if m.labels == nil {
+		m.labels = make(map[string]*labelCounters)
+	}
+
+	maxLabels := m.maxLabels
+	if maxLabels <= 0 {
+		maxLabels = defaultMaxMetricLabels
+	}
+	if len(m.labels) >= maxLabels {
+		label = OtherMetricLabel // Cardinality guard
+		if counters, ok = m.labels[label]; ok {
+			return counters
+		}
+	}
+
+	counters = &labelCounters{}
+	m.labels[label] = counters
+	return counters
+}
+
+// RecordServerEvictions adds keys evicted by the server under maxmemory
+func (m *Metrics) RecordServerEvictions(count uint64) {
+	m.serverEvictions.Add(count)
+}
+
+// RecordPartialValue increments the counter of large values found with missing chunks
+func (m *Metrics) RecordPartialValue() {
+	m.partialValues.Add(1)
+}
+
+// RecordMissingDependencySet increments the counter of invalidations without a dependency set
+func (m *Metrics) RecordMissingDependencySet() {
+	m.missingDependencySets.Add(1)
+}
+
 // RecordDependency increments dependency counter
 func (m *Metrics) RecordDependency() {
 	m.dependencyCount.Add(1)
@@ -116,20 +335,40 @@ func (m *Metrics) GetSnapshot() MetricsSnapshot {
 	}
 
 	return MetricsSnapshot{
-		CacheHits:             hits,
-		CacheMisses:           misses,
-		CacheErrors:           m.cacheErrors.Load(),
-		CacheHitRate:          hitRate,
-		GetOperations:         getOps,
-		SetOperations:         setOps,
-		DeleteOperations:      deleteOps,
-		AvgGetLatency:         avgGetLatency,
-		AvgSetLatency:         avgSetLatency,
-		AvgDeleteLatency:      avgDeleteLatency,
-		CompressionBytesSaved: m.compressionSaves.Load(),
-		ChunkedOperations:     m.chunkedOperations.Load(),
-		InvalidationCount:     m.invalidationCount.Load(),
-		DependencyCount:       m.dependencyCount.Load(),
+		CacheHits:                 hits,
+		CacheMisses:               misses,
+		CacheErrors:               m.cacheErrors.Load(),
+		LocalHits:                 m.localHits.Load(),
+		CacheTimeouts:             m.cacheTimeouts.Load(),
+		CacheCancellations:        m.cacheCancellations.Load(),
+		CacheHitRate:              hitRate,
+		GetOperations:             getOps,
+		SetOperations:             setOps,
+		DeleteOperations:          deleteOps,
+		AvgGetLatency:             avgGetLatency,
+		AvgSetLatency:             avgSetLatency,
+		AvgDeleteLatency:          avgDeleteLatency,
+		CompressionBytesSaved:     m.compressionSaves.Load(),
+		ChunkedOperations:         m.chunkedOperations.Load(),
+		OversizeSkips:             m.oversizeSkips.Load(),
+		InvalidationCount:         m.invalidationCount.Load(),
+		DependencyCount:           m.dependencyCount.Load(),
+		PatternInvalidations:      m.patternInvalidations.Load(),
+		CoalescedInvalidations:    m.coalescedInvalidations.Load(),
+		RelationshipDepthExceeded: m.depthExceeded.Load(),
+		IncompleteInvalidations:   m.incompleteInvalidations.Load(),
+		RetriedInvalidations:      m.retriedInvalidations.Load(),
+		DroppedInvalidations:      m.droppedInvalidations.Load(),
+		DependencyOverflows:       m.dependencyOverflows.Load(),
+		OverflowsByEntity:         m.overflowSnapshot(),
+		TableDependencies:         m.tableDependencies.Load(),
+		DeferredPopulations:       m.deferredPopulations.Load(),
+		DeferredPopulated:         m.deferredPopulated.Load(),
+		DeferredPopulationDrops:   m.deferredPopulationDrops.Load(),
+		ServerEvictions:           m.serverEvictions.Load(),
+		PartialValues:             m.partialValues.Load(),
+		MissingDependencySets:     m.missingDependencySets.Load(),
+		ByTable:                   m.labelSnapshot(),
 	}
 }
 
@@ -138,6 +377,9 @@ func (m *Metrics) Reset() {
 	m.cacheHits.Store(0)
 	m.cacheMisses.Store(0)
 	m.cacheErrors.Store(0)
+	m.localHits.Store(0)
+	m.cacheTimeouts.Store(0)
+	m.cacheCancellations.Store(0)
 	m.getOperations.Store(0)
 	m.setOperations.Store(0)
 	m.deleteOperations.Store(0)
@@ -146,8 +388,70 @@ func (m *Metrics) Reset() {
 	m.totalDeleteLatency.Store(0)
 	m.compressionSaves.Store(0)
 	m.chunkedOperations.Store(0)
+	m.oversizeSkips.Store(0)
 	m.invalidationCount.Store(0)
 	m.dependencyCount.Store(0)
+	m.patternInvalidations.Store(0)
+	m.coalescedInvalidations.Store(0)
+	m.depthExceeded.Store(0)
+	m.incompleteInvalidations.Store(0)
+	m.retriedInvalidations.Store(0)
+	m.droppedInvalidations.Store(0)
+	m.dependencyOverflows.Store(0)
+	m.tableDependencies.Store(0)
+	m.deferredPopulations.Store(0)
+	m.deferredPopulated.Store(0)
+	m.deferredPopulationDrops.Store(0)
+	m.serverEvictions.Store(0)
+	m.partialValues.Store(0)
+	m.missingDependencySets.Store(0)
+
+	m.overflowMu.Lock()
+	m.overflowsByEntity = nil
+	m.overflowMu.Unlock()
+
+	m.labelsMu.Lock()
+	m.labels = nil
+	m.labelsMu.Unlock()
+}
+
+// labelSnapshot copies the per-table counters
+func (m *Metrics) labelSnapshot() map[string]TableMetrics {
+	m.labelsMu.RLock()
+	defer m.labelsMu.RUnlock()
+
+	snapshot := make(map[string]TableMetrics, len(m.labels))
+	for label, counters := range m.labels {
+		stats := TableMetrics{
+			Hits:                      counters.hits.Load(),
+			Misses:                    counters.misses.Load(),
+			DictionaryValues:          counters.dictionaryValues.Load(),
+			DictionaryOriginalBytes:   counters.dictionaryOriginalBytes.Load(),
+			DictionaryCompressedBytes: counters.dictionaryCompressedBytes.Load(),
+			OversizeSkips:             counters.oversizeSkips.Load(),
+			RelationshipDepthExceeded: counters.depthExceeded.Load(),
+		}
+		if total := stats.Hits + stats.Misses; total > 0 {
+			stats.HitRate = float64(stats.Hits) / float64(total) * 100
+		}
+		if stats.DictionaryCompressedBytes > 0 {
+			stats.DictionaryRatio = float64(stats.DictionaryOriginalBytes) / float64(stats.DictionaryCompressedBytes)
+		}
+		snapshot[label] = stats
+	}
+	return snapshot
+}
+
+// overflowSnapshot copies the per-entity overflow counters
+func (m *Metrics) overflowSnapshot() map[string]uint64 {
+	m.overflowMu.Lock()
+	defer m.overflowMu.Unlock()
+
+	snapshot := make(map[string]uint64, len(m.overflowsByEntity))
+	for entity, count := range m.overflowsByEntity {
+		snapshot[entity] = count
+	}
+	return snapshot
 }
 
 // MetricsSnapshot represents a point-in-time snapshot of metrics
@@ -157,6 +461,11 @@ type MetricsSnapshot struct {
 	CacheMisses  uint64
 	CacheErrors  uint64
 	CacheHitRate float64 // Percentage
+	LocalHits    uint64  // Hits served by the L1 cache, included in CacheHits
+
+	// Aborted operations (not included in CacheErrors)
+	CacheTimeouts      uint64
+	CacheCancellations uint64
 
 	// Operation counts
 	GetOperations    uint64
@@ -171,8 +480,56 @@ type MetricsSnapshot struct {
 	// Large value metrics
 	CompressionBytesSaved uint64
 	ChunkedOperations     uint64
+	OversizeSkips         uint64 // Query results not cached due to LargeValue.SkipCachingAbove
 
 	// Invalidation metrics
-	InvalidationCount uint64
-	DependencyCount   uint64
+	InvalidationCount      uint64
+	DependencyCount        uint64
+	PatternInvalidations   uint64 // Pattern wipes executed
+	CoalescedInvalidations uint64 // Pattern wipes absorbed by the debounce window
+
+	// RelationshipDepthExceeded counts relationship traversals cut by
+	// Invalidation.MaxRelationshipDepth: loaded associations deeper than the limit are not
+	// tracked, so changes to them do not invalidate the cached values embedding them
+	RelationshipDepthExceeded uint64
+
+	// Invalidation completion metrics (see Invalidation.Timeout)
+	IncompleteInvalidations uint64 // Invalidations after writes that failed or timed out, queued for retry
+	RetriedInvalidations    uint64 // Queued invalidations completed by a retry
+	DroppedInvalidations    uint64 // Queued invalidations given up: queue full, out of attempts or closed
+
+	// Dependency set overflow metrics
+	DependencyOverflows uint64            // Dependencies diverted from overflowed sets
+	OverflowsByEntity   map[string]uint64 // Hot entities ("entityType:id"), bounded
+	TableDependencies   uint64            // Registrations above MaxDependenciesPerKey, tracked per table
+
+	// Deferred population metrics
+	DeferredPopulations     uint64 // Failed cache stores queued for retry
+	DeferredPopulated       uint64 // Queued stores written by a retry
+	DeferredPopulationDrops uint64 // Stores dropped: over MaxBytes or still failing after MaxAttempts
+
+	// maxmemory eviction metrics
+	ServerEvictions       uint64 // Keys evicted by the server since the first check (see Manager.CheckEvictions)
+	PartialValues         uint64 // Large values found with evicted chunks and discarded
+	MissingDependencySets uint64 // Invalidations that fell back to a pattern wipe
+
+	// Per-table logical reads recorded by repositories and dictionary compression,
+	// bounded by Config.MaxMetricLabels
+	ByTable map[string]TableMetrics
+}
+
+// TableMetrics are the cache hits and misses of the logical reads of one table and the
+// dictionary compression of its values
+type TableMetrics struct {
+	Hits    uint64
+	Misses  uint64
+	HitRate float64 // Percentage
+
+	DictionaryValues          uint64  // Values stored dictionary-compressed
+	DictionaryOriginalBytes   uint64  // Their size before compression
+	DictionaryCompressedBytes uint64  // Their stored size
+	DictionaryRatio           float64 // Original / compressed size (0 if none)
+
+	OversizeSkips             uint64 // Query results not cached due to LargeValue.SkipCachingAbove
+	RelationshipDepthExceeded uint64 // Relationship traversals of the table's entities cut by MaxRelationshipDepth
 }
@@ -0,0 +1,60 @@
+package redis
+
+import (
+	"fmt"
+	"strings"
+)
+
+// reservedKeyMarker marks the internal keys derived from a caller's key
+// Values are stored under the caller's key, with internal keys derived from it by a
+// suffix: "<key>_internal:meta" (compression and chunk metadata), "<key>_internal:chunk:<n>"
+// (chunks) and "<key>_internal:lock" (regeneration locks). A caller's key containing
+// "_internal:" could name such a key of another value - writing "a_internal:meta"
+// overwrites the metadata of "a" - so the read, write and delete methods reject keys
+// containing the marker with ErrInvalidKey. Keys built by the repositories never
+// contain it. Config.AllowReservedKeys turns the check off for existing key schemes.
+const reservedKeyMarker = "_internal:"
+
+// checkKey rejects a caller's key containing the reserved internal marker
+func (m *Manager) checkKey(key string) error {
+	if m.config.AllowReservedKeys || !strings.Contains(key, reservedKeyMarker) {
+		return nil
+	}
+	return fmt.Errorf("%w: %q contains the reserved marker %q", ErrInvalidKey, key, reservedKeyMarker)
+}
+
+// checkKeys is checkKey for several keys
+func (m *Manager) checkKeys(keys []string) error {
+	for _, key := range keys {
+		if err := m.checkKey(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// maxKeyBytes is the longest key the Set methods accept
+const maxKeyBytes = 1024
+
+// validateKey checks a key written by a Set method: the reserved marker (see checkKey),
+// then the key constraints. Keys are written verbatim, so the Set methods also reject
+// keys that are almost certainly programming errors: empty keys, keys longer than
+// maxKeyBytes (well above Config.MaxKeyLength, which repository keys respect) and keys
+// containing spaces or control characters such as newlines, which break redis-cli, SCAN
+// patterns and logs. Repository keys hash suffixes containing them, so only caller-built
+// keys are affected.
+func (m *Manager) validateKey(key string) error {
+	if err := m.checkKey(key); err != nil {
+		return err
+	}
+	if key == "" {
+		return fmt.Errorf("%w: key cannot be empty", ErrInvalidKey)
+	}
+	if len(key) > maxKeyBytes {
+		return fmt.Errorf("%w: key of %d bytes exceeds %d bytes", ErrInvalidKey, len(key), maxKeyBytes)
+	}
+	if strings.IndexFunc(key, func(c rune) bool { return c <= ' ' || c == 0x7f }) >= 0 {
+		return fmt.Errorf("%w: %q contains a space or control character", ErrInvalidKey, key)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package redis
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReservedMarkerKeysAreRejected(t *testing.T) {
+	m, mr := newTestManager(t)
+	ctx := context.Background()
+
+	mr.Set("a", "1")
+	key := "a" + cacheMetadataSuffix // Would overwrite the metadata of "a"
+	if err := m.Set(ctx, key, []byte("chunked:1:1")); !IsInvalidKey(err) {
+		t.Errorf("Set(%q) = %v, want ErrInvalidKey", key, err)
+	}
+	if err := m.SetLargeWithTTL(ctx, key, []byte("x"), time.Minute); !IsInvalidKey(err) {
+		t.Errorf("SetLargeWithTTL(%q) = %v, want ErrInvalidKey", key, err)
+	}
+	if _, err := m.Get(ctx, key); !IsInvalidKey(err) {
+		t.Errorf("Get(%q) = %v, want ErrInvalidKey", key, err)
+	}
+	if err := m.DeleteLarge(ctx, key); !IsInvalidKey(err) {
+		t.Errorf("DeleteLarge(%q) = %v, want ErrInvalidKey", key, err)
+	}
+	if mr.Exists(key) {
+		t.Errorf("reserved key %q was written", key)
+	}
+
+	// Existing key schemes can opt out
+	m.config.AllowReservedKeys = true
+	if err := m.Set(ctx, "legacy_internal:1", []byte("x")); err != nil {
+		t.Errorf("Set with AllowReservedKeys = %v, want nil", err)
+	}
+}
+
+func TestSetRejectsMalformedKeys(t *testing.T) {
+	m, mr := newTestManager(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		key  string
+	}{
+		{name: "over-long", key: "sql4go:app:users:" + strings.Repeat("x", maxKeyBytes)},
+		{name: "newline", key: "sql4go:app:users:1\n"},
+		{name: "space", key: "sql4go:app:users:ada lovelace"},
+		{name: "empty", key: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := m.Set(ctx, tt.key, []byte("x")); !IsInvalidKey(err) {
+				t.Errorf("Set = %v, want ErrInvalidKey", err)
+			}
+			if err := m.SetLargeWithTTL(ctx, tt.key, []byte("x"), time.Minute); !IsInvalidKey(err) {
+				t.Errorf("SetLargeWithTTL = %v, want ErrInvalidKey", err)
+			}
+		})
+	}
+	if keys := mr.Keys(); len(keys) != 0 {
+		t.Errorf("malformed keys were written: %q", keys)
+	}
+
+	// The longest accepted key
+	if err := m.Set(ctx, strings.Repeat("k", maxKeyBytes), []byte("x")); err != nil {
+		t.Errorf("Set of a %d-byte key = %v, want nil", maxKeyBytes, err)
+	}
+}
@@ -0,0 +1,131 @@
+package redis
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// msgpackValuePrefix marks a MessagePack value stored under the JSON format
+// JSON text never starts with a NUL byte, so prefixed values are unambiguous.
+//
+// A cache hit must return the same value as the database read it replaced:
+//   - Raw JSON fields (json.RawMessage, datatypes.JSON) are stored verbatim. encoding/json
+//     compacts them on Marshal, so under the JSON format values containing them (or
+//     interface{} values) are stored as MessagePack behind this prefix instead.
+//   - interface{} values (e.g. map[string]interface{}) decode integers as int64/uint64
+//     and floats as float64, without the float64-only precision loss of encoding/json.
+//   - Typed struct fields (ints, decimals stored as strings, []byte) round-trip exactly;
+//     time.Time keeps its instant (compare with Equal, as the location may differ).
+const msgpackValuePrefix = "\x00sql4go:msgpack\x00"
+
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+
+	// verbatimTypes caches needsVerbatimEncoding per type
+	verbatimTypes sync.Map // reflect.Type -> bool
+)
+
+// msgpackUnmarshal decodes MessagePack, decoding interface{} numbers as int64/uint64/float64
+func msgpackUnmarshal(data []byte, target interface{}) error {
+	dec := msgpack.GetDecoder()
+	defer msgpack.PutDecoder(dec)
+
+	dec.Reset(bytes.NewReader(data))
+	dec.UseLooseInterfaceDecoding(true)
+	return dec.Decode(target)
+}
+
+// EqualEncoded reports whether two values serialize to the same cached data under the
+// configured format, e.g. a cached value and the database value it should mirror.
+// Map keys are sorted, so map iteration order does not matter; time.Time compares by
+// its instant.
+func (m *Manager) EqualEncoded(a, b interface{}) (bool, error) {
+	encodedA, err := m.canonicalEncoding(a)
+	if err != nil {
+		return false, err
+	}
+	encodedB, err := m.canonicalEncoding(b)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(encodedA, encodedB), nil
+}
+
+// DecodeValue deserializes raw cached data (e.g. from GetMany) into target like GetValue,
+// without its side effects: undecodable data is reported, not deleted
+func (m *Manager) DecodeValue(ctx context.Context, data []byte, target interface{}) error {
+	if err := m.decodeValue(ctx, data, target); err != nil {
+		return fmt.Errorf("%w: %v", ErrSerializationFailed, err)
+	}
+	return nil
+}
+
+// canonicalEncoding serializes a value like marshal, with sorted MessagePack map keys
+func (m *Manager) canonicalEncoding(value interface{}) ([]byte, error) {
+	if m.config.SerializationFormat == SerializationJSON && !needsVerbatimEncoding(reflect.TypeOf(value)) {
+		return json.Marshal(value) // encoding/json sorts map keys
+	}
+
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	enc.SetSortMapKeys(true)
+	if err := enc.Encode(value); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// needsVerbatimEncoding reports whether encoding/json would alter values of type t:
+// raw JSON byte slices (re-compacted) or interface{} values (numbers become float64)
+func needsVerbatimEncoding(t reflect.Type) bool {
+	if t == nil {
+		return false
+	}
+	if cached, ok := verbatimTypes.Load(t); ok {
+		return cached.(bool)
+	}
+
+	result := inspectVerbatimEncoding(t, make(map[reflect.Type]bool))
+	verbatimTypes.Store(t, result)
+	return result
+}
+
+// inspectVerbatimEncoding walks a type for needsVerbatimEncoding
+func inspectVerbatimEncoding(t reflect.Type, visited map[reflect.Type]bool) bool {
+	if visited[t] {
+		return false // Recursive type - already being inspected
+	}
+	visited[t] = true
+
+	// Raw JSON: a byte slice with its own MarshalJSON (json.RawMessage, datatypes.JSON)
+	if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+		return t.Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(jsonMarshalerType)
+	}
+	// Other custom JSON encodings are opaque (e.g. time.Time)
+	if t.Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(jsonMarshalerType) {
+		return false
+	}
+
+	switch t.Kind() {
+	case reflect.Interface:
+		return true
+	case reflect.Ptr, reflect.Slice, reflect.Array:
+		return inspectVerbatimEncoding(t.Elem(), visited)
+	case reflect.Map:
+		return inspectVerbatimEncoding(t.Key(), visited) || inspectVerbatimEncoding(t.Elem(), visited)
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.IsExported() && inspectVerbatimEncoding(field.Type, visited) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,438 @@
+package redis
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Snapshot format (integers big-endian): the header snapshotHeader, then records, then
+// the byte snapshotEnd. A record is a kind byte, the expiry as int64 Unix milliseconds
+// (0 = no expiry), a uint32 count and count entries:
+//   - snapshotValues: count pairs of key and value, written together
+//   - snapshotSet: the set key, then count members
+//
+// Keys, values and members are each a uint32 length followed by the bytes.
+const (
+	snapshotHeader    = "sql4go-snapshot/1\n"
+	snapshotValues    = byte('v')
+	snapshotSet       = byte('s')
+	snapshotEnd       = byte('e')
+	snapshotScanCount = 500               // SCAN COUNT hint and keys read per pipeline
+	snapshotBatchSize = 500               // Records written per import pipeline
+	maxSnapshotField  = 512 * 1024 * 1024 // Largest key, value or member accepted on import (Redis' limit)
+)
+
+// snapshotRecord is one record of a snapshot
+type snapshotRecord struct {
+	kind     byte
+	expireAt int64    // Unix milliseconds; 0 = no expiry
+	key      string   // Set key (snapshotSet)
+	keys     []string // Value keys (snapshotValues)
+	values   [][]byte // Values (snapshotValues) or members (snapshotSet)
+}
+
+// ExportNamespace writes a snapshot of the manager's namespace to w and returns the
+// number of keys written (see ImportNamespace)
+// Keys are read with SCAN in batches, so the cache stays available; keys written during
+// the export may or may not be included.
+//
+// After a failover to an empty replica, every read misses at once and the stampede lands
+// on the database; ImportNamespace restores the snapshot onto another instance before
+// traffic is cut over. A snapshot contains:
+//   - repository cache keys ("sql4go[:ns]:*"), with the metadata and chunks of a chunked
+//     value kept together in one record, so a value is restored whole or not at all
+//   - dependency sets, so restored entries are still invalidated by entity writes
+//   - compression dictionaries, which dictionary-compressed values need to be read
+//
+// Regeneration locks and the key-value store (see Cache) are not exported. Keys are
+// restored verbatim, so import into a manager with the same Namespace and cluster hash
+// tag setting. Each key keeps its absolute expiry: keys that expired since the export
+// are skipped on import.
+func (m *Manager) ExportNamespace(ctx context.Context, w io.Writer) (int, error) {
+	if err := m.checkClient(); err != nil {
+		return 0, err
+	}
+
+	out := bufio.NewWriter(w)
+	if _, err := out.WriteString(snapshotHeader); err != nil {
+		return 0, fmt.Errorf("failed to write snapshot: %w", err)
+	}
+
+	exported := 0
+	scopes := []struct {
+		pattern string
+		export  func(ctx context.Context, keys []string) ([]snapshotRecord, error)
+	}{
+		{m.entityKeyPrefix() + cacheKeySeparator + "*", m.snapshotValues},
+		{m.dictionaryKey("*"), m.snapshotValues},
+		{m.keyPrefix() + cacheKeySeparator + cacheDependencyPrefix + cacheKeySeparator + "*", m.snapshotSets},
+		{m.keyPrefix() + cacheKeySeparator + cacheHashTagVersion + cacheKeySeparator + cacheDependencyPrefix + cacheKeySeparator + "*", m.snapshotSets},
+	}
+	for _, scope := range scopes {
+		var cursor uint64
+		for {
+			keys, next, err := m.client.Scan(ctx, cursor, scope.pattern, snapshotScanCount).Result()
+			if err != nil {
+				return exported, fmt.Errorf("failed to scan keys with pattern %s: %w", scope.pattern, m.recordError(ctx, err))
+			}
+			records, err := scope.export(ctx, keys)
+			if err != nil {
+				return exported, err
+			}
+			for _, record := range records {
+				if err := writeSnapshotRecord(out, record); err != nil {
+					return exported, fmt.Errorf("failed to write snapshot: %w", err)
+				}
+				exported += max(len(record.keys), 1)
+			}
+			if cursor = next; cursor == 0 {
+				break
+			}
+		}
+	}
+
+	if err := out.WriteByte(snapshotEnd); err != nil {
+		return exported, fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	if err := out.Flush(); err != nil {
+		return exported, fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	return exported, nil
+}
+
+// snapshotValues reads string keys; a chunked value's metadata key brings its chunks
+// along, chunk keys themselves are skipped
+func (m *Manager) snapshotValues(ctx context.Context, keys []string) ([]snapshotRecord, error) {
+	keys = slices.DeleteFunc(keys, func(key string) bool {
+		return strings.HasSuffix(key, cacheLockSuffix) || strings.Contains(key, cacheChunkPrefix)
+	})
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	pipe := m.client.Pipeline()
+	values := make([]*redis.StringCmd, len(keys))
+	ttls := make([]*redis.DurationCmd, len(keys))
+	for i, key := range keys {
+		values[i] = pipe.Get(ctx, key)
+		ttls[i] = pipe.PTTL(ctx, key)
+	}
+	_, _ = pipe.Exec(ctx) // Errors are checked per command
+
+	now := time.Now()
+	var records []snapshotRecord
+	for i, key := range keys {
+		value, err := values[i].Bytes()
+		if err == nil {
+			err = ttls[i].Err()
+		}
+		if err != nil {
+			if skipSnapshotKey(err) {
+				continue // Expired or deleted since the scan, or not a string
+			}
+			return nil, fmt.Errorf("failed to read keys for snapshot: %w", m.recordError(ctx, err))
+		}
+		expireAt, ok := snapshotExpiry(now, ttls[i].Val())
+		if !ok {
+			continue
+		}
+
+		record := snapshotRecord{kind: snapshotValues, expireAt: expireAt, keys: []string{key}, values: [][]byte{value}}
+		if base, isMetadata := strings.CutSuffix(key, cacheMetadataSuffix); isMetadata {
+			chunkKeys, chunks, err := m.snapshotChunks(ctx, base, string(value))
+			if err != nil {
+				return nil, err
+			}
+			if chunks == nil {
+				continue // A chunk is missing; the value is unreadable anyway
+			}
+			record.keys = append(record.keys, chunkKeys...)
+			record.values = append(record.values, chunks...)
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// snapshotChunks reads the chunks of a chunked value, or returns nil chunks if the
+// metadata is malformed or a chunk is missing
+func (m *Manager) snapshotChunks(ctx context.Context, key, metadata string) ([]string, [][]byte, error) {
+	parts := strings.Split(metadata, ":")
+	if len(parts) != 3 || parts[0] != "chunked" {
+		return nil, nil, nil
+	}
+	chunkCount, err := strconv.Atoi(parts[2])
+	if err != nil || chunkCount < 0 {
+		return nil, nil, nil
+	}
+
+	pipe := m.client.Pipeline()
+	chunkKeys := make([]string, chunkCount)
+	results := make([]*redis.StringCmd, chunkCount)
+	for i := range chunkKeys {
+		chunkKeys[i] = fmt.Sprintf("%s%s:%d", key, cacheChunkPrefix, i)
+		results[i] = pipe.Get(ctx, chunkKeys[i])
+	}
+	_, _ = pipe.Exec(ctx) // Errors are checked per command
+
+	chunks := make([][]byte, chunkCount)
+	for i, result := range results {
+		chunk, err := result.Bytes()
+		if err != nil {
+			if skipSnapshotKey(err) {
+				return nil, nil, nil
+			}
+			return nil, nil, fmt.Errorf("failed to read chunks of %s for snapshot: %w", key, m.recordError(ctx, err))
+		}
+		chunks[i] = chunk
+	}
+	return chunkKeys, chunks, nil
+}
+
+// snapshotSets reads dependency sets (and their overflow sets)
+func (m *Manager) snapshotSets(ctx context.Context, keys []string) ([]snapshotRecord, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	pipe := m.client.Pipeline()
+	members := make([]*redis.StringSliceCmd, len(keys))
+	ttls := make([]*redis.DurationCmd, len(keys))
+	for i, key := range keys {
+		members[i] = pipe.SMembers(ctx, key)
+		ttls[i] = pipe.PTTL(ctx, key)
+	}
+	_, _ = pipe.Exec(ctx) // Errors are checked per command
+
+	now := time.Now()
+	var records []snapshotRecord
+	for i, key := range keys {
+		setMembers, err := members[i].Result()
+		if err == nil {
+			err = ttls[i].Err()
+		}
+		if err != nil {
+			if skipSnapshotKey(err) {
+				continue // Not a set
+			}
+			return nil, fmt.Errorf("failed to read dependency sets for snapshot: %w", m.recordError(ctx, err))
+		}
+		if len(setMembers) == 0 {
+			continue
+		}
+		expireAt, ok := snapshotExpiry(now, ttls[i].Val())
+		if !ok {
+			continue
+		}
+
+		record := snapshotRecord{kind: snapshotSet, expireAt: expireAt, key: key, values: make([][]byte, len(setMembers))}
+		for j, member := range setMembers {
+			record.values[j] = []byte(member)
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// skipSnapshotKey reports whether a pipelined read error only skips its key: the key is
+// missing, or a reply error such as WRONGTYPE for a key of another type. Other errors
+// (connection, timeout) abort the export
+func skipSnapshotKey(err error) bool {
+	var replyErr redis.Error
+	return err == redis.Nil || errors.As(err, &replyErr)
+}
+
+// snapshotExpiry converts a PTTL result into an absolute expiry (0 = no expiry); ok is
+// false if the key no longer exists
+func snapshotExpiry(now time.Time, ttl time.Duration) (expireAt int64, ok bool) {
+	switch {
+	case ttl == -2:
+		return 0, false
+	case ttl < 0:
+		return 0, true
+	}
+	return now.Add(ttl).UnixMilli(), true
+}
+
+// ImportNamespace restores a snapshot written by ExportNamespace and returns the number of
+// keys restored
+// Keys are written with pipelined SETs (SADDs for dependency sets) with their remaining
+// TTL, overwriting existing keys; keys whose expiry has passed are skipped. A snapshot
+// that is truncated or malformed fails with ErrInvalidSnapshot, after the records before
+// the damage were restored.
+func (m *Manager) ImportNamespace(ctx context.Context, r io.Reader) (int, error) {
+	if err := m.checkClient(); err != nil {
+		return 0, err
+	}
+
+	in := bufio.NewReader(r)
+	header := make([]byte, len(snapshotHeader))
+	if _, err := io.ReadFull(in, header); err != nil || string(header) != snapshotHeader {
+		return 0, fmt.Errorf("%w: missing snapshot header", ErrInvalidSnapshot)
+	}
+
+	imported, pending := 0, 0
+	pipe := m.client.Pipeline()
+	flush := func() error {
+		if pending == 0 {
+			return nil
+		}
+		pending = 0
+		if _, err := pipe.Exec(ctx); err != nil {
+			return fmt.Errorf("failed to restore snapshot: %w", m.recordError(ctx, err))
+		}
+		return nil
+	}
+
+	for {
+		record, err := readSnapshotRecord(in)
+		if err != nil {
+			return imported, errors.Join(err, flush())
+		}
+		if record == nil {
+			return imported, flush() // End of snapshot
+		}
+
+		var ttl time.Duration // 0 = no expiry
+		if record.expireAt != 0 {
+			if ttl = time.Until(time.UnixMilli(record.expireAt)); ttl <= 0 {
+				continue // Expired since the export
+			}
+		}
+
+		switch record.kind {
+		case snapshotValues:
+			for i, key := range record.keys {
+				pipe.Set(ctx, key, record.values[i], ttl)
+			}
+			imported += len(record.keys)
+		case snapshotSet:
+			members := make([]interface{}, len(record.values))
+			for i, member := range record.values {
+				members[i] = member
+			}
+			pipe.Del(ctx, record.key)
+			pipe.SAdd(ctx, record.key, members...)
+			if ttl > 0 {
+				pipe.PExpire(ctx, record.key, ttl)
+			}
+			imported++
+		}
+
+		if pending++; pending >= snapshotBatchSize {
+			if err := flush(); err != nil {
+				return imported, err
+			}
+		}
+	}
+}
+
+// writeSnapshotRecord writes one record
+func writeSnapshotRecord(out *bufio.Writer, record snapshotRecord) error {
+	header := []byte{record.kind}
+	header = binary.BigEndian.AppendUint64(header, uint64(record.expireAt))
+	header = binary.BigEndian.AppendUint32(header, uint32(len(record.values)))
+	if _, err := out.Write(header); err != nil {
+		return err
+	}
+
+	if record.kind == snapshotSet {
+		if err := writeSnapshotField(out, []byte(record.key)); err != nil {
+			return err
+		}
+	}
+	for i, value := range record.values {
+		if record.kind == snapshotValues {
+			if err := writeSnapshotField(out, []byte(record.keys[i])); err != nil {
+				return err
+			}
+		}
+		if err := writeSnapshotField(out, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeSnapshotField writes a length-prefixed key, value or member
+func writeSnapshotField(out *bufio.Writer, data []byte) error {
+	if _, err := out.Write(binary.BigEndian.AppendUint32(nil, uint32(len(data)))); err != nil {
+		return err
+	}
+	_, err := out.Write(data)
+	return err
+}
+
+// readSnapshotRecord reads one record, or returns nil at the end of the snapshot
+func readSnapshotRecord(in *bufio.Reader) (*snapshotRecord, error) {
+	kind, err := in.ReadByte()
+	if err != nil {
+		return nil, fmt.Errorf("%w: truncated before the end marker", ErrInvalidSnapshot)
+	}
+	if kind == snapshotEnd {
+		return nil, nil
+	}
+	if kind != snapshotValues && kind != snapshotSet {
+		return nil, fmt.Errorf("%w: unknown record kind %q", ErrInvalidSnapshot, kind)
+	}
+
+	var header [12]byte
+	if _, err := io.ReadFull(in, header[:]); err != nil {
+		return nil, fmt.Errorf("%w: truncated record", ErrInvalidSnapshot)
+	}
+	record := &snapshotRecord{
+		kind:     kind,
+		expireAt: int64(binary.BigEndian.Uint64(header[:8])),
+	}
+	count := binary.BigEndian.Uint32(header[8:])
+
+	if kind == snapshotSet {
+		key, err := readSnapshotField(in)
+		if err != nil {
+			return nil, err
+		}
+		record.key = string(key)
+	}
+	for i := uint32(0); i < count; i++ {
+		if kind == snapshotValues {
+			key, err := readSnapshotField(in)
+			if err != nil {
+				return nil, err
+			}
+			record.keys = append(record.keys, string(key))
+		}
+		value, err := readSnapshotField(in)
+		if err != nil {
+			return nil, err
+		}
+		record.values = append(record.values, value)
+	}
+	return record, nil
+}
+
+// readSnapshotField reads a length-prefixed key, value or member
+func readSnapshotField(in *bufio.Reader) ([]byte, error) {
+	var length [4]byte
+	if _, err := io.ReadFull(in, length[:]); err != nil {
+		return nil, fmt.Errorf("%w: truncated record", ErrInvalidSnapshot)
+	}
+	size := binary.BigEndian.Uint32(length[:])
+	if size > maxSnapshotField {
+		return nil, fmt.Errorf("%w: field of %d bytes", ErrInvalidSnapshot, size)
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(in, data); err != nil {
+		return nil, fmt.Errorf("%w: truncated record", ErrInvalidSnapshot)
+	}
+	return data, nil
+}
@@ -0,0 +1,49 @@
+package redis
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+)
+
+func TestExportNamespaceWithInvalidationLog(t *testing.T) {
+	source, sourceRedis := newTestManager(t)
+	ctx := context.Background()
+
+	key := "sql4go:app:users:s1:find_by_id:1"
+	if err := source.SetValueWithTTL(ctx, key, map[string]string{"name": "ada"}, time.Minute); err != nil {
+		t.Fatalf("SetValueWithTTL: %v", err)
+	}
+	if err := source.RecordTableInvalidation(ctx, "app", "users", "update"); err != nil {
+		t.Fatalf("RecordTableInvalidation: %v", err)
+	}
+	// A hash someone else left among the repository keys is skipped, not fatal
+	sourceRedis.HSet("sql4go:app:_legacy", "users", "x")
+
+	var snapshot bytes.Buffer
+	exported, err := source.ExportNamespace(ctx, &snapshot)
+	if err != nil {
+		t.Fatalf("ExportNamespace: %v", err)
+	}
+	if exported != 1 {
+		t.Errorf("exported %d keys, want only the cached value", exported)
+	}
+
+	target, targetRedis := newTestManager(t)
+	if _, err := target.ImportNamespace(ctx, &snapshot); err != nil {
+		t.Fatalf("ImportNamespace: %v", err)
+	}
+	if !targetRedis.Exists(key) {
+		t.Errorf("imported snapshot is missing %s", key)
+	}
+
+	// Wiping the database's repository keys leaves the log alone
+	if err := source.InvalidatePattern(ctx, "sql4go:app:*"); err != nil {
+		t.Fatalf("InvalidatePattern: %v", err)
+	}
+	last, err := source.LastTableInvalidation(ctx, "app", "users")
+	if err != nil || last.Operation != "update" {
+		t.Errorf("LastTableInvalidation = (%+v, %v), want the recorded update", last, err)
+	}
+}
@@ -0,0 +1,195 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// associationChange is an association write whose associated side is invalidated
+type associationChange struct {
+	operation string
+	tables    []associatedTable
+}
+
+// associatedTable is a table touched by an association write and its affected rows
+type associatedTable struct {
+	table     string
+	modelType reflect.Type
+	ids       []interface{}
+}
+
+// AppendAssociation links values (entities, pointers or slices of them) to entity
+// through its association field (e.g. "Roles"), creating rows that don't exist yet
+//
+// AppendAssociation, ReplaceAssociation and DeleteAssociation wrap GORM's Association
+// API (db.Model(entity).Association(name)) to link and unlink related rows without
+// handling foreign keys or join rows by hand. A change touches both sides, so after the
+// statement:
+//   - the owner is invalidated like an Update (its table, FindByID entry, dependents)
+//   - the associated table is wiped, and the FindByID entries, dependency sets and
+//     invalidation patterns of the associated rows are invalidated; for Replace this
+//     includes the rows linked before, which were unlinked
+//   - many2many join tables are wiped as well, for repositories over the join table
+//
+// Inside a transaction, both sides are invalidated after commit. With the outbox, the
+// owner is invalidated by the relay, the associated side right after commit.
+func (r *GenericRepository[T]) AppendAssociation(ctx context.Context, entity *T, association string, values ...interface{}) (bool, error) {
+	return r.writeAssociation(ctx, "append_association", entity, association, values,
+		func(tx Repository[T]) (bool, error) { return tx.AppendAssociation(ctx, entity, association, values...) },
+		func(a *gorm.Association) error { return a.Append(values...) })
+}
+
+// ReplaceAssociation replaces the rows linked to entity through its association field
+// with values; no values unlinks every row
+func (r *GenericRepository[T]) ReplaceAssociation(ctx context.Context, entity *T, association string, values ...interface{}) (bool, error) {
+	return r.writeAssociation(ctx, "replace_association", entity, association, values,
+		func(tx Repository[T]) (bool, error) {
+			return tx.ReplaceAssociation(ctx, entity, association, values...)
+		},
+		func(a *gorm.Association) error { return a.Replace(values...) })
+}
+
+// DeleteAssociation unlinks values from entity; the rows themselves are not deleted
+// (has one/has many foreign keys are set to NULL, many2many join rows are removed)
+func (r *GenericRepository[T]) DeleteAssociation(ctx context.Context, entity *T, association string, values ...interface{}) (bool, error) {
+	return r.writeAssociation(ctx, "delete_association", entity, association, values,
+		func(tx Repository[T]) (bool, error) { return tx.DeleteAssociation(ctx, entity, association, values...) },
+		func(a *gorm.Association) error { return a.Delete(values...) })
+}
+
+// writeAssociation runs an association write and invalidates both of its sides
+// viaTx repeats the write on a transaction repository for the outbox.
+func (r *GenericRepository[T]) writeAssociation(ctx context.Context, operation string, entity *T, association string, values []interface{},
+	viaTx func(tx Repository[T]) (bool, error), write func(a *gorm.Association) error) (bool, error) {
+	if err := r.checkWritable(); err != nil {
+		return false, err
+	}
+
+	// Outbox: write and record the owner's outbox row in one transaction
+	if handled, err := r.writeViaOutbox(ctx, func(tx Repository[T]) error {
+		_, err := viaTx(tx)
+		return err
+	}); handled {
+		return err == nil && r.redis != nil, err
+	}
+
+	// Input validation
+	if entity == nil {
+		return false, fmt.Errorf("entity cannot be nil")
+	}
+	if association == "" {
+		return false, fmt.Errorf("association cannot be empty")
+	}
+	if isZeroKey(r.primaryKeyValue(*entity)) {
+		return false, fmt.Errorf("entity must have a primary key")
+	}
+
+	// Write-behind: the owner row must exist before it is linked
+	if err := r.awaitQueuedWrites(ctx); err != nil {
+		return false, fmt.Errorf("write-behind flush error: %w", err)
+	}
+
+	// Apply query timeout
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	assoc := r.writeSession(ctx).Model(entity).Association(association)
+	if assoc.Error != nil {
+		return false, fmt.Errorf("association %s: %w", association, assoc.Error)
+	}
+	relationship := assoc.Relationship
+
+	// Rows linked before a Replace are unlinked by it
+	var previous []interface{}
+	if operation == "replace_association" {
+		linked := reflect.New(reflect.SliceOf(relationship.FieldSchema.ModelType))
+		start := time.Now()
+		err := assoc.Find(linked.Interface())
+		r.observeQuery(ctx, operation+"_lookup", start, err)
+		if err != nil {
+			return false, fmt.Errorf("database error: %w", classifyQueryError(ctx, err))
+		}
+		previous = []interface{}{linked.Elem().Interface()}
+	}
+
+	// Execute database operation
+	start := time.Now()
+	err := write(assoc)
+	r.observeQuery(ctx, operation, start, err)
+	if err != nil {
+		return false, fmt.Errorf("database error: %w", classifyQueryError(ctx, err))
+	}
+
+	// Invalidate the associated side, then the owner (write-through also caches it) and notify
+	change := associationChange{operation: operation, tables: associatedTables(ctx, relationship, append(previous, values...))}
+	if r.tx != nil {
+		r.tx.recordAssociation(change)
+	} else if r.redis != nil {
+		r.invalidateAssociated(ctx, change)
+	}
+	return r.afterWrite(ctx, ChangeUpdate, *entity), nil
+}
+
+// associatedTables returns the tables of a relationship affected by a write of values:
+// the associated table with the primary keys of values, and a many2many join table
+func associatedTables(ctx context.Context, relationship *schema.Relationship, values []interface{}) []associatedTable {
+	fieldSchema := relationship.FieldSchema
+	associated := associatedTable{table: fieldSchema.Table, modelType: fieldSchema.ModelType}
+
+	if pkField := fieldSchema.PrioritizedPrimaryField; pkField != nil {
+		var collect func(value reflect.Value)
+		collect = func(value reflect.Value) {
+			value = reflect.Indirect(value)
+			switch value.Kind() {
+			case reflect.Slice, reflect.Array:
+				for i := 0; i < value.Len(); i++ {
+					collect(value.Index(i))
+				}
+			case reflect.Struct:
+				if value.Type() == fieldSchema.ModelType {
+					if id, zero := pkField.ValueOf(ctx, value); !zero {
+						associated.ids = append(associated.ids, id)
+					}
+				}
+			}
+		}
+		for _, value := range values {
+			collect(reflect.ValueOf(value))
+		}
+	}
+
+	tables := []associatedTable{associated}
+	if relationship.JoinTable != nil {
+		tables = append(tables, associatedTable{table: relationship.JoinTable.Table, modelType: relationship.JoinTable.ModelType})
+	}
+	return tables
+}
+
+// invalidateAssociated invalidates the associated side of an association write (best
+// effort), as the invalidation callbacks do for tables without a repository instance
+func (r *GenericRepository[T]) invalidateAssociated(ctx context.Context, change associationChange) {
+	ctx, cancel := invalidationContext(ctx, r.redis)
+	defer cancel()
+
+	for _, associated := range change.tables {
+		namespace := cacheKeyNamespace(r.redis, r.dbName, associated.table)
+		keyNamespace := versionedNamespace(namespace, associated.modelType)
+
+		// Invalidate all caches for the table (ignore errors - best effort)
+		_ = r.redis.InvalidatePatternCoalesced(ctx, tableCachePattern(namespace))
+		_ = r.redis.RecordTableInvalidation(ctx, r.dbName, associated.table, change.operation)
+
+		// Invalidate the cached entity, exists entry and dependencies of every affected row (ignore errors - best effort)
+		for _, id := range associated.ids {
+			_ = r.redis.DeleteLarge(ctx, buildCacheKey(keyNamespace, "find_by_id", fmt.Sprintf("%v", id), maxKeyLength(r.redis)))
+			_ = r.redis.DeleteKeys(ctx, []string{existsCacheKey(keyNamespace, id, maxKeyLength(r.redis))})
+			_ = r.redis.InvalidateEntityDependencies(ctx, dependencyType(r.redis, r.dbName, associated.table), id)
+			_ = r.redis.InvalidateKeyPatterns(ctx, r.dbName, associated.table, id)
+		}
+	}
+}
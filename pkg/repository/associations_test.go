@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// testCart has many testCartLines
+type testCart struct {
+	ID    uint           `gorm:"primaryKey"`
+	Lines []testCartLine `gorm:"foreignKey:CartID"`
+}
+
+func (testCart) TableName() string { return "carts" }
+
+func (c testCart) GetPrimaryKeyValue() interface{} { return c.ID }
+
+// testCartLine belongs to a testCart
+type testCartLine struct {
+	ID     uint `gorm:"primaryKey"`
+	CartID uint `gorm:"column:cart_id"`
+}
+
+func (testCartLine) TableName() string { return "cart_lines" }
+
+func (l testCartLine) GetPrimaryKeyValue() interface{} { return l.ID }
+
+func TestAppendAssociationInvalidatesBothSides(t *testing.T) {
+	env := newTestEnv(t)
+	carts := newTestRepository[testCart](env)
+	lines := newTestRepository[testCartLine](env)
+	ctx := context.Background()
+
+	// Cache the owner and the line about to be linked
+	env.mock.ExpectQuery("SELECT \\* FROM `carts`").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	cart, _, _, err := carts.FindByID(ctx, 1)
+	if err != nil || cart == nil {
+		t.Fatalf("FindByID(cart 1) = (%v, %v)", cart, err)
+	}
+	env.mock.ExpectQuery("SELECT \\* FROM `cart_lines`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "cart_id"}).AddRow(5, 2))
+	if _, _, _, err := lines.FindByID(ctx, 5); err != nil {
+		t.Fatalf("FindByID(line 5): %v", err)
+	}
+	cartKey := carts.generateCacheKey("find_by_id", "1")
+	lineKey := lines.generateCacheKey("find_by_id", "5")
+	if !env.mr.Exists(cartKey) || !env.mr.Exists(lineKey) {
+		t.Fatalf("both sides should be cached before the append, keys %v", env.cachedKeys())
+	}
+
+	env.mock.ExpectExec("INSERT INTO `cart_lines`").WillReturnResult(sqlmock.NewResult(5, 1))
+	if _, err := carts.AppendAssociation(ctx, cart, "Lines", &testCartLine{ID: 5}); err != nil {
+		t.Fatalf("AppendAssociation: %v", err)
+	}
+	env.verify()
+
+	if env.mr.Exists(cartKey) {
+		t.Errorf("owner entry %s survived the append", cartKey)
+	}
+	if env.mr.Exists(lineKey) {
+		t.Errorf("associated entry %s survived the append", lineKey)
+	}
+}
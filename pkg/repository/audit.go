@@ -0,0 +1,212 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ammar0144/sql4go/pkg/redis"
+)
+
+const (
+	auditMaxSampleSize  = 10000                 // Entries audited at most
+	auditBatchSize      = 100                   // Entries read and compared per batch
+	auditBatchInterval  = 50 * time.Millisecond // Pause between database queries
+	auditMaxExampleKeys = 10                    // Mismatching keys listed in a report
+)
+
+// AuditRepair is what AuditCache does with stale entries
+type AuditRepair int
+
+const (
+	// AuditRepairNone only reports stale entries (default)
+	AuditRepairNone AuditRepair = iota
+	// AuditRepairRecache overwrites stale entries with the database row, and deletes
+	// entries of rows that no longer exist
+	AuditRepairRecache
+	// AuditRepairDelete deletes stale entries
+	AuditRepairDelete
+)
+
+// AuditReport is the result of a cache audit of one table
+type AuditReport struct {
+	Database      string        `json:"database"`
+	Table         string        `json:"table"`
+	SchemaVersion string        `json:"schema_version"`
+	Sampled       int           `json:"sampled"`       // Entries compared with the database
+	Matched       int           `json:"matched"`       // Entries equal to their row
+	Mismatched    int           `json:"mismatched"`    // Entries differing from their row (or a cached not-found of an existing row)
+	MissingInDB   int           `json:"missing_in_db"` // Cached entities whose row no longer exists
+	Skipped       int           `json:"skipped"`       // Entries expired since the scan, undecodable or with a hashed id
+	Repaired      int           `json:"repaired"`      // Stale entries recached or deleted (see AuditRepair)
+	Truncated     bool          `json:"truncated"`     // More entries exist than were sampled
+	MismatchKeys  []string      `json:"mismatch_keys"` // Examples of stale entries (mismatched or missing in the database)
+	Duration      time.Duration `json:"duration"`      // Time the audit took
+}
+
+// AuditCache compares up to sampleSize cached FindByID entries of this table with the
+// database; stale entries are repaired only with an explicit repair mode
+//
+// It measures how stale the cache of a table is, e.g. after an incident: entries of the
+// current schema version are sampled with SCAN, their rows read from the database and
+// both compared by their cached encoding (see redis.Manager.EqualEncoded), as the
+// verification mode does for live reads.
+//   - Bounded: at most auditMaxSampleSize entries; rows are read with IN lists of
+//     auditBatchSize ids, pausing auditBatchInterval between queries
+//   - Read-only: nothing is written unless a repair mode is passed explicitly
+//     (AuditRepairRecache or AuditRepairDelete); undecodable entries are skipped,
+//     not discarded as reads do
+//
+// Entries written between the cache read and the database query are reported as
+// mismatches; compare the counts of repeated audits rather than single keys. Entries
+// whose id segment is hashed (see MaxKeyLength) cannot be mapped to a row and are skipped.
+func (r *GenericRepository[T]) AuditCache(ctx context.Context, sampleSize int, repair ...AuditRepair) (AuditReport, error) {
+	report := AuditReport{
+		Database:      r.dbName,
+		Table:         r.tableName,
+		SchemaVersion: r.schemaVersion,
+		MismatchKeys:  []string{},
+	}
+	if r.redis == nil || sampleSize <= 0 {
+		return report, nil
+	}
+	if r.redis.manager == nil {
+		return report, unsupported("AuditCache")
+	}
+	mode := AuditRepairNone
+	if len(repair) > 0 {
+		mode = repair[0]
+	}
+
+	start := time.Now()
+	defer func() { report.Duration = time.Since(start) }()
+
+	// Sample the FindByID entries, leaving out metadata, chunk and lock keys
+	prefix := r.keyNamespace + cacheKeySeparator + "find_by_id" + cacheKeySeparator
+	keys, truncated, err := r.redis.manager.ScanKeys(ctx, prefix+"*", min(sampleSize, auditMaxSampleSize))
+	if err != nil {
+		return report, err
+	}
+	report.Truncated = truncated || sampleSize > auditMaxSampleSize
+
+	entries := make(map[string]string, len(keys)) // Cache key -> id segment
+	sampled := make([]string, 0, len(keys))
+	for _, key := range keys {
+		id := strings.TrimPrefix(key, prefix)
+		if strings.Contains(id, "_internal") {
+			continue
+		}
+		if strings.HasPrefix(id, cacheKeyHashedMarker) {
+			report.Skipped++
+			continue
+		}
+		entries[key] = id
+		sampled = append(sampled, key)
+	}
+
+	for batchStart := 0; batchStart < len(sampled); batchStart += auditBatchSize {
+		if batchStart > 0 {
+			select {
+			case <-ctx.Done():
+				return report, ctx.Err()
+			case <-time.After(auditBatchInterval):
+			}
+		}
+		batch := sampled[batchStart:min(batchStart+auditBatchSize, len(sampled))]
+		if err := r.auditBatch(ctx, batch, entries, mode, &report); err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+// auditBatch compares one batch of cached entries with their rows
+func (r *GenericRepository[T]) auditBatch(ctx context.Context, keys []string, entries map[string]string, mode AuditRepair, report *AuditReport) error {
+	cached, err := r.redis.GetMany(ctx, keys)
+	if err != nil {
+		return err
+	}
+
+	ids := make([]interface{}, 0, len(cached))
+	for _, key := range keys {
+		if _, ok := cached[key]; ok {
+			ids = append(ids, entries[key])
+		}
+	}
+	report.Skipped += len(keys) - len(ids) // Expired or evicted since the scan
+	if len(ids) == 0 {
+		return nil
+	}
+
+	rows, err := r.findInChunks(ctx, "audit_cache", r.primaryKey, ids)
+	if err != nil {
+		return fmt.Errorf("database error: %w", classifyQueryError(ctx, err))
+	}
+	byID := make(map[string]T, len(rows))
+	for _, row := range rows {
+		byID[fmt.Sprintf("%v", r.primaryKeyValue(row))] = row
+	}
+
+	for _, key := range keys {
+		data, ok := cached[key]
+		if !ok {
+			continue
+		}
+		row, exists := byID[entries[key]]
+
+		var stale bool
+		switch {
+		case redis.IsNullMarker(data):
+			stale = exists // Cached not-found of an existing row
+		case !exists:
+			report.Sampled++
+			report.MissingInDB++
+			r.recordStaleEntry(ctx, key, nil, mode, report)
+			continue
+		default:
+			var entity T
+			if err := r.redis.manager.DecodeValue(ctx, data, &entity); err != nil {
+				report.Skipped++
+				continue
+			}
+			equal, err := r.redis.EqualEncoded(entity, row)
+			if err != nil {
+				report.Skipped++
+				continue
+			}
+			stale = !equal
+		}
+
+		report.Sampled++
+		if !stale {
+			report.Matched++
+			continue
+		}
+		report.Mismatched++
+		r.recordStaleEntry(ctx, key, &row, mode, report)
+	}
+	return nil
+}
+
+// recordStaleEntry lists a stale entry in the report and repairs it if requested
+// row is the database row, nil if it no longer exists.
+func (r *GenericRepository[T]) recordStaleEntry(ctx context.Context, key string, row *T, mode AuditRepair, report *AuditReport) {
+	if len(report.MismatchKeys) < auditMaxExampleKeys {
+		report.MismatchKeys = append(report.MismatchKeys, key)
+	}
+	if mode == AuditRepairNone {
+		return
+	}
+
+	// Delete first, which also drops the entry from the L1 caches of other processes (as Refresh does)
+	if err := r.redis.DeleteLarge(ctx, key); err != nil {
+		return
+	}
+	if mode == AuditRepairRecache && row != nil {
+		if err := r.redis.SetValueWithTTL(ctx, key, *row, r.operationTTL("find_by_id")); err != nil {
+			return
+		}
+	}
+	report.Repaired++
+}
@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ammar0144/sql4go/pkg/redis"
+)
+
+// seedAuditCache caches five FindByID entries against the rows the audit reads:
+// 1 and 5 are current, 2 is outdated, 3 was deleted and 4 is a not-found of a new row
+func seedAuditCache(t *testing.T, env *testEnv, repo *GenericRepository[testUser]) {
+	t.Helper()
+	ctx := context.Background()
+
+	for _, user := range []testUser{{ID: 1, Name: "ann", Email: "a@x"}, {ID: 2, Name: "bob", Email: "b@x"}, {ID: 3, Name: "cat", Email: "c@x"}} {
+		if err := env.cache.SetValueWithTTL(ctx, repo.entityCacheKey(user.ID), user, time.Hour); err != nil {
+			t.Fatalf("SetValueWithTTL: %v", err)
+		}
+	}
+	for _, id := range []uint{4, 5} {
+		if err := env.cache.SetNull(ctx, repo.entityCacheKey(id)); err != nil {
+			t.Fatalf("SetNull: %v", err)
+		}
+	}
+}
+
+// expectAuditRows answers the audit's IN query with the current rows
+func expectAuditRows(env *testEnv) {
+	env.mock.ExpectQuery("SELECT \\* FROM `users` WHERE `id` IN").WillReturnRows(userRows(
+		testUser{ID: 1, Name: "ann", Email: "a@x"},
+		testUser{ID: 2, Name: "bobby", Email: "b@x"},
+		testUser{ID: 4, Name: "dan", Email: "d@x"},
+	))
+}
+
+func TestAuditCacheCountsStaleEntries(t *testing.T) {
+	env := newTestEnv(t)
+	repo := newTestRepository[testUser](env)
+	seedAuditCache(t, env, repo)
+	before := env.cachedKeys()
+
+	expectAuditRows(env)
+	report, err := repo.AuditCache(context.Background(), 100)
+	if err != nil {
+		t.Fatalf("AuditCache: %v", err)
+	}
+	env.verify()
+
+	if report.Sampled != 5 || report.Matched != 2 || report.Mismatched != 2 || report.MissingInDB != 1 || report.Skipped != 0 {
+		t.Errorf("report = %+v, want 5 sampled: 2 matched, 2 mismatched, 1 missing in the database", report)
+	}
+	if report.Repaired != 0 || report.Truncated || len(report.MismatchKeys) != 3 {
+		t.Errorf("report = %+v, want 3 example keys, nothing repaired or truncated", report)
+	}
+
+	// Without a repair mode nothing is written
+	if after := env.cachedKeys(); len(after) != len(before) {
+		t.Errorf("cached keys after the audit = %q, want %q", after, before)
+	}
+	var cached testUser
+	if err := env.cache.GetValue(context.Background(), repo.entityCacheKey(uint(2)), &cached); err != nil || cached.Name != "bob" {
+		t.Errorf("entry 2 after the audit = (%+v, %v), want the stale entry untouched", cached, err)
+	}
+}
+
+func TestAuditCacheRepairs(t *testing.T) {
+	tests := []struct {
+		mode AuditRepair
+		want map[uint]string // Id -> cached name ("" for a not-found, absent if deleted)
+	}{
+		{AuditRepairRecache, map[uint]string{1: "ann", 2: "bobby", 4: "dan", 5: ""}},
+		{AuditRepairDelete, map[uint]string{1: "ann", 5: ""}},
+	}
+	for _, tt := range tests {
+		env := newTestEnv(t)
+		repo := newTestRepository[testUser](env)
+		seedAuditCache(t, env, repo)
+		ctx := context.Background()
+
+		expectAuditRows(env)
+		report, err := repo.AuditCache(ctx, 100, tt.mode)
+		if err != nil {
+			t.Fatalf("AuditCache(%v): %v", tt.mode, err)
+		}
+		env.verify()
+		if report.Repaired != 3 {
+			t.Errorf("mode %v: Repaired = %d, want the 3 stale entries", tt.mode, report.Repaired)
+		}
+
+		for id := uint(1); id <= 5; id++ {
+			want, kept := tt.want[id]
+			var cached testUser
+			err := env.cache.GetValue(ctx, repo.entityCacheKey(id), &cached)
+			switch {
+			case !kept && !redis.IsKeyNotFound(err):
+				t.Errorf("mode %v: entry %d = (%+v, %v), want it deleted", tt.mode, id, cached, err)
+			case kept && want == "" && !redis.IsNullValue(err):
+				t.Errorf("mode %v: entry %d = (%+v, %v), want a cached not-found", tt.mode, id, cached, err)
+			case kept && want != "" && (err != nil || cached.Name != want):
+				t.Errorf("mode %v: entry %d = (%+v, %v), want %s", tt.mode, id, cached, err, want)
+			}
+		}
+	}
+}
+
+func TestAuditCacheBoundsSample(t *testing.T) {
+	env := newTestEnv(t)
+	repo := newTestRepository[testUser](env)
+	seedAuditCache(t, env, repo)
+
+	env.mock.ExpectQuery("SELECT \\* FROM `users` WHERE `id` IN").WillReturnRows(userRows())
+	report, err := repo.AuditCache(context.Background(), 2)
+	if err != nil {
+		t.Fatalf("AuditCache: %v", err)
+	}
+	if report.Sampled+report.Skipped > 2 || !report.Truncated {
+		t.Errorf("report = %+v, want at most 2 entries and Truncated", report)
+	}
+
+	// A sample size of zero audits nothing
+	if report, err := repo.AuditCache(context.Background(), 0); err != nil || report.Sampled != 0 {
+		t.Errorf("AuditCache(0) = (%+v, %v), want an empty report", report, err)
+	}
+	env.verify()
+}
@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// BenchmarkGenerateCacheKeyFromQuery measures hashing a query and its arguments into a key
+func BenchmarkGenerateCacheKeyFromQuery(b *testing.B) {
+	env := newTestEnv(b)
+	repo := newTestRepository[testUser](env)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = repo.generateCacheKeyFromQuery("find_where", "name = ? AND email LIKE ? AND id > ?", "ann", "%@x", 100)
+	}
+}
+
+// BenchmarkExtractRelationshipsFromEntity measures the reflection over an entity with
+// loaded associations that runs whenever a value is cached with its dependencies
+func BenchmarkExtractRelationshipsFromEntity(b *testing.B) {
+	order := testOrder{ID: 1, UserID: 7, User: &testUser{ID: 7, Name: "ann"}}
+	for i := uint(1); i <= 10; i++ {
+		order.Items = append(order.Items, testOrderItem{ID: i, OrderID: 1})
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = extractRelationshipsFromEntity(order, order.ID, 3)
+	}
+}
+
+// BenchmarkFindByIDCacheHit measures a FindByID served from the cache
+// It reads from miniredis over loopback, so its time is dominated by the round trip,
+// while its allocations are the repository's own.
+func BenchmarkFindByIDCacheHit(b *testing.B) {
+	env := newTestEnv(b)
+	repo := newTestRepository[testUser](env)
+	ctx := context.Background()
+
+	env.mock.ExpectQuery("SELECT \\* FROM `users`").WillReturnRows(userRows(testUser{ID: 1, Name: "ann", Email: "a@x"}))
+	if _, _, stored, err := repo.FindByID(ctx, 1); err != nil || !stored {
+		b.Fatalf("FindByID = (stored %v, %v), want a cached row", stored, err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, hit, _, err := repo.FindByID(ctx, 1); err != nil || !hit {
+			b.Fatalf("FindByID = (hit %v, %v), want a cache hit", hit, err)
+		}
+	}
+}
+
+// testDocument is an entity with a large column
+type testDocument struct {
+	ID   uint   `gorm:"primaryKey"`
+	Body string `gorm:"column:body"`
+}
+
+func (testDocument) TableName() string { return "documents" }
+
+func (d testDocument) GetPrimaryKeyValue() interface{} { return d.ID }
+
+// BenchmarkExistsLargeEntity compares Exists, which reads a one-byte entry, with the
+// FindByID it used to delegate to, which decodes the whole cached 256 KiB row
+func BenchmarkExistsLargeEntity(b *testing.B) {
+	env := newTestEnv(b)
+	repo := newTestRepository[testDocument](env)
+	ctx := context.Background()
+
+	body := strings.Repeat("x", 256<<10)
+	env.mock.ExpectQuery("SELECT \\* FROM `documents`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "body"}).AddRow(1, body))
+	if _, _, stored, err := repo.FindByID(ctx, 1); err != nil || !stored {
+		b.Fatalf("FindByID = (stored %v, %v), want a cached row", stored, err)
+	}
+	env.mock.ExpectQuery("SELECT 1 FROM `documents`").WillReturnRows(sqlmock.NewRows([]string{"1"}).AddRow(1))
+	if exists, _, stored, err := repo.Exists(ctx, 1); err != nil || !exists || !stored {
+		b.Fatalf("Exists = (%v, stored %v, %v), want a cached true", exists, stored, err)
+	}
+
+	b.Run("Exists", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if exists, hit, _, err := repo.Exists(ctx, 1); err != nil || !exists || !hit {
+				b.Fatalf("Exists = (%v, hit %v, %v), want a cached true", exists, hit, err)
+			}
+		}
+	})
+	b.Run("FindByID", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if entity, hit, _, err := repo.FindByID(ctx, 1); err != nil || entity == nil || !hit {
+				b.Fatalf("FindByID = (hit %v, %v), want a cache hit", hit, err)
+			}
+		}
+	})
+}
+
+// BenchmarkFindWhereCacheHit compares FindWhere, which returns a new slice of the 500
+// cached rows on every call, with FindWhereInto reusing one slice
+func BenchmarkFindWhereCacheHit(b *testing.B) {
+	env := newTestEnv(b)
+	repo := newTestRepository[testUser](env, WithOrderingPolicy(OrderingIgnore))
+	ctx := context.Background()
+	cacheUserList(b, env, repo, 500)
+
+	b.Run("FindWhere", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, hit, _, err := repo.FindWhere(ctx, "id > ?", 0); err != nil || !hit {
+				b.Fatalf("FindWhere = (hit %v, %v), want a cache hit", hit, err)
+			}
+		}
+	})
+	b.Run("FindWhereInto", func(b *testing.B) {
+		var dest []testUser
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if hit, _, err := repo.FindWhereInto(ctx, &dest, "id > ?", 0); err != nil || !hit {
+				b.Fatalf("FindWhereInto = (hit %v, %v), want a cache hit", hit, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,188 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/ammar0144/sql4go/pkg/db"
+	"github.com/ammar0144/sql4go/pkg/redis"
+)
+
+// Cache is the cache backend of repositories, so it can be swapped for a fake in unit
+// tests, MemoryCache, or another store; *redis.Manager implements it. The semantics of
+// each method are those of the redis.Manager method of the same name.
+//
+// Features only Redis provides - regeneration locks, cache metrics, batch reads,
+// coalesced table wipes, the invalidation log, CacheInfo, PurgeOperation and
+// AuditCache - are used when the cache is a *redis.Manager; with another Cache they are
+// skipped or fail with ErrCacheUnsupported.
+type Cache interface {
+	Config() *redis.Config
+
+	GetValue(ctx context.Context, key string, target interface{}) error
+	GetLargeValue(ctx context.Context, key string, target interface{}) error
+
+	SetValueWithTTL(ctx context.Context, key string, value interface{}, ttl time.Duration) error
+	SetValueWithDependenciesTTL(ctx context.Context, cacheKey string, value interface{}, dependencies map[string][]interface{}, ttl time.Duration) error
+	SetLargeValueWithDependenciesTTL(ctx context.Context, cacheKey string, value interface{}, dependencies map[string][]interface{}, ttl time.Duration) error
+	SetNull(ctx context.Context, key string) error
+
+	DeleteKeys(ctx context.Context, keys []string) error
+	DeleteLarge(ctx context.Context, key string) error
+	InvalidatePattern(ctx context.Context, pattern string) error
+	InvalidateEntityDependencies(ctx context.Context, entityType string, entityID interface{}) error
+	InvalidateKeyPatterns(ctx context.Context, dbName, entityType string, entityID interface{}) error
+}
+
+var _ Cache = (*redis.Manager)(nil)
+
+// managerCache returns redisManager as a Cache, keeping a nil manager a nil Cache
+func managerCache(redisManager *redis.Manager) Cache {
+	if redisManager == nil {
+		return nil
+	}
+	return redisManager
+}
+
+// NewGenericRepositoryWithCache creates a generic repository caching through any Cache
+// implementation (nil disables caching); see NewGenericRepository
+//
+// A nil cache is kept as a nil backend rather than replaced by a no-op one: a no-op cache
+// would still build keys, extract dependencies and report results as stored. Copies
+// that bypass the cache (operations with caching disabled, read-your-writes) rely on it
+// too, so repository code checks for a nil backend before each cache access.
+func NewGenericRepositoryWithCache[T Entity](dbManager *db.Manager, cache Cache, opts ...Option) Repository[T] {
+	return newGenericRepository[T](dbManager, cache, opts...)
+}
+
+// cacheBackend is the cache of a repository and, when it is a *redis.Manager, the
+// manager used for the features only Redis provides (see Cache)
+type cacheBackend struct {
+	Cache
+	manager   *redis.Manager // nil for other caches
+	writeOnly bool           // Reads miss while writes go through (see withoutReads)
+}
+
+// newCacheBackend wraps cache; nil for a nil cache
+func newCacheBackend(cache Cache) *cacheBackend {
+	if cache == nil {
+		return nil
+	}
+	manager, _ := cache.(*redis.Manager)
+	return &cacheBackend{Cache: cache, manager: manager}
+}
+
+// withoutReads returns a copy of the backend whose reads miss, for reads that bypass
+// the cache but refresh it
+func (b *cacheBackend) withoutReads() *cacheBackend {
+	copied := *b
+	copied.writeOnly = true
+	return &copied
+}
+
+// unsupported returns ErrCacheUnsupported for a feature only Redis provides
+func unsupported(method string) error {
+	return fmt.Errorf("%s: %w", method, ErrCacheUnsupported)
+}
+
+func (b *cacheBackend) Logger() redis.Logger {
+	if b.manager == nil {
+		return log.Default()
+	}
+	return b.manager.Logger()
+}
+
+func (b *cacheBackend) GetValue(ctx context.Context, key string, target interface{}) error {
+	if b.writeOnly {
+		return redis.ErrKeyNotFound
+	}
+	return b.Cache.GetValue(ctx, key, target)
+}
+
+func (b *cacheBackend) GetLargeValue(ctx context.Context, key string, target interface{}) error {
+	if b.writeOnly {
+		return redis.ErrKeyNotFound
+	}
+	return b.Cache.GetLargeValue(ctx, key, target)
+}
+
+// GetMany reads raw values in one round trip; ErrCacheUnsupported without Redis
+func (b *cacheBackend) GetMany(ctx context.Context, keys []string) (map[string][]byte, error) {
+	if b.writeOnly {
+		return map[string][]byte{}, nil
+	}
+	if b.manager == nil {
+		return nil, unsupported("GetMany")
+	}
+	return b.manager.GetMany(ctx, keys)
+}
+
+// GetManyValues reads several values, in one round trip with Redis
+func (b *cacheBackend) GetManyValues(ctx context.Context, keys []string, newTarget func() interface{}) (map[string]interface{}, error) {
+	if b.writeOnly {
+		return map[string]interface{}{}, nil
+	}
+	if b.manager != nil {
+		return b.manager.GetManyValues(ctx, keys, newTarget)
+	}
+
+	values := make(map[string]interface{}, len(keys))
+	for _, key := range keys {
+		target := newTarget()
+		err := b.Cache.GetValue(ctx, key, target)
+		switch {
+		case err == nil:
+			values[key] = target
+		case redis.IsKeyNotFound(err) || redis.IsNullValue(err):
+		default:
+			return nil, err
+		}
+	}
+	return values, nil
+}
+
+// SetLargeValueWithTTL stores a large value without dependencies
+func (b *cacheBackend) SetLargeValueWithTTL(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	if b.manager != nil {
+		return b.manager.SetLargeValueWithTTL(ctx, key, value, ttl)
+	}
+	return b.Cache.SetLargeValueWithDependenciesTTL(ctx, key, value, nil, ttl)
+}
+
+// InvalidatePatternCoalesced wipes a pattern, coalesced with Redis (see
+// redis.Invalidation.DebounceWindow) and immediately otherwise
+func (b *cacheBackend) InvalidatePatternCoalesced(ctx context.Context, pattern string) error {
+	if b.manager == nil {
+		return b.Cache.InvalidatePattern(ctx, pattern)
+	}
+	return b.manager.InvalidatePatternCoalesced(ctx, pattern)
+}
+
+// RecordTableInvalidation records a table wipe in the invalidation log of Redis
+func (b *cacheBackend) RecordTableInvalidation(ctx context.Context, dbName, table, operation string) error {
+	if b.manager == nil {
+		return nil
+	}
+	return b.manager.RecordTableInvalidation(ctx, dbName, table, operation)
+}
+
+// EqualEncoded compares two values by their encoding: the manager's serialization
+// format with Redis, JSON otherwise
+func (b *cacheBackend) EqualEncoded(x, y interface{}) (bool, error) {
+	if b.manager != nil {
+		return b.manager.EqualEncoded(x, y)
+	}
+	encodedX, err := json.Marshal(x)
+	if err != nil {
+		return false, err
+	}
+	encodedY, err := json.Marshal(y)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(encodedX, encodedY), nil
+}
@@ -0,0 +1,306 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ammar0144/sql4go/pkg/redis"
+)
+
+const (
+	cacheInfoScanLimit  = 10000 // Keys inspected by CacheInfo at most
+	cacheInfoMaxSampled = 1000  // Keys whose MEMORY USAGE is sampled at most
+)
+
+// CacheReport summarizes the cached keys of one table
+type CacheReport struct {
+	Database      string                     `json:"database"`
+	Table         string                     `json:"table"`
+	SchemaVersion string                     `json:"schema_version"` // Current version; keys of older versions are included
+	TotalKeys     int                        `json:"total_keys"`     // Raw keys, including chunk and metadata keys
+	TotalBytes    int64                      `json:"total_bytes"`    // Estimated from sampled MEMORY USAGE
+	Operations    map[string]OperationReport `json:"operations"`     // Keyed by operation (find_by_id, find_where, ...)
+	Truncated     bool                       `json:"truncated"`      // More keys exist than were scanned
+
+	LastInvalidation *InvalidationInfo `json:"last_invalidation,omitempty"` // nil if none was recorded
+}
+
+// InvalidationInfo is the last table-level invalidation of a table
+type InvalidationInfo struct {
+	Time      time.Time `json:"time"`      // Zero if none was recorded
+	Operation string    `json:"operation"` // create, update, delete, invalidate_cache
+	Host      string    `json:"host"`      // Hostname of the invalidating process
+}
+
+// OperationReport summarizes the cached keys of one operation
+type OperationReport struct {
+	Keys      int           `json:"keys"`
+	Bytes     int64         `json:"bytes"`      // Estimated from sampled MEMORY USAGE
+	OldestTTL time.Duration `json:"oldest_ttl"` // Shortest remaining TTL (the key cached longest ago)
+	NewestTTL time.Duration `json:"newest_ttl"` // Longest remaining TTL (the most recently cached key)
+}
+
+// CacheInfo reports the cached keys of this repository's table by operation
+// At most 10000 keys are scanned (see Truncated) and the memory usage of at most
+// 1000 of them is sampled and extrapolated.
+//
+// Like PurgeOperation, which drops the keys of one operation while leaving the others
+// warm, it SCANs the table's keys across schema versions; in Redis Cluster mode SCAN
+// only covers the node serving the table's keys when hash tags are enabled.
+// AdminHandler exposes both, LastInvalidation and AuditCache over HTTP next to
+// MetricsHandler.
+func (r *GenericRepository[T]) CacheInfo(ctx context.Context) (CacheReport, error) {
+	report := CacheReport{
+		Database:      r.dbName,
+		Table:         r.tableName,
+		SchemaVersion: r.schemaVersion,
+		Operations:    make(map[string]OperationReport),
+	}
+	if r.redis == nil {
+		return report, nil
+	}
+	if r.redis.manager == nil {
+		return report, unsupported("CacheInfo")
+	}
+
+	keys, truncated, err := r.redis.manager.ScanKeys(ctx, tableCachePattern(r.tableNamespace), cacheInfoScanLimit)
+	if err != nil {
+		return report, err
+	}
+	sampleEvery := (len(keys) + cacheInfoMaxSampled - 1) / cacheInfoMaxSampled
+	infos, err := r.redis.manager.InspectKeys(ctx, keys, sampleEvery)
+	if err != nil {
+		return report, err
+	}
+
+	sampledBytes := make(map[string]int64)
+	sampledKeys := make(map[string]int)
+	for _, info := range infos {
+		operation := r.keyOperation(info.Key)
+		op := report.Operations[operation]
+		op.Keys++
+		if info.TTL >= 0 {
+			if op.OldestTTL == 0 || info.TTL < op.OldestTTL {
+				op.OldestTTL = info.TTL
+			}
+			if info.TTL > op.NewestTTL {
+				op.NewestTTL = info.TTL
+			}
+		}
+		if info.MemoryBytes >= 0 {
+			sampledBytes[operation] += info.MemoryBytes
+			sampledKeys[operation]++
+		}
+		report.Operations[operation] = op
+	}
+
+	// Extrapolate the sampled memory usage to all keys of each operation
+	for operation, op := range report.Operations {
+		if sampledKeys[operation] > 0 {
+			op.Bytes = sampledBytes[operation] * int64(op.Keys) / int64(sampledKeys[operation])
+		}
+		report.Operations[operation] = op
+		report.TotalKeys += op.Keys
+		report.TotalBytes += op.Bytes
+	}
+	report.Truncated = truncated
+
+	// Best effort - the report is still useful without it
+	if last, err := r.LastInvalidation(ctx); err == nil && !last.Time.IsZero() {
+		report.LastInvalidation = &last
+	}
+	return report, nil
+}
+
+// LastInvalidation returns the last table-level invalidation of this repository's table:
+// a write wiping the table's query caches, or InvalidateCache. Invalidations are recorded
+// by every process sharing the cache in the invalidation log of redis.Manager (one hash
+// field per table); the zero InvalidationInfo means none was recorded.
+func (r *GenericRepository[T]) LastInvalidation(ctx context.Context) (InvalidationInfo, error) {
+	if r.redis == nil || r.redis.manager == nil {
+		return InvalidationInfo{}, nil // Only Redis records invalidations
+	}
+
+	last, err := r.redis.manager.LastTableInvalidation(ctx, r.dbName, r.tableName)
+	if redis.IsKeyNotFound(err) {
+		return InvalidationInfo{}, nil
+	}
+	if err != nil {
+		return InvalidationInfo{}, err
+	}
+	return InvalidationInfo{Time: last.Time, Operation: last.Operation, Host: last.Host}, nil
+}
+
+// recordTableInvalidation records a table-level invalidation in the invalidation log
+// (ignore errors - best effort)
+func (r *GenericRepository[T]) recordTableInvalidation(ctx context.Context, operation string) {
+	_ = r.redis.RecordTableInvalidation(ctx, r.dbName, r.tableName, operation)
+}
+
+// PurgeOperation deletes the cached keys of one operation of this table (e.g. "find_where"),
+// across schema versions, leaving the other operations' keys in place
+func (r *GenericRepository[T]) PurgeOperation(ctx context.Context, operation string) error {
+	if operation == "" || strings.ContainsAny(operation, ":*?[]") {
+		return fmt.Errorf("invalid operation %q", operation)
+	}
+	if r.redis == nil {
+		return nil
+	}
+	if r.redis.manager == nil {
+		return unsupported("PurgeOperation")
+	}
+
+	keys, _, err := r.redis.manager.ScanKeys(ctx, tableCachePattern(r.tableNamespace), 0)
+	if err != nil {
+		return err
+	}
+
+	const deleteBatchSize = 100
+	var batch []string
+	for _, key := range keys {
+		if r.keyOperation(key) != operation {
+			continue
+		}
+		batch = append(batch, key)
+		if len(batch) == deleteBatchSize {
+			if err := r.redis.DeleteKeys(ctx, batch); err != nil {
+				return fmt.Errorf("failed to delete batch: %w", err)
+			}
+			batch = batch[:0]
+		}
+	}
+	return r.redis.DeleteKeys(ctx, batch)
+}
+
+// keyOperation returns the operation segment of a cache key of this table
+// Keys are "<table namespace>:<schema version>:<operation>[:<suffix>]".
+func (r *GenericRepository[T]) keyOperation(key string) string {
+	rest := strings.TrimPrefix(key, r.tableNamespace+cacheKeySeparator)
+	segments := strings.SplitN(rest, cacheKeySeparator, 3)
+	if len(segments) < 2 {
+		return "unknown"
+	}
+	// Large values of keys without a suffix add "_internal:meta"/"_internal:chunk:<n>"
+	if idx := strings.Index(segments[1], "_internal"); idx > 0 {
+		return segments[1][:idx]
+	}
+	return segments[1]
+}
+
+// CacheAdmin is implemented by repositories and served by AdminHandler
+type CacheAdmin interface {
+	CacheInfo(ctx context.Context) (CacheReport, error)
+	PurgeOperation(ctx context.Context, operation string) error
+	AuditCache(ctx context.Context, sampleSize int, repair ...AuditRepair) (AuditReport, error)
+}
+
+// AdminHandler returns an http.Handler for inspecting and purging the caches of repos,
+// keyed by a name used in requests (typically the table name):
+//   - GET  ?table=orders                      -> JSON CacheReports; all repositories without table
+//   - POST ?table=orders&operation=find_where -> purge one operation of one repository
+//   - GET  ?table=orders&audit=500            -> JSON AuditReports of up to 500 entries each
+//   - POST ?table=orders&audit=500&repair=recache|delete -> audit and repair one repository
+//
+// It has no authentication; mount it on an internal-only listener.
+func AdminHandler(repos map[string]CacheAdmin) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ctx := req.Context()
+		table := req.URL.Query().Get("table")
+
+		names := make([]string, 0, len(repos))
+		for name := range repos {
+			if table == "" || name == table {
+				names = append(names, name)
+			}
+		}
+		if table != "" && len(names) == 0 {
+			http.Error(w, fmt.Sprintf("unknown table %q", table), http.StatusNotFound)
+			return
+		}
+		sort.Strings(names)
+
+		if audit := req.URL.Query().Get("audit"); audit != "" {
+			serveAudit(w, req, repos, names, audit)
+			return
+		}
+
+		switch req.Method {
+		case http.MethodGet:
+			reports := make([]CacheReport, 0, len(names))
+			for _, name := range names {
+				report, err := repos[name].CacheInfo(ctx)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+				reports = append(reports, report)
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(reports)
+		case http.MethodPost:
+			operation := req.URL.Query().Get("operation")
+			if table == "" || operation == "" {
+				http.Error(w, "table and operation are required", http.StatusBadRequest)
+				return
+			}
+			if err := repos[table].PurgeOperation(ctx, operation); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+// serveAudit serves the audit requests of AdminHandler; repairs require POST and a table
+func serveAudit(w http.ResponseWriter, req *http.Request, repos map[string]CacheAdmin, names []string, audit string) {
+	sampleSize, err := strconv.Atoi(audit)
+	if err != nil || sampleSize <= 0 {
+		http.Error(w, fmt.Sprintf("invalid audit sample size %q", audit), http.StatusBadRequest)
+		return
+	}
+
+	repair := AuditRepairNone
+	switch req.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		switch req.URL.Query().Get("repair") {
+		case "recache":
+			repair = AuditRepairRecache
+		case "delete":
+			repair = AuditRepairDelete
+		default:
+			http.Error(w, "repair must be recache or delete", http.StatusBadRequest)
+			return
+		}
+		if req.URL.Query().Get("table") == "" {
+			http.Error(w, "table is required", http.StatusBadRequest)
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	reports := make([]AuditReport, 0, len(names))
+	for _, name := range names {
+		report, err := repos[name].AuditCache(req.Context(), sampleSize, repair)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		reports = append(reports, report)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(reports)
+}
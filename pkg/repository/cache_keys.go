@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/ammar0144/sql4go/pkg/redis"
+)
+
+// defaultMaxKeyLength is the key length limit of the default Redis configuration
+var defaultMaxKeyLength = redis.DefaultConfig().MaxKeyLength
+
+// CacheKeyForID returns the FindByID cache key of the entity with the given primary key
+// in database dbName, e.g. "sql4go:app:users:1a2b3c4d:find_by_id:42"
+// It fails for entity types a repository cannot be created for (see TableName).
+//
+// Runbooks and admin tools need the Redis key of a row or query without running the
+// repository. CacheKeyForID and CacheKeyForQuery compute them with the helpers used by
+// the repository itself (cacheKeyNamespace, versionedNamespace, buildCacheKey,
+// queryKeySuffix), so they follow any change of the key format.
+//
+// The package functions assume the default Redis configuration (redis.DefaultConfig):
+// no Namespace, no cluster hash tags and its MaxKeyLength, so long or unsafe suffixes
+// are hashed as a default repository hashes them. They take the key-related options of
+// the repository whose keys they compute: WithKeyHasher (query segments) and
+// WithSchemaVersion; other options are ignored. Without WithSchemaVersion, the schema
+// version is the one registered by a repository of the table in this process, or else
+// the fingerprint of T. Use the repository methods for keys matching a repository's
+// actual configuration.
+func CacheKeyForID[T Entity](dbName string, id interface{}, opts ...Option) (string, error) {
+	namespace, err := defaultKeyNamespace[T](dbName, applyOptions(opts))
+	if err != nil {
+		return "", err
+	}
+	return buildCacheKey(namespace, "find_by_id", fmt.Sprintf("%v", id), defaultMaxKeyLength), nil
+}
+
+// CacheKeyForQuery returns the cache key of a query operation ("find_where", "count",
+// "first", ...) with the given conditions in database dbName; query and args are
+// those passed to the repository method, including a *db.Builder (whose ordering and
+// limits are part of the key). It fails for builders the repository rejects and for
+// entity types a repository cannot be created for.
+func CacheKeyForQuery[T Entity](dbName, operation string, query interface{}, args []interface{}, opts ...Option) (string, error) {
+	options := applyOptions(opts)
+	namespace, err := defaultKeyNamespace[T](dbName, options)
+	if err != nil {
+		return "", err
+	}
+	query, args, clauses, err := splitBuilder(query, args)
+	if err != nil {
+		return "", err
+	}
+	chain := make([]chainClause, len(clauses))
+	for i, c := range clauses {
+		chain[i] = c.chainClause
+	}
+	return buildCacheKey(namespace, operation, queryKeySuffix(query, args, chainKeyOf(chain), options.KeyHasher), defaultMaxKeyLength), nil
+}
+
+// defaultKeyNamespace returns the versioned key namespace of T's table in database dbName
+// under the default Redis configuration
+func defaultKeyNamespace[T Entity](dbName string, options Options) (string, error) {
+	entityType := reflect.TypeOf((*T)(nil)).Elem()
+	tableName, err := entityTableName(entityType)
+	if err != nil {
+		return "", err
+	}
+	namespace := cacheKeyNamespace(nil, dbName, tableName)
+	if options.SchemaVersion != "" {
+		return namespace + cacheKeySeparator + options.SchemaVersion, nil
+	}
+	return versionedNamespace(namespace, entityType), nil
+}
+
+// CacheKeyForID returns the FindByID cache key of the entity with the given primary key,
+// as used by this repository (namespace, hash tags, key length limit and chained clauses)
+func (r *GenericRepository[T]) CacheKeyForID(id interface{}) string {
+	return r.generateCacheKey("find_by_id", fmt.Sprintf("%v", id))
+}
+
+// CacheKeyForQuery returns the cache key this repository uses for a query operation with
+// the given conditions (for find_where with WithNormalizedCollections, the ID list key);
+// "" for builders the repository rejects
+func (r *GenericRepository[T]) CacheKeyForQuery(operation string, query interface{}, args ...interface{}) string {
+	r, query, args, err := r.scopeBuilder(query, args)
+	if err != nil {
+		return ""
+	}
+	if operation == "find_where" && r.normalizedCollections() {
+		return r.idListKey(operation, query, args...)
+	}
+	return r.generateCacheKeyFromQuery(operation, query, args...)
+}
@@ -0,0 +1,212 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/ammar0144/sql4go/pkg/redis"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestLongKeySuffixIsHashed(t *testing.T) {
+	namespace := "sql4go:app:users:s1"
+	long := strings.Repeat("x", 1000)
+
+	key := buildCacheKey(namespace, "find_by_id", long, 64)
+	if len(key) > 64 {
+		t.Errorf("key length = %d, want at most 64: %s", len(key), key)
+	}
+	if !strings.HasPrefix(key, namespace+":find_by_id:"+cacheKeyHashedMarker) {
+		t.Errorf("key %s does not keep the namespace and operation with a hashed suffix", key)
+	}
+	if again := buildCacheKey(namespace, "find_by_id", long, 64); again != key {
+		t.Errorf("hashed key not deterministic: %s != %s", again, key)
+	}
+	if other := buildCacheKey(namespace, "find_by_id", long+"y", 64); other == key {
+		t.Errorf("different suffixes hashed to the same key %s", key)
+	}
+	if short := buildCacheKey(namespace, "find_by_id", "42", 64); short != namespace+":find_by_id:42" {
+		t.Errorf("short suffix was not kept verbatim: %s", short)
+	}
+}
+
+func TestRepositoryKeysRespectMaxKeyLength(t *testing.T) {
+	env := newTestEnv(t)
+	repo := newTestRepository[testUser](env)
+
+	limit := env.cache.Config().MaxKeyLength
+	key := repo.CacheKeyForID(strings.Repeat("9", 2*limit))
+	if len(key) > limit {
+		t.Errorf("FindByID key length = %d, want at most %d", len(key), limit)
+	}
+}
+
+func TestNamespacesIsolateManagers(t *testing.T) {
+	env := newTestEnv(t, func(c *redis.Config) { c.Namespace = redis.RandomNamespace("a") })
+	repoA := newTestRepository[testUser](env)
+
+	// A second manager over the same Redis, in another namespace
+	config := *env.cache.Config()
+	config.Namespace = redis.RandomNamespace("b")
+	managerB, err := redis.NewManager(&config)
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+	t.Cleanup(func() { _ = managerB.Close() })
+	env.expectDatabaseName()
+	repoB := NewGenericRepository[testUser](env.dbm, managerB).(*GenericRepository[testUser])
+	t.Cleanup(func() { _ = repoB.Close() })
+
+	if repoA.CacheKeyForID(1) == repoB.CacheKeyForID(1) {
+		t.Fatalf("both namespaces use the key %s", repoA.CacheKeyForID(1))
+	}
+
+	ctx := context.Background()
+	env.mock.ExpectQuery("SELECT \\* FROM `users`").WillReturnRows(userRows(testUser{ID: 1, Name: "from a"}))
+	env.mock.ExpectQuery("SELECT \\* FROM `users`").WillReturnRows(userRows(testUser{ID: 1, Name: "from b"}))
+	for _, repo := range []*GenericRepository[testUser]{repoA, repoB} {
+		if _, hit, _, err := repo.FindByID(ctx, 1); err != nil || hit {
+			t.Fatalf("first FindByID = (hit %v, %v), want a miss in a fresh namespace", hit, err)
+		}
+	}
+
+	for repo, want := range map[*GenericRepository[testUser]]string{repoA: "from a", repoB: "from b"} {
+		user, hit, _, err := repo.FindByID(ctx, 1)
+		if err != nil || !hit || user.Name != want {
+			t.Errorf("FindByID = (%+v, hit %v, %v), want the cached %q", user, hit, err, want)
+		}
+	}
+	env.verify()
+}
+
+// testUnnamed is an entity without a table name
+type testUnnamed struct {
+	ID uint `gorm:"primaryKey"`
+}
+
+func (testUnnamed) TableName() string { return "" }
+
+func (u testUnnamed) GetPrimaryKeyValue() interface{} { return u.ID }
+
+func TestExportedKeysMatchRepositoryKeys(t *testing.T) {
+	env := newTestEnv(t)
+	repo := newTestRepository[testUser](env)
+	ctx := context.Background()
+
+	long := strings.Repeat("9", 2*env.cache.Config().MaxKeyLength)
+	for _, id := range []interface{}{7, "with space", long} {
+		want, err := CacheKeyForID[testUser]("app", id)
+		if err != nil {
+			t.Fatalf("CacheKeyForID(%v): %v", id, err)
+		}
+		if got := repo.CacheKeyForID(id); got != want {
+			t.Errorf("CacheKeyForID(%.20v) = %s, repository uses %s", id, want, got)
+		}
+
+		// The key a read actually stores
+		env.mock.ExpectQuery("SELECT \\* FROM `users`").WillReturnRows(userRows(testUser{ID: 7, Name: "ann"}))
+		if _, _, stored, err := repo.FindByID(ctx, id); err != nil || !stored {
+			t.Fatalf("FindByID(%.20v) = (stored %v, %v), want a cached row", id, stored, err)
+		}
+		if !env.mr.Exists(want) {
+			t.Errorf("FindByID(%.20v) did not store %s; keys %q", id, want, env.cachedKeys())
+		}
+	}
+
+	queries := []struct {
+		operation string
+		query     interface{}
+		args      []interface{}
+		read      func() (bool, error)
+	}{
+		{
+			operation: "find_where", query: "name = ?", args: []interface{}{"ann"},
+			read: func() (bool, error) { _, _, stored, err := repo.FindWhere(ctx, "name = ?", "ann"); return stored, err },
+		},
+		{
+			operation: "first", query: map[string]interface{}{"name": long},
+			read: func() (bool, error) {
+				_, _, stored, err := repo.First(ctx, map[string]interface{}{"name": long})
+				return stored, err
+			},
+		},
+	}
+	for _, q := range queries {
+		want, err := CacheKeyForQuery[testUser]("app", q.operation, q.query, q.args)
+		if err != nil {
+			t.Fatalf("CacheKeyForQuery(%s): %v", q.operation, err)
+		}
+		if got := repo.CacheKeyForQuery(q.operation, q.query, q.args...); got != want {
+			t.Errorf("CacheKeyForQuery(%s) = %s, repository uses %s", q.operation, want, got)
+		}
+
+		env.mock.ExpectQuery("SELECT \\* FROM `users`").WillReturnRows(userRows(testUser{ID: 7, Name: "ann"}))
+		if stored, err := q.read(); err != nil || !stored {
+			t.Fatalf("%s = (stored %v, %v), want a cached result", q.operation, stored, err)
+		}
+		if !env.mr.Exists(want) {
+			t.Errorf("%s did not store %s; keys %q", q.operation, want, env.cachedKeys())
+		}
+	}
+	env.verify()
+}
+
+func TestExportedKeysRejectInvalidEntity(t *testing.T) {
+	if key, err := CacheKeyForID[testUnnamed]("app", 1); err == nil {
+		t.Errorf("CacheKeyForID of an entity without a table = %q, want an error", key)
+	}
+	if key, err := CacheKeyForQuery[testUnnamed]("app", "find_where", "id = ?", []interface{}{1}); err == nil {
+		t.Errorf("CacheKeyForQuery of an entity without a table = %q, want an error", key)
+	}
+}
+
+func TestCustomKeyHasherChangesKeysDeterministically(t *testing.T) {
+	args := []interface{}{"printer"}
+	constant := KeyHasher(func(string) uint64 { return 0x0123456789abcdef })
+	key, err := CacheKeyForQuery[testTicket]("app", "find_where", "title = ?", args, WithKeyHasher(constant), WithSchemaVersion("v9"))
+	if err != nil || key != "sql4go:app:tickets:v9:find_where:0123456789ab" {
+		t.Errorf("CacheKeyForQuery with a constant hasher = (%s, %v), want its hash as the suffix", key, err)
+	}
+
+	keyWith := func(opts ...Option) string {
+		t.Helper()
+		key, err := CacheKeyForQuery[testTicket]("app", "find_where", "title = ?", args, opts...)
+		if err != nil {
+			t.Fatalf("CacheKeyForQuery: %v", err)
+		}
+		return key
+	}
+	seeded := keyWith(WithKeyHasher(SeededKeyHasher(42)))
+	if again := keyWith(WithKeyHasher(SeededKeyHasher(42))); again != seeded {
+		t.Errorf("seed 42 gave %s, then %s", seeded, again)
+	}
+	if unseeded := keyWith(); unseeded == seeded {
+		t.Errorf("seed 42 and the default hasher both gave %s", seeded)
+	}
+	if other := keyWith(WithKeyHasher(SeededKeyHasher(43))); other == seeded {
+		t.Errorf("seeds 42 and 43 both gave %s", seeded)
+	}
+
+	// A repository with the same options stores the computed keys
+	env := newTestEnv(t)
+	opts := []Option{WithKeyHasher(SeededKeyHasher(42)), WithSchemaVersion("v9")}
+	repo := newTestRepository[testTicket](env, opts...)
+	want := keyWith(opts...)
+	if got := repo.CacheKeyForQuery("find_where", "title = ?", args...); got != want {
+		t.Errorf("repository key %s, CacheKeyForQuery %s", got, want)
+	}
+	env.mock.ExpectQuery("SELECT \\* FROM `tickets`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title"}).AddRow(7, "printer"))
+	if _, _, stored, err := repo.FindWhere(context.Background(), "title = ?", args...); err != nil || !stored {
+		t.Fatalf("FindWhere = (stored %v, %v), want a cached result", stored, err)
+	}
+	if !env.mr.Exists(want) {
+		t.Errorf("FindWhere did not store %s; keys %q", want, env.cachedKeys())
+	}
+	if id, err := CacheKeyForID[testTicket]("app", 7, opts...); err != nil || id != repo.CacheKeyForID(7) {
+		t.Errorf("CacheKeyForID = (%s, %v), repository uses %s", id, err, repo.CacheKeyForID(7))
+	}
+	env.verify()
+}
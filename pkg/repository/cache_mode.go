@@ -0,0 +1,45 @@
+package repository
+
+import "context"
+
+// cacheMode is the cache mode of a request context
+type cacheMode int
+
+const (
+	cacheModeDefault cacheMode = iota
+	cacheModeBypass
+	cacheModeOnly
+)
+
+// cacheModeContextKey marks the cache mode of a context (see ContextWithCacheBypass)
+type cacheModeContextKey struct{}
+
+// ContextWithCacheBypass returns a context whose repository reads skip the cache and
+// query the database, refreshing the cache with the result (see WithCacheBypassPopulation)
+//
+// Support tooling and degradation modes need to change how one request uses the cache,
+// and the context already reaches every layer where a per-call option would not. Read
+// methods check the context for:
+//   - ContextWithCacheBypass: cache reads are skipped and the database is queried; the
+//     fresh result still replaces the cached entry, unless disabled with
+//     WithCacheBypassPopulation(false). Bypassed reads count as cache misses.
+//   - ContextWithCacheOnly: only the cache is read; a read that would query the database
+//     fails with ErrCacheOnlyMiss instead. Cached not-found markers are still answered.
+//
+// Writes are unaffected by either mode. Inside a transaction, reads always query the
+// database, so they fail with ErrCacheOnlyMiss under ContextWithCacheOnly.
+func ContextWithCacheBypass(ctx context.Context) context.Context {
+	return context.WithValue(ctx, cacheModeContextKey{}, cacheModeBypass)
+}
+
+// ContextWithCacheOnly returns a context whose repository reads are answered from the
+// cache only, failing with ErrCacheOnlyMiss instead of querying the database
+func ContextWithCacheOnly(ctx context.Context) context.Context {
+	return context.WithValue(ctx, cacheModeContextKey{}, cacheModeOnly)
+}
+
+// cacheModeOf returns the cache mode of a context
+func cacheModeOf(ctx context.Context) cacheMode {
+	mode, _ := ctx.Value(cacheModeContextKey{}).(cacheMode)
+	return mode
+}
@@ -0,0 +1,192 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"path"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ammar0144/sql4go/pkg/redis"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// fakeCache is an in-memory Cache recording the calls; it has no TTLs or dependency sets
+type fakeCache struct {
+	mu     sync.Mutex
+	config *redis.Config
+	values map[string][]byte
+	calls  []string
+}
+
+func newFakeCache() *fakeCache {
+	return &fakeCache{config: redis.DefaultConfig(), values: make(map[string][]byte)}
+}
+
+func (c *fakeCache) record(call string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls = append(c.calls, call)
+}
+
+func (c *fakeCache) Config() *redis.Config {
+	return c.config
+}
+
+func (c *fakeCache) get(call, key string, target interface{}) error {
+	c.record(call)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	data, ok := c.values[key]
+	if !ok {
+		return redis.ErrKeyNotFound
+	}
+	if data == nil {
+		return redis.ErrNullValue
+	}
+	return json.Unmarshal(data, target)
+}
+
+func (c *fakeCache) set(call, key string, value interface{}) error {
+	c.record(call)
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.values[key] = data
+	return nil
+}
+
+func (c *fakeCache) GetValue(ctx context.Context, key string, target interface{}) error {
+	return c.get("GetValue", key, target)
+}
+
+func (c *fakeCache) GetLargeValue(ctx context.Context, key string, target interface{}) error {
+	return c.get("GetLargeValue", key, target)
+}
+
+func (c *fakeCache) SetValueWithTTL(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	return c.set("SetValueWithTTL", key, value)
+}
+
+func (c *fakeCache) SetValueWithDependenciesTTL(ctx context.Context, key string, value interface{}, dependencies map[string][]interface{}, ttl time.Duration) error {
+	return c.set("SetValueWithDependenciesTTL", key, value)
+}
+
+func (c *fakeCache) SetLargeValueWithDependenciesTTL(ctx context.Context, key string, value interface{}, dependencies map[string][]interface{}, ttl time.Duration) error {
+	return c.set("SetLargeValueWithDependenciesTTL", key, value)
+}
+
+func (c *fakeCache) SetNull(ctx context.Context, key string) error {
+	c.record("SetNull")
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.values[key] = nil
+	return nil
+}
+
+func (c *fakeCache) delete(call string, keys ...string) error {
+	c.record(call)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, key := range keys {
+		delete(c.values, key)
+	}
+	return nil
+}
+
+func (c *fakeCache) DeleteKeys(ctx context.Context, keys []string) error {
+	return c.delete("DeleteKeys", keys...)
+}
+
+func (c *fakeCache) DeleteLarge(ctx context.Context, key string) error {
+	return c.delete("DeleteLarge", key)
+}
+
+func (c *fakeCache) InvalidatePattern(ctx context.Context, pattern string) error {
+	c.record("InvalidatePattern")
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key := range c.values {
+		if matched, _ := path.Match(pattern, key); matched {
+			delete(c.values, key)
+		}
+	}
+	return nil
+}
+
+func (c *fakeCache) InvalidateEntityDependencies(ctx context.Context, entityType string, entityID interface{}) error {
+	c.record("InvalidateEntityDependencies")
+	return nil
+}
+
+func (c *fakeCache) InvalidateKeyPatterns(ctx context.Context, dbName, entityType string, entityID interface{}) error {
+	c.record("InvalidateKeyPatterns")
+	return nil
+}
+
+// recorded returns the calls made so far and resets them
+func (c *fakeCache) recorded() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	calls := c.calls
+	c.calls = nil
+	return calls
+}
+
+// newFakeCacheRepository builds a repository caching through cache
+func newFakeCacheRepository(env *testEnv, cache Cache) *GenericRepository[testUser] {
+	env.t.Helper()
+	env.expectDatabaseName()
+	repo := NewGenericRepositoryWithCache[testUser](env.dbm, cache).(*GenericRepository[testUser])
+	env.t.Cleanup(func() { _ = repo.Close() })
+	return repo
+}
+
+func TestRepositoryUsesCacheInterface(t *testing.T) {
+	env := newTestEnv(t)
+	cache := newFakeCache()
+	repo := newFakeCacheRepository(env, cache)
+	ctx := context.Background()
+
+	// A miss is read from the database and stored, the next read is a hit
+	user := testUser{ID: 1, Name: "ann", Email: "a@x"}
+	env.mock.ExpectQuery("SELECT \\* FROM `users`").WillReturnRows(userRows(user))
+	if _, hit, stored, err := repo.FindByID(ctx, 1); err != nil || hit || !stored {
+		t.Fatalf("first FindByID = (hit %v, stored %v, %v), want a stored miss", hit, stored, err)
+	}
+	if found, hit, _, err := repo.FindByID(ctx, 1); err != nil || !hit || found.Name != "ann" {
+		t.Fatalf("second FindByID = (%+v, hit %v, %v), want a cache hit", found, hit, err)
+	}
+	if calls := cache.recorded(); !slices.Equal(calls, []string{"GetValue", "SetValueWithTTL", "GetValue"}) {
+		t.Errorf("FindByID calls = %q", calls)
+	}
+
+	// A write deletes the entity's keys and wipes the table
+	env.expectUniqueValues(user)
+	env.mock.ExpectExec("UPDATE `users` SET").WillReturnResult(sqlmock.NewResult(0, 1))
+	if _, err := repo.Update(ctx, &user); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	env.verify()
+	calls := cache.recorded()
+	if !slices.Contains(calls, "InvalidatePattern") || !slices.Contains(calls, "DeleteKeys") {
+		t.Errorf("Update calls = %q, want InvalidatePattern and DeleteKeys", calls)
+	}
+	if len(cache.values) != 0 {
+		t.Errorf("cached after Update: %v", cache.values)
+	}
+
+	// Redis-only features degrade instead of failing
+	if _, ok := repo.CacheMetrics(); ok {
+		t.Error("CacheMetrics reported metrics of a cache other than *redis.Manager")
+	}
+	if _, err := repo.CacheInfo(ctx); !IsCacheUnsupported(err) {
+		t.Errorf("CacheInfo error = %v, want ErrCacheUnsupported", err)
+	}
+}
@@ -0,0 +1,218 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/ammar0144/sql4go/pkg/redis"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// invalidationHandledKey marks GORM statements whose cache invalidation is already
+// handled by the repository, so the invalidation callbacks do not run twice
+const invalidationHandledKey = "sql4go:invalidation_handled"
+
+// InvalidationPlugin is a GORM plugin that invalidates sql4go caches for writes
+// issued directly through *gorm.DB (legacy code, admin scripts, etc.)
+//
+// Supported statements:
+//   - Create / Save / Updates / Update / Delete, including batch statements
+//   - Updates with map values: the primary key is taken from the model or the map
+//     when present; otherwise only the table-wide invalidation runs
+//
+// Out of scope:
+//   - Raw SQL executed via db.Exec() or db.Raw() - GORM does not run the
+//     create/update/delete callbacks for those, so call InvalidateCache manually
+//
+// Invalidation runs after GORM commits its default transaction. Writes inside an
+// explicit db.Transaction() are invalidated before the outer commit, so a concurrent
+// reader may briefly re-cache the pre-commit value until the next write or TTL. An
+// invalidation that fails or times out is retried in the background like a repository's
+// (see invalidationContext).
+type InvalidationPlugin struct {
+	redis  *cacheBackend
+	dbName string
+	retry  *invalidationRetrier // Retries incomplete invalidations
+}
+
+// NewInvalidationPlugin creates a plugin that invalidates caches through redisManager
+func NewInvalidationPlugin(redisManager *redis.Manager) *InvalidationPlugin {
+	cache := newCacheBackend(managerCache(redisManager))
+	return &InvalidationPlugin{redis: cache, retry: newInvalidationRetrier(cache)}
+}
+
+// RegisterInvalidationCallbacks registers the invalidation plugin on a GORM connection
+// Writes through gormDB (e.g. dbManager.DB()) then invalidate the same caches the
+// repository would: the table-wide pattern plus the entity dependency sets
+func RegisterInvalidationCallbacks(gormDB *gorm.DB, redisManager *redis.Manager) error {
+	if gormDB == nil {
+		return fmt.Errorf("gorm db cannot be nil")
+	}
+	if redisManager == nil {
+		return fmt.Errorf("redis manager cannot be nil")
+	}
+	return gormDB.Use(NewInvalidationPlugin(redisManager))
+}
+
+// Name implements gorm.Plugin
+func (p *InvalidationPlugin) Name() string {
+	return "sql4go:invalidation"
+}
+
+// Initialize implements gorm.Plugin by hooking the create/update/delete callbacks
+func (p *InvalidationPlugin) Initialize(gormDB *gorm.DB) error {
+	p.dbName = extractDatabaseName(gormDB)
+
+	callback := gormDB.Callback()
+	if err := callback.Create().After("gorm:commit_or_rollback_transaction").Register("sql4go:invalidate_create", p.invalidator(ChangeCreate)); err != nil {
+		return fmt.Errorf("failed to register create callback: %w", err)
+	}
+	if err := callback.Update().After("gorm:commit_or_rollback_transaction").Register("sql4go:invalidate_update", p.invalidator(ChangeUpdate)); err != nil {
+		return fmt.Errorf("failed to register update callback: %w", err)
+	}
+	if err := callback.Delete().After("gorm:commit_or_rollback_transaction").Register("sql4go:invalidate_delete", p.invalidator(ChangeDelete)); err != nil {
+		return fmt.Errorf("failed to register delete callback: %w", err)
+	}
+	return nil
+}
+
+// invalidator returns the callback of one operation, sharing the body of invalidate
+func (p *InvalidationPlugin) invalidator(operation ChangeOperation) func(tx *gorm.DB) {
+	return func(tx *gorm.DB) {
+		p.invalidate(tx, operation)
+	}
+}
+
+// invalidate is the callback body shared by create, update and delete
+func (p *InvalidationPlugin) invalidate(tx *gorm.DB, operation ChangeOperation) {
+	if tx.Error != nil || tx.RowsAffected == 0 || tx.Statement.Schema == nil {
+		return
+	}
+	if _, handled := tx.Get(invalidationHandledKey); handled {
+		return
+	}
+
+	// Detached from the statement's deadline, so a timeout cannot cut the invalidation short
+	ctx, cancel := invalidationContext(tx.Statement.Context, p.redis)
+	defer cancel()
+	tableName := tx.Statement.Table
+	if tableName == "" {
+		tableName = tx.Statement.Schema.Table
+	}
+
+	namespace := cacheKeyNamespace(p.redis, p.dbName, tableName)
+	keyNamespace := versionedNamespace(namespace, tx.Statement.Schema.ModelType)
+	affected := affectedEntities(tx.Statement)
+	ids := make([]interface{}, len(affected))
+	for i, entity := range affected {
+		ids[i] = entity.id
+	}
+
+	// Invalidate all caches for the table, plus the cached entity, exists entry and
+	// dependencies of every affected entity; retried in the background if incomplete
+	invalidateCaches := func(ctx context.Context) error {
+		return p.invalidateEntities(ctx, tableName, namespace, keyNamespace, ids)
+	}
+	if err := invalidateCaches(ctx); err != nil {
+		p.retry.add(&pendingInvalidation{
+			table:  p.dbName + "." + tableName,
+			target: fmt.Sprintf("%d entities after %s", len(ids), operation),
+			run: func(ctx context.Context) error {
+				ctx, cancel := invalidationContext(ctx, p.redis)
+				defer cancel()
+				return invalidateCaches(ctx)
+			},
+		}, err)
+	}
+
+	// Invalidation records and related entities (ignore errors - best effort)
+	_ = p.redis.RecordTableInvalidation(ctx, p.dbName, tableName, string(operation))
+	for _, entity := range affected {
+		if entity.entity != nil {
+			invalidateRelatedEntities(ctx, p.redis, p.dbName, tableName, relatedEntitiesOf(p.redis, tableName, entity.entity, entity.id))
+		}
+	}
+}
+
+// invalidateEntities wipes the table-wide caches and the cached entity, exists entry
+// and dependencies of every id, continuing past failures; it is idempotent, so a retry
+// repeats it in full
+func (p *InvalidationPlugin) invalidateEntities(ctx context.Context, tableName, namespace, keyNamespace string, ids []interface{}) error {
+	errs := []error{p.redis.InvalidatePatternCoalesced(ctx, tableCachePattern(namespace))}
+	for _, id := range ids {
+		errs = append(errs,
+			p.redis.DeleteLarge(ctx, buildCacheKey(keyNamespace, "find_by_id", fmt.Sprintf("%v", id), maxKeyLength(p.redis))),
+			p.redis.DeleteKeys(ctx, []string{existsCacheKey(keyNamespace, id, maxKeyLength(p.redis))}),
+			p.redis.InvalidateEntityDependencies(ctx, dependencyType(p.redis, p.dbName, tableName), id),
+			p.redis.InvalidateKeyPatterns(ctx, p.dbName, tableName, id),
+		)
+	}
+	return errors.Join(errs...)
+}
+
+// affectedEntity is an entity touched by a statement; entity is nil when only the
+// primary key is known (e.g. Updates with a map)
+type affectedEntity struct {
+	id     interface{}
+	entity interface{}
+}
+
+// affectedEntities determines the primary keys touched by a statement from its
+// Schema and ReflectValue, handling single values, batches and map updates
+func affectedEntities(stmt *gorm.Statement) []affectedEntity {
+	pkField := stmt.Schema.PrioritizedPrimaryField
+	if pkField == nil && len(stmt.Schema.PrimaryFields) > 0 {
+		pkField = stmt.Schema.PrimaryFields[0]
+	}
+	if pkField == nil {
+		return nil
+	}
+
+	var affected []affectedEntity
+	collect := func(value reflect.Value) {
+		value = reflect.Indirect(value)
+		if !value.IsValid() || value.Kind() != reflect.Struct {
+			return
+		}
+		if id, isZero := pkField.ValueOf(stmt.Context, value); !isZero {
+			affected = append(affected, affectedEntity{id: id, entity: value.Interface()})
+		}
+	}
+
+	switch stmt.ReflectValue.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < stmt.ReflectValue.Len(); i++ {
+			collect(stmt.ReflectValue.Index(i))
+		}
+	case reflect.Struct:
+		collect(stmt.ReflectValue)
+	}
+
+	// Updates with map values carry no full entity; use the primary key from the map if present
+	if len(affected) == 0 {
+		if id := primaryKeyFromMap(stmt.Dest, pkField); id != nil {
+			affected = append(affected, affectedEntity{id: id})
+		}
+	}
+
+	return affected
+}
+
+// primaryKeyFromMap extracts the primary key from an update map keyed by column or field name
+func primaryKeyFromMap(dest interface{}, pkField *schema.Field) interface{} {
+	values, ok := dest.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	if id, exists := values[pkField.DBName]; exists && id != nil {
+		return id
+	}
+	if id, exists := values[pkField.Name]; exists && id != nil {
+		return id
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"gorm.io/gorm"
+)
+
+// newPluginEnv registers the invalidation plugin and caches, for users 1 to 3, an order
+// query depending on the user; it returns the keys of those queries by user id
+func newPluginEnv(t *testing.T) (*testEnv, *GenericRepository[testUser], map[uint]string) {
+	t.Helper()
+	env := newTestEnv(t)
+	repo := newTestRepository[testUser](env)
+	env.expectDatabaseName()
+	if err := RegisterInvalidationCallbacks(env.gorm, env.cache); err != nil {
+		t.Fatalf("RegisterInvalidationCallbacks: %v", err)
+	}
+
+	dependents := make(map[uint]string)
+	for id := uint(1); id <= 3; id++ {
+		key := fmt.Sprintf("sql4go:app:orders:s1:find_where:user_id=%d", id)
+		dependencies := map[string][]interface{}{"users": {id}}
+		if err := env.cache.SetValueWithDependenciesTTL(context.Background(), key, []int{1}, dependencies, time.Hour); err != nil {
+			t.Fatalf("SetValueWithDependenciesTTL: %v", err)
+		}
+		dependents[id] = key
+	}
+	return env, repo, dependents
+}
+
+// expectInvalidated checks which users' dependent queries were invalidated
+func expectInvalidated(t *testing.T, env *testEnv, dependents map[uint]string, ids ...uint) {
+	t.Helper()
+	invalidated := make(map[uint]bool)
+	for _, id := range ids {
+		invalidated[id] = true
+	}
+	for id, key := range dependents {
+		if exists := env.mr.Exists(key); exists == invalidated[id] {
+			t.Errorf("query depending on user %d cached = %v, want %v", id, exists, !invalidated[id])
+		}
+	}
+}
+
+func TestInvalidationPluginBatchCreate(t *testing.T) {
+	env, _, dependents := newPluginEnv(t)
+
+	users := []testUser{{ID: 1, Name: "ann", Email: "a@x"}, {ID: 2, Name: "bob", Email: "b@x"}}
+	env.mock.ExpectExec("INSERT INTO `users`").WillReturnResult(sqlmock.NewResult(2, 2))
+	if err := env.gorm.Create(&users).Error; err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	env.verify()
+
+	expectInvalidated(t, env, dependents, 1, 2)
+}
+
+func TestInvalidationPluginUpdatesWithMap(t *testing.T) {
+	tests := []struct {
+		name    string
+		values  map[string]interface{}
+		query   func(db *gorm.DB) *gorm.DB
+		wantIDs []uint
+	}{
+		{
+			name:    "primary key column in the map",
+			values:  map[string]interface{}{"id": uint(2), "name": "bob"},
+			query:   func(db *gorm.DB) *gorm.DB { return db.Where("id = ?", 2) },
+			wantIDs: []uint{2},
+		},
+		{
+			name:    "primary key field in the map",
+			values:  map[string]interface{}{"ID": uint(3), "name": "cat"},
+			query:   func(db *gorm.DB) *gorm.DB { return db.Where("id = ?", 3) },
+			wantIDs: []uint{3},
+		},
+		{
+			name:   "no primary key",
+			values: map[string]interface{}{"name": "bob"},
+			query:  func(db *gorm.DB) *gorm.DB { return db.Where("email = ?", "b@x") },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env, repo, dependents := newPluginEnv(t)
+
+			// The table-wide wipe runs in every case
+			tableKey := repo.generateCacheKey("find_all", "")
+			env.mr.Set(tableKey, "x")
+
+			env.mock.ExpectExec("UPDATE `users` SET").WillReturnResult(sqlmock.NewResult(0, 1))
+			if err := tt.query(env.gorm.Model(&testUser{})).Updates(tt.values).Error; err != nil {
+				t.Fatalf("Updates: %v", err)
+			}
+			env.verify()
+
+			if env.mr.Exists(tableKey) {
+				t.Errorf("%s survived the update", tableKey)
+			}
+			expectInvalidated(t, env, dependents, tt.wantIDs...)
+		})
+	}
+}
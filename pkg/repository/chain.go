@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// chainClause is one chained clause and its cache key material
+type chainClause struct {
+	kind     string // where, or, not, select, omit, preload, joins, order, limit, offset
+	material string
+}
+
+// derive returns a copy of the repository with a clause applied to its query
+//
+// Chainable methods (Where, Or, Not, Select, Omit, Preload, Joins, Order, Limit,
+// Offset) return a derived repository whose GORM session carries the clause, applied
+// by the terminal method. Derived repositories are isolated: branching two queries off one never leaks
+// clauses between them. Each clause also records its cache key material, folded into
+// the keys of all reads, so chained and unchained queries never share cache entries.
+//
+// Concurrency: neither the base repository nor a derived one is ever modified, so both
+// can be used and chained from any number of goroutines. derive copies the struct and
+// gives the copy its own GORM session and chain slice (capped, so appends never write
+// into a shared backing array). The copies share the components built at construction
+// (cache manager, write-behind queue, outbox relay, change notifier, verifier, re-warm
+// scheduler), which synchronize internally; Close on any copy stops them for all.
+func (r *GenericRepository[T]) derive(kind, material string, apply func(db *gorm.DB) *gorm.DB) *GenericRepository[T] {
+	newRepo := *r
+	// A new session makes later chaining clone the statement rather than mutate this one
+	newRepo.db = apply(r.db).Session(&gorm.Session{})
+	newRepo.chain = append(r.chain[:len(r.chain):len(r.chain)], chainClause{kind: kind, material: material})
+	return &newRepo
+}
+
+// chainKey returns the cache key material of the chained clauses ("" if none)
+// ANDed conditions (Where, Not) are sorted, so identical conditions chained in any order
+// share a key; once Or is used, conditions keep their order since it changes the result.
+func (r *GenericRepository[T]) chainKey() string {
+	return chainKeyOf(r.chain)
+}
+
+// chainKeyOf returns the cache key material of the given chained clauses ("" if none)
+func chainKeyOf(chain []chainClause) string {
+	if len(chain) == 0 {
+		return ""
+	}
+
+	var conditions, clauses []string
+	ordered := false
+	for _, c := range chain {
+		entry := c.kind + "(" + c.material + ")"
+		switch c.kind {
+		case "where", "not":
+			conditions = append(conditions, entry)
+		case "or":
+			conditions = append(conditions, entry)
+			ordered = true
+		default:
+			clauses = append(clauses, entry)
+		}
+	}
+	if !ordered {
+		sort.Strings(conditions)
+	}
+	return strings.Join(conditions, ",") + "|" + strings.Join(clauses, ",")
+}
+
+// conditionKeyText returns the cache key material of a chained condition
+// A *gorm.DB condition (a group or subquery) is rendered to SQL with a dry run.
+func (r *GenericRepository[T]) conditionKeyText(query interface{}, args []interface{}) string {
+	if q, ok := query.(*gorm.DB); ok {
+		stmt := q.Session(&gorm.Session{DryRun: true}).Find(new([]T)).Statement
+		return queryKeyText(stmt.SQL.String(), stmt.Vars)
+	}
+	return queryKeyText(query, args)
+}
+
+// restrictColumns derives a repository reading only some columns (Select, Omit)
+// Unknown columns are reported by the terminal call. The key material is the sorted
+// column set, so a partial result is never served to a caller expecting full entities.
+func (r *GenericRepository[T]) restrictColumns(kind string, columns []string, apply func(db *gorm.DB, names []string) *gorm.DB) Repository[T] {
+	var errs []error
+	if len(columns) == 0 {
+		errs = append(errs, fmt.Errorf("%s requires at least one column", kind))
+	}
+
+	names := make([]string, 0, len(columns))
+	material := make([]string, 0, len(columns))
+	for _, column := range columns {
+		name, err := r.resolveColumn(column)
+		if err != nil {
+			errs = append(errs, err)
+			material = append(material, column)
+			continue
+		}
+		names = append(names, name)
+		material = append(material, name)
+	}
+	sort.Strings(material)
+
+	newRepo := r.derive(kind, strings.Join(material, ","), func(db *gorm.DB) *gorm.DB {
+		db = apply(db, names)
+		if err := errors.Join(errs...); err != nil {
+			_ = db.AddError(err)
+		}
+		return db
+	})
+	newRepo.partial = true
+	return newRepo
+}
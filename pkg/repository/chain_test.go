@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestStackedWhereShareKeyRegardlessOfOrder(t *testing.T) {
+	env := newTestEnv(t)
+	repo := newTestRepository[testUser](env)
+	ctx := context.Background()
+
+	forward := repo.Where(ctx, "name = ?", "ann").Where(ctx, "email = ?", "a@x").Where(ctx, "id > ?", 0)
+	reverse := repo.Where(ctx, "id > ?", 0).Where(ctx, "email = ?", "a@x").Where(ctx, "name = ?", "ann")
+
+	env.mock.ExpectQuery("SELECT \\* FROM `users` WHERE name = \\? AND email = \\? AND id > \\?").
+		WithArgs("ann", "a@x", 0).
+		WillReturnRows(userRows(testUser{ID: 1, Name: "ann", Email: "a@x"}))
+
+	if _, hit, stored, err := forward.FindAll(ctx); err != nil || hit || !stored {
+		t.Fatalf("FindAll = (hit %v, stored %v, %v), want a cached miss", hit, stored, err)
+	}
+	users, hit, _, err := reverse.FindAll(ctx)
+	if err != nil || !hit || len(users) != 1 {
+		t.Fatalf("FindAll in reverse order = (%v, hit %v, %v), want the cached result", users, hit, err)
+	}
+	env.verify()
+
+	// Or makes the order significant
+	withOr := repo.Where(ctx, "name = ?", "ann").Or(ctx, "email = ?", "a@x").(*GenericRepository[testUser])
+	swapped := repo.Where(ctx, "email = ?", "a@x").Or(ctx, "name = ?", "ann").(*GenericRepository[testUser])
+	if withOr.chainKey() == swapped.chainKey() {
+		t.Errorf("Or conditions in different orders share the key material %q", withOr.chainKey())
+	}
+}
+
+func TestDerivedRepositoriesAreIsolated(t *testing.T) {
+	env := newTestEnv(t)
+	repo := newTestRepository[testUser](env)
+	ctx := context.Background()
+
+	base := repo.Where(ctx, "name = ?", "ann")
+	_ = base.Where(ctx, "email = ?", "a@x") // A branch that is never run
+
+	env.mock.ExpectQuery("SELECT \\* FROM `users` WHERE name = \\? AND id > \\?$").
+		WithArgs("ann", 0).
+		WillReturnRows(userRows())
+	if _, _, _, err := base.Where(ctx, "id > ?", 0).FindAll(ctx); err != nil {
+		t.Fatalf("FindAll: %v", err)
+	}
+
+	env.mock.ExpectQuery("SELECT \\* FROM `users` WHERE name = \\?$").
+		WithArgs("ann").
+		WillReturnRows(userRows())
+	if _, _, _, err := base.FindAll(ctx); err != nil {
+		t.Fatalf("FindAll on the base: %v", err)
+	}
+	env.verify()
+}
+
+func TestPartialColumnRepositoriesRejectWrites(t *testing.T) {
+	env := newTestEnv(t)
+	repo := newTestRepository[testUser](env)
+	ctx := context.Background()
+
+	for name, partial := range map[string]Repository[testUser]{
+		"select": repo.Select(ctx, "name"),
+		"omit":   repo.Omit(ctx, "email"),
+	} {
+		user := &testUser{ID: 1, Name: "ann"}
+		if _, err := partial.Create(ctx, user); !errors.Is(err, ErrPartialColumns) {
+			t.Errorf("%s: Create error = %v, want ErrPartialColumns", name, err)
+		}
+		if _, err := partial.Update(ctx, user); !IsPartialColumns(err) {
+			t.Errorf("%s: Update error = %v, want ErrPartialColumns", name, err)
+		}
+		if _, err := partial.Delete(ctx, 1); !IsPartialColumns(err) {
+			t.Errorf("%s: Delete error = %v, want ErrPartialColumns", name, err)
+		}
+	}
+	// No SQL is expected: writes fail before reaching the database
+	env.verify()
+}
+
+func TestPartialColumnReadsAreKeyedByColumnSet(t *testing.T) {
+	env := newTestEnv(t)
+	repo := newTestRepository[testUser](env)
+	ctx := context.Background()
+
+	env.mock.ExpectQuery("SELECT `name`,`id` FROM `users`").
+		WillReturnRows(sqlmock.NewRows([]string{"name", "id"}).AddRow("ann", 1))
+	if _, hit, stored, err := repo.Select(ctx, "name", "id").FindAll(ctx); err != nil || hit || !stored {
+		t.Fatalf("Select FindAll = (hit %v, stored %v, %v), want a cached miss", hit, stored, err)
+	}
+
+	// The same columns in another order, and as field names, share the entry
+	for _, columns := range [][]string{{"id", "name"}, {"Name", "ID"}} {
+		users, hit, _, err := repo.Select(ctx, columns...).FindAll(ctx)
+		if err != nil || !hit || len(users) != 1 || users[0].Name != "ann" {
+			t.Errorf("Select(%v) FindAll = (%v, hit %v, %v), want the cached partial result", columns, users, hit, err)
+		}
+	}
+	env.verify()
+
+	// A full read never gets the partial entities
+	env.mock.ExpectQuery("SELECT \\* FROM `users`$").
+		WillReturnRows(userRows(testUser{ID: 1, Name: "ann", Email: "ann@example.com"}))
+	users, hit, _, err := repo.FindAll(ctx)
+	if err != nil || hit || len(users) != 1 || users[0].Email != "ann@example.com" {
+		t.Fatalf("full FindAll = (%v, hit %v, %v), want the full row from the database", users, hit, err)
+	}
+
+	// Omit of the same columns is a different read
+	env.mock.ExpectQuery("SELECT `users`.`email` FROM `users`").
+		WillReturnRows(sqlmock.NewRows([]string{"email"}).AddRow("ann@example.com"))
+	if _, hit, _, err := repo.Omit(ctx, "name", "id").FindAll(ctx); err != nil || hit {
+		t.Fatalf("Omit FindAll = (hit %v, %v), want a database read", hit, err)
+	}
+	env.verify()
+}
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// stressWorkers and stressIterations size TestConcurrentRepositoryUse
+const (
+	stressWorkers    = 50
+	stressIterations = 4
+)
+
+// TestConcurrentRepositoryUse shares one base repository per entity between goroutines
+// running FindByID, Update and Preload chains; run it with -race
+func TestConcurrentRepositoryUse(t *testing.T) {
+	env := newTestEnv(t)
+	users := newTestRepository[testUser](env)
+	carts := newTestRepository[testCart](env)
+	ctx := context.Background()
+
+	// Statements arrive in any order: register enough for every goroutine up front
+	env.mock.MatchExpectationsInOrder(false)
+	for range stressWorkers * stressIterations {
+		env.mock.ExpectQuery("SELECT \\* FROM `users` WHERE `users`.`id` = \\?").
+			WillReturnRows(userRows(testUser{ID: 1, Name: "ada", Email: "ada@example.com"}))
+		env.mock.ExpectQuery("SELECT `id`,`email` FROM `users`").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "email"}).AddRow(1, "ada@example.com"))
+		env.mock.ExpectExec("UPDATE `users`").WillReturnResult(sqlmock.NewResult(0, 1))
+		env.mock.ExpectQuery("SELECT \\* FROM `carts`").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		env.mock.ExpectQuery("SELECT \\* FROM `cart_lines`").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "cart_id"}).AddRow(5, 1))
+	}
+
+	errs := make(chan error, stressWorkers)
+	var wg sync.WaitGroup
+	for worker := range stressWorkers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range stressIterations {
+				if err := stressIteration(ctx, users, carts, worker, i); err != nil {
+					errs <- fmt.Errorf("worker %d, iteration %d: %w", worker, i, err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	if _, ok := users.CacheMetrics(); !ok {
+		t.Errorf("CacheMetrics unavailable after the stress run")
+	}
+}
+
+// stressIteration runs one read, write and chained read
+func stressIteration(ctx context.Context, users *GenericRepository[testUser], carts *GenericRepository[testCart], worker, i int) error {
+	if _, _, _, err := users.FindByID(ctx, 1); err != nil {
+		return fmt.Errorf("FindByID: %w", err)
+	}
+
+	user := testUser{ID: 1, Name: fmt.Sprintf("ada-%d-%d", worker, i), Email: "ada@example.com"}
+	if _, err := users.Update(ctx, &user); err != nil {
+		return fmt.Errorf("Update: %w", err)
+	}
+
+	// Branch two chains off the shared base repository
+	preloaded := carts.Preload(ctx, "Lines")
+	if i%2 == 0 {
+		preloaded = preloaded.Limit(ctx, 10)
+	}
+	cart, _, _, err := preloaded.FindByID(ctx, 1)
+	if err != nil {
+		return fmt.Errorf("Preload(Lines).FindByID: %w", err)
+	}
+	if cart == nil || len(cart.Lines) != 1 {
+		return fmt.Errorf("Preload(Lines).FindByID = %+v, want cart 1 with its line", cart)
+	}
+
+	_, _ = users.CacheMetrics()
+	return nil
+}
@@ -0,0 +1,255 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// CreateWithConflict inserts entity with a caller-supplied ON CONFLICT clause and reports
+// whether a row was inserted or updated (false if the conflict left the row unchanged)
+//
+// CreateWithConflict and CreateBatchWithConflict pass the caller's clause.OnConflict to
+// GORM unchanged, for upserts CreateIgnore does not cover: updating some columns only,
+// assignments with gorm.Expr (bumping updated_at, incrementing a counter), or UpdateAll.
+// Cache invalidation treats the statement as a write to the entity's table: the table's
+// query caches are dropped and each written entity's caches and dependencies are
+// invalidated as for Update. Only a row CreateWithConflict inserted is written through;
+// an updated row is the result of the DoUpdates assignments (gorm.Expr, columns left
+// out), not the entity as passed, so it is only invalidated, as is every row of a batch.
+//
+// MySQL does not report the primary key of a row updated by ON DUPLICATE KEY UPDATE, so
+// for entities inserted without a primary key (auto-increment) the key GORM back-fills
+// is only reliable for new rows. For those entities the primary key is re-read by the
+// values of the conflict target columns (onConflict.Columns, e.g. a unique email) and
+// set on the entity before invalidation. Without target columns such entities cannot be
+// identified: only the table's caches are dropped, their dependents in other tables
+// expire with their TTL, and no change event is emitted for them.
+func (r *GenericRepository[T]) CreateWithConflict(ctx context.Context, entity *T, onConflict clause.OnConflict) (bool, error) {
+	if err := r.checkWritable(); err != nil {
+		return false, err
+	}
+
+	// Outbox: write and record the outbox row in one transaction
+	var written bool
+	if handled, err := r.writeViaOutbox(ctx, func(tx Repository[T]) (err error) {
+		written, err = tx.CreateWithConflict(ctx, entity, onConflict)
+		return err
+	}); handled {
+		return written, err
+	}
+
+	// Input validation
+	if entity == nil {
+		return false, fmt.Errorf("entity cannot be nil")
+	}
+	targets, err := r.conflictTargetFields(onConflict)
+	if err != nil {
+		return false, err
+	}
+
+	// Assign a generated primary key if enabled (see WithGeneratedIDs)
+	if err := r.assignGeneratedID(ctx, entity); err != nil {
+		return false, err
+	}
+	unkeyed := isZeroKey(r.primaryKeyValue(*entity))
+
+	// Write-behind: whether a row is written is only known once written
+	if err := r.awaitQueuedWrites(ctx); err != nil {
+		return false, fmt.Errorf("write-behind flush error: %w", err)
+	}
+
+	// Apply query timeout
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	// Execute database operation
+	start := time.Now()
+	tx := r.writeSession(ctx).Clauses(onConflict).Create(entity)
+	r.observeQuery(ctx, "create_with_conflict", start, tx.Error)
+	if tx.Error != nil {
+		return false, fmt.Errorf("database error: %w", classifyQueryError(ctx, tx.Error))
+	}
+	if tx.RowsAffected == 0 {
+		return false, nil // Conflict without changes
+	}
+
+	// MySQL counts 1 affected row for an insert and 2 for an update of an existing row
+	operation := ChangeCreate
+	if tx.RowsAffected > 1 {
+		operation = ChangeUpdate
+		if unkeyed && !r.resolveConflictKeys(ctx, []*T{entity}, targets)[0] {
+			r.invalidateTableCaches(ctx, ChangeUpdate)
+			return true, nil
+		}
+	}
+
+	// Invalidate related caches (write-through also caches an inserted row) and notify
+	r.notifyWrite(ctx, operation, *entity, operation == ChangeCreate)
+	return true, nil
+}
+
+// CreateBatchWithConflict inserts entities with a caller-supplied ON CONFLICT clause and
+// returns the affected row count as reported by MySQL: 1 per inserted row, 2 per updated
+// row and 0 per row left unchanged
+// If any row was affected, every entity of the batch is treated as written.
+func (r *GenericRepository[T]) CreateBatchWithConflict(ctx context.Context, entities []*T, onConflict clause.OnConflict) (int64, error) {
+	if err := r.checkWritable(); err != nil {
+		return 0, err
+	}
+
+	// Outbox: write and record the outbox rows in one transaction
+	var affected int64
+	if handled, err := r.writeViaOutbox(ctx, func(tx Repository[T]) (err error) {
+		affected, err = tx.CreateBatchWithConflict(ctx, entities, onConflict)
+		return err
+	}); handled {
+		return affected, err
+	}
+
+	if len(entities) == 0 {
+		return 0, nil
+	}
+	targets, err := r.conflictTargetFields(onConflict)
+	if err != nil {
+		return 0, err
+	}
+
+	// Assign generated primary keys if enabled (see WithGeneratedIDs) and remember the
+	// entities left without one
+	var unkeyed []*T
+	for _, entity := range entities {
+		if entity == nil {
+			continue
+		}
+		if err := r.assignGeneratedID(ctx, entity); err != nil {
+			return 0, err
+		}
+		if isZeroKey(r.primaryKeyValue(*entity)) {
+			unkeyed = append(unkeyed, entity)
+		}
+	}
+
+	// Write-behind: keep queued writes ordered before this batch
+	if err := r.awaitQueuedWrites(ctx); err != nil {
+		return 0, fmt.Errorf("write-behind flush error: %w", err)
+	}
+
+	// Apply query timeout
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	// Execute batch database operation
+	start := time.Now()
+	tx := r.writeSession(ctx).Clauses(onConflict).Create(&entities)
+	r.observeQuery(ctx, "create_batch_with_conflict", start, tx.Error)
+	if tx.Error != nil {
+		return 0, fmt.Errorf("batch create error: %w", classifyQueryError(ctx, tx.Error))
+	}
+	if tx.RowsAffected == 0 {
+		return 0, nil // All conflicts without changes
+	}
+
+	// GORM back-fills a batch's keys sequentially from the first inserted id, which is
+	// wrong once any row conflicted. Re-read them by the conflict target columns; without
+	// target columns they are trusted only if every row was inserted (one row each).
+	skipped := make(map[*T]bool)
+	if len(unkeyed) > 0 && (len(targets) > 0 || tx.RowsAffected != int64(len(entities))) {
+		for i, resolved := range r.resolveConflictKeys(ctx, unkeyed, targets) {
+			if !resolved {
+				skipped[unkeyed[i]] = true
+			}
+		}
+		if len(skipped) > 0 {
+			r.invalidateTableCaches(ctx, ChangeUpdate)
+		}
+	}
+
+	// Invalidate related caches for all entities and emit one change event per entity;
+	// which rows were updated is unknown, so none is written through
+	for _, entity := range entities {
+		if entity != nil && !skipped[entity] {
+			r.notifyWrite(ctx, ChangeCreate, *entity, false)
+		}
+	}
+
+	return tx.RowsAffected, nil
+}
+
+// conflictTargetFields resolves the conflict target columns of an ON CONFLICT clause
+func (r *GenericRepository[T]) conflictTargetFields(onConflict clause.OnConflict) ([]*schema.Field, error) {
+	fields := make([]*schema.Field, 0, len(onConflict.Columns))
+	for _, column := range onConflict.Columns {
+		name, err := r.resolveColumn(column.Name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid conflict target: %w", err)
+		}
+		fields = append(fields, r.entitySchema.LookUpField(name))
+	}
+	return fields, nil
+}
+
+// resolveConflictKeys re-reads the primary keys of written entities by the values of the
+// conflict target columns and sets them on the entities (best effort)
+// It reports, per entity, whether its primary key is known.
+func (r *GenericRepository[T]) resolveConflictKeys(ctx context.Context, entities []*T, targets []*schema.Field) []bool {
+	resolved := make([]bool, len(entities))
+	if len(targets) == 0 || r.entitySchema == nil || r.entitySchema.PrioritizedPrimaryField == nil {
+		return resolved
+	}
+	pkField := r.entitySchema.PrioritizedPrimaryField
+
+	// Match rows to entities by the text of their target column values
+	targetKey := func(rv reflect.Value) string {
+		parts := make([]string, len(targets))
+		for i, field := range targets {
+			value, _ := field.ValueOf(ctx, rv)
+			parts[i] = fmt.Sprintf("%v", value)
+		}
+		return strings.Join(parts, "\x00")
+	}
+
+	columns := []string{pkField.DBName}
+	conditions := make([]clause.Expression, 0, len(entities))
+	for _, entity := range entities {
+		rv := reflect.Indirect(reflect.ValueOf(entity))
+		eqs := make([]clause.Expression, len(targets))
+		for i, field := range targets {
+			value, _ := field.ValueOf(ctx, rv)
+			eqs[i] = clause.Eq{Column: clause.Column{Name: field.DBName}, Value: value}
+		}
+		conditions = append(conditions, clause.And(eqs...))
+	}
+	for _, field := range targets {
+		columns = append(columns, field.DBName)
+	}
+
+	// Read from the primary (inside a transaction, from the transaction itself)
+	var rows []T
+	start := time.Now()
+	err := r.writeSession(ctx).Select(columns).Where(clause.Or(conditions...)).Find(&rows).Error
+	r.observeQuery(ctx, "resolve_conflict_keys", start, err)
+	if err != nil {
+		return resolved
+	}
+
+	keys := make(map[string]interface{}, len(rows))
+	for i := range rows {
+		rv := reflect.ValueOf(&rows[i]).Elem()
+		if pk, isZero := pkField.ValueOf(ctx, rv); !isZero {
+			keys[targetKey(rv)] = pk
+		}
+	}
+	for i, entity := range entities {
+		rv := reflect.Indirect(reflect.ValueOf(entity))
+		if pk, ok := keys[targetKey(rv)]; ok {
+			resolved[i] = pkField.Set(ctx, rv, pk) == nil
+		}
+	}
+	return resolved
+}
@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ammar0144/sql4go/pkg/redis"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func TestCreateWithConflictUpdateInvalidatesWithoutWriteThrough(t *testing.T) {
+	env := newTestEnv(t, func(c *redis.Config) { c.Strategy = redis.CacheStrategyWriteThrough })
+	repo := newTestRepository[testUser](env)
+	ctx := context.Background()
+
+	// The row is cached before the upsert
+	env.mock.ExpectQuery("SELECT \\* FROM `users`").WillReturnRows(userRows(testUser{ID: 1, Name: "ann", Email: "a@x"}))
+	if _, _, stored, err := repo.FindByID(ctx, 1); err != nil || !stored {
+		t.Fatalf("FindByID = (stored %v, %v), want a cached row", stored, err)
+	}
+
+	// MySQL reports 2 affected rows for an update of the existing row
+	env.mock.ExpectExec("INSERT INTO `users` .* ON DUPLICATE KEY UPDATE `name`=CONCAT\\(name, '!'\\)").
+		WillReturnResult(sqlmock.NewResult(1, 2))
+
+	user := &testUser{ID: 1, Name: "ann", Email: "a@x"}
+	written, err := repo.CreateWithConflict(ctx, user, clause.OnConflict{
+		Columns:   []clause.Column{{Name: "email"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"name": gorm.Expr("CONCAT(name, '!')")}),
+	})
+	if err != nil || !written {
+		t.Fatalf("CreateWithConflict = (%v, %v), want a written row", written, err)
+	}
+	env.verify()
+
+	// The stored name is "ann!", not the entity as passed: the next read goes to the database
+	if env.mr.Exists(repo.CacheKeyForID(1)) {
+		t.Error("the updated row was written through as passed")
+	}
+	env.mock.ExpectQuery("SELECT \\* FROM `users`").WillReturnRows(userRows(testUser{ID: 1, Name: "ann!", Email: "a@x"}))
+	if found, hit, _, err := repo.FindByID(ctx, 1); err != nil || hit || found.Name != "ann!" {
+		t.Errorf("FindByID after the upsert = (%+v, hit %v, %v), want the stored row from the database", found, hit, err)
+	}
+	env.verify()
+}
+
+func TestCreateWithConflictInsertWritesThrough(t *testing.T) {
+	env := newTestEnv(t, func(c *redis.Config) { c.Strategy = redis.CacheStrategyWriteThrough })
+	repo := newTestRepository[testUser](env)
+
+	env.mock.ExpectExec("INSERT INTO `users` .* ON DUPLICATE KEY UPDATE").WillReturnResult(sqlmock.NewResult(7, 1))
+
+	user := &testUser{Name: "cid", Email: "c@x"}
+	written, err := repo.CreateWithConflict(context.Background(), user, clause.OnConflict{
+		Columns:   []clause.Column{{Name: "email"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"name": gorm.Expr("VALUES(name)")}),
+	})
+	if err != nil || !written || user.ID != 7 {
+		t.Fatalf("CreateWithConflict = (%v, %v) with id %d, want an inserted row with id 7", written, err, user.ID)
+	}
+	if !env.mr.Exists(repo.CacheKeyForID(7)) {
+		t.Error("the inserted row was not written through")
+	}
+	env.verify()
+}
@@ -0,0 +1,174 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+// CreateIgnore inserts entity unless it duplicates an existing key
+// Caches are invalidated and a change event emitted only if the row was inserted.
+//
+// CreateIgnore and CreateBatchIgnore insert rows unless a row with the same primary or
+// unique key exists. They use clause.OnConflict{DoNothing: true}, which the MySQL
+// driver renders as ON DUPLICATE KEY UPDATE <pk> = <pk> rather than INSERT IGNORE:
+// INSERT IGNORE also downgrades other failures (NOT NULL, foreign key, truncation) to
+// warnings, which would be reported as duplicates. Only duplicate keys are skipped here;
+// every other constraint failure is returned as an error.
+//
+// Whether a row was inserted comes from RowsAffected (1 per inserted row, 0 per
+// skipped duplicate). Connections opened with clientFoundRows=true count duplicates as
+// affected and must not be used with these methods.
+func (r *GenericRepository[T]) CreateIgnore(ctx context.Context, entity *T) (bool, error) {
+	if err := r.checkWritable(); err != nil {
+		return false, err
+	}
+
+	// Outbox: write and record the outbox row in one transaction
+	var inserted bool
+	if handled, err := r.writeViaOutbox(ctx, func(tx Repository[T]) (err error) {
+		inserted, err = tx.CreateIgnore(ctx, entity)
+		return err
+	}); handled {
+		return inserted, err
+	}
+
+	// Input validation
+	if entity == nil {
+		return false, fmt.Errorf("entity cannot be nil")
+	}
+
+	// Assign a generated primary key if enabled (see WithGeneratedIDs)
+	if err := r.assignGeneratedID(ctx, entity); err != nil {
+		return false, err
+	}
+
+	// Write-behind: whether the row is inserted is only known once written
+	if err := r.awaitQueuedWrites(ctx); err != nil {
+		return false, fmt.Errorf("write-behind flush error: %w", err)
+	}
+
+	// Apply query timeout
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	// Execute database operation
+	start := time.Now()
+	tx := r.writeSession(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(entity)
+	r.observeQuery(ctx, "create_ignore", start, tx.Error)
+	if tx.Error != nil {
+		return false, fmt.Errorf("database error: %w", classifyQueryError(ctx, tx.Error))
+	}
+	if tx.RowsAffected == 0 {
+		return false, nil // Duplicate - nothing changed
+	}
+
+	// Invalidate related caches (write-through also caches the new value) and notify
+	r.afterWrite(ctx, ChangeCreate, *entity)
+	return true, nil
+}
+
+// CreateBatchIgnore inserts entities, skipping those that duplicate an existing key,
+// and returns the number of rows inserted
+// If every row was inserted, the entities are handled as by CreateBatch. MySQL does not
+// report which rows were skipped, so if only some were:
+//   - No entity is written through: a skipped entity is not the stored row
+//   - Generated primary keys are reset to the zero value: GORM back-fills them
+//     sequentially from the first inserted id, which is wrong once a row was skipped.
+//     Such entities are invalidated by a table wipe and get no change event.
+//   - Entities with a caller-set primary key are invalidated by that key and emit a
+//     change event, including the skipped duplicates
+//   - The FindByUnique keys of every entity's unique values are deleted
+func (r *GenericRepository[T]) CreateBatchIgnore(ctx context.Context, entities []*T) (int64, error) {
+	if err := r.checkWritable(); err != nil {
+		return 0, err
+	}
+
+	// Outbox: write and record the outbox rows in one transaction
+	var inserted int64
+	if handled, err := r.writeViaOutbox(ctx, func(tx Repository[T]) (err error) {
+		inserted, err = tx.CreateBatchIgnore(ctx, entities)
+		return err
+	}); handled {
+		return inserted, err
+	}
+
+	if len(entities) == 0 {
+		return 0, nil
+	}
+
+	// Assign generated primary keys if enabled (see WithGeneratedIDs) and remember the
+	// entities left for the database to key
+	var written []*T
+	unkeyed := make(map[*T]bool)
+	for _, entity := range entities {
+		if entity == nil {
+			continue
+		}
+		if err := r.assignGeneratedID(ctx, entity); err != nil {
+			return 0, err
+		}
+		written = append(written, entity)
+		unkeyed[entity] = isZeroKey(r.primaryKeyValue(*entity))
+	}
+
+	// Write-behind: keep queued writes ordered before this batch
+	if err := r.awaitQueuedWrites(ctx); err != nil {
+		return 0, fmt.Errorf("write-behind flush error: %w", err)
+	}
+
+	// Apply query timeout
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	// Execute batch database operation
+	start := time.Now()
+	tx := r.writeSession(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&entities)
+	r.observeQuery(ctx, "create_batch_ignore", start, tx.Error)
+	if tx.Error != nil {
+		return 0, fmt.Errorf("batch create error: %w", classifyQueryError(ctx, tx.Error))
+	}
+	if tx.RowsAffected == 0 {
+		return 0, nil // All duplicates - nothing changed
+	}
+
+	// Every row inserted - invalidate related caches for all entities (write-through
+	// also caches them) and emit one change event per entity
+	if tx.RowsAffected == int64(len(written)) {
+		writes := make([]txWrite[T], len(written))
+		for i, entity := range written {
+			writes[i] = txWrite[T]{operation: ChangeCreate, entity: *entity, writeThrough: true}
+		}
+		r.notifyWrites(ctx, writes)
+		return tx.RowsAffected, nil
+	}
+
+	// Some rows were skipped (see above)
+	wipe := false
+	for _, entity := range written {
+		r.invalidateUniqueKeys(ctx, *entity)
+		if unkeyed[entity] {
+			r.clearPrimaryKey(ctx, entity)
+			wipe = true
+			continue
+		}
+		r.notifyWrite(ctx, ChangeCreate, *entity, false)
+	}
+	if wipe {
+		r.invalidateTableCaches(ctx, ChangeCreate)
+	}
+
+	return tx.RowsAffected, nil
+}
+
+// clearPrimaryKey resets the primary key of entity to its zero value
+func (r *GenericRepository[T]) clearPrimaryKey(ctx context.Context, entity *T) {
+	if r.entitySchema == nil || r.entitySchema.PrioritizedPrimaryField == nil {
+		return
+	}
+	field := r.entitySchema.PrioritizedPrimaryField
+	_ = field.Set(ctx, reflect.ValueOf(entity).Elem(), reflect.Zero(field.FieldType).Interface())
+}
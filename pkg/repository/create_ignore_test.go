@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ammar0144/sql4go/pkg/redis"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestCreateBatchIgnoreWithSkippedRowsDoesNotWriteThrough(t *testing.T) {
+	env := newTestEnv(t, func(c *redis.Config) { c.Strategy = redis.CacheStrategyWriteThrough })
+	repo := newTestRepository[testUser](env)
+	ctx := context.Background()
+
+	// A cached not-found of one of the inserted emails
+	uniqueKey := repo.uniqueCacheKey("email", "b@x")
+	env.mr.Set(uniqueKey, "null")
+
+	// Two of three rows inserted; MySQL reports the first inserted id only
+	env.mock.ExpectExec("INSERT INTO `users` .* ON DUPLICATE KEY UPDATE").
+		WillReturnResult(sqlmock.NewResult(10, 2))
+
+	users := []*testUser{{Name: "ann", Email: "a@x"}, {Name: "bob", Email: "b@x"}, {Name: "cid", Email: "c@x"}}
+	inserted, err := repo.CreateBatchIgnore(ctx, users)
+	if err != nil || inserted != 2 {
+		t.Fatalf("CreateBatchIgnore = (%d, %v), want 2 rows inserted", inserted, err)
+	}
+	env.verify()
+
+	for _, user := range users {
+		if user.ID != 0 {
+			t.Errorf("%s has the back-filled id %d, want it reset", user.Name, user.ID)
+		}
+	}
+	for _, id := range []int{10, 11, 12} {
+		if key := repo.CacheKeyForID(id); env.mr.Exists(key) {
+			t.Errorf("entity %d was written through to %s", id, key)
+		}
+	}
+	if env.mr.Exists(uniqueKey) {
+		t.Error("the FindByUnique key of an inserted email was not deleted")
+	}
+}
+
+func TestCreateBatchIgnoreWithAllRowsInsertedWritesThrough(t *testing.T) {
+	env := newTestEnv(t, func(c *redis.Config) { c.Strategy = redis.CacheStrategyWriteThrough })
+	repo := newTestRepository[testUser](env)
+	ctx := context.Background()
+
+	env.mock.ExpectExec("INSERT INTO `users` .* ON DUPLICATE KEY UPDATE").
+		WillReturnResult(sqlmock.NewResult(10, 2))
+
+	users := []*testUser{{Name: "ann", Email: "a@x"}, {Name: "bob", Email: "b@x"}}
+	if inserted, err := repo.CreateBatchIgnore(ctx, users); err != nil || inserted != 2 {
+		t.Fatalf("CreateBatchIgnore = (%d, %v), want 2 rows inserted", inserted, err)
+	}
+	env.verify()
+
+	for i, user := range users {
+		if want := uint(10 + i); user.ID != want {
+			t.Errorf("%s has id %d, want the back-filled %d", user.Name, user.ID, want)
+		}
+	}
+	if !env.mr.Exists(repo.CacheKeyForID(users[1].ID)) {
+		t.Error("the inserted entity was not written through")
+	}
+}
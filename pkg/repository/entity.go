@@ -1,5 +1,10 @@
 package repository
 
+import (
+	"fmt"
+	"strings"
+)
+
 // Entity interface defines the minimal contract for repository entities
 // GORM models should implement this for optimal caching and relationship detection
 // If not implemented, the repository will use reflection as fallback
@@ -10,8 +15,8 @@ type Entity interface {
 	TableName() string
 
 	// GetPrimaryKeyValue returns the actual value of the primary key
-	// Used for cache invalidation and dependency tracking
-	// If not implemented, reflection will be used to find 'ID' field
+	// Single-column keys are read through the GORM schema instead; it is used for
+	// composite keys and with WithEntityPrimaryKeyValue
 	GetPrimaryKeyValue() interface{}
 }
 
@@ -26,6 +31,32 @@ type RelationshipAware interface {
 	GetRelationships() map[string][]RelatedEntity
 }
 
+// PrimaryKeyAware allows entities to declare their primary key column explicitly
+// The declared column takes precedence over the GORM schema and reflection (primaryKey
+// tags, ID/UUID field names), removing the guesswork for non-standard key names. When
+// GORM maps a different column (or none) as the primary key, FindByID and Delete match
+// the declared column explicitly.
+type PrimaryKeyAware interface {
+	Entity
+
+	// PrimaryKeyColumn returns the database column of the primary key (e.g. "sku")
+	PrimaryKeyColumn() string
+}
+
+// CompositeKey returns the identifier of a multi-column key, used in cache keys and
+// dependency sets: the column values joined by commas, e.g. CompositeKey(7, "eu") is "7,eu".
+// Entities with a composite primary key should return CompositeKey of their key columns
+// from GetPrimaryKeyValue, in the order in which other entities' composite foreign keys
+// reference them (gorm:"foreignKey:OrgID,UserID"), so the dependencies registered for
+// the related rows match the entity's own.
+func CompositeKey(values ...interface{}) string {
+	parts := make([]string, len(values))
+	for i, value := range values {
+		parts[i] = fmt.Sprintf("%v", value)
+	}
+	return strings.Join(parts, ",")
+}
+
 // RelatedEntity represents a relationship to another entity
 type RelatedEntity struct {
 	EntityType string      // The related entity type (table name)
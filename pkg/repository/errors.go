@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// Sentinel errors for repository operations
+var (
+	// ErrQueryTimeout is returned when a query was aborted by a context deadline,
+	// typically the configured QueryTimeout. It also matches context.DeadlineExceeded.
+	ErrQueryTimeout = errors.New("query timeout")
+
+	// ErrCanceled is returned when a query was aborted by caller cancellation
+	// It also matches context.Canceled.
+	ErrCanceled = errors.New("query canceled")
+
+	// ErrReadOnlyTransaction is returned by write methods of a repository bound to a
+	// read-only transaction (see ReadOnlyTx), without contacting the database
+	ErrReadOnlyTransaction = errors.New("write in read-only transaction")
+
+	// ErrPartialColumns is returned by write methods of a repository restricted by
+	// Select or Omit, whose entities may not be fully populated
+	ErrPartialColumns = errors.New("write on a Select/Omit repository")
+
+	// ErrPrimaryKeyNotPopulated is returned by CreateBatch when rows were inserted but
+	// the generated primary keys of some entities were not back-filled
+	ErrPrimaryKeyNotPopulated = errors.New("generated primary key not populated")
+
+	// ErrInvalidEntity is returned by Validate and TryNewGenericRepository for entities
+	// whose mapping to the database is broken (see Validate)
+	ErrInvalidEntity = errors.New("invalid entity")
+
+	// ErrResultTruncated is returned by FindAll and FindWhere together with the first
+	// MaxQueryRows rows when the query matched more; the result is not cached
+	ErrResultTruncated = errors.New("result truncated at max query rows")
+
+	// ErrNotFound is returned by FindByID, First, Take, Last and FindByUnique instead of
+	// a nil result when no record matches, if enabled with WithReturnNotFoundError
+	ErrNotFound = errors.New("record not found")
+
+	// ErrCacheOnlyMiss is returned by reads under ContextWithCacheOnly when the result
+	// is not cached and would have to be queried from the database
+	ErrCacheOnlyMiss = errors.New("not cached (cache-only request)")
+
+	// ErrCacheUnsupported is returned by operations only a Redis cache supports, e.g.
+	// CacheInfo on a MemoryCache (see Cache)
+	ErrCacheUnsupported = errors.New("not supported by the cache backend")
+)
+
+// IsQueryTimeout checks if an error is ErrQueryTimeout
+func IsQueryTimeout(err error) bool {
+	return errors.Is(err, ErrQueryTimeout)
+}
+
+// IsCanceled checks if an error is ErrCanceled
+func IsCanceled(err error) bool {
+	return errors.Is(err, ErrCanceled)
+}
+
+// IsCacheOnlyMiss checks if an error is ErrCacheOnlyMiss
+func IsCacheOnlyMiss(err error) bool {
+	return errors.Is(err, ErrCacheOnlyMiss)
+}
+
+// IsCacheUnsupported checks if an error is ErrCacheUnsupported
+func IsCacheUnsupported(err error) bool {
+	return errors.Is(err, ErrCacheUnsupported)
+}
+
+// IsReadOnlyTransaction checks if an error is ErrReadOnlyTransaction
+func IsReadOnlyTransaction(err error) bool {
+	return errors.Is(err, ErrReadOnlyTransaction)
+}
+
+// IsPartialColumns checks if an error is ErrPartialColumns
+func IsPartialColumns(err error) bool {
+	return errors.Is(err, ErrPartialColumns)
+}
+
+// IsPrimaryKeyNotPopulated checks if an error is ErrPrimaryKeyNotPopulated
+func IsPrimaryKeyNotPopulated(err error) bool {
+	return errors.Is(err, ErrPrimaryKeyNotPopulated)
+}
+
+// IsInvalidEntity checks if an error is ErrInvalidEntity
+func IsInvalidEntity(err error) bool {
+	return errors.Is(err, ErrInvalidEntity)
+}
+
+// IsResultTruncated checks if an error is ErrResultTruncated
+func IsResultTruncated(err error) bool {
+	return errors.Is(err, ErrResultTruncated)
+}
+
+// IsNotFound checks if an error reports a missing record: ErrNotFound, or
+// gorm.ErrRecordNotFound from queries run on the underlying *gorm.DB, so API layers
+// can map both to 404 with one check
+func IsNotFound(err error) bool {
+	return errors.Is(err, ErrNotFound) || errors.Is(err, gorm.ErrRecordNotFound)
+}
+
+// classifyQueryError wraps a failed query's error with ErrQueryTimeout or ErrCanceled
+// when the context caused the failure; driver errors are returned unchanged.
+// The driver may report an aborted query as e.g. "invalid connection", so the
+// context is checked as well.
+func classifyQueryError(ctx context.Context, err error) error {
+	if err == nil || errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return fmt.Errorf("%w: %w", ErrQueryTimeout, err)
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return fmt.Errorf("%w: %w: %w", ErrQueryTimeout, context.DeadlineExceeded, err)
+	case errors.Is(err, context.Canceled):
+		return fmt.Errorf("%w: %w", ErrCanceled, err)
+	case errors.Is(ctx.Err(), context.Canceled):
+		return fmt.Errorf("%w: %w: %w", ErrCanceled, context.Canceled, err)
+	}
+	return err
+}
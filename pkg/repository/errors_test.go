@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/ammar0144/sql4go/pkg/redis"
+
+	"gorm.io/gorm"
+)
+
+func TestIsNotFound(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "sentinel", err: ErrNotFound, want: true},
+		{name: "wrapped sentinel", err: fmt.Errorf("loading user 7: %w", ErrNotFound), want: true},
+		{name: "gorm record not found", err: gorm.ErrRecordNotFound, want: true},
+		{name: "nil", err: nil},
+		{name: "unrelated", err: errors.New("connection refused")},
+		{name: "cache miss", err: redis.ErrKeyNotFound},
+		{name: "other sentinel", err: ErrResultTruncated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsNotFound(tt.err); got != tt.want {
+				t.Errorf("IsNotFound(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,216 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// defaultChangeEventBuffer is the change event queue size unless set by WithChangeEventBuffer
+const defaultChangeEventBuffer = 1024
+
+// ChangeOperation is the kind of write that produced a ChangeEvent
+type ChangeOperation string
+
+// Change operations
+const (
+	ChangeCreate ChangeOperation = "create"
+	ChangeUpdate ChangeOperation = "update"
+	ChangeDelete ChangeOperation = "delete"
+)
+
+// ChangeEvent describes a committed write to one entity
+type ChangeEvent struct {
+	Operation  ChangeOperation
+	Database   string
+	Table      string
+	PrimaryKey interface{}
+	Entity     interface{} // Snapshot of the written entity (T); for deletes, the row as last read
+	Time       time.Time   // When the write was applied (or committed)
+}
+
+// changeNotifier delivers change events of one repository to its subscribers
+type changeNotifier struct {
+	bufferSize int
+
+	mu          sync.RWMutex // Guards subscribers, queue and closed
+	subscribers []func(ChangeEvent)
+	queue       chan ChangeEvent // Created with the first subscriber
+	closed      bool
+	stopped     chan struct{}
+
+	dropped atomic.Uint64
+}
+
+// newChangeNotifier creates a notifier; its worker starts with the first subscriber
+func newChangeNotifier(bufferSize int) *changeNotifier {
+	if bufferSize < 1 {
+		bufferSize = defaultChangeEventBuffer
+	}
+	return &changeNotifier{bufferSize: bufferSize}
+}
+
+// subscribe adds a handler, starting the delivery worker on first use
+func (n *changeNotifier) subscribe(handler func(ChangeEvent)) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.subscribers = append(n.subscribers, handler)
+	if n.queue == nil && !n.closed {
+		n.queue = make(chan ChangeEvent, n.bufferSize)
+		n.stopped = make(chan struct{})
+		go n.run(n.queue)
+	}
+}
+
+// publish queues an event; dropped if the queue is full or nobody is subscribed
+func (n *changeNotifier) publish(event ChangeEvent) {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	if n.queue == nil || n.closed {
+		return
+	}
+
+	select {
+	case n.queue <- event:
+	default:
+		n.dropped.Add(1) // Queue full - drop rather than block the write
+	}
+}
+
+// run delivers queued events until the queue is closed
+func (n *changeNotifier) run(queue chan ChangeEvent) {
+	defer close(n.stopped)
+
+	for event := range queue {
+		n.mu.RLock()
+		subscribers := n.subscribers
+		n.mu.RUnlock()
+
+		for _, handler := range subscribers {
+			handler(event)
+		}
+	}
+}
+
+// close stops accepting events and waits until the queued ones are delivered
+func (n *changeNotifier) close() {
+	n.mu.Lock()
+	if n.closed {
+		n.mu.Unlock()
+		return
+	}
+	n.closed = true
+	queue, stopped := n.queue, n.stopped
+	n.mu.Unlock()
+
+	if queue != nil {
+		close(queue)
+		<-stopped
+	}
+}
+
+// Subscribe registers a handler for change events of this repository
+// Handlers run on a single background goroutine and must not call Close.
+//
+// Handlers are called after each successful write:
+//   - Create, Update and Delete emit one event; CreateBatch and UpdateBatch emit one
+//     event per entity
+//   - In a transaction, events are emitted after the outermost commit and dropped on rollback
+//   - Under write_behind, events are emitted once the queued write reaches the database
+//   - With WithOutbox, events are emitted by the outbox relay, at least once
+//
+// Delivery is asynchronous: events are buffered in a bounded queue (see
+// WithChangeEventBuffer) and handed to the subscribers, in order, by one goroutine.
+// DROP POLICY: when the queue is full the new event is dropped, never blocking the
+// write; drops are counted by DroppedChangeEvents. Slow subscribers should hand events
+// off (e.g. to their own queue) rather than process them inline.
+func (r *GenericRepository[T]) Subscribe(handler func(ChangeEvent)) {
+	if handler == nil {
+		return
+	}
+	r.events.subscribe(handler)
+}
+
+// DroppedChangeEvents returns the number of change events dropped because the queue was full
+func (r *GenericRepository[T]) DroppedChangeEvents() uint64 {
+	return r.events.dropped.Load()
+}
+
+// afterWrite is the post-write notification point shared by all write methods
+// It invalidates the entity's caches (write-through for creates and updates) and emits
+// a change event. Bound to a transaction, both are deferred until commit.
+// Returns whether caches were invalidated now.
+func (r *GenericRepository[T]) afterWrite(ctx context.Context, operation ChangeOperation, entity T) bool {
+	return r.notifyWrite(ctx, operation, entity, true)
+}
+
+// notifyWrite is afterWrite with optional write-through: writes whose stored row may
+// differ from entity (a skipped duplicate, an upsert) only invalidate
+func (r *GenericRepository[T]) notifyWrite(ctx context.Context, operation ChangeOperation, entity T, writeThrough bool) bool {
+	if r.tx != nil {
+		r.tx.record(operation, entity, writeThrough)
+		return false
+	}
+	r.recordWrite(ctx, entity)
+
+	cacheInvalidated := false
+	if r.redis != nil && r.redis.Config().Enabled {
+		if err := r.invalidateEntityCaches(ctx, operation, entity); err != nil {
+			r.retryInvalidation(operation, &entity, err) // Incomplete - see invalidationContext
+		} else {
+			cacheInvalidated = true
+		}
+		if operation != ChangeDelete && writeThrough {
+			r.writeThrough(ctx, entity)
+		}
+	}
+
+	// With the outbox, the relay emits the event once the row is relayed
+	if r.outbox == nil {
+		r.emitChange(operation, entity)
+	}
+	return cacheInvalidated
+}
+
+// notifyWrites is notifyWrite for the writes of a batch or a committed transaction
+// Every write is invalidated before any is written through: each invalidation wipes the
+// table's keys, which would otherwise delete the entities written through before it.
+// Only the last write of each primary key is written through, unless it is a delete.
+func (r *GenericRepository[T]) notifyWrites(ctx context.Context, writes []txWrite[T]) {
+	if r.tx != nil {
+		for _, write := range writes {
+			r.tx.record(write.operation, write.entity, write.writeThrough)
+		}
+		return
+	}
+
+	last := make(map[string]int, len(writes))
+	for i, write := range writes {
+		r.notifyWrite(ctx, write.operation, write.entity, false)
+		last[fmt.Sprintf("%v", r.primaryKeyValue(write.entity))] = i
+	}
+	if r.redis == nil || !r.redis.Config().Enabled {
+		return
+	}
+	for i, write := range writes {
+		if write.writeThrough && write.operation != ChangeDelete && last[fmt.Sprintf("%v", r.primaryKeyValue(write.entity))] == i {
+			r.writeThrough(ctx, write.entity)
+		}
+	}
+}
+
+// emitChange publishes a change event for a written entity
+func (r *GenericRepository[T]) emitChange(operation ChangeOperation, entity T) {
+	r.events.publish(ChangeEvent{
+		Operation:  operation,
+		Database:   r.dbName,
+		Table:      r.tableName,
+		PrimaryKey: r.primaryKeyValue(entity),
+		Entity:     entity,
+		Time:       time.Now(),
+	})
+}
@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ammar0144/sql4go/pkg/redis"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestWriteThroughUpdateServesFindByIDFromCache(t *testing.T) {
+	env := newTestEnv(t, func(c *redis.Config) { c.Strategy = redis.CacheStrategyWriteThrough })
+	repo := newTestRepository[testUser](env)
+	ctx := context.Background()
+
+	user := testUser{ID: 1, Name: "ann", Email: "a@x"}
+	env.expectUniqueValues(user)
+	env.mock.ExpectExec("UPDATE `users` SET").WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if _, err := repo.Update(ctx, &user); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+
+	// No query is expected: the updated row was written through
+	found, hit, _, err := repo.FindByID(ctx, 1)
+	if err != nil || !hit || found == nil || found.Name != "ann" {
+		t.Fatalf("FindByID after Update = (%+v, hit %v, %v), want the written row from the cache", found, hit, err)
+	}
+	env.verify()
+}
@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ammar0144/sql4go/pkg/redis"
+	"gorm.io/gorm"
+)
+
+// Exists checks if a record exists by ID
+//
+// Exists answers from its own entry instead of the FindByID entry: "<ns>:exists:<id>"
+// holds true (one byte in MessagePack), so checking an entity with a large JSON column
+// neither transfers nor decodes it. On a miss the database is asked SELECT 1 ... LIMIT 1
+// rather than for the whole row, behind the regeneration lock when enabled.
+//   - caching follows find_by_id (OperationCache, OperationTTLs); a missing row is cached
+//     as the not-found marker unless DisableNullCaching is set
+//   - writes delete the entry together with the FindByID entry
+//   - with write-behind, Exists reads the FindByID entry, the only place queued creates
+//     are visible before they are flushed
+//
+// ExistsMany still reads FindByID entries, as it answers many ids with one MGET.
+func (r *GenericRepository[T]) Exists(ctx context.Context, id interface{}) (bool, bool, bool, error) {
+	if uncached := r.cacheBypass(ctx, "find_by_id"); uncached != nil {
+		return uncached.Exists(ctx, id)
+	}
+	if uncached := r.writtenBypass(ctx, id); uncached != nil {
+		return uncached.Exists(ctx, id)
+	}
+
+	// Write-behind: queued creates are only visible in the FindByID cache
+	if r.writeBehind != nil {
+		entity, cacheHit, cacheStored, err := r.findByID(ctx, id)
+		if err != nil {
+			return false, false, false, err
+		}
+		return entity != nil, cacheHit, cacheStored, nil
+	}
+
+	// Input validation
+	if id == nil {
+		return false, false, false, fmt.Errorf("id cannot be nil")
+	}
+
+	// Apply query timeout
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	// Check if context is already cancelled
+	if err := ctx.Err(); err != nil {
+		return false, false, false, fmt.Errorf("context cancelled before operation: %w", err)
+	}
+
+	cacheKey := r.generateCacheKey("exists", fmt.Sprintf("%v", id))
+
+	// Try cache first
+	if r.redis != nil {
+		load := func(db *GenericRepository[T]) (bool, error) {
+			exists, _, _, err := db.Exists(ctx, id)
+			return exists, err
+		}
+		if exists, err := r.getCachedExists(ctx, cacheKey); err == nil {
+			return serveCacheHit(ctx, r, "exists", cacheKey, exists, load), true, false, nil // Cache hit
+		}
+		r.recordCacheLookups(0, 1)
+	}
+
+	// Cache miss - let a single process regenerate the entry if configured
+	var cached bool
+	release, filled := r.awaitRegeneration(ctx, cacheKey, func(ctx context.Context) bool {
+		exists, err := r.getCachedExists(ctx, cacheKey)
+		cached = exists
+		return err == nil
+	})
+	defer release()
+	if filled {
+		return cached, true, false, nil
+	}
+
+	// Query database for the primary key only
+	var exists bool
+	start := time.Now()
+	err := r.readQuery(ctx, func(db *gorm.DB) error {
+		var one int
+		result := db.Model(new(T)).Select("1").Where(r.primaryKeyCondition(id)).Limit(1).Scan(&one)
+		exists = result.RowsAffected > 0
+		return result.Error
+	})
+	r.observeQuery(ctx, "exists", start, err)
+	if err != nil {
+		return false, false, false, fmt.Errorf("database error: %w", classifyQueryError(ctx, err))
+	}
+	if !exists {
+		return false, false, r.storeNullResult(ctx, cacheKey), nil
+	}
+
+	// Cache the result
+	cacheStored := false
+	if r.redis != nil {
+		if err := r.redis.SetValueWithTTL(ctx, cacheKey, true, r.operationTTL("find_by_id")); err == nil {
+			cacheStored = true
+		}
+		// Ignore cache errors - best effort
+	}
+
+	return true, false, cacheStored, nil // From DB, cacheStored status
+}
+
+// getCachedExists reads an exists entry; a cached not-found is a hit reporting false
+func (r *GenericRepository[T]) getCachedExists(ctx context.Context, cacheKey string) (bool, error) {
+	var exists bool
+	err := r.redis.GetValue(ctx, cacheKey, &exists)
+	if redis.IsNullValue(err) {
+		return false, nil
+	}
+	return exists, err
+}
+
+// existsCacheKey returns the Exists key of an id within a table's key namespace
+func existsCacheKey(keyNamespace string, id interface{}, maxLength int) string {
+	return buildCacheKey(keyNamespace, "exists", fmt.Sprintf("%v", id), maxLength)
+}
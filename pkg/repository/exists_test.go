@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestExistsUsesLightweightEntryInvalidatedByWrites(t *testing.T) {
+	env := newTestEnv(t)
+	repo := newTestRepository[testUser](env)
+	ctx := context.Background()
+
+	// A miss asks the database for SELECT 1, not the row
+	env.mock.ExpectQuery("SELECT 1 FROM `users` WHERE `users`.`id` = \\? LIMIT \\?").
+		WillReturnRows(sqlmock.NewRows([]string{"1"}).AddRow(1))
+	if exists, hit, stored, err := repo.Exists(ctx, 1); err != nil || !exists || hit || !stored {
+		t.Fatalf("Exists on a miss = (%v, hit %v, stored %v, %v), want a stored true", exists, hit, stored, err)
+	}
+	existsKey := existsCacheKey(repo.keyNamespace, uint(1), maxKeyLength(repo.redis))
+	if !env.mr.Exists(existsKey) {
+		t.Fatalf("exists entry %s not cached; keys %v", existsKey, env.cachedKeys())
+	}
+	if exists, hit, _, err := repo.Exists(ctx, 1); err != nil || !exists || !hit {
+		t.Errorf("Exists = (%v, hit %v, %v), want a cached true", exists, hit, err)
+	}
+
+	user := testUser{ID: 1, Name: "ada", Email: "ada@example.com"}
+	env.mock.ExpectQuery("SELECT \\* FROM `users`").WillReturnRows(userRows(user))
+	env.mock.ExpectExec("DELETE FROM `users`").WillReturnResult(sqlmock.NewResult(0, 1))
+	if _, err := repo.Delete(ctx, 1); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	env.verify()
+	if env.mr.Exists(existsKey) {
+		t.Errorf("exists entry %s survived the Delete", existsKey)
+	}
+}
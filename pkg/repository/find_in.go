@@ -0,0 +1,393 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ammar0144/sql4go/pkg/redis"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// defaultInListChunkSize is the IN list size per statement when not configured
+const defaultInListChunkSize = 1000
+
+// FindWhereIn finds records whose column is in values, splitting large IN lists
+// The distinct values are sorted and queried in chunks of InListChunkSize
+// (see WithInListChunking), sequentially or with bounded concurrency, and the merged
+// result is cached under a key derived from the sorted values, so the same set of
+// values hits the cache regardless of order or duplicates.
+//
+// Ordering: rows are returned chunk by chunk in sorted-value order, each chunk in the
+// database's order; chainables such as Order and Limit apply per chunk. With
+// WithInListInputOrder, rows are re-sorted by the position of their column value in
+// values (rows sharing a value keep their relative order).
+func (r *GenericRepository[T]) FindWhereIn(ctx context.Context, column string, values []interface{}) ([]T, bool, bool, error) {
+	if uncached := r.cacheBypass(ctx, "find_where_in"); uncached != nil {
+		return uncached.FindWhereIn(ctx, column, values)
+	}
+
+	// Apply query timeout
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	// Check if context is already cancelled
+	if err := ctx.Err(); err != nil {
+		return nil, false, false, fmt.Errorf("context cancelled before operation: %w", err)
+	}
+
+	columnName, err := r.resolveColumn(column)
+	if err != nil {
+		return nil, false, false, err
+	}
+	if len(values) == 0 {
+		return []T{}, false, false, nil
+	}
+
+	// Distinct values in sorted order - deterministic chunks and cache key
+	distinct, keyText := sortedDistinctValues(values)
+	cacheKey := r.generateCacheKey("find_where_in", fmt.Sprintf("%s:%016x", columnName, r.options.KeyHasher.sum(keyText)))
+
+	// Try cache first
+	if r.redis != nil {
+		var entities []T
+		if err := r.redis.GetLargeValue(ctx, cacheKey, &entities); err == nil {
+			r.recordCacheLookups(1, 0)
+			return r.orderByInput(ctx, columnName, values, entities), true, false, nil // Cache hit
+		} else if redis.IsNullValue(err) {
+			r.recordCacheLookups(1, 0)
+			return []T{}, true, false, nil // Cached empty result
+		} else if !redis.IsKeyNotFound(err) {
+			// Unexpected cache error; continue to DB
+		}
+		r.recordCacheLookups(0, 1)
+	}
+
+	// Cache miss - query database chunk by chunk
+	entities, err := r.findInChunks(ctx, "find_where_in", columnName, distinct)
+	if err != nil {
+		return nil, false, false, err
+	}
+
+	// Cache the merged result with dependencies (empty results follow the null caching policy)
+	if len(entities) == 0 {
+		return entities, false, r.storeNullResult(ctx, cacheKey), nil
+	}
+	cacheStored := false
+	if r.redis != nil {
+		dependencies := r.extractDependenciesFromEntities(entities)
+		if err := r.redis.SetLargeValueWithDependenciesTTL(ctx, cacheKey, entities, dependencies, r.operationTTL("find_where_in")); err == nil {
+			cacheStored = true
+		}
+		// Ignore cache errors - best effort
+	}
+
+	return r.orderByInput(ctx, columnName, values, entities), false, cacheStored, nil // From DB, cacheStored status
+}
+
+// FindByIDs finds records by primary key, reading cached entities in one round trip
+// Cached entities are read from their FindByID keys in a single pipeline; the remaining
+// ids are queried with IN lists (chunked as in FindWhereIn) and cached individually.
+// Rows are returned in the order of ids; unknown and duplicate ids are skipped.
+// cacheHit is true if every row came from the cache, cacheStored if fetched rows were cached.
+func (r *GenericRepository[T]) FindByIDs(ctx context.Context, ids []interface{}) ([]T, bool, bool, error) {
+	if uncached := r.cacheBypass(ctx, "find_by_id"); uncached != nil {
+		return uncached.FindByIDs(ctx, ids)
+	}
+	if uncached := r.writtenBypass(ctx, ids...); uncached != nil {
+		return uncached.FindByIDs(ctx, ids)
+	}
+
+	// Apply query timeout
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	// Check if context is already cancelled
+	if err := ctx.Err(); err != nil {
+		return nil, false, false, fmt.Errorf("context cancelled before operation: %w", err)
+	}
+	if len(ids) == 0 {
+		return []T{}, false, false, nil
+	}
+
+	distinct, _ := sortedDistinctValues(ids)
+	byID := make(map[string]T, len(distinct))
+	missing := distinct
+
+	// Try cache first - all FindByID keys in one round trip
+	if r.redis != nil {
+		keys := make([]string, len(distinct))
+		for i, id := range distinct {
+			keys[i] = r.generateCacheKey("find_by_id", fmt.Sprintf("%v", id))
+		}
+		if cached, err := r.redis.GetManyValues(ctx, keys, func() interface{} { return new(T) }); err == nil {
+			missing = nil
+			for i, id := range distinct {
+				if value, ok := cached[keys[i]]; ok {
+					byID[fmt.Sprintf("%v", id)] = *value.(*T)
+				} else {
+					missing = append(missing, id)
+				}
+			}
+		}
+		// On a cache error all ids are queried from the database
+		r.recordCacheLookups(len(distinct)-len(missing), len(missing))
+	}
+
+	// Cache misses - query database and cache each row under its FindByID key
+	cacheHit := len(missing) == 0
+	cacheStored := false
+	if len(missing) > 0 {
+		entities, err := r.findInChunks(ctx, "find_by_ids", r.primaryKey, missing)
+		if err != nil {
+			return nil, false, false, err
+		}
+		for _, entity := range entities {
+			id := fmt.Sprintf("%v", r.primaryKeyValue(entity))
+			byID[id] = entity
+			if r.redis != nil {
+				if err := r.redis.SetValueWithTTL(ctx, r.generateCacheKey("find_by_id", id), entity, r.operationTTL("find_by_id")); err == nil {
+					cacheStored = true
+				}
+				// Ignore cache errors - best effort
+			}
+		}
+	}
+
+	// Input order, skipping unknown and duplicate ids
+	entities := make([]T, 0, len(byID))
+	for _, id := range ids {
+		key := fmt.Sprintf("%v", id)
+		if entity, ok := byID[key]; ok {
+			entities = append(entities, entity)
+			delete(byID, key)
+		}
+	}
+	return entities, cacheHit, cacheStored, nil
+}
+
+// ExistsMany reports which of ids exist, without hydrating entities
+// Cached FindByID entries answer in one round trip (an entity means it exists, a null
+// marker that it does not); the remaining ids are checked with chunked
+// SELECT pk ... WHERE pk IN (...) queries. Ids found missing are cached as null markers
+// under their FindByID keys, following the null caching policy (DisableNullCaching).
+// cacheHit is true if every id was answered by the cache, cacheStored if a null marker was cached.
+// Ids are the keys of the returned map, so ids that cannot be map keys (e.g. []byte)
+// are rejected with an error; convert them first (string(b)).
+func (r *GenericRepository[T]) ExistsMany(ctx context.Context, ids []interface{}) (map[interface{}]bool, bool, bool, error) {
+	for _, id := range ids {
+		if id != nil && !reflect.ValueOf(id).Comparable() {
+			return nil, false, false, fmt.Errorf("id of type %T cannot be a map key", id)
+		}
+	}
+
+	if uncached := r.cacheBypass(ctx, "find_by_id"); uncached != nil {
+		return uncached.ExistsMany(ctx, ids)
+	}
+	if uncached := r.writtenBypass(ctx, ids...); uncached != nil {
+		return uncached.ExistsMany(ctx, ids)
+	}
+
+	// Apply query timeout
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	// Check if context is already cancelled
+	if err := ctx.Err(); err != nil {
+		return nil, false, false, fmt.Errorf("context cancelled before operation: %w", err)
+	}
+
+	distinct, _ := sortedDistinctValues(ids)
+	exists := make(map[string]bool, len(distinct))
+	missing := distinct
+
+	// Try cache first - all FindByID keys in one round trip
+	if r.redis != nil && len(distinct) > 0 {
+		keys := make([]string, len(distinct))
+		for i, id := range distinct {
+			keys[i] = r.generateCacheKey("find_by_id", fmt.Sprintf("%v", id))
+		}
+		if cached, err := r.redis.GetMany(ctx, keys); err == nil {
+			missing = nil
+			for i, id := range distinct {
+				if data, ok := cached[keys[i]]; ok {
+					exists[fmt.Sprintf("%v", id)] = !redis.IsNullMarker(data)
+				} else {
+					missing = append(missing, id)
+				}
+			}
+		}
+		// On a cache error all ids are checked in the database
+		r.recordCacheLookups(len(distinct)-len(missing), len(missing))
+	}
+
+	// Cache misses - select the primary keys that exist
+	cacheHit := len(missing) == 0
+	cacheStored := false
+	if len(missing) > 0 {
+		chunkSize := r.options.InListChunkSize
+		if chunkSize <= 0 {
+			chunkSize = defaultInListChunkSize
+		}
+		for start := 0; start < len(missing); start += chunkSize {
+			chunk := missing[start:min(start+chunkSize, len(missing))]
+			var found []string
+			queryStart := time.Now()
+			err := r.readQuery(ctx, func(db *gorm.DB) error {
+				return db.Model(new(T)).Where(clause.IN{Column: clause.Column{Name: r.primaryKey}, Values: chunk}).Pluck(r.primaryKey, &found).Error
+			})
+			r.observeQuery(ctx, "exists_many", queryStart, err)
+			if err != nil {
+				return nil, false, false, fmt.Errorf("database error: %w", classifyQueryError(ctx, err))
+			}
+			for _, id := range found {
+				exists[id] = true
+			}
+		}
+
+		for _, id := range missing {
+			key := fmt.Sprintf("%v", id)
+			if !exists[key] {
+				if r.storeNullResult(ctx, r.generateCacheKey("find_by_id", key)) {
+					cacheStored = true
+				}
+			}
+		}
+	}
+
+	result := make(map[interface{}]bool, len(ids))
+	for _, id := range ids {
+		result[id] = exists[fmt.Sprintf("%v", id)]
+	}
+	return result, cacheHit, cacheStored, nil
+}
+
+// findInChunks runs one IN query per chunk and merges the results in chunk order
+// Chunks run sequentially inside transactions, which use a single connection. If the
+// context is cancelled before every chunk ran, the context error is returned rather
+// than the rows of the chunks that did.
+func (r *GenericRepository[T]) findInChunks(ctx context.Context, operation, columnName string, values []interface{}) ([]T, error) {
+	chunkSize := r.options.InListChunkSize
+	if chunkSize <= 0 {
+		chunkSize = defaultInListChunkSize
+	}
+	concurrency := r.options.InListConcurrency
+	if concurrency < 1 || r.tx != nil {
+		concurrency = 1
+	}
+
+	var chunks [][]interface{}
+	for start := 0; start < len(values); start += chunkSize {
+		chunks = append(chunks, values[start:min(start+chunkSize, len(values))])
+	}
+
+	// Abort the remaining chunks on the first failure
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make([][]T, len(chunks))
+	errs := make([]error, len(chunks))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	started := 0
+	for i, chunk := range chunks {
+		sem <- struct{}{}
+		if ctx.Err() != nil {
+			<-sem
+			break
+		}
+		started++
+
+		wg.Add(1)
+		go func(i int, chunk []interface{}) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			start := time.Now()
+			err := r.readQuery(ctx, func(db *gorm.DB) error {
+				return db.Where(clause.IN{Column: clause.Column{Name: columnName}, Values: chunk}).Find(&results[i]).Error
+			})
+			r.observeQuery(ctx, operation, start, err)
+			if err != nil {
+				errs[i] = err
+				cancel()
+			}
+		}(i, chunk)
+	}
+	wg.Wait()
+
+	var entities []T
+	for i := range chunks[:started] {
+		if errs[i] != nil {
+			return nil, fmt.Errorf("database error in chunk %d of %d: %w", i+1, len(chunks), classifyQueryError(ctx, errs[i]))
+		}
+		entities = append(entities, results[i]...)
+	}
+	if started < len(chunks) {
+		// Cancelled between chunks - never return (or cache) a partial result
+		return nil, fmt.Errorf("cancelled after %d of %d chunks: %w", started, len(chunks), ctx.Err())
+	}
+	if entities == nil {
+		entities = []T{}
+	}
+	return entities, nil
+}
+
+// orderByInput re-sorts rows by the position of their column value in values
+// when WithInListInputOrder is set; otherwise rows are returned unchanged
+func (r *GenericRepository[T]) orderByInput(ctx context.Context, columnName string, values []interface{}, entities []T) []T {
+	if !r.options.InListInputOrder || r.entitySchema == nil {
+		return entities
+	}
+	field := r.entitySchema.LookUpField(columnName)
+	if field == nil {
+		return entities
+	}
+
+	positions := make(map[string]int, len(values))
+	for i, value := range values {
+		key := fmt.Sprintf("%v", value)
+		if _, seen := positions[key]; !seen {
+			positions[key] = i
+		}
+	}
+
+	position := func(entity *T) int {
+		value, _ := field.ValueOf(ctx, reflect.Indirect(reflect.ValueOf(entity)))
+		if pos, ok := positions[fmt.Sprintf("%v", value)]; ok {
+			return pos
+		}
+		return len(values) // Unknown values last
+	}
+
+	sort.SliceStable(entities, func(i, j int) bool {
+		return position(&entities[i]) < position(&entities[j])
+	})
+	return entities
+}
+
+// sortedDistinctValues returns the distinct values sorted by their text form,
+// along with that sorted text joined for cache key hashing
+func sortedDistinctValues(values []interface{}) ([]interface{}, string) {
+	byText := make(map[string]interface{}, len(values))
+	for _, value := range values {
+		byText[fmt.Sprintf("%v", value)] = value
+	}
+
+	texts := make([]string, 0, len(byText))
+	for text := range byText {
+		texts = append(texts, text)
+	}
+	sort.Strings(texts)
+
+	distinct := make([]interface{}, len(texts))
+	for i, text := range texts {
+		distinct[i] = byText[text]
+	}
+	return distinct, strings.Join(texts, "\x00")
+}
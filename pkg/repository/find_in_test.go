@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func TestFindWhereInCancelledBetweenChunks(t *testing.T) {
+	env := newTestEnv(t)
+	repo := newTestRepository[testUser](env, WithInListChunking(2, 1))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The caller goes away once the first chunk has been read
+	if err := env.gorm.Callback().Query().After("gorm:query").Register("test:cancel", func(*gorm.DB) { cancel() }); err != nil {
+		t.Fatalf("register callback: %v", err)
+	}
+	env.mock.ExpectQuery("SELECT \\* FROM `users` WHERE `id` IN \\(\\?,\\?\\)").
+		WithArgs(1, 2).
+		WillReturnRows(userRows(testUser{ID: 1, Name: "ann"}))
+
+	users, _, stored, err := repo.FindWhereIn(ctx, "id", []interface{}{1, 2, 3})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("FindWhereIn = (%v, %v), want context.Canceled", users, err)
+	}
+	if users != nil || stored {
+		t.Errorf("FindWhereIn returned %v (stored %v), want no partial result", users, stored)
+	}
+	if keys := env.cachedKeys(); len(keys) != 0 {
+		t.Errorf("cache keys after a cancelled FindWhereIn = %v, want none", keys)
+	}
+	env.verify()
+}
+
+func TestExistsManyRejectsUnhashableIDs(t *testing.T) {
+	env := newTestEnv(t)
+	repo := newTestRepository[testUser](env)
+
+	result, _, _, err := repo.ExistsMany(context.Background(), []interface{}{1, []byte("2")})
+	if err == nil || result != nil {
+		t.Fatalf("ExistsMany with a []byte id = (%v, %v), want an error", result, err)
+	}
+	env.verify() // Rejected before any query
+}
@@ -3,91 +3,165 @@ package repository
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"reflect"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/ammar0144/sql4go/pkg/db"
 	"github.com/ammar0144/sql4go/pkg/redis"
 
 	"github.com/cespare/xxhash/v2"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
 )
 
 // Cache key constants for consistent key generation
 const (
-	cacheKeyPrefix     = "sql4go"
-	cacheKeySeparator  = ":"
-	cacheKeyHashLength = 12 // Balance between uniqueness and key length
+	cacheKeyPrefix       = "sql4go"
+	cacheKeySeparator    = ":"
+	cacheKeyHashLength   = 12   // Balance between uniqueness and key length
+	cacheKeyVersion      = "v2" // Key namespace version when cluster hash tags are enabled
+	cacheKeyHashedMarker = "#"  // Prefix of suffixes hashed due to MaxKeyLength
 )
 
 // GenericRepository provides comprehensive CRUD operations with intelligent caching
 // It automatically handles cache-first reads and relationship-aware invalidation
+// A repository is immutable after construction and safe for concurrent use; chained
+// and transaction repositories are copies sharing its goroutine-safe components.
 type GenericRepository[T Entity] struct {
-	db         *gorm.DB
-	dbManager  *db.Manager
-	redis      *redis.Manager
-	entityType reflect.Type
-	tableName  string
-	primaryKey string
-	dbName     string // Database name for cache key isolation
+	db             *gorm.DB
+	dbManager      *db.Manager
+	redis          *cacheBackend // nil: no caching (see NewGenericRepositoryWithCache)
+	entityType     reflect.Type
+	entitySchema   *schema.Schema // Parsed GORM schema (nil if parsing failed)
+	tableName      string
+	primaryKey     string
+	primaryField   *schema.Field   // Schema field primary key values are read from (nil: GetPrimaryKeyValue, see resolvePrimaryKey)
+	customKey      bool            // primaryKey is declared (PrimaryKeyAware) and not GORM's primary key
+	uniqueFields   []*schema.Field // Single-column unique fields, whose FindByUnique keys Update clears
+	dbName         string          // Database name for cache key isolation
+	keyNamespace   string          // Prefix shared by all cache keys of this table and schema version
+	tableNamespace string          // Prefix shared by all cache keys of this table, across schema versions
+	schemaVersion  string          // Schema version segment of cache keys (see SchemaFingerprint)
+	options        Options
+	writeBehind    *writeBehindQueue[T] // Background DB writer (nil unless Strategy is write_behind)
+	tx             *txScope[T]          // Set on repositories bound to a transaction (see WithTransaction)
+	events         *changeNotifier      // Change event subscribers (see Subscribe)
+	outbox         *outboxRelay[T]      // Transactional outbox relay (nil unless WithOutbox)
+	readOnly       bool                 // Reads run in read-only transactions, writes fail (see ReadOnlyTx)
+	partial        bool                 // Reads are restricted by Select or Omit, writes fail
+	chain          []chainClause        // Cache key material of chained clauses (see derive)
+	verifier       *cacheVerifier       // Cache consistency verification (nil unless WithCacheVerification)
+	rewarm         *rewarmScheduler[T]  // Re-warming after invalidation (nil unless WithRewarm)
+
+	invalidationRetry *invalidationRetrier // Retries incomplete invalidations (see invalidationContext)
 }
 
 // NewGenericRepository creates a new generic repository with GORM and Redis integration
-func NewGenericRepository[T Entity](dbManager *db.Manager, redisManager *redis.Manager) Repository[T] {
+// Per-repository behavior can be adjusted with options (e.g. WithSegmentedCollections)
+func NewGenericRepository[T Entity](dbManager *db.Manager, redisManager *redis.Manager, opts ...Option) Repository[T] {
+	return newGenericRepository[T](dbManager, managerCache(redisManager), opts...)
+}
+
+// newGenericRepository creates a generic repository caching through cache (nil disables caching)
+func newGenericRepository[T Entity](dbManager *db.Manager, cache Cache, opts ...Option) Repository[T] {
+	redisManager := newCacheBackend(cache)
+
 	// Obtain the reflect.Type for the generic type parameter T in a safe way
 	entityType := reflect.TypeOf((*T)(nil)).Elem()
 
-	// Create a model instance suitable for assertions and for calling Entity methods
-	var model interface{}
-	if entityType.Kind() == reflect.Ptr {
-		model = reflect.New(entityType.Elem()).Interface()
-	} else {
-		model = reflect.New(entityType).Interface()
+	// Verify the entity and its TableName()
+	tableName, err := entityTableName(entityType)
+	if err != nil {
+		panic(err.Error())
 	}
 
-	// Assert the model implements Entity
-	ent, ok := model.(Entity)
-	if !ok {
-		panic(fmt.Sprintf("entity type %v does not implement repository.Entity", entityType))
+	// Extract database name from GORM connection
+	dbName := extractDatabaseName(dbManager.DB())
+
+	// Parse the GORM schema once; used for column validation and primary key lookup
+	entitySchema, _ := parseEntitySchema(dbManager.DB(), entityType)
+
+	// Version cache keys by the entity structure unless pinned (see WithSchemaVersion)
+	options := applyOptions(opts)
+	namespace := cacheKeyNamespace(redisManager, dbName, tableName)
+	schemaVersion := options.SchemaVersion
+	if schemaVersion == "" {
+		schemaVersion = fingerprintType(entityType)
+	}
+	if redisManager != nil {
+		registerSchemaVersion(namespace, schemaVersion)
+	}
+	if redisManager != nil && redisManager.manager != nil {
+		cacheManagers.Store(redisManager.manager, struct{}{})
 	}
 
-	// Verify TableName()
-	tableName := ent.TableName()
-	if tableName == "" {
-		panic(fmt.Sprintf("entity type %v returned empty TableName(), Entity interface not properly implemented", entityType))
+	repo := &GenericRepository[T]{
+		db:             dbManager.DB(),
+		dbManager:      dbManager,
+		redis:          redisManager,
+		entityType:     entityType,
+		entitySchema:   entitySchema,
+		tableName:      tableName,
+		dbName:         dbName,
+		keyNamespace:   namespace + cacheKeySeparator + schemaVersion,
+		tableNamespace: namespace,
+		schemaVersion:  schemaVersion,
+		options:        options,
+		events:         newChangeNotifier(options.ChangeEventBuffer),
 	}
 
-	// Extract database name from GORM connection
-	dbName := extractDatabaseName(dbManager.DB())
+	repo.primaryKey, repo.primaryField = resolvePrimaryKey(entityType, entitySchema, options)
+	if pk := declaredPrimaryKeyColumn(entityType); pk != "" {
+		repo.customKey = entitySchema == nil || len(entitySchema.PrimaryFields) == 0 || entitySchema.PrimaryFields[0].DBName != pk
+	}
 
-	return &GenericRepository[T]{
-		db:         dbManager.DB(),
-		dbManager:  dbManager,
-		redis:      redisManager,
-		entityType: entityType,
-		tableName:  tableName,
-		primaryKey: func() string {
-			// Prefer extracting the DB column name for primary key via GORM schema
-			if db := dbManager.DB(); db != nil {
-				if pk := extractPrimaryKeyNameFromDB(db, entityType); pk != "" {
-					return pk
-				}
-			}
-			return extractPrimaryKeyName(entityType)
-		}(),
-		dbName: dbName,
+	repo.uniqueFields = uniqueFieldsOf(entitySchema)
+
+	if options.CacheVerification != nil {
+		repo.verifier = &cacheVerifier{config: *options.CacheVerification}
+	}
+
+	// Outbox repositories own a relay; see WithOutbox and Close
+	if options.Outbox != nil {
+		repo.outbox = newOutboxRelay(repo, options.Outbox.withDefaults())
+	}
+
+	// Re-warming repositories own a scheduler; see WithRewarm and Close
+	if options.Rewarm != nil && redisManager != nil {
+		repo.rewarm = newRewarmScheduler(repo, options.Rewarm.withDefaults())
+	}
+
+	// Cached repositories retry incomplete invalidations in the background; see Close
+	if redisManager != nil {
+		repo.invalidationRetry = newInvalidationRetrier(redisManager)
+	}
+
+	// Write-behind repositories own a background writer; see Flush and Close
+	if redisManager != nil && redisManager.Config().Strategy == redis.CacheStrategyWriteBehind {
+		wb := redisManager.Config().WriteBehind
+		repo.writeBehind = newWriteBehindQueue(repo, wb.QueueSize, wb.BatchSize, wb.FlushInterval)
 	}
+
+	return repo
 }
 
 // NewGenericRepositoryDBOnly creates a repository without Redis (database only)
 // For cases where caching is not needed
-func NewGenericRepositoryDBOnly[T Entity](manager *db.Manager) Repository[T] {
-	return NewGenericRepository[T](manager, nil)
+func NewGenericRepositoryDBOnly[T Entity](manager *db.Manager, opts ...Option) Repository[T] {
+	return NewGenericRepository[T](manager, nil, opts...)
 }
 
 // withQueryTimeout wraps a context with the configured query timeout
 func (r *GenericRepository[T]) withQueryTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if ctx.Value(rewarmContextKey{}) != nil {
+		return ctx, func() {} // Re-warming runs are bounded by RewarmConfig.Timeout instead
+	}
 	if r.dbManager != nil && r.dbManager.Config() != nil {
 		timeout := r.dbManager.Config().QueryTimeout
 		if timeout > 0 {
@@ -98,12 +172,32 @@ func (r *GenericRepository[T]) withQueryTimeout(ctx context.Context) (context.Co
 	return ctx, func() {}
 }
 
+// writeSession returns the GORM session used for repository writes
+// The repository invalidates caches itself, so statements issued through this
+// session are skipped by the invalidation callbacks (see RegisterInvalidationCallbacks)
+func (r *GenericRepository[T]) writeSession(ctx context.Context) *gorm.DB {
+	return r.db.WithContext(ctx).Set(invalidationHandledKey, true)
+}
+
 // ============================================================================
 // READ OPERATIONS - Cache-First Implementation
 // ============================================================================
 
 // FindByID finds a record by ID with cache-first strategy
+// A missing record is returned as nil, or as ErrNotFound with WithReturnNotFoundError.
 func (r *GenericRepository[T]) FindByID(ctx context.Context, id interface{}) (*T, bool, bool, error) {
+	return r.notFoundResult(r.findByID(ctx, id))
+}
+
+// findByID is FindByID returning a missing record as nil regardless of the options
+func (r *GenericRepository[T]) findByID(ctx context.Context, id interface{}) (*T, bool, bool, error) {
+	if uncached := r.cacheBypass(ctx, "find_by_id"); uncached != nil {
+		return uncached.findByID(ctx, id)
+	}
+	if uncached := r.writtenBypass(ctx, id); uncached != nil {
+		return uncached.findByID(ctx, id)
+	}
+
 	// Input validation
 	if id == nil {
 		return nil, false, false, fmt.Errorf("id cannot be nil")
@@ -123,28 +217,37 @@ func (r *GenericRepository[T]) FindByID(ctx context.Context, id interface{}) (*T
 
 	// Try cache first
 	if r.redis != nil {
+		load := func(db *GenericRepository[T]) (*T, error) {
+			entity, _, _, err := db.findByID(ctx, id)
+			return entity, err
+		}
 		var entity T
 		if err := r.redis.GetValue(ctx, cacheKey, &entity); err == nil {
-			return &entity, true, false, nil // Cache hit
+			return serveCacheHit(ctx, r, "find_by_id", cacheKey, &entity, load), true, false, nil // Cache hit
+		} else if redis.IsNullValue(err) {
+			return serveCacheHit(ctx, r, "find_by_id", cacheKey, nil, load), true, false, nil // Cached not-found
 		} else if !redis.IsKeyNotFound(err) {
 			// Unexpected cache error; continue to DB (best-effort cache)
 		}
+		r.recordCacheLookups(0, 1)
 	}
 
 	// Cache miss - query database (use primary key lookup to avoid injecting column names)
 	var entity T
-	result := r.db.WithContext(ctx).First(&entity, id)
-	if result.Error != nil {
-		if result.Error == gorm.ErrRecordNotFound {
-			return nil, false, false, nil // Not found, not an error
+	start := time.Now()
+	err := r.readQuery(ctx, func(db *gorm.DB) error { return r.findByPrimaryKey(db, &entity, id) })
+	r.observeQuery(ctx, "find_by_id", start, err)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, false, r.storeNullResult(ctx, cacheKey), nil // Not found, not an error
 		}
-		return nil, false, false, fmt.Errorf("database error: %w", result.Error)
+		return nil, false, false, fmt.Errorf("database error: %w", classifyQueryError(ctx, err))
 	}
 
 	// Cache the result
 	cacheStored := false
 	if r.redis != nil {
-		if err := r.redis.SetValue(ctx, cacheKey, entity); err == nil {
+		if err := r.redis.SetValueWithTTL(ctx, cacheKey, entity, r.operationTTL("find_by_id")); err == nil {
 			cacheStored = true
 		}
 		// Ignore cache errors - best effort
@@ -155,55 +258,187 @@ func (r *GenericRepository[T]) FindByID(ctx context.Context, id interface{}) (*T
 
 // FindAll finds all records with caching
 func (r *GenericRepository[T]) FindAll(ctx context.Context) ([]T, bool, bool, error) {
+	var entities []T
+	cacheHit, cacheStored, err := r.FindAllInto(ctx, &entities)
+	if err != nil && !errors.Is(err, ErrResultTruncated) {
+		return nil, false, false, err
+	}
+	return entities, cacheHit, cacheStored, err
+}
+
+// FindAllInto is FindAll decoding into *dest, reusing its capacity
+//
+// FindAllInto and FindWhereInto return their rows in a slice owned by the caller rather
+// than a new one. A cache hit decodes into the existing backing array when its capacity
+// suffices, so a hot endpoint reusing one buffer per worker (e.g. from a sync.Pool)
+// stops allocating a slice of the whole result on every call:
+//   - *dest is emptied first; its elements are zeroed, so fields missing from a cached
+//     encoding never keep values of the previous call
+//   - cache semantics are those of FindAll and FindWhere (keys, verification, regeneration
+//     lock, null caching); both are implemented on top of the Into variants
+//   - rows loaded from the database, segmented collections (WithSegmentedCollections) and
+//     normalized collections (WithNormalizedCollections) replace *dest with a new slice
+//
+// The rows in *dest stay valid until the caller passes it to the next call.
+func (r *GenericRepository[T]) FindAllInto(ctx context.Context, dest *[]T) (bool, bool, error) {
+	if dest == nil {
+		return false, false, fmt.Errorf("dest cannot be nil")
+	}
+	if uncached := r.cacheBypass(ctx, "find_all"); uncached != nil {
+		return uncached.FindAllInto(ctx, dest)
+	}
+	if uncached := r.orderingBypass("find_all", nil); uncached != nil {
+		return uncached.FindAllInto(ctx, dest)
+	}
+	resetInto(dest)
+
 	// Apply query timeout
 	ctx, cancel := r.withQueryTimeout(ctx)
 	defer cancel()
 
 	// Check if context is already cancelled
 	if err := ctx.Err(); err != nil {
-		return nil, false, false, fmt.Errorf("context cancelled before operation: %w", err)
+		return false, false, fmt.Errorf("context cancelled before operation: %w", err)
 	}
 
-	cacheKey := r.generateCacheKey("find_all", "")
+	cacheKey := r.findAllCacheKey()
 
 	// Try cache first
 	if r.redis != nil {
-		var entities []T
-		if err := r.redis.GetLargeValue(ctx, cacheKey, &entities); err == nil {
-			return entities, true, false, nil // Cache hit
+		load := func(db *GenericRepository[T]) ([]T, error) {
+			entities, _, _, err := db.FindAll(ctx)
+			return entities, err
+		}
+		if err := r.getCachedAllInto(ctx, dest); err == nil {
+			*dest = serveCacheHit(ctx, r, "find_all", cacheKey, *dest, load)
+			return true, false, nil // Cache hit
+		} else if redis.IsNullValue(err) {
+			*dest = serveCacheHit(ctx, r, "find_all", cacheKey, emptyInto(dest), load)
+			return true, false, nil // Cached empty result
 		} else if !redis.IsKeyNotFound(err) {
 			// Unexpected cache error; continue to DB
 		}
+		r.recordCacheLookups(0, 1)
 	}
 
-	// Cache miss - query database
-	var entities []T
-	result := r.db.WithContext(ctx).Find(&entities)
-	if result.Error != nil {
-		return nil, false, false, fmt.Errorf("database error: %w", result.Error)
+	// Cache miss - let a single process regenerate the entry if configured
+	release, filled := r.awaitRegeneration(ctx, cacheKey, func(ctx context.Context) bool {
+		return r.getCachedAllInto(ctx, dest) == nil
+	})
+	defer release()
+	if filled {
+		return true, false, nil // Populated by the lock winner
 	}
 
-	// Cache the result
+	// Query database
+	maxRows := r.maxQueryRows()
+	start := time.Now()
+	err := r.readQuery(ctx, func(db *gorm.DB) error { return capRows(r.orderedQuery(db, nil), maxRows).Find(dest).Error })
+	r.observeQuery(ctx, "find_all", start, err)
+	if err != nil {
+		resetInto(dest)
+		return false, false, fmt.Errorf("database error: %w", classifyQueryError(ctx, err))
+	}
+	if len(*dest) > maxRows && maxRows > 0 {
+		*dest = (*dest)[:maxRows]
+		return false, false, fmt.Errorf("%w: find_all matched more than %d rows", ErrResultTruncated, maxRows)
+	}
+
+	// Cache the result (empty results follow the null caching policy)
+	if len(*dest) == 0 {
+		return false, r.storeNullResult(ctx, cacheKey), nil
+	}
 	cacheStored := false
 	if r.redis != nil {
-		if err := r.redis.SetLargeValue(ctx, cacheKey, entities); err == nil {
+		if err := r.setCachedAll(ctx, *dest); err == nil {
 			cacheStored = true
 		}
 		// Ignore cache errors - best effort
 	}
 
-	return entities, false, cacheStored, nil // From DB, cacheStored status
+	return false, cacheStored, nil // From DB, cacheStored status
+}
+
+// FindPage returns rows [offset, offset+limit) of the FindAll result
+// With WithSegmentedCollections only the cache segments covering the range are read;
+// otherwise the page is cut from the cached FindAll result. On a miss the full
+// collection is loaded and cached through FindAll.
+func (r *GenericRepository[T]) FindPage(ctx context.Context, offset, limit int) ([]T, bool, bool, error) {
+	if uncached := r.cacheBypass(ctx, "find_all"); uncached != nil {
+		return uncached.FindPage(ctx, offset, limit)
+	}
+
+	if offset < 0 {
+		offset = 0 // Normalize negative values to 0
+	}
+	if limit < 0 {
+		limit = 0 // Normalize negative values to 0
+	}
+
+	// Read only the needed segments if the collection is segmented
+	if r.redis != nil && r.options.SegmentSize > 0 {
+		if entities, err := r.getSegments(ctx, "find_all", "", offset, limit); err == nil {
+			r.recordCacheLookups(1, 0)
+			return entities, true, false, nil // Cache hit
+		} else if redis.IsNullValue(err) {
+			r.recordCacheLookups(1, 0)
+			return []T{}, true, false, nil // Cached empty result
+		} else if !redis.IsKeyNotFound(err) {
+			// Unexpected cache error; continue to FindAll
+		}
+	}
+
+	entities, cacheHit, cacheStored, err := r.FindAll(ctx)
+	if err != nil {
+		return nil, false, false, err
+	}
+
+	// Cut the page from the full collection
+	if offset >= len(entities) {
+		return []T{}, cacheHit, cacheStored, nil
+	}
+	end := min(offset+limit, len(entities))
+	return entities[offset:end], cacheHit, cacheStored, nil
 }
 
 // FindWhere finds records with conditions and caching
 func (r *GenericRepository[T]) FindWhere(ctx context.Context, query interface{}, args ...interface{}) ([]T, bool, bool, error) {
+	var entities []T
+	cacheHit, cacheStored, err := r.FindWhereInto(ctx, &entities, query, args...)
+	if err != nil && !errors.Is(err, ErrResultTruncated) {
+		return nil, false, false, err
+	}
+	return entities, cacheHit, cacheStored, err
+}
+
+// FindWhereInto is FindWhere decoding into *dest, reusing its capacity (see FindAllInto)
+func (r *GenericRepository[T]) FindWhereInto(ctx context.Context, dest *[]T, query interface{}, args ...interface{}) (bool, bool, error) {
+	if dest == nil {
+		return false, false, fmt.Errorf("dest cannot be nil")
+	}
+	if _, isBuilder := query.(*db.Builder); isBuilder {
+		// Run the builder's conditions on a repository chaining its ordering and limits
+		scoped, where, whereArgs, err := r.scopeBuilder(query, args)
+		if err != nil {
+			return false, false, err
+		}
+		return scoped.FindWhereInto(ctx, dest, where, whereArgs...)
+	}
+	if uncached := r.cacheBypass(ctx, "find_where"); uncached != nil {
+		return uncached.FindWhereInto(ctx, dest, query, args...)
+	}
+	if uncached := r.orderingBypass("find_where", query); uncached != nil {
+		return uncached.FindWhereInto(ctx, dest, query, args...)
+	}
+	resetInto(dest)
+
 	// Apply query timeout
 	ctx, cancel := r.withQueryTimeout(ctx)
 	defer cancel()
 
 	// Check if context is already cancelled
 	if err := ctx.Err(); err != nil {
-		return nil, false, false, fmt.Errorf("context cancelled before operation: %w", err)
+		return false, false, fmt.Errorf("context cancelled before operation: %w", err)
 	}
 
 	// Validate query type - don't cache *gorm.DB queries as they're not deterministic
@@ -212,46 +447,217 @@ func (r *GenericRepository[T]) FindWhere(ctx context.Context, query interface{},
 		shouldCache = false
 	}
 
-	// Generate cache key from query and args
+	// Generate cache key from query and args (an ID list key if normalized, the
+	// segment index if segmented)
 	var cacheKey string
-	if shouldCache {
+	normalized := shouldCache && r.normalizedCollections()
+	segmentQuery, segmented := r.segmentedWhere(query, args)
+	segmented = segmented && shouldCache && !normalized
+	if normalized {
+		cacheKey = r.idListKey("find_where", query, args...)
+	} else if segmented {
+		cacheKey = r.segmentIndexKey("find_where", segmentQuery)
+	} else if shouldCache {
 		cacheKey = r.generateCacheKeyFromQuery("find_where", query, args...)
 	}
 
 	// Try cache first (only if cacheable)
 	if r.redis != nil && shouldCache {
-		var entities []T
-		if err := r.redis.GetLargeValue(ctx, cacheKey, &entities); err == nil {
-			return entities, true, false, nil // Cache hit
+		load := func(db *GenericRepository[T]) ([]T, error) {
+			entities, _, _, err := db.FindWhere(ctx, query, args...)
+			return entities, err
+		}
+		var err error
+		if normalized {
+			var entities []T
+			var complete, stored bool
+			entities, complete, stored, err = r.getNormalized(ctx, cacheKey)
+			if err == nil && !complete {
+				r.recordCacheLookups(0, 1)
+				*dest = entities
+				return false, stored, nil // Cached ID list, rows partly from DB
+			}
+			if err == nil {
+				*dest = entities
+			}
+		} else {
+			err = r.getCachedWhereInto(ctx, cacheKey, segmentQuery, segmented, dest)
+		}
+		if err == nil {
+			*dest = serveCacheHit(ctx, r, "find_where", cacheKey, *dest, load)
+			return true, false, nil // Cache hit
+		} else if redis.IsNullValue(err) {
+			*dest = serveCacheHit(ctx, r, "find_where", cacheKey, emptyInto(dest), load)
+			return true, false, nil // Cached empty result
 		} else if !redis.IsKeyNotFound(err) {
 			// Unexpected cache error; continue to DB
 		}
+		r.recordCacheLookups(0, 1)
 	}
 
-	// Cache miss - query database
-	var entities []T
-	result := r.db.WithContext(ctx).Where(query, args...).Find(&entities)
-	if result.Error != nil {
-		return nil, false, false, fmt.Errorf("database error: %w", result.Error)
+	// Cache miss - let a single process regenerate the entry if configured
+	if shouldCache {
+		release, filled := r.awaitRegeneration(ctx, cacheKey, func(ctx context.Context) bool {
+			if normalized {
+				entities, _, _, err := r.getNormalized(ctx, cacheKey)
+				*dest = entities
+				return err == nil
+			}
+			return r.getCachedWhereInto(ctx, cacheKey, segmentQuery, segmented, dest) == nil
+		})
+		defer release()
+		if filled {
+			return true, false, nil // Populated by the lock winner
+		}
+	}
+
+	// Query database
+	maxRows := r.maxQueryRows()
+	start := time.Now()
+	err := r.readQuery(ctx, func(db *gorm.DB) error {
+		return capRows(r.orderedQuery(db.Where(query, args...), query), maxRows).Find(dest).Error
+	})
+	r.observeQuery(ctx, "find_where", start, err)
+	if err != nil {
+		resetInto(dest)
+		return false, false, fmt.Errorf("database error: %w", classifyQueryError(ctx, err))
 	}
+	if len(*dest) > maxRows && maxRows > 0 {
+		*dest = (*dest)[:maxRows]
+		return false, false, fmt.Errorf("%w: find_where matched more than %d rows", ErrResultTruncated, maxRows)
+	}
+	entities := *dest
 
-	// Cache the result with dependencies (only if cacheable)
+	// Cache the result with dependencies (only if cacheable; empty results follow the null caching policy)
+	if shouldCache && len(entities) == 0 {
+		return false, r.storeNullResult(ctx, cacheKey), nil
+	}
 	cacheStored := false
-	if r.redis != nil && shouldCache {
+	if r.redis != nil && normalized {
+		if err := r.setNormalized(ctx, "find_where", cacheKey, entities); err == nil {
+			cacheStored = true
+		}
+		// Ignore cache errors - best effort
+	} else if r.redis != nil && segmented {
+		if err := r.setSegments(ctx, "find_where", segmentQuery, entities, r.extractDependenciesFromEntities(entities)); err == nil {
+			cacheStored = true
+		}
+		// Ignore cache errors - best effort
+	} else if r.redis != nil && shouldCache {
 		dependencies := r.extractDependenciesFromEntities(entities)
-		if data, err := r.marshalEntities(entities); err == nil {
-			// best-effort cache store; ignore cache errors here
-			if err := r.redis.SetLargeWithDependencies(ctx, cacheKey, data, dependencies); err == nil {
-				cacheStored = true
+		// Use the manager's serialization format so GetLargeValue can read it back
+		if err := r.redis.SetLargeValueWithDependenciesTTL(ctx, cacheKey, entities, dependencies, r.operationTTL("find_where")); err == nil {
+			cacheStored = true
+		}
+		// Ignore cache errors - best effort
+	}
+
+	return false, cacheStored, nil // From DB, cacheStored status
+}
+
+// getCachedWhereInto reads a cached FindWhere result into *dest, segmented or not
+func (r *GenericRepository[T]) getCachedWhereInto(ctx context.Context, cacheKey, segmentQuery string, segmented bool, dest *[]T) error {
+	if segmented {
+		entities, err := r.getSegments(ctx, "find_where", segmentQuery, 0, -1)
+		if err == nil {
+			*dest = entities
+		}
+		return err
+	}
+	return r.redis.GetLargeValue(ctx, cacheKey, dest)
+}
+
+// FindWherePage returns rows [offset, offset+limit) of the FindWhere result
+// For an ordered query with WithSegmentedCollections only the cache segments covering
+// the range are read; otherwise the page is cut from the FindWhere result. On a miss the
+// full result is loaded and cached through FindWhere.
+func (r *GenericRepository[T]) FindWherePage(ctx context.Context, offset, limit int, query interface{}, args ...interface{}) ([]T, bool, bool, error) {
+	if offset < 0 {
+		offset = 0 // Normalize negative values to 0
+	}
+	if limit < 0 {
+		limit = 0 // Normalize negative values to 0
+	}
+
+	// Read only the needed segments if the result is segmented
+	_, isBuilder := query.(*db.Builder)
+	_, isGormDB := query.(*gorm.DB)
+	if r.redis != nil && !isBuilder && !isGormDB && r.cacheBypass(ctx, "find_where") == nil && !r.normalizedCollections() {
+		if segmentQuery, segmented := r.segmentedWhere(query, args); segmented {
+			if entities, err := r.getSegments(ctx, "find_where", segmentQuery, offset, limit); err == nil {
+				r.recordCacheLookups(1, 0)
+				return entities, true, false, nil // Cache hit
+			} else if redis.IsNullValue(err) {
+				r.recordCacheLookups(1, 0)
+				return []T{}, true, false, nil // Cached empty result
 			}
 		}
 	}
 
-	return entities, false, cacheStored, nil // From DB, cacheStored status
+	entities, cacheHit, cacheStored, err := r.FindWhere(ctx, query, args...)
+	if err != nil && !errors.Is(err, ErrResultTruncated) {
+		return nil, false, false, err
+	}
+
+	// Cut the page from the full result
+	if offset >= len(entities) {
+		return []T{}, cacheHit, cacheStored, err
+	}
+	end := min(offset+limit, len(entities))
+	return entities[offset:end], cacheHit, cacheStored, err
 }
 
-// First finds the first record matching conditions
+// First finds the first record matching conditions, ordered by primary key
 func (r *GenericRepository[T]) First(ctx context.Context, query interface{}, args ...interface{}) (*T, bool, bool, error) {
+	return r.notFoundResult(r.findOne(ctx, "first", func(db *gorm.DB, dest *T) *gorm.DB { return db.First(dest) }, query, args...))
+}
+
+// Take finds one record matching conditions, in no particular order
+func (r *GenericRepository[T]) Take(ctx context.Context, query interface{}, args ...interface{}) (*T, bool, bool, error) {
+	return r.notFoundResult(r.findOne(ctx, "take", func(db *gorm.DB, dest *T) *gorm.DB { return db.Take(dest) }, query, args...))
+}
+
+// Last finds the last record matching conditions, ordered by primary key
+// A chained Order takes precedence; the primary key only breaks ties.
+func (r *GenericRepository[T]) Last(ctx context.Context, query interface{}, args ...interface{}) (*T, bool, bool, error) {
+	return r.notFoundResult(r.findOne(ctx, "last", func(db *gorm.DB, dest *T) *gorm.DB { return db.Last(dest) }, query, args...))
+}
+
+// FindByUnique finds the record whose unique column (e.g. email or slug) equals value
+// The column must be a field of the entity schema (Go field name or DB column name). The
+// entry is cached under a key including the column and, like First, depends on the found
+// entity, so updating or deleting it (including changing the unique value) invalidates it.
+// For columns declared unique in the GORM schema, Update also clears the keys of the old
+// and new value directly (see invalidateChangedUniqueKeys).
+func (r *GenericRepository[T]) FindByUnique(ctx context.Context, column string, value interface{}) (*T, bool, bool, error) {
+	columnName, err := r.resolveColumn(column)
+	if err != nil {
+		return nil, false, false, err
+	}
+	if value == nil {
+		return nil, false, false, fmt.Errorf("value cannot be nil")
+	}
+
+	query := map[string]interface{}{columnName: value}
+	return r.notFoundResult(r.findOne(ctx, "find_by_unique", func(db *gorm.DB, dest *T) *gorm.DB { return db.Take(dest) }, query))
+}
+
+// findOne runs a single-record lookup (First, Take or Last) with caching
+// The cached entry depends on the returned entity's primary key, so updating or
+// deleting that entity invalidates it. A missing record is returned as nil.
+func (r *GenericRepository[T]) findOne(ctx context.Context, operation string, find func(db *gorm.DB, dest *T) *gorm.DB, query interface{}, args ...interface{}) (*T, bool, bool, error) {
+	if _, isBuilder := query.(*db.Builder); isBuilder {
+		// Run the builder's conditions on a repository chaining its ordering and limits
+		scoped, where, whereArgs, err := r.scopeBuilder(query, args)
+		if err != nil {
+			return nil, false, false, err
+		}
+		return scoped.findOne(ctx, operation, find, where, whereArgs...)
+	}
+	if uncached := r.cacheBypass(ctx, operation); uncached != nil {
+		return uncached.findOne(ctx, operation, find, query, args...)
+	}
+
 	// Apply query timeout
 	ctx, cancel := r.withQueryTimeout(ctx)
 	defer cancel()
@@ -269,33 +675,44 @@ func (r *GenericRepository[T]) First(ctx context.Context, query interface{}, arg
 
 	var cacheKey string
 	if shouldCache {
-		cacheKey = r.generateCacheKeyFromQuery("first", query, args...)
+		cacheKey = r.generateCacheKeyFromQuery(operation, query, args...)
 	}
 
 	// Try cache first (only if cacheable)
 	if r.redis != nil && shouldCache {
+		load := func(db *GenericRepository[T]) (*T, error) {
+			entity, _, _, err := db.findOne(ctx, operation, find, query, args...)
+			return entity, err
+		}
 		var entity T
 		if err := r.redis.GetValue(ctx, cacheKey, &entity); err == nil {
-			return &entity, true, false, nil // Cache hit
+			return serveCacheHit(ctx, r, operation, cacheKey, &entity, load), true, false, nil // Cache hit
+		} else if redis.IsNullValue(err) {
+			return serveCacheHit(ctx, r, operation, cacheKey, nil, load), true, false, nil // Cached not-found
 		} else if !redis.IsKeyNotFound(err) {
 			// Unexpected cache error; continue to DB
 		}
+		r.recordCacheLookups(0, 1)
 	}
 
 	// Cache miss - query database
 	var entity T
-	result := r.db.WithContext(ctx).Where(query, args...).First(&entity)
-	if result.Error != nil {
-		if result.Error == gorm.ErrRecordNotFound {
-			return nil, false, false, nil // Not found, not an error
+	start := time.Now()
+	err := r.readQuery(ctx, func(db *gorm.DB) error { return find(db.Where(query, args...), &entity).Error })
+	r.observeQuery(ctx, operation, start, err)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			cacheStored := shouldCache && r.storeNullResult(ctx, cacheKey)
+			return nil, false, cacheStored, nil // Not found, not an error
 		}
-		return nil, false, false, fmt.Errorf("database error: %w", result.Error)
+		return nil, false, false, fmt.Errorf("database error: %w", classifyQueryError(ctx, err))
 	}
 
-	// Cache the result (only if cacheable)
+	// Cache the result (only if cacheable), registered as depending on the entity
 	cacheStored := false
 	if r.redis != nil && shouldCache {
-		if err := r.redis.SetValue(ctx, cacheKey, entity); err == nil {
+		dependencies := r.extractDependenciesFromEntities([]T{entity})
+		if err := r.redis.SetValueWithDependenciesTTL(ctx, cacheKey, entity, dependencies, r.operationTTL(operation)); err == nil {
 			cacheStored = true
 		}
 		// Ignore cache errors - best effort
@@ -306,6 +723,10 @@ func (r *GenericRepository[T]) First(ctx context.Context, query interface{}, arg
 
 // Count counts records with caching
 func (r *GenericRepository[T]) Count(ctx context.Context) (int64, bool, bool, error) {
+	if uncached := r.cacheBypass(ctx, "count"); uncached != nil {
+		return uncached.Count(ctx)
+	}
+
 	// Apply query timeout
 	ctx, cancel := r.withQueryTimeout(ctx)
 	defer cancel()
@@ -321,24 +742,30 @@ func (r *GenericRepository[T]) Count(ctx context.Context) (int64, bool, bool, er
 	if r.redis != nil {
 		var count int64
 		if err := r.redis.GetValue(ctx, cacheKey, &count); err == nil {
-			return count, true, false, nil // Cache hit
+			return serveCacheHit(ctx, r, "count", cacheKey, count, func(db *GenericRepository[T]) (int64, error) {
+				count, _, _, err := db.Count(ctx)
+				return count, err
+			}), true, false, nil // Cache hit
 		} else if !redis.IsKeyNotFound(err) {
 			// Unexpected cache error; continue to DB
 		}
+		r.recordCacheLookups(0, 1)
 	}
 
 	// Cache miss - query database
 	var count int64
 	var entity T
-	result := r.db.WithContext(ctx).Model(&entity).Count(&count)
-	if result.Error != nil {
-		return 0, false, false, fmt.Errorf("database error: %w", result.Error)
+	start := time.Now()
+	err := r.readQuery(ctx, func(db *gorm.DB) error { return db.Model(&entity).Count(&count).Error })
+	r.observeQuery(ctx, "count", start, err)
+	if err != nil {
+		return 0, false, false, fmt.Errorf("database error: %w", classifyQueryError(ctx, err))
 	}
 
 	// Cache the result
 	cacheStored := false
 	if r.redis != nil {
-		if err := r.redis.SetValue(ctx, cacheKey, count); err == nil {
+		if err := r.redis.SetValueWithTTL(ctx, cacheKey, count, r.operationTTL("count")); err == nil {
 			cacheStored = true
 		}
 		// Ignore cache errors - best effort
@@ -347,42 +774,158 @@ func (r *GenericRepository[T]) Count(ctx context.Context) (int64, bool, bool, er
 	return count, false, cacheStored, nil // From DB, cacheStored status
 }
 
-// Exists checks if a record exists by ID
-func (r *GenericRepository[T]) Exists(ctx context.Context, id interface{}) (bool, bool, bool, error) {
-	entity, cacheHit, cacheStored, err := r.FindByID(ctx, id)
+// CountDistinct counts distinct values of a column with caching
+// The column must be a field of the entity schema (Go field name or DB column name)
+func (r *GenericRepository[T]) CountDistinct(ctx context.Context, column string) (int64, bool, bool, error) {
+	if uncached := r.cacheBypass(ctx, "count_distinct"); uncached != nil {
+		return uncached.CountDistinct(ctx, column)
+	}
+
+	// Input validation
+	columnName, err := r.resolveColumn(column)
+	if err != nil {
+		return 0, false, false, err
+	}
+
+	// Apply query timeout
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	// Check if context is already cancelled
+	if err := ctx.Err(); err != nil {
+		return 0, false, false, fmt.Errorf("context cancelled before operation: %w", err)
+	}
+
+	cacheKey := r.generateCacheKey("count_distinct", columnName)
+
+	// Try cache first
+	if r.redis != nil {
+		var count int64
+		if err := r.redis.GetValue(ctx, cacheKey, &count); err == nil {
+			return serveCacheHit(ctx, r, "count_distinct", cacheKey, count, func(db *GenericRepository[T]) (int64, error) {
+				count, _, _, err := db.CountDistinct(ctx, column)
+				return count, err
+			}), true, false, nil // Cache hit
+		} else if !redis.IsKeyNotFound(err) {
+			// Unexpected cache error; continue to DB
+		}
+		r.recordCacheLookups(0, 1)
+	}
+
+	// Cache miss - query database (COUNT(DISTINCT column))
+	var count int64
+	var entity T
+	start := time.Now()
+	err = r.readQuery(ctx, func(db *gorm.DB) error { return db.Model(&entity).Distinct(columnName).Count(&count).Error })
+	r.observeQuery(ctx, "count_distinct", start, err)
 	if err != nil {
-		return false, false, false, err
+		return 0, false, false, fmt.Errorf("database error: %w", classifyQueryError(ctx, err))
+	}
+
+	// Cache the result
+	cacheStored := false
+	if r.redis != nil {
+		if err := r.redis.SetValueWithTTL(ctx, cacheKey, count, r.operationTTL("count_distinct")); err == nil {
+			cacheStored = true
+		}
+		// Ignore cache errors - best effort
+	}
+
+	return count, false, cacheStored, nil // From DB, cacheStored status
+}
+
+// notFoundResult turns the nil result of a single-record lookup into ErrNotFound when
+// WithReturnNotFoundError is set; cacheHit and cacheStored are kept, so a cached
+// not-found still reports a cache hit
+func (r *GenericRepository[T]) notFoundResult(entity *T, cacheHit, cacheStored bool, err error) (*T, bool, bool, error) {
+	if err == nil && entity == nil && r.options.ReturnNotFoundError {
+		return nil, cacheHit, cacheStored, ErrNotFound
 	}
-	return entity != nil, cacheHit, cacheStored, nil
+	return entity, cacheHit, cacheStored, err
 }
 
 // ============================================================================
 // QUERY BUILDER METHODS - Chainable GORM Operations
 // ============================================================================
 
+// Where adds a condition, ANDed with the others, applied by the terminal method
+// (FindAll, Count, First, Delete, ...); also accepts a *db.Builder, whose ordering
+// and limits are chained as well
+func (r *GenericRepository[T]) Where(ctx context.Context, query interface{}, args ...interface{}) Repository[T] {
+	return r.chainCondition("where", query, args, (*gorm.DB).Where)
+}
+
+// Or adds a condition ORed with the preceding ones
+func (r *GenericRepository[T]) Or(ctx context.Context, query interface{}, args ...interface{}) Repository[T] {
+	return r.chainCondition("or", query, args, (*gorm.DB).Or)
+}
+
+// Not adds a negated condition, ANDed with the others
+func (r *GenericRepository[T]) Not(ctx context.Context, query interface{}, args ...interface{}) Repository[T] {
+	return r.chainCondition("not", query, args, (*gorm.DB).Not)
+}
+
+// chainCondition chains a Where, Or or Not condition. The ORDER BY, LIMIT and OFFSET
+// of a *db.Builder are chained before it in Where and rejected in Or and Not, where
+// they have no meaning; builder errors are returned by the terminal method.
+func (r *GenericRepository[T]) chainCondition(kind string, query interface{}, args []interface{}, apply func(db *gorm.DB, query interface{}, args ...interface{}) *gorm.DB) Repository[T] {
+	query, args, clauses, err := splitBuilder(query, args)
+	if err == nil && kind != "where" && len(clauses) > 0 {
+		err = fmt.Errorf("query builder ORDER BY, LIMIT and OFFSET are not supported in %s conditions", strings.ToUpper(kind))
+	}
+	if err != nil {
+		return r.derive(kind, err.Error(), func(db *gorm.DB) *gorm.DB {
+			_ = db.AddError(err)
+			return db
+		})
+	}
+
+	scoped := r
+	for _, c := range clauses {
+		scoped = scoped.derive(c.kind, c.material, c.apply)
+	}
+	return scoped.derive(kind, r.conditionKeyText(query, args), func(db *gorm.DB) *gorm.DB {
+		return apply(db, query, args...)
+	})
+}
+
+// Select reads only the given columns (Go field or DB column names); the other fields
+// of returned entities are zero. Write methods fail with ErrPartialColumns.
+func (r *GenericRepository[T]) Select(ctx context.Context, columns ...string) Repository[T] {
+	return r.restrictColumns("select", columns, func(db *gorm.DB, names []string) *gorm.DB {
+		return db.Select(names)
+	})
+}
+
+// Omit reads all but the given columns; see Select
+func (r *GenericRepository[T]) Omit(ctx context.Context, columns ...string) Repository[T] {
+	return r.restrictColumns("omit", columns, func(db *gorm.DB, names []string) *gorm.DB {
+		return db.Omit(names...)
+	})
+}
+
 // Preload specifies associations to preload (returns new repository instance)
 func (r *GenericRepository[T]) Preload(ctx context.Context, associations ...string) Repository[T] {
-	newRepo := *r
-	db := newRepo.db
-	for _, association := range associations {
-		db = db.Preload(association)
-	}
-	newRepo.db = db
-	return &newRepo
+	return r.derive("preload", strings.Join(associations, ","), func(db *gorm.DB) *gorm.DB {
+		for _, association := range associations {
+			db = db.Preload(association)
+		}
+		return db
+	})
 }
 
 // Joins specifies joins to perform
 func (r *GenericRepository[T]) Joins(ctx context.Context, query string, args ...interface{}) Repository[T] {
-	newRepo := *r
-	newRepo.db = newRepo.db.Joins(query, args...)
-	return &newRepo
+	return r.derive("joins", queryKeyText(query, args), func(db *gorm.DB) *gorm.DB {
+		return db.Joins(query, args...)
+	})
 }
 
 // Order specifies ordering
 func (r *GenericRepository[T]) Order(ctx context.Context, value interface{}) Repository[T] {
-	newRepo := *r
-	newRepo.db = newRepo.db.Order(value)
-	return &newRepo
+	return r.derive("order", fmt.Sprint(value), func(db *gorm.DB) *gorm.DB {
+		return db.Order(value)
+	})
 }
 
 // Limit specifies limit
@@ -390,9 +933,9 @@ func (r *GenericRepository[T]) Limit(ctx context.Context, limit int) Repository[
 	if limit < 0 {
 		limit = 0 // Normalize negative values to 0
 	}
-	newRepo := *r
-	newRepo.db = newRepo.db.Limit(limit)
-	return &newRepo
+	return r.derive("limit", strconv.Itoa(limit), func(db *gorm.DB) *gorm.DB {
+		return db.Limit(limit)
+	})
 }
 
 // Offset specifies offset
@@ -400,9 +943,9 @@ func (r *GenericRepository[T]) Offset(ctx context.Context, offset int) Repositor
 	if offset < 0 {
 		offset = 0 // Normalize negative values to 0
 	}
-	newRepo := *r
-	newRepo.db = newRepo.db.Offset(offset)
-	return &newRepo
+	return r.derive("offset", strconv.Itoa(offset), func(db *gorm.DB) *gorm.DB {
+		return db.Offset(offset)
+	})
 }
 
 // ============================================================================
@@ -411,63 +954,143 @@ func (r *GenericRepository[T]) Offset(ctx context.Context, offset int) Repositor
 
 // Create creates a new record with automatic cache invalidation
 func (r *GenericRepository[T]) Create(ctx context.Context, entity *T) (bool, error) {
+	result, err := r.CreateWithResult(ctx, entity)
+	return result.CacheInvalidated, err
+}
+
+// CreateWithResult creates a new record like Create and also reports the affected rows
+// and the resolved primary key (including auto-increment and generated IDs)
+func (r *GenericRepository[T]) CreateWithResult(ctx context.Context, entity *T) (CreateResult, error) {
+	if err := r.checkWritable(); err != nil {
+		return CreateResult{}, err
+	}
+
+	// Outbox: write and record the outbox row in one transaction
+	var result CreateResult
+	if handled, err := r.writeViaOutbox(ctx, func(tx Repository[T]) (err error) {
+		result, err = tx.CreateWithResult(ctx, entity)
+		return err
+	}); handled {
+		result.CacheInvalidated = err == nil && r.redis != nil
+		return result, err
+	}
+
 	// Input validation
 	if entity == nil {
-		return false, fmt.Errorf("entity cannot be nil")
+		return CreateResult{}, fmt.Errorf("entity cannot be nil")
 	}
 
-	// Apply query timeout
-	ctx, cancel := r.withQueryTimeout(ctx)
-	defer cancel()
+	// Assign a generated primary key if enabled (see WithGeneratedIDs)
+	if err := r.assignGeneratedID(ctx, entity); err != nil {
+		return CreateResult{}, err
+	}
 
-	// Execute database operation
-	if err := r.db.WithContext(ctx).Create(entity).Error; err != nil {
-		return false, fmt.Errorf("database error: %w", err)
+	// Write-behind: cache now, write to the database in the background
+	if r.queueWrite(ctx, *entity, true) {
+		return CreateResult{PrimaryKey: r.primaryKeyValue(*entity), CacheInvalidated: true, Queued: true}, nil
 	}
 
-	// Invalidate related caches
-	cacheInvalidated := false
-	if r.redis != nil {
-		r.invalidateEntityCaches(ctx, *entity)
-		cacheInvalidated = true // Best effort - assume success
+	// Written synchronously - not before the writes already queued
+	if err := r.awaitQueuedWrites(ctx); err != nil {
+		return CreateResult{}, fmt.Errorf("write-behind flush error: %w", err)
 	}
 
-	return cacheInvalidated, nil
+	// Apply query timeout
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	// Execute database operation
+	start := time.Now()
+	tx := r.writeSession(ctx).Create(entity)
+	r.observeQuery(ctx, "create", start, tx.Error)
+	if tx.Error != nil {
+		return CreateResult{}, fmt.Errorf("database error: %w", classifyQueryError(ctx, tx.Error))
+	}
+
+	// Pick up the values assigned by the database (see WithReloadAfterCreate)
+	r.reloadCreated(ctx, entity)
+
+	// Invalidate related caches (write-through also caches the new value) and notify
+	return CreateResult{
+		RowsAffected:     tx.RowsAffected,
+		PrimaryKey:       r.primaryKeyValue(*entity),
+		CacheInvalidated: r.afterWrite(ctx, ChangeCreate, *entity),
+	}, nil
 }
 
 // Update updates a record with relationship-aware cache invalidation
 func (r *GenericRepository[T]) Update(ctx context.Context, entity *T) (bool, error) {
+	if err := r.checkWritable(); err != nil {
+		return false, err
+	}
+
+	// Outbox: write and record the outbox row in one transaction
+	if handled, err := r.writeViaOutbox(ctx, func(tx Repository[T]) error {
+		_, err := tx.Update(ctx, entity)
+		return err
+	}); handled {
+		return err == nil && r.redis != nil, err
+	}
+
 	// Input validation
 	if entity == nil {
 		return false, fmt.Errorf("entity cannot be nil")
 	}
 
+	// Write-behind: cache now, write to the database in the background
+	if r.queueWrite(ctx, *entity, false) {
+		return true, nil
+	}
+
+	// Written synchronously - not overtaken by an older queued write of the row
+	if err := r.awaitQueuedWrites(ctx); err != nil {
+		return false, fmt.Errorf("write-behind flush error: %w", err)
+	}
+
 	// Apply query timeout
 	ctx, cancel := r.withQueryTimeout(ctx)
 	defer cancel()
 
-	// Execute database operation
-	if err := r.db.WithContext(ctx).Save(entity).Error; err != nil {
-		return false, fmt.Errorf("database error: %w", err)
-	}
+	// Load the unique values being replaced, whose FindByUnique keys go stale
+	previous := r.loadUniqueValues(ctx, *entity)
 
-	// Invalidate related caches
-	cacheInvalidated := false
-	if r.redis != nil {
-		r.invalidateEntityCaches(ctx, *entity)
-		cacheInvalidated = true // Best effort - assume success
+	// Execute database operation
+	start := time.Now()
+	err := r.writeSession(ctx).Save(entity).Error
+	r.observeQuery(ctx, "update", start, err)
+	if err != nil {
+		return false, fmt.Errorf("database error: %w", classifyQueryError(ctx, err))
 	}
 
-	return cacheInvalidated, nil
+	// Invalidate related caches (write-through also caches the new value) and notify
+	r.invalidateChangedUniqueKeys(ctx, previous, *entity)
+	return r.afterWrite(ctx, ChangeUpdate, *entity), nil
 }
 
 // Delete deletes a record by ID with cache invalidation
 func (r *GenericRepository[T]) Delete(ctx context.Context, id interface{}) (bool, error) {
-	// Input validation
-	if id == nil {
+	if err := r.checkWritable(); err != nil {
+		return false, err
+	}
+
+	// Outbox: write and record the outbox row in one transaction
+	if handled, err := r.writeViaOutbox(ctx, func(tx Repository[T]) error {
+		_, err := tx.Delete(ctx, id)
+		return err
+	}); handled {
+		return err == nil && r.redis != nil, err
+	}
+
+	// Input validation
+	if id == nil {
 		return false, fmt.Errorf("id cannot be nil")
 	}
 
+	// Write-behind: don't let a queued write resurrect the deleted row
+	if err := r.awaitQueuedWrites(ctx); err != nil {
+		return false, fmt.Errorf("write-behind flush error: %w", err)
+	}
+
 	// Apply query timeout
 	ctx, cancel := r.withQueryTimeout(ctx)
 	defer cancel()
@@ -475,50 +1098,96 @@ func (r *GenericRepository[T]) Delete(ctx context.Context, id interface{}) (bool
 	// First get the entity to invalidate relationships
 	// Use GORM's safe primary key lookup instead of string formatting to prevent SQL injection
 	var entity T
-	if err := r.db.WithContext(ctx).First(&entity, id).Error; err != nil {
+	start := time.Now()
+	err := r.findByPrimaryKey(r.db.WithContext(ctx), &entity, id)
+	r.observeQuery(ctx, "delete_lookup", start, err)
+	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return false, nil // Entity doesn't exist, no error
 		}
-		return false, fmt.Errorf("database error while finding entity to delete: %w", err)
+		return false, fmt.Errorf("database error while finding entity to delete: %w", classifyQueryError(ctx, err))
 	}
 
 	// Execute database operation
-	if err := r.db.WithContext(ctx).Delete(&entity).Error; err != nil {
-		return false, fmt.Errorf("database error: %w", err)
+	start = time.Now()
+	session := r.writeSession(ctx)
+	if r.customKey {
+		session = session.Where(r.primaryKeyCondition(id))
 	}
-
-	// Invalidate related caches
-	cacheInvalidated := false
-	if r.redis != nil {
-		r.invalidateEntityCaches(ctx, entity)
-		cacheInvalidated = true // Best effort - assume success
+	err = session.Delete(&entity).Error
+	r.observeQuery(ctx, "delete", start, err)
+	if err != nil {
+		return false, fmt.Errorf("database error: %w", classifyQueryError(ctx, err))
 	}
 
-	return cacheInvalidated, nil
+	// Invalidate related caches and notify
+	return r.afterWrite(ctx, ChangeDelete, entity), nil
 }
 
 // CreateBatch creates multiple records in batch with cache invalidation
 func (r *GenericRepository[T]) CreateBatch(ctx context.Context, entities []*T) error {
+	if err := r.checkWritable(); err != nil {
+		return err
+	}
+
+	// Outbox: write and record the outbox rows in one transaction
+	if handled, err := r.writeViaOutbox(ctx, func(tx Repository[T]) error {
+		return tx.CreateBatch(ctx, entities)
+	}); handled {
+		return err
+	}
+
 	if len(entities) == 0 {
 		return nil
 	}
 
+	// Assign generated primary keys if enabled (see WithGeneratedIDs)
+	for _, entity := range entities {
+		if entity != nil {
+			if err := r.assignGeneratedID(ctx, entity); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Write-behind: keep queued writes ordered before this batch
+	if err := r.awaitQueuedWrites(ctx); err != nil {
+		return fmt.Errorf("write-behind flush error: %w", err)
+	}
+
 	// Apply query timeout
 	ctx, cancel := r.withQueryTimeout(ctx)
 	defer cancel()
 
 	// Execute batch database operation
-	if err := r.db.WithContext(ctx).Create(&entities).Error; err != nil {
-		return fmt.Errorf("batch create error: %w", err)
+	start := time.Now()
+	err := r.writeSession(ctx).Create(&entities).Error
+	r.observeQuery(ctx, "create_batch", start, err)
+	if err != nil {
+		return fmt.Errorf("batch create error: %w", classifyQueryError(ctx, err))
 	}
 
-	// Invalidate related caches for all entities
-	if r.redis != nil {
-		for _, entity := range entities {
-			if entity != nil {
-				r.invalidateEntityCaches(ctx, *entity)
-			}
+	// Invalidate related caches for all entities (write-through also caches the new values)
+	// and emit one change event per entity. Entities whose generated primary key was not
+	// back-filled are skipped: their caches and dependencies would be keyed by a zero id.
+	var unresolved []int
+	writes := make([]txWrite[T], 0, len(entities))
+	for i, entity := range entities {
+		if entity == nil {
+			continue
 		}
+		if isZeroKey(r.primaryKeyValue(*entity)) {
+			unresolved = append(unresolved, i)
+			continue
+		}
+		writes = append(writes, txWrite[T]{operation: ChangeCreate, entity: *entity, writeThrough: true})
+	}
+	r.notifyWrites(ctx, writes)
+
+	if len(unresolved) > 0 {
+		// The rows were inserted - still drop the table's query caches
+		r.invalidateTableCaches(ctx, ChangeCreate)
+		return fmt.Errorf("%w: entities at indexes %v", ErrPrimaryKeyNotPopulated, unresolved)
 	}
 
 	return nil
@@ -526,27 +1195,47 @@ func (r *GenericRepository[T]) CreateBatch(ctx context.Context, entities []*T) e
 
 // UpdateBatch updates multiple records in batch with cache invalidation
 func (r *GenericRepository[T]) UpdateBatch(ctx context.Context, entities []*T) error {
+	if err := r.checkWritable(); err != nil {
+		return err
+	}
+
+	// Outbox: write and record the outbox rows in one transaction
+	if handled, err := r.writeViaOutbox(ctx, func(tx Repository[T]) error {
+		return tx.UpdateBatch(ctx, entities)
+	}); handled {
+		return err
+	}
+
 	if len(entities) == 0 {
 		return nil
 	}
 
+	// Write-behind: keep queued writes ordered before this batch
+	if err := r.awaitQueuedWrites(ctx); err != nil {
+		return fmt.Errorf("write-behind flush error: %w", err)
+	}
+
 	// Apply query timeout
 	ctx, cancel := r.withQueryTimeout(ctx)
 	defer cancel()
 
 	// Execute batch database operation
-	if err := r.db.WithContext(ctx).Save(&entities).Error; err != nil {
-		return fmt.Errorf("batch update error: %w", err)
+	start := time.Now()
+	err := r.writeSession(ctx).Save(&entities).Error
+	r.observeQuery(ctx, "update_batch", start, err)
+	if err != nil {
+		return fmt.Errorf("batch update error: %w", classifyQueryError(ctx, err))
 	}
 
-	// Invalidate related caches for all entities
-	if r.redis != nil {
-		for _, entity := range entities {
-			if entity != nil {
-				r.invalidateEntityCaches(ctx, *entity)
-			}
+	// Invalidate related caches for all entities (write-through also caches the new values)
+	// and emit one change event per entity
+	writes := make([]txWrite[T], 0, len(entities))
+	for _, entity := range entities {
+		if entity != nil {
+			writes = append(writes, txWrite[T]{operation: ChangeUpdate, entity: *entity, writeThrough: true})
 		}
 	}
+	r.notifyWrites(ctx, writes)
 
 	return nil
 }
@@ -558,13 +1247,16 @@ func (r *GenericRepository[T]) InvalidateCache(ctx context.Context) error {
 	}
 
 	// Invalidate all caches for this table in this database
-	pattern := fmt.Sprintf("sql4go:%s:%s:*", r.dbName, r.tableName)
-	return r.redis.InvalidatePattern(ctx, pattern)
+	err := r.redis.InvalidatePattern(ctx, tableCachePattern(r.tableNamespace))
+	r.recordTableInvalidation(ctx, "invalidate_cache")
+	r.scheduleRewarm()
+	return err
 }
 
 // WarmCache preloads commonly accessed data
+// No-op under the lazy_loading strategy, where values are only cached on first read
 func (r *GenericRepository[T]) WarmCache(ctx context.Context) error {
-	if r.redis == nil {
+	if r.redis == nil || r.redis.Config().Strategy == redis.CacheStrategyLazyLoading {
 		return nil
 	}
 
@@ -575,20 +1267,441 @@ func (r *GenericRepository[T]) WarmCache(ctx context.Context) error {
 	return nil
 }
 
+// Refresh re-reads a record from the database, bypassing the cache, and overwrites its
+// FindByID entry with the fresh value; the entry of a missing record is deleted (or holds
+// the not-found marker unless DisableNullCaching is set)
+// Use it to repair the cache after out-of-band writes, e.g. by another service. The old
+// entry is deleted first, which also drops it from the L1 caches of other processes.
+// Queued write-behind writes are flushed first, so the database is current.
+// Returns nil without error if the record does not exist, like FindByID.
+func (r *GenericRepository[T]) Refresh(ctx context.Context, id interface{}) (*T, error) {
+	if uncached := r.cacheBypass(ctx, "find_by_id"); uncached != nil {
+		return uncached.Refresh(ctx, id)
+	}
+
+	// Input validation
+	if id == nil {
+		return nil, fmt.Errorf("id cannot be nil")
+	}
+
+	// Write-behind: a queued write is newer than the database row
+	if err := r.awaitQueuedWrites(ctx); err != nil {
+		return nil, fmt.Errorf("write-behind flush error: %w", err)
+	}
+
+	// Apply query timeout
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	var entity T
+	start := time.Now()
+	err := r.readQuery(ctx, func(db *gorm.DB) error { return r.findByPrimaryKey(db, &entity, id) })
+	r.observeQuery(ctx, "refresh", start, err)
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, fmt.Errorf("database error: %w", classifyQueryError(ctx, err))
+	}
+
+	if r.redis != nil {
+		// Ignore cache errors - best effort, like FindByID
+		cacheKey := r.generateCacheKey("find_by_id", fmt.Sprintf("%v", id))
+		_ = r.redis.DeleteLarge(ctx, cacheKey)
+		_ = r.redis.DeleteKeys(ctx, []string{r.generateCacheKey("exists", fmt.Sprintf("%v", id))})
+		if err == nil {
+			_ = r.redis.SetValueWithTTL(ctx, cacheKey, entity, r.operationTTL("find_by_id"))
+		} else {
+			r.storeNullResult(ctx, cacheKey)
+		}
+	}
+
+	if err != nil {
+		return nil, nil // Not found, not an error
+	}
+	return &entity, nil
+}
+
+// RefreshMany is Refresh for many records, e.g. after a bulk out-of-band update
+// The ids are read with IN lists (chunked as in FindWhereIn); the FindByID entries of
+// found records are overwritten and those of ids that no longer exist are deleted (or
+// hold the not-found marker unless DisableNullCaching is set). Duplicate ids are refreshed once.
+func (r *GenericRepository[T]) RefreshMany(ctx context.Context, ids []interface{}) error {
+	if uncached := r.cacheBypass(ctx, "find_by_id"); uncached != nil {
+		return uncached.RefreshMany(ctx, ids)
+	}
+
+	// Input validation
+	for _, id := range ids {
+		if id == nil {
+			return fmt.Errorf("ids cannot contain nil")
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	// Write-behind: a queued write is newer than the database row
+	if err := r.awaitQueuedWrites(ctx); err != nil {
+		return fmt.Errorf("write-behind flush error: %w", err)
+	}
+
+	// Apply query timeout
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	distinct, _ := sortedDistinctValues(ids)
+	entities, err := r.findInChunks(ctx, "refresh", r.primaryKey, distinct)
+	if err != nil {
+		return err
+	}
+	if r.redis == nil {
+		return nil
+	}
+
+	// Ignore cache errors - best effort, like Refresh
+	existsKeys := make([]string, len(distinct))
+	for i, id := range distinct {
+		existsKeys[i] = r.generateCacheKey("exists", fmt.Sprintf("%v", id))
+	}
+	_ = r.redis.DeleteKeys(ctx, existsKeys)
+
+	found := make(map[string]bool, len(entities))
+	for _, entity := range entities {
+		id := fmt.Sprintf("%v", r.primaryKeyValue(entity))
+		found[id] = true
+		cacheKey := r.generateCacheKey("find_by_id", id)
+		_ = r.redis.DeleteLarge(ctx, cacheKey)
+		_ = r.redis.SetValueWithTTL(ctx, cacheKey, entity, r.operationTTL("find_by_id"))
+	}
+	for _, id := range distinct {
+		if key := fmt.Sprintf("%v", id); !found[key] {
+			cacheKey := r.generateCacheKey("find_by_id", key)
+			_ = r.redis.DeleteLarge(ctx, cacheKey)
+			r.storeNullResult(ctx, cacheKey)
+		}
+	}
+	return nil
+}
+
+// CacheMetrics returns the Redis manager's metrics snapshot (per process, shared by all repositories)
+// The bool is false when caching is disabled (DB-only repository or disabled cache config)
+// or the cache is not a *redis.Manager
+func (r *GenericRepository[T]) CacheMetrics() (redis.MetricsSnapshot, bool) {
+	if r.redis == nil || r.redis.manager == nil || !r.redis.Config().Enabled {
+		return redis.MetricsSnapshot{}, false
+	}
+	return r.redis.manager.GetMetrics(), true
+}
+
 // ============================================================================
 // HELPER METHODS - Cache Key Generation and Management
 // ============================================================================
 
+// awaitRegeneration coordinates regeneration of an expensive cache entry across processes
+// With RegenerationLock enabled, the lock winner gets release (to call once the cache is
+// populated) and regenerates the entry. Losers call poll until WaitTimeout: filled is true
+// once poll reads the entry populated by the winner, otherwise they fall through to the
+// database. Lock errors fall through to the database as well (best effort).
+func (r *GenericRepository[T]) awaitRegeneration(ctx context.Context, cacheKey string, poll func(ctx context.Context) bool) (release func(), filled bool) {
+	release = func() {}
+	if r.redis == nil || r.redis.manager == nil || !r.redis.Config().RegenerationLock.Enabled || cacheModeOf(ctx) == cacheModeBypass {
+		return release, false
+	}
+	lockConfig := r.redis.Config().RegenerationLock
+
+	token, acquired, err := r.redis.manager.TryLock(ctx, cacheKey, lockConfig.LockTTL)
+	if err != nil {
+		return release, false
+	}
+	if acquired {
+		return func() {
+			// Release even if the request context ended (ignore errors - the lock expires anyway)
+			_ = r.redis.manager.Unlock(context.WithoutCancel(ctx), cacheKey, token)
+		}, false
+	}
+
+	// Another process is regenerating - poll the cache briefly
+	waitCtx, cancel := context.WithTimeout(ctx, lockConfig.WaitTimeout)
+	defer cancel()
+	ticker := time.NewTicker(lockConfig.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-waitCtx.Done():
+			return release, false
+		case <-ticker.C:
+			if poll(waitCtx) {
+				return release, true
+			}
+		}
+	}
+}
+
+// findAllCacheKey returns the key holding the FindAll result (the segment index if segmented)
+func (r *GenericRepository[T]) findAllCacheKey() string {
+	if r.options.SegmentSize > 0 {
+		return r.segmentIndexKey("find_all", "")
+	}
+	return r.generateCacheKey("find_all", "")
+}
+
+// getCachedAll reads the cached FindAll result, segmented or not
+func (r *GenericRepository[T]) getCachedAll(ctx context.Context) ([]T, error) {
+	var entities []T
+	if err := r.getCachedAllInto(ctx, &entities); err != nil {
+		return nil, err
+	}
+	return entities, nil
+}
+
+// getCachedAllInto reads the cached FindAll result into *dest, segmented or not
+func (r *GenericRepository[T]) getCachedAllInto(ctx context.Context, dest *[]T) error {
+	if r.options.SegmentSize > 0 {
+		entities, err := r.getSegments(ctx, "find_all", "", 0, -1)
+		if err == nil {
+			*dest = entities
+		}
+		return err
+	}
+	return r.redis.GetLargeValue(ctx, r.findAllCacheKey(), dest)
+}
+
+// setCachedAll stores the FindAll result, segmented or not
+func (r *GenericRepository[T]) setCachedAll(ctx context.Context, entities []T) error {
+	if r.options.SegmentSize > 0 {
+		return r.setSegments(ctx, "find_all", "", entities, nil)
+	}
+	return r.redis.SetLargeValueWithTTL(ctx, r.findAllCacheKey(), entities, r.operationTTL("find_all"))
+}
+
+// operationTTL returns the cache TTL configured for an operation
+// (see WithOperationCache and redis.Config.OperationTTLs)
+func (r *GenericRepository[T]) operationTTL(operation string) time.Duration {
+	if config, ok := r.options.OperationCache[operation]; ok && config.TTL > 0 {
+		return config.TTL
+	}
+	return r.redis.Config().TTLFor(operation)
+}
+
+// operationCached reports whether an operation uses the cache
+// (see WithOperationCache and redis.Config.DisabledOperations)
+func (r *GenericRepository[T]) operationCached(operation string) bool {
+	if r.redis == nil {
+		return false
+	}
+	if config, ok := r.options.OperationCache[operation]; ok {
+		return !config.Disabled
+	}
+	return r.redis.Config().OperationEnabled(operation)
+}
+
+// cacheBypass returns a copy of the repository without cache for a read operation whose
+// caching is disabled, or nil if the operation uses the cache (or there is none). Under
+// ContextWithCacheBypass the copy's cache only takes writes, so the read refreshes it.
+func (r *GenericRepository[T]) cacheBypass(ctx context.Context, operation string) *GenericRepository[T] {
+	if r.redis == nil {
+		return nil
+	}
+	if r.redis.writeOnly {
+		return nil
+	}
+
+	bypass := cacheModeOf(ctx) == cacheModeBypass
+	if r.operationCached(operation) && !bypass {
+		return nil
+	}
+
+	uncached := *r
+	uncached.redis = nil
+	if bypass && r.operationCached(operation) && !r.options.NoBypassPopulation {
+		uncached.redis = r.redis.withoutReads()
+	}
+	return &uncached
+}
+
+// recordCacheLookups counts the cache hits and misses of this table's logical reads
+// in the per-table cache metrics (see redis.Metrics.RecordCacheHitFor)
+func (r *GenericRepository[T]) recordCacheLookups(hits, misses int) {
+	if r.redis == nil || r.redis.manager == nil {
+		return
+	}
+	for i := 0; i < hits; i++ {
+		r.redis.manager.RecordCacheHitFor(r.tableName)
+	}
+	for i := 0; i < misses; i++ {
+		r.redis.manager.RecordCacheMissFor(r.tableName)
+	}
+}
+
+// storeNullResult caches an empty or not-found result unless DisableNullCaching is set
+// Returns whether the null marker was stored (best effort)
+func (r *GenericRepository[T]) storeNullResult(ctx context.Context, cacheKey string) bool {
+	if r.redis == nil || r.redis.Config().DisableNullCaching {
+		return false
+	}
+	return r.redis.SetNull(ctx, cacheKey) == nil
+}
+
 // generateCacheKey creates a cache key for simple operations with database isolation
+// Reads of a chained repository get a key of their own (see chainKey).
 func (r *GenericRepository[T]) generateCacheKey(operation, suffix string) string {
+	if chain := r.chainKey(); chain != "" {
+		chainSuffix := fmt.Sprintf("c%016x", r.options.KeyHasher.sum(chain))[:cacheKeyHashLength+1]
+		if suffix == "" {
+			suffix = chainSuffix
+		} else {
+			suffix += cacheKeySeparator + chainSuffix
+		}
+	}
+	return buildCacheKey(r.keyNamespace, operation, suffix, maxKeyLength(r.redis))
+}
+
+// entityCacheKey returns the FindByID key of an entity, ignoring chained clauses
+// Used by writes, which keep the unchained FindByID entry current.
+func (r *GenericRepository[T]) entityCacheKey(id interface{}) string {
+	return buildCacheKey(r.keyNamespace, "find_by_id", fmt.Sprintf("%v", id), maxKeyLength(r.redis))
+}
+
+// buildCacheKey builds a cache key for an operation within a table's key namespace
+// If the key exceeds maxLength (0 = unlimited) or the suffix is not keySafe, the suffix
+// is replaced by its xxhash, so long ids, composite keys or ids with spaces still
+// produce bounded, deterministic keys the cache accepts.
+// Shared with the GORM invalidation callbacks, which have no repository instance
+func buildCacheKey(namespace, operation, suffix string, maxLength int) string {
 	if suffix == "" {
-		return fmt.Sprintf("%s%s%s%s%s%s%s", cacheKeyPrefix, cacheKeySeparator, r.dbName, cacheKeySeparator, r.tableName, cacheKeySeparator, operation)
+		return fmt.Sprintf("%s%s%s", namespace, cacheKeySeparator, operation)
+	}
+
+	key := fmt.Sprintf("%s%s%s%s%s", namespace, cacheKeySeparator, operation, cacheKeySeparator, suffix)
+	if (maxLength > 0 && len(key) > maxLength) || !keySafe(suffix) {
+		// The marker keeps hashed suffixes apart from raw suffixes of the same text
+		hashedSuffix := fmt.Sprintf("%s%016x", cacheKeyHashedMarker, xxhash.Sum64String(suffix))
+		key = fmt.Sprintf("%s%s%s%s%s", namespace, cacheKeySeparator, operation, cacheKeySeparator, hashedSuffix)
+	}
+	return key
+}
+
+// keySafe reports whether a key suffix can be used verbatim: the cache rejects keys
+// containing spaces or control characters (e.g. string ids with spaces), so such
+// suffixes are hashed like over-long ones
+func keySafe(suffix string) bool {
+	return strings.IndexFunc(suffix, func(c rune) bool { return c <= ' ' || c == 0x7f }) < 0
+}
+
+// maxKeyLength returns the configured cache key length limit (0 = unlimited)
+func maxKeyLength(redisManager *cacheBackend) int {
+	if redisManager == nil {
+		return 0
+	}
+	return redisManager.Config().MaxKeyLength
+}
+
+// cacheKeyNamespace returns the prefix shared by all cache keys of a table
+// Default: "sql4go:app:users"
+// Cluster hash tags: "sql4go:v2:{app:users}" - all keys of the table hash to one slot
+// A configured redis namespace follows the prefix: "sql4go:{ns}:app:users"
+func cacheKeyNamespace(redisManager *cacheBackend, dbName, tableName string) string {
+	prefix := cacheKeyPrefix
+	if redisManager != nil && redisManager.Config().Namespace != "" {
+		prefix += cacheKeySeparator + redisManager.Config().Namespace
+	}
+	if redisManager != nil && redisManager.Config().Cluster.HashTags {
+		return fmt.Sprintf("%s%s%s%s{%s%s%s}", prefix, cacheKeySeparator, cacheKeyVersion, cacheKeySeparator, dbName, cacheKeySeparator, tableName)
+	}
+	return fmt.Sprintf("%s%s%s%s%s", prefix, cacheKeySeparator, dbName, cacheKeySeparator, tableName)
+}
+
+// dependencyType returns the entity type used for dependency sets
+// With cluster hash tags the type is qualified as "db:table", matching the hash tag
+// of the table's cache keys
+func dependencyType(redisManager *cacheBackend, dbName, entityType string) string {
+	if redisManager != nil && redisManager.Config().Cluster.HashTags {
+		return dbName + cacheKeySeparator + entityType
+	}
+	return entityType
+}
+
+// builderClause is an ORDER BY, LIMIT or OFFSET clause taken from a *db.Builder
+type builderClause struct {
+	chainClause
+	apply func(db *gorm.DB) *gorm.DB
+}
+
+// splitBuilder converts a *db.Builder into its parameterized WHERE conditions and its
+// ORDER BY, LIMIT and OFFSET clauses, in the form the Order, Limit and Offset chainables
+// record them, so a builder yields the same SQL, args and cache key as the equivalent
+// hand-written query and chain. Builders with other clauses (column list, DISTINCT,
+// joins, GROUP BY, HAVING, subqueries) are rejected. Any args passed alongside a
+// builder are ignored in favor of the builder's own; other queries are returned as is.
+func splitBuilder(query interface{}, args []interface{}) (interface{}, []interface{}, []builderClause, error) {
+	builder, ok := query.(*db.Builder)
+	if !ok || builder == nil {
+		return query, args, nil, nil
+	}
+	if extra := builder.ExtraClauses(); len(extra) > 0 {
+		return nil, nil, nil, fmt.Errorf("query builder clauses not supported by the repository: %s (use the chainable methods)", strings.Join(extra, ", "))
+	}
+
+	whereSQL, whereArgs := builder.BuildWhere()
+	if whereSQL == "" {
+		whereSQL = "1 = 1" // No conditions - match all rows
+	}
+
+	var clauses []builderClause
+	for _, term := range builder.OrderByTerms() {
+		clauses = append(clauses, builderClause{chainClause{kind: "order", material: term}, func(db *gorm.DB) *gorm.DB {
+			return db.Order(term)
+		}})
+	}
+	limit, offset := builder.LimitOffset()
+	if limit > 0 {
+		clauses = append(clauses, builderClause{chainClause{kind: "limit", material: strconv.Itoa(limit)}, func(db *gorm.DB) *gorm.DB {
+			return db.Limit(limit)
+		}})
+	}
+	if offset > 0 {
+		clauses = append(clauses, builderClause{chainClause{kind: "offset", material: strconv.Itoa(offset)}, func(db *gorm.DB) *gorm.DB {
+			return db.Offset(offset)
+		}})
+	}
+	return whereSQL, whereArgs, clauses, nil
+}
+
+// scopeBuilder splits a *db.Builder (see splitBuilder) and returns a copy of the
+// repository with its ORDER BY, LIMIT and OFFSET chained, along with its conditions;
+// other queries are returned as is with r
+func (r *GenericRepository[T]) scopeBuilder(query interface{}, args []interface{}) (*GenericRepository[T], interface{}, []interface{}, error) {
+	query, args, clauses, err := splitBuilder(query, args)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	scoped := r
+	for _, c := range clauses {
+		scoped = scoped.derive(c.kind, c.material, c.apply)
 	}
-	return fmt.Sprintf("%s%s%s%s%s%s%s%s%s", cacheKeyPrefix, cacheKeySeparator, r.dbName, cacheKeySeparator, r.tableName, cacheKeySeparator, operation, cacheKeySeparator, suffix)
+	return scoped, query, args, nil
 }
 
 // generateCacheKeyFromQuery creates a cache key from query and parameters with database isolation
 func (r *GenericRepository[T]) generateCacheKeyFromQuery(operation string, query interface{}, args ...interface{}) string {
+	return buildCacheKey(r.keyNamespace, operation, queryKeySuffix(query, args, r.chainKey(), r.options.KeyHasher), maxKeyLength(r.redis))
+}
+
+// queryKeySuffix returns the key suffix of a query: the truncated hash (xxhash unless a
+// hasher is set) of the query text, its parameters and the chain key (if any)
+func queryKeySuffix(query interface{}, args []interface{}, chain string, hasher KeyHasher) string {
+	combined := queryKeyText(query, args)
+	if chain != "" {
+		combined += cacheKeySeparator + chain
+	}
+
+	// Create hash for consistent, short keys (xxhash: fast non-cryptographic hash)
+	hash := hasher.sum(combined)
+	hashStr := fmt.Sprintf("%016x", hash)
+	return hashStr[:cacheKeyHashLength]
+}
+
+// queryKeyText returns the text identifying a query and its parameters in cache keys
+func queryKeyText(query interface{}, args []interface{}) string {
 	// Handle different query types for consistent cache key generation
 	var queryStr string
 
@@ -622,23 +1735,37 @@ func (r *GenericRepository[T]) generateCacheKeyFromQuery(operation string, query
 		// Fallback to string representation if marshal fails
 		argsData = []byte(fmt.Sprintf("%v", args))
 	}
-	argsStr := string(argsData)
 
-	combined := queryStr + cacheKeySeparator + argsStr
+	return queryStr + cacheKeySeparator + string(argsData)
+}
 
-	// Create hash for consistent, short keys using xxhash (fast non-cryptographic hash)
-	hash := xxhash.Sum64String(combined)
-	hashStr := fmt.Sprintf("%016x", hash)
-	return fmt.Sprintf("%s%s%s%s%s%s%s%s%s", cacheKeyPrefix, cacheKeySeparator, r.dbName, cacheKeySeparator, r.tableName, cacheKeySeparator, operation, cacheKeySeparator, hashStr[:cacheKeyHashLength])
+// checkWritable returns why write methods are rejected on this repository, if they are
+func (r *GenericRepository[T]) checkWritable() error {
+	switch {
+	case r.readOnly:
+		return ErrReadOnlyTransaction
+	case r.partial:
+		return ErrPartialColumns
+	}
+	return nil
 }
 
-// marshalEntities converts entities to bytes for Redis storage
-func (r *GenericRepository[T]) marshalEntities(entities []T) ([]byte, error) {
-	data, err := json.Marshal(entities)
-	if err != nil {
-		return nil, err
+// resolveColumn validates a column against the entity schema and returns its DB column name
+// Only schema columns are accepted, so the result is safe to use as an SQL identifier
+func (r *GenericRepository[T]) resolveColumn(column string) (string, error) {
+	if column == "" {
+		return "", fmt.Errorf("column cannot be empty")
+	}
+	if r.entitySchema == nil {
+		return "", fmt.Errorf("cannot validate column %q: schema unavailable for table %s", column, r.tableName)
 	}
-	return data, nil
+
+	field := r.entitySchema.LookUpField(column)
+	if field == nil || field.DBName == "" {
+		return "", fmt.Errorf("unknown column %q for table %s", column, r.tableName)
+	}
+
+	return field.DBName, nil
 }
 
 // extractDependenciesFromEntities builds dependency map for relationship invalidation
@@ -649,6 +1776,11 @@ func (r *GenericRepository[T]) extractDependenciesFromEntities(entities []T) map
 		return dependencies
 	}
 
+	addDependency := func(entityType string, entityID interface{}) {
+		entityType = dependencyType(r.redis, r.dbName, entityType)
+		dependencies[entityType] = append(dependencies[entityType], entityID)
+	}
+
 	for _, entity := range entities {
 		// Skip zero-value entities
 		var zero T
@@ -656,32 +1788,18 @@ func (r *GenericRepository[T]) extractDependenciesFromEntities(entities []T) map
 			continue
 		}
 
-		// Add this entity's dependency
-		pkValue := entity.GetPrimaryKeyValue()
-		if pkValue != nil {
-			dependencies[r.tableName] = append(dependencies[r.tableName], pkValue)
+		// Add this entity's dependency (a zero key would register under "...:0")
+		pkValue := r.primaryKeyValue(entity)
+		if isZeroKey(pkValue) {
+			continue
 		}
+		addDependency(r.tableName, pkValue)
 
-		// First, check if entity manually implements RelationshipAware
-		if relEntity, ok := any(entity).(RelationshipAware); ok {
-			relationships := relEntity.GetRelationships()
-			for _, relatedEntities := range relationships {
-				for _, related := range relatedEntities {
-					if related.EntityID != nil {
-						dependencies[related.EntityType] = append(dependencies[related.EntityType], related.EntityID)
-					}
-				}
-			}
-		} else {
-			// Automatic relationship detection using GORM reflection
-			if pkValue != nil {
-				autoRelationships := extractRelationshipsFromEntity(entity, pkValue)
-				for _, relatedEntities := range autoRelationships {
-					for _, related := range relatedEntities {
-						if related.EntityID != nil {
-							dependencies[related.EntityType] = append(dependencies[related.EntityType], related.EntityID)
-						}
-					}
+		// RelationshipAware first, GORM reflection if AutoDetectRelationships is enabled
+		for _, relatedEntities := range relatedEntitiesOf(r.redis, r.tableName, entity, pkValue) {
+			for _, related := range relatedEntities {
+				if related.EntityID != nil {
+					addDependency(related.EntityType, related.EntityID)
 				}
 			}
 		}
@@ -691,75 +1809,230 @@ func (r *GenericRepository[T]) extractDependenciesFromEntities(entities []T) map
 }
 
 // invalidateEntityCaches handles cache invalidation for entity changes
-func (r *GenericRepository[T]) invalidateEntityCaches(ctx context.Context, entity T) {
-	// Invalidate all caches for this entity type (ignore errors - best effort)
-	_ = r.InvalidateCache(ctx)
+// The table-wide wipe is coalesced when a debounce window is configured, while the
+// entity's own keys are always invalidated immediately. Runs under an invalidation
+// context (see invalidationContext); returns the errors of the steps that failed.
+func (r *GenericRepository[T]) invalidateEntityCaches(ctx context.Context, operation ChangeOperation, entity T) error {
+	ctx, cancel := invalidationContext(ctx, r.redis)
+	defer cancel()
 
-	// Invalidate specific entity dependencies (ignore errors - best effort)
-	_ = r.redis.InvalidateEntityDependencies(ctx, r.tableName, entity.GetPrimaryKeyValue())
+	pkValue := r.primaryKeyValue(entity)
+	err := errors.Join(
+		// Invalidate all caches for this entity type
+		r.redis.InvalidatePatternCoalesced(ctx, tableCachePattern(r.tableNamespace)),
+		// Invalidate the cached entity and its exists entry
+		r.redis.DeleteLarge(ctx, r.entityCacheKey(pkValue)),
+		r.redis.DeleteKeys(ctx, []string{existsCacheKey(r.keyNamespace, pkValue, maxKeyLength(r.redis))}),
+		// Invalidate specific entity dependencies
+		r.redis.InvalidateEntityDependencies(ctx, dependencyType(r.redis, r.dbName, r.tableName), pkValue),
+		// Invalidate the configured and registered key patterns
+		r.redis.InvalidateKeyPatterns(ctx, r.dbName, r.tableName, pkValue),
+	)
+	r.recordTableInvalidation(ctx, string(operation))
+	r.scheduleRewarm()
 
-	// Handle relationship-aware invalidation
-	var relationships map[string][]RelatedEntity
+	// Invalidate all related entity caches (ignore errors - best effort)
+	invalidateRelatedEntities(ctx, r.redis, r.dbName, r.tableName, relatedEntitiesOf(r.redis, r.tableName, entity, pkValue))
+	return err
+}
 
-	// First check if entity manually implements RelationshipAware
-	if relEntity, ok := any(entity).(RelationshipAware); ok {
-		relationships = relEntity.GetRelationships()
-	} else {
-		// Use automatic GORM relationship detection
-		relationships = extractRelationshipsFromEntity(entity, entity.GetPrimaryKeyValue())
+// writeThrough stores a written entity under its FindByID key when Strategy is write_through,
+// so the next FindByID is a cache hit. The cached value is the entity as written by GORM;
+// columns computed by the database itself (triggers, generated columns) are not reflected.
+func (r *GenericRepository[T]) writeThrough(ctx context.Context, entity T) {
+	if r.redis.Config().Strategy != redis.CacheStrategyWriteThrough || !r.operationCached("find_by_id") {
+		return
 	}
 
-	// Invalidate all related entity caches (ignore errors - best effort)
-	for _, relatedEntities := range relationships {
+	pkValue := r.primaryKeyValue(entity)
+	if isZeroKey(pkValue) {
+		return
+	}
+
+	// Ignore cache errors - best effort (the key was already invalidated)
+	cacheKey := r.entityCacheKey(pkValue)
+	_ = r.redis.SetValueWithTTL(ctx, cacheKey, entity, r.operationTTL("find_by_id"))
+}
+
+// reloadCreated re-reads a created row into entity when WithReloadAfterCreate is set
+// The lookup runs on the primary (or the transaction), never on a read-only replica
+// that may not have the row yet.
+func (r *GenericRepository[T]) reloadCreated(ctx context.Context, entity *T) {
+	if !r.options.ReloadAfterCreate {
+		return
+	}
+	id := r.primaryKeyValue(*entity)
+	if isZeroKey(id) {
+		return
+	}
+
+	var reloaded T
+	start := time.Now()
+	err := r.findByPrimaryKey(r.db.WithContext(ctx), &reloaded, id)
+	r.observeQuery(ctx, "create_reload", start, err)
+	if err != nil {
+		if r.redis != nil {
+			r.redis.Logger().Printf("sql4go: failed to reload created %s.%s %v: %v", r.dbName, r.tableName, id, err)
+		}
+		return
+	}
+	*entity = reloaded
+}
+
+// relatedEntitiesOf returns the relationships of an entity used for invalidation
+// Manual RelationshipAware implementations take precedence; GORM reflection is only used
+// when Invalidation.AutoDetectRelationships is enabled, as its table names are guessed
+// (pluralized type names) and may not match the real tables. A traversal cut by
+// Invalidation.MaxRelationshipDepth is counted per table and logged once per table.
+func relatedEntitiesOf(redisManager *cacheBackend, tableName string, entity interface{}, entityID interface{}) map[string][]RelatedEntity {
+	if relEntity, ok := entity.(RelationshipAware); ok {
+		return relEntity.GetRelationships()
+	}
+	if redisManager == nil || !redisManager.Config().Invalidation.AutoDetectRelationships {
+		return nil
+	}
+
+	maxDepth := redisManager.Config().Invalidation.MaxRelationshipDepth
+	relationships, truncated := extractRelationshipsFromEntity(entity, entityID, maxDepth)
+	if truncated {
+		if redisManager.manager != nil {
+			redisManager.manager.RecordRelationshipDepthExceeded(tableName)
+		}
+		if _, logged := loggedDepthExceeded.LoadOrStore(tableName, struct{}{}); !logged {
+			redisManager.Logger().Printf("sql4go: relationships of %s are loaded deeper than MaxRelationshipDepth (%d); deeper associations are not tracked for invalidation", tableName, maxDepth)
+		}
+	}
+	return relationships
+}
+
+// loggedDepthExceeded holds the tables whose cut relationship traversal was logged
+// (table -> struct{})
+var loggedDepthExceeded sync.Map
+
+// loggedRelations holds the table relations already logged by invalidateRelatedEntities
+// ("db.table -> related table (relation)" -> struct{})
+var loggedRelations sync.Map
+
+// invalidateRelatedEntities clears the dependency sets of all related entities (best effort)
+// The first invalidation of each related table is logged, so misconfigured
+// relationships (e.g. guessed table names) are visible.
+func invalidateRelatedEntities(ctx context.Context, redisManager *cacheBackend, dbName, tableName string, relationships map[string][]RelatedEntity) {
+	for relation, relatedEntities := range relationships {
 		for _, related := range relatedEntities {
-			if related.EntityID != nil {
-				_ = r.redis.InvalidateEntityDependencies(ctx, related.EntityType, related.EntityID)
+			if related.EntityID == nil {
+				continue
 			}
+			_ = redisManager.InvalidateEntityDependencies(ctx, dependencyType(redisManager, dbName, related.EntityType), related.EntityID)
+
+			description := fmt.Sprintf("%s.%s -> %s (%s)", dbName, tableName, related.EntityType, relation)
+			if _, logged := loggedRelations.LoadOrStore(description, struct{}{}); !logged {
+				redisManager.Logger().Printf("sql4go: invalidating related table caches: %s", description)
+			}
+		}
+	}
+}
+
+// invalidateTableCaches drops every cache key of the table, for writes whose entities
+// cannot be identified; a failed wipe is retried (see invalidationContext). Inside a
+// transaction it is skipped.
+func (r *GenericRepository[T]) invalidateTableCaches(ctx context.Context, operation ChangeOperation) {
+	if r.redis != nil && r.redis.Config().Enabled && r.tx == nil {
+		if err := r.wipeTableCaches(ctx, operation); err != nil {
+			r.retryInvalidation(operation, nil, err)
+		}
+	}
+}
+
+// wipeTableCaches drops every cache key of the table under an invalidation context
+func (r *GenericRepository[T]) wipeTableCaches(ctx context.Context, operation ChangeOperation) error {
+	ctx, cancel := invalidationContext(ctx, r.redis)
+	defer cancel()
+
+	if err := r.redis.InvalidatePatternCoalesced(ctx, tableCachePattern(r.tableNamespace)); err != nil {
+		return err
+	}
+	r.recordTableInvalidation(ctx, string(operation))
+	r.scheduleRewarm()
+	return nil
+}
+
+// maxQueryRows returns the row cap of FindAll and FindWhere (0 = unlimited)
+// A chained Limit within the cap already bounds the result, so no cap applies.
+func (r *GenericRepository[T]) maxQueryRows() int {
+	limit := r.options.MaxQueryRows
+	if limit == 0 && r.dbManager != nil && r.dbManager.Config() != nil {
+		limit = r.dbManager.Config().MaxQueryRows
+	}
+	if limit <= 0 {
+		return 0
+	}
+	for i := len(r.chain) - 1; i >= 0; i-- {
+		if r.chain[i].kind == "limit" {
+			if chained, err := strconv.Atoi(r.chain[i].material); err == nil && chained <= limit {
+				return 0
+			}
+			break // The last Limit wins
 		}
 	}
+	return limit
+}
+
+// capRows limits a query to one row more than maxRows, so truncation can be detected
+func capRows(db *gorm.DB, maxRows int) *gorm.DB {
+	if maxRows <= 0 {
+		return db
+	}
+	return db.Limit(maxRows + 1)
+}
+
+// tableCachePattern returns the SCAN pattern matching every cache key of a table
+func tableCachePattern(namespace string) string {
+	return namespace + cacheKeySeparator + "*"
 }
 
 // ============================================================================
 // UTILITY FUNCTIONS
 // ============================================================================
 
-// extractPrimaryKeyName extracts the primary key field name from entity type
-// Uses reflection to find the field tagged as primary key or defaults to "id"
-func extractPrimaryKeyName(entityType reflect.Type) string {
-	// Handle pointer types
+// isZeroKey reports whether a primary key value is unset (nil or the zero value)
+func isZeroKey(value interface{}) bool {
+	return value == nil || reflect.ValueOf(value).IsZero()
+}
+
+// declaredPrimaryKeyColumn returns the primary key column declared by a PrimaryKeyAware
+// entity type, or "" if none is declared
+func declaredPrimaryKeyColumn(entityType reflect.Type) string {
+	var model interface{}
 	if entityType.Kind() == reflect.Ptr {
-		entityType = entityType.Elem()
+		model = reflect.New(entityType.Elem()).Interface()
+	} else {
+		model = reflect.New(entityType).Interface()
 	}
-
-	// Look for field with gorm:"primaryKey" tag
-	for i := 0; i < entityType.NumField(); i++ {
-		field := entityType.Field(i)
-		gormTag := field.Tag.Get("gorm")
-
-		if strings.Contains(gormTag, "primaryKey") || strings.Contains(gormTag, "primary_key") {
-			return strings.ToLower(field.Name)
-		}
+	if aware, ok := model.(PrimaryKeyAware); ok {
+		return aware.PrimaryKeyColumn()
 	}
+	return ""
+}
 
-	// Look for common primary key field names
-	for i := 0; i < entityType.NumField(); i++ {
-		field := entityType.Field(i)
-		fieldName := strings.ToLower(field.Name)
-
-		if fieldName == "id" || fieldName == "uuid" {
-			return fieldName
-		}
+// findByPrimaryKey reads the row with primary key id into dest
+// GORM's inline primary key lookup is used unless the entity declares a column GORM
+// does not map as the primary key (see PrimaryKeyAware).
+func (r *GenericRepository[T]) findByPrimaryKey(db *gorm.DB, dest *T, id interface{}) error {
+	if r.customKey {
+		return db.Where(r.primaryKeyCondition(id)).Take(dest).Error
 	}
+	return db.First(dest, id).Error
+}
 
-	// Default fallback
-	return "id"
+// primaryKeyCondition returns the equality condition on the primary key column
+func (r *GenericRepository[T]) primaryKeyCondition(id interface{}) clause.Eq {
+	return clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: r.primaryKey}, Value: id}
 }
 
-// extractPrimaryKeyNameFromDB tries to obtain the primary key column name using GORM schema
-// Returns empty string if it cannot be determined
-func extractPrimaryKeyNameFromDB(gormDB *gorm.DB, entityType reflect.Type) string {
+// parseEntitySchema parses the GORM schema of an entity type
+func parseEntitySchema(gormDB *gorm.DB, entityType reflect.Type) (*schema.Schema, error) {
 	if gormDB == nil || entityType == nil {
-		return ""
+		return nil, fmt.Errorf("gorm db and entity type are required to parse schema")
 	}
 
 	// Create a model instance suitable for GORM's schema parser
@@ -773,43 +2046,43 @@ func extractPrimaryKeyNameFromDB(gormDB *gorm.DB, entityType reflect.Type) strin
 
 	stmt := &gorm.Statement{DB: gormDB}
 	if err := stmt.Parse(model); err != nil {
-		return ""
+		return nil, err
 	}
 
 	if stmt.Schema == nil {
-		return ""
-	}
-
-	// Use PrimaryFields if present
-	if len(stmt.Schema.PrimaryFields) > 0 {
-		if f := stmt.Schema.PrimaryFields[0]; f != nil {
-			return f.DBName
-		}
+		return nil, fmt.Errorf("no schema parsed for entity type %v", entityType)
 	}
 
-	return ""
+	return stmt.Schema, nil
 }
 
 // extractRelationshipsFromEntity automatically detects GORM relationships using reflection
 // This eliminates the need to manually implement RelationshipAware interface
-// Respects max relationship depth to prevent excessive recursion
-func extractRelationshipsFromEntity(entity interface{}, entityID interface{}) map[string][]RelatedEntity {
-	return extractRelationshipsFromEntityWithDepth(entity, entityID, 0, 3) // Default max depth of 3
+// Loaded associations are traversed up to maxDepth levels (< 1 = default of 3);
+// truncated reports that loaded associations below the limit were left out.
+func extractRelationshipsFromEntity(entity interface{}, entityID interface{}, maxDepth int) (relationships map[string][]RelatedEntity, truncated bool) {
+	if maxDepth < 1 {
+		maxDepth = 3
+	}
+	return extractRelationshipsFromEntityWithDepth(entity, entityID, 0, maxDepth)
 }
 
 // extractRelationshipsFromEntityWithDepth is the internal implementation with depth tracking
-func extractRelationshipsFromEntityWithDepth(entity interface{}, entityID interface{}, currentDepth, maxDepth int) map[string][]RelatedEntity {
+// The relationships of the entity itself are at depth 0; those of its loaded associations
+// at depth 1, and so on.
+func extractRelationshipsFromEntityWithDepth(entity interface{}, entityID interface{}, currentDepth, maxDepth int) (map[string][]RelatedEntity, bool) {
 	relationships := make(map[string][]RelatedEntity)
+	truncated := false
 
 	// Enforce maximum depth to prevent excessive recursion
 	if currentDepth >= maxDepth {
-		return relationships
+		return relationships, false
 	}
 
 	// Safely get entity type
 	entityType := reflect.TypeOf(entity)
 	if entityType == nil {
-		return relationships // Return empty for nil interface
+		return relationships, false // Return empty for nil interface
 	}
 	if entityType.Kind() == reflect.Ptr {
 		entityType = entityType.Elem()
@@ -819,17 +2092,125 @@ func extractRelationshipsFromEntityWithDepth(entity interface{}, entityID interf
 	entityValue := reflect.ValueOf(entity)
 	if entityValue.Kind() == reflect.Ptr {
 		if entityValue.IsNil() {
-			return relationships // Return empty for nil pointer
+			return relationships, false // Return empty for nil pointer
 		}
 		entityValue = entityValue.Elem()
 	}
 
 	// Verify we have a valid, non-zero value
 	if !entityValue.IsValid() {
-		return relationships
+		return relationships, false
+	}
+
+	// Relationship fields are static per type; only their values are read per entity
+	for _, rel := range relationshipFields(entityType) {
+		var relatedEntityID interface{}
+
+		if rel.relationType == "belongs_to" {
+			// For belongs_to, get the foreign key value (a CompositeKey for multi-column keys)
+			relatedEntityID = foreignKeyValue(entityValue, rel.foreignKeys)
+		} else {
+			// For has_one/has_many, use current entity's ID
+			relatedEntityID = entityID
+		}
+
+		relationships[rel.relationType] = append(relationships[rel.relationType], RelatedEntity{
+			EntityType: rel.targetEntity,
+			EntityID:   relatedEntityID,
+		})
+
+		// Loaded associations embed their own related entities in cached values
+		for _, nested := range loadedAssociations(entityValue.Field(rel.index)) {
+			if currentDepth+1 >= maxDepth {
+				truncated = true
+				break
+			}
+			nestedRelationships, nestedTruncated := extractRelationshipsFromEntityWithDepth(nested, nested.GetPrimaryKeyValue(), currentDepth+1, maxDepth)
+			for relationType, related := range nestedRelationships {
+				relationships[relationType] = append(relationships[relationType], related...)
+			}
+			truncated = truncated || nestedTruncated
+		}
+	}
+
+	return relationships, truncated
+}
+
+// loadedAssociations returns the loaded entities of an association field: a non-nil
+// pointer, a non-zero struct, or the elements of a slice; entities that do not implement
+// Entity cannot be identified and are skipped
+func loadedAssociations(field reflect.Value) []Entity {
+	switch field.Kind() {
+	case reflect.Ptr:
+		if field.IsNil() {
+			return nil
+		}
+		return loadedAssociations(field.Elem())
+	case reflect.Struct:
+		if field.IsZero() {
+			return nil
+		}
+		if field.CanAddr() {
+			if entity, ok := field.Addr().Interface().(Entity); ok {
+				return []Entity{entity}
+			}
+		}
+		if entity, ok := field.Interface().(Entity); ok {
+			return []Entity{entity}
+		}
+	case reflect.Slice, reflect.Array:
+		var entities []Entity
+		for i := 0; i < field.Len(); i++ {
+			entities = append(entities, loadedAssociations(field.Index(i))...)
+		}
+		return entities
+	}
+	return nil
+}
+
+// relationshipField is the relationship metadata of one entity field
+type relationshipField struct {
+	index        int // Index of the association field
+	relationType string
+	targetEntity string
+	foreignKeys  []int // Indexes of the belongs_to foreign key fields, in tag order; nil if absent
+}
+
+// foreignKeyValue returns the value of a belongs_to foreign key, nil if any column is unset
+// Multi-column keys are returned as a CompositeKey of the column values.
+func foreignKeyValue(entityValue reflect.Value, foreignKeys []int) interface{} {
+	if len(foreignKeys) == 0 {
+		return nil
+	}
+
+	values := make([]interface{}, len(foreignKeys))
+	for i, index := range foreignKeys {
+		fieldValue := entityValue.Field(index)
+		if !fieldValue.IsValid() || fieldValue.IsZero() {
+			return nil
+		}
+		values[i] = fieldValue.Interface()
+	}
+	if len(values) == 1 {
+		return values[0]
+	}
+	return CompositeKey(values...)
+}
+
+// relationshipFieldsCache holds the relationship fields of each entity type (reflect.Type -> []relationshipField)
+var relationshipFieldsCache sync.Map
+
+// relationshipFields returns the relationship fields of a struct type, parsing its
+// GORM tags on first use only
+func relationshipFields(entityType reflect.Type) []relationshipField {
+	if cached, ok := relationshipFieldsCache.Load(entityType); ok {
+		return cached.([]relationshipField)
+	}
+	if entityType.Kind() != reflect.Struct {
+		return nil
 	}
 
-	// Scan all fields for GORM relationship tags
+	var fields []relationshipField
 	for i := 0; i < entityType.NumField(); i++ {
 		field := entityType.Field(i)
 		gormTag := field.Tag.Get("gorm")
@@ -839,55 +2220,49 @@ func extractRelationshipsFromEntityWithDepth(entity interface{}, entityID interf
 		}
 
 		// Determine relationship type and target entity
-		relationType, targetEntity := parseGORMRelationship(field, gormTag)
+		relationType, targetEntity := parseGORMRelationship(entityType, field, gormTag)
 		if relationType == "" || targetEntity == "" {
 			continue
 		}
 
-		var relatedEntityID interface{}
-
-		// For belongs_to, get the foreign key value
+		rel := relationshipField{index: i, relationType: relationType, targetEntity: targetEntity}
 		if relationType == "belongs_to" {
 			foreignKey := extractForeignKeyFromTag(gormTag)
 			if foreignKey == "" {
 				foreignKey = field.Name + "ID" // GORM default convention
 			}
 
-			// Find the foreign key field value
-			for j := 0; j < entityType.NumField(); j++ {
-				if strings.EqualFold(entityType.Field(j).Name, foreignKey) {
-					fieldValue := entityValue.Field(j)
-					if fieldValue.IsValid() && !fieldValue.IsZero() {
-						relatedEntityID = fieldValue.Interface()
-					}
+			// Find the foreign key fields; composite keys list them comma-separated
+			for _, name := range strings.Split(foreignKey, ",") {
+				index := fieldIndex(entityType, name)
+				if index < 0 {
+					rel.foreignKeys = nil // Unresolvable column - no dependency rather than a partial one
 					break
 				}
+				rel.foreignKeys = append(rel.foreignKeys, index)
 			}
-		} else {
-			// For has_one/has_many, use current entity's ID
-			relatedEntityID = entityID
 		}
-
-		relationships[relationType] = append(relationships[relationType], RelatedEntity{
-			EntityType: targetEntity,
-			EntityID:   relatedEntityID,
-		})
+		fields = append(fields, rel)
 	}
 
-	return relationships
+	cached, _ := relationshipFieldsCache.LoadOrStore(entityType, fields)
+	return cached.([]relationshipField)
 }
 
 // parseGORMRelationship parses GORM tag to extract relationship type and target entity
-func parseGORMRelationship(field reflect.StructField, gormTag string) (relationType, targetEntity string) {
+func parseGORMRelationship(ownerType reflect.Type, field reflect.StructField, gormTag string) (relationType, targetEntity string) {
 	// Check for explicit relationship types in GORM tags
 	if strings.Contains(gormTag, "foreignKey:") {
 		// This field likely defines a relationship
 		fieldType := field.Type
 
-		// Handle slice types (has_many)
+		// Handle slice types (has_many); a single struct whose foreign key columns are
+		// fields of the owner is belongs_to, otherwise they live in the target (has_one)
 		if fieldType.Kind() == reflect.Slice {
 			fieldType = fieldType.Elem()
 			relationType = "has_many"
+		} else if hasFields(ownerType, extractForeignKeyFromTag(gormTag)) {
+			relationType = "belongs_to"
 		} else {
 			relationType = "has_one"
 		}
@@ -949,6 +2324,30 @@ func parseGORMRelationship(field reflect.StructField, gormTag string) (relationT
 	return relationType, targetEntity
 }
 
+// hasFields reports whether a struct type has all of the comma-separated fields
+func hasFields(structType reflect.Type, names string) bool {
+	if names == "" {
+		return false
+	}
+	for _, name := range strings.Split(names, ",") {
+		if fieldIndex(structType, name) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// fieldIndex returns the index of a struct field by case-insensitive name, -1 if absent
+func fieldIndex(structType reflect.Type, name string) int {
+	name = strings.TrimSpace(name)
+	for i := 0; i < structType.NumField(); i++ {
+		if strings.EqualFold(structType.Field(i).Name, name) {
+			return i
+		}
+	}
+	return -1
+}
+
 // extractForeignKeyFromTag extracts foreign key field name from GORM tag
 func extractForeignKeyFromTag(gormTag string) string {
 	parts := strings.Split(gormTag, ";")
@@ -0,0 +1,495 @@
+package repository
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ammar0144/sql4go/pkg/db"
+	"github.com/ammar0144/sql4go/pkg/redis"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"gorm.io/gorm"
+)
+
+func TestCountDistinctCachesByColumn(t *testing.T) {
+	env := newTestEnv(t)
+	repo := newTestRepository[testUser](env)
+	ctx := context.Background()
+
+	env.mock.ExpectQuery("SELECT COUNT\\(DISTINCT\\(`email`\\)\\) FROM `users`").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+
+	count, hit, stored, err := repo.CountDistinct(ctx, "email")
+	if err != nil || count != 3 || hit || !stored {
+		t.Fatalf("db path = (%d, %v, %v, %v), want (3, false, true, nil)", count, hit, stored, err)
+	}
+
+	// Served from the cache, without another query; the field name resolves to the same key
+	count, hit, stored, err = repo.CountDistinct(ctx, "Email")
+	if err != nil || count != 3 || !hit || stored {
+		t.Fatalf("cached path = (%d, %v, %v, %v), want (3, true, false, nil)", count, hit, stored, err)
+	}
+	if !env.mr.Exists(repo.generateCacheKey("count_distinct", "email")) {
+		t.Errorf("count_distinct key for email not stored; keys: %v", env.cachedKeys())
+	}
+	env.verify()
+}
+
+func TestCountDistinctRejectsUnknownColumn(t *testing.T) {
+	env := newTestEnv(t)
+	repo := newTestRepository[testUser](env)
+
+	for _, column := range []string{"", "nickname", "email; DROP TABLE users"} {
+		_, _, _, err := repo.CountDistinct(context.Background(), column)
+		if err == nil {
+			t.Errorf("CountDistinct(%q) succeeded, want a column error", column)
+		} else if column != "" && !strings.Contains(err.Error(), "unknown column") {
+			t.Errorf("CountDistinct(%q) error = %v, want unknown column", column, err)
+		}
+	}
+	if keys := env.cachedKeys(); len(keys) != 0 {
+		t.Errorf("rejected columns stored keys: %v", keys)
+	}
+	env.verify()
+}
+
+func TestFindWhereWithBuilder(t *testing.T) {
+	env := newTestEnv(t)
+	repo := newTestRepository[testUser](env)
+	ctx := context.Background()
+
+	builder := func() *db.Builder {
+		return db.NewBuilder("users").Where("name", db.Equal, "ann").OrderBy("email", true).Limit(2).Offset(4)
+	}
+
+	env.mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `users` WHERE name = ? ORDER BY email DESC LIMIT ? OFFSET ?")).
+		WithArgs("ann", 2, 4).
+		WillReturnRows(userRows(testUser{ID: 7, Name: "ann", Email: "b@x"}, testUser{ID: 3, Name: "ann", Email: "a@x"}))
+
+	users, hit, stored, err := repo.FindWhere(ctx, builder())
+	if err != nil || hit || !stored {
+		t.Fatalf("FindWhere = (%v, %v, %v), want a stored DB result", hit, stored, err)
+	}
+	if len(users) != 2 || users[0].ID != 7 || users[1].ID != 3 {
+		t.Fatalf("FindWhere returned %+v", users)
+	}
+
+	// The key is that of the equivalent condition and chain, and identical across builders
+	where, args := builder().BuildWhere()
+	chained := repo.Order(ctx, "email DESC").Limit(ctx, 2).Offset(ctx, 4).(*GenericRepository[testUser])
+	key := chained.CacheKeyForQuery("find_where", where, args...)
+	if got := repo.CacheKeyForQuery("find_where", builder()); got != key {
+		t.Errorf("builder key = %q, want chained key %q", got, key)
+	}
+	if got, err := CacheKeyForQuery[testUser]("app", "find_where", builder(), nil); err != nil || got != key {
+		t.Errorf("package-level builder key = (%q, %v), want %q", got, err, key)
+	}
+	unordered := repo.CacheKeyForQuery("find_where", db.NewBuilder("users").Where("name", db.Equal, "ann"))
+	if unordered == key {
+		t.Errorf("ordering and limits are not part of the key %q", key)
+	}
+	if !env.mr.Exists(key) {
+		t.Fatalf("key %q not stored; keys: %v", key, env.cachedKeys())
+	}
+
+	users, hit, _, err = repo.FindWhere(ctx, builder())
+	if err != nil || !hit || len(users) != 2 {
+		t.Fatalf("second FindWhere = (%d rows, hit %v, %v), want a cache hit", len(users), hit, err)
+	}
+	env.verify()
+}
+
+func TestBuilderRejectsUnsupportedClauses(t *testing.T) {
+	env := newTestEnv(t)
+	repo := newTestRepository[testUser](env)
+	ctx := context.Background()
+
+	joined := db.NewBuilder("users").InnerJoin("orders", "orders.user_id = users.id").Where("orders.total", db.GreaterThan, 10)
+	if _, _, _, err := repo.FindWhere(ctx, joined); err == nil || !strings.Contains(err.Error(), "JOIN") {
+		t.Errorf("FindWhere with a join: err = %v, want unsupported JOIN", err)
+	}
+	if _, _, _, err := repo.First(ctx, db.NewBuilder("users").GroupBy("name")); err == nil {
+		t.Error("First with GROUP BY succeeded, want an error")
+	}
+	if _, _, _, err := repo.Or(ctx, db.NewBuilder("users").Limit(1)).FindAll(ctx); err == nil {
+		t.Error("Or with a builder limit succeeded, want an error")
+	}
+	env.verify()
+}
+
+func TestFindByIDCorruptCacheFallsBackToDatabase(t *testing.T) {
+	env := newTestEnv(t)
+	repo := newTestRepository[testUser](env)
+	ctx := context.Background()
+
+	key := repo.CacheKeyForID(1)
+	env.mr.Set(key, "{not json")
+	env.mock.ExpectQuery("SELECT \\* FROM `users` WHERE `users`.`id` = \\?").
+		WithArgs(1, 1).
+		WillReturnRows(userRows(testUser{ID: 1, Name: "ann", Email: "a@x"}))
+
+	user, hit, _, err := repo.FindByID(ctx, 1)
+	if err != nil {
+		t.Fatalf("FindByID over a corrupt entry: %v", err)
+	}
+	if hit || user == nil || user.Name != "ann" {
+		t.Fatalf("FindByID = (%+v, hit %v), want the database row", user, hit)
+	}
+
+	// The corrupt entry was replaced by the database row
+	user, hit, _, err = repo.FindByID(ctx, 1)
+	if err != nil || !hit || user.Name != "ann" {
+		t.Fatalf("second FindByID = (%+v, hit %v, %v), want a cache hit", user, hit, err)
+	}
+	env.verify()
+}
+
+func TestCacheMetrics(t *testing.T) {
+	env := newTestEnv(t)
+	repo := newTestRepository[testUser](env)
+	ctx := context.Background()
+
+	env.mock.ExpectQuery("SELECT \\* FROM `users`").WillReturnRows(userRows(testUser{ID: 1, Name: "ann"}))
+	_, _, _, _ = repo.FindByID(ctx, 1)
+	_, _, _, _ = repo.FindByID(ctx, 1)
+
+	metrics, enabled := repo.CacheMetrics()
+	if !enabled {
+		t.Fatal("CacheMetrics reports caching disabled on a cached repository")
+	}
+	if metrics.CacheHits == 0 || metrics.CacheMisses == 0 {
+		t.Errorf("CacheMetrics = %d hits, %d misses; want both recorded", metrics.CacheHits, metrics.CacheMisses)
+	}
+
+	uncached := newUncachedTestRepository[testUser](env)
+	if _, enabled := uncached.CacheMetrics(); enabled {
+		t.Error("CacheMetrics reports caching enabled on a DB-only repository")
+	}
+	env.verify()
+}
+
+func TestFindWhereCachesEmptyResults(t *testing.T) {
+	env := newTestEnv(t)
+	repo := newTestRepository[testUser](env)
+	ctx := context.Background()
+
+	env.mock.ExpectQuery("SELECT \\* FROM `users` WHERE name = \\? ORDER BY id").WithArgs("nobody").WillReturnRows(userRows())
+
+	for i, wantHit := range []bool{false, true} {
+		users, hit, _, err := repo.FindWhere(ctx, "name = ? ORDER BY id", "nobody")
+		if err != nil || len(users) != 0 || hit != wantHit {
+			t.Fatalf("call %d: FindWhere = (%d rows, hit %v, %v), want (0, %v, nil)", i, len(users), hit, err, wantHit)
+		}
+	}
+	env.verify()
+}
+
+// A hand-built Config leaves DisableNullCaching at its zero value, which keeps caching
+// empty results as DefaultConfig does
+func TestFindWhereCachesEmptyResultsWithHandBuiltConfig(t *testing.T) {
+	env := newTestEnv(t, func(c *redis.Config) {
+		*c = redis.Config{
+			Enabled:      true,
+			Host:         c.Host,
+			Port:         c.Port,
+			DefaultTTL:   time.Hour,
+			NullCacheTTL: time.Minute,
+			PoolSize:     1,
+			Invalidation: c.Invalidation,
+		}
+	})
+	repo := newTestRepository[testUser](env)
+	ctx := context.Background()
+
+	env.mock.ExpectQuery("SELECT \\* FROM `users` WHERE name = \\? ORDER BY id").WithArgs("nobody").WillReturnRows(userRows())
+	for i, wantHit := range []bool{false, true} {
+		users, hit, _, err := repo.FindWhere(ctx, "name = ? ORDER BY id", "nobody")
+		if err != nil || len(users) != 0 || hit != wantHit {
+			t.Fatalf("call %d: FindWhere = (%d rows, hit %v, %v), want (0, %v, nil)", i, len(users), hit, err, wantHit)
+		}
+	}
+	env.verify()
+}
+
+func TestFindWhereSkipsEmptyResultsWithoutNullCaching(t *testing.T) {
+	env := newTestEnv(t, func(c *redis.Config) { c.DisableNullCaching = true })
+	repo := newTestRepository[testUser](env)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		env.mock.ExpectQuery("SELECT \\* FROM `users` WHERE name = \\? ORDER BY id").WithArgs("nobody").WillReturnRows(userRows())
+		users, hit, stored, err := repo.FindWhere(ctx, "name = ? ORDER BY id", "nobody")
+		if err != nil || len(users) != 0 || hit || stored {
+			t.Fatalf("call %d: FindWhere = (%d rows, hit %v, stored %v, %v), want an uncached empty result", i, len(users), hit, stored, err)
+		}
+	}
+	if keys := env.cachedKeys(); len(keys) != 0 {
+		t.Errorf("empty result cached under %v", keys)
+	}
+	env.verify()
+}
+
+func TestOperationTTLs(t *testing.T) {
+	env := newTestEnv(t, func(c *redis.Config) {
+		c.OperationTTLs = map[string]time.Duration{"find_by_id": 10 * time.Minute, "count": 15 * time.Second}
+	})
+	repo := newTestRepository[testUser](env)
+	ctx := context.Background()
+
+	env.mock.ExpectQuery("SELECT \\* FROM `users`").WillReturnRows(userRows(testUser{ID: 1, Name: "ann"}))
+	env.mock.ExpectQuery("SELECT count\\(\\*\\) FROM `users`").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	env.mock.ExpectQuery("SELECT COUNT\\(DISTINCT\\(`name`\\)\\) FROM `users`").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	_, _, _, _ = repo.FindByID(ctx, 1)
+	_, _, _, _ = repo.Count(ctx)
+	_, _, _, _ = repo.CountDistinct(ctx, "name")
+
+	for key, want := range map[string]time.Duration{
+		repo.CacheKeyForID(1):                           10 * time.Minute,
+		repo.generateCacheKey("count", ""):              15 * time.Second,
+		repo.generateCacheKey("count_distinct", "name"): env.cache.Config().DefaultTTL,
+	} {
+		if got := env.mr.TTL(key); got != want {
+			t.Errorf("TTL of %s = %v, want %v", key, got, want)
+		}
+	}
+	env.verify()
+}
+
+func TestWarmCacheByStrategy(t *testing.T) {
+	t.Run("lazy loading", func(t *testing.T) {
+		env := newTestEnv(t, func(c *redis.Config) { c.Strategy = redis.CacheStrategyLazyLoading })
+		repo := newTestRepository[testUser](env)
+
+		if err := repo.WarmCache(context.Background()); err != nil {
+			t.Fatalf("WarmCache: %v", err)
+		}
+		if keys := env.cachedKeys(); len(keys) != 0 {
+			t.Errorf("WarmCache populated %v under lazy loading", keys)
+		}
+		env.verify() // No queries
+	})
+
+	t.Run("read through", func(t *testing.T) {
+		env := newTestEnv(t)
+		repo := newTestRepository[testUser](env)
+
+		env.mock.ExpectQuery("SELECT \\* FROM `users`").WillReturnRows(userRows(testUser{ID: 1, Name: "ann"}))
+		env.mock.ExpectQuery("SELECT count\\(\\*\\) FROM `users`").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+		if err := repo.WarmCache(context.Background()); err != nil {
+			t.Fatalf("WarmCache: %v", err)
+		}
+		for _, key := range []string{repo.findAllCacheKey(), repo.generateCacheKey("count", "")} {
+			if !env.mr.Exists(key) {
+				t.Errorf("WarmCache did not populate %s; keys: %v", key, env.cachedKeys())
+			}
+		}
+		env.verify()
+	})
+}
+
+func TestCreateWithResultReportsPrimaryKeyAndRowsAffected(t *testing.T) {
+	env := newTestEnv(t)
+	repo := newTestRepository[testUser](env)
+
+	env.mock.ExpectExec("INSERT INTO `users`").
+		WithArgs("ann", "a@x").
+		WillReturnResult(sqlmock.NewResult(42, 1))
+
+	user := &testUser{Name: "ann", Email: "a@x"}
+	result, err := repo.CreateWithResult(context.Background(), user)
+	if err != nil {
+		t.Fatalf("CreateWithResult: %v", err)
+	}
+	if user.ID != 42 {
+		t.Errorf("entity ID = %d, want the generated 42", user.ID)
+	}
+	if result.PrimaryKey != uint(42) || result.RowsAffected != 1 {
+		t.Errorf("result = %+v, want primary key 42 and 1 row affected", result)
+	}
+	env.verify()
+}
+
+func TestFindWhereSkipsCachingResultOverSoftLimit(t *testing.T) {
+	env := newTestEnv(t)
+	repo := newTestRepository[testUser](env)
+	ctx := context.Background()
+	users := []testUser{{ID: 1, Name: "ann", Email: "a@x"}, {ID: 2, Name: "bob", Email: "b@x"}}
+
+	// Measure the encoded result: without a limit it is cached as is
+	env.mock.ExpectQuery("SELECT \\* FROM `users`").WillReturnRows(userRows(users...))
+	if _, _, stored, err := repo.FindWhere(ctx, "name <> ?", ""); err != nil || !stored {
+		t.Fatalf("FindWhere = (stored %v, %v), want a cached result", stored, err)
+	}
+	key := repo.generateCacheKeyFromQuery("find_where", "name <> ?", "")
+	encoded, err := env.mr.Get(key)
+	if err != nil {
+		t.Fatalf("cached result: %v", err)
+	}
+	env.mr.FlushAll()
+
+	// One byte over the limit: served, not cached, counted
+	env.cache.Config().LargeValue.SkipCachingAbove = len(encoded) - 1
+	env.mock.ExpectQuery("SELECT \\* FROM `users`").WillReturnRows(userRows(users...))
+	found, _, stored, err := repo.FindWhere(ctx, "name <> ?", "")
+	if err != nil || stored || len(found) != 2 {
+		t.Fatalf("FindWhere over the limit = (%d rows, stored %v, %v), want 2 uncached rows", len(found), stored, err)
+	}
+	env.verify()
+
+	if env.mr.Exists(key) {
+		t.Error("a result over the limit was cached")
+	}
+	metrics := env.cache.GetMetrics()
+	if metrics.OversizeSkips != 1 || metrics.ByTable["users"].OversizeSkips != 1 {
+		t.Errorf("OversizeSkips = %d (users: %d), want 1", metrics.OversizeSkips, metrics.ByTable["users"].OversizeSkips)
+	}
+}
+
+// testTicket has a column the database generates
+type testTicket struct {
+	ID    uint   `gorm:"primaryKey"`
+	Title string `gorm:"column:title"`
+	Code  string `gorm:"->;column:code"` // e.g. CONCAT('T-', id), never written
+}
+
+func (testTicket) TableName() string { return "tickets" }
+
+func (t testTicket) GetPrimaryKeyValue() interface{} { return t.ID }
+
+func TestReloadAfterCreateCachesGeneratedColumns(t *testing.T) {
+	env := newTestEnv(t, func(c *redis.Config) { c.Strategy = redis.CacheStrategyWriteThrough })
+	repo := newTestRepository[testTicket](env, WithReloadAfterCreate())
+	ctx := context.Background()
+
+	env.mock.ExpectExec("INSERT INTO `tickets` \\(`title`\\)").WillReturnResult(sqlmock.NewResult(7, 1))
+	env.mock.ExpectQuery("SELECT \\* FROM `tickets`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "code"}).AddRow(7, "printer", "T-7"))
+
+	ticket := &testTicket{Title: "printer"}
+	if _, err := repo.Create(ctx, ticket); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	env.verify()
+	if ticket.Code != "T-7" {
+		t.Errorf("created entity code = %q, want the generated T-7", ticket.Code)
+	}
+
+	// No query is expected: the reloaded row was written through
+	found, hit, _, err := repo.FindByID(ctx, 7)
+	if err != nil || !hit || found.Code != "T-7" {
+		t.Errorf("FindByID after Create = (%+v, hit %v, %v), want the cached row with its generated code", found, hit, err)
+	}
+}
+
+func TestUpdateBatchWritesThroughEveryEntity(t *testing.T) {
+	env := newTestEnv(t, func(c *redis.Config) { c.Strategy = redis.CacheStrategyWriteThrough })
+	repo := newTestRepository[testUser](env)
+	ctx := context.Background()
+
+	batch := []*testUser{
+		{ID: 1, Name: "ada", Email: "ada@example.com"},
+		{ID: 2, Name: "alan", Email: "alan@example.com"},
+		{ID: 3, Name: "ava", Email: "ava@example.com"},
+	}
+	env.mock.ExpectExec("INSERT INTO `users`").WillReturnResult(sqlmock.NewResult(3, 3))
+	if err := repo.UpdateBatch(ctx, batch); err != nil {
+		t.Fatalf("UpdateBatch: %v", err)
+	}
+	env.verify()
+
+	// Each entity's table wipe must not delete the entities written through before it
+	for _, user := range batch {
+		found, hit, _, err := repo.FindByID(ctx, user.ID)
+		if err != nil || !hit || found.Name != user.Name {
+			t.Errorf("FindByID(%d) after UpdateBatch = (%+v, hit %v, %v), want the written-through %s", user.ID, found, hit, err, user.Name)
+		}
+	}
+}
+
+func TestCreateBatchBackfillsEveryID(t *testing.T) {
+	env := newTestEnv(t)
+	repo := newTestRepository[testSite](env)
+
+	env.mock.ExpectExec("INSERT INTO `sites`").WillReturnResult(sqlmock.NewResult(10, 3))
+	sites := []*testSite{{OrgID: 7, RegionCode: "eu"}, {OrgID: 7, RegionCode: "eu"}, {OrgID: 8, RegionCode: "us"}}
+	if err := repo.CreateBatch(context.Background(), sites); err != nil {
+		t.Fatalf("CreateBatch: %v", err)
+	}
+	env.verify()
+
+	for i, site := range sites {
+		if want := uint(10 + i); site.ID != want {
+			t.Errorf("site %d has id %d, want the back-filled %d", i, site.ID, want)
+		}
+	}
+}
+
+func TestCreateBatchWithUnresolvedIDsRegistersNoZeroKeys(t *testing.T) {
+	env := newTestEnv(t, func(c *redis.Config) { c.Strategy = redis.CacheStrategyWriteThrough })
+	repo := newTestRepository[testSite](env)
+	ctx := context.Background()
+
+	var mu sync.Mutex
+	var events []interface{}
+	repo.Subscribe(func(event ChangeEvent) {
+		mu.Lock()
+		events = append(events, event.PrimaryKey)
+		mu.Unlock()
+	})
+
+	// A driver that reports the generated id of the first row only
+	if err := env.gorm.Callback().Create().After("gorm:create").Register("test:first_id_only", func(tx *gorm.DB) {
+		if sites, ok := tx.Statement.Dest.(*[]*testSite); ok {
+			for _, site := range (*sites)[1:] {
+				site.ID = 0
+			}
+		}
+	}); err != nil {
+		t.Fatalf("register callback: %v", err)
+	}
+
+	env.mock.ExpectExec("INSERT INTO `sites`").WillReturnResult(sqlmock.NewResult(10, 3))
+	sites := []*testSite{{OrgID: 7, RegionCode: "eu"}, {OrgID: 7, RegionCode: "eu"}, {OrgID: 8, RegionCode: "us"}}
+	err := repo.CreateBatch(ctx, sites)
+	if !IsPrimaryKeyNotPopulated(err) || !strings.Contains(err.Error(), "indexes [1 2]") {
+		t.Fatalf("CreateBatch error = %v, want ErrPrimaryKeyNotPopulated for indexes [1 2]", err)
+	}
+	env.verify()
+
+	// Only the resolved entity is notified
+	_ = repo.Close() // Delivers the queued events
+	mu.Lock()
+	defer mu.Unlock()
+	if len(events) != 1 || events[0] != uint(10) {
+		t.Errorf("change events for ids %v, want only 10", events)
+	}
+
+	// The batch registers dependencies under the resolved id only
+	batch := make([]testSite, len(sites))
+	for i, site := range sites {
+		batch[i] = *site
+	}
+	if ids := repo.extractDependenciesFromEntities(batch)["sites"]; len(ids) != 1 || ids[0] != uint(10) {
+		t.Errorf("sites dependencies = %v, want only 10", ids)
+	}
+
+	for _, key := range env.cachedKeys() {
+		if strings.HasSuffix(key, ":0") {
+			t.Errorf("key %s is keyed by a zero id", key)
+		}
+		if !strings.Contains(key, ":deps:") {
+			continue
+		}
+		members, _ := env.mr.Members(key)
+		for _, member := range members {
+			if strings.HasSuffix(member, ":0") {
+				t.Errorf("dependency set %s holds %s, keyed by a zero id", key, member)
+			}
+		}
+	}
+}
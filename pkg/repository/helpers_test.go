@@ -0,0 +1,219 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ammar0144/sql4go/pkg/db"
+	"github.com/ammar0144/sql4go/pkg/redis"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alicebob/miniredis/v2"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testUser is the entity most repository tests use
+type testUser struct {
+	ID    uint   `gorm:"primaryKey"`
+	Name  string `gorm:"column:name"`
+	Email string `gorm:"column:email;uniqueIndex"`
+}
+
+func (testUser) TableName() string { return "users" }
+
+func (u testUser) GetPrimaryKeyValue() interface{} { return u.ID }
+
+// testOrder is an entity with a belongs-to and a has-many association
+type testOrder struct {
+	ID     uint            `gorm:"primaryKey"`
+	UserID uint            `gorm:"column:user_id"`
+	User   *testUser       `gorm:"foreignKey:UserID"`
+	Items  []testOrderItem `gorm:"foreignKey:OrderID"`
+}
+
+func (o testOrder) GetPrimaryKeyValue() interface{} { return o.ID }
+
+// testOrderItem is a has-many association of testOrder
+type testOrderItem struct {
+	ID      uint `gorm:"primaryKey"`
+	OrderID uint `gorm:"column:order_id"`
+}
+
+func (i testOrderItem) GetPrimaryKeyValue() interface{} { return i.ID }
+
+// userColumns are the columns of testUser rows returned by mocked queries
+var userColumns = []string{"id", "name", "email"}
+
+// testEnv is a mocked database and a miniredis-backed cache manager
+// Repositories under test run against go-sqlmock through the MySQL dialector and
+// against a miniredis server, so both the SQL and the cache keys are observable.
+type testEnv struct {
+	t     testing.TB
+	mock  sqlmock.Sqlmock
+	gorm  *gorm.DB
+	dbm   *db.Manager
+	mr    *miniredis.Miniredis
+	cache *redis.Manager
+
+	txs *txRecorder
+}
+
+// mockDSNs numbers the sqlmock connections opened by tests
+var mockDSNs atomic.Int64
+
+// txRecorder records the options of the transactions begun on a connection
+type txRecorder struct {
+	mu      sync.Mutex
+	options []driver.TxOptions
+}
+
+// recorded returns the options of the transactions begun so far
+func (r *txRecorder) recorded() []driver.TxOptions {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]driver.TxOptions(nil), r.options...)
+}
+
+// txRecordingConnector hands out a sqlmock connection that records BeginTx options,
+// which sqlmock itself ignores
+type txRecordingConnector struct {
+	driver   driver.Driver
+	dsn      string
+	recorder *txRecorder
+}
+
+func (c *txRecordingConnector) Connect(context.Context) (driver.Conn, error) {
+	conn, err := c.driver.Open(c.dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &txRecordingConn{Conn: conn, recorder: c.recorder}, nil
+}
+
+func (c *txRecordingConnector) Driver() driver.Driver { return c.driver }
+
+// txRecordingConn forwards to the sqlmock connection
+type txRecordingConn struct {
+	driver.Conn
+	recorder *txRecorder
+}
+
+func (c *txRecordingConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	c.recorder.mu.Lock()
+	c.recorder.options = append(c.recorder.options, opts)
+	c.recorder.mu.Unlock()
+	return c.Conn.(driver.ConnBeginTx).BeginTx(ctx, opts)
+}
+
+func (c *txRecordingConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return c.Conn.(driver.QueryerContext).QueryContext(ctx, query, args)
+}
+
+func (c *txRecordingConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	return c.Conn.(driver.ExecerContext).ExecContext(ctx, query, args)
+}
+
+// newTestEnv starts a miniredis server and a sqlmock connection named "app"
+func newTestEnv(t testing.TB, configure ...func(*redis.Config)) *testEnv {
+	t.Helper()
+
+	dsn := fmt.Sprintf("sqlmock_%d", mockDSNs.Add(1))
+	mockDB, mock, err := sqlmock.NewWithDSN(dsn)
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	txs := &txRecorder{}
+	sqlDB := sql.OpenDB(&txRecordingConnector{driver: mockDB.Driver(), dsn: dsn, recorder: txs})
+	t.Cleanup(func() {
+		_ = sqlDB.Close()
+		_ = mockDB.Close()
+	})
+
+	gormDB, err := gorm.Open(mysql.New(mysql.Config{Conn: sqlDB, SkipInitializeWithVersion: true}), &gorm.Config{
+		SkipDefaultTransaction: true,
+		Logger:                 logger.Discard,
+	})
+	if err != nil {
+		t.Fatalf("gorm: %v", err)
+	}
+	dbm, err := db.NewManagerWithDB(gormDB, nil)
+	if err != nil {
+		t.Fatalf("db manager: %v", err)
+	}
+
+	mr := miniredis.RunT(t)
+	config := redis.DefaultConfig()
+	config.Host = mr.Host()
+	config.Port, _ = strconv.Atoi(mr.Port())
+	config.MinIdleConns = 0
+	for _, fn := range configure {
+		fn(config)
+	}
+	cache, err := redis.NewManager(config)
+	if err != nil {
+		t.Fatalf("redis manager: %v", err)
+	}
+	t.Cleanup(func() { _ = cache.Close() })
+
+	return &testEnv{t: t, mock: mock, gorm: gormDB, dbm: dbm, mr: mr, cache: cache, txs: txs}
+}
+
+// expectDatabaseName answers the database name lookup made by the repository constructor
+func (e *testEnv) expectDatabaseName() {
+	e.mock.ExpectQuery(`SELECT DATABASE\(\)`).WillReturnRows(sqlmock.NewRows([]string{"DATABASE()"}).AddRow("app"))
+	e.mock.ExpectQuery("SELECT SCHEMA_NAME").WillReturnRows(sqlmock.NewRows([]string{"SCHEMA_NAME"}).AddRow("app"))
+}
+
+// verify fails the test on unmet SQL expectations
+func (e *testEnv) verify() {
+	e.t.Helper()
+	if err := e.mock.ExpectationsWereMet(); err != nil {
+		e.t.Errorf("sql expectations: %v", err)
+	}
+}
+
+// newTestRepository builds a cached repository over the environment and closes it on cleanup
+func newTestRepository[T Entity](e *testEnv, opts ...Option) *GenericRepository[T] {
+	e.t.Helper()
+	e.expectDatabaseName()
+	repo := NewGenericRepository[T](e.dbm, e.cache, opts...).(*GenericRepository[T])
+	e.t.Cleanup(func() { _ = repo.Close() })
+	return repo
+}
+
+// newUncachedTestRepository builds a DB-only repository over the environment
+func newUncachedTestRepository[T Entity](e *testEnv, opts ...Option) *GenericRepository[T] {
+	e.t.Helper()
+	e.expectDatabaseName()
+	repo := NewGenericRepository[T](e.dbm, nil, opts...).(*GenericRepository[T])
+	e.t.Cleanup(func() { _ = repo.Close() })
+	return repo
+}
+
+// expectUniqueValues answers the lookup of a user's unique columns made before an Update
+func (e *testEnv) expectUniqueValues(user testUser) {
+	e.mock.ExpectQuery("SELECT `id`,`email` FROM `users`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email"}).AddRow(user.ID, user.Email))
+}
+
+// userRows returns mocked rows for the given users
+func userRows(users ...testUser) *sqlmock.Rows {
+	rows := sqlmock.NewRows(userColumns)
+	for _, user := range users {
+		rows.AddRow(user.ID, user.Name, user.Email)
+	}
+	return rows
+}
+
+// cachedKeys lists the keys stored in miniredis
+func (e *testEnv) cachedKeys() []string {
+	return e.mr.Keys()
+}
@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// IDKind selects the generated primary key format (see WithGeneratedIDs)
+type IDKind string
+
+const (
+	IDKindULID   IDKind = "ulid"   // 26-char Crockford base32, fits char(26)
+	IDKindUUIDv7 IDKind = "uuidv7" // 36-char RFC 9562 UUID version 7, fits char(36)
+)
+
+// crockfordAlphabet is the ULID base32 alphabet
+const crockfordAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// newID generates an ID of the given kind
+// Both kinds start with a millisecond timestamp, so IDs sort roughly by creation time
+func newID(kind IDKind) (string, error) {
+	var b [16]byte
+	var ts [8]byte
+	binary.BigEndian.PutUint64(ts[:], uint64(time.Now().UnixMilli()))
+	copy(b[:6], ts[2:]) // 48-bit timestamp
+	if _, err := rand.Read(b[6:]); err != nil {
+		return "", err
+	}
+
+	switch kind {
+	case IDKindULID:
+		return encodeULID(b), nil
+	case IDKindUUIDv7:
+		b[6] = b[6]&0x0f | 0x70 // Version 7
+		b[8] = b[8]&0x3f | 0x80 // RFC 9562 variant
+		return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+	default:
+		return "", fmt.Errorf("unsupported id kind %q", kind)
+	}
+}
+
+// encodeULID encodes 128 bits as 26 Crockford base32 characters (2 leading zero bits)
+func encodeULID(b [16]byte) string {
+	bit := func(p int) byte {
+		if p < 0 {
+			return 0
+		}
+		return (b[p/8] >> (7 - p%8)) & 1
+	}
+
+	out := make([]byte, 26)
+	for i := range out {
+		var v byte
+		for p := i*5 - 2; p < i*5+3; p++ {
+			v = v<<1 | bit(p)
+		}
+		out[i] = crockfordAlphabet[v]
+	}
+	return string(out)
+}
+
+// assignGeneratedID sets a generated ID on an entity whose string primary key is empty
+// Non-string and already populated primary keys are left untouched.
+func (r *GenericRepository[T]) assignGeneratedID(ctx context.Context, entity *T) error {
+	if r.options.IDKind == "" || r.entitySchema == nil || r.entitySchema.PrioritizedPrimaryField == nil {
+		return nil
+	}
+
+	field := r.entitySchema.PrioritizedPrimaryField
+	if field.FieldType.Kind() != reflect.String {
+		return nil
+	}
+
+	rv := reflect.ValueOf(entity)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+
+	if _, isZero := field.ValueOf(ctx, rv); !isZero {
+		return nil
+	}
+
+	id, err := newID(r.options.IDKind)
+	if err != nil {
+		return fmt.Errorf("failed to generate id: %w", err)
+	}
+	return field.Set(ctx, rv, id)
+}
@@ -2,6 +2,10 @@ package repository
 
 import (
 	"context"
+	"database/sql"
+
+	"github.com/ammar0144/sql4go/pkg/redis"
+	"gorm.io/gorm/clause"
 )
 
 // Repository defines the generic repository interface
@@ -10,32 +14,138 @@ type Repository[T any] interface {
 	// Returns: (result, cacheHit, cacheStored, error)
 	// - cacheHit: true if data retrieved from Redis cache
 	// - cacheStored: true if data successfully stored to Redis after DB query
+	// Single-record lookups return a nil result when nothing matches, or ErrNotFound
+	// with WithReturnNotFoundError
 	FindByID(ctx context.Context, id interface{}) (*T, bool, bool, error)
+	// FindByIDs reads cached rows in one round trip and queries only the misses
+	FindByIDs(ctx context.Context, ids []interface{}) ([]T, bool, bool, error)
+	// FindAll and FindWhere return the first MaxQueryRows rows with ErrResultTruncated
+	// when a row cap is configured and exceeded (see WithMaxQueryRows)
 	FindAll(ctx context.Context) ([]T, bool, bool, error)
+	// FindAllInto and FindWhereInto decode into the caller's slice, reusing its capacity
+	FindAllInto(ctx context.Context, dest *[]T) (bool, bool, error)
+	FindPage(ctx context.Context, offset, limit int) ([]T, bool, bool, error)
+	// FindWhere, First, Take and Last also accept a *db.Builder: its conditions, ordering and limits are applied
 	FindWhere(ctx context.Context, query interface{}, args ...interface{}) ([]T, bool, bool, error)
+	FindWhereInto(ctx context.Context, dest *[]T, query interface{}, args ...interface{}) (bool, bool, error)
+	// FindPage and FindWherePage read only the cache segments of a page (see WithSegmentedCollections)
+	FindWherePage(ctx context.Context, offset, limit int, query interface{}, args ...interface{}) ([]T, bool, bool, error)
+	// FindWhereIn splits large IN lists into chunks (see WithInListChunking)
+	FindWhereIn(ctx context.Context, column string, values []interface{}) ([]T, bool, bool, error)
 	First(ctx context.Context, query interface{}, args ...interface{}) (*T, bool, bool, error)
+	Take(ctx context.Context, query interface{}, args ...interface{}) (*T, bool, bool, error)
+	// FindByUnique looks up a record by a unique column other than the primary key
+	FindByUnique(ctx context.Context, column string, value interface{}) (*T, bool, bool, error)
+	// Last respects a chained Order; the primary key only breaks ties
+	Last(ctx context.Context, query interface{}, args ...interface{}) (*T, bool, bool, error)
 	Count(ctx context.Context) (int64, bool, bool, error)
+	CountDistinct(ctx context.Context, column string) (int64, bool, bool, error)
 	Exists(ctx context.Context, id interface{}) (bool, bool, bool, error)
+	// ExistsMany checks many ids with one cache round trip and primary-key-only queries
+	ExistsMany(ctx context.Context, ids []interface{}) (map[interface{}]bool, bool, bool, error)
 
 	// GORM Query Methods (Cached)
+	// Chained clauses are applied by the terminal method and folded into its cache key;
+	// Where, Or and Not compose conditions (Delete only deletes a row matching them)
+	Where(ctx context.Context, query interface{}, args ...interface{}) Repository[T]
+	Or(ctx context.Context, query interface{}, args ...interface{}) Repository[T]
+	Not(ctx context.Context, query interface{}, args ...interface{}) Repository[T]
+	// Select and Omit restrict the columns read; writes on them fail with ErrPartialColumns
+	Select(ctx context.Context, columns ...string) Repository[T]
+	Omit(ctx context.Context, columns ...string) Repository[T]
 	Preload(ctx context.Context, associations ...string) Repository[T]
 	Joins(ctx context.Context, query string, args ...interface{}) Repository[T]
 	Order(ctx context.Context, value interface{}) Repository[T]
 	Limit(ctx context.Context, limit int) Repository[T]
 	Offset(ctx context.Context, offset int) Repository[T]
+	// ReadOnlyTx runs reads in read-only transactions; writes fail with ErrReadOnlyTransaction
+	ReadOnlyTx(ctx context.Context) Repository[T]
 
 	// Commands (Write Operations - Relationship-Aware Cache Invalidation)
 	// Returns: (cacheInvalidated, error)
-	// - cacheInvalidated: true if related caches were successfully invalidated
+	// - cacheInvalidated: true if related caches were successfully invalidated; false if the
+	//   invalidation failed or timed out and was queued for a retry (see redis.InvalidationConfig.RetryAttempts)
 	Create(ctx context.Context, entity *T) (bool, error)
+	CreateWithResult(ctx context.Context, entity *T) (CreateResult, error)
+	// CreateIgnore skips duplicate keys, reporting whether the row was inserted
+	CreateIgnore(ctx context.Context, entity *T) (bool, error)
 	Update(ctx context.Context, entity *T) (bool, error)
 	Delete(ctx context.Context, id interface{}) (bool, error)
 
+	// Associations (GORM association mode; invalidates the owner and the associated side)
+	AppendAssociation(ctx context.Context, entity *T, association string, values ...interface{}) (bool, error)
+	ReplaceAssociation(ctx context.Context, entity *T, association string, values ...interface{}) (bool, error)
+	DeleteAssociation(ctx context.Context, entity *T, association string, values ...interface{}) (bool, error)
+
 	// Batch Operations
 	CreateBatch(ctx context.Context, entities []*T) error
 	UpdateBatch(ctx context.Context, entities []*T) error
+	// CreateBatchIgnore skips duplicate keys and returns the number of rows inserted
+	CreateBatchIgnore(ctx context.Context, entities []*T) (int64, error)
+	// CreateWithConflict passes a custom ON CONFLICT clause (e.g. DoUpdates with gorm.Expr)
+	// to the insert and reports whether a row was inserted or updated
+	CreateWithConflict(ctx context.Context, entity *T, onConflict clause.OnConflict) (bool, error)
+	// CreateBatchWithConflict returns the affected rows (1 per insert, 2 per update)
+	CreateBatchWithConflict(ctx context.Context, entities []*T, onConflict clause.OnConflict) (int64, error)
+
+	// Transactions
+	// fn receives a repository bound to the transaction; reads bypass the cache and
+	// invalidation runs after commit. An optional isolation level overrides the default.
+	WithTransaction(ctx context.Context, fn func(tx Repository[T]) error, isolation ...sql.IsolationLevel) error
+	// WithReadTransaction runs fn in a read-only snapshot transaction, bypassing the cache
+	WithReadTransaction(ctx context.Context, fn func(tx Repository[T]) error) error
+
+	// Change Events
+	// Subscribe registers an asynchronous handler called after each committed write;
+	// events that do not fit the bounded queue are dropped and counted
+	Subscribe(handler func(ChangeEvent))
+	DroppedChangeEvents() uint64
+
+	// Write-Behind (Flush is a no-op unless the cache strategy is write_behind)
+	// Flush writes queued writes and returns their errors; Close drains the queue and
+	// pending change events on shutdown
+	Flush(ctx context.Context) error
+	Close() error
 
 	// Cache Management
 	InvalidateCache(ctx context.Context) error
 	WarmCache(ctx context.Context) error
+	// Refresh re-reads a record from the database and overwrites its FindByID entry
+	Refresh(ctx context.Context, id interface{}) (*T, error)
+	// RefreshMany refreshes many records with IN queries, clearing the entries of missing ids
+	RefreshMany(ctx context.Context, ids []interface{}) error
+	// CacheInfo and PurgeOperation inspect and purge this table's keys by operation
+	CacheInfo(ctx context.Context) (CacheReport, error)
+	PurgeOperation(ctx context.Context, operation string) error
+	// LastInvalidation reports when and by what this table's caches were last wiped
+	LastInvalidation(ctx context.Context) (InvalidationInfo, error)
+	// AuditCache compares a sample of cached FindByID entries with the database,
+	// repairing stale entries only with an explicit AuditRepair mode
+	AuditCache(ctx context.Context, sampleSize int, repair ...AuditRepair) (AuditReport, error)
+	// SchemaVersion returns the schema version segment of cache keys (pinned or computed)
+	SchemaVersion() string
+	// CacheKeyForID and CacheKeyForQuery return the cache keys this repository uses for
+	// FindByID and for a query operation (see the package functions of the same name)
+	CacheKeyForID(id interface{}) string
+	CacheKeyForQuery(operation string, query interface{}, args ...interface{}) string
+	// CacheMetrics returns the cache metrics snapshot and whether caching is enabled
+	CacheMetrics() (redis.MetricsSnapshot, bool)
+	// QueryMetrics returns the database query latency metrics of this repository's table
+	QueryMetrics() []QueryStats
+	// OutboxStats returns the outbox relay metrics and whether the outbox is enabled
+	OutboxStats() (OutboxStats, bool)
+	// CacheVerificationStats returns the verification counters and whether verification
+	// is enabled (see WithCacheVerification)
+	CacheVerificationStats() (VerificationStats, bool)
+	// RewarmStats returns the re-warming counters and whether re-warming is enabled
+	// (see WithRewarm)
+	RewarmStats() (RewarmStats, bool)
+}
+
+// CreateResult is the outcome of CreateWithResult
+type CreateResult struct {
+	RowsAffected     int64       // Rows inserted (0 if the write was queued)
+	PrimaryKey       interface{} // Resolved primary key value (auto-increment or generated)
+	CacheInvalidated bool        // Related caches were invalidated (false in a transaction, where it runs on commit)
+	Queued           bool        // Write-behind: cached and queued, not yet written to the database
 }
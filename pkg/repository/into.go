@@ -0,0 +1,16 @@
+package repository
+
+// resetInto empties a destination slice, keeping its capacity
+// Elements are zeroed as decoders write into reused elements field by field.
+func resetInto[T any](dest *[]T) {
+	clear((*dest)[:cap(*dest)])
+	*dest = (*dest)[:0]
+}
+
+// emptyInto returns the empty result of an Into read: *dest emptied, or []T{} when nil
+func emptyInto[T any](dest *[]T) []T {
+	if *dest == nil {
+		return []T{}
+	}
+	return (*dest)[:0]
+}
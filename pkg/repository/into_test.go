@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+)
+
+// cacheUserList caches FindWhere("id > ?", 0) over n users and returns them
+func cacheUserList(t testing.TB, env *testEnv, repo *GenericRepository[testUser], n int) []testUser {
+	t.Helper()
+	users := make([]testUser, n)
+	for i := range users {
+		users[i] = testUser{ID: uint(i + 1), Name: fmt.Sprintf("user-%d", i+1), Email: fmt.Sprintf("u%d@example.com", i+1)}
+	}
+	env.mock.ExpectQuery("SELECT \\* FROM `users` WHERE id > \\?").WillReturnRows(userRows(users...))
+	if _, _, stored, err := repo.FindWhere(context.Background(), "id > ?", 0); err != nil || !stored {
+		t.Fatalf("FindWhere = (stored %v, %v), want a cached result", stored, err)
+	}
+	return users
+}
+
+func TestFindWhereIntoReusesCallerSlice(t *testing.T) {
+	env := newTestEnv(t)
+	repo := newTestRepository[testUser](env, WithOrderingPolicy(OrderingIgnore))
+	ctx := context.Background()
+	users := cacheUserList(t, env, repo, 200)
+
+	// Same rows and cache semantics as FindWhere, in the caller's backing array
+	dest := make([]testUser, 0, 300)
+	dest = append(dest, testUser{ID: 999, Name: "previous"})
+	backing := &dest[:1][0]
+	hit, _, err := repo.FindWhereInto(ctx, &dest, "id > ?", 0)
+	if err != nil || !hit || !slices.Equal(dest, users) {
+		t.Fatalf("FindWhereInto = (%d rows, hit %v, %v), want the 200 cached users", len(dest), hit, err)
+	}
+	if &dest[0] != backing {
+		t.Errorf("FindWhereInto allocated a new slice although the caller's had room")
+	}
+
+	value := testing.AllocsPerRun(20, func() {
+		_, _, _, _ = repo.FindWhere(ctx, "id > ?", 0)
+	})
+	into := testing.AllocsPerRun(20, func() {
+		_, _, _ = repo.FindWhereInto(ctx, &dest, "id > ?", 0)
+	})
+	if into >= value {
+		t.Errorf("allocations per cache hit: FindWhereInto %.0f, FindWhere %.0f, want fewer with a reused slice", into, value)
+	}
+	env.verify()
+}
@@ -0,0 +1,260 @@
+package repository
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	defaultInvalidationTimeout       = 5 * time.Second        // Deadline of an invalidation after a write
+	defaultInvalidationRetryQueue    = 1024                   // Incomplete invalidations waiting per repository
+	defaultInvalidationRetryAttempts = 5                      // Retries before an invalidation is dropped
+	defaultInvalidationRetryInterval = 100 * time.Millisecond // Delay of the first retry, doubled after each failure
+)
+
+// invalidationContext returns the context of an invalidation following a write: the
+// values of ctx without its cancellation and deadline, bounded by Invalidation.Timeout
+//
+// Invalidating after a write is a chain of Redis commands: the table wipe, the FindByID and
+// exists entries, dependency sets, key patterns and related entities. Run under the
+// write's context, a query timeout firing between them would abandon the rest and leave
+// part of the cache stale without any signal. Instead:
+//   - invalidation runs under invalidationContext: the values of the write's context
+//     without its cancellation and deadline, bounded by Invalidation.Timeout (default 5s)
+//   - a write whose invalidation failed or timed out returns cacheInvalidated == false,
+//     counts as an IncompleteInvalidation and is queued for the repository's retry
+//     worker, which repeats the invalidation in full (it is idempotent) with exponential
+//     backoff (Invalidation.RetryInterval, RetryAttempts)
+//   - invalidations not fitting the queue (Invalidation.RetryQueueSize) or still failing
+//     after RetryAttempts are dropped with a log line (DroppedInvalidations); Close
+//     retries what is still queued once
+//   - the invalidation callbacks (InvalidationPlugin) retry the writes made through
+//     *gorm.DB the same way, with a retrier of their own
+//
+// Queued invalidations are retried in the order they become due, so a table wipe in its
+// first backoff never waits behind an entity in its last. The worker starts with the
+// first incomplete invalidation and exits once none is queued, so an idle retrier holds
+// no goroutine, whether or not Close is called. Related entities, invalidation records
+// and re-warming remain best effort and do not make an invalidation incomplete. With
+// write-behind, a retry flushes the queued writes first, as its wipe would drop their
+// cached values.
+func invalidationContext(ctx context.Context, cache *cacheBackend) (context.Context, context.CancelFunc) {
+	timeout := cache.Config().Invalidation.Timeout
+	if timeout <= 0 {
+		timeout = defaultInvalidationTimeout
+	}
+	return context.WithTimeout(context.WithoutCancel(ctx), timeout)
+}
+
+// pendingInvalidation is an incomplete invalidation waiting for a retry
+type pendingInvalidation struct {
+	table    string                          // Invalidated table, for logs ("app.users")
+	target   string                          // What of it, for logs ("entity 7 after update")
+	run      func(ctx context.Context) error // Repeats the invalidation in full
+	attempts int
+	due      time.Time
+}
+
+// pendingQueue is a min-heap of pending invalidations by due time
+type pendingQueue []*pendingInvalidation
+
+func (q pendingQueue) Len() int           { return len(q) }
+func (q pendingQueue) Less(i, j int) bool { return q[i].due.Before(q[j].due) }
+func (q pendingQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+func (q *pendingQueue) Push(x any)        { *q = append(*q, x.(*pendingInvalidation)) }
+func (q *pendingQueue) Pop() any {
+	old := *q
+	item := old[len(old)-1]
+	old[len(old)-1] = nil
+	*q = old[:len(old)-1]
+	return item
+}
+
+// invalidationRetrier retries incomplete invalidations in the background; a repository
+// shares its retrier by pointer with derived repositories
+type invalidationRetrier struct {
+	cache    *cacheBackend
+	size     int
+	attempts int
+	interval time.Duration
+
+	mu      sync.Mutex
+	pending pendingQueue
+	closed  bool
+	running bool          // A worker is running
+	wake    chan struct{} // Wakes the worker for an earlier item or close
+	stopped chan struct{} // Closed when the running worker exits
+}
+
+// newInvalidationRetrier creates a retrier over cache; its worker starts on demand
+func newInvalidationRetrier(cache *cacheBackend) *invalidationRetrier {
+	config := cache.Config().Invalidation
+	q := &invalidationRetrier{
+		cache:    cache,
+		size:     config.RetryQueueSize,
+		attempts: config.RetryAttempts,
+		interval: config.RetryInterval,
+		wake:     make(chan struct{}, 1),
+	}
+	if q.size <= 0 {
+		q.size = defaultInvalidationRetryQueue
+	}
+	if q.attempts <= 0 {
+		q.attempts = defaultInvalidationRetryAttempts
+	}
+	if q.interval <= 0 {
+		q.interval = defaultInvalidationRetryInterval
+	}
+	return q
+}
+
+// retryInvalidation records an incomplete invalidation and queues it for a retry
+// entity is nil for a table wipe.
+func (r *GenericRepository[T]) retryInvalidation(operation ChangeOperation, entity *T, err error) {
+	item := &pendingInvalidation{
+		table:  r.dbName + "." + r.tableName,
+		target: fmt.Sprintf("table after %s", operation),
+	}
+	if entity == nil {
+		item.run = func(ctx context.Context) error { return r.wipeTableCaches(ctx, operation) }
+	} else {
+		item.target = fmt.Sprintf("entity %v after %s", r.primaryKeyValue(*entity), operation)
+		item.run = func(ctx context.Context) error {
+			// Write-behind: wiping would drop the cached values of queued writes
+			if r.writeBehind != nil {
+				flushCtx, cancel := invalidationContext(ctx, r.redis)
+				_ = r.awaitQueuedWrites(flushCtx)
+				cancel()
+			}
+			return r.invalidateEntityCaches(ctx, operation, *entity)
+		}
+	}
+	r.invalidationRetry.add(item, err)
+}
+
+// add records an incomplete invalidation and queues it, or drops it if the queue is
+// full or closed (q may be nil)
+func (q *invalidationRetrier) add(item *pendingInvalidation, err error) {
+	if q == nil {
+		return
+	}
+	if q.cache.manager != nil {
+		q.cache.manager.RecordIncompleteInvalidation()
+	}
+	if !q.enqueue(item) {
+		q.drop(item, err)
+	}
+}
+
+// drop gives up on an incomplete invalidation
+func (q *invalidationRetrier) drop(item *pendingInvalidation, err error) {
+	if q.cache.manager != nil {
+		q.cache.manager.RecordDroppedInvalidation()
+	}
+	q.cache.Logger().Printf("sql4go: dropped incomplete cache invalidation of %s (%s, %d retries): %v",
+		item.table, item.target, item.attempts, err)
+}
+
+// enqueue queues an invalidation for its next retry, starting the worker if needed;
+// returns false if the queue is full or closed
+func (q *invalidationRetrier) enqueue(item *pendingInvalidation) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed || len(q.pending) >= q.size {
+		return false
+	}
+	item.due = time.Now().Add(q.interval << item.attempts)
+	heap.Push(&q.pending, item)
+
+	if !q.running {
+		q.running = true
+		q.stopped = make(chan struct{})
+		go q.run(q.stopped)
+		return true
+	}
+	select {
+	case q.wake <- struct{}{}: // The item may be due before the one the worker waits for
+	default:
+	}
+	return true
+}
+
+// run retries queued invalidations as they become due; it exits once none is queued,
+// or after retrying the queued ones once on close
+func (q *invalidationRetrier) run(stopped chan struct{}) {
+	defer close(stopped)
+
+	for {
+		q.mu.Lock()
+		if q.closed {
+			pending := q.pending
+			q.pending = nil
+			q.running = false
+			q.mu.Unlock()
+			for _, item := range pending {
+				q.retry(item, true)
+			}
+			return
+		}
+		if len(q.pending) == 0 {
+			q.running = false
+			q.mu.Unlock()
+			return
+		}
+		wait := time.Until(q.pending[0].due)
+		if wait <= 0 {
+			item := heap.Pop(&q.pending).(*pendingInvalidation)
+			q.mu.Unlock()
+			q.retry(item, false)
+			continue
+		}
+		q.mu.Unlock()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-q.wake:
+			timer.Stop()
+		}
+	}
+}
+
+// retry repeats an invalidation, requeueing it on failure unless final
+func (q *invalidationRetrier) retry(item *pendingInvalidation, final bool) {
+	err := item.run(context.Background())
+	if err == nil {
+		if q.cache.manager != nil {
+			q.cache.manager.RecordRetriedInvalidation()
+		}
+		return
+	}
+
+	item.attempts++
+	if final || item.attempts >= q.attempts || !q.enqueue(item) {
+		q.drop(item, err)
+	}
+}
+
+// close stops the worker after retrying the queued invalidations once
+func (q *invalidationRetrier) close() {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return
+	}
+	q.closed = true
+	running, stopped := q.running, q.stopped
+	q.mu.Unlock()
+
+	if running {
+		select {
+		case q.wake <- struct{}{}:
+		default:
+		}
+		<-stopped
+	}
+}
@@ -0,0 +1,221 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ammar0144/sql4go/pkg/redis"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"gorm.io/gorm"
+)
+
+// retryTestConfig retries incomplete invalidations quickly
+func retryTestConfig(c *redis.Config) {
+	c.Invalidation.RetryInterval = 10 * time.Millisecond
+	c.Invalidation.Timeout = time.Second
+}
+
+// cacheUser caches user 1 under its FindByID key and returns the key
+func cacheUser(t *testing.T, env *testEnv, repo *GenericRepository[testUser], user testUser) string {
+	t.Helper()
+	env.mock.ExpectQuery("SELECT \\* FROM `users`").WillReturnRows(userRows(user))
+	if _, _, stored, err := repo.FindByID(context.Background(), user.ID); err != nil || !stored {
+		t.Fatalf("FindByID = (stored %v, %v), want a stored miss", stored, err)
+	}
+	key := repo.entityCacheKey(user.ID)
+	if !env.mr.Exists(key) {
+		t.Fatalf("key %s not cached", key)
+	}
+	return key
+}
+
+// cancelAfterUpdate cancels the write context as soon as GORM has executed an update,
+// i.e. after the database committed it and before the cache is invalidated
+func cancelAfterUpdate(t *testing.T, env *testEnv, cancel context.CancelFunc) {
+	t.Helper()
+	if err := env.gorm.Callback().Update().After("gorm:update").Before("sql4go:invalidate_update").
+		Register("test:cancel", func(*gorm.DB) { cancel() }); err != nil {
+		t.Fatalf("register callback: %v", err)
+	}
+}
+
+// awaitKeyDeleted polls until key is gone from miniredis
+func awaitKeyDeleted(t *testing.T, env *testEnv, key string) {
+	t.Helper()
+	for deadline := time.Now().Add(2 * time.Second); env.mr.Exists(key); {
+		if time.Now().After(deadline) {
+			t.Fatalf("key %s still cached", key)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestCancelAfterCommitStillInvalidates(t *testing.T) {
+	env := newTestEnv(t)
+	repo := newTestRepository[testUser](env)
+	user := testUser{ID: 1, Name: "ann", Email: "a@x"}
+	key := cacheUser(t, env, repo, user)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cancelAfterUpdate(t, env, cancel)
+
+	user.Name = "anna"
+	env.expectUniqueValues(user)
+	env.mock.ExpectExec("UPDATE `users` SET").WillReturnResult(sqlmock.NewResult(0, 1))
+	cacheInvalidated, err := repo.Update(ctx, &user)
+	if err != nil || !cacheInvalidated {
+		t.Fatalf("Update = (%v, %v), want the committed write invalidated", cacheInvalidated, err)
+	}
+	if ctx.Err() == nil {
+		t.Fatal("write context not cancelled after the commit")
+	}
+	if env.mr.Exists(key) {
+		t.Errorf("key %s still cached after the cancelled write", key)
+	}
+	env.verify()
+}
+
+func TestInvalidationPluginCancelAfterCommitStillInvalidates(t *testing.T) {
+	env := newTestEnv(t)
+	repo := newTestRepository[testUser](env)
+	user := testUser{ID: 1, Name: "ann", Email: "a@x"}
+	key := cacheUser(t, env, repo, user)
+
+	env.expectDatabaseName()
+	if err := RegisterInvalidationCallbacks(env.gorm, env.cache); err != nil {
+		t.Fatalf("RegisterInvalidationCallbacks: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cancelAfterUpdate(t, env, cancel)
+
+	user.Name = "anna"
+	env.mock.ExpectExec("UPDATE `users` SET").WillReturnResult(sqlmock.NewResult(0, 1))
+	if err := env.gorm.WithContext(ctx).Save(&user).Error; err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if ctx.Err() == nil {
+		t.Fatal("write context not cancelled after the commit")
+	}
+	if env.mr.Exists(key) {
+		t.Errorf("key %s still cached after the cancelled write", key)
+	}
+	env.verify()
+}
+
+func TestIncompleteInvalidationIsRetried(t *testing.T) {
+	env := newTestEnv(t, retryTestConfig)
+	repo := newTestRepository[testUser](env)
+	user := testUser{ID: 1, Name: "ann", Email: "a@x"}
+	key := cacheUser(t, env, repo, user)
+
+	// Redis fails for the write's invalidation and recovers before the first retry
+	user.Name = "anna"
+	env.expectUniqueValues(user)
+	env.mock.ExpectExec("UPDATE `users` SET").WillReturnResult(sqlmock.NewResult(0, 1))
+	env.mr.SetError("LOADING")
+	cacheInvalidated, err := repo.Update(context.Background(), &user)
+	env.mr.SetError("")
+	if err != nil || cacheInvalidated {
+		t.Fatalf("Update = (%v, %v), want an incomplete invalidation", cacheInvalidated, err)
+	}
+
+	awaitKeyDeleted(t, env, key)
+	metrics := env.cache.GetMetrics()
+	if metrics.IncompleteInvalidations != 1 || metrics.DroppedInvalidations != 0 {
+		t.Errorf("metrics = %d incomplete, %d dropped, want 1 and 0", metrics.IncompleteInvalidations, metrics.DroppedInvalidations)
+	}
+	env.verify()
+}
+
+func TestInvalidationPluginRetriesIncompleteInvalidation(t *testing.T) {
+	env := newTestEnv(t, retryTestConfig)
+	repo := newTestRepository[testUser](env)
+	user := testUser{ID: 1, Name: "ann", Email: "a@x"}
+	key := cacheUser(t, env, repo, user)
+
+	env.expectDatabaseName()
+	if err := RegisterInvalidationCallbacks(env.gorm, env.cache); err != nil {
+		t.Fatalf("RegisterInvalidationCallbacks: %v", err)
+	}
+
+	user.Name = "anna"
+	env.mock.ExpectExec("UPDATE `users` SET").WillReturnResult(sqlmock.NewResult(0, 1))
+	env.mr.SetError("LOADING")
+	err := env.gorm.Save(&user).Error
+	env.mr.SetError("")
+	if err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	awaitKeyDeleted(t, env, key)
+	if metrics := env.cache.GetMetrics(); metrics.IncompleteInvalidations != 1 {
+		t.Errorf("IncompleteInvalidations = %d, want 1", metrics.IncompleteInvalidations)
+	}
+	env.verify()
+}
+
+func TestInvalidationRetrierRunsByDueTime(t *testing.T) {
+	env := newTestEnv(t, func(c *redis.Config) { c.Invalidation.RetryInterval = 10 * time.Millisecond })
+	q := newInvalidationRetrier(newCacheBackend(managerCache(env.cache)))
+
+	var mu sync.Mutex
+	var order []string
+	done := make(chan struct{}, 2)
+	item := func(target string, attempts int) *pendingInvalidation {
+		return &pendingInvalidation{table: "app.users", target: target, attempts: attempts, run: func(context.Context) error {
+			mu.Lock()
+			order = append(order, target)
+			mu.Unlock()
+			done <- struct{}{}
+			return nil
+		}}
+	}
+
+	// Queued first but due after 80ms; the later one is due after 10ms
+	q.add(item("late", 3), errors.New("boom"))
+	q.add(item("early", 0), errors.New("boom"))
+	for range 2 {
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("queued invalidations not retried")
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(order) != 2 || order[0] != "early" || order[1] != "late" {
+		t.Errorf("retry order = %q, want early before late", order)
+	}
+}
+
+func TestInvalidationRetrierWorkerExitsWhenIdle(t *testing.T) {
+	env := newTestEnv(t, func(c *redis.Config) { c.Invalidation.RetryInterval = time.Millisecond })
+	q := newInvalidationRetrier(newCacheBackend(managerCache(env.cache)))
+
+	// Never closed: the worker must not outlive the queued invalidation
+	q.add(&pendingInvalidation{table: "app.users", target: "table after update", run: func(context.Context) error { return nil }}, errors.New("boom"))
+	q.mu.Lock()
+	stopped := q.stopped
+	q.mu.Unlock()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("worker still running with an empty queue")
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.running || len(q.pending) != 0 {
+		t.Errorf("retrier running %v with %d pending, want an idle retrier", q.running, len(q.pending))
+	}
+	if metrics := env.cache.GetMetrics(); metrics.RetriedInvalidations != 1 {
+		t.Errorf("RetriedInvalidations = %d, want 1", metrics.RetriedInvalidations)
+	}
+}
@@ -0,0 +1,38 @@
+package repository
+
+import "github.com/cespare/xxhash/v2"
+
+// KeyHasher hashes the text identifying a query into its cache key segment; it must be
+// deterministic and safe for concurrent use
+//
+// Query conditions enter cache keys as a 64-bit hash of their text (see queryKeySuffix),
+// by default the unseeded xxhash, so two applications sharing a Redis compute the same
+// key for the same query and anyone can predict it. WithKeyHasher replaces the hash for
+// the keys only this repository computes:
+//   - query suffixes (FindWhere, Count, First, FindByUnique, normalized ID lists, ...),
+//     FindWhereIn value lists and the suffix of chained repositories
+//   - not hashed ids (too long or not keySafe): those keys are also computed by the GORM
+//     invalidation callbacks and association writes, which know no repository options
+//
+// Changing the hasher moves the affected entries to fresh keys, like a schema version
+// change. The package CacheKeyForQuery assumes the default hash; use the repository
+// method instead.
+type KeyHasher func(text string) uint64
+
+// SeededKeyHasher returns an xxhash KeyHasher with the given seed, so keys differ from
+// those of applications using another seed (or none)
+func SeededKeyHasher(seed uint64) KeyHasher {
+	return func(text string) uint64 {
+		digest := xxhash.NewWithSeed(seed)
+		_, _ = digest.WriteString(text)
+		return digest.Sum64()
+	}
+}
+
+// sum hashes text with the hasher, or with the unseeded xxhash if none is set
+func (h KeyHasher) sum(text string) uint64 {
+	if h == nil {
+		return xxhash.Sum64String(text)
+	}
+	return h(text)
+}
@@ -0,0 +1,335 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ammar0144/sql4go/pkg/redis"
+)
+
+// memorySweepInterval is the number of writes between sweeps of expired entries
+const memorySweepInterval = 1024
+
+// memoryEntry is a cached value and its expiry (zero: none)
+type memoryEntry struct {
+	data      []byte // nil for a null marker
+	expiresAt time.Time
+}
+
+func (e memoryEntry) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && !now.Before(e.expiresAt)
+}
+
+// MemoryCache is a Cache keeping values in a map of the process, for local development
+// and tests without a Redis server (see NewMemoryCache). FindByID, FindAll, FindWhere,
+// null results and dependency invalidation behave as with Redis; the features only Redis
+// provides are skipped (see Cache).
+//
+// Values are encoded as JSON, so a read returns a copy. A TTL is checked when the key
+// is read, and expired entries are swept every memorySweepInterval writes; a TTL <= 0
+// keeps the key until it is invalidated. The data is not shared between processes and
+// its size is not bounded. It is meant for development and tests, not production.
+type MemoryCache struct {
+	config *redis.Config
+	now    func() time.Time // Clock of TTLs; replaced in tests
+
+	mu           sync.Mutex
+	entries      map[string]memoryEntry
+	dependencies map[string]map[string]struct{} // "entityType:id" -> dependent keys
+	writes       int                            // Writes since the last sweep
+}
+
+// NewMemoryCache creates an in-memory cache using the TTLs, namespace and invalidation
+// patterns of config; nil uses redis.DefaultConfig(). Connection settings are ignored.
+func NewMemoryCache(config *redis.Config) (*MemoryCache, error) {
+	if config == nil {
+		config = redis.DefaultConfig()
+	}
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid cache config: %w", err)
+	}
+
+	return &MemoryCache{
+		config:       config,
+		now:          time.Now,
+		entries:      make(map[string]memoryEntry),
+		dependencies: make(map[string]map[string]struct{}),
+	}, nil
+}
+
+// NewCache creates the cache selected by config.Backend: a *redis.Manager for redis
+// (the default) or a *MemoryCache for memory. Pass it to NewGenericRepositoryWithCache.
+func NewCache(config *redis.Config) (Cache, error) {
+	if config != nil && config.Backend == redis.BackendMemory {
+		return NewMemoryCache(config)
+	}
+
+	manager, err := redis.NewManager(config)
+	if err != nil {
+		return nil, err
+	}
+	return manager, nil
+}
+
+var _ Cache = (*MemoryCache)(nil)
+
+// Config returns the configuration the cache was created with
+func (c *MemoryCache) Config() *redis.Config {
+	return c.config
+}
+
+// GetValue reads a value into target; redis.ErrKeyNotFound on a miss or expired key,
+// redis.ErrNullValue for a null marker
+func (c *MemoryCache) GetValue(ctx context.Context, key string, target interface{}) error {
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	if ok && entry.expired(c.now()) {
+		delete(c.entries, key)
+		ok = false
+	}
+	c.mu.Unlock()
+
+	if !ok {
+		return redis.ErrKeyNotFound
+	}
+	if entry.data == nil {
+		return redis.ErrNullValue
+	}
+	if err := json.Unmarshal(entry.data, target); err != nil {
+		return fmt.Errorf("%w: %w: %v", redis.ErrKeyNotFound, redis.ErrSerializationFailed, err)
+	}
+	return nil
+}
+
+// GetLargeValue reads a value stored by SetLargeValueWithDependenciesTTL; large values
+// are stored as plain ones
+func (c *MemoryCache) GetLargeValue(ctx context.Context, key string, target interface{}) error {
+	return c.GetValue(ctx, key, target)
+}
+
+// SetValueWithTTL stores a value for ttl
+func (c *MemoryCache) SetValueWithTTL(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	return c.SetValueWithDependenciesTTL(ctx, key, value, nil, ttl)
+}
+
+// SetValueWithDependenciesTTL stores a value for ttl and records that it depends on the
+// given entities (entityType -> ids), so InvalidateEntityDependencies removes it
+func (c *MemoryCache) SetValueWithDependenciesTTL(ctx context.Context, cacheKey string, value interface{}, dependencies map[string][]interface{}, ttl time.Duration) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("%w: %v", redis.ErrSerializationFailed, err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.store(cacheKey, data, ttl)
+	for entityType, ids := range dependencies {
+		for _, id := range ids {
+			dependencyKey := memoryDependencyKey(entityType, id)
+			if c.dependencies[dependencyKey] == nil {
+				c.dependencies[dependencyKey] = make(map[string]struct{})
+			}
+			c.dependencies[dependencyKey][cacheKey] = struct{}{}
+		}
+	}
+	return nil
+}
+
+// SetLargeValueWithDependenciesTTL stores a large value as a plain one
+func (c *MemoryCache) SetLargeValueWithDependenciesTTL(ctx context.Context, cacheKey string, value interface{}, dependencies map[string][]interface{}, ttl time.Duration) error {
+	return c.SetValueWithDependenciesTTL(ctx, cacheKey, value, dependencies, ttl)
+}
+
+// SetNull caches an empty or not-found result for NullCacheTTL (DefaultTTL if unset)
+func (c *MemoryCache) SetNull(ctx context.Context, key string) error {
+	ttl := c.config.NullCacheTTL
+	if ttl <= 0 {
+		ttl = c.config.DefaultTTL
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.store(key, nil, ttl)
+	return nil
+}
+
+// store sets an entry and sweeps expired ones every memorySweepInterval writes
+// c.mu must be held
+func (c *MemoryCache) store(key string, data []byte, ttl time.Duration) {
+	now := c.now()
+	entry := memoryEntry{data: data}
+	if ttl > 0 {
+		entry.expiresAt = now.Add(ttl)
+	}
+	c.entries[key] = entry
+
+	c.writes++
+	if c.writes >= memorySweepInterval {
+		c.writes = 0
+		c.sweep(now)
+	}
+}
+
+// sweep removes expired entries and the dependencies of keys no longer cached
+// c.mu must be held
+func (c *MemoryCache) sweep(now time.Time) {
+	for key, entry := range c.entries {
+		if entry.expired(now) {
+			delete(c.entries, key)
+		}
+	}
+	for dependencyKey, keys := range c.dependencies {
+		for key := range keys {
+			if _, ok := c.entries[key]; !ok {
+				delete(keys, key)
+			}
+		}
+		if len(keys) == 0 {
+			delete(c.dependencies, dependencyKey)
+		}
+	}
+}
+
+// DeleteKeys removes keys
+func (c *MemoryCache) DeleteKeys(ctx context.Context, keys []string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, key := range keys {
+		delete(c.entries, key)
+	}
+	return nil
+}
+
+// DeleteLarge removes a key stored by SetLargeValueWithDependenciesTTL
+func (c *MemoryCache) DeleteLarge(ctx context.Context, key string) error {
+	return c.DeleteKeys(ctx, []string{key})
+}
+
+// InvalidatePattern removes the keys matching a glob pattern (Redis MATCH syntax)
+func (c *MemoryCache) InvalidatePattern(ctx context.Context, pattern string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key := range c.entries {
+		if matchGlob(pattern, key) {
+			delete(c.entries, key)
+		}
+	}
+	return nil
+}
+
+// InvalidateEntityDependencies removes the keys depending on an entity
+func (c *MemoryCache) InvalidateEntityDependencies(ctx context.Context, entityType string, entityID interface{}) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	dependencyKey := memoryDependencyKey(entityType, entityID)
+	for key := range c.dependencies[dependencyKey] {
+		delete(c.entries, key)
+	}
+	delete(c.dependencies, dependencyKey)
+	return nil
+}
+
+// InvalidateKeyPatterns removes the keys matching the configured invalidation patterns
+// of an entity (Invalidation.KeyPatterns, see redis.Config.InvalidationPatterns)
+func (c *MemoryCache) InvalidateKeyPatterns(ctx context.Context, dbName, entityType string, entityID interface{}) error {
+	for _, pattern := range c.config.InvalidationPatterns(dbName, entityType, entityID) {
+		if err := c.InvalidatePattern(ctx, pattern); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// memoryDependencyKey identifies the dependencies of an entity
+func memoryDependencyKey(entityType string, entityID interface{}) string {
+	return fmt.Sprintf("%s:%v", entityType, entityID)
+}
+
+// matchGlob reports whether key matches a Redis glob pattern: * and ? wildcards,
+// [abc], [^abc] and [a-z] classes, and \ escapes
+func matchGlob(pattern, key string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for len(pattern) > 1 && pattern[1] == '*' {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 1 {
+				return true
+			}
+			for i := 0; i <= len(key); i++ {
+				if matchGlob(pattern[1:], key[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(key) == 0 {
+				return false
+			}
+		case '[':
+			if len(key) == 0 {
+				return false
+			}
+			end, matched := matchClass(pattern, key[0])
+			if !matched {
+				return false
+			}
+			pattern = pattern[end:]
+			key = key[1:]
+			continue
+		case '\\':
+			if len(pattern) > 1 {
+				pattern = pattern[1:]
+			}
+			fallthrough
+		default:
+			if len(key) == 0 || pattern[0] != key[0] {
+				return false
+			}
+		}
+		pattern = pattern[1:]
+		key = key[1:]
+	}
+	return len(key) == 0
+}
+
+// matchClass matches b against the class at the start of pattern ("[...]") and
+// returns the length of the class; an unterminated class runs to the end of pattern
+func matchClass(pattern string, b byte) (int, bool) {
+	i := 1
+	negate := i < len(pattern) && pattern[i] == '^'
+	if negate {
+		i++
+	}
+
+	matched := false
+	for ; i < len(pattern) && pattern[i] != ']'; i++ {
+		switch {
+		case pattern[i] == '\\' && i+1 < len(pattern):
+			i++
+			matched = matched || pattern[i] == b
+		case i+2 < len(pattern) && pattern[i+1] == '-' && pattern[i+2] != ']':
+			low, high := pattern[i], pattern[i+2]
+			if low > high {
+				low, high = high, low
+			}
+			matched = matched || (low <= b && b <= high)
+			i += 2
+		default:
+			matched = matched || pattern[i] == b
+		}
+	}
+	if i < len(pattern) {
+		i++ // Closing bracket
+	}
+	return i, matched != negate
+}
@@ -0,0 +1,178 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ammar0144/sql4go/pkg/redis"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// newMemoryCache creates an in-memory cache with a clock the test advances
+func newMemoryCache(t *testing.T) (*MemoryCache, *time.Time) {
+	t.Helper()
+
+	config := redis.DefaultConfig()
+	config.Backend = redis.BackendMemory
+	cache, err := NewCache(config)
+	if err != nil {
+		t.Fatalf("NewCache: %v", err)
+	}
+	memory, ok := cache.(*MemoryCache)
+	if !ok {
+		t.Fatalf("NewCache with the memory backend = %T, want *MemoryCache", cache)
+	}
+
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	memory.now = func() time.Time { return now }
+	return memory, &now
+}
+
+func TestMemoryCacheServesAndInvalidatesReads(t *testing.T) {
+	env := newTestEnv(t)
+	cache, _ := newMemoryCache(t)
+	repo := newFakeCacheRepository(env, cache)
+	ctx := context.Background()
+
+	ann := testUser{ID: 1, Name: "ann", Email: "a@x"}
+	bob := testUser{ID: 2, Name: "bob", Email: "b@x"}
+
+	env.mock.ExpectQuery("SELECT \\* FROM `users` WHERE `users`.`id` = \\?").WillReturnRows(userRows(ann))
+	if _, hit, stored, err := repo.FindByID(ctx, 1); err != nil || hit || !stored {
+		t.Fatalf("first FindByID = (hit %v, stored %v, %v), want a stored miss", hit, stored, err)
+	}
+	env.mock.ExpectQuery("SELECT \\* FROM `users`$").WillReturnRows(userRows(ann, bob))
+	if _, hit, stored, err := repo.FindAll(ctx); err != nil || hit || !stored {
+		t.Fatalf("first FindAll = (hit %v, stored %v, %v), want a stored miss", hit, stored, err)
+	}
+	env.mock.ExpectQuery("SELECT \\* FROM `users` WHERE `users`.`id` = \\?").WillReturnRows(userRows())
+	if user, hit, _, err := repo.FindByID(ctx, 9); err != nil || hit || user != nil {
+		t.Fatalf("FindByID of a missing id = (%v, hit %v, %v), want a miss", user, hit, err)
+	}
+	env.verify()
+
+	// Cached rows, lists and not-found results are served without a query
+	if found, hit, _, err := repo.FindByID(ctx, 1); err != nil || !hit || found.Name != "ann" {
+		t.Fatalf("second FindByID = (%+v, hit %v, %v), want a cache hit", found, hit, err)
+	}
+	if all, hit, _, err := repo.FindAll(ctx); err != nil || !hit || len(all) != 2 {
+		t.Fatalf("second FindAll = (%+v, hit %v, %v), want a cache hit", all, hit, err)
+	}
+	if user, hit, _, err := repo.FindByID(ctx, 9); err != nil || !hit || user != nil {
+		t.Fatalf("cached FindByID of a missing id = (%v, hit %v, %v), want a null hit", user, hit, err)
+	}
+
+	// A write invalidates the entity and the table's lists
+	ann.Name = "anne"
+	env.expectUniqueValues(ann)
+	env.mock.ExpectExec("UPDATE `users` SET").WillReturnResult(sqlmock.NewResult(0, 1))
+	if _, err := repo.Update(ctx, &ann); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	env.mock.ExpectQuery("SELECT \\* FROM `users` WHERE `users`.`id` = \\?").WillReturnRows(userRows(ann))
+	if found, hit, _, err := repo.FindByID(ctx, 1); err != nil || hit || found.Name != "anne" {
+		t.Fatalf("FindByID after Update = (%+v, hit %v, %v), want a database read", found, hit, err)
+	}
+	env.mock.ExpectQuery("SELECT \\* FROM `users`$").WillReturnRows(userRows(ann, bob))
+	if _, hit, _, err := repo.FindAll(ctx); err != nil || hit {
+		t.Fatalf("FindAll after Update = (hit %v, %v), want a database read", hit, err)
+	}
+	env.verify()
+}
+
+func TestMemoryCacheExpiresKeys(t *testing.T) {
+	env := newTestEnv(t)
+	cache, now := newMemoryCache(t)
+	repo := newFakeCacheRepository(env, cache)
+	ctx := context.Background()
+
+	ann := testUser{ID: 1, Name: "ann", Email: "a@x"}
+	env.mock.ExpectQuery("SELECT \\* FROM `users` WHERE `users`.`id` = \\?").WillReturnRows(userRows(ann))
+	if _, _, stored, err := repo.FindByID(ctx, 1); err != nil || !stored {
+		t.Fatalf("FindByID = (stored %v, %v), want a stored miss", stored, err)
+	}
+
+	*now = now.Add(cache.Config().DefaultTTL - time.Second)
+	if _, hit, _, err := repo.FindByID(ctx, 1); err != nil || !hit {
+		t.Fatalf("FindByID before the TTL = (hit %v, %v), want a cache hit", hit, err)
+	}
+
+	*now = now.Add(time.Second)
+	env.mock.ExpectQuery("SELECT \\* FROM `users` WHERE `users`.`id` = \\?").WillReturnRows(userRows(ann))
+	if _, hit, _, err := repo.FindByID(ctx, 1); err != nil || hit {
+		t.Fatalf("FindByID after the TTL = (hit %v, %v), want a database read", hit, err)
+	}
+	env.verify()
+}
+
+func TestMemoryCacheInvalidatesDependentKeys(t *testing.T) {
+	env := newTestEnv(t)
+	cache, _ := newMemoryCache(t)
+	env.expectDatabaseName()
+	repo := NewGenericRepositoryWithCache[testSite](env.dbm, cache).(*GenericRepository[testSite])
+	t.Cleanup(func() { _ = repo.Close() })
+	ctx := context.Background()
+
+	// The site depends on its region through a composite foreign key
+	siteRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "org_id", "region_code"}).AddRow(1, 7, "eu")
+	}
+	env.mock.ExpectQuery("SELECT \\* FROM `sites`").WillReturnRows(siteRows())
+	if _, _, stored, err := repo.First(ctx, "org_id = ?", 7); err != nil || !stored {
+		t.Fatalf("First = (stored %v, %v), want a cached row", stored, err)
+	}
+	if _, hit, _, err := repo.First(ctx, "org_id = ?", 7); err != nil || !hit {
+		t.Fatalf("second First = (hit %v, %v), want a cache hit", hit, err)
+	}
+
+	// Another region leaves it cached, its own region removes it
+	regions := convertStructNameToTableName("testRegion")
+	if err := cache.InvalidateEntityDependencies(ctx, regions, CompositeKey(uint(7), "us")); err != nil {
+		t.Fatalf("InvalidateEntityDependencies: %v", err)
+	}
+	if _, hit, _, err := repo.First(ctx, "org_id = ?", 7); err != nil || !hit {
+		t.Fatalf("First after another region's invalidation = (hit %v, %v), want a cache hit", hit, err)
+	}
+	if err := cache.InvalidateEntityDependencies(ctx, regions, CompositeKey(uint(7), "eu")); err != nil {
+		t.Fatalf("InvalidateEntityDependencies: %v", err)
+	}
+	env.mock.ExpectQuery("SELECT \\* FROM `sites`").WillReturnRows(siteRows())
+	if _, hit, _, err := repo.First(ctx, "org_id = ?", 7); err != nil || hit {
+		t.Fatalf("First after its region's invalidation = (hit %v, %v), want a database read", hit, err)
+	}
+	env.verify()
+}
+
+func TestRedisManagerRejectsMemoryBackend(t *testing.T) {
+	config := redis.DefaultConfig()
+	config.Backend = redis.BackendMemory
+	if _, err := redis.NewManager(config); err == nil {
+		t.Error("NewManager accepted the memory backend, want an error pointing to NewCache")
+	}
+}
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern, key string
+		want         bool
+	}{
+		{"sql4go:app:users:*", "sql4go:app:users:v1:find_by_id:1", true},
+		{"sql4go:app:users:*", "sql4go:app:orders:v1:find_by_id:1", false},
+		{"*find_where:*1*", "sql4go:app:orders:find_where:user_id=1", true},
+		{"key:?", "key:1", true},
+		{"key:?", "key:12", false},
+		{"key:[0-4]", "key:3", true},
+		{"key:[^0-4]", "key:3", false},
+		{"key:[ab]c", "key:bc", true},
+		{"key:\\*", "key:*", true},
+		{"key:\\*", "key:1", false},
+		{"key", "key:1", false},
+	}
+	for _, tt := range tests {
+		if got := matchGlob(tt.pattern, tt.key); got != tt.want {
+			t.Errorf("matchGlob(%q, %q) = %v, want %v", tt.pattern, tt.key, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+)
+
+// idListKey returns the key of the ID list of a normalized query result
+func (r *GenericRepository[T]) idListKey(operation string, query interface{}, args ...interface{}) string {
+	suffix := "ids" + cacheKeySeparator + queryKeySuffix(query, args, r.chainKey(), r.options.KeyHasher)
+	return buildCacheKey(r.keyNamespace, operation, suffix, maxKeyLength(r.redis))
+}
+
+// normalizedCollections reports whether query results are cached as ID lists
+// Rows are stored as FindByID entries, so this requires find_by_id caching.
+func (r *GenericRepository[T]) normalizedCollections() bool {
+	return r.options.NormalizedCollections && r.operationCached("find_by_id")
+}
+
+// getNormalized reads a normalized query result, filling missing rows from the database
+// complete is true if every row came from the cache, stored if filled rows were cached.
+// Returns the error of reading the ID list (ErrKeyNotFound, ErrNullValue, ...) or of the
+// database query filling missing rows.
+//
+// By default a FindWhere result is cached as one serialized slice, so an entity matched
+// by many queries is stored once per query. With WithNormalizedCollections, it is stored as:
+//   - "<ns>:find_where:ids:<hash>" -> the primary keys of the rows, in result order
+//   - "<ns>:find_by_id:<id>"       -> each row, shared with FindByID and FindByIDs
+//
+// Rows are written before the ID list, so a reader of a new list finds its rows. A read
+// fetches the list, then all rows in one MGET; rows that are missing (expired, evicted,
+// invalidated by a write, or unreadable) are queried with IN lists and cached again:
+//   - a row deleted from the database is left out, and the list is deleted so the next
+//     read regenerates it
+//   - a failed MGET is treated as every row missing
+//   - a failed database query falls through to the full FindWhere query
+//
+// The result is a cache hit only if the list and every row came from the cache. Rows are
+// as fresh as FindByID entries: writes delete an entity's entry, and rows with preloaded
+// associations depend on the associated entities like FindWhere results. Writes still
+// wipe the table's lists, as an update can change which rows a query matches.
+func (r *GenericRepository[T]) getNormalized(ctx context.Context, listKey string) (entities []T, complete, stored bool, err error) {
+	var ids []string
+	if err := r.redis.GetLargeValue(ctx, listKey, &ids); err != nil {
+		return nil, false, false, err
+	}
+	if len(ids) == 0 {
+		return []T{}, true, false, nil
+	}
+
+	// All rows in one round trip
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = r.generateCacheKey("find_by_id", id)
+	}
+	cached, err := r.redis.GetManyValues(ctx, keys, func() interface{} { return new(T) })
+	if err != nil {
+		cached = nil // Treat every row as missing
+	}
+
+	byID := make(map[string]T, len(ids))
+	var missing []interface{}
+	for i, id := range ids {
+		if value, ok := cached[keys[i]]; ok {
+			byID[id] = *value.(*T)
+		} else {
+			missing = append(missing, id)
+		}
+	}
+
+	// Fill the missing rows from the database and cache them again
+	if len(missing) > 0 {
+		rows, err := r.findInChunks(ctx, "find_where_fill", r.primaryKey, missing)
+		if err != nil {
+			return nil, false, false, err
+		}
+		for _, row := range rows {
+			byID[fmt.Sprintf("%v", r.primaryKeyValue(row))] = row
+		}
+		stored = len(rows) > 0 && r.setNormalizedRows(ctx, rows)
+	}
+
+	// List order, skipping rows deleted since the list was cached
+	entities = make([]T, 0, len(ids))
+	for _, id := range ids {
+		if entity, ok := byID[id]; ok {
+			entities = append(entities, entity)
+		}
+	}
+	if len(entities) < len(ids) {
+		_ = r.redis.DeleteLarge(ctx, listKey) // Stale list - regenerate on the next read (best effort)
+	}
+	return entities, len(missing) == 0, stored, nil
+}
+
+// setNormalized caches a query result as FindByID entries followed by its ID list
+// Rows that fail to cache are filled from the database on read.
+func (r *GenericRepository[T]) setNormalized(ctx context.Context, operation, listKey string, entities []T) error {
+	r.setNormalizedRows(ctx, entities)
+
+	ids := make([]string, len(entities))
+	for i, entity := range entities {
+		ids[i] = fmt.Sprintf("%v", r.primaryKeyValue(entity))
+	}
+
+	// The list depends on its rows, so deleting or updating one invalidates it
+	dependencies := r.extractDependenciesFromEntities(entities)
+	return r.redis.SetLargeValueWithDependenciesTTL(ctx, listKey, ids, dependencies, r.operationTTL(operation))
+}
+
+// setNormalizedRows caches rows under their FindByID keys, with the dependencies of
+// each row (e.g. preloaded associations); reports whether every row was cached
+func (r *GenericRepository[T]) setNormalizedRows(ctx context.Context, rows []T) bool {
+	stored := true
+	ttl := r.operationTTL("find_by_id")
+	for _, row := range rows {
+		cacheKey := r.generateCacheKey("find_by_id", fmt.Sprintf("%v", r.primaryKeyValue(row)))
+		dependencies := r.extractDependenciesFromEntities([]T{row})
+		if err := r.redis.SetValueWithDependenciesTTL(ctx, cacheKey, row, dependencies, ttl); err != nil {
+			stored = false // Best effort - the row is filled from the database on read
+		}
+	}
+	return stored
+}
@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// cacheNormalizedUsers caches the result of FindWhere("name LIKE ?", "a%") as an ID list
+// over the FindByID entries of ada, alan and ava, and returns the key of the list
+func cacheNormalizedUsers(t *testing.T, env *testEnv, repo *GenericRepository[testUser], users []testUser) string {
+	t.Helper()
+	env.mock.ExpectQuery("SELECT \\* FROM `users` WHERE name LIKE \\?").WillReturnRows(userRows(users...))
+	if _, _, stored, err := repo.FindWhere(context.Background(), "name LIKE ?", "a%"); err != nil || !stored {
+		t.Fatalf("FindWhere = (stored %v, %v), want a cached result", stored, err)
+	}
+	for _, key := range env.cachedKeys() {
+		if strings.Contains(key, ":find_where:ids:") {
+			return key
+		}
+	}
+	t.Fatalf("no ID list among the cached keys %v", env.cachedKeys())
+	return ""
+}
+
+// names returns the names of users, in order
+func names(users []testUser) string {
+	var out []string
+	for _, user := range users {
+		out = append(out, user.Name)
+	}
+	return strings.Join(out, ",")
+}
+
+var normalizedUsers = []testUser{
+	{ID: 3, Name: "ava", Email: "ava@example.com"},
+	{ID: 1, Name: "ada", Email: "ada@example.com"},
+	{ID: 2, Name: "alan", Email: "alan@example.com"},
+}
+
+func TestNormalizedCollectionsServeRowsFromFindByIDEntries(t *testing.T) {
+	env := newTestEnv(t)
+	repo := newTestRepository[testUser](env, WithNormalizedCollections())
+	ctx := context.Background()
+	cacheNormalizedUsers(t, env, repo, normalizedUsers)
+
+	// The rows are shared with FindByID
+	user, hit, _, err := repo.FindByID(ctx, 1)
+	if err != nil || !hit || user.Name != "ada" {
+		t.Errorf("FindByID(1) = (%+v, hit %v, %v), want the row cached by FindWhere", user, hit, err)
+	}
+
+	users, hit, _, err := repo.FindWhere(ctx, "name LIKE ?", "a%")
+	if err != nil || !hit || names(users) != "ava,ada,alan" {
+		t.Errorf("cached FindWhere = (%s, hit %v, %v), want ava,ada,alan from the cache", names(users), hit, err)
+	}
+	env.verify()
+}
+
+func TestNormalizedCollectionsFillMissingRows(t *testing.T) {
+	env := newTestEnv(t)
+	repo := newTestRepository[testUser](env, WithNormalizedCollections())
+	ctx := context.Background()
+	listKey := cacheNormalizedUsers(t, env, repo, normalizedUsers)
+
+	// Row 1 was evicted; row 2 is unreadable, as after a partial MGET failure
+	env.mr.Del(repo.generateCacheKey("find_by_id", "1"))
+	env.mr.Set(repo.generateCacheKey("find_by_id", "2"), "{not json")
+
+	env.mock.ExpectQuery("SELECT \\* FROM `users` WHERE `id` IN \\(\\?,\\?\\)").
+		WithArgs("1", "2").
+		WillReturnRows(userRows(testUser{ID: 2, Name: "alan", Email: "alan@example.com"}, testUser{ID: 1, Name: "ada", Email: "ada@example.com"}))
+	users, hit, stored, err := repo.FindWhere(ctx, "name LIKE ?", "a%")
+	if err != nil || hit || !stored || names(users) != "ava,ada,alan" {
+		t.Errorf("FindWhere with missing rows = (%s, hit %v, stored %v, %v), want ava,ada,alan filled and stored", names(users), hit, stored, err)
+	}
+	env.verify()
+
+	// The filled rows were cached again, the list kept
+	if !env.mr.Exists(listKey) {
+		t.Errorf("ID list %s was deleted although every row still exists", listKey)
+	}
+	users, hit, _, err = repo.FindWhere(ctx, "name LIKE ?", "a%")
+	if err != nil || !hit || names(users) != "ava,ada,alan" {
+		t.Errorf("FindWhere after the fill = (%s, hit %v, %v), want a full cache hit", names(users), hit, err)
+	}
+}
+
+func TestNormalizedCollectionsDropRowsDeletedFromDatabase(t *testing.T) {
+	env := newTestEnv(t)
+	repo := newTestRepository[testUser](env, WithNormalizedCollections())
+	ctx := context.Background()
+	listKey := cacheNormalizedUsers(t, env, repo, normalizedUsers)
+
+	// Row 1 is no longer cached and no longer in the database
+	env.mr.Del(repo.generateCacheKey("find_by_id", "1"))
+	env.mock.ExpectQuery("SELECT \\* FROM `users` WHERE `id` = \\?").WithArgs("1").WillReturnRows(userRows())
+
+	users, hit, _, err := repo.FindWhere(ctx, "name LIKE ?", "a%")
+	if err != nil || hit || names(users) != "ava,alan" {
+		t.Errorf("FindWhere with a deleted row = (%s, hit %v, %v), want ava,alan", names(users), hit, err)
+	}
+	if env.mr.Exists(listKey) {
+		t.Errorf("stale ID list %s was kept", listKey)
+	}
+	env.verify()
+}
+
+func TestNormalizedCollectionsDoNotServeStaleRows(t *testing.T) {
+	env := newTestEnv(t)
+	repo := newTestRepository[testUser](env, WithNormalizedCollections())
+	ctx := context.Background()
+	cacheNormalizedUsers(t, env, repo, normalizedUsers)
+
+	renamed := testUser{ID: 1, Name: "adeline", Email: "ada@example.com"}
+	env.expectUniqueValues(renamed)
+	env.mock.ExpectExec("UPDATE `users`").WillReturnResult(sqlmock.NewResult(0, 1))
+	if _, err := repo.Update(ctx, &renamed); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if env.mr.Exists(repo.generateCacheKey("find_by_id", "1")) {
+		t.Errorf("the updated row's entry survived the Update")
+	}
+
+	// Whether the list survives or not, the updated row comes from the database
+	env.mock.ExpectQuery("SELECT \\* FROM `users` WHERE").
+		WillReturnRows(userRows(normalizedUsers[0], renamed, normalizedUsers[2]))
+	users, _, _, err := repo.FindWhere(ctx, "name LIKE ?", "a%")
+	if err != nil || names(users) != "ava,adeline,alan" {
+		t.Errorf("FindWhere after an Update = (%s, %v), want ava,adeline,alan", names(users), err)
+	}
+	env.verify()
+}
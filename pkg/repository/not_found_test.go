@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"context"
+	"testing"
+)
+
+func TestNotFoundModes(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		opts    []Option
+		wantErr error
+	}{
+		{name: "nil result by default"},
+		{name: "ErrNotFound when enabled", opts: []Option{WithReturnNotFoundError(true)}, wantErr: ErrNotFound},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			repo := newTestRepository[testUser](env, tt.opts...)
+			ctx := context.Background()
+
+			env.mock.ExpectQuery("SELECT \\* FROM `users` WHERE `users`.`id` = \\?").WillReturnRows(userRows())
+			user, hit, _, err := repo.FindByID(ctx, 9)
+			if user != nil || hit || err != tt.wantErr {
+				t.Errorf("FindByID of a missing id = (%v, hit %v, %v), want (nil, miss, %v)", user, hit, err, tt.wantErr)
+			}
+
+			// The not-found is cached and reported the same way
+			user, hit, _, err = repo.FindByID(ctx, 9)
+			if user != nil || !hit || err != tt.wantErr {
+				t.Errorf("cached FindByID of a missing id = (%v, hit %v, %v), want (nil, hit, %v)", user, hit, err, tt.wantErr)
+			}
+
+			env.mock.ExpectQuery("SELECT \\* FROM `users` WHERE name = \\?").WillReturnRows(userRows())
+			user, _, _, err = repo.First(ctx, "name = ?", "nobody")
+			if user != nil || err != tt.wantErr {
+				t.Errorf("First with no match = (%v, %v), want (nil, %v)", user, err, tt.wantErr)
+			}
+			env.verify()
+		})
+	}
+}
@@ -0,0 +1,287 @@
+package repository
+
+import "time"
+
+// Options holds per-repository settings
+// Cache-wide settings live in redis.Config; these apply to a single repository
+type Options struct {
+	// SegmentSize enables segmented caching of FindAll and ordered FindWhere results
+	// when > 0 (see WithSegmentedCollections)
+	SegmentSize int
+
+	// IDKind enables primary key generation on Create/CreateBatch when set
+	// (see WithGeneratedIDs)
+	IDKind IDKind
+
+	// SchemaVersion pins the schema version segment of cache keys when set
+	// (see WithSchemaVersion)
+	SchemaVersion string
+
+	// IN list splitting for FindWhereIn (see WithInListChunking, WithInListInputOrder)
+	InListChunkSize   int  // Values per statement (default 1000)
+	InListConcurrency int  // Chunks queried in parallel (default 1 = sequential)
+	InListInputOrder  bool // Re-sort results by the order of the input values
+
+	// ChangeEventBuffer is the size of the change event queue (default 1024)
+	// (see WithChangeEventBuffer)
+	ChangeEventBuffer int
+
+	// Outbox enables the transactional outbox and its relay when set (see WithOutbox)
+	Outbox *OutboxConfig
+
+	// CacheVerification cross-checks cache hits against the database when set
+	// (see WithCacheVerification)
+	CacheVerification *CacheVerification
+
+	// Rewarm regenerates FindAll and Count in the background after table invalidations
+	// when set (see WithRewarm)
+	Rewarm *RewarmConfig
+
+	// MaxQueryRows overrides db.Config.MaxQueryRows when non-zero; negative is unlimited
+	// (see WithMaxQueryRows)
+	MaxQueryRows int
+
+	// OperationCache enables, disables and sets the TTL of individual read operations,
+	// overriding redis.Config (see WithOperationCache)
+	OperationCache map[string]OperationCache
+
+	// ReloadAfterCreate re-selects created rows to pick up database-generated values
+	// (see WithReloadAfterCreate)
+	ReloadAfterCreate bool
+
+	// ReturnNotFoundError makes single-record lookups return ErrNotFound instead of a nil
+	// result (see WithReturnNotFoundError)
+	ReturnNotFoundError bool
+
+	// NormalizedCollections caches FindWhere results as ID lists plus FindByID entries
+	// (see WithNormalizedCollections)
+	NormalizedCollections bool
+
+	// Ordering handles cached FindAll and FindWhere queries without ORDER BY
+	// (see WithOrderingPolicy)
+	Ordering OrderingPolicy
+
+	// NoBypassPopulation keeps reads under ContextWithCacheBypass from caching their
+	// results (see WithCacheBypassPopulation)
+	NoBypassPopulation bool
+
+	// ReadYourWritesWindow is how long reads under ContextWithReadYourWrites bypass the
+	// cache for an entity written with the context (default 5s, see WithReadYourWritesWindow)
+	ReadYourWritesWindow time.Duration
+
+	// EntityPrimaryKeyValue reads primary key values with GetPrimaryKeyValue instead of
+	// the schema field (see WithEntityPrimaryKeyValue)
+	EntityPrimaryKeyValue bool
+
+	// KeyHasher replaces xxhash for the query segments of cache keys when set
+	// (see WithKeyHasher)
+	KeyHasher KeyHasher
+}
+
+// OperationCache configures the caching of one read operation
+type OperationCache struct {
+	Disabled bool          // Bypass the cache: no reads, writes or keys
+	TTL      time.Duration // Overrides redis.Config.OperationTTLs when > 0
+}
+
+// Option configures a repository
+type Option func(*Options)
+
+// WithSegmentedCollections caches FindAll and ordered FindWhere results as segments of
+// rowsPerSegment rows plus a small index entry, so FindPage and FindWherePage read only
+// the segments they need instead of the whole collection. Intended for large tables;
+// values <= 0 disable segmentation. Unordered FindWhere results are not segmented.
+func WithSegmentedCollections(rowsPerSegment int) Option {
+	return func(o *Options) {
+		o.SegmentSize = rowsPerSegment
+	}
+}
+
+// WithGeneratedIDs assigns a generated ID of the given kind (IDKindULID or IDKindUUIDv7)
+// to entities whose primary key is a string and empty at Create/CreateBatch time.
+// The ID is set on the caller's entity before the INSERT and used for cache invalidation.
+func WithGeneratedIDs(kind IDKind) Option {
+	return func(o *Options) {
+		o.IDKind = kind
+	}
+}
+
+// WithSchemaVersion pins the schema version segment of this repository's cache keys
+// (e.g. "v3") instead of the fingerprint computed from the entity structure.
+// Bump it whenever cached values of the old shape must no longer be read.
+func WithSchemaVersion(version string) Option {
+	return func(o *Options) {
+		o.SchemaVersion = version
+	}
+}
+
+// WithInListChunking sets how FindWhereIn splits large IN lists: at most chunkSize
+// values per statement, with up to concurrency statements in flight.
+// Each concurrent chunk holds a pooled connection, so keep concurrency well below MaxOpenConns.
+func WithInListChunking(chunkSize, concurrency int) Option {
+	return func(o *Options) {
+		o.InListChunkSize = chunkSize
+		o.InListConcurrency = concurrency
+	}
+}
+
+// WithInListInputOrder makes FindWhereIn return rows in the order of the input values
+// instead of chunk order
+func WithInListInputOrder() Option {
+	return func(o *Options) {
+		o.InListInputOrder = true
+	}
+}
+
+// WithChangeEventBuffer sets how many change events are buffered for Subscribe handlers
+// before new events are dropped (see DroppedChangeEvents)
+func WithChangeEventBuffer(size int) Option {
+	return func(o *Options) {
+		o.ChangeEventBuffer = size
+	}
+}
+
+// WithOutbox records every write in the sql4go_outbox table within the write's
+// transaction and starts a relay that invalidates caches and emits change events from it,
+// so neither is lost if the process dies after the commit. Create the table with
+// MigrateOutbox (or OutboxSchema) first. Zero config fields use their defaults.
+func WithOutbox(config OutboxConfig) Option {
+	return func(o *Options) {
+		o.Outbox = &config
+	}
+}
+
+// WithCacheVerification re-reads cache hits from the database and records mismatches
+// (see CacheVerificationStats). A debugging aid for invalidation gaps: it adds a
+// database query per verified hit, so enable it in staging or with a low SampleRate.
+func WithCacheVerification(config CacheVerification) Option {
+	return func(o *Options) {
+		o.CacheVerification = &config
+	}
+}
+
+// WithRewarm regenerates the table's FindAll and Count caches in the background after
+// invalidations, debounced to one run per Debounce, so readers after a burst of writes
+// mostly hit warm entries. Zero config fields use their defaults; no-op without a cache.
+func WithRewarm(config RewarmConfig) Option {
+	return func(o *Options) {
+		o.Rewarm = &config
+	}
+}
+
+// WithMaxQueryRows caps the rows read by FindAll and FindWhere of this repository,
+// overriding db.Config.MaxQueryRows; a negative value removes the cap. Larger results
+// return their first rows with ErrResultTruncated and are not cached.
+func WithMaxQueryRows(rows int) Option {
+	return func(o *Options) {
+		o.MaxQueryRows = rows
+	}
+}
+
+// WithOperationCache configures caching per read operation of this repository, e.g.
+// find_by_id cached for an hour and find_all never cached. Keys are the operation names
+// of redis.Config.OperationTTLs (find_by_id also covers FindByIDs, Exists and ExistsMany;
+// find_all covers FindPage). An entry replaces the redis.Config.DisabledOperations
+// setting of its operation. Disabled operations query the database and return
+// cacheHit and cacheStored false. Later calls add to earlier ones.
+func WithOperationCache(operations map[string]OperationCache) Option {
+	return func(o *Options) {
+		if o.OperationCache == nil {
+			o.OperationCache = make(map[string]OperationCache, len(operations))
+		}
+		for operation, config := range operations {
+			o.OperationCache[operation] = config
+		}
+	}
+}
+
+// WithReloadAfterCreate re-selects the row after Create and CreateWithResult, so the
+// entity - and the value cached by write_through - holds the values assigned by the
+// database: column defaults, generated columns, trigger updates. It costs one primary
+// key lookup per create. Write-behind creates are not reloaded, as the row is written
+// later; if the reload fails, the entity is kept as written and the error is logged.
+// The reloaded row replaces the entity, without its associations.
+func WithReloadAfterCreate() Option {
+	return func(o *Options) {
+		o.ReloadAfterCreate = true
+	}
+}
+
+// WithReturnNotFoundError makes FindByID, First, Take, Last and FindByUnique return
+// ErrNotFound instead of (nil, ..., nil) when no record matches, for callers preferring
+// errors.Is over a nil check. cacheHit is still true for a cached not-found. Exists,
+// Refresh and the multi-record methods are unaffected. Disabled by default.
+func WithReturnNotFoundError(enabled bool) Option {
+	return func(o *Options) {
+		o.ReturnNotFoundError = enabled
+	}
+}
+
+// WithNormalizedCollections caches FindWhere results as the ordered primary keys of their
+// rows, with each row stored once under its FindByID key and shared by every query that
+// returns it, instead of one serialized slice per query. Reads fetch the rows in one MGET
+// and query only the missing ones. It shrinks the cache for overlapping queries at the
+// cost of a second round trip per read; it has no effect while find_by_id caching is
+// disabled. See normalized.go.
+func WithNormalizedCollections() Option {
+	return func(o *Options) {
+		o.NormalizedCollections = true
+	}
+}
+
+// WithOrderingPolicy sets how FindAll and FindWhere queries without an explicit ordering
+// are cached: warned about once per call site (OrderingWarn, the default), ordered by
+// the primary key (OrderingPrimaryKey), not cached (OrderingStrict) or left alone
+// (OrderingIgnore). See ordering.go.
+func WithOrderingPolicy(policy OrderingPolicy) Option {
+	return func(o *Options) {
+		o.Ordering = policy
+	}
+}
+
+// WithCacheBypassPopulation sets whether reads under ContextWithCacheBypass cache the
+// fresh result they read from the database (default true). See cache_mode.go.
+func WithCacheBypassPopulation(enabled bool) Option {
+	return func(o *Options) {
+		o.NoBypassPopulation = !enabled
+	}
+}
+
+// WithReadYourWritesWindow sets how long after a write with a ContextWithReadYourWrites
+// context reads of the entity with that context bypass the cache (default 5s); it should
+// cover Invalidation.DebounceWindow and the outbox relay interval. See read_your_writes.go.
+func WithReadYourWritesWindow(window time.Duration) Option {
+	return func(o *Options) {
+		o.ReadYourWritesWindow = window
+	}
+}
+
+// WithEntityPrimaryKeyValue makes GetPrimaryKeyValue the source of primary key values
+// for cache keys, dependencies and events, for entities whose identity in the cache is
+// not the raw value of the key field. By default single-column keys are read through
+// the GORM schema field. See primary_key.go.
+func WithEntityPrimaryKeyValue() Option {
+	return func(o *Options) {
+		o.EntityPrimaryKeyValue = true
+	}
+}
+
+// WithKeyHasher hashes the query segments of this repository's cache keys with hasher
+// instead of the unseeded xxhash, e.g. SeededKeyHasher(seed) to keep the keys of
+// applications sharing a Redis apart and unpredictable. See key_hasher.go.
+func WithKeyHasher(hasher KeyHasher) Option {
+	return func(o *Options) {
+		o.KeyHasher = hasher
+	}
+}
+
+// applyOptions builds Options from a list of options
+func applyOptions(opts []Option) Options {
+	var options Options
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&options)
+		}
+	}
+	return options
+}
@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// OrderingPolicy controls cached collection queries without an explicit ordering
+//
+// Without an ORDER BY, the database may return the rows of FindAll and FindWhere in any
+// order, and a result cached by another process can come back in a different order than
+// the database gives this one, breaking pagination that assumes a stable order. A query
+// counts as ordered when a chained Order is present or its string condition contains
+// ORDER BY. For unordered queries, the OrderingPolicy (see WithOrderingPolicy):
+//   - OrderingWarn (default) logs a warning once per call site and operation
+//   - OrderingPrimaryKey appends ORDER BY <primary key> before executing and caching
+//   - OrderingStrict warns and neither reads nor stores the cache for the query
+//   - OrderingIgnore does nothing
+//
+// Warnings are only logged for cached operations. The order of FindWhereIn results is
+// governed by WithInListInputOrder instead.
+type OrderingPolicy int
+
+const (
+	// OrderingWarn logs unordered cached queries once per call site (default)
+	OrderingWarn OrderingPolicy = iota
+	// OrderingPrimaryKey orders unordered queries by the primary key
+	OrderingPrimaryKey
+	// OrderingStrict does not cache unordered query results
+	OrderingStrict
+	// OrderingIgnore leaves unordered queries as they are
+	OrderingIgnore
+)
+
+// orderByRegexp matches an ORDER BY within a string condition
+var orderByRegexp = regexp.MustCompile(`(?i)\border\s+by\b`)
+
+// maxLoggedCallSites bounds the call sites remembered for ordering warnings
+const maxLoggedCallSites = 1000
+
+// loggedCallSites holds the call sites already warned about ("operation@file:line")
+var (
+	loggedCallSites   = make(map[string]struct{})
+	loggedCallSitesMu sync.Mutex
+)
+
+// ordered reports whether a collection query has an explicit ordering
+func (r *GenericRepository[T]) ordered(query interface{}) bool {
+	for _, c := range r.chain {
+		if c.kind == "order" {
+			return true
+		}
+	}
+	text, ok := query.(string)
+	return ok && orderByRegexp.MatchString(text)
+}
+
+// orderingBypass applies the ordering policy to an unordered query of a cached
+// operation: it logs the call site, and under OrderingStrict returns a copy of the
+// repository without cache to run the query on (nil otherwise)
+func (r *GenericRepository[T]) orderingBypass(operation string, query interface{}) *GenericRepository[T] {
+	policy := r.options.Ordering
+	if r.redis == nil || policy == OrderingPrimaryKey || policy == OrderingIgnore || r.ordered(query) {
+		return nil
+	}
+
+	r.warnUnordered(operation, policy == OrderingStrict)
+	if policy != OrderingStrict {
+		return nil
+	}
+	uncached := *r
+	uncached.redis = nil
+	return &uncached
+}
+
+// orderedQuery appends ORDER BY <primary key> to an unordered query under
+// OrderingPrimaryKey; GORM's own tie-breaking of First and Last is unaffected
+func (r *GenericRepository[T]) orderedQuery(db *gorm.DB, query interface{}) *gorm.DB {
+	if r.options.Ordering != OrderingPrimaryKey || r.ordered(query) {
+		return db
+	}
+	return db.Order(clause.OrderByColumn{Column: clause.Column{Table: clause.CurrentTable, Name: r.primaryKey}})
+}
+
+// warnUnordered logs an unordered cached query once per call site and operation
+func (r *GenericRepository[T]) warnUnordered(operation string, uncached bool) {
+	site := callSite()
+	key := operation + "@" + site
+
+	loggedCallSitesMu.Lock()
+	_, logged := loggedCallSites[key]
+	if !logged && len(loggedCallSites) < maxLoggedCallSites {
+		loggedCallSites[key] = struct{}{}
+	} else {
+		logged = true
+	}
+	loggedCallSitesMu.Unlock()
+	if logged {
+		return
+	}
+
+	consequence := "cached copies may return rows in a different order than the database"
+	if uncached {
+		consequence = "the result is not cached (OrderingStrict)"
+	}
+	r.redis.Logger().Printf("sql4go: %s on %s.%s without ORDER BY at %s: %s; chain Order or use WithOrderingPolicy", operation, r.dbName, r.tableName, site, consequence)
+}
+
+// callSite returns the file:line of the first caller outside this package
+func callSite() string {
+	pcs := make([]uintptr, 16)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs)])
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, packagePath+".") {
+			return fmt.Sprintf("%s:%d", frame.File, frame.Line)
+		}
+		if !more {
+			return "unknown"
+		}
+	}
+}
+
+// packagePath is the import path of this package, for skipping its frames
+var packagePath = reflect.TypeOf(OrderingPolicy(0)).PkgPath()
@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// forgetOrderingWarnings lets the next unordered query of each call site warn again
+func forgetOrderingWarnings(t *testing.T) {
+	t.Helper()
+	loggedCallSitesMu.Lock()
+	clear(loggedCallSites)
+	loggedCallSitesMu.Unlock()
+}
+
+func TestOrderingWarnLogsOnceAndCaches(t *testing.T) {
+	forgetOrderingWarnings(t)
+	env := newTestEnv(t)
+	logger := &recordingLogger{}
+	env.cache.SetLogger(logger)
+	repo := newTestRepository[testUser](env)
+	ctx := context.Background()
+
+	ada := testUser{ID: 1, Name: "ada", Email: "ada@example.com"}
+	env.mock.ExpectQuery("SELECT \\* FROM `users` WHERE name = \\?$").WillReturnRows(userRows(ada))
+	for range 2 {
+		if _, _, _, err := repo.FindWhere(ctx, "name = ?", "ada"); err != nil {
+			t.Fatalf("FindWhere: %v", err)
+		}
+	}
+	env.verify() // The second call was served from the cache
+
+	var warnings []string
+	for _, message := range logger.logged() {
+		if strings.Contains(message, "without ORDER BY") {
+			warnings = append(warnings, message)
+		}
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "find_where on app.users") {
+		t.Errorf("ordering warnings = %q, want one for find_where on app.users", warnings)
+	}
+}
+
+func TestOrderingPrimaryKeyOrdersUnorderedQueries(t *testing.T) {
+	env := newTestEnv(t)
+	repo := newTestRepository[testUser](env, WithOrderingPolicy(OrderingPrimaryKey))
+	ctx := context.Background()
+
+	env.mock.ExpectQuery("SELECT \\* FROM `users` WHERE name = \\? ORDER BY `users`.`id`$").WillReturnRows(userRows())
+	if _, _, _, err := repo.FindWhere(ctx, "name = ?", "ada"); err != nil {
+		t.Fatalf("unordered FindWhere: %v", err)
+	}
+
+	// An explicit ordering is kept as it is
+	env.mock.ExpectQuery("SELECT \\* FROM `users` WHERE name = \\? ORDER BY email$").WillReturnRows(userRows())
+	if _, _, _, err := repo.Order(ctx, "email").FindWhere(ctx, "name = ?", "ada"); err != nil {
+		t.Fatalf("ordered FindWhere: %v", err)
+	}
+	env.verify()
+}
+
+func TestOrderingStrictSkipsCacheForUnorderedQueries(t *testing.T) {
+	forgetOrderingWarnings(t)
+	env := newTestEnv(t)
+	repo := newTestRepository[testUser](env, WithOrderingPolicy(OrderingStrict))
+	ctx := context.Background()
+
+	ada := testUser{ID: 1, Name: "ada", Email: "ada@example.com"}
+	for range 2 {
+		env.mock.ExpectQuery("SELECT \\* FROM `users` WHERE name = \\?$").WillReturnRows(userRows(ada))
+		if _, hit, stored, err := repo.FindWhere(ctx, "name = ?", "ada"); err != nil || hit || stored {
+			t.Fatalf("unordered FindWhere = (hit %v, stored %v, %v), want an uncached read", hit, stored, err)
+		}
+	}
+	if keys := env.cachedKeys(); len(keys) != 0 {
+		t.Errorf("unordered result was cached under %v", keys)
+	}
+
+	// Ordered queries are still cached
+	env.mock.ExpectQuery("SELECT \\* FROM `users` WHERE name = \\? ORDER BY id").WillReturnRows(userRows(ada))
+	if _, _, stored, err := repo.FindWhere(ctx, "name = ? ORDER BY id", "ada"); err != nil || !stored {
+		t.Errorf("ordered FindWhere = (stored %v, %v), want a cached result", stored, err)
+	}
+	env.verify()
+}
@@ -0,0 +1,436 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cespare/xxhash/v2"
+	"gorm.io/gorm"
+)
+
+// OutboxTableName is the table holding outbox rows (see MigrateOutbox)
+const OutboxTableName = "sql4go_outbox"
+
+// OutboxSchema is the DDL of the outbox table, for migration tools other than MigrateOutbox
+const OutboxSchema = `CREATE TABLE IF NOT EXISTS sql4go_outbox (
+  id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  database_name VARCHAR(64) NOT NULL,
+  entity_table VARCHAR(64) NOT NULL,
+  operation VARCHAR(16) NOT NULL,
+  primary_key VARCHAR(255) NOT NULL,
+  payload MEDIUMBLOB,
+  attempts INT NOT NULL DEFAULT 0,
+  last_error VARCHAR(1024) NOT NULL DEFAULT '',
+  created_at DATETIME(3) NOT NULL,
+  next_attempt_at DATETIME(3) NOT NULL,
+  processed_at DATETIME(3) NULL,
+  PRIMARY KEY (id),
+  KEY idx_sql4go_outbox_pending (database_name, entity_table, processed_at, next_attempt_at)
+)`
+
+// OutboxEntry is a row of the outbox table
+type OutboxEntry struct {
+	ID            uint64     `gorm:"primaryKey;autoIncrement"`
+	DatabaseName  string     `gorm:"size:64;not null;index:idx_sql4go_outbox_pending,priority:1"`
+	EntityTable   string     `gorm:"size:64;not null;index:idx_sql4go_outbox_pending,priority:2"`
+	Operation     string     `gorm:"size:16;not null"`
+	PrimaryKey    string     `gorm:"size:255;not null"`
+	Payload       []byte     `gorm:"type:mediumblob"` // JSON snapshot of the entity
+	Attempts      int        `gorm:"not null;default:0"`
+	LastError     string     `gorm:"size:1024;not null;default:''"`
+	CreatedAt     time.Time  `gorm:"precision:3;not null"`
+	NextAttemptAt time.Time  `gorm:"precision:3;not null;index:idx_sql4go_outbox_pending,priority:4"`
+	ProcessedAt   *time.Time `gorm:"precision:3;index:idx_sql4go_outbox_pending,priority:3"`
+}
+
+// TableName returns the outbox table name
+func (OutboxEntry) TableName() string {
+	return OutboxTableName
+}
+
+// MigrateOutbox creates or updates the outbox table
+func MigrateOutbox(gormDB *gorm.DB) error {
+	return gormDB.AutoMigrate(&OutboxEntry{})
+}
+
+// OutboxConfig controls the outbox relay
+//
+// Without the outbox, cache invalidation and change events run after the database write;
+// a crash in between leaves caches stale and subscribers without the event. With it:
+//   - Every write runs in a transaction that also inserts one sql4go_outbox row per entity
+//     (writes inside WithTransaction add their rows to that transaction; writes of a
+//     nested WithTransaction that returned an error are rolled back and add none)
+//   - After commit the caches are still invalidated immediately (best effort), so reads
+//     see the write right away
+//   - A background relay reads pending rows, invalidates the caches again (idempotent) and
+//     emits the change events, then marks the rows processed. Change events are only
+//     emitted by the relay, at least once.
+//   - One pod relays per table: the relay holds a MySQL advisory lock (GET_LOCK) on a
+//     dedicated connection, and the others take over when it is released
+//   - Failed rows are retried with exponential backoff; processed rows are deleted after
+//     OutboxConfig.Retention
+//
+// The outbox replaces write_behind queueing: writes run synchronously.
+type OutboxConfig struct {
+	PollInterval time.Duration // How often pending rows are relayed (default 1s)
+	BatchSize    int           // Rows relayed per poll (default 100)
+	MaxBackoff   time.Duration // Upper bound of the retry delay of failed rows (default 5m)
+	Retention    time.Duration // Processed rows are deleted after this long (default 24h)
+}
+
+// withDefaults fills unset fields with their defaults
+func (c OutboxConfig) withDefaults() OutboxConfig {
+	if c.PollInterval <= 0 {
+		c.PollInterval = time.Second
+	}
+	if c.BatchSize <= 0 {
+		c.BatchSize = 100
+	}
+	if c.MaxBackoff <= 0 {
+		c.MaxBackoff = 5 * time.Minute
+	}
+	if c.Retention <= 0 {
+		c.Retention = 24 * time.Hour
+	}
+	return c
+}
+
+// writeViaOutbox runs a write method in a transaction that also inserts its outbox rows
+// Returns false, without running write, if the outbox is disabled or the repository
+// is already bound to a transaction (whose commit inserts the rows).
+func (r *GenericRepository[T]) writeViaOutbox(ctx context.Context, write func(tx Repository[T]) error) (bool, error) {
+	if r.outbox == nil || r.tx != nil {
+		return false, nil
+	}
+	return true, r.WithTransaction(ctx, write)
+}
+
+// insertOutboxEntries inserts the outbox rows of a transaction's writes before it commits
+func (r *GenericRepository[T]) insertOutboxEntries(tx *gorm.DB, writes []txWrite[T]) error {
+	if r.outbox == nil || len(writes) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	entries := make([]OutboxEntry, 0, len(writes))
+	for _, write := range writes {
+		payload, err := json.Marshal(write.entity)
+		if err != nil {
+			return fmt.Errorf("outbox payload error: %w", err)
+		}
+		entries = append(entries, OutboxEntry{
+			DatabaseName:  r.dbName,
+			EntityTable:   r.tableName,
+			Operation:     string(write.operation),
+			PrimaryKey:    fmt.Sprintf("%v", r.primaryKeyValue(write.entity)),
+			Payload:       payload,
+			CreatedAt:     now,
+			NextAttemptAt: now,
+		})
+	}
+
+	if err := tx.Set(invalidationHandledKey, true).Create(&entries).Error; err != nil {
+		return fmt.Errorf("outbox insert error: %w", err)
+	}
+	return nil
+}
+
+// outboxRelay relays the outbox rows of one repository's table
+type outboxRelay[T Entity] struct {
+	repo     *GenericRepository[T]
+	db       *gorm.DB
+	config   OutboxConfig
+	lockName string
+	metrics  *outboxGauges
+
+	conn *sql.Conn // Dedicated connection holding the advisory lock (nil if not held)
+
+	cancel    context.CancelFunc
+	stopped   chan struct{}
+	closeOnce sync.Once
+}
+
+// newOutboxRelay starts the relay of a repository
+func newOutboxRelay[T Entity](repo *GenericRepository[T], config OutboxConfig) *outboxRelay[T] {
+	lockName := fmt.Sprintf("sql4go_outbox:%s:%s", repo.dbName, repo.tableName)
+	if len(lockName) > 64 { // MySQL limit for lock names
+		lockName = fmt.Sprintf("sql4go_outbox:%016x", xxhash.Sum64String(lockName))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	o := &outboxRelay[T]{
+		repo:     repo,
+		db:       repo.dbManager.DB(),
+		config:   config,
+		lockName: lockName,
+		metrics:  outboxMetrics.gauges(queryMetricsKey{database: repo.dbName, table: repo.tableName}),
+		cancel:   cancel,
+		stopped:  make(chan struct{}),
+	}
+	go o.run(ctx)
+	return o
+}
+
+// run relays pending rows every PollInterval while holding the lock
+func (o *outboxRelay[T]) run(ctx context.Context) {
+	defer close(o.stopped)
+	defer o.releaseLock()
+
+	ticker := time.NewTicker(o.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		if o.acquireLock(ctx) {
+			o.relay(ctx)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// acquireLock takes the advisory lock unless this relay already holds it
+func (o *outboxRelay[T]) acquireLock(ctx context.Context) bool {
+	if o.conn != nil {
+		// Still held as long as the connection is alive
+		if err := o.conn.PingContext(ctx); err == nil {
+			return true
+		}
+		o.releaseLock()
+	}
+
+	sqlDB, err := o.db.DB()
+	if err != nil {
+		return false
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return false
+	}
+
+	var acquired sql.NullInt64
+	if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, 0)", o.lockName).Scan(&acquired); err != nil || acquired.Int64 != 1 {
+		_ = conn.Close() // Another pod relays this table
+		return false
+	}
+	o.conn = conn
+	return true
+}
+
+// releaseLock releases the advisory lock and its connection
+func (o *outboxRelay[T]) releaseLock() {
+	if o.conn == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, _ = o.conn.ExecContext(ctx, "SELECT RELEASE_LOCK(?)", o.lockName)
+	_ = o.conn.Close()
+	o.conn = nil
+}
+
+// relay processes one batch of due rows, records the lag and deletes expired rows
+func (o *outboxRelay[T]) relay(ctx context.Context) {
+	table := o.db.WithContext(ctx).Set(invalidationHandledKey, true).Model(&OutboxEntry{}).
+		Where("database_name = ? AND entity_table = ?", o.repo.dbName, o.repo.tableName).
+		Session(&gorm.Session{}) // Reusable for the statements below
+
+	var entries []OutboxEntry
+	err := table.
+		Where("processed_at IS NULL AND next_attempt_at <= ?", time.Now()).
+		Order("id").Limit(o.config.BatchSize).Find(&entries).Error
+	if err != nil {
+		return // Database unavailable - retry on the next poll
+	}
+
+	var processed []uint64
+	for _, entry := range entries {
+		if err := o.process(ctx, entry); err != nil {
+			o.metrics.failures.Add(1)
+			o.retryLater(ctx, entry, err)
+			continue
+		}
+		processed = append(processed, entry.ID)
+	}
+	if len(processed) > 0 {
+		if err := table.Where("id IN ?", processed).Update("processed_at", time.Now()).Error; err == nil {
+			o.metrics.relayed.Add(uint64(len(processed)))
+		}
+	}
+
+	// Lag: pending rows and the age of the oldest one
+	var lag struct {
+		Pending int64
+		Oldest  *time.Time
+	}
+	if err := table.Where("processed_at IS NULL").
+		Select("COUNT(*) AS pending, MIN(created_at) AS oldest").Scan(&lag).Error; err == nil {
+		o.metrics.pending.Store(lag.Pending)
+		if lag.Oldest != nil {
+			o.metrics.lag.Store(int64(time.Since(*lag.Oldest)))
+		} else {
+			o.metrics.lag.Store(0)
+		}
+	}
+
+	// Retention: delete old processed rows in small steps
+	_ = table.Where("processed_at < ?", time.Now().Add(-o.config.Retention)).
+		Limit(1000).Delete(&OutboxEntry{}).Error
+}
+
+// process invalidates the caches of one row's entity and emits its change event
+func (o *outboxRelay[T]) process(ctx context.Context, entry OutboxEntry) error {
+	var entity T
+	if err := json.Unmarshal(entry.Payload, &entity); err != nil {
+		return fmt.Errorf("outbox payload error: %w", err)
+	}
+
+	if o.repo.redis != nil {
+		if err := o.repo.invalidateEntityCachesStrict(ctx, ChangeOperation(entry.Operation), entity); err != nil {
+			return err
+		}
+	}
+
+	o.repo.emitChange(ChangeOperation(entry.Operation), entity)
+	return nil
+}
+
+// retryLater schedules a failed row with exponential backoff
+func (o *outboxRelay[T]) retryLater(ctx context.Context, entry OutboxEntry, cause error) {
+	backoff := o.config.PollInterval << min(entry.Attempts, 20)
+	if backoff <= 0 || backoff > o.config.MaxBackoff {
+		backoff = o.config.MaxBackoff
+	}
+
+	message := cause.Error()
+	if len(message) > 1024 {
+		message = message[:1024]
+	}
+	_ = o.db.WithContext(ctx).Set(invalidationHandledKey, true).Model(&OutboxEntry{}).Where("id = ?", entry.ID).
+		Updates(map[string]interface{}{
+			"attempts":        entry.Attempts + 1,
+			"last_error":      message,
+			"next_attempt_at": time.Now().Add(backoff),
+		}).Error
+}
+
+// close stops the relay and releases its lock
+func (o *outboxRelay[T]) close() {
+	o.closeOnce.Do(o.cancel)
+	<-o.stopped
+}
+
+// invalidateEntityCachesStrict is invalidateEntityCaches reporting cache errors,
+// so the relay retries rows whose invalidation failed
+func (r *GenericRepository[T]) invalidateEntityCachesStrict(ctx context.Context, operation ChangeOperation, entity T) error {
+	pkValue := r.primaryKeyValue(entity)
+	err := errors.Join(
+		r.redis.InvalidatePattern(ctx, tableCachePattern(r.tableNamespace)),
+		r.redis.DeleteLarge(ctx, r.entityCacheKey(pkValue)),
+		r.redis.DeleteKeys(ctx, []string{existsCacheKey(r.keyNamespace, pkValue, maxKeyLength(r.redis))}),
+		r.redis.InvalidateEntityDependencies(ctx, dependencyType(r.redis, r.dbName, r.tableName), pkValue),
+		r.redis.InvalidateKeyPatterns(ctx, r.dbName, r.tableName, pkValue),
+	)
+	if err != nil {
+		return fmt.Errorf("outbox invalidation error: %w", err)
+	}
+	r.recordTableInvalidation(ctx, string(operation))
+	r.scheduleRewarm()
+
+	// Related entities - best effort, as in invalidateEntityCaches
+	invalidateRelatedEntities(ctx, r.redis, r.dbName, r.tableName, relatedEntitiesOf(r.redis, r.tableName, entity, pkValue))
+	return nil
+}
+
+// OutboxStats is a snapshot of the outbox relay metrics of one table
+// Pending and Lag are measured by the pod holding the relay lock.
+type OutboxStats struct {
+	Database string
+	Table    string
+	Pending  int64         // Unprocessed rows
+	Lag      time.Duration // Age of the oldest unprocessed row
+	Relayed  uint64        // Rows relayed by this process
+	Failures uint64        // Failed relay attempts in this process
+}
+
+// outboxGauges holds the relay metrics of one table
+type outboxGauges struct {
+	pending  atomic.Int64
+	lag      atomic.Int64 // Nanoseconds
+	relayed  atomic.Uint64
+	failures atomic.Uint64
+}
+
+// outboxMetricsRegistry holds the relay metrics of all repositories in the process
+type outboxMetricsRegistry struct {
+	mu     sync.Mutex
+	tables map[queryMetricsKey]*outboxGauges
+}
+
+// outboxMetrics is the process-wide registry shared by all outbox relays
+var outboxMetrics = &outboxMetricsRegistry{tables: make(map[queryMetricsKey]*outboxGauges)}
+
+// gauges returns the metrics of a table, creating them on first use
+func (reg *outboxMetricsRegistry) gauges(key queryMetricsKey) *outboxGauges {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	g, ok := reg.tables[key]
+	if !ok {
+		g = &outboxGauges{}
+		reg.tables[key] = g
+	}
+	return g
+}
+
+// snapshot returns the stats of all tables with an outbox relay, sorted by database and table
+func (reg *outboxMetricsRegistry) snapshot() []OutboxStats {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	stats := make([]OutboxStats, 0, len(reg.tables))
+	for key, g := range reg.tables {
+		stats = append(stats, OutboxStats{
+			Database: key.database,
+			Table:    key.table,
+			Pending:  g.pending.Load(),
+			Lag:      time.Duration(g.lag.Load()),
+			Relayed:  g.relayed.Load(),
+			Failures: g.failures.Load(),
+		})
+	}
+
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Database != stats[j].Database {
+			return stats[i].Database < stats[j].Database
+		}
+		return stats[i].Table < stats[j].Table
+	})
+	return stats
+}
+
+// OutboxStats returns the outbox relay metrics of this repository's table
+// Returns false if the outbox is not enabled (see WithOutbox).
+func (r *GenericRepository[T]) OutboxStats() (OutboxStats, bool) {
+	if r.outbox == nil {
+		return OutboxStats{}, false
+	}
+	g := r.outbox.metrics
+	return OutboxStats{
+		Database: r.dbName,
+		Table:    r.tableName,
+		Pending:  g.pending.Load(),
+		Lag:      time.Duration(g.lag.Load()),
+		Relayed:  g.relayed.Load(),
+		Failures: g.failures.Load(),
+	}, true
+}
@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"reflect"
+
+	"gorm.io/gorm/schema"
+)
+
+// defaultPrimaryKeyColumn is the primary key column assumed without a parsed schema
+const defaultPrimaryKeyColumn = "id"
+
+// resolvePrimaryKey returns the primary key column of an entity type and the schema
+// field its values are read from (nil to use GetPrimaryKeyValue)
+//
+// The primary key is resolved once, from the GORM schema parsed at construction:
+//   - the column is the one declared by PrimaryKeyAware, otherwise the schema's primary
+//     field (so gorm:"column:user_uuid;primaryKey" yields "user_uuid"), "id" if the
+//     schema could not be parsed
+//   - values are read from the key's struct field through the schema field's ValueOf,
+//     so FindByID keys, dependency sets and change events agree with the foreign keys
+//     other entities hold, whatever GetPrimaryKeyValue returns
+//
+// GetPrimaryKeyValue remains the override: it is used for composite keys (whose column
+// order it defines, see CompositeKey), for keys without a schema field, and for every
+// entity of repositories created with WithEntityPrimaryKeyValue.
+func resolvePrimaryKey(entityType reflect.Type, entitySchema *schema.Schema, options Options) (string, *schema.Field) {
+	declared := declaredPrimaryKeyColumn(entityType)
+
+	var field *schema.Field
+	switch {
+	case entitySchema == nil:
+	case declared != "":
+		field = entitySchema.LookUpField(declared)
+	case len(entitySchema.PrimaryFields) == 1:
+		field = entitySchema.PrimaryFields[0]
+	case len(entitySchema.PrimaryFields) > 1:
+		// Composite key: the column of the first field, values from GetPrimaryKeyValue
+		return entitySchema.PrimaryFields[0].DBName, nil
+	}
+
+	column := declared
+	if column == "" && field != nil {
+		column = field.DBName
+	}
+	if column == "" {
+		column = defaultPrimaryKeyColumn
+	}
+	if options.EntityPrimaryKeyValue || field == nil || field.DBName == "" {
+		return column, nil
+	}
+	return column, field
+}
+
+// primaryKeyValue returns the primary key value of an entity (see resolvePrimaryKey)
+func (r *GenericRepository[T]) primaryKeyValue(entity T) interface{} {
+	if r.primaryField == nil {
+		return entity.GetPrimaryKeyValue()
+	}
+	value, _ := r.primaryField.ValueOf(context.Background(), reflect.ValueOf(entity))
+	return value
+}
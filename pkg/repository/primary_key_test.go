@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// testAccount has a primary key column renamed by its gorm tag
+type testAccount struct {
+	UserID string `gorm:"column:user_uuid;primaryKey"`
+	Name   string `gorm:"column:name"`
+}
+
+func (testAccount) TableName() string { return "accounts" }
+
+func (a testAccount) GetPrimaryKeyValue() interface{} { return a.UserID }
+
+func TestRenamedPrimaryKeyColumn(t *testing.T) {
+	env := newTestEnv(t)
+	repo := newTestRepository[testAccount](env)
+
+	if repo.primaryKey != "user_uuid" {
+		t.Fatalf("primary key column = %q, want user_uuid", repo.primaryKey)
+	}
+	if id := repo.primaryKeyValue(testAccount{UserID: "u-1"}); id != "u-1" {
+		t.Errorf("primary key value = %v, want u-1", id)
+	}
+
+	env.mock.ExpectQuery("SELECT 1 FROM `accounts` WHERE `accounts`.`user_uuid` = \\?").
+		WithArgs("u-1", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"1"}).AddRow(1))
+	if exists, _, _, err := repo.Exists(context.Background(), "u-1"); err != nil || !exists {
+		t.Fatalf("Exists = (%v, %v), want true", exists, err)
+	}
+	env.verify()
+}
+
+// testProduct declares a primary key column GORM does not map as its primary key
+type testProduct struct {
+	ID   uint   `gorm:"primaryKey"`
+	SKU  string `gorm:"column:sku"`
+	Name string `gorm:"column:name"`
+}
+
+func (testProduct) TableName() string { return "products" }
+
+func (p testProduct) GetPrimaryKeyValue() interface{} { return p.SKU }
+
+func (testProduct) PrimaryKeyColumn() string { return "sku" }
+
+func TestDeclaredPrimaryKeyColumn(t *testing.T) {
+	env := newTestEnv(t)
+	repo := newTestRepository[testProduct](env)
+	ctx := context.Background()
+
+	env.mock.ExpectQuery("SELECT \\* FROM `products` WHERE `products`.`sku` = \\?").
+		WithArgs("ab-1", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "sku", "name"}).AddRow(7, "ab-1", "pen"))
+	found, _, stored, err := repo.FindByID(ctx, "ab-1")
+	if err != nil || !stored || found == nil || found.ID != 7 {
+		t.Fatalf("FindByID = (%+v, stored %v, %v), want product 7 cached", found, stored, err)
+	}
+	env.verify()
+
+	// Cached under the declared key, not the id column
+	if key := repo.CacheKeyForID("ab-1"); !env.mr.Exists(key) || !strings.HasSuffix(key, ":find_by_id:ab-1") {
+		t.Errorf("cached keys %q, want %s", env.cachedKeys(), key)
+	}
+	if id := repo.primaryKeyValue(*found); id != "ab-1" {
+		t.Errorf("primary key value = %v, want ab-1", id)
+	}
+	if found, hit, _, err := repo.FindByID(ctx, "ab-1"); err != nil || !hit || found.Name != "pen" {
+		t.Errorf("second FindByID = (%+v, hit %v, %v), want a cache hit", found, hit, err)
+	}
+}
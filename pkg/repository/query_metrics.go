@@ -0,0 +1,332 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ammar0144/sql4go/pkg/redis"
+
+	"gorm.io/gorm"
+)
+
+// queryLatencyBuckets are the histogram upper bounds for database query latency
+var queryLatencyBuckets = []time.Duration{
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+}
+
+// QueryStats is a snapshot of the database query metrics of one operation on one table
+type QueryStats struct {
+	Database     string
+	Table        string
+	Operation    string
+	Count        uint64
+	SlowCount    uint64        // Queries at or above db.LoggingConfig.SlowQueryThreshold
+	ErrorCount   uint64        // Failed queries, excluding timeouts, cancellations and not-found
+	TimeoutCount uint64        // Queries aborted by a context deadline (ErrQueryTimeout)
+	CancelCount  uint64        // Queries aborted by caller cancellation (ErrCanceled)
+	TotalLatency time.Duration // Sum of all query latencies
+	Buckets      []LatencyBucket
+}
+
+// LatencyBucket is a cumulative histogram bucket
+type LatencyBucket struct {
+	UpperBound time.Duration // Inclusive upper bound
+	Count      uint64        // Queries with latency <= UpperBound
+}
+
+// AvgLatency returns the average query latency
+func (s QueryStats) AvgLatency() time.Duration {
+	if s.Count == 0 {
+		return 0
+	}
+	return s.TotalLatency / time.Duration(s.Count)
+}
+
+// queryMetricsKey identifies a metrics series
+type queryMetricsKey struct {
+	database  string
+	table     string
+	operation string
+}
+
+// latencyHistogram is a lock-free fixed-bucket latency histogram
+type latencyHistogram struct {
+	buckets   []atomic.Uint64 // Non-cumulative; the last bucket is +Inf
+	count     atomic.Uint64
+	slowCount atomic.Uint64
+	sum       atomic.Uint64 // Nanoseconds
+
+	errorCount   atomic.Uint64
+	timeoutCount atomic.Uint64
+	cancelCount  atomic.Uint64
+}
+
+// observe records one query latency
+func (h *latencyHistogram) observe(duration time.Duration, slow bool) {
+	bucket := sort.Search(len(queryLatencyBuckets), func(i int) bool {
+		return duration <= queryLatencyBuckets[i]
+	})
+	h.buckets[bucket].Add(1)
+	h.count.Add(1)
+	h.sum.Add(uint64(duration.Nanoseconds()))
+	if slow {
+		h.slowCount.Add(1)
+	}
+}
+
+// observeError counts a failed query by error class
+func (h *latencyHistogram) observeError(err error) {
+	switch {
+	case errors.Is(err, ErrQueryTimeout):
+		h.timeoutCount.Add(1)
+	case errors.Is(err, ErrCanceled):
+		h.cancelCount.Add(1)
+	default:
+		h.errorCount.Add(1)
+	}
+}
+
+// queryMetricsRegistry holds the histograms of all repositories in the process
+type queryMetricsRegistry struct {
+	mu         sync.RWMutex
+	histograms map[queryMetricsKey]*latencyHistogram
+}
+
+// queryMetrics is the process-wide registry shared by all repositories
+//
+// Every repository times its GORM calls into a process-wide registry keyed by
+// (database, table, operation). This covers the DB side of cache misses and writes,
+// complementing the Redis latency in redis.Metrics.
+var queryMetrics = &queryMetricsRegistry{histograms: make(map[queryMetricsKey]*latencyHistogram)}
+
+// cacheManagers holds the cache managers of all repositories, whose per-table cache
+// metrics WritePrometheusMetrics exports (*redis.Manager -> struct{})
+var cacheManagers sync.Map
+
+// histogram returns the histogram of a series, creating it on first use
+func (reg *queryMetricsRegistry) histogram(key queryMetricsKey) *latencyHistogram {
+	reg.mu.RLock()
+	h, ok := reg.histograms[key]
+	reg.mu.RUnlock()
+	if ok {
+		return h
+	}
+
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	if h, ok = reg.histograms[key]; !ok {
+		h = &latencyHistogram{buckets: make([]atomic.Uint64, len(queryLatencyBuckets)+1)}
+		reg.histograms[key] = h
+	}
+	return h
+}
+
+// snapshot returns the stats of all series matching filter, sorted by database, table and operation
+func (reg *queryMetricsRegistry) snapshot(filter func(queryMetricsKey) bool) []QueryStats {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+
+	stats := make([]QueryStats, 0, len(reg.histograms))
+	for key, h := range reg.histograms {
+		if filter != nil && !filter(key) {
+			continue
+		}
+
+		buckets := make([]LatencyBucket, len(queryLatencyBuckets))
+		var cumulative uint64
+		for i, bound := range queryLatencyBuckets {
+			cumulative += h.buckets[i].Load()
+			buckets[i] = LatencyBucket{UpperBound: bound, Count: cumulative}
+		}
+
+		stats = append(stats, QueryStats{
+			Database:     key.database,
+			Table:        key.table,
+			Operation:    key.operation,
+			Count:        h.count.Load(),
+			SlowCount:    h.slowCount.Load(),
+			ErrorCount:   h.errorCount.Load(),
+			TimeoutCount: h.timeoutCount.Load(),
+			CancelCount:  h.cancelCount.Load(),
+			TotalLatency: time.Duration(h.sum.Load()),
+			Buckets:      buckets,
+		})
+	}
+
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Database != stats[j].Database {
+			return stats[i].Database < stats[j].Database
+		}
+		if stats[i].Table != stats[j].Table {
+			return stats[i].Table < stats[j].Table
+		}
+		return stats[i].Operation < stats[j].Operation
+	})
+	return stats
+}
+
+// reset clears all series
+func (reg *queryMetricsRegistry) reset() {
+	reg.mu.Lock()
+	reg.histograms = make(map[queryMetricsKey]*latencyHistogram)
+	reg.mu.Unlock()
+}
+
+// observeQuery records the latency and outcome of a GORM call started at start
+// Failures are counted by class; record-not-found is not a failure
+func (r *GenericRepository[T]) observeQuery(ctx context.Context, operation string, start time.Time, err error) {
+	duration := time.Since(start)
+
+	slow := false
+	if r.dbManager != nil && r.dbManager.Config() != nil {
+		threshold := r.dbManager.Config().Logging.SlowQueryThreshold
+		slow = threshold > 0 && duration >= threshold
+	}
+
+	h := queryMetrics.histogram(queryMetricsKey{database: r.dbName, table: r.tableName, operation: operation})
+	h.observe(duration, slow)
+	if err = classifyQueryError(ctx, err); err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		h.observeError(err)
+	}
+}
+
+// QueryMetrics returns the database query metrics of this repository's table
+func (r *GenericRepository[T]) QueryMetrics() []QueryStats {
+	return queryMetrics.snapshot(func(key queryMetricsKey) bool {
+		return key.database == r.dbName && key.table == r.tableName
+	})
+}
+
+// AllQueryMetrics returns the database query metrics of all repositories in the process
+func AllQueryMetrics() []QueryStats {
+	return queryMetrics.snapshot(nil)
+}
+
+// ResetQueryMetrics clears the database query metrics of all repositories
+func ResetQueryMetrics() {
+	queryMetrics.reset()
+}
+
+// WritePrometheusMetrics writes the database query metrics in the Prometheus text format
+// Series are labeled with database, table and operation; the per-table cache reads of
+// the repositories' cache managers are labeled with namespace and table
+func WritePrometheusMetrics(w io.Writer) error {
+	stats := AllQueryMetrics()
+
+	if _, err := fmt.Fprint(w, "# HELP sql4go_db_query_duration_seconds Database query latency by table and operation.\n# TYPE sql4go_db_query_duration_seconds histogram\n"); err != nil {
+		return err
+	}
+	for _, s := range stats {
+		labels := fmt.Sprintf(`database=%q,table=%q,operation=%q`, s.Database, s.Table, s.Operation)
+		for _, bucket := range s.Buckets {
+			if _, err := fmt.Fprintf(w, "sql4go_db_query_duration_seconds_bucket{%s,le=\"%g\"} %d\n", labels, bucket.UpperBound.Seconds(), bucket.Count); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintf(w, "sql4go_db_query_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", labels, s.Count); err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "sql4go_db_query_duration_seconds_sum{%s} %g\nsql4go_db_query_duration_seconds_count{%s} %d\n", labels, s.TotalLatency.Seconds(), labels, s.Count); err != nil {
+			return err
+		}
+	}
+
+	if _, err := fmt.Fprint(w, "# HELP sql4go_db_query_errors_total Failed database queries by class (error, timeout, canceled).\n# TYPE sql4go_db_query_errors_total counter\n"); err != nil {
+		return err
+	}
+	for _, s := range stats {
+		labels := fmt.Sprintf(`database=%q,table=%q,operation=%q`, s.Database, s.Table, s.Operation)
+		if _, err := fmt.Fprintf(w, "sql4go_db_query_errors_total{%s,class=\"error\"} %d\nsql4go_db_query_errors_total{%s,class=\"timeout\"} %d\nsql4go_db_query_errors_total{%s,class=\"canceled\"} %d\n", labels, s.ErrorCount, labels, s.TimeoutCount, labels, s.CancelCount); err != nil {
+			return err
+		}
+	}
+
+	if _, err := fmt.Fprint(w, "# HELP sql4go_db_slow_queries_total Database queries at or above the slow query threshold.\n# TYPE sql4go_db_slow_queries_total counter\n"); err != nil {
+		return err
+	}
+	for _, s := range stats {
+		if _, err := fmt.Fprintf(w, "sql4go_db_slow_queries_total{database=%q,table=%q,operation=%q} %d\n", s.Database, s.Table, s.Operation, s.SlowCount); err != nil {
+			return err
+		}
+	}
+
+	if err := writeCachePrometheusMetrics(w); err != nil {
+		return err
+	}
+
+	// Outbox relay metrics (only tables using WithOutbox)
+	outbox := outboxMetrics.snapshot()
+	if len(outbox) == 0 {
+		return nil
+	}
+	if _, err := fmt.Fprint(w, "# HELP sql4go_outbox_pending Unprocessed outbox rows.\n# TYPE sql4go_outbox_pending gauge\n# HELP sql4go_outbox_lag_seconds Age of the oldest unprocessed outbox row.\n# TYPE sql4go_outbox_lag_seconds gauge\n# HELP sql4go_outbox_relayed_total Outbox rows relayed by this process.\n# TYPE sql4go_outbox_relayed_total counter\n# HELP sql4go_outbox_failures_total Failed outbox relay attempts in this process.\n# TYPE sql4go_outbox_failures_total counter\n"); err != nil {
+		return err
+	}
+	for _, s := range outbox {
+		labels := fmt.Sprintf(`database=%q,table=%q`, s.Database, s.Table)
+		if _, err := fmt.Fprintf(w, "sql4go_outbox_pending{%s} %d\nsql4go_outbox_lag_seconds{%s} %g\nsql4go_outbox_relayed_total{%s} %d\nsql4go_outbox_failures_total{%s} %d\n", labels, s.Pending, labels, s.Lag.Seconds(), labels, s.Relayed, labels, s.Failures); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeCachePrometheusMetrics writes the per-table cache hits and misses of every cache
+// manager used by a repository, labeled with the manager's namespace
+func writeCachePrometheusMetrics(w io.Writer) error {
+	var managers []*redis.Manager
+	cacheManagers.Range(func(key, _ interface{}) bool {
+		managers = append(managers, key.(*redis.Manager))
+		return true
+	})
+	if len(managers) == 0 {
+		return nil
+	}
+	sort.Slice(managers, func(i, j int) bool { return managers[i].Config().Namespace < managers[j].Config().Namespace })
+
+	if _, err := fmt.Fprint(w, "# HELP sql4go_cache_reads_total Cache lookups of repository reads by table and result (hit, miss).\n# TYPE sql4go_cache_reads_total counter\n"); err != nil {
+		return err
+	}
+	for _, manager := range managers {
+		byTable := manager.GetMetrics().ByTable
+		tables := make([]string, 0, len(byTable))
+		for table := range byTable {
+			tables = append(tables, table)
+		}
+		sort.Strings(tables)
+
+		for _, table := range tables {
+			labels := fmt.Sprintf(`namespace=%q,table=%q`, manager.Config().Namespace, table)
+			if _, err := fmt.Fprintf(w, "sql4go_cache_reads_total{%s,result=\"hit\"} %d\nsql4go_cache_reads_total{%s,result=\"miss\"} %d\n", labels, byTable[table].Hits, labels, byTable[table].Misses); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// MetricsHandler returns an http.Handler serving WritePrometheusMetrics for scraping
+func MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_ = WritePrometheusMetrics(w)
+	})
+}
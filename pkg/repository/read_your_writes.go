@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// defaultReadYourWritesWindow is how long a recorded write bypasses the cache by default
+const defaultReadYourWritesWindow = 5 * time.Second
+
+// readYourWritesContextKey holds the recentWrites of a context
+type readYourWritesContextKey struct{}
+
+// recentWrites is the request-scoped set of written entities ("db.table:id" -> time)
+type recentWrites struct {
+	mu      sync.Mutex
+	written map[string]time.Time
+}
+
+// ContextWithReadYourWrites returns a context that records the entities written with it,
+// so later reads of them with the same context bypass the cache
+//
+// Invalidation can trail a write: table wipes are coalesced by Invalidation.DebounceWindow,
+// the outbox relay invalidates after the fact, and other processes drop their L1 copies
+// when the invalidation broadcast arrives. A request reading an entity right after
+// writing it could then be served the old value. ContextWithReadYourWrites gives a
+// request a set of the entities it wrote:
+//   - every committed write with the context records its entity (Create, Update, Delete,
+//     upserts, ...; writes in a transaction once it commits)
+//   - FindByID, FindByIDs and ExistsMany of an id recorded within the window (see
+//     WithReadYourWritesWindow, default 5s) query the database, refreshing the cached
+//     entry like ContextWithCacheBypass
+//
+// Queued write-behind writes are not recorded, as the cache already holds them. Bulk
+// writes without entities (UpdateWhere, DeleteWhere, ...) and collection reads are
+// unaffected. The set lives as long as the context, so use one per request.
+func ContextWithReadYourWrites(ctx context.Context) context.Context {
+	return context.WithValue(ctx, readYourWritesContextKey{}, &recentWrites{written: make(map[string]time.Time)})
+}
+
+// recentWritesOf returns the recorded writes of a context (nil if not enabled)
+func recentWritesOf(ctx context.Context) *recentWrites {
+	writes, _ := ctx.Value(readYourWritesContextKey{}).(*recentWrites)
+	return writes
+}
+
+// recordWrite records a written entity in the context's recent writes, if any
+func (r *GenericRepository[T]) recordWrite(ctx context.Context, entity T) {
+	writes := recentWritesOf(ctx)
+	if writes == nil {
+		return
+	}
+	key := r.writtenKey(r.primaryKeyValue(entity))
+
+	writes.mu.Lock()
+	writes.written[key] = time.Now()
+	writes.mu.Unlock()
+}
+
+// writtenBypass returns a copy of the repository whose cache reads miss if any of ids
+// was written with ctx within the window (nil otherwise)
+func (r *GenericRepository[T]) writtenBypass(ctx context.Context, ids ...interface{}) *GenericRepository[T] {
+	writes := recentWritesOf(ctx)
+	if writes == nil || r.redis == nil {
+		return nil
+	}
+	if r.redis.writeOnly {
+		return nil
+	}
+
+	window := r.options.ReadYourWritesWindow
+	if window <= 0 {
+		window = defaultReadYourWritesWindow
+	}
+
+	recent := false
+	writes.mu.Lock()
+	for _, id := range ids {
+		if written, ok := writes.written[r.writtenKey(id)]; ok && time.Since(written) < window {
+			recent = true
+			break
+		}
+	}
+	writes.mu.Unlock()
+	if !recent {
+		return nil
+	}
+
+	uncached := *r
+	uncached.redis = r.redis.withoutReads()
+	return &uncached
+}
+
+// writtenKey returns the key of an entity in the recent writes
+func (r *GenericRepository[T]) writtenKey(id interface{}) string {
+	return fmt.Sprintf("%s.%s%s%v", r.dbName, r.tableName, cacheKeySeparator, id)
+}
@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ammar0144/sql4go/pkg/redis"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestReadYourWritesBypassesLaggingInvalidation(t *testing.T) {
+	env := newTestEnv(t, func(c *redis.Config) { c.Invalidation.Strategy = redis.InvalidationAsync })
+	repo := newTestRepository[testUser](env)
+	ctx := ContextWithReadYourWrites(context.Background())
+
+	stale := testUser{ID: 1, Name: "ada", Email: "ada@example.com"}
+	updated := testUser{ID: 1, Name: "adeline", Email: "ada@example.com"}
+	env.expectUniqueValues(stale)
+	env.mock.ExpectExec("UPDATE `users`").WillReturnResult(sqlmock.NewResult(0, 1))
+	if _, err := repo.Update(ctx, &updated); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+
+	// The invalidation has not landed yet: the old value is still cached
+	cacheKey := repo.generateCacheKey("find_by_id", "1")
+	if err := env.cache.SetValueWithTTL(ctx, cacheKey, stale, time.Minute); err != nil {
+		t.Fatalf("SetValueWithTTL: %v", err)
+	}
+
+	env.mock.ExpectQuery("SELECT \\* FROM `users` WHERE `users`.`id` = \\?").WillReturnRows(userRows(updated))
+	user, hit, _, err := repo.FindByID(ctx, 1)
+	if err != nil || hit || user.Name != "adeline" {
+		t.Errorf("FindByID after the write = (%+v, hit %v, %v), want adeline from the database", user, hit, err)
+	}
+	env.verify()
+
+	// Other requests are served the cache, which the read refreshed
+	user, hit, _, err = repo.FindByID(context.Background(), 1)
+	if err != nil || !hit || user.Name != "adeline" {
+		t.Errorf("FindByID from another request = (%+v, hit %v, %v), want the refreshed adeline", user, hit, err)
+	}
+}
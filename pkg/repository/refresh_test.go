@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ammar0144/sql4go/pkg/redis"
+)
+
+func TestRefreshOverwritesStaleEntry(t *testing.T) {
+	env := newTestEnv(t)
+	repo := newTestRepository[testUser](env)
+	ctx := context.Background()
+
+	// Another service renamed the user after it was cached
+	if err := env.cache.SetValue(ctx, repo.CacheKeyForID(1), testUser{ID: 1, Name: "stale", Email: "a@x"}); err != nil {
+		t.Fatalf("SetValue: %v", err)
+	}
+	env.mock.ExpectQuery("SELECT \\* FROM `users`").WillReturnRows(userRows(testUser{ID: 1, Name: "fresh", Email: "a@x"}))
+
+	refreshed, err := repo.Refresh(ctx, 1)
+	if err != nil || refreshed == nil || refreshed.Name != "fresh" {
+		t.Fatalf("Refresh = (%+v, %v), want the database row", refreshed, err)
+	}
+	env.verify()
+
+	// No query is expected: the entry now holds the fresh row
+	found, hit, _, err := repo.FindByID(ctx, 1)
+	if err != nil || !hit || found.Name != "fresh" {
+		t.Errorf("FindByID after Refresh = (%+v, hit %v, %v), want the fresh row from the cache", found, hit, err)
+	}
+}
+
+func TestRefreshManyClearsDeletedIDs(t *testing.T) {
+	for _, cacheNulls := range []bool{true, false} {
+		env := newTestEnv(t, func(c *redis.Config) { c.DisableNullCaching = !cacheNulls })
+		repo := newTestRepository[testUser](env)
+		ctx := context.Background()
+
+		for _, id := range []uint{1, 2, 3} {
+			if err := env.cache.SetValue(ctx, repo.CacheKeyForID(id), testUser{ID: id, Name: "stale"}); err != nil {
+				t.Fatalf("SetValue: %v", err)
+			}
+		}
+
+		// User 2 was deleted by another service
+		env.mock.ExpectQuery("SELECT \\* FROM `users` WHERE .*IN \\(\\?,\\?,\\?\\)").
+			WillReturnRows(userRows(testUser{ID: 1, Name: "one"}, testUser{ID: 3, Name: "three"}))
+		if err := repo.RefreshMany(ctx, []interface{}{3, 1, 2, 1}); err != nil {
+			t.Fatalf("RefreshMany: %v", err)
+		}
+		env.verify()
+
+		for id, want := range map[uint]string{1: "one", 3: "three"} {
+			if found, hit, _, err := repo.FindByID(ctx, id); err != nil || !hit || found.Name != want {
+				t.Errorf("caching nulls %v: FindByID(%d) = (%+v, hit %v, %v), want %q from the cache", cacheNulls, id, found, hit, err, want)
+			}
+		}
+
+		key := repo.CacheKeyForID(2)
+		data, err := env.mr.Get(key)
+		switch {
+		case cacheNulls && (err != nil || !redis.IsNullMarker([]byte(data))):
+			t.Errorf("entry of the deleted user = (%q, %v), want the not-found marker", data, err)
+		case !cacheNulls && env.mr.Exists(key):
+			t.Errorf("entry of the deleted user = %q, want it deleted", data)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ammar0144/sql4go/pkg/redis"
+)
+
+// newRegenerationRepository builds a repository with the regeneration lock enabled
+func newRegenerationRepository(t *testing.T, waitTimeout time.Duration) (*testEnv, *GenericRepository[testUser]) {
+	env := newTestEnv(t, func(c *redis.Config) {
+		c.RegenerationLock.Enabled = true
+		c.RegenerationLock.LockTTL = time.Minute
+		c.RegenerationLock.WaitTimeout = waitTimeout
+		c.RegenerationLock.PollInterval = 5 * time.Millisecond
+	})
+	return env, newTestRepository[testUser](env)
+}
+
+func TestRegenerationWinnerReleasesLock(t *testing.T) {
+	env, repo := newRegenerationRepository(t, time.Second)
+	ctx := context.Background()
+
+	env.mock.ExpectQuery("SELECT \\* FROM `users`").WillReturnRows(userRows(testUser{ID: 1, Name: "ann", Email: "a@x"}))
+	if _, hit, stored, err := repo.FindAll(ctx); err != nil || hit || !stored {
+		t.Fatalf("FindAll = (hit %v, stored %v, %v), want a stored database read", hit, stored, err)
+	}
+	env.verify()
+
+	if _, acquired, err := env.cache.TryLock(ctx, repo.findAllCacheKey(), time.Minute); err != nil || !acquired {
+		t.Errorf("TryLock after the winner's FindAll = (%v, %v), want the lock released", acquired, err)
+	}
+}
+
+func TestRegenerationLoserServedFromCache(t *testing.T) {
+	env, repo := newRegenerationRepository(t, 5*time.Second)
+	ctx := context.Background()
+
+	// Another process holds the lock and fills the cache a little later
+	cacheKey := repo.findAllCacheKey()
+	if _, acquired, err := env.cache.TryLock(ctx, cacheKey, time.Minute); err != nil || !acquired {
+		t.Fatalf("TryLock = (%v, %v), want the lock", acquired, err)
+	}
+	filled := make(chan error, 1)
+	go func() {
+		time.Sleep(30 * time.Millisecond)
+		filled <- env.cache.SetLargeValueWithTTL(ctx, cacheKey, []testUser{{ID: 1, Name: "ann", Email: "a@x"}}, time.Hour)
+	}()
+
+	// No query is expected: the loser polls until the winner's entry appears
+	users, hit, _, err := repo.FindAll(ctx)
+	if err := <-filled; err != nil {
+		t.Fatalf("winner's SetLargeValueWithTTL: %v", err)
+	}
+	if err != nil || !hit || len(users) != 1 || users[0].Name != "ann" {
+		t.Fatalf("FindAll = (%+v, hit %v, %v), want the winner's entry", users, hit, err)
+	}
+	env.verify()
+}
+
+func TestRegenerationLoserFallsThroughAfterWaitTimeout(t *testing.T) {
+	waitTimeout := 50 * time.Millisecond
+	env, repo := newRegenerationRepository(t, waitTimeout)
+	ctx := context.Background()
+
+	// The holder never fills the cache
+	if _, acquired, err := env.cache.TryLock(ctx, repo.findAllCacheKey(), time.Minute); err != nil || !acquired {
+		t.Fatalf("TryLock = (%v, %v), want the lock", acquired, err)
+	}
+
+	env.mock.ExpectQuery("SELECT \\* FROM `users`").WillReturnRows(userRows(testUser{ID: 1, Name: "ann", Email: "a@x"}))
+	start := time.Now()
+	users, hit, _, err := repo.FindAll(ctx)
+	if err != nil || hit || len(users) != 1 {
+		t.Fatalf("FindAll = (%+v, hit %v, %v), want a database read", users, hit, err)
+	}
+	if elapsed := time.Since(start); elapsed < waitTimeout {
+		t.Errorf("FindAll returned after %v, before the %v wait timeout", elapsed, waitTimeout)
+	}
+	env.verify()
+}
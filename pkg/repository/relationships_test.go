@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ammar0144/sql4go/pkg/redis"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestRelationshipFieldsParsedOncePerType(t *testing.T) {
+	orderType := reflect.TypeOf(testOrder{})
+	relationshipFieldsCache.Delete(orderType)
+
+	first := relationshipFields(orderType)
+	if len(first) != 2 {
+		t.Fatalf("relationship fields = %+v, want User and Items", first)
+	}
+	if _, ok := relationshipFieldsCache.Load(orderType); !ok {
+		t.Fatal("relationship fields were not cached for the type")
+	}
+
+	second := relationshipFields(orderType)
+	if &first[0] != &second[0] {
+		t.Error("second lookup parsed the type again instead of reusing the cached fields")
+	}
+	if allocs := testing.AllocsPerRun(100, func() { _ = relationshipFields(orderType) }); allocs != 0 {
+		t.Errorf("cached lookup allocates %.0f times, want 0", allocs)
+	}
+}
+
+// testRegion is referenced by a composite key
+type testRegion struct {
+	OrgID uint   `gorm:"primaryKey"`
+	Code  string `gorm:"primaryKey"`
+}
+
+// testSite belongs to a testRegion through a two-column foreign key
+type testSite struct {
+	ID         uint        `gorm:"primaryKey"`
+	OrgID      uint        `gorm:"column:org_id"`
+	RegionCode string      `gorm:"column:region_code"`
+	Region     *testRegion `gorm:"foreignKey:OrgID,RegionCode;references:OrgID,Code"`
+}
+
+func (testSite) TableName() string { return "sites" }
+
+func (s testSite) GetPrimaryKeyValue() interface{} { return s.ID }
+
+func TestCompositeForeignKeyDependency(t *testing.T) {
+	regions := convertStructNameToTableName("testRegion")
+
+	relationships, _ := extractRelationshipsFromEntity(testSite{ID: 1, OrgID: 7, RegionCode: "eu"}, uint(1), 1)
+	related := relationships["belongs_to"]
+	if len(related) != 1 || related[0].EntityType != regions || related[0].EntityID != CompositeKey(uint(7), "eu") {
+		t.Fatalf("relationships = %+v, want %s 7,eu", relationships, regions)
+	}
+
+	// A partially set key is no dependency at all
+	relationships, _ = extractRelationshipsFromEntity(testSite{ID: 1, OrgID: 7}, uint(1), 1)
+	if related := relationships["belongs_to"]; len(related) != 1 || related[0].EntityID != nil {
+		t.Errorf("relationships with an unset key column = %+v, want no region id", related)
+	}
+
+	// A cached read registers under the composite id of the region
+	env := newTestEnv(t)
+	repo := newTestRepository[testSite](env)
+	env.mock.ExpectQuery("SELECT \\* FROM `sites`").WillReturnRows(
+		sqlmock.NewRows([]string{"id", "org_id", "region_code"}).AddRow(1, 7, "eu"))
+	if _, _, stored, err := repo.First(context.Background(), "org_id = ?", 7); err != nil || !stored {
+		t.Fatalf("First = (stored %v, %v), want a cached row", stored, err)
+	}
+	env.verify()
+
+	if key := "gensql4go:deps:" + regions + ":7,eu"; !env.mr.Exists(key) {
+		t.Errorf("dependency set %s missing; keys %q", key, env.cachedKeys())
+	}
+}
+
+func TestRelationshipDepthExceededIsCounted(t *testing.T) {
+	// The order is at depth 0 and its loaded user at depth 1
+	order := testOrder{ID: 3, UserID: 1, User: &testUser{ID: 1, Name: "ann"}}
+
+	tests := []struct {
+		name     string
+		maxDepth int
+		want     uint64
+	}{
+		{name: "graph deeper than the limit", maxDepth: 1, want: 1},
+		{name: "graph within the limit", maxDepth: 2, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, func(c *redis.Config) { c.Invalidation.MaxRelationshipDepth = tt.maxDepth })
+			logger := &recordingLogger{}
+			env.cache.SetLogger(logger)
+			table := fmt.Sprintf("orders_%d", mockDSNs.Load()) // Cut traversals are logged once per table
+
+			related := relatedEntitiesOf(newCacheBackend(env.cache), table, order, order.ID)
+			if len(related["belongs_to"]) == 0 {
+				t.Fatalf("relationships = %+v, want the user", related)
+			}
+
+			metrics := env.cache.GetMetrics()
+			if metrics.RelationshipDepthExceeded != tt.want || metrics.ByTable[table].RelationshipDepthExceeded != tt.want {
+				t.Errorf("RelationshipDepthExceeded = %d (%s: %d), want %d", metrics.RelationshipDepthExceeded, table, metrics.ByTable[table].RelationshipDepthExceeded, tt.want)
+			}
+			if logged := logger.logged(); uint64(len(logged)) != tt.want {
+				t.Errorf("logged %q, want %d warnings", logged, tt.want)
+			}
+		})
+	}
+}
+
+func TestFailedDependencyRegistrationIsNotCached(t *testing.T) {
+	env := newTestEnv(t)
+	repo := newTestRepository[testSite](env)
+	ctx := context.Background()
+
+	// The region's dependency key holds a string, so its SADD fails with WRONGTYPE
+	// while the SET of the value in the same pipeline succeeds
+	regionDeps := "gensql4go:deps:" + convertStructNameToTableName("testRegion") + ":7,eu"
+	_ = env.mr.Set(regionDeps, "not a set")
+
+	for i := 0; i < 2; i++ {
+		env.mock.ExpectQuery("SELECT \\* FROM `sites`").WillReturnRows(
+			sqlmock.NewRows([]string{"id", "org_id", "region_code"}).AddRow(1, 7, "eu"))
+		site, hit, stored, err := repo.First(ctx, "org_id = ?", 7)
+		if err != nil || site == nil || hit || stored {
+			t.Fatalf("First #%d = (%+v, hit %v, stored %v, %v), want an uncached database read", i+1, site, hit, stored, err)
+		}
+	}
+	env.verify()
+
+	for _, key := range env.cachedKeys() {
+		if strings.Contains(key, ":first:") {
+			t.Errorf("value %s of the failed write was left in the cache", key)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// rewarmContextKey marks the context of a re-warming run (see withQueryTimeout)
+type rewarmContextKey struct{}
+
+// RewarmConfig controls re-warming after invalidation
+//
+// Every write wipes the table's find_all and count keys, so right after a burst of writes
+// the next readers all regenerate the most expensive queries at once. With re-warming,
+// each table invalidation schedules a background regeneration of FindAll and Count:
+//   - Debounced: invalidations within RewarmConfig.Debounce of the first one are served
+//     by a single run, so a table is regenerated at most once per Debounce
+//   - The delay is extended by Invalidation.DebounceWindow, so the run follows the
+//     coalesced table wipe instead of being wiped by it
+//   - Runs use their own context with RewarmConfig.Timeout, independent of the request
+//     that triggered the invalidation; it replaces the database QueryTimeout, so it can
+//     be longer than the timeout of interactive queries
+//   - Close stops the scheduler, canceling a run in progress
+//
+// Readers arriving before the run completes still regenerate the entries themselves;
+// enable RegenerationLock to have them wait for the run instead.
+type RewarmConfig struct {
+	Debounce time.Duration // Delay after an invalidation, coalescing later ones (default 5s)
+	Timeout  time.Duration // Timeout of a regeneration run (default 30s)
+}
+
+// withDefaults fills unset fields with their defaults
+func (c RewarmConfig) withDefaults() RewarmConfig {
+	if c.Debounce <= 0 {
+		c.Debounce = 5 * time.Second
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = 30 * time.Second
+	}
+	return c
+}
+
+// RewarmStats counts the re-warming runs of a repository
+type RewarmStats struct {
+	Scheduled    uint64        // Invalidations that requested a run
+	Runs         uint64        // Regeneration runs
+	Errors       uint64        // Runs in which FindAll or Count failed
+	LastDuration time.Duration // Duration of the latest run
+}
+
+// rewarmScheduler regenerates the FindAll and Count caches of a repository after
+// invalidations, debounced; it is shared by pointer with derived repositories
+type rewarmScheduler[T Entity] struct {
+	repo    *GenericRepository[T]
+	config  RewarmConfig
+	trigger chan struct{}
+
+	scheduled    atomic.Uint64
+	runs         atomic.Uint64
+	errors       atomic.Uint64
+	lastDuration atomic.Int64 // Nanoseconds
+
+	cancel    context.CancelFunc
+	stopped   chan struct{}
+	closeOnce sync.Once
+}
+
+// newRewarmScheduler starts the re-warming scheduler of a repository
+func newRewarmScheduler[T Entity](repo *GenericRepository[T], config RewarmConfig) *rewarmScheduler[T] {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &rewarmScheduler[T]{
+		repo:    repo,
+		config:  config,
+		trigger: make(chan struct{}, 1),
+		cancel:  cancel,
+		stopped: make(chan struct{}),
+	}
+	go s.run(ctx)
+	return s
+}
+
+// schedule requests a run; requests while one is pending are coalesced
+func (s *rewarmScheduler[T]) schedule() {
+	s.scheduled.Add(1)
+	select {
+	case s.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// run waits for requests and regenerates the caches Debounce after each first request
+func (s *rewarmScheduler[T]) run(ctx context.Context) {
+	defer close(s.stopped)
+
+	delay := s.config.Debounce
+	if s.repo.redis != nil {
+		delay += s.repo.redis.Config().Invalidation.DebounceWindow
+	}
+
+	for {
+		select {
+		case <-s.trigger:
+		case <-ctx.Done():
+			return
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+
+		// Requests made during the delay are served by this run
+		select {
+		case <-s.trigger:
+		default:
+		}
+		s.rewarm(ctx)
+	}
+}
+
+// rewarm regenerates the FindAll and Count caches
+func (s *rewarmScheduler[T]) rewarm(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(context.WithValue(ctx, rewarmContextKey{}, true), s.config.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	_, _, _, findErr := s.repo.FindAll(ctx)
+	_, _, _, countErr := s.repo.Count(ctx)
+	s.lastDuration.Store(int64(time.Since(start)))
+	s.runs.Add(1)
+
+	if err := errors.Join(findErr, countErr); err != nil {
+		s.errors.Add(1)
+		if s.repo.redis != nil {
+			s.repo.redis.Logger().Printf("sql4go: failed to re-warm caches of %s.%s: %v", s.repo.dbName, s.repo.tableName, err)
+		}
+	}
+}
+
+// close stops the scheduler; pending requests are dropped
+func (s *rewarmScheduler[T]) close() {
+	s.closeOnce.Do(s.cancel)
+	<-s.stopped
+}
+
+// scheduleRewarm requests a re-warming run after a table invalidation (see WithRewarm)
+func (r *GenericRepository[T]) scheduleRewarm() {
+	if r.rewarm != nil && r.tx == nil {
+		r.rewarm.schedule()
+	}
+}
+
+// RewarmStats returns the re-warming counters and whether re-warming is enabled
+// (see WithRewarm)
+func (r *GenericRepository[T]) RewarmStats() (RewarmStats, bool) {
+	if r.rewarm == nil {
+		return RewarmStats{}, false
+	}
+	return RewarmStats{
+		Scheduled:    r.rewarm.scheduled.Load(),
+		Runs:         r.rewarm.runs.Load(),
+		Errors:       r.rewarm.errors.Load(),
+		LastDuration: time.Duration(r.rewarm.lastDuration.Load()),
+	}, true
+}
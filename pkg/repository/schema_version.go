@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cespare/xxhash/v2"
+)
+
+// schemaVersions maps a table's namespace to the schema version its repository uses,
+// so the invalidation callbacks build the same keys as the repository
+var schemaVersions sync.Map // namespace -> version
+
+// SchemaFingerprint computes the structural fingerprint of an entity type
+// It hashes the name, type and gorm/json/msgpack tags of every field, including nested structs.
+//
+// Every cache key of a table carries a fingerprint of the entity's structure:
+//
+//	"sql4go:app:users:<fingerprint>:find_by_id:42"
+//
+// Adding, removing, renaming or retyping a field (or changing its gorm/json/msgpack tags)
+// changes the fingerprint, so a deploy with a changed struct never reads values cached in
+// the old shape; those expire via TTL. Table-wide invalidation still matches every
+// fingerprint, so instances of different versions (rolling deploys) invalidate each other.
+// Use WithSchemaVersion to pin the segment explicitly instead.
+func SchemaFingerprint[T any]() string {
+	return fingerprintType(reflect.TypeOf((*T)(nil)).Elem())
+}
+
+// fingerprintType computes the structural fingerprint of a type
+func fingerprintType(t reflect.Type) string {
+	var b strings.Builder
+	describeType(&b, t, make(map[reflect.Type]bool))
+	return fmt.Sprintf("%08x", uint32(xxhash.Sum64String(b.String())))
+}
+
+// describeType writes a canonical description of a type's structure
+func describeType(b *strings.Builder, t reflect.Type, visited map[reflect.Type]bool) {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array || t.Kind() == reflect.Map {
+		if t.Kind() == reflect.Map {
+			b.WriteString("map[" + t.Key().String() + "]")
+		} else {
+			b.WriteString(t.Kind().String() + " ")
+		}
+		t = t.Elem()
+	}
+
+	b.WriteString(t.String())
+	if t.Kind() != reflect.Struct || t == reflect.TypeOf(time.Time{}) || visited[t] {
+		return
+	}
+	visited[t] = true
+
+	b.WriteString("{")
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		fmt.Fprintf(b, "%s %q %q %q ", field.Name, field.Tag.Get("gorm"), field.Tag.Get("json"), field.Tag.Get("msgpack"))
+		describeType(b, field.Type, visited)
+		b.WriteString(";")
+	}
+	b.WriteString("}")
+}
+
+// registerSchemaVersion records the schema version used for a table's namespace
+func registerSchemaVersion(namespace, version string) {
+	schemaVersions.Store(namespace, version)
+}
+
+// versionedNamespace returns the namespace of a table's cache keys, including the schema
+// version registered by its repository or, failing that, the fingerprint of modelType
+func versionedNamespace(namespace string, modelType reflect.Type) string {
+	if version, ok := schemaVersions.Load(namespace); ok {
+		return namespace + cacheKeySeparator + version.(string)
+	}
+	return namespace + cacheKeySeparator + fingerprintType(modelType)
+}
+
+// SchemaVersion returns the schema version segment of this repository's cache keys:
+// the pinned version (WithSchemaVersion) or the computed fingerprint
+func (r *GenericRepository[T]) SchemaVersion() string {
+	return r.schemaVersion
+}
@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"reflect"
+	"testing"
+)
+
+// baseUserType returns the entity type the fingerprint tests change one aspect of
+// Each test type is a local "user" declared in its own function, so the fingerprints
+// differ only by structure: local types of the same name have the same reflect name.
+func baseUserType() reflect.Type {
+	type address struct {
+		City string `json:"city"`
+	}
+	type user struct {
+		ID      uint   `gorm:"primaryKey" json:"id"`
+		Name    string `gorm:"column:name" json:"name"`
+		Address address
+	}
+	return reflect.TypeOf(user{})
+}
+
+func TestFingerprintTypeTracksStructure(t *testing.T) {
+	base := fingerprintType(baseUserType())
+	if again := fingerprintType(baseUserType()); again != base {
+		t.Fatalf("fingerprint of an unchanged type = %s, then %s", base, again)
+	}
+
+	changed := map[string]func() reflect.Type{
+		"added field": func() reflect.Type {
+			type address struct {
+				City string `json:"city"`
+			}
+			type user struct {
+				ID      uint   `gorm:"primaryKey" json:"id"`
+				Name    string `gorm:"column:name" json:"name"`
+				Address address
+				Email   string
+			}
+			return reflect.TypeOf(user{})
+		},
+		"renamed field": func() reflect.Type {
+			type address struct {
+				City string `json:"city"`
+			}
+			type user struct {
+				ID       uint   `gorm:"primaryKey" json:"id"`
+				FullName string `gorm:"column:name" json:"name"`
+				Address  address
+			}
+			return reflect.TypeOf(user{})
+		},
+		"retyped field": func() reflect.Type {
+			type address struct {
+				City string `json:"city"`
+			}
+			type user struct {
+				ID      uint64 `gorm:"primaryKey" json:"id"`
+				Name    string `gorm:"column:name" json:"name"`
+				Address address
+			}
+			return reflect.TypeOf(user{})
+		},
+		"gorm tag": func() reflect.Type {
+			type address struct {
+				City string `json:"city"`
+			}
+			type user struct {
+				ID      uint   `gorm:"primaryKey" json:"id"`
+				Name    string `gorm:"column:full_name" json:"name"`
+				Address address
+			}
+			return reflect.TypeOf(user{})
+		},
+		"json tag": func() reflect.Type {
+			type address struct {
+				City string `json:"city"`
+			}
+			type user struct {
+				ID      uint   `gorm:"primaryKey" json:"id"`
+				Name    string `gorm:"column:name" json:"full_name"`
+				Address address
+			}
+			return reflect.TypeOf(user{})
+		},
+		"msgpack tag": func() reflect.Type {
+			type address struct {
+				City string `json:"city"`
+			}
+			type user struct {
+				ID      uint   `gorm:"primaryKey" json:"id"`
+				Name    string `gorm:"column:name" json:"name" msgpack:"n"`
+				Address address
+			}
+			return reflect.TypeOf(user{})
+		},
+		"nested struct field": func() reflect.Type {
+			type address struct {
+				Town string `json:"city"`
+			}
+			type user struct {
+				ID      uint   `gorm:"primaryKey" json:"id"`
+				Name    string `gorm:"column:name" json:"name"`
+				Address address
+			}
+			return reflect.TypeOf(user{})
+		},
+	}
+	for name, build := range changed {
+		if fingerprint := fingerprintType(build()); fingerprint == base {
+			t.Errorf("%s: fingerprint %s unchanged", name, fingerprint)
+		}
+	}
+}
+
+func TestFingerprintTypeIgnoresUnexportedFields(t *testing.T) {
+	type address struct {
+		City string `json:"city"`
+	}
+	type user struct {
+		ID      uint   `gorm:"primaryKey" json:"id"`
+		Name    string `gorm:"column:name" json:"name"`
+		Address address
+		loaded  bool // Not serialized
+	}
+	if fingerprint, base := fingerprintType(reflect.TypeOf(user{})), fingerprintType(baseUserType()); fingerprint != base {
+		t.Errorf("fingerprint with an unexported field = %s, want %s", fingerprint, base)
+	}
+}
+
+func TestFingerprintTypeHandlesRecursiveTypes(t *testing.T) {
+	type node struct {
+		ID       uint
+		Parent   *node
+		Children []node
+	}
+	if SchemaFingerprint[node]() != fingerprintType(reflect.TypeOf(node{})) {
+		t.Error("SchemaFingerprint differs from the fingerprint of the type")
+	}
+}
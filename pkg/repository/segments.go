@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/ammar0144/sql4go/pkg/redis"
+)
+
+// segmentIndex describes a segmented collection
+//
+// With WithSegmentedCollections, a FindAll result is stored as:
+//   - "<ns>:find_all:seg:index"         -> segmentIndex (total rows, segment count, generation)
+//   - "<ns>:find_all:seg:<gen>:<n>"     -> rows [n*size, (n+1)*size)
+//
+// and an ordered FindWhere result (chained Order, ORDER BY in the condition, or
+// OrderingPrimaryKey) under "<ns>:find_where:seg:<query hash>:index" and
+// "<ns>:find_where:seg:<query hash>:<gen>:<n>". Unordered FindWhere results keep a single
+// entry: without a stable order, a page of segments is not a page of the result.
+//
+// Segments are written before the index, and segment keys carry the index generation,
+// so a reader never combines segments of different regenerations. Writes invalidate the
+// index along with the rest of the table's keys, which invalidates all segments logically;
+// stale segments are removed by the same pattern wipe or expire with their TTL. A
+// FindWhere index is also registered in the dependency sets of its rows, like the single
+// entry it replaces. FindPage and FindWherePage read only the segments covering the page.
+type segmentIndex struct {
+	Generation   string `json:"generation" msgpack:"generation"`
+	TotalRows    int    `json:"total_rows" msgpack:"total_rows"`
+	SegmentSize  int    `json:"segment_size" msgpack:"segment_size"`
+	SegmentCount int    `json:"segment_count" msgpack:"segment_count"`
+}
+
+// segmentIndexKey returns the index key of a segmented collection
+// query is the query key suffix of a FindWhere collection, "" for FindAll.
+func (r *GenericRepository[T]) segmentIndexKey(operation, query string) string {
+	if query == "" {
+		return r.generateCacheKey(operation, "seg:index")
+	}
+	return buildCacheKey(r.keyNamespace, operation, "seg:"+query+":index", maxKeyLength(r.redis))
+}
+
+// segmentKey returns the key of one segment of a segmented collection
+func (r *GenericRepository[T]) segmentKey(operation, query, generation string, segment int) string {
+	if query == "" {
+		return r.generateCacheKey(operation, fmt.Sprintf("seg:%s:%d", generation, segment))
+	}
+	return buildCacheKey(r.keyNamespace, operation, fmt.Sprintf("seg:%s:%s:%d", query, generation, segment), maxKeyLength(r.redis))
+}
+
+// segmentedWhere reports whether a FindWhere result is cached as segments, and the
+// query key suffix naming its keys
+func (r *GenericRepository[T]) segmentedWhere(query interface{}, args []interface{}) (string, bool) {
+	if r.options.SegmentSize <= 0 || (!r.ordered(query) && r.options.Ordering != OrderingPrimaryKey) {
+		return "", false
+	}
+	return queryKeySuffix(query, args, r.chainKey(), r.options.KeyHasher), true
+}
+
+// getSegments reads rows [offset, offset+limit) of a segmented collection
+// A negative limit reads all rows from offset. Only the segments covering the range are read.
+// Returns ErrKeyNotFound if the index or any needed segment is missing.
+func (r *GenericRepository[T]) getSegments(ctx context.Context, operation, query string, offset, limit int) ([]T, error) {
+	var index segmentIndex
+	if err := r.redis.GetValue(ctx, r.segmentIndexKey(operation, query), &index); err != nil {
+		return nil, err
+	}
+	if index.SegmentSize <= 0 {
+		return nil, redis.ErrKeyNotFound // Malformed index - regenerate
+	}
+
+	end := index.TotalRows
+	if limit >= 0 && offset+limit < end {
+		end = offset + limit
+	}
+	if offset >= end {
+		return []T{}, nil
+	}
+
+	entities := make([]T, 0, end-offset)
+	for segment := offset / index.SegmentSize; segment <= (end-1)/index.SegmentSize; segment++ {
+		var rows []T
+		if err := r.redis.GetLargeValue(ctx, r.segmentKey(operation, query, index.Generation, segment), &rows); err != nil {
+			return nil, redis.ErrKeyNotFound // Segment evicted or expired - regenerate
+		}
+
+		// Keep only the rows within [offset, end)
+		segmentStart := segment * index.SegmentSize
+		from := max(offset-segmentStart, 0)
+		to := min(end-segmentStart, len(rows))
+		if from < to {
+			entities = append(entities, rows[from:to]...)
+		}
+	}
+
+	return entities, nil
+}
+
+// setSegments stores a collection as segments followed by its index
+// The index is registered in the dependency sets, if any.
+func (r *GenericRepository[T]) setSegments(ctx context.Context, operation, query string, entities []T, dependencies map[string][]interface{}) error {
+	size := r.options.SegmentSize
+	ttl := r.operationTTL(operation)
+	index := segmentIndex{
+		Generation:   strconv.FormatInt(time.Now().UnixNano(), 36),
+		TotalRows:    len(entities),
+		SegmentSize:  size,
+		SegmentCount: (len(entities) + size - 1) / size, // Ceiling division
+	}
+
+	for segment := 0; segment < index.SegmentCount; segment++ {
+		start := segment * size
+		end := min(start+size, len(entities))
+		if err := r.redis.SetLargeValueWithTTL(ctx, r.segmentKey(operation, query, index.Generation, segment), entities[start:end], ttl); err != nil {
+			return err
+		}
+	}
+
+	// Index last, so readers never see an index without its segments
+	indexKey := r.segmentIndexKey(operation, query)
+	if len(dependencies) > 0 {
+		return r.redis.SetValueWithDependenciesTTL(ctx, indexKey, index, dependencies, ttl)
+	}
+	return r.redis.SetValueWithTTL(ctx, indexKey, index, ttl)
+}
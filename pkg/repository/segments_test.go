@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestSegmentedFindAllAndPage(t *testing.T) {
+	env := newTestEnv(t)
+	repo := newTestRepository[testUser](env, WithSegmentedCollections(2))
+	ctx := context.Background()
+
+	users := []testUser{{ID: 1, Name: "a"}, {ID: 2, Name: "b"}, {ID: 3, Name: "c"}, {ID: 4, Name: "d"}, {ID: 5, Name: "e"}}
+	env.mock.ExpectQuery("SELECT \\* FROM `users`").WillReturnRows(userRows(users...))
+
+	all, _, stored, err := repo.FindAll(ctx)
+	if err != nil || len(all) != 5 || !stored {
+		t.Fatalf("FindAll = (%d rows, stored %v, %v)", len(all), stored, err)
+	}
+	segments := 0
+	for _, key := range env.cachedKeys() {
+		if strings.Contains(key, ":find_all:seg:") && !strings.HasSuffix(key, ":seg:index") && !strings.Contains(key, "_internal") {
+			segments++
+		}
+	}
+	if segments != 3 || !env.mr.Exists(repo.segmentIndexKey("find_all", "")) {
+		t.Fatalf("want an index and 3 segments; keys: %v", env.cachedKeys())
+	}
+
+	page, hit, _, err := repo.FindPage(ctx, 3, 2)
+	if err != nil || !hit || len(page) != 2 || page[0].ID != 4 || page[1].ID != 5 {
+		t.Fatalf("FindPage(3, 2) = (%+v, hit %v, %v), want rows 4 and 5 from the cache", page, hit, err)
+	}
+	env.verify()
+}
+
+func TestSegmentedOrderedFindWhereAndPage(t *testing.T) {
+	env := newTestEnv(t)
+	repo := newTestRepository[testUser](env, WithSegmentedCollections(2))
+	ctx := context.Background()
+
+	query := "name <> ? ORDER BY id"
+	users := []testUser{{ID: 1, Name: "a"}, {ID: 2, Name: "b"}, {ID: 3, Name: "c"}, {ID: 4, Name: "d"}, {ID: 5, Name: "e"}}
+	env.mock.ExpectQuery("SELECT \\* FROM `users` WHERE name <> \\? ORDER BY id").WithArgs("x").WillReturnRows(userRows(users...))
+
+	if found, _, stored, err := repo.FindWhere(ctx, query, "x"); err != nil || len(found) != 5 || !stored {
+		t.Fatalf("FindWhere = (%d rows, stored %v, %v), want 5 stored rows", len(found), stored, err)
+	}
+	segmentQuery, segmented := repo.segmentedWhere(query, []interface{}{"x"})
+	if !segmented || !env.mr.Exists(repo.segmentIndexKey("find_where", segmentQuery)) {
+		t.Fatalf("no FindWhere segment index; keys: %v", env.cachedKeys())
+	}
+	segments := 0
+	for _, key := range env.cachedKeys() {
+		if strings.Contains(key, ":find_where:seg:") && !strings.HasSuffix(key, ":index") && !strings.Contains(key, "_internal") {
+			segments++
+		}
+	}
+	if segments != 3 {
+		t.Errorf("FindWhere stored %d segments, want 3; keys: %v", segments, env.cachedKeys())
+	}
+	if env.mr.Exists(repo.generateCacheKeyFromQuery("find_where", query, "x")) {
+		t.Error("segmented FindWhere also stored a single entry")
+	}
+
+	// Hits read the segments: all of them, or only those of a page
+	found, hit, _, err := repo.FindWhere(ctx, query, "x")
+	if err != nil || !hit || len(found) != 5 || found[4].ID != 5 {
+		t.Fatalf("second FindWhere = (%d rows, hit %v, %v), want all rows from the cache", len(found), hit, err)
+	}
+	page, hit, _, err := repo.FindWherePage(ctx, 1, 2, query, "x")
+	if err != nil || !hit || len(page) != 2 || page[0].ID != 2 || page[1].ID != 3 {
+		t.Fatalf("FindWherePage(1, 2) = (%+v, hit %v, %v), want rows 2 and 3 from the cache", page, hit, err)
+	}
+	env.verify()
+
+	// A write invalidates the index like FindAll's, and the next read regenerates it
+	env.mock.ExpectQuery("SELECT \\* FROM `users` WHERE `users`.`id` = \\?").WillReturnRows(userRows(users[4]))
+	env.mock.ExpectExec("DELETE FROM `users`").WillReturnResult(sqlmock.NewResult(0, 1))
+	if _, err := repo.Delete(ctx, 5); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	env.mock.ExpectQuery("SELECT \\* FROM `users` WHERE name <> \\? ORDER BY id").WithArgs("x").WillReturnRows(userRows(users[:4]...))
+	page, hit, _, err = repo.FindWherePage(ctx, 2, 5, query, "x")
+	if err != nil || hit || len(page) != 2 || page[1].ID != 4 {
+		t.Fatalf("FindWherePage after Delete = (%+v, hit %v, %v), want rows 3 and 4 from the database", page, hit, err)
+	}
+	env.verify()
+}
+
+func TestSegmentedFindWhereIndexIsInvalidatedByItsRows(t *testing.T) {
+	env := newTestEnv(t)
+	repo := newTestRepository[testUser](env, WithSegmentedCollections(1))
+	ctx := context.Background()
+
+	query := "name <> ? ORDER BY id"
+	env.mock.ExpectQuery("SELECT \\* FROM `users` WHERE name <> \\? ORDER BY id").WithArgs("x").
+		WillReturnRows(userRows(testUser{ID: 1, Name: "a"}, testUser{ID: 2, Name: "b"}))
+	if _, _, stored, err := repo.FindWhere(ctx, query, "x"); err != nil || !stored {
+		t.Fatalf("FindWhere = (stored %v, %v)", stored, err)
+	}
+	env.verify()
+
+	segmentQuery, _ := repo.segmentedWhere(query, []interface{}{"x"})
+	indexKey := repo.segmentIndexKey("find_where", segmentQuery)
+	if err := env.cache.InvalidateEntityDependencies(ctx, repo.tableName, uint(2)); err != nil {
+		t.Fatalf("InvalidateEntityDependencies: %v", err)
+	}
+	if env.mr.Exists(indexKey) {
+		t.Errorf("segment index %s survived the invalidation of one of its rows", indexKey)
+	}
+}
+
+func TestSegmentedCollectionsLeaveUnorderedFindWhereWhole(t *testing.T) {
+	env := newTestEnv(t)
+	repo := newTestRepository[testUser](env, WithSegmentedCollections(1), WithOrderingPolicy(OrderingIgnore))
+	ctx := context.Background()
+
+	env.mock.ExpectQuery("SELECT \\* FROM `users` WHERE name <> \\?$").WithArgs("x").
+		WillReturnRows(userRows(testUser{ID: 1, Name: "a"}, testUser{ID: 2, Name: "b"}))
+	if _, _, stored, err := repo.FindWhere(ctx, "name <> ?", "x"); err != nil || !stored {
+		t.Fatalf("FindWhere = (stored %v, %v)", stored, err)
+	}
+	key := repo.CacheKeyForQuery("find_where", "name <> ?", "x")
+	if !env.mr.Exists(key) {
+		t.Errorf("unordered FindWhere entry %s not stored whole; keys: %v", key, env.cachedKeys())
+	}
+	for _, k := range env.cachedKeys() {
+		if strings.Contains(k, ":seg:") {
+			t.Errorf("unordered FindWhere stored segment key %s", k)
+		}
+	}
+
+	page, hit, _, err := repo.FindWherePage(ctx, 1, 1, "name <> ?", "x")
+	if err != nil || !hit || len(page) != 1 || page[0].ID != 2 {
+		t.Fatalf("FindWherePage = (%+v, hit %v, %v), want row 2 cut from the cached result", page, hit, err)
+	}
+	env.verify()
+}
@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ammar0144/sql4go/pkg/redis"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// testRecord has the column types whose cached copy most easily drifts from the row
+type testRecord struct {
+	ID        uint `gorm:"primaryKey"`
+	CreatedAt time.Time
+	Price     string                 // DECIMAL(20,8) read as a string
+	Amount    json.Number            // DECIMAL kept as a JSON number
+	Blob      []byte                 // VARBINARY
+	Payload   json.RawMessage        // JSON column
+	Attrs     map[string]interface{} `gorm:"serializer:json"`
+}
+
+func (testRecord) TableName() string { return "records" }
+
+func (d testRecord) GetPrimaryKeyValue() interface{} { return d.ID }
+
+// assertRecordsEquivalent compares a cache hit with the database read it replaced
+// time.Time is compared by instant and raw JSON byte for byte
+func assertRecordsEquivalent(t *testing.T, fromDB, fromCache testRecord) {
+	t.Helper()
+
+	if !fromCache.CreatedAt.Equal(fromDB.CreatedAt) {
+		t.Errorf("CreatedAt = %v from the cache, %v from the database", fromCache.CreatedAt, fromDB.CreatedAt)
+	}
+	if !bytes.Equal(fromCache.Payload, fromDB.Payload) {
+		t.Errorf("Payload = %q from the cache, %q from the database", fromCache.Payload, fromDB.Payload)
+	}
+
+	fromDB.CreatedAt, fromCache.CreatedAt = time.Time{}, time.Time{}
+	fromDB.Payload, fromCache.Payload = nil, nil
+	if !reflect.DeepEqual(fromCache, fromDB) {
+		t.Errorf("cached record = %#v, database read = %#v", fromCache, fromDB)
+	}
+}
+
+func TestCacheHitMatchesDatabaseRead(t *testing.T) {
+	createdAt := time.Date(2024, 2, 29, 23, 59, 58, 123456789, time.FixedZone("UTC+5:30", 5*3600+1800))
+
+	tests := []struct {
+		name string
+		row  testRecord
+	}{
+		{
+			name: "nested raw JSON keeps whitespace and key order",
+			row: testRecord{
+				ID: 1, CreatedAt: createdAt, Price: "12345678901.23456789", Amount: "9007199254740993",
+				Blob:    []byte{0x00, 0xff, 0x10, '"', '\\'},
+				Payload: json.RawMessage(`{"z": 1,  "a": {"n": [1, 2.50, {"deep": null}]}, "big": 9007199254740993}`),
+			},
+		},
+		{
+			name: "top-level JSON array and high precision decimal",
+			row: testRecord{
+				ID: 2, CreatedAt: createdAt.UTC(), Price: "0.00000001", Amount: "-1.10",
+				Blob:    []byte("plain bytes"),
+				Payload: json.RawMessage("[\n  \"x\",\n  1e3\n]"),
+			},
+		},
+		{
+			name: "empty bytes and JSON null",
+			row: testRecord{
+				ID: 3, CreatedAt: createdAt, Price: "0", Amount: "0",
+				Blob:    []byte{},
+				Payload: json.RawMessage(`null`),
+			},
+		},
+	}
+
+	for _, format := range []redis.SerializationFormat{redis.SerializationJSON, redis.SerializationMsgPack} {
+		for _, tt := range tests {
+			t.Run(string(format)+"/"+tt.name, func(t *testing.T) {
+				env := newTestEnv(t, func(c *redis.Config) { c.SerializationFormat = format })
+				repo := newTestRepository[testRecord](env)
+				ctx := context.Background()
+
+				var attrs []byte
+				if tt.row.Attrs != nil {
+					attrs, _ = json.Marshal(tt.row.Attrs)
+				}
+				env.mock.ExpectQuery("SELECT \\* FROM `records`").WillReturnRows(
+					sqlmock.NewRows([]string{"id", "created_at", "price", "amount", "blob", "payload", "attrs"}).
+						AddRow(tt.row.ID, tt.row.CreatedAt, tt.row.Price, string(tt.row.Amount), tt.row.Blob, []byte(tt.row.Payload), attrs))
+
+				fromDB, hit, _, err := repo.FindByID(ctx, tt.row.ID)
+				if err != nil || hit || fromDB == nil {
+					t.Fatalf("first FindByID = (%+v, hit %v, %v), want a database read", fromDB, hit, err)
+				}
+				assertRecordsEquivalent(t, tt.row, *fromDB)
+
+				fromCache, hit, _, err := repo.FindByID(ctx, tt.row.ID)
+				if err != nil || !hit || fromCache == nil {
+					t.Fatalf("second FindByID = (%+v, hit %v, %v), want a cache hit", fromCache, hit, err)
+				}
+				assertRecordsEquivalent(t, *fromDB, *fromCache)
+				env.verify()
+			})
+		}
+	}
+}
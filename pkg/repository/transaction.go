@@ -0,0 +1,162 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// txWrite is a write recorded in a transaction, or one entity of a batch (see notifyWrites)
+type txWrite[T Entity] struct {
+	operation    ChangeOperation
+	entity       T
+	writeThrough bool // See notifyWrite
+}
+
+// txScope collects the writes of a transaction, for invalidation and notification on commit
+type txScope[T Entity] struct {
+	mu           sync.Mutex
+	writes       []txWrite[T]
+	associations []associationChange // Associated sides of association writes
+}
+
+// record remembers a written entity
+func (s *txScope[T]) record(operation ChangeOperation, entity T, writeThrough bool) {
+	s.mu.Lock()
+	s.writes = append(s.writes, txWrite[T]{operation: operation, entity: entity, writeThrough: writeThrough})
+	s.mu.Unlock()
+}
+
+// recordAssociation remembers the associated side of an association write
+func (s *txScope[T]) recordAssociation(change associationChange) {
+	s.mu.Lock()
+	s.associations = append(s.associations, change)
+	s.mu.Unlock()
+}
+
+// merge adopts the writes of a nested scope whose savepoint was kept
+func (s *txScope[T]) merge(child *txScope[T]) {
+	child.mu.Lock()
+	writes, associations := child.writes, child.associations
+	child.mu.Unlock()
+
+	s.mu.Lock()
+	s.writes = append(s.writes, writes...)
+	s.associations = append(s.associations, associations...)
+	s.mu.Unlock()
+}
+
+// WithTransaction runs fn inside a database transaction
+// The transaction commits if fn returns nil and rolls back otherwise. An optional
+// isolation level (e.g. sql.LevelSerializable) applies to this transaction only;
+// without one the connection's default is used. Nested calls use a savepoint of the
+// outer transaction and ignore the isolation level; their writes join the outer
+// transaction's only if the nested call returns nil.
+//
+// fn receives a repository bound to the transaction:
+//   - Reads inside the transaction query the database directly; the cache is neither
+//     read (it does not reflect uncommitted writes) nor populated (they may roll back)
+//   - Cache invalidation of written entities and their change events are deferred until
+//     the transaction commits, so concurrent readers never cache a value that is rolled
+//     back, and are skipped on rollback. Write methods therefore report cacheInvalidated = false.
+//   - Writes are executed synchronously, even under the write_behind strategy
+//
+// Read-only repositories (ReadOnlyTx, WithReadTransaction) reject write methods with
+// ErrReadOnlyTransaction before issuing any statement.
+func (r *GenericRepository[T]) WithTransaction(ctx context.Context, fn func(tx Repository[T]) error, isolation ...sql.IsolationLevel) error {
+	if r.readOnly {
+		return ErrReadOnlyTransaction
+	}
+
+	if r.tx != nil {
+		// Nested: a savepoint with its own scope, merged into the outer scope only if fn
+		// succeeds - writes rolled back to the savepoint are never invalidated or notified
+		child := &txScope[T]{}
+		err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			return fn(r.bindTransaction(tx, child))
+		})
+		if err != nil {
+			return err
+		}
+		r.tx.merge(child)
+		return nil
+	}
+
+	var opts []*sql.TxOptions
+	if len(isolation) > 0 {
+		opts = append(opts, &sql.TxOptions{Isolation: isolation[0]})
+	}
+
+	scope := &txScope[T]{}
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := fn(r.bindTransaction(tx, scope)); err != nil {
+			return err
+		}
+		return r.insertOutboxEntries(tx, scope.writes) // No-op unless WithOutbox
+	}, opts...)
+	if err != nil {
+		return err
+	}
+
+	// Committed - invalidate what was written and notify subscribers
+	if r.redis != nil {
+		for _, change := range scope.associations {
+			r.invalidateAssociated(ctx, change)
+		}
+	}
+	r.notifyWrites(ctx, scope.writes)
+	return nil
+}
+
+// WithReadTransaction runs fn inside a read-only transaction for consistent multi-query reads
+// The transaction runs at REPEATABLE READ, so all reads in fn see one point-in-time
+// snapshot; they bypass the cache, which is not part of that snapshot. MySQL rejects
+// writes in the transaction. Called on a repository already bound to a transaction,
+// fn simply runs in that transaction.
+func (r *GenericRepository[T]) WithReadTransaction(ctx context.Context, fn func(tx Repository[T]) error) error {
+	if r.tx != nil {
+		return fn(r)
+	}
+
+	opts := &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true}
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		txRepo := r.bindTransaction(tx, &txScope[T]{})
+		txRepo.readOnly = true
+		return fn(txRepo)
+	}, opts)
+}
+
+// ReadOnlyTx returns a repository whose database reads each run in a read-only
+// transaction (START TRANSACTION READ ONLY), which MySQL optimizes and proxies can
+// route to replicas. Cached reads are served as usual. Write methods fail fast with
+// ErrReadOnlyTransaction. For several reads sharing one snapshot use WithReadTransaction.
+func (r *GenericRepository[T]) ReadOnlyTx(ctx context.Context) Repository[T] {
+	newRepo := *r
+	newRepo.readOnly = true
+	return &newRepo
+}
+
+// readQuery runs a database read, inside its own read-only transaction if the
+// repository is read-only and not already bound to a transaction. Under
+// ContextWithCacheOnly the read is refused with ErrCacheOnlyMiss.
+func (r *GenericRepository[T]) readQuery(ctx context.Context, query func(db *gorm.DB) error) error {
+	if cacheModeOf(ctx) == cacheModeOnly {
+		return ErrCacheOnlyMiss
+	}
+	if !r.readOnly || r.tx != nil {
+		return query(r.db.WithContext(ctx))
+	}
+	return r.db.WithContext(ctx).Transaction(query, &sql.TxOptions{ReadOnly: true})
+}
+
+// bindTransaction returns a copy of the repository bound to a transaction
+func (r *GenericRepository[T]) bindTransaction(tx *gorm.DB, scope *txScope[T]) *GenericRepository[T] {
+	txRepo := *r
+	txRepo.db = tx
+	txRepo.redis = nil       // Bypass the cache; invalidation runs on the outer repository
+	txRepo.writeBehind = nil // Write synchronously within the transaction
+	txRepo.tx = scope
+	return &txRepo
+}
@@ -0,0 +1,217 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ammar0144/sql4go/pkg/redis"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestWithTransactionIsolationLevel(t *testing.T) {
+	env := newTestEnv(t)
+	repo := newTestRepository[testUser](env)
+	ctx := context.Background()
+	noop := func(tx Repository[testUser]) error { return nil }
+
+	env.mock.ExpectBegin()
+	env.mock.ExpectCommit()
+	if err := repo.WithTransaction(ctx, noop, sql.LevelSerializable); err != nil {
+		t.Fatalf("WithTransaction: %v", err)
+	}
+
+	env.mock.ExpectBegin()
+	env.mock.ExpectCommit()
+	if err := repo.WithTransaction(ctx, noop); err != nil {
+		t.Fatalf("WithTransaction: %v", err)
+	}
+	env.verify()
+
+	recorded := env.txs.recorded()
+	if len(recorded) != 2 {
+		t.Fatalf("began %d transactions, want 2", len(recorded))
+	}
+	if got := recorded[0].Isolation; got != driver.IsolationLevel(sql.LevelSerializable) {
+		t.Errorf("isolation = %v, want serializable", sql.IsolationLevel(got))
+	}
+	if got := recorded[1].Isolation; got != driver.IsolationLevel(sql.LevelDefault) {
+		t.Errorf("isolation without a level = %v, want the connection default", sql.IsolationLevel(got))
+	}
+}
+
+func TestWithReadTransactionBypassesCache(t *testing.T) {
+	env := newTestEnv(t)
+	repo := newTestRepository[testUser](env)
+	ctx := context.Background()
+
+	// The cache holds a stale copy of user 1
+	env.mock.ExpectQuery("SELECT \\* FROM `users`").WillReturnRows(userRows(testUser{ID: 1, Name: "stale"}))
+	if _, _, stored, err := repo.FindByID(ctx, 1); err != nil || !stored {
+		t.Fatalf("FindByID = (stored %v, %v), want a cached row", stored, err)
+	}
+	keys := env.cachedKeys()
+
+	env.mock.ExpectBegin()
+	env.mock.ExpectQuery("SELECT \\* FROM `users` WHERE `users`.`id` = \\?").
+		WithArgs(1, 1).
+		WillReturnRows(userRows(testUser{ID: 1, Name: "snapshot"}))
+	env.mock.ExpectQuery("SELECT \\* FROM `users` WHERE `users`.`id` = \\?").
+		WithArgs(2, 1).
+		WillReturnRows(userRows(testUser{ID: 2, Name: "bob"}))
+	env.mock.ExpectCommit()
+
+	err := repo.WithReadTransaction(ctx, func(tx Repository[testUser]) error {
+		user, hit, stored, err := tx.FindByID(ctx, 1)
+		if err != nil {
+			return err
+		}
+		if hit || stored || user.Name != "snapshot" {
+			t.Errorf("FindByID(1) = (%q, hit %v, stored %v), want the snapshot row, uncached", user.Name, hit, stored)
+		}
+		if _, hit, stored, err = tx.FindByID(ctx, 2); err != nil || hit || stored {
+			t.Errorf("FindByID(2) = (hit %v, stored %v, %v), want an uncached read", hit, stored, err)
+		}
+		if _, err := tx.Update(ctx, user); !IsReadOnlyTransaction(err) {
+			t.Errorf("Update in a read transaction = %v, want ErrReadOnlyTransaction", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WithReadTransaction: %v", err)
+	}
+	env.verify()
+
+	if got := env.cachedKeys(); len(got) != len(keys) {
+		t.Errorf("cache keys after the read transaction = %v, want %v", got, keys)
+	}
+	if recorded := env.txs.recorded(); len(recorded) != 1 || !recorded[0].ReadOnly ||
+		recorded[0].Isolation != driver.IsolationLevel(sql.LevelRepeatableRead) {
+		t.Errorf("transaction options = %+v, want a read-only REPEATABLE READ transaction", recorded)
+	}
+}
+
+func TestNestedTransactionFailureKeepsOuterWrites(t *testing.T) {
+	env := newTestEnv(t)
+	repo := newTestRepository[testUser](env)
+	ctx := context.Background()
+
+	events := make(chan ChangeEvent, 4)
+	repo.Subscribe(func(event ChangeEvent) { events <- event })
+
+	env.mock.ExpectBegin()
+	env.mock.ExpectExec("UPDATE `users` SET").WithArgs("ann 2", "a@x", 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	env.mock.ExpectExec("SAVEPOINT").WillReturnResult(sqlmock.NewResult(0, 0))
+	env.mock.ExpectExec("UPDATE `users` SET").WithArgs("bob 2", "b@x", 2).WillReturnResult(sqlmock.NewResult(0, 1))
+	env.mock.ExpectExec("ROLLBACK TO SAVEPOINT").WillReturnResult(sqlmock.NewResult(0, 0))
+	env.mock.ExpectCommit()
+
+	innerErr := errors.New("inner failure")
+	err := repo.WithTransaction(ctx, func(tx Repository[testUser]) error {
+		if _, err := tx.Update(ctx, &testUser{ID: 1, Name: "ann 2", Email: "a@x"}); err != nil {
+			return err
+		}
+		err := tx.WithTransaction(ctx, func(inner Repository[testUser]) error {
+			if _, err := inner.Update(ctx, &testUser{ID: 2, Name: "bob 2", Email: "b@x"}); err != nil {
+				return err
+			}
+			return innerErr
+		})
+		if !errors.Is(err, innerErr) {
+			t.Errorf("nested WithTransaction = %v, want the inner error", err)
+		}
+		return nil // The outer transaction commits regardless
+	})
+	if err != nil {
+		t.Fatalf("WithTransaction: %v", err)
+	}
+	env.verify()
+
+	// Only the committed write is notified
+	select {
+	case event := <-events:
+		if event.Operation != ChangeUpdate || event.PrimaryKey != uint(1) {
+			t.Errorf("event = %+v, want the update of user 1", event)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no change event for the committed update")
+	}
+	select {
+	case event := <-events:
+		t.Errorf("unexpected event %+v for a rolled back write", event)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestOutboxSkipsRowsOfRolledBackNestedTransaction(t *testing.T) {
+	env := newTestEnv(t)
+	repo := newTestRepository[testUser](env, WithOutbox(OutboxConfig{PollInterval: time.Hour}))
+	repo.outbox.close() // Rows are still inserted; only the relay is stopped
+	ctx := context.Background()
+
+	env.mock.ExpectBegin()
+	env.mock.ExpectExec("UPDATE `users` SET").WithArgs("ann 2", "a@x", 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	env.mock.ExpectExec("SAVEPOINT").WillReturnResult(sqlmock.NewResult(0, 0))
+	env.mock.ExpectExec("UPDATE `users` SET").WithArgs("bob 2", "b@x", 2).WillReturnResult(sqlmock.NewResult(0, 1))
+	env.mock.ExpectExec("ROLLBACK TO SAVEPOINT").WillReturnResult(sqlmock.NewResult(0, 0))
+	// One outbox row, for the committed update only
+	env.mock.ExpectExec("INSERT INTO `sql4go_outbox` .* VALUES \\([?,]+\\)$").
+		WithArgs("app", "users", "update", "1", sqlmock.AnyArg(), 0, "", sqlmock.AnyArg(), sqlmock.AnyArg(), nil).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	env.mock.ExpectCommit()
+
+	err := repo.WithTransaction(ctx, func(tx Repository[testUser]) error {
+		if _, err := tx.Update(ctx, &testUser{ID: 1, Name: "ann 2", Email: "a@x"}); err != nil {
+			return err
+		}
+		_ = tx.WithTransaction(ctx, func(inner Repository[testUser]) error {
+			if _, err := inner.Update(ctx, &testUser{ID: 2, Name: "bob 2", Email: "b@x"}); err != nil {
+				return err
+			}
+			return errors.New("inner failure")
+		})
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WithTransaction: %v", err)
+	}
+	env.verify()
+}
+
+func TestCommitWritesThroughLastWriteOfEachEntity(t *testing.T) {
+	env := newTestEnv(t, func(c *redis.Config) { c.Strategy = redis.CacheStrategyWriteThrough })
+	repo := newTestRepository[testUser](env)
+	ctx := context.Background()
+
+	env.mock.ExpectBegin()
+	env.mock.ExpectExec("INSERT INTO `users`").WillReturnResult(sqlmock.NewResult(1, 1))
+	env.mock.ExpectExec("INSERT INTO `users`").WillReturnResult(sqlmock.NewResult(2, 1))
+	env.mock.ExpectQuery("SELECT \\* FROM `users`").WillReturnRows(userRows(testUser{ID: 2, Name: "alan", Email: "alan@example.com"}))
+	env.mock.ExpectExec("DELETE FROM `users`").WillReturnResult(sqlmock.NewResult(0, 1))
+	env.mock.ExpectCommit()
+	err := repo.WithTransaction(ctx, func(tx Repository[testUser]) error {
+		if _, err := tx.Create(ctx, &testUser{ID: 1, Name: "ada", Email: "ada@example.com"}); err != nil {
+			return err
+		}
+		if _, err := tx.Create(ctx, &testUser{ID: 2, Name: "alan", Email: "alan@example.com"}); err != nil {
+			return err
+		}
+		_, err := tx.Delete(ctx, 2)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("WithTransaction: %v", err)
+	}
+	env.verify()
+
+	if found, hit, _, err := repo.FindByID(ctx, 1); err != nil || !hit || found.Name != "ada" {
+		t.Errorf("FindByID(1) after commit = (%+v, hit %v, %v), want the written-through ada", found, hit, err)
+	}
+	if env.mr.Exists(repo.generateCacheKey("find_by_id", "2")) {
+		t.Errorf("user 2, deleted in the transaction, was written through")
+	}
+}
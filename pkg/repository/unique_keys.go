@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// uniqueFieldsOf returns the single-column unique fields of an entity schema
+func uniqueFieldsOf(entitySchema *schema.Schema) []*schema.Field {
+	if entitySchema == nil {
+		return nil
+	}
+
+	unique := make(map[string]bool)
+	for _, field := range entitySchema.Fields {
+		if field.Unique && field.DBName != "" {
+			unique[field.DBName] = true
+		}
+	}
+	for _, index := range entitySchema.ParseIndexes() {
+		if index.Class == "UNIQUE" && len(index.Fields) == 1 && index.Fields[0].DBName != "" {
+			unique[index.Fields[0].DBName] = true
+		}
+	}
+
+	var fields []*schema.Field
+	for _, field := range entitySchema.Fields {
+		if unique[field.DBName] && !field.PrimaryKey {
+			fields = append(fields, field)
+			delete(unique, field.DBName) // Embedded duplicates are listed once
+		}
+	}
+	return fields
+}
+
+// uniqueCacheKey returns the unchained FindByUnique key of a column value
+func (r *GenericRepository[T]) uniqueCacheKey(column string, value interface{}) string {
+	query := map[string]interface{}{column: value}
+	return buildCacheKey(r.keyNamespace, "find_by_unique", queryKeySuffix(query, nil, "", r.options.KeyHasher), maxKeyLength(r.redis))
+}
+
+// loadUniqueValues loads the stored row of entity restricted to its unique columns, or
+// returns nil if no FindByUnique keys need clearing (no cache, no unique columns, a
+// transaction, or no stored row)
+func (r *GenericRepository[T]) loadUniqueValues(ctx context.Context, entity T) *T {
+	if r.redis == nil || r.tx != nil || len(r.uniqueFields) == 0 || !r.operationCached("find_by_unique") {
+		return nil
+	}
+	id := r.primaryKeyValue(entity)
+	if isZeroKey(id) {
+		return nil // Save inserts the row
+	}
+
+	columns := make([]string, 0, len(r.uniqueFields)+1)
+	columns = append(columns, r.primaryKey)
+	for _, field := range r.uniqueFields {
+		columns = append(columns, field.DBName)
+	}
+
+	var previous T
+	start := time.Now()
+	err := r.findByPrimaryKey(r.db.WithContext(ctx).Select(columns), &previous, id)
+	r.observeQuery(ctx, "update_lookup", start, err)
+	if err != nil {
+		if err != gorm.ErrRecordNotFound {
+			r.redis.Logger().Printf("sql4go: failed to load unique values of %s.%s %v: %v", r.dbName, r.tableName, id, err)
+		}
+		return nil
+	}
+	return &previous
+}
+
+// invalidateChangedUniqueKeys deletes the FindByUnique keys of the old and new values of
+// every unique column that differs between previous and entity (best effort)
+//
+// FindByUnique caches entries under the looked-up value (e.g. the email), depending on
+// the found entity. When an Update changes a unique column, the entry of the old value
+// would keep resolving to the entity until the table wipe runs (which
+// Invalidation.DebounceWindow may delay), and a cached not-found of the new value has no
+// dependency at all. Update therefore loads the unique values being replaced and deletes
+// the FindByUnique keys of both the old and the new value of every changed column.
+//
+// Unique columns are those declared in the GORM schema: a `unique` tag or a single-column
+// `uniqueIndex`. Only unchained keys are cleared, and only outside transactions: by
+// Update, and by CreateBatchIgnore for batches with skipped duplicates (the values of
+// every entity). Other writes rely on the table wipe.
+func (r *GenericRepository[T]) invalidateChangedUniqueKeys(ctx context.Context, previous *T, entity T) {
+	if previous == nil {
+		return
+	}
+
+	oldValue := reflect.ValueOf(previous).Elem()
+	newValue := reflect.ValueOf(&entity).Elem()
+	var keys []string
+	for _, field := range r.uniqueFields {
+		oldField, oldZero := field.ValueOf(ctx, oldValue)
+		newField, newZero := field.ValueOf(ctx, newValue)
+		if oldZero == newZero && reflect.DeepEqual(oldField, newField) {
+			continue
+		}
+		if !oldZero {
+			keys = append(keys, r.uniqueCacheKey(field.DBName, oldField))
+		}
+		if !newZero {
+			keys = append(keys, r.uniqueCacheKey(field.DBName, newField))
+		}
+	}
+	if len(keys) > 0 {
+		_ = r.redis.DeleteKeys(ctx, keys)
+	}
+}
+
+// invalidateUniqueKeys deletes the FindByUnique keys of the unique values of entity
+// (best effort)
+func (r *GenericRepository[T]) invalidateUniqueKeys(ctx context.Context, entity T) {
+	if r.redis == nil || r.tx != nil || len(r.uniqueFields) == 0 || !r.operationCached("find_by_unique") {
+		return
+	}
+
+	rv := reflect.ValueOf(&entity).Elem()
+	var keys []string
+	for _, field := range r.uniqueFields {
+		if value, isZero := field.ValueOf(ctx, rv); !isZero {
+			keys = append(keys, r.uniqueCacheKey(field.DBName, value))
+		}
+	}
+	if len(keys) > 0 {
+		_ = r.redis.DeleteKeys(ctx, keys)
+	}
+}
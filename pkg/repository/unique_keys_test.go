@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ammar0144/sql4go/pkg/redis"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestFindByUniqueInvalidatedOnUpdate(t *testing.T) {
+	// A long debounce window delays the table wipe, so only the unique-key
+	// invalidation can clear the entries
+	env := newTestEnv(t, func(c *redis.Config) { c.Invalidation.DebounceWindow = time.Hour })
+	repo := newTestRepository[testUser](env)
+	ctx := context.Background()
+
+	user := testUser{ID: 1, Name: "ann", Email: "a@x"}
+	env.mock.ExpectQuery("SELECT \\* FROM `users` WHERE `users`.`email` = \\?").WithArgs("a@x", 1).WillReturnRows(userRows(user))
+	env.mock.ExpectQuery("SELECT \\* FROM `users` WHERE `users`.`email` = \\?").WithArgs("b@x", 1).WillReturnRows(userRows())
+
+	if found, _, stored, err := repo.FindByUnique(ctx, "email", "a@x"); err != nil || !stored || found == nil || found.ID != 1 {
+		t.Fatalf("FindByUnique(a@x) = (%+v, stored %v, %v), want user 1 cached", found, stored, err)
+	}
+	if found, hit, _, err := repo.FindByUnique(ctx, "Email", "a@x"); err != nil || !hit || found == nil {
+		t.Fatalf("FindByUnique by field name = (%+v, hit %v, %v), want a cache hit", found, hit, err)
+	}
+	if found, _, stored, err := repo.FindByUnique(ctx, "email", "b@x"); err != nil || !stored || found != nil {
+		t.Fatalf("FindByUnique(b@x) = (%+v, stored %v, %v), want a cached not-found", found, stored, err)
+	}
+	oldKey, newKey := repo.uniqueCacheKey("email", "a@x"), repo.uniqueCacheKey("email", "b@x")
+	if !env.mr.Exists(oldKey) || !env.mr.Exists(newKey) {
+		t.Fatalf("cached keys %q, want %s and %s", env.cachedKeys(), oldKey, newKey)
+	}
+
+	// The email changes from a@x to b@x
+	env.expectUniqueValues(user)
+	env.mock.ExpectExec("UPDATE `users` SET").WillReturnResult(sqlmock.NewResult(0, 1))
+	user.Email = "b@x"
+	if _, err := repo.Update(ctx, &user); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	env.verify()
+
+	if env.mr.Exists(oldKey) {
+		t.Error("the entry of the old value still resolves to the user")
+	}
+	if env.mr.Exists(newKey) {
+		t.Error("the cached not-found of the new value survived the update")
+	}
+}
+
+func TestFindByUniqueRejectsUnknownColumn(t *testing.T) {
+	env := newTestEnv(t)
+	repo := newTestRepository[testUser](env)
+
+	if _, _, _, err := repo.FindByUnique(context.Background(), "nickname", "ann"); err == nil {
+		t.Fatal("FindByUnique on a column outside the schema succeeded")
+	}
+	env.verify()
+}
+
+func TestUpdateClearsOldUniqueKeyWithoutDependencies(t *testing.T) {
+	// A backend without dependency sets whose table wipes never run: only the
+	// unique-key invalidation of Update can clear the entry
+	env := newTestEnv(t)
+	cache := noWipeFakeCache{newFakeCache()}
+	repo := newFakeCacheRepository(env, cache)
+	ctx := context.Background()
+
+	user := testUser{ID: 1, Name: "ann", Email: "a@x"}
+	env.mock.ExpectQuery("SELECT \\* FROM `users` WHERE `users`.`email` = \\?").WillReturnRows(userRows(user))
+	if _, _, stored, err := repo.FindByUnique(ctx, "email", "a@x"); err != nil || !stored {
+		t.Fatalf("FindByUnique = (stored %v, %v), want a cached row", stored, err)
+	}
+	oldKey := repo.uniqueCacheKey("email", "a@x")
+	if _, ok := cache.values[oldKey]; !ok {
+		t.Fatalf("%s not cached", oldKey)
+	}
+
+	env.expectUniqueValues(user)
+	env.mock.ExpectExec("UPDATE `users` SET").WillReturnResult(sqlmock.NewResult(0, 1))
+	user.Email = "c@x"
+	if _, err := repo.Update(ctx, &user); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	env.verify()
+
+	if _, ok := cache.values[oldKey]; ok {
+		t.Errorf("%s still resolves to the user after the email changed", oldKey)
+	}
+}
+
+// noWipeFakeCache is a fakeCache whose pattern invalidations are dropped
+type noWipeFakeCache struct {
+	*fakeCache
+}
+
+func (c noWipeFakeCache) InvalidatePattern(ctx context.Context, pattern string) error {
+	c.record("InvalidatePattern")
+	return nil
+}
@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/ammar0144/sql4go/pkg/db"
+	"github.com/ammar0144/sql4go/pkg/redis"
+)
+
+// entityTableName returns the table name of an entity type, verifying that it
+// implements Entity and returns a non-empty TableName()
+func entityTableName(entityType reflect.Type) (string, error) {
+	// Create a model instance suitable for assertions and for calling Entity methods
+	var model interface{}
+	if entityType.Kind() == reflect.Ptr {
+		model = reflect.New(entityType.Elem()).Interface()
+	} else {
+		model = reflect.New(entityType).Interface()
+	}
+
+	ent, ok := model.(Entity)
+	if !ok {
+		return "", fmt.Errorf("entity type %v does not implement repository.Entity", entityType)
+	}
+	tableName := ent.TableName()
+	if tableName == "" {
+		return "", fmt.Errorf("entity type %v returned empty TableName(), Entity interface not properly implemented", entityType)
+	}
+	return tableName, nil
+}
+
+// Validate checks that entity T maps to the database, and returns every problem found
+// (joined, each wrapping ErrInvalidEntity):
+//   - TableName() is set and matches the table of the parsed GORM schema
+//   - the table exists (via the Migrator)
+//   - a primary key is declared (by tag, field name or PrimaryKeyAware) and its column exists
+//   - the targets of GORM relationships and of RelationshipAware.GetRelationships (as
+//     returned for the zero entity) are existing tables
+//
+// It queries the database's information schema and is meant for startup, not per request.
+//
+// A misconfigured entity (a TableName that is not the GORM table, a missing primary key,
+// a relationship to a table that does not exist) otherwise shows up as a panic in
+// NewGenericRepository or as cache keys and invalidations that silently miss. Validate
+// checks an entity against the schema and the live database so services can fail a
+// deploy at startup; TryNewGenericRepository creates a repository without panicking.
+func Validate[T Entity](dbManager *db.Manager) error {
+	entityType := reflect.TypeOf((*T)(nil)).Elem()
+	if dbManager == nil || dbManager.DB() == nil {
+		return fmt.Errorf("%w: %v: database manager is required", ErrInvalidEntity, entityType)
+	}
+
+	var problems []error
+	problem := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Errorf("%w: %v: %s", ErrInvalidEntity, entityType, fmt.Sprintf(format, args...)))
+	}
+
+	tableName, err := entityTableName(entityType)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidEntity, err)
+	}
+
+	entitySchema, err := parseEntitySchema(dbManager.DB(), entityType)
+	if err != nil {
+		return fmt.Errorf("%w: %v: cannot parse GORM schema: %w", ErrInvalidEntity, entityType, err)
+	}
+	if entitySchema.Table != tableName {
+		problem("TableName() returns %q but GORM maps the entity to table %q", tableName, entitySchema.Table)
+	}
+
+	migrator := dbManager.DB().Migrator()
+	tableExists := migrator.HasTable(tableName)
+	if !tableExists {
+		problem("table %q does not exist", tableName)
+	}
+
+	if declared := declaredPrimaryKeyColumn(entityType); declared != "" {
+		if tableExists && !migrator.HasColumn(tableName, declared) {
+			problem("declared primary key column %q does not exist in table %q", declared, tableName)
+		}
+	} else if len(entitySchema.PrimaryFields) == 0 {
+		problem("no primary key: tag a field with gorm:\"primaryKey\" or name it ID")
+	} else if tableExists {
+		for _, field := range entitySchema.PrimaryFields {
+			if !migrator.HasColumn(tableName, field.DBName) {
+				problem("primary key column %q does not exist in table %q", field.DBName, tableName)
+			}
+		}
+	}
+
+	// Relationship targets, each table checked once
+	targets := make(map[string]string) // table -> where it is referenced
+	for name, relationship := range entitySchema.Relationships.Relations {
+		if relationship.FieldSchema != nil {
+			targets[relationship.FieldSchema.Table] = fmt.Sprintf("GORM relationship %s", name)
+		}
+		if relationship.JoinTable != nil {
+			targets[relationship.JoinTable.Table] = fmt.Sprintf("join table of GORM relationship %s", name)
+		}
+	}
+	for relationType, related := range declaredRelationships[T]() {
+		for _, entity := range related {
+			if entity.EntityType == "" {
+				problem("GetRelationships returns a %s relationship without EntityType", relationType)
+				continue
+			}
+			targets[entity.EntityType] = fmt.Sprintf("GetRelationships (%s)", relationType)
+		}
+	}
+	tables := make([]string, 0, len(targets))
+	for table := range targets {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+	for _, table := range tables {
+		if table != tableName && !migrator.HasTable(table) {
+			problem("%s references table %q, which does not exist", targets[table], table)
+		}
+	}
+
+	return errors.Join(problems...)
+}
+
+// declaredRelationships returns the GetRelationships of the zero entity, or nil if T is
+// not RelationshipAware or its GetRelationships cannot handle the zero entity
+func declaredRelationships[T Entity]() (relationships map[string][]RelatedEntity) {
+	defer func() {
+		if recover() != nil {
+			relationships = nil // e.g. dereferencing a nil association
+		}
+	}()
+
+	var zero T
+	if aware, ok := interface{}(zero).(RelationshipAware); ok {
+		return aware.GetRelationships()
+	}
+	if aware, ok := interface{}(&zero).(RelationshipAware); ok {
+		return aware.GetRelationships()
+	}
+	return nil
+}
+
+// TryNewGenericRepository is NewGenericRepository returning an error wrapping
+// ErrInvalidEntity instead of panicking on an invalid entity or a missing database
+// manager. It does not query the database; call Validate for the full checks.
+func TryNewGenericRepository[T Entity](dbManager *db.Manager, redisManager *redis.Manager, opts ...Option) (Repository[T], error) {
+	entityType := reflect.TypeOf((*T)(nil)).Elem()
+	if dbManager == nil || dbManager.DB() == nil {
+		return nil, fmt.Errorf("%w: %v: database manager is required", ErrInvalidEntity, entityType)
+	}
+	if _, err := entityTableName(entityType); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidEntity, err)
+	}
+	return NewGenericRepository[T](dbManager, redisManager, opts...), nil
+}
@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// recordingLogger collects the messages logged through a cache manager
+type recordingLogger struct {
+	mu       sync.Mutex
+	messages []string
+}
+
+func (l *recordingLogger) Printf(format string, args ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.messages = append(l.messages, fmt.Sprintf(format, args...))
+}
+
+func (l *recordingLogger) logged() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string(nil), l.messages...)
+}
+
+func TestCacheVerificationRecordsStaleEntry(t *testing.T) {
+	tests := []struct {
+		name     string
+		serve    bool
+		wantName string
+	}{
+		{name: "serves cached value", wantName: "stale"},
+		{name: "serves database value", serve: true, wantName: "ann"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			logger := &recordingLogger{}
+			env.cache.SetLogger(logger)
+			repo := newTestRepository[testUser](env, WithCacheVerification(CacheVerification{ServeDatabase: tt.serve}))
+			ctx := context.Background()
+
+			// An entry an invalidation missed: the row was renamed to "ann" since
+			key := repo.CacheKeyForID(1)
+			if err := env.cache.SetValue(ctx, key, testUser{ID: 1, Name: "stale", Email: "a@x"}); err != nil {
+				t.Fatalf("SetValue: %v", err)
+			}
+			env.mock.ExpectQuery("SELECT \\* FROM `users`").WillReturnRows(userRows(testUser{ID: 1, Name: "ann", Email: "a@x"}))
+
+			found, hit, _, err := repo.FindByID(ctx, 1)
+			if err != nil || !hit || found == nil || found.Name != tt.wantName {
+				t.Fatalf("FindByID = (%+v, hit %v, %v), want %q from a cache hit", found, hit, err, tt.wantName)
+			}
+			env.verify()
+
+			stats, enabled := repo.CacheVerificationStats()
+			if !enabled || stats.Verified != 1 || stats.Mismatches != 1 || stats.Errors != 0 {
+				t.Errorf("CacheVerificationStats = (%+v, %v), want one verified mismatch", stats, enabled)
+			}
+			logged := logger.logged()
+			if len(logged) != 1 || !strings.Contains(logged[0], key) {
+				t.Errorf("logged %q, want one message naming %s", logged, key)
+			}
+		})
+	}
+}
+
+func TestCacheVerificationAcceptsFreshEntry(t *testing.T) {
+	env := newTestEnv(t)
+	repo := newTestRepository[testUser](env, WithCacheVerification(CacheVerification{}))
+	ctx := context.Background()
+
+	user := testUser{ID: 1, Name: "ann", Email: "a@x"}
+	env.mock.ExpectQuery("SELECT \\* FROM `users`").WillReturnRows(userRows(user))
+	env.mock.ExpectQuery("SELECT \\* FROM `users`").WillReturnRows(userRows(user))
+
+	if _, _, stored, err := repo.FindByID(ctx, 1); err != nil || !stored {
+		t.Fatalf("first FindByID = (stored %v, %v), want a cached row", stored, err)
+	}
+	if _, hit, _, err := repo.FindByID(ctx, 1); err != nil || !hit {
+		t.Fatalf("second FindByID = (hit %v, %v), want a cache hit", hit, err)
+	}
+	env.verify()
+
+	if stats, _ := repo.CacheVerificationStats(); stats.Verified != 1 || stats.Mismatches != 0 {
+		t.Errorf("CacheVerificationStats = %+v, want one verified match", stats)
+	}
+}
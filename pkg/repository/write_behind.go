@@ -0,0 +1,321 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// writeBehindOp is a queued database write
+type writeBehindOp[T Entity] struct {
+	entity T
+	create bool
+	cached chan bool // Receives whether queueWrite stored the entity in the cache
+}
+
+// flushRequest asks the worker to write everything queued
+type flushRequest struct {
+	reply   chan error
+	takeErr bool // Return and clear the write errors (Flush) or keep them (internal ordering)
+}
+
+// writeBehindQueue batches queued writes of one repository
+//
+// Create and Update queue the database write, store the entity under its FindByID key
+// and return immediately; a background worker flushes the queue in batches.
+//
+// DATA LOSS WARNING: queued writes live in process memory only. Writes not yet flushed
+// are lost if the process crashes. Call Flush to wait for pending writes and Close on
+// shutdown to drain the queue. Use this only for write-heavy, loss-tolerant data such
+// as counters.
+//
+// Further caveats:
+//   - Create requires the primary key to be set by the caller (no auto-increment);
+//     entities with a zero primary key are written synchronously
+//   - Queries that hit the database (FindWhere, Count, ...) do not see queued writes
+//   - Delete, CreateBatch and UpdateBatch flush the queue first and then run synchronously,
+//     as do Create and Update when the queue is full
+//   - A failed batch drops the affected FindByID keys so the cache does not serve rows
+//     that never reached the database; the error is returned by the next Flush/Close
+type writeBehindQueue[T Entity] struct {
+	repo      *GenericRepository[T]
+	batchSize int
+	interval  time.Duration
+
+	ops      chan writeBehindOp[T]
+	flushReq chan flushRequest
+	done     chan struct{}
+	stopped  chan struct{}
+
+	mu     sync.RWMutex // Guards closed against concurrent enqueue
+	closed bool
+
+	errMu sync.Mutex
+	err   error // Write errors since the last Flush
+}
+
+// newWriteBehindQueue starts the background worker of a repository
+func newWriteBehindQueue[T Entity](repo *GenericRepository[T], queueSize, batchSize int, interval time.Duration) *writeBehindQueue[T] {
+	q := &writeBehindQueue[T]{
+		repo:      repo,
+		batchSize: batchSize,
+		interval:  interval,
+		ops:       make(chan writeBehindOp[T], queueSize),
+		flushReq:  make(chan flushRequest),
+		done:      make(chan struct{}),
+		stopped:   make(chan struct{}),
+	}
+	go q.run()
+	return q
+}
+
+// enqueue queues a write; returns false if the queue is full or closed,
+// in which case the caller writes synchronously
+func (q *writeBehindQueue[T]) enqueue(op writeBehindOp[T]) bool {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	if q.closed {
+		return false
+	}
+
+	select {
+	case q.ops <- op:
+		return true
+	default:
+		return false // Queue full - apply backpressure by writing synchronously
+	}
+}
+
+// run is the background worker loop
+func (q *writeBehindQueue[T]) run() {
+	defer close(q.stopped)
+
+	ticker := time.NewTicker(q.interval)
+	defer ticker.Stop()
+
+	var pending []writeBehindOp[T]
+	for {
+		select {
+		case op := <-q.ops:
+			pending = append(pending, op)
+			if len(pending) >= q.batchSize {
+				q.write(pending)
+				pending = nil
+			}
+		case <-ticker.C:
+			q.write(pending)
+			pending = nil
+		case req := <-q.flushReq:
+			q.write(q.drain(pending))
+			pending = nil
+			if req.takeErr {
+				req.reply <- q.takeErr()
+			} else {
+				req.reply <- nil
+			}
+		case <-q.done:
+			q.write(q.drain(pending))
+			return
+		}
+	}
+}
+
+// drain appends all writes currently in the queue to pending
+func (q *writeBehindQueue[T]) drain(pending []writeBehindOp[T]) []writeBehindOp[T] {
+	for {
+		select {
+		case op := <-q.ops:
+			pending = append(pending, op)
+		default:
+			return pending
+		}
+	}
+}
+
+// write flushes a batch to the database, in batches of at most batchSize
+func (q *writeBehindQueue[T]) write(ops []writeBehindOp[T]) {
+	for len(ops) > 0 {
+		n := min(len(ops), q.batchSize)
+		q.writeBatch(ops[:n])
+		ops = ops[n:]
+	}
+}
+
+// writeBatch writes one batch: creates in one INSERT, updates in one upsert
+func (q *writeBehindQueue[T]) writeBatch(ops []writeBehindOp[T]) {
+	var creates, updates, uncached []T
+	for _, op := range ops {
+		if op.create {
+			creates = append(creates, op.entity)
+		} else {
+			updates = append(updates, op.entity)
+		}
+		// Wait for the caller's cache update, so a failed write's cleanup cannot precede it
+		if !<-op.cached {
+			uncached = append(uncached, op.entity)
+		}
+	}
+
+	ctx, cancel := q.repo.withQueryTimeout(context.Background())
+	defer cancel()
+
+	if len(creates) > 0 {
+		start := time.Now()
+		err := q.repo.writeSession(ctx).Create(&creates).Error
+		q.repo.observeQuery(ctx, "write_behind_create", start, err)
+		q.handleResult(ctx, ChangeCreate, creates, err)
+	}
+	if len(updates) > 0 {
+		start := time.Now()
+		err := q.repo.writeSession(ctx).Save(&updates).Error
+		q.repo.observeQuery(ctx, "write_behind_update", start, err)
+		q.handleResult(ctx, ChangeUpdate, updates, err)
+	}
+
+	// Entries the caller failed to update may have been re-read from the database
+	// before the write; drop them now that it has landed (or failed)
+	for _, entity := range uncached {
+		_ = q.repo.redis.DeleteLarge(ctx, q.repo.entityCacheKey(q.repo.primaryKeyValue(entity)))
+	}
+}
+
+// handleResult emits the change events of a written batch, or records a failed batch
+// and drops the cached values it would have written
+func (q *writeBehindQueue[T]) handleResult(ctx context.Context, operation ChangeOperation, entities []T, err error) {
+	if err == nil {
+		for _, entity := range entities {
+			q.repo.emitChange(operation, entity)
+		}
+		return
+	}
+
+	q.errMu.Lock()
+	q.err = errors.Join(q.err, fmt.Errorf("write-behind batch of %d failed: %w", len(entities), classifyQueryError(ctx, err)))
+	q.errMu.Unlock()
+
+	// Ignore cache errors - best effort
+	for _, entity := range entities {
+		cacheKey := q.repo.entityCacheKey(q.repo.primaryKeyValue(entity))
+		_ = q.repo.redis.DeleteLarge(ctx, cacheKey)
+	}
+}
+
+// takeErr returns and clears the write errors since the last call
+func (q *writeBehindQueue[T]) takeErr() error {
+	q.errMu.Lock()
+	defer q.errMu.Unlock()
+	err := q.err
+	q.err = nil
+	return err
+}
+
+// flush writes all queued writes
+// With takeErr it returns and clears the write errors since the last flush
+func (q *writeBehindQueue[T]) flush(ctx context.Context, takeErr bool) error {
+	req := flushRequest{reply: make(chan error, 1), takeErr: takeErr}
+	select {
+	case q.flushReq <- req:
+	case <-q.stopped:
+		if takeErr {
+			return q.takeErr() // Already closed - everything was written
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case err := <-req.reply:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// close stops accepting writes, drains the queue and stops the worker
+func (q *writeBehindQueue[T]) close() error {
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.done)
+	}
+	q.mu.Unlock()
+
+	<-q.stopped
+	return q.takeErr()
+}
+
+// queueWrite queues a write-behind Create/Update, then updates the cache
+// Returns false if the write must be executed synchronously instead; the cache is
+// only touched once the write is queued, so a synchronous fallback that fails
+// leaves no trace of the entity in the cache.
+func (r *GenericRepository[T]) queueWrite(ctx context.Context, entity T, create bool) bool {
+	if r.writeBehind == nil || r.outbox != nil {
+		return false // The outbox needs the write in its transaction
+	}
+	if !r.operationCached("find_by_id") {
+		return false // Queued writes are only visible through the FindByID cache
+	}
+	pkValue := r.primaryKeyValue(entity)
+	if isZeroKey(pkValue) {
+		return false // Primary key assigned by the database - write synchronously
+	}
+
+	// The worker holds the write until the cache is updated (see writeBatch)
+	cached := make(chan bool, 1)
+	if !r.writeBehind.enqueue(writeBehindOp[T]{entity: entity, create: create, cached: cached}) {
+		return false
+	}
+
+	// Cache so the write is visible to FindByID immediately
+	operation := ChangeUpdate
+	if create {
+		operation = ChangeCreate
+	}
+	_ = r.invalidateEntityCaches(ctx, operation, entity) // Best effort - the entry is overwritten next
+	cacheKey := r.entityCacheKey(pkValue)
+	cached <- r.redis.SetValueWithTTL(ctx, cacheKey, entity, r.operationTTL("find_by_id")) == nil
+	return true
+}
+
+// Flush writes all queued write-behind writes to the database
+// Returns the errors of failed writes since the last Flush. No-op for other strategies.
+func (r *GenericRepository[T]) Flush(ctx context.Context) error {
+	if r.writeBehind == nil {
+		return nil
+	}
+	return r.writeBehind.flush(ctx, true)
+}
+
+// awaitQueuedWrites writes all queued writes before a synchronous write,
+// so it is not overtaken by an older queued write. Write errors are kept for Flush.
+func (r *GenericRepository[T]) awaitQueuedWrites(ctx context.Context) error {
+	if r.writeBehind == nil {
+		return nil
+	}
+	return r.writeBehind.flush(ctx, false)
+}
+
+// Close drains the write-behind queue, stops the outbox relay and re-warming, delivers
+// pending change events and stops the workers
+// Call on shutdown; afterwards writes are executed synchronously and emit no events.
+func (r *GenericRepository[T]) Close() error {
+	var err error
+	if r.writeBehind != nil {
+		err = r.writeBehind.close() // First, as flushed writes emit change events
+	}
+	if r.outbox != nil {
+		r.outbox.close()
+	}
+	if r.rewarm != nil {
+		r.rewarm.close()
+	}
+	if r.invalidationRetry != nil {
+		r.invalidationRetry.close() // After the writers, whose invalidations it may retry
+	}
+	r.events.close()
+	return err
+}
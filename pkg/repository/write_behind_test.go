@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ammar0144/sql4go/pkg/redis"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// newWriteBehindEnv returns an environment whose cache uses the write-behind strategy
+func newWriteBehindEnv(t *testing.T, queueSize, batchSize int) *testEnv {
+	return newTestEnv(t, func(c *redis.Config) {
+		c.Strategy = redis.CacheStrategyWriteBehind
+		c.WriteBehind = redis.WriteBehindConfig{QueueSize: queueSize, BatchSize: batchSize, FlushInterval: time.Hour}
+	})
+}
+
+func TestWriteBehindCachesImmediatelyAndWritesOnFlush(t *testing.T) {
+	env := newWriteBehindEnv(t, 10, 10)
+	repo := newTestRepository[testUser](env)
+	ctx := context.Background()
+
+	result, err := repo.CreateWithResult(ctx, &testUser{ID: 1, Name: "ann", Email: "a@x"})
+	if err != nil || !result.Queued {
+		t.Fatalf("Create = (%+v, %v), want a queued write", result, err)
+	}
+
+	// Visible through the cache before the database write
+	user, hit, _, err := repo.FindByID(ctx, 1)
+	if err != nil || !hit || user.Name != "ann" {
+		t.Fatalf("FindByID = (%+v, hit %v, %v), want the queued row from the cache", user, hit, err)
+	}
+
+	env.mock.ExpectExec("INSERT INTO `users`").WithArgs("ann", "a@x", 1).WillReturnResult(sqlmock.NewResult(1, 1))
+	if err := repo.Flush(ctx); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	env.verify()
+}
+
+func TestWriteBehindEnqueueFailureLeavesCacheUntouched(t *testing.T) {
+	env := newWriteBehindEnv(t, 10, 10)
+	repo := newTestRepository[testUser](env)
+	ctx := context.Background()
+
+	// A closed queue rejects writes, which then run synchronously
+	if err := repo.writeBehind.close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	env.mock.ExpectExec("INSERT INTO `users`").WillReturnError(errors.New("duplicate entry"))
+
+	result, err := repo.CreateWithResult(ctx, &testUser{ID: 1, Name: "ann", Email: "a@x"})
+	if err == nil || result.Queued {
+		t.Fatalf("Create = (%+v, %v), want the synchronous write's error", result, err)
+	}
+	if env.mr.Exists(repo.CacheKeyForID(1)) {
+		t.Error("the failed write was cached")
+	}
+	env.verify()
+}
+
+func TestWriteBehindFallbackWaitsForQueuedWrites(t *testing.T) {
+	env := newWriteBehindEnv(t, 1, 1)
+	repo := newTestRepository[testUser](env)
+	ctx := context.Background()
+
+	// The first write occupies the worker, the second fills the queue, the third
+	// falls back to a synchronous write that must land after both
+	env.mock.ExpectExec("INSERT INTO `users`").WithArgs("v1", "a@x", 1).WillDelayFor(200 * time.Millisecond).WillReturnResult(sqlmock.NewResult(0, 1))
+	env.mock.ExpectExec("INSERT INTO `users`").WithArgs("v2", "a@x", 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	env.expectUniqueValues(testUser{ID: 1, Email: "a@x"})
+	env.mock.ExpectExec("UPDATE `users` SET").WithArgs("v3", "a@x", 1).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	for i, name := range []string{"v1", "v2", "v3"} {
+		if _, err := repo.Update(ctx, &testUser{ID: 1, Name: name, Email: "a@x"}); err != nil {
+			t.Fatalf("Update %s: %v", name, err)
+		}
+		if i == 0 {
+			time.Sleep(50 * time.Millisecond) // Let the worker pick up the first write
+		}
+	}
+	env.verify() // Expectations are ordered: v1 and v2 were written before v3
+}